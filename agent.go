@@ -3,6 +3,7 @@
 package gocbcore
 
 import (
+	"crypto/tls"
 	"crypto/x509"
 	"errors"
 	"fmt"
@@ -16,6 +17,8 @@ import (
 // Agent represents the base client handling connections to a Couchbase Server.
 // This is used internally by the higher level classes for communicating with the cluster,
 // it can also be used to perform more advanced operations with a cluster.
+// An Agent is safe for concurrent use by multiple goroutines, including calling Close whilst other operations are
+// being dispatched, in which case those operations fail rather than being left incomplete.
 type Agent struct {
 	clientID             string
 	bucketName           string
@@ -26,26 +29,39 @@ type Agent struct {
 	httpMux          *httpMux
 	dialer           *memdClientDialerComponent
 
-	cfgManager   *configManagementComponent
-	errMap       *errMapComponent
-	collections  *collectionsComponent
-	tracer       *tracerComponent
-	http         *httpComponent
-	diagnostics  *diagnosticsComponent
-	crud         *crudComponent
-	observe      *observeComponent
-	stats        *statsComponent
-	n1ql         *n1qlQueryComponent
-	analytics    *analyticsQueryComponent
-	search       *searchQueryComponent
-	views        *viewQueryComponent
-	zombieLogger *zombieLoggerComponent
+	cfgManager        *configManagementComponent
+	errMap            *errMapComponent
+	collections       *collectionsComponent
+	collectionsMgmt   *collectionsMgmtComponent
+	tracer            *tracerComponent
+	http              *httpComponent
+	diagnostics       *diagnosticsComponent
+	crud              *crudComponent
+	observe           *observeComponent
+	observeDurability *observeDurabilityComponent
+	stats             *statsComponent
+	n1ql              *n1qlQueryComponent
+	analytics         *analyticsQueryComponent
+	search            *searchQueryComponent
+	views             *viewQueryComponent
+	zombieLogger      *zombieLoggerComponent
+	connEvents        *kvConnectionEventsComponent
+	mirror            *mirrorComponent
+	memdProxy         *memdProxyComponent
+	integrity         *integrityComponent
 
 	// These connection settings are only ever changed when ForceReconnect or ReconfigureSecurity are called.
 	connectionSettingsLock sync.Mutex
 	auth                   AuthProvider
 	authMechanisms         []AuthMechanism
 	tlsConfig              *dynTLSConfig
+	// tokenRefreshUnregister stops KV connections from being re-authenticated when the bearer token is refreshed.
+	tokenRefreshUnregister func()
+	// tlsChangeUnregister stops connections from being rebuilt when the root CAs change.
+	tlsChangeUnregister func()
+
+	// tlsBaseConfig is the configuration which TLS connections are based on, it is used when TLS is reconfigured.
+	tlsBaseConfig *tls.Config
 
 	srvDetails  *srvDetails
 	shutdownSig chan struct{}
@@ -81,10 +97,18 @@ func createAgent(config *AgentConfig) (*Agent, error) {
 		errMap: newErrMapManager(config.BucketName),
 		auth:   config.SecurityConfig.Auth,
 
+		connEvents: newKVConnectionEventsComponent(),
+
 		shutdownSig: make(chan struct{}),
 	}
 
-	tlsConfig, err := setupTLSConfig(config.SeedConfig.MemdAddrs, config.SecurityConfig)
+	tlsBaseConfig, err := config.SecurityConfig.tlsBaseConfig()
+	if err != nil {
+		return nil, err
+	}
+	c.tlsBaseConfig = tlsBaseConfig
+
+	tlsConfig, err := setupTLSConfig(config.SeedConfig.MemdAddrs, config.SecurityConfig, tlsBaseConfig)
 	if err != nil {
 		return nil, err
 	}
@@ -100,6 +124,9 @@ func createAgent(config *AgentConfig) (*Agent, error) {
 	}
 
 	circuitBreakerConfig := config.CircuitBreakerConfig
+	if kvBreakerConfig, ok := config.ServiceCircuitBreakerConfigs[MemdService]; ok {
+		circuitBreakerConfig = kvBreakerConfig
+	}
 	userAgent := config.UserAgent
 	useMutationTokens := config.IoConfig.UseMutationTokens
 	disableDecompression := config.CompressionConfig.DisableDecompression
@@ -131,11 +158,34 @@ func createAgent(config *AgentConfig) (*Agent, error) {
 		kvPoolSize = config.KVConfig.PoolSize
 	}
 
+	kvPoolGrowQueueLatency := 5 * time.Millisecond
+	if config.KVConfig.PoolGrowQueueLatency > 0 {
+		kvPoolGrowQueueLatency = config.KVConfig.PoolGrowQueueLatency
+	}
+
+	kvPoolIdleTimeout := 60 * time.Second
+	if config.KVConfig.PoolIdleTimeout > 0 {
+		kvPoolIdleTimeout = config.KVConfig.PoolIdleTimeout
+	}
+
 	maxQueueSize := 2048
 	if config.KVConfig.MaxQueueSize > 0 {
 		maxQueueSize = config.KVConfig.MaxQueueSize
 	}
 
+	nmvRetryDelay := defaultNotMyVbucketRetryDelay
+	if config.KVConfig.NotMyVbucketRetryDelay > 0 {
+		nmvRetryDelay = config.KVConfig.NotMyVbucketRetryDelay
+	}
+
+	var blockOnFullQueueMaxWait time.Duration
+	if config.KVConfig.BlockOnFullQueue {
+		blockOnFullQueueMaxWait = 2500 * time.Millisecond
+		if config.KVConfig.BlockOnFullQueueMaxWait > 0 {
+			blockOnFullQueueMaxWait = config.KVConfig.BlockOnFullQueueMaxWait
+		}
+	}
+
 	kvBufferSize := uint(0)
 	if config.KVConfig.ConnectionBufferSize > 0 {
 		kvBufferSize = config.KVConfig.ConnectionBufferSize
@@ -249,6 +299,11 @@ func createAgent(config *AgentConfig) (*Agent, error) {
 		seedNodeAddr = host
 	}
 
+	memdProxyListener, err := listenMemdProxy(config.MemdProxyConfig)
+	if err != nil {
+		return nil, err
+	}
+
 	c.cfgManager = newConfigManager(
 		configManagerProperties{
 			NetworkType:  config.IoConfig.NetworkType,
@@ -260,6 +315,11 @@ func createAgent(config *AgentConfig) (*Agent, error) {
 	)
 
 	c.tracer = newTracerComponent(config.TracerConfig.Tracer, config.BucketName, config.TracerConfig.NoRootTraceSpans, config.MeterConfig.Meter, c.cfgManager)
+	c.cfgManager.SetTracer(c.tracer)
+	configCache := newConfigCacheComponent(config.ConfigCacheConfig, config.BucketName)
+	if configCache != nil {
+		c.cfgManager.SetConfigCache(configCache)
+	}
 
 	c.dialer = newMemdClientDialerComponent(
 		memdClientDialerProps{
@@ -270,7 +330,13 @@ func createAgent(config *AgentConfig) (*Agent, error) {
 			CompressionMinRatio:  compressionMinRatio,
 			DisableDecompression: disableDecompression,
 			NoTLSSeedNode:        config.SecurityConfig.NoTLSSeedNode,
+			RequireCertAuth:      config.SecurityConfig.RequireCertificateAuth,
 			ConnBufSize:          kvBufferSize,
+			WriteFlushInterval:   config.KVConfig.WriteFlushInterval,
+			MaxWriteBatchSize:    config.KVConfig.MaxWriteBatchSize,
+			RateLimit:            config.KVConfig.RateLimit,
+
+			BucketWarmupRetryWindow: config.KVConfig.BucketWarmupRetryWindow,
 		},
 		bootstrapProps{
 			HelloProps: helloProps{
@@ -294,13 +360,22 @@ func createAgent(config *AgentConfig) (*Agent, error) {
 		c.zombieLogger,
 		c.tracer,
 		c.cfgManager,
+		c.connEvents,
 	)
 	c.kvMux = newKVMux(
 		kvMuxProps{
-			QueueSize:          maxQueueSize,
-			PoolSize:           kvPoolSize,
-			CollectionsEnabled: useCollections,
-			NoTLSSeedNode:      config.SecurityConfig.NoTLSSeedNode,
+			QueueSize:               maxQueueSize,
+			PoolSize:                kvPoolSize,
+			CollectionsEnabled:      useCollections,
+			NoTLSSeedNode:           config.SecurityConfig.NoTLSSeedNode,
+			GracefulCloseTimeout:    config.KVConfig.GracefulCloseTimeout,
+			MaxPoolSize:             config.KVConfig.MaxPoolSize,
+			PoolGrowQueueLatency:    kvPoolGrowQueueLatency,
+			PoolIdleTimeout:         kvPoolIdleTimeout,
+			BlockOnFullQueueMaxWait: blockOnFullQueueMaxWait,
+			ReadOnly:                config.KVConfig.ReadOnly,
+			NotMyVbucketPolicy:      config.KVConfig.NotMyVbucketPolicy,
+			NotMyVbucketRetryDelay:  nmvRetryDelay,
 		},
 		c.cfgManager,
 		c.errMap,
@@ -330,8 +405,12 @@ func createAgent(config *AgentConfig) (*Agent, error) {
 	)
 	c.http = newHTTPComponent(
 		httpComponentProps{
-			UserAgent:            userAgent,
-			DefaultRetryStrategy: c.defaultRetryStrategy,
+			UserAgent:             userAgent,
+			DefaultRetryStrategy:  c.defaultRetryStrategy,
+			CircuitBreakerConfigs: config.ServiceCircuitBreakerConfigs,
+			AuditHook:             config.HTTPConfig.AuditHook,
+			MaxResponseBodySize:   config.HTTPConfig.MaxResponseBodySize,
+			EndpointSelection:     config.HTTPConfig.EndpointSelectionPolicy,
 		},
 		httpClientProps{
 			maxIdleConns:        config.HTTPConfig.MaxIdleConns,
@@ -345,67 +424,69 @@ func createAgent(config *AgentConfig) (*Agent, error) {
 	)
 
 	var poller configPollerController
-	if len(config.SeedConfig.MemdAddrs) == 0 && config.BucketName == "" {
-		// The http poller can't run without a bucket. We don't trigger an error for this case
-		// because AgentGroup users who use memcached buckets on non-default ports will end up here.
-		logDebugf("No bucket name specified and only http addresses specified, not running config poller")
-		c.diagnostics = newDiagnosticsComponent(c.kvMux, c.httpMux, c.http, c.bucketName, c.defaultRetryStrategy, nil)
+	if config.SecurityConfig.NoTLSSeedNode {
+		poller = newSeedConfigController(srcHTTPAddrs[0].Address, c.bucketName,
+			httpPollerProperties{
+				httpComponent:        c.http,
+				confHTTPRetryDelay:   confHTTPRetryDelay,
+				confHTTPRedialPeriod: confHTTPRedialPeriod,
+				confHTTPMaxWait:      confHTTPMaxWait,
+			}, c.cfgManager)
 	} else {
-		if config.SecurityConfig.NoTLSSeedNode {
-			poller = newSeedConfigController(srcHTTPAddrs[0].Address, c.bucketName,
+		var httpPoller *httpConfigController
+		// Without a bucket the http poller is only needed to bootstrap when there are no memd addresses to
+		// perform GCCCP against, once it has found the KV nodes the poller controller moves over to GCCCP.
+		if c.bucketName != "" || len(config.SeedConfig.MemdAddrs) == 0 {
+			httpPoller = newHTTPConfigController(
+				c.bucketName,
 				httpPollerProperties{
 					httpComponent:        c.http,
 					confHTTPRetryDelay:   confHTTPRetryDelay,
 					confHTTPRedialPeriod: confHTTPRedialPeriod,
 					confHTTPMaxWait:      confHTTPMaxWait,
-				}, c.cfgManager)
-		} else {
-			var httpPoller *httpConfigController
-			if c.bucketName != "" {
-				httpPoller = newHTTPConfigController(
-					c.bucketName,
-					httpPollerProperties{
-						httpComponent:        c.http,
-						confHTTPRetryDelay:   confHTTPRetryDelay,
-						confHTTPRedialPeriod: confHTTPRedialPeriod,
-						confHTTPMaxWait:      confHTTPMaxWait,
-					},
-					c.httpMux,
-					c.cfgManager,
-				)
-			}
-			cccpFetcher := newCCCPConfigFetcher(confCccpMaxWait)
-			poller = newPollerController(
-				newCCCPConfigController(
-					cccpPollerProperties{
-						confCccpPollPeriod: confCccpPollPeriod,
-						cccpConfigFetcher:  cccpFetcher,
-					},
-					c.kvMux,
-					c.cfgManager,
-					c.isPollingFallbackError,
-					c.onCCCPNoConfigFromAnyNode,
-				),
-				httpPoller,
+				},
+				c.httpMux,
 				c.cfgManager,
-				c.isPollingFallbackError,
 			)
-			c.cfgManager.SetConfigFetcher(cccpFetcher)
 		}
-		c.pollerController = poller
-		c.diagnostics = newDiagnosticsComponent(c.kvMux, c.httpMux, c.http, c.bucketName, c.defaultRetryStrategy, c.pollerController)
+		cccpFetcher := newCCCPConfigFetcher(confCccpMaxWait)
+		poller = newPollerController(
+			newCCCPConfigController(
+				cccpPollerProperties{
+					confCccpPollPeriod: confCccpPollPeriod,
+					cccpConfigFetcher:  cccpFetcher,
+				},
+				c.kvMux,
+				c.cfgManager,
+				c.isPollingFallbackError,
+				c.onCCCPNoConfigFromAnyNode,
+			),
+			httpPoller,
+			c.cfgManager,
+			c.isPollingFallbackError,
+		)
+		c.cfgManager.SetConfigFetcher(cccpFetcher)
 	}
+	c.pollerController = poller
+	c.diagnostics = newDiagnosticsComponent(c.kvMux, c.httpMux, c.http, c.bucketName, c.defaultRetryStrategy, c.pollerController)
 	c.dialer.AddBootstrapFailHandler(c.diagnostics)
 	c.dialer.AddCCCPUnsupportedHandler(c)
 	c.cfgManager.AddConfigWatcher(c.dialer)
 
 	c.observe = newObserveComponent(c.collections, c.defaultRetryStrategy, c.tracer, c.kvMux)
-	c.crud = newCRUDComponent(c.collections, c.defaultRetryStrategy, c.tracer, c.errMap, c.kvMux, c.kvMux, disableDecompression, c.kvMux)
+	c.observeDurability = newObserveDurabilityComponent(c.observe, c.kvMux)
+	c.crud = newCRUDComponent(c.collections, c.defaultRetryStrategy, c.tracer, c.errMap, c.kvMux, c.kvMux, disableDecompression, c.kvMux,
+		config.ValueHooks)
 	c.stats = newStatsComponent(c.kvMux, c.defaultRetryStrategy, c.tracer)
 	c.n1ql = newN1QLQueryComponent(c.http, c.cfgManager, c.tracer)
 	c.analytics = newAnalyticsQueryComponent(c.http, c.tracer)
 	c.search = newSearchQueryComponent(c.http, c.cfgManager, c.tracer)
 	c.views = newViewQueryComponent(c.http, c.tracer)
+	c.collectionsMgmt = newCollectionsMgmtComponent(c.http, c.tracer, c.bucketName, c.defaultRetryStrategy)
+	c.mirror = newMirrorComponent(config.MirrorConfig)
+	c.memdProxy = newMemdProxyComponent(memdProxyListener, config.MemdProxyConfig, c.kvMux, c.tracer,
+		c.defaultRetryStrategy)
+	c.integrity = newIntegrityComponent(config.KVConfig.EnableChecksums, c.crud)
 
 	// Kick everything off.
 	cfg := &routeConfig{
@@ -417,13 +498,44 @@ func createAgent(config *AgentConfig) (*Agent, error) {
 	c.httpMux.OnNewRouteConfig(cfg)
 	c.kvMux.OnNewRouteConfig(cfg)
 
+	if configCache != nil {
+		// Pre-build routing from the last known config, this is replaced as soon as the cluster provides a config.
+		if cachedCfg := configCache.Load(seedHosts(append(srcMemdAddrs, srcHTTPAddrs...))); cachedCfg != nil {
+			c.cfgManager.OnCachedConfig(cachedCfg, cfg)
+		}
+	}
+
 	if c.pollerController != nil {
 		go c.pollerController.Run()
 	}
 
+	c.connectionSettingsLock.Lock()
+	c.watchTokenRefreshLocked(c.auth)
+	c.connectionSettingsLock.Unlock()
+
+	if config.SecurityConfig.TLSFileWatcher != nil && config.SecurityConfig.ReconnectOnRootCAChange {
+		c.tlsChangeUnregister = config.SecurityConfig.TLSFileWatcher.onChange(c.onTLSFilesChanged)
+	}
+
 	return c, nil
 }
 
+// watchTokenRefreshLocked re-authenticates KV connections whenever auth refreshes its bearer token, replacing any
+// previously watched provider.
+func (agent *Agent) watchTokenRefreshLocked(auth AuthProvider) {
+	if agent.tokenRefreshUnregister != nil {
+		agent.tokenRefreshUnregister()
+		agent.tokenRefreshUnregister = nil
+	}
+
+	tokenAuth, ok := auth.(tokenAuthProvider)
+	if !ok {
+		return
+	}
+
+	agent.tokenRefreshUnregister = tokenAuth.onTokenRefresh(agent.kvMux.ReauthenticateClients)
+}
+
 // Close shuts down the agent, disconnecting from all servers and failing
 // any outstanding operations with ErrShutdown.
 func (agent *Agent) Close() error {
@@ -432,6 +544,21 @@ func (agent *Agent) Close() error {
 	if poller != nil {
 		poller.Stop()
 	}
+
+	agent.connectionSettingsLock.Lock()
+	agent.watchTokenRefreshLocked(nil)
+	agent.connectionSettingsLock.Unlock()
+
+	if agent.tlsChangeUnregister != nil {
+		agent.tlsChangeUnregister()
+	}
+
+	if agent.memdProxy != nil {
+		if err := agent.memdProxy.Close(); err != nil {
+			logDebugf("Failed to close memd proxy: %v", err)
+		}
+	}
+
 	routeCloseErr := agent.kvMux.Close()
 	agent.cfgManager.Close()
 
@@ -448,6 +575,23 @@ func (agent *Agent) Close() error {
 	return routeCloseErr
 }
 
+// MemdProxyAddress returns the address that the memd proxy is listening on, or an empty string if the proxy is
+// not enabled.
+// Volatile: This API is subject to change at any time.
+func (agent *Agent) MemdProxyAddress() string {
+	if agent.memdProxy == nil {
+		return ""
+	}
+
+	return agent.memdProxy.Address()
+}
+
+// HTTPEndpointLatencies returns the recent latencies observed for each query, search and analytics endpoint.
+// Volatile: This API is subject to change at any time.
+func (agent *Agent) HTTPEndpointLatencies() []HTTPEndpointLatency {
+	return agent.http.EndpointLatencies()
+}
+
 // ClientID returns the unique id for this agent
 func (agent *Agent) ClientID() string {
 	return agent.clientID
@@ -535,6 +679,42 @@ func (agent *Agent) HasSeenConfig() (bool, error) {
 	return seen > -1, nil
 }
 
+// SetReadOnly enables or disables read-only mode at runtime. Whilst enabled any operation which could modify a
+// document, including touching its expiry, fails with ErrReadOnlyMode without being sent to the server. Reads are
+// unaffected. Mutations which are already in flight are not affected, but those waiting to be retried are rejected.
+// Volatile: This API is subject to change at any time.
+func (agent *Agent) SetReadOnly(enabled bool) {
+	agent.kvMux.SetReadOnly(enabled)
+}
+
+// IsReadOnly returns whether the agent is currently in read-only mode.
+// Volatile: This API is subject to change at any time.
+func (agent *Agent) IsReadOnly() bool {
+	return agent.kvMux.IsReadOnly()
+}
+
+// OnIOError registers a handler which is invoked whenever an attempt to connect, or reconnect, to a KV node fails.
+// The returned function unregisters the handler.
+// Volatile: This API is subject to change at any time.
+func (agent *Agent) OnIOError(handler KVIOErrorHandler) func() {
+	return agent.connEvents.OnIOError(handler)
+}
+
+// OnDisconnect registers a handler which is invoked whenever an established KV connection is dropped, for example
+// due to the node failing or closing the connection. The returned function unregisters the handler.
+// Volatile: This API is subject to change at any time.
+func (agent *Agent) OnDisconnect(handler KVDisconnectHandler) func() {
+	return agent.connEvents.OnDisconnect(handler)
+}
+
+// OnReconnect registers a handler which is invoked whenever a KV connection is established to a node whose previous
+// connection was dropped, along with how long the node was without a connection. The returned function unregisters
+// the handler.
+// Volatile: This API is subject to change at any time.
+func (agent *Agent) OnReconnect(handler KVReconnectHandler) func() {
+	return agent.connEvents.OnReconnect(handler)
+}
+
 // WaitUntilReady is used to verify that the SDK has been able to establish connections to the cluster.
 // If no strategy is set then a fast fail retry strategy will be applied - only RetryReason that are set to always
 // retry will be retried. This includes for WaitUntilReady, that is the SDK will wait until connections succeed
@@ -582,6 +762,52 @@ func (agent *Agent) ForceReconnect() {
 	agent.kvMux.ForceReconnect(tlsConfig, mechs, auth, true)
 }
 
+// ReloadCredentialsOptions are the options available to the ReloadCredentials function.
+// Volatile: This API is subject to change at any time.
+type ReloadCredentialsOptions struct {
+	// Auth is the AuthProvider which supplies the new credentials or client certificate.
+	Auth AuthProvider
+
+	// ForceReconnect causes all existing connections to be rebuilt using the new credentials, by default existing
+	// connections are kept and only newly established connections use the new credentials.
+	ForceReconnect bool
+}
+
+// ReloadCredentials replaces the AuthProvider being used by the agent, allowing passwords or client certificates
+// to be rotated without recreating the agent. Any persistent in flight requests (e.g. DCP) will be terminated with
+// ErrForcedReconnect if ForceReconnect is set.
+// Volatile: This API is subject to change at any time.
+func (agent *Agent) ReloadCredentials(opts ReloadCredentialsOptions) error {
+	if opts.Auth == nil {
+		return wrapError(errInvalidArgument, "must provide Auth")
+	}
+
+	agent.connectionSettingsLock.Lock()
+	// The client certificate is supplied to TLS connections by the AuthProvider, so the TLS config must be rebuilt.
+	tlsConfig := agent.tlsConfig
+	if tlsConfig != nil {
+		tlsConfig = createTLSConfig(opts.Auth, tlsConfig.Provider, agent.tlsBaseConfig)
+	}
+	mechs := agent.authMechanisms
+
+	agent.auth = opts.Auth
+	agent.tlsConfig = tlsConfig
+	agent.watchTokenRefreshLocked(opts.Auth)
+	agent.connectionSettingsLock.Unlock()
+
+	if opts.ForceReconnect {
+		agent.kvMux.ForceReconnect(tlsConfig, mechs, opts.Auth, true)
+	} else {
+		agent.kvMux.UpdateCredentials(tlsConfig, mechs, opts.Auth)
+	}
+	agent.httpMux.UpdateTLS(tlsConfig, opts.Auth)
+	if opts.ForceReconnect {
+		agent.http.cli.CloseIdleConnections()
+	}
+
+	return nil
+}
+
 // ReconfigureSecurityOptions are the options available to the ReconfigureSecurity function.
 type ReconfigureSecurityOptions struct {
 	UseTLS bool
@@ -629,14 +855,18 @@ func (agent *Agent) ReconfigureSecurity(opts ReconfigureSecurityOptions) error {
 	var tlsConfig *dynTLSConfig
 	if opts.UseTLS {
 		if opts.TLSRootCAProvider == nil {
+			agent.connectionSettingsLock.Unlock()
 			return wrapError(errInvalidArgument, "must provide TLSRootCAProvider when UseTLS is true")
 		}
-		tlsConfig = createTLSConfig(auth, opts.TLSRootCAProvider)
+		tlsConfig = createTLSConfig(auth, opts.TLSRootCAProvider, agent.tlsBaseConfig)
 	}
 
 	agent.auth = auth
 	agent.authMechanisms = mechs
 	agent.tlsConfig = tlsConfig
+	if authProvided {
+		agent.watchTokenRefreshLocked(auth)
+	}
 	agent.connectionSettingsLock.Unlock()
 
 	agent.cfgManager.UseTLS(tlsConfig != nil)
@@ -645,6 +875,16 @@ func (agent *Agent) ReconfigureSecurity(opts ReconfigureSecurityOptions) error {
 	return nil
 }
 
+func (agent *Agent) onTLSFilesChanged(rootCAsChanged bool) {
+	if !rootCAsChanged {
+		return
+	}
+
+	logInfof("Root CAs have changed, reconnecting all connections")
+	agent.ForceReconnect()
+	agent.http.cli.CloseIdleConnections()
+}
+
 func (agent *Agent) onCCCPUnsupported(err error) {
 	// If this error is a legitimate fallback reason then we should immediately start the http poller.
 	// This should always be a poller fallback error but lets just be sure.
@@ -821,9 +1061,16 @@ func authMechanismsFromConfig(authMechanisms []AuthMechanism, useTLS bool) []Aut
 	return authMechanisms
 }
 
-func setupTLSConfig(addrs []string, config SecurityConfig) (*dynTLSConfig, error) {
+func setupTLSConfig(addrs []string, config SecurityConfig, baseConfig *tls.Config) (*dynTLSConfig, error) {
+	if config.RequireCertificateAuth && (!config.UseTLS || config.NoTLSSeedNode) {
+		return nil, wrapError(errInvalidArgument, "certificate authentication requires TLS for all connections")
+	}
+
 	var tlsConfig *dynTLSConfig
 	if config.UseTLS {
+		if config.TLSRootCAProvider == nil && config.TLSFileWatcher != nil {
+			config.TLSRootCAProvider = config.TLSFileWatcher.RootCAs
+		}
 		if config.TLSRootCAProvider == nil {
 			logDebugf("TLS enabled with no root ca provider - trusting system cert pool and Capella root CA")
 
@@ -837,7 +1084,7 @@ func setupTLSConfig(addrs []string, config SecurityConfig) (*dynTLSConfig, error
 				return pool
 			}
 		}
-		tlsConfig = createTLSConfig(config.Auth, config.TLSRootCAProvider)
+		tlsConfig = createTLSConfig(config.Auth, config.TLSRootCAProvider, baseConfig)
 	} else {
 		var endsInCloud bool
 		for _, host := range addrs {
@@ -1,11 +1,14 @@
 package gocbcore
 
 import (
+	"crypto/tls"
 	"crypto/x509"
 	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/couchbase/gocbcore/v10/connstr"
@@ -48,12 +51,34 @@ type AgentConfig struct {
 
 	CircuitBreakerConfig CircuitBreakerConfig
 
+	// ServiceCircuitBreakerConfigs allows circuit breakers to be configured per service. An entry for MemdService
+	// overrides CircuitBreakerConfig, HTTP services only use circuit breakers when they have an entry here.
+	// Volatile: This API is subject to change at any time.
+	ServiceCircuitBreakerConfigs map[ServiceType]CircuitBreakerConfig
+
 	OrphanReporterConfig OrphanReporterConfig
 
 	TracerConfig TracerConfig
 
 	MeterConfig MeterConfig
 
+	// Volatile: This API is subject to change at any time.
+	MirrorConfig MirrorConfig
+
+	// MemdProxyConfig allows a local memcached protocol listener to be started which forwards requests through the
+	// agent, this is intended for debugging.
+	// Volatile: This API is subject to change at any time.
+	MemdProxyConfig MemdProxyConfig
+
+	// ConfigCacheConfig allows the last known cluster config to be persisted to disk and used to build routing on
+	// the next startup.
+	// Volatile: This API is subject to change at any time.
+	ConfigCacheConfig ConfigCacheConfig
+
+	// ValueHooks allows document values to be transformed as they are written and read.
+	// Volatile: This API is subject to change at any time.
+	ValueHooks ValueHooks
+
 	InternalConfig InternalConfig
 }
 
@@ -115,6 +140,87 @@ type SecurityConfig struct {
 	// since PLAIN sends the credentials in cleartext. It is disabled by default to prevent downgrade attacks. We
 	// recommend using a TLS connection if using PLAIN.
 	AuthMechanisms []AuthMechanism
+
+	// TLSConfig, if set, is used as the base configuration for all TLS connections. The SDK will still set the root
+	// CAs and server name for each connection, as well as the client certificate callback if no client certificates
+	// are configured.
+	// Volatile: This API is subject to change at any time.
+	TLSConfig *tls.Config
+
+	// TLSCipherSuites and TLSCurvePreferences, if set, override the cipher suites and curves used for TLS connections.
+	// Note that the cipher suites used by TLS 1.3 are not configurable.
+	// Volatile: This API is subject to change at any time.
+	TLSCipherSuites     []uint16
+	TLSCurvePreferences []tls.CurveID
+
+	// TLSMinVersion and TLSMaxVersion, if set, override the range of TLS versions which connections can negotiate,
+	// e.g. setting TLSMinVersion to tls.VersionTLS13 only allows TLS 1.3. TLSMinVersion defaults to TLS 1.2.
+	// Volatile: This API is subject to change at any time.
+	TLSMinVersion uint16
+	TLSMaxVersion uint16
+
+	// TLSKeyLogWriter, if set, is written the TLS secrets for every connection in NSS key log format, allowing traffic
+	// to be decrypted by tools such as Wireshark. This entirely compromises the security of TLS and so must only be
+	// used for debugging in test environments, InsecureAllowTLSKeyLog must also be set for it to be used.
+	// Volatile: This API is subject to change at any time.
+	TLSKeyLogWriter        io.Writer
+	InsecureAllowTLSKeyLog bool
+
+	// TLSFileWatcher, if set, supplies the root CAs for TLS connections when TLSRootCAProvider is not set so that
+	// root CAs which are updated on disk are used by subsequently dialed connections.
+	// Volatile: This API is subject to change at any time.
+	TLSFileWatcher *TLSFileWatcher
+
+	// ReconnectOnRootCAChange causes all existing connections to be rebuilt whenever TLSFileWatcher loads changed
+	// root CAs, rather than only new connections using them.
+	// Volatile: This API is subject to change at any time.
+	ReconnectOnRootCAChange bool
+
+	// RequireCertificateAuth specifies that KV connections authenticate using only the client certificate, SASL
+	// authentication is never attempted. Agent creation fails if TLS is not enabled for every connection.
+	// Volatile: This API is subject to change at any time.
+	RequireCertificateAuth bool
+}
+
+// tlsBaseConfig creates the configuration which all TLS connections are based on.
+func (config SecurityConfig) tlsBaseConfig() (*tls.Config, error) {
+	var baseConfig *tls.Config
+	if config.TLSConfig != nil {
+		baseConfig = config.TLSConfig.Clone()
+	} else {
+		baseConfig = &tls.Config{
+			MinVersion: tls.VersionTLS12,
+		}
+	}
+
+	if len(config.TLSCipherSuites) > 0 {
+		baseConfig.CipherSuites = config.TLSCipherSuites
+	}
+	if config.TLSMinVersion != 0 {
+		baseConfig.MinVersion = config.TLSMinVersion
+	}
+	if config.TLSMaxVersion != 0 {
+		baseConfig.MaxVersion = config.TLSMaxVersion
+	}
+	if baseConfig.MaxVersion != 0 && baseConfig.MinVersion > baseConfig.MaxVersion {
+		return nil, wrapError(errInvalidArgument, "TLS minimum version cannot be greater than the maximum version")
+	}
+	if len(config.TLSCurvePreferences) > 0 {
+		baseConfig.CurvePreferences = config.TLSCurvePreferences
+	}
+	if config.TLSKeyLogWriter != nil {
+		baseConfig.KeyLogWriter = config.TLSKeyLogWriter
+	}
+
+	if baseConfig.KeyLogWriter != nil {
+		if !config.InsecureAllowTLSKeyLog {
+			return nil, wrapError(errInvalidArgument, "TLS key logging requires InsecureAllowTLSKeyLog to be set")
+		}
+
+		logWarnf("TLS key logging is enabled, all TLS traffic can be decrypted using the key log")
+	}
+
+	return baseConfig, nil
 }
 
 func (config SecurityConfig) fromSpec(spec connstr.ResolvedConnSpec) (SecurityConfig, error) {
@@ -148,9 +254,83 @@ func (config SecurityConfig) fromSpec(spec connstr.ResolvedConnSpec) (SecurityCo
 		config.NoTLSSeedNode = true
 	}
 
+	// This option is experimental
+	if valStr, ok := fetchOption(spec, "require_cert_auth"); ok {
+		val, err := strconv.ParseBool(valStr)
+		if err != nil {
+			return SecurityConfig{}, fmt.Errorf("require_cert_auth option must be a boolean")
+		}
+		config.RequireCertificateAuth = val
+	}
+
+	if valStr, ok := fetchOption(spec, "tls_min_version"); ok {
+		val, err := parseTLSVersion(valStr)
+		if err != nil {
+			return SecurityConfig{}, fmt.Errorf("tls_min_version option must be one of 1.2 or 1.3")
+		}
+		config.TLSMinVersion = val
+	}
+
+	if valStr, ok := fetchOption(spec, "tls_max_version"); ok {
+		val, err := parseTLSVersion(valStr)
+		if err != nil {
+			return SecurityConfig{}, fmt.Errorf("tls_max_version option must be one of 1.2 or 1.3")
+		}
+		config.TLSMaxVersion = val
+	}
+
+	if valStr, ok := fetchOption(spec, "tls_cipher_suites"); ok {
+		val, err := parseTLSCipherSuites(valStr)
+		if err != nil {
+			return SecurityConfig{}, fmt.Errorf("tls_cipher_suites option must be a comma separated list of cipher suite names: %v", err)
+		}
+		config.TLSCipherSuites = val
+	}
+
 	return config, nil
 }
 
+func parseTLSVersion(val string) (uint16, error) {
+	switch val {
+	case "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	default:
+		return 0, errInvalidArgument
+	}
+}
+
+// parseTLSCipherSuites parses a comma separated list of cipher suite names, as named by the standard library, into
+// their IDs. Suites with known security issues are rejected.
+func parseTLSCipherSuites(val string) ([]uint16, error) {
+	var suites []uint16
+	for _, name := range strings.Split(val, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+
+		var found bool
+		for _, suite := range tls.CipherSuites() {
+			if suite.Name == name {
+				suites = append(suites, suite.ID)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown or insecure cipher suite %s", name)
+		}
+	}
+
+	if len(suites) == 0 {
+		return nil, errInvalidArgument
+	}
+
+	return suites, nil
+}
+
 // CompressionConfig specifies options for controlling compression applied to documents using KV.
 type CompressionConfig struct {
 	Enabled              bool
@@ -256,6 +436,8 @@ type IoConfig struct {
 	EnablePITRHello             bool
 	UseCollections              bool
 
+	// UseClusterMapNotifications enables the server to push cluster map changes to the client, rather than the client
+	// having to poll for them. Nodes which do not support notifications continue to be polled.
 	UseClusterMapNotifications bool
 }
 
@@ -323,6 +505,18 @@ type HTTPConfig struct {
 	// IdleConnTimeout is the maximum amount of time an idle (keep-alive) connection will remain idle before closing
 	// itself.
 	IdleConnectionTimeout time.Duration
+	// AuditHook, if set, is invoked for every HTTP request sent to the cluster.
+	// Volatile: This API is subject to change at any time.
+	AuditHook HTTPAuditHook
+	// EndpointSelectionPolicy specifies how query, search and analytics endpoints are chosen, defaults to random.
+	// Volatile: This API is subject to change at any time.
+	EndpointSelectionPolicy HTTPEndpointSelectionPolicy
+	// MaxResponseBodySize is the largest HTTP response body, in bytes, which the SDK will read in full, such as error
+	// responses and management responses, defaults to 64MiB. Larger responses fail with ErrHTTPResponseTooLarge.
+	// The limit also applies to each config received on a streamed config connection, the stream is reconnected if a
+	// config exceeds it. Streamed results and response bodies returned by DoHTTPRequest are not limited.
+	// Volatile: This API is subject to change at any time.
+	MaxResponseBodySize int64
 }
 
 func (config HTTPConfig) fromSpec(spec connstr.ResolvedConnSpec) (HTTPConfig, error) {
@@ -366,6 +560,26 @@ func (config HTTPConfig) fromSpec(spec connstr.ResolvedConnSpec) (HTTPConfig, er
 		config.ConnectTimeout = val
 	}
 
+	// This option is experimental
+	if valStr, ok := fetchOption(spec, "http_endpoint_selection"); ok {
+		switch valStr {
+		case "random":
+			config.EndpointSelectionPolicy = HTTPEndpointSelectionRandom
+		case "fastest":
+			config.EndpointSelectionPolicy = HTTPEndpointSelectionFastest
+		default:
+			return HTTPConfig{}, fmt.Errorf("http_endpoint_selection option must be random or fastest")
+		}
+	}
+
+	if valStr, ok := fetchOption(spec, "max_http_response_body_size"); ok {
+		val, err := strconv.ParseInt(valStr, 10, 64)
+		if err != nil || val <= 0 {
+			return HTTPConfig{}, fmt.Errorf("max_http_response_body_size option must be a positive number")
+		}
+		config.MaxResponseBodySize = val
+	}
+
 	return config, nil
 }
 
@@ -379,12 +593,105 @@ type KVConfig struct {
 
 	// The number of connections to create to each node.
 	PoolSize int
+
+	// MaxPoolSize enables dynamic sizing of the pool of connections to each node when it is greater than PoolSize,
+	// which then becomes the minimum number of connections. Connections are added, one at a time, whilst requests
+	// are waiting in the queue for a node for longer than PoolGrowQueueLatency and are removed once they have not
+	// been used for PoolIdleTimeout. Not supported by the DCPAgent.
+	// Volatile: This API is subject to change at any time.
+	MaxPoolSize int
+	// PoolGrowQueueLatency is how long requests must be waiting to be written before another connection is added,
+	// defaults to 5ms. Only used when MaxPoolSize is set.
+	// Volatile: This API is subject to change at any time.
+	PoolGrowQueueLatency time.Duration
+	// PoolIdleTimeout is how long a connection above PoolSize can go unused before it is closed, defaults to 60s.
+	// Only used when MaxPoolSize is set.
+	// Volatile: This API is subject to change at any time.
+	PoolIdleTimeout time.Duration
 	// The maximum number of requests that can be queued waiting to be sent to a node.
 	MaxQueueSize int
+	// BlockOnFullQueue makes dispatching an operation block when the queue for the node is full, until space
+	// becomes available, BlockOnFullQueueMaxWait elapses or the operation deadline is reached, rather than failing
+	// immediately with a QueueOverloadError. Only applies to the initial dispatch of key value operations, retries and
+	// internal requests never block. Not supported by the DCPAgent.
+	// Volatile: This API is subject to change at any time.
+	BlockOnFullQueue bool
+	// BlockOnFullQueueMaxWait is the longest that dispatching an operation blocks for when BlockOnFullQueue is
+	// enabled, defaults to 2.5s.
+	// Volatile: This API is subject to change at any time.
+	BlockOnFullQueueMaxWait time.Duration
+
+	// ReadOnly makes the agent start in read-only mode, see Agent.SetReadOnly. Not supported by the DCPAgent.
+	// Volatile: This API is subject to change at any time.
+	ReadOnly bool
 
 	// Note: if you create multiple agents with different buffer sizes within the same environment then you will
 	// get indeterminate behaviour, the connections may not even use the provided buffer size.
 	ConnectionBufferSize uint
+
+	// EnableChecksums enables storing a checksum of the document body in a system xattr when JSON documents are
+	// written using Add, Set or Replace, and verifying it when documents are read using Get. These operations are
+	// performed using sub-document operations when enabled. Documents which fail verification return a
+	// ChecksumMismatchError. Documents whose body has been modified by other operations since the checksum was
+	// written, such as Append, are returned without verification. When ValueHooks are set the checksum is of the
+	// value returned by BeforeWrite, as that is the value that is stored, and the value hooks are applied to the
+	// document but not the sub-document value hooks.
+	// Volatile: This API is subject to change at any time.
+	EnableChecksums bool
+
+	// BucketWarmupRetryWindow enables retrying select bucket during connection bootstrap when the bucket is not
+	// found, as happens whilst a bucket is being created or warmed up. Retries happen for at most this period of time
+	// after the first such failure of each connection attempt, defaults to 0 which disables the behaviour.
+	// Authentication failures are never retried.
+	// Volatile: This API is subject to change at any time.
+	BucketWarmupRetryWindow time.Duration
+
+	// WriteFlushInterval enables coalescing of writes to KV connections, requests which are sent within this period
+	// of one another are gathered into a single network write. Defaults to 0 which disables coalescing.
+	// Volatile: This API is subject to change at any time.
+	WriteFlushInterval time.Duration
+
+	// MaxWriteBatchSize is the number of bytes of coalesced writes after which they are flushed without waiting for
+	// the WriteFlushInterval to elapse. Defaults to 64KiB, only used when WriteFlushInterval is set.
+	// Volatile: This API is subject to change at any time.
+	MaxWriteBatchSize int
+
+	// GracefulCloseTimeout is how long a connection to a node which is no longer part of the cluster config is
+	// given for its in-flight requests to complete before it is forcibly closed, no new requests are dispatched to
+	// it in the meantime. Requests still in flight when it is closed are handed to the retry strategy. Defaults to
+	// 0 which waits for all in-flight requests to complete.
+	// Volatile: This API is subject to change at any time.
+	GracefulCloseTimeout time.Duration
+
+	// RateLimit enables client side rate limiting of the operations dispatched to each node.
+	// Volatile: This API is subject to change at any time.
+	RateLimit KVRateLimitConfig
+
+	// NotMyVbucketPolicy specifies how requests which fail with NotMyVbucket are retried, which can be tuned for
+	// workloads running during heavy rebalances.
+	// Volatile: This API is subject to change at any time.
+	NotMyVbucketPolicy NotMyVbucketPolicy
+	// NotMyVbucketRetryDelay is the delay used by NotMyVbucketPolicyFixedDelay, and the initial delay used by
+	// NotMyVbucketPolicyExponentialBackoff, defaults to 10ms.
+	// Volatile: This API is subject to change at any time.
+	NotMyVbucketRetryDelay time.Duration
+}
+
+// KVRateLimitConfig specifies limits on the rate at which operations are dispatched to each KV node, allowing bulk
+// workloads to be throttled before they trip server side rate limits. Operations which would exceed the limits fail
+// with ErrRateLimitedLocally and are handed to the retry strategy, which is told how long until the operation is
+// expected to fit within the limits. The best effort retry strategy waits for that long before retrying.
+// Limits are disabled when both OpsPerSecond and MaxInFlightBytes are 0.
+// Volatile: This API is subject to change at any time.
+type KVRateLimitConfig struct {
+	// OpsPerSecond is the maximum number of operations per second to dispatch to each node, 0 means unlimited.
+	OpsPerSecond int
+	// Burst is the number of operations which may be dispatched to a node at once, above the steady rate.
+	// Defaults to a tenth of OpsPerSecond.
+	Burst int
+	// MaxInFlightBytes is the maximum number of bytes of requests which may be awaiting a response from each
+	// node, 0 means unlimited.
+	MaxInFlightBytes int
 }
 
 func (config KVConfig) fromSpec(spec connstr.ResolvedConnSpec) (KVConfig, error) {
@@ -432,6 +739,157 @@ func (config KVConfig) fromSpec(spec connstr.ResolvedConnSpec) (KVConfig, error)
 		config.ServerWaitBackoff = time.Duration(val) * time.Millisecond
 	}
 
+	// This option is experimental
+	if valStr, ok := fetchOption(spec, "kv_checksums"); ok {
+		val, err := strconv.ParseBool(valStr)
+		if err != nil {
+			return KVConfig{}, fmt.Errorf("kv_checksums option must be a boolean")
+		}
+		config.EnableChecksums = val
+	}
+
+	// This option is experimental
+	if valStr, ok := fetchOption(spec, "kv_bucket_warmup_retry_window"); ok {
+		val, err := parseDurationOrInt(valStr)
+		if err != nil {
+			return KVConfig{}, fmt.Errorf("kv_bucket_warmup_retry_window option must be a duration or a number")
+		}
+		config.BucketWarmupRetryWindow = val
+	}
+
+	// This option is experimental
+	if valStr, ok := fetchOption(spec, "kv_write_flush_interval"); ok {
+		val, err := parseDurationOrInt(valStr)
+		if err != nil {
+			return KVConfig{}, fmt.Errorf("kv_write_flush_interval option must be a duration or a number")
+		}
+		config.WriteFlushInterval = val
+	}
+
+	// This option is experimental
+	if valStr, ok := fetchOption(spec, "kv_block_on_full_queue"); ok {
+		val, err := strconv.ParseBool(valStr)
+		if err != nil {
+			return KVConfig{}, fmt.Errorf("kv_block_on_full_queue option must be a boolean")
+		}
+		config.BlockOnFullQueue = val
+	}
+
+	// This option is experimental
+	if valStr, ok := fetchOption(spec, "kv_block_on_full_queue_max_wait"); ok {
+		val, err := parseDurationOrInt(valStr)
+		if err != nil {
+			return KVConfig{}, fmt.Errorf("kv_block_on_full_queue_max_wait option must be a duration or a number")
+		}
+		config.BlockOnFullQueueMaxWait = val
+	}
+
+	// This option is experimental
+	if valStr, ok := fetchOption(spec, "kv_read_only"); ok {
+		val, err := strconv.ParseBool(valStr)
+		if err != nil {
+			return KVConfig{}, fmt.Errorf("kv_read_only option must be a boolean")
+		}
+		config.ReadOnly = val
+	}
+
+	// This option is experimental
+	if valStr, ok := fetchOption(spec, "kv_max_pool_size"); ok {
+		val, err := strconv.ParseInt(valStr, 10, 64)
+		if err != nil {
+			return KVConfig{}, fmt.Errorf("kv_max_pool_size option must be a number")
+		}
+		config.MaxPoolSize = int(val)
+	}
+
+	// This option is experimental
+	if valStr, ok := fetchOption(spec, "kv_pool_grow_queue_latency"); ok {
+		val, err := parseDurationOrInt(valStr)
+		if err != nil {
+			return KVConfig{}, fmt.Errorf("kv_pool_grow_queue_latency option must be a duration or a number")
+		}
+		config.PoolGrowQueueLatency = val
+	}
+
+	// This option is experimental
+	if valStr, ok := fetchOption(spec, "kv_pool_idle_timeout"); ok {
+		val, err := parseDurationOrInt(valStr)
+		if err != nil {
+			return KVConfig{}, fmt.Errorf("kv_pool_idle_timeout option must be a duration or a number")
+		}
+		config.PoolIdleTimeout = val
+	}
+
+	// This option is experimental
+	if valStr, ok := fetchOption(spec, "kv_graceful_close_timeout"); ok {
+		val, err := parseDurationOrInt(valStr)
+		if err != nil {
+			return KVConfig{}, fmt.Errorf("kv_graceful_close_timeout option must be a duration or a number")
+		}
+		config.GracefulCloseTimeout = val
+	}
+
+	// This option is experimental
+	if valStr, ok := fetchOption(spec, "kv_max_write_batch_size"); ok {
+		val, err := strconv.ParseInt(valStr, 10, 64)
+		if err != nil {
+			return KVConfig{}, fmt.Errorf("kv_max_write_batch_size option must be a number")
+		}
+		config.MaxWriteBatchSize = int(val)
+	}
+
+	// This option is experimental
+	if valStr, ok := fetchOption(spec, "kv_rate_limit_ops_per_second"); ok {
+		val, err := strconv.ParseInt(valStr, 10, 64)
+		if err != nil {
+			return KVConfig{}, fmt.Errorf("kv_rate_limit_ops_per_second option must be a number")
+		}
+		config.RateLimit.OpsPerSecond = int(val)
+	}
+
+	// This option is experimental
+	if valStr, ok := fetchOption(spec, "kv_rate_limit_burst"); ok {
+		val, err := strconv.ParseInt(valStr, 10, 64)
+		if err != nil {
+			return KVConfig{}, fmt.Errorf("kv_rate_limit_burst option must be a number")
+		}
+		config.RateLimit.Burst = int(val)
+	}
+
+	// This option is experimental
+	if valStr, ok := fetchOption(spec, "kv_rate_limit_max_in_flight_bytes"); ok {
+		val, err := strconv.ParseInt(valStr, 10, 64)
+		if err != nil {
+			return KVConfig{}, fmt.Errorf("kv_rate_limit_max_in_flight_bytes option must be a number")
+		}
+		config.RateLimit.MaxInFlightBytes = int(val)
+	}
+
+	// This option is experimental
+	if valStr, ok := fetchOption(spec, "kv_not_my_vbucket_policy"); ok {
+		switch valStr {
+		case "default":
+			config.NotMyVbucketPolicy = NotMyVbucketPolicyDefault
+		case "immediate":
+			config.NotMyVbucketPolicy = NotMyVbucketPolicyImmediate
+		case "fixed":
+			config.NotMyVbucketPolicy = NotMyVbucketPolicyFixedDelay
+		case "exponential":
+			config.NotMyVbucketPolicy = NotMyVbucketPolicyExponentialBackoff
+		default:
+			return KVConfig{}, fmt.Errorf("kv_not_my_vbucket_policy option must be default, immediate, fixed or exponential")
+		}
+	}
+
+	// This option is experimental
+	if valStr, ok := fetchOption(spec, "kv_not_my_vbucket_retry_delay"); ok {
+		val, err := parseDurationOrInt(valStr)
+		if err != nil {
+			return KVConfig{}, fmt.Errorf("kv_not_my_vbucket_retry_delay option must be a duration or a number")
+		}
+		config.NotMyVbucketRetryDelay = val
+	}
+
 	return config, nil
 }
 
@@ -565,6 +1023,85 @@ func (config *AgentConfig) redacted() interface{} {
 	return newConfig
 }
 
+// Validate checks the configuration for missing or contradictory settings without performing any network activity.
+// If any problems are found then a ConfigValidationError is returned which lists all of them, rather than only the
+// first.
+// Volatile: This API is subject to change at any time.
+func (config *AgentConfig) Validate() error {
+	var problems []string
+	addProblem := func(format string, v ...interface{}) {
+		problems = append(problems, fmt.Sprintf(format, v...))
+	}
+
+	if len(config.SeedConfig.MemdAddrs) == 0 && len(config.SeedConfig.HTTPAddrs) == 0 &&
+		config.SeedConfig.SRVRecord == nil {
+		addProblem("SeedConfig must contain at least one memd address, http address or SRV record")
+	}
+
+	if config.SecurityConfig.Auth == nil {
+		addProblem("SecurityConfig.Auth must be set")
+	} else if config.SecurityConfig.UseTLS && !config.SecurityConfig.Auth.SupportsTLS() {
+		addProblem("SecurityConfig.UseTLS is set but SecurityConfig.Auth does not support TLS connections")
+	} else if !config.SecurityConfig.UseTLS && !config.SecurityConfig.Auth.SupportsNonTLS() {
+		addProblem("SecurityConfig.UseTLS is not set but SecurityConfig.Auth does not support non-TLS connections")
+	}
+
+	if !config.SecurityConfig.UseTLS && config.SecurityConfig.TLSRootCAProvider != nil {
+		addProblem("SecurityConfig.TLSRootCAProvider is set but SecurityConfig.UseTLS is not")
+	}
+	if !config.SecurityConfig.UseTLS && config.SecurityConfig.NoTLSSeedNode {
+		addProblem("SecurityConfig.NoTLSSeedNode is set but SecurityConfig.UseTLS is not")
+	}
+	if !config.SecurityConfig.UseTLS && len(config.SecurityConfig.AuthMechanisms) > 0 {
+		plainOnly := true
+		for _, mech := range config.SecurityConfig.AuthMechanisms {
+			if mech != PlainAuthMechanism {
+				plainOnly = false
+				break
+			}
+		}
+		if plainOnly {
+			addProblem("SecurityConfig.AuthMechanisms only allows PLAIN, which sends credentials in cleartext, but " +
+				"SecurityConfig.UseTLS is not set")
+		}
+	}
+
+	if config.CompressionConfig.MinSize < 0 {
+		addProblem("CompressionConfig.MinSize cannot be negative, got %d", config.CompressionConfig.MinSize)
+	}
+	if config.CompressionConfig.MinRatio < 0 || config.CompressionConfig.MinRatio > 1 {
+		addProblem("CompressionConfig.MinRatio must be between 0 and 1, got %v", config.CompressionConfig.MinRatio)
+	}
+
+	if config.KVConfig.PoolSize < 0 {
+		addProblem("KVConfig.PoolSize cannot be negative, got %d", config.KVConfig.PoolSize)
+	}
+	if config.KVConfig.MaxQueueSize < 0 {
+		addProblem("KVConfig.MaxQueueSize cannot be negative, got %d", config.KVConfig.MaxQueueSize)
+	}
+	if config.KVConfig.ConnectTimeout < 0 {
+		addProblem("KVConfig.ConnectTimeout cannot be negative, got %s", config.KVConfig.ConnectTimeout)
+	}
+	if config.HTTPConfig.MaxIdleConns < 0 {
+		addProblem("HTTPConfig.MaxIdleConns cannot be negative, got %d", config.HTTPConfig.MaxIdleConns)
+	}
+	if config.HTTPConfig.MaxIdleConnsPerHost < 0 {
+		addProblem("HTTPConfig.MaxIdleConnsPerHost cannot be negative, got %d", config.HTTPConfig.MaxIdleConnsPerHost)
+	}
+	if config.HTTPConfig.MaxConnsPerHost < 0 {
+		addProblem("HTTPConfig.MaxConnsPerHost cannot be negative, got %d", config.HTTPConfig.MaxConnsPerHost)
+	}
+
+	if len(problems) > 0 {
+		return ConfigValidationError{
+			InnerError: errInvalidArgument,
+			Problems:   problems,
+		}
+	}
+
+	return nil
+}
+
 func fetchOption(spec connstr.ResolvedConnSpec, name string) (string, bool) {
 	optValue := spec.Options[name]
 	if len(optValue) == 0 {
@@ -591,6 +1128,11 @@ func fetchOption(spec connstr.ResolvedConnSpec, name string) (string, bool) {
 //	max_idle_http_connections (int) - Maximum number of idle HTTP connections in the pool.
 //	max_perhost_idle_http_connections (int) - Maximum number of idle HTTP connections in the pool per host.
 //	idle_http_connection_timeout (duration) - Maximum length of time for an idle connection to stay in the pool in ms.
+//	http_endpoint_selection (string) - How query, search and analytics endpoints are chosen (random, fastest).
+//	require_cert_auth (bool) - Whether KV connections must authenticate using only the client certificate.
+//	tls_min_version (string) - The minimum TLS version that connections can use (1.2, 1.3).
+//	tls_max_version (string) - The maximum TLS version that connections can use (1.2, 1.3).
+//	tls_cipher_suites (string) - Comma separated list of cipher suite names that TLS 1.2 connections can use.
 //	orphaned_response_logging (bool) - Whether to enable orphaned response logging.
 //	orphaned_response_logging_interval (duration) - How often to print the orphan log records.
 //	orphaned_response_logging_sample_size (int) - The maximum number of orphan log records to track.
@@ -599,9 +1141,21 @@ func fetchOption(spec connstr.ResolvedConnSpec, name string) (string, bool) {
 //	http_redial_period (duration) - The maximum length of time for the HTTP poller to stay connected before reconnecting.
 //	http_retry_delay (duration) - The length of time to wait between HTTP poller retries if connecting fails.
 //	kv_pool_size (int) - The number of connections to create to each KV node.
+//	kv_block_on_full_queue (bool) - Whether dispatching blocks, rather than fails, when a KV node's queue is full.
+//	kv_block_on_full_queue_max_wait (duration) - The longest that dispatching blocks for when a queue is full.
+//	kv_read_only (bool) - Whether the agent starts in read-only mode, rejecting all mutations.
+//	kv_max_pool_size (int) - The number of connections the pool to each KV node can grow to.
+//	kv_pool_grow_queue_latency (duration) - How long requests must wait in the queue before the pool grows.
+//	kv_pool_idle_timeout (duration) - How long a connection above kv_pool_size can be unused before it is closed.
 //	max_queue_size (int) - The maximum number of requests that can be queued for sending per connection.
 //	unordered_execution_enabled (bool) - Whether to enable the "out of order responses" feature.
 //	server_wait_backoff (duration) -The period of time waited between kv reconnect attmepts to a node after connection failure
+//	kv_graceful_close_timeout (duration) - How long in-flight requests to a removed KV node are given to complete.
+//	kv_rate_limit_ops_per_second (int) - The maximum number of operations per second to dispatch to each KV node.
+//	kv_rate_limit_burst (int) - The number of operations which may be dispatched to a KV node at once.
+//	kv_rate_limit_max_in_flight_bytes (int) - The maximum number of bytes of requests in flight to each KV node.
+//	kv_not_my_vbucket_policy (string) - How NotMyVbucket failures are retried (default, immediate, fixed, exponential).
+//	kv_not_my_vbucket_retry_delay (duration) - The delay used by the fixed and exponential NotMyVbucket policies.
 func (config *AgentConfig) FromConnStr(connStr string) error {
 	baseSpec, err := connstr.Parse(connStr)
 	if err != nil {
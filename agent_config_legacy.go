@@ -0,0 +1,211 @@
+package gocbcore
+
+import (
+	"crypto/x509"
+	"fmt"
+	"time"
+)
+
+// LegacyAgentConfig is the flat configuration layout used by AgentConfig prior to its options being split into
+// grouped configuration structs. It exists to allow code written against the old layout to be migrated
+// incrementally, ToAgentConfig converts it to the current layout and logs a warning for each field which has moved.
+// Deprecated: Use AgentConfig.
+type LegacyAgentConfig struct {
+	MemdAddrs []string
+	// HttpAddrs is the old, incorrectly cased, name for HTTPAddrs. If both are set then they must be equal.
+	HttpAddrs  []string // nolint: revive,stylecheck
+	HTTPAddrs  []string
+	BucketName string
+	UserAgent  string
+
+	// Username and Password are used to create a PasswordAuthProvider when Auth is not set.
+	Username string
+	Password string
+
+	UseTLS            bool
+	TLSRootCAProvider func() *x509.CertPool
+	NetworkType       string
+	Auth              AuthProvider
+	AuthMechanisms    []AuthMechanism
+
+	UseCompression       bool
+	DisableDecompression bool
+	CompressionMinSize   int
+	CompressionMinRatio  float64
+
+	UseMutationTokens      bool
+	UseDurations           bool
+	UseOutOfOrderResponses bool
+	UseCollections         bool
+
+	HTTPRedialPeriod time.Duration
+	HTTPRetryDelay   time.Duration
+	CccpMaxWait      time.Duration
+	CccpPollPeriod   time.Duration
+
+	KVConnectTimeout time.Duration
+	KvPoolSize       int
+	MaxQueueSize     int
+
+	HTTPMaxIdleConns          int
+	HTTPMaxIdleConnsPerHost   int
+	HTTPIdleConnectionTimeout time.Duration
+
+	Tracer           RequestTracer
+	NoRootTraceSpans bool
+
+	DefaultRetryStrategy RetryStrategy
+	CircuitBreakerConfig CircuitBreakerConfig
+
+	UseZombieLogger        bool
+	ZombieLoggerInterval   time.Duration
+	ZombieLoggerSampleSize int
+}
+
+// ToAgentConfig converts the legacy configuration into an AgentConfig. A warning is logged for every field which is
+// set, naming the field in AgentConfig which replaces it.
+// Deprecated: Use AgentConfig directly.
+func (config LegacyAgentConfig) ToAgentConfig() (*AgentConfig, error) {
+	agentConfig, warnings, err := config.migrate()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, warning := range warnings {
+		logWarnf("%s", warning)
+	}
+
+	return agentConfig, nil
+}
+
+// migrate performs the conversion for ToAgentConfig, returning the warnings rather than logging them.
+func (config LegacyAgentConfig) migrate() (*AgentConfig, []string, error) {
+	var warnings []string
+	moved := func(isSet bool, oldName, newName string) {
+		if isSet {
+			warnings = append(warnings, fmt.Sprintf("LegacyAgentConfig.%s is deprecated, use AgentConfig.%s instead",
+				oldName, newName))
+		}
+	}
+
+	httpAddrs := config.HTTPAddrs
+	if len(config.HttpAddrs) > 0 {
+		if len(httpAddrs) > 0 && !stringSlicesEqual(httpAddrs, config.HttpAddrs) {
+			return nil, nil, wrapError(errInvalidArgument, "HttpAddrs and HTTPAddrs cannot both be set to different values")
+		}
+		httpAddrs = config.HttpAddrs
+	}
+
+	auth := config.Auth
+	if config.Username != "" || config.Password != "" {
+		if auth != nil {
+			return nil, nil, wrapError(errInvalidArgument, "Username and Password cannot be used with Auth")
+		}
+		auth = PasswordAuthProvider{
+			Username: config.Username,
+			Password: config.Password,
+		}
+	}
+
+	moved(len(config.MemdAddrs) > 0, "MemdAddrs", "SeedConfig.MemdAddrs")
+	moved(len(config.HttpAddrs) > 0, "HttpAddrs", "SeedConfig.HTTPAddrs")
+	moved(len(config.HTTPAddrs) > 0, "HTTPAddrs", "SeedConfig.HTTPAddrs")
+	moved(config.Username != "", "Username", "SecurityConfig.Auth")
+	moved(config.Password != "", "Password", "SecurityConfig.Auth")
+	moved(config.UseTLS, "UseTLS", "SecurityConfig.UseTLS")
+	moved(config.TLSRootCAProvider != nil, "TLSRootCAProvider", "SecurityConfig.TLSRootCAProvider")
+	moved(config.NetworkType != "", "NetworkType", "IoConfig.NetworkType")
+	moved(config.Auth != nil, "Auth", "SecurityConfig.Auth")
+	moved(len(config.AuthMechanisms) > 0, "AuthMechanisms", "SecurityConfig.AuthMechanisms")
+	moved(config.UseCompression, "UseCompression", "CompressionConfig.Enabled")
+	moved(config.DisableDecompression, "DisableDecompression", "CompressionConfig.DisableDecompression")
+	moved(config.CompressionMinSize != 0, "CompressionMinSize", "CompressionConfig.MinSize")
+	moved(config.CompressionMinRatio != 0, "CompressionMinRatio", "CompressionConfig.MinRatio")
+	moved(config.UseMutationTokens, "UseMutationTokens", "IoConfig.UseMutationTokens")
+	moved(config.UseDurations, "UseDurations", "IoConfig.UseDurations")
+	moved(config.UseOutOfOrderResponses, "UseOutOfOrderResponses", "IoConfig.UseOutOfOrderResponses")
+	moved(config.UseCollections, "UseCollections", "IoConfig.UseCollections")
+	moved(config.HTTPRedialPeriod != 0, "HTTPRedialPeriod", "ConfigPollerConfig.HTTPRedialPeriod")
+	moved(config.HTTPRetryDelay != 0, "HTTPRetryDelay", "ConfigPollerConfig.HTTPRetryDelay")
+	moved(config.CccpMaxWait != 0, "CccpMaxWait", "ConfigPollerConfig.CccpMaxWait")
+	moved(config.CccpPollPeriod != 0, "CccpPollPeriod", "ConfigPollerConfig.CccpPollPeriod")
+	moved(config.KVConnectTimeout != 0, "KVConnectTimeout", "KVConfig.ConnectTimeout")
+	moved(config.KvPoolSize != 0, "KvPoolSize", "KVConfig.PoolSize")
+	moved(config.MaxQueueSize != 0, "MaxQueueSize", "KVConfig.MaxQueueSize")
+	moved(config.HTTPMaxIdleConns != 0, "HTTPMaxIdleConns", "HTTPConfig.MaxIdleConns")
+	moved(config.HTTPMaxIdleConnsPerHost != 0, "HTTPMaxIdleConnsPerHost", "HTTPConfig.MaxIdleConnsPerHost")
+	moved(config.HTTPIdleConnectionTimeout != 0, "HTTPIdleConnectionTimeout", "HTTPConfig.IdleConnectionTimeout")
+	moved(config.Tracer != nil, "Tracer", "TracerConfig.Tracer")
+	moved(config.NoRootTraceSpans, "NoRootTraceSpans", "TracerConfig.NoRootTraceSpans")
+	moved(config.UseZombieLogger, "UseZombieLogger", "OrphanReporterConfig.Enabled")
+	moved(config.ZombieLoggerInterval != 0, "ZombieLoggerInterval", "OrphanReporterConfig.ReportInterval")
+	moved(config.ZombieLoggerSampleSize != 0, "ZombieLoggerSampleSize", "OrphanReporterConfig.SampleSize")
+
+	return &AgentConfig{
+		BucketName: config.BucketName,
+		UserAgent:  config.UserAgent,
+		SeedConfig: SeedConfig{
+			HTTPAddrs: httpAddrs,
+			MemdAddrs: config.MemdAddrs,
+		},
+		SecurityConfig: SecurityConfig{
+			UseTLS:            config.UseTLS,
+			TLSRootCAProvider: config.TLSRootCAProvider,
+			Auth:              auth,
+			AuthMechanisms:    config.AuthMechanisms,
+		},
+		CompressionConfig: CompressionConfig{
+			Enabled:              config.UseCompression,
+			DisableDecompression: config.DisableDecompression,
+			MinSize:              config.CompressionMinSize,
+			MinRatio:             config.CompressionMinRatio,
+		},
+		ConfigPollerConfig: ConfigPollerConfig{
+			HTTPRedialPeriod: config.HTTPRedialPeriod,
+			HTTPRetryDelay:   config.HTTPRetryDelay,
+			CccpMaxWait:      config.CccpMaxWait,
+			CccpPollPeriod:   config.CccpPollPeriod,
+		},
+		IoConfig: IoConfig{
+			NetworkType:            config.NetworkType,
+			UseMutationTokens:      config.UseMutationTokens,
+			UseDurations:           config.UseDurations,
+			UseOutOfOrderResponses: config.UseOutOfOrderResponses,
+			UseCollections:         config.UseCollections,
+		},
+		KVConfig: KVConfig{
+			ConnectTimeout: config.KVConnectTimeout,
+			PoolSize:       config.KvPoolSize,
+			MaxQueueSize:   config.MaxQueueSize,
+		},
+		HTTPConfig: HTTPConfig{
+			MaxIdleConns:          config.HTTPMaxIdleConns,
+			MaxIdleConnsPerHost:   config.HTTPMaxIdleConnsPerHost,
+			IdleConnectionTimeout: config.HTTPIdleConnectionTimeout,
+		},
+		DefaultRetryStrategy: config.DefaultRetryStrategy,
+		CircuitBreakerConfig: config.CircuitBreakerConfig,
+		OrphanReporterConfig: OrphanReporterConfig{
+			Enabled:        config.UseZombieLogger,
+			ReportInterval: config.ZombieLoggerInterval,
+			SampleSize:     config.ZombieLoggerSampleSize,
+		},
+		TracerConfig: TracerConfig{
+			Tracer:           config.Tracer,
+			NoRootTraceSpans: config.NoRootTraceSpans,
+		},
+	}, warnings, nil
+}
+
+func stringSlicesEqual(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
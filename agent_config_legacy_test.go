@@ -0,0 +1,53 @@
+package gocbcore
+
+import (
+	"errors"
+	"time"
+)
+
+func (suite *UnitTestSuite) TestLegacyAgentConfigMigrate() {
+	config, warnings, err := LegacyAgentConfig{
+		BucketName:       "default",
+		HttpAddrs:        []string{"10.0.0.1:8091"},
+		MemdAddrs:        []string{"10.0.0.1:11210"},
+		Username:         "Administrator",
+		Password:         "password",
+		UseCompression:   true,
+		KVConnectTimeout: 5 * time.Second,
+		KvPoolSize:       2,
+	}.migrate()
+	suite.Require().Nil(err, err)
+
+	suite.Assert().Equal("default", config.BucketName)
+	suite.Assert().Equal([]string{"10.0.0.1:8091"}, config.SeedConfig.HTTPAddrs)
+	suite.Assert().Equal([]string{"10.0.0.1:11210"}, config.SeedConfig.MemdAddrs)
+	suite.Assert().Equal(PasswordAuthProvider{Username: "Administrator", Password: "password"},
+		config.SecurityConfig.Auth)
+	suite.Assert().True(config.CompressionConfig.Enabled)
+	suite.Assert().Equal(5*time.Second, config.KVConfig.ConnectTimeout)
+	suite.Assert().Equal(2, config.KVConfig.PoolSize)
+
+	suite.Assert().Equal([]string{
+		"LegacyAgentConfig.MemdAddrs is deprecated, use AgentConfig.SeedConfig.MemdAddrs instead",
+		"LegacyAgentConfig.HttpAddrs is deprecated, use AgentConfig.SeedConfig.HTTPAddrs instead",
+		"LegacyAgentConfig.Username is deprecated, use AgentConfig.SecurityConfig.Auth instead",
+		"LegacyAgentConfig.Password is deprecated, use AgentConfig.SecurityConfig.Auth instead",
+		"LegacyAgentConfig.UseCompression is deprecated, use AgentConfig.CompressionConfig.Enabled instead",
+		"LegacyAgentConfig.KVConnectTimeout is deprecated, use AgentConfig.KVConfig.ConnectTimeout instead",
+		"LegacyAgentConfig.KvPoolSize is deprecated, use AgentConfig.KVConfig.PoolSize instead",
+	}, warnings)
+}
+
+func (suite *UnitTestSuite) TestLegacyAgentConfigMigrateConflicts() {
+	_, _, err := LegacyAgentConfig{
+		HttpAddrs: []string{"10.0.0.1:8091"},
+		HTTPAddrs: []string{"10.0.0.2:8091"},
+	}.migrate()
+	suite.Assert().True(errors.Is(err, ErrInvalidArgument), err)
+
+	_, _, err = LegacyAgentConfig{
+		Username: "Administrator",
+		Auth:     PasswordAuthProvider{Username: "Administrator"},
+	}.migrate()
+	suite.Assert().True(errors.Is(err, ErrInvalidArgument), err)
+}
@@ -1,6 +1,9 @@
 package gocbcore
 
 import (
+	"bytes"
+	"crypto/tls"
+	"errors"
 	"testing"
 	"time"
 )
@@ -881,6 +884,231 @@ func (suite *StandardTestSuite) TestAgentConfig_MaxQueueSize() {
 	}
 }
 
+func (suite *StandardTestSuite) TestAgentConfig_WriteCoalescing() {
+	tests := []struct {
+		name             string
+		connStr          string
+		expectedInterval time.Duration
+		expectedSize     int
+		wantErr          bool
+	}{
+		{
+			name:             "valid",
+			connStr:          "couchbase://10.112.192.101?kv_write_flush_interval=50us&kv_max_write_batch_size=1024",
+			expectedInterval: 50 * time.Microsecond,
+			expectedSize:     1024,
+		},
+		{
+			name:    "invalid interval",
+			connStr: "couchbase://10.112.192.101?kv_write_flush_interval=squirrel",
+			wantErr: true,
+		},
+		{
+			name:    "invalid size",
+			connStr: "couchbase://10.112.192.101?kv_max_write_batch_size=squirrel",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		suite.T().Run(tt.name, func(t *testing.T) {
+			config := &AgentConfig{}
+			if err := config.FromConnStr(tt.connStr); (err != nil) != tt.wantErr {
+				t.Errorf("FromConnStr() error = %v, wanted error = %t", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if config.KVConfig.WriteFlushInterval != tt.expectedInterval {
+				suite.T().Fatalf("Expected %s but was %s", tt.expectedInterval, config.KVConfig.WriteFlushInterval)
+			}
+			if config.KVConfig.MaxWriteBatchSize != tt.expectedSize {
+				suite.T().Fatalf("Expected %d but was %d", tt.expectedSize, config.KVConfig.MaxWriteBatchSize)
+			}
+		})
+	}
+}
+
+func (suite *StandardTestSuite) TestAgentConfig_DynamicPoolSizing() {
+	tests := []struct {
+		name                string
+		connStr             string
+		expectedMaxPoolSize int
+		expectedGrowLatency time.Duration
+		expectedIdleTimeout time.Duration
+		wantErr             bool
+	}{
+		{
+			name:                "valid",
+			connStr:             "couchbase://10.112.192.101?kv_max_pool_size=4&kv_pool_grow_queue_latency=2ms&kv_pool_idle_timeout=30s",
+			expectedMaxPoolSize: 4,
+			expectedGrowLatency: 2 * time.Millisecond,
+			expectedIdleTimeout: 30 * time.Second,
+		},
+		{
+			name:    "invalid max pool size",
+			connStr: "couchbase://10.112.192.101?kv_max_pool_size=squirrel",
+			wantErr: true,
+		},
+		{
+			name:    "invalid grow latency",
+			connStr: "couchbase://10.112.192.101?kv_pool_grow_queue_latency=squirrel",
+			wantErr: true,
+		},
+		{
+			name:    "invalid idle timeout",
+			connStr: "couchbase://10.112.192.101?kv_pool_idle_timeout=squirrel",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		suite.T().Run(tt.name, func(t *testing.T) {
+			config := &AgentConfig{}
+			if err := config.FromConnStr(tt.connStr); (err != nil) != tt.wantErr {
+				t.Errorf("FromConnStr() error = %v, wanted error = %t", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if config.KVConfig.MaxPoolSize != tt.expectedMaxPoolSize {
+				suite.T().Fatalf("Expected %d but was %d", tt.expectedMaxPoolSize, config.KVConfig.MaxPoolSize)
+			}
+			if config.KVConfig.PoolGrowQueueLatency != tt.expectedGrowLatency {
+				suite.T().Fatalf("Expected %s but was %s", tt.expectedGrowLatency, config.KVConfig.PoolGrowQueueLatency)
+			}
+			if config.KVConfig.PoolIdleTimeout != tt.expectedIdleTimeout {
+				suite.T().Fatalf("Expected %s but was %s", tt.expectedIdleTimeout, config.KVConfig.PoolIdleTimeout)
+			}
+		})
+	}
+}
+
+func (suite *StandardTestSuite) TestAgentConfig_GracefulCloseTimeout() {
+	tests := []struct {
+		name     string
+		connStr  string
+		expected time.Duration
+		wantErr  bool
+	}{
+		{
+			name:     "duration",
+			connStr:  "couchbase://10.112.192.101?kv_graceful_close_timeout=5s",
+			expected: 5 * time.Second,
+		},
+		{
+			name:     "milliseconds",
+			connStr:  "couchbase://10.112.192.101?kv_graceful_close_timeout=2500",
+			expected: 2500 * time.Millisecond,
+		},
+		{
+			name:    "invalid",
+			connStr: "couchbase://10.112.192.101?kv_graceful_close_timeout=squirrel",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		suite.T().Run(tt.name, func(t *testing.T) {
+			config := &AgentConfig{}
+			if err := config.FromConnStr(tt.connStr); (err != nil) != tt.wantErr {
+				t.Errorf("FromConnStr() error = %v, wanted error = %t", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if config.KVConfig.GracefulCloseTimeout != tt.expected {
+				suite.T().Fatalf("Expected %s but was %s", tt.expected, config.KVConfig.GracefulCloseTimeout)
+			}
+		})
+	}
+}
+
+func (suite *StandardTestSuite) TestAgentConfig_ReadOnly() {
+	tests := []struct {
+		name     string
+		connStr  string
+		expected bool
+		wantErr  bool
+	}{
+		{
+			name:     "true",
+			connStr:  "couchbase://10.112.192.101?kv_read_only=true",
+			expected: true,
+		},
+		{
+			name:     "false",
+			connStr:  "couchbase://10.112.192.101?kv_read_only=false",
+			expected: false,
+		},
+		{
+			name:    "invalid",
+			connStr: "couchbase://10.112.192.101?kv_read_only=squirrel",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		suite.T().Run(tt.name, func(t *testing.T) {
+			config := &AgentConfig{}
+			if err := config.FromConnStr(tt.connStr); (err != nil) != tt.wantErr {
+				t.Errorf("FromConnStr() error = %v, wanted error = %t", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if config.KVConfig.ReadOnly != tt.expected {
+				suite.T().Fatalf("Expected %t but was %t", tt.expected, config.KVConfig.ReadOnly)
+			}
+		})
+	}
+}
+
+func (suite *StandardTestSuite) TestAgentConfig_HTTPEndpointSelection() {
+	tests := []struct {
+		name     string
+		connStr  string
+		expected HTTPEndpointSelectionPolicy
+		wantErr  bool
+	}{
+		{
+			name:     "random",
+			connStr:  "couchbase://10.112.192.101?http_endpoint_selection=random",
+			expected: HTTPEndpointSelectionRandom,
+		},
+		{
+			name:     "fastest",
+			connStr:  "couchbase://10.112.192.101?http_endpoint_selection=fastest",
+			expected: HTTPEndpointSelectionFastest,
+		},
+		{
+			name:    "invalid",
+			connStr: "couchbase://10.112.192.101?http_endpoint_selection=squirrel",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		suite.T().Run(tt.name, func(t *testing.T) {
+			config := &AgentConfig{}
+			if err := config.FromConnStr(tt.connStr); (err != nil) != tt.wantErr {
+				t.Errorf("FromConnStr() error = %v, wanted error = %t", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if config.HTTPConfig.EndpointSelectionPolicy != tt.expected {
+				suite.T().Fatalf("Expected %d but was %d", tt.expected, config.HTTPConfig.EndpointSelectionPolicy)
+			}
+		})
+	}
+}
+
 func (suite *StandardTestSuite) TestAgentConfig_UseClusterMapNotifications() {
 	tests := []struct {
 		name     string
@@ -921,3 +1149,123 @@ func (suite *StandardTestSuite) TestAgentConfig_UseClusterMapNotifications() {
 		})
 	}
 }
+
+func (suite *UnitTestSuite) TestAgentConfigValidate() {
+	config := &AgentConfig{
+		SeedConfig: SeedConfig{
+			MemdAddrs: []string{"10.112.192.101:11210"},
+		},
+		SecurityConfig: SecurityConfig{
+			Auth: PasswordAuthProvider{Username: "Administrator", Password: "password"},
+		},
+	}
+	suite.Assert().Nil(config.Validate())
+
+	config = &AgentConfig{
+		SecurityConfig: SecurityConfig{
+			Auth:           CertificateAuthenticator{},
+			AuthMechanisms: []AuthMechanism{PlainAuthMechanism},
+		},
+		CompressionConfig: CompressionConfig{
+			Enabled:  true,
+			MinRatio: 1.5,
+		},
+		KVConfig: KVConfig{
+			PoolSize: -1,
+		},
+		// net/http allows more idle connections per host than in total, so this is not a problem.
+		HTTPConfig: HTTPConfig{
+			MaxIdleConns:        10,
+			MaxIdleConnsPerHost: 20,
+		},
+	}
+	err := config.Validate()
+	suite.Require().True(errors.Is(err, ErrInvalidArgument), err)
+
+	var validationErr ConfigValidationError
+	suite.Require().True(errors.As(err, &validationErr))
+	suite.Assert().Equal([]string{
+		"SeedConfig must contain at least one memd address, http address or SRV record",
+		"SecurityConfig.UseTLS is not set but SecurityConfig.Auth does not support non-TLS connections",
+		"SecurityConfig.AuthMechanisms only allows PLAIN, which sends credentials in cleartext, but " +
+			"SecurityConfig.UseTLS is not set",
+		"CompressionConfig.MinRatio must be between 0 and 1, got 1.5",
+		"KVConfig.PoolSize cannot be negative, got -1",
+	}, validationErr.Problems)
+}
+
+func (suite *UnitTestSuite) TestSecurityConfigTLSBaseConfig() {
+	config := SecurityConfig{
+		TLSConfig: &tls.Config{
+			MinVersion: tls.VersionTLS13,
+		},
+		TLSCipherSuites:     []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256},
+		TLSCurvePreferences: []tls.CurveID{tls.X25519},
+	}
+
+	baseConfig, err := config.tlsBaseConfig()
+	suite.Require().Nil(err)
+	suite.Assert().Equal(uint16(tls.VersionTLS13), baseConfig.MinVersion)
+	suite.Assert().Equal([]uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256}, baseConfig.CipherSuites)
+	suite.Assert().Equal([]tls.CurveID{tls.X25519}, baseConfig.CurvePreferences)
+	suite.Assert().NotSame(config.TLSConfig, baseConfig)
+
+	dynConfig := createTLSConfig(PasswordAuthProvider{}, nil, baseConfig)
+	hostConfig, err := dynConfig.MakeForHost("localhost")
+	suite.Require().Nil(err)
+	suite.Assert().Equal([]tls.CurveID{tls.X25519}, hostConfig.CurvePreferences)
+	suite.Assert().NotNil(hostConfig.GetClientCertificate)
+	suite.Assert().Nil(baseConfig.GetClientCertificate)
+}
+
+func (suite *UnitTestSuite) TestSecurityConfigTLSKeyLogRequiresInsecureFlag() {
+	_, err := SecurityConfig{
+		TLSKeyLogWriter: &bytes.Buffer{},
+	}.tlsBaseConfig()
+	suite.Assert().ErrorIs(err, ErrInvalidArgument)
+
+	_, err = SecurityConfig{
+		TLSConfig: &tls.Config{
+			KeyLogWriter: &bytes.Buffer{},
+		},
+	}.tlsBaseConfig()
+	suite.Assert().ErrorIs(err, ErrInvalidArgument)
+}
+
+func (suite *UnitTestSuite) TestSecurityConfigTLSVersions() {
+	config := SecurityConfig{
+		TLSMinVersion: tls.VersionTLS13,
+		TLSMaxVersion: tls.VersionTLS13,
+	}
+
+	baseConfig, err := config.tlsBaseConfig()
+	suite.Require().Nil(err)
+
+	dynConfig := createTLSConfig(PasswordAuthProvider{}, nil, baseConfig)
+	hostConfig, err := dynConfig.MakeForHost("localhost")
+	suite.Require().Nil(err)
+	suite.Assert().Equal(uint16(tls.VersionTLS13), hostConfig.MinVersion)
+	suite.Assert().Equal(uint16(tls.VersionTLS13), hostConfig.MaxVersion)
+
+	_, err = SecurityConfig{
+		TLSMinVersion: tls.VersionTLS13,
+		TLSMaxVersion: tls.VersionTLS12,
+	}.tlsBaseConfig()
+	suite.Assert().ErrorIs(err, ErrInvalidArgument)
+}
+
+func (suite *UnitTestSuite) TestAgentConfigTLSConnStrOptions() {
+	config := AgentConfig{}
+	err := config.FromConnStr("couchbases://10.0.0.1?tls_min_version=1.3&tls_max_version=1.3&" +
+		"tls_cipher_suites=TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384")
+	suite.Require().Nil(err, err)
+	suite.Assert().Equal(uint16(tls.VersionTLS13), config.SecurityConfig.TLSMinVersion)
+	suite.Assert().Equal(uint16(tls.VersionTLS13), config.SecurityConfig.TLSMaxVersion)
+	suite.Assert().Equal([]uint16{
+		tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+		tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	}, config.SecurityConfig.TLSCipherSuites)
+
+	suite.Assert().NotNil((&AgentConfig{}).FromConnStr("couchbases://10.0.0.1?tls_min_version=1.0"))
+	suite.Assert().NotNil((&AgentConfig{}).FromConnStr("couchbases://10.0.0.1?tls_cipher_suites=TLS_RSA_WITH_RC4_128_SHA"))
+}
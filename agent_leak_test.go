@@ -0,0 +1,117 @@
+package gocbcore
+
+import (
+	"crypto/x509"
+	"fmt"
+	"time"
+
+	"github.com/couchbase/gocbcore/v10/memd"
+)
+
+func (suite *UnitTestSuite) TestAgentCloseDoesNotLeak() {
+	globalTestLogger.SuppressWarnings(true)
+	defer globalTestLogger.SuppressWarnings(false)
+
+	type tCase struct {
+		name      string
+		connStr   string
+		configure func(config *AgentConfig)
+	}
+
+	testCases := []tCase{
+		{
+			name:    "memd seed",
+			connStr: "couchbase://%s",
+		},
+		{
+			name:    "memd seed with pool",
+			connStr: "couchbase://%s?kv_pool_size=4",
+		},
+		{
+			name:    "write coalescing",
+			connStr: "couchbase://%s?kv_write_flush_interval=1ms",
+		},
+		{
+			name:    "tls",
+			connStr: "couchbases://%s",
+			configure: func(config *AgentConfig) {
+				config.SecurityConfig.TLSRootCAProvider = func() *x509.CertPool {
+					return nil
+				}
+			},
+		},
+		{
+			name:    "http seed pollers",
+			connStr: "http://%s",
+			configure: func(config *AgentConfig) {
+				config.ConfigPollerConfig.HTTPRedialPeriod = 10 * time.Millisecond
+				config.ConfigPollerConfig.HTTPRetryDelay = 10 * time.Millisecond
+			},
+		},
+		{
+			name:    "zombie logger",
+			connStr: "couchbase://%s",
+			configure: func(config *AgentConfig) {
+				config.OrphanReporterConfig = OrphanReporterConfig{
+					Enabled:        true,
+					ReportInterval: 10 * time.Millisecond,
+				}
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		suite.Run(tc.name, func() {
+			before := takeTestLeakSnapshot()
+
+			listener, err := newTestBlackholeListener()
+			suite.Require().Nil(err, err)
+
+			config := AgentConfig{}
+			err = config.FromConnStr(fmt.Sprintf(tc.connStr, listener.Addr()))
+			suite.Require().Nil(err, err)
+			config.BucketName = "default"
+			config.SecurityConfig.Auth = PasswordAuthProvider{Username: "user", Password: "pass"}
+			if tc.configure != nil {
+				tc.configure(&config)
+			}
+
+			agent, err := CreateAgent(&config)
+			suite.Require().Nil(err, err)
+
+			// Give the agent time to start dialling and polling before we shut it down.
+			time.Sleep(50 * time.Millisecond)
+
+			suite.Require().Nil(agent.Close())
+			listener.Close()
+
+			assertNoTestLeaks(suite.T(), before, 5*time.Second)
+		})
+	}
+}
+
+func (suite *UnitTestSuite) TestDCPAgentCloseDoesNotLeak() {
+	globalTestLogger.SuppressWarnings(true)
+	defer globalTestLogger.SuppressWarnings(false)
+
+	before := takeTestLeakSnapshot()
+
+	listener, err := newTestBlackholeListener()
+	suite.Require().Nil(err, err)
+
+	config := DCPAgentConfig{}
+	err = config.FromConnStr(fmt.Sprintf("couchbase://%s", listener.Addr()))
+	suite.Require().Nil(err, err)
+	config.BucketName = "default"
+	config.SecurityConfig.Auth = PasswordAuthProvider{Username: "user", Password: "pass"}
+
+	agent, err := CreateDcpAgent(&config, "leak-test", memd.DcpOpenFlagProducer)
+	suite.Require().Nil(err, err)
+
+	time.Sleep(50 * time.Millisecond)
+
+	suite.Require().Nil(agent.Close())
+	listener.Close()
+
+	assertNoTestLeaks(suite.T(), before, 5*time.Second)
+}
@@ -7,6 +7,10 @@ type GetCallback func(*GetResult, error)
 
 // Get retrieves a document.
 func (agent *Agent) Get(opts GetOptions, cb GetCallback) (PendingOp, error) {
+	cb = agent.mirror.WrapGet(opts, cb)
+	if agent.integrity != nil {
+		return agent.integrity.Get(opts, cb)
+	}
 	return agent.crud.Get(opts, cb)
 }
 
@@ -34,6 +38,17 @@ func (agent *Agent) GetOneReplica(opts GetOneReplicaOptions, cb GetReplicaCallba
 	return agent.crud.GetOneReplica(opts, cb)
 }
 
+// QuorumGetCallback is invoked upon completion of a QuorumGet operation.
+type QuorumGetCallback func(*QuorumGetResult, error)
+
+// QuorumGet retrieves a document from the active and all replica servers concurrently, completing once the
+// configured quorum of copies have been read with the same CAS. ErrDocumentUnretrievable is returned if the copies
+// diverge such that no CAS can reach the quorum.
+// Volatile: This API is subject to change at any time.
+func (agent *Agent) QuorumGet(opts QuorumGetOptions, cb QuorumGetCallback) (PendingOp, error) {
+	return agent.crud.QuorumGet(opts, cb)
+}
+
 // TouchCallback is invoked upon completion of a Touch operation.
 type TouchCallback func(*TouchResult, error)
 
@@ -55,6 +70,29 @@ type DeleteCallback func(*DeleteResult, error)
 
 // Delete removes a document.
 func (agent *Agent) Delete(opts DeleteOptions, cb DeleteCallback) (PendingOp, error) {
+	if opts.LegacyDurability {
+		var res *DeleteResult
+		odOpts := observeDurabilityOptionsFrom(opts.DurabilityLevel, opts.RetryStrategy, opts.Deadline, opts.User,
+			opts.TraceContext)
+		opts.DurabilityLevel = 0
+		opts.LegacyDurability = false
+		return agent.observeDurability.Mutate(odOpts, func(tokenCb func(MutationToken, error)) (PendingOp, error) {
+			return agent.Delete(opts, func(result *DeleteResult, err error) {
+				if err != nil {
+					tokenCb(MutationToken{}, err)
+					return
+				}
+				res = result
+				tokenCb(result.MutationToken, nil)
+			})
+		}, func(err error) {
+			if err != nil {
+				cb(nil, err)
+				return
+			}
+			cb(res, nil)
+		})
+	}
 	return agent.crud.Delete(opts, cb)
 }
 
@@ -63,19 +101,78 @@ type StoreCallback func(*StoreResult, error)
 
 // Add stores a document as long as it does not already exist.
 func (agent *Agent) Add(opts AddOptions, cb StoreCallback) (PendingOp, error) {
+	if opts.LegacyDurability {
+		odOpts := observeDurabilityOptionsFrom(opts.DurabilityLevel, opts.RetryStrategy, opts.Deadline, opts.User,
+			opts.TraceContext)
+		opts.DurabilityLevel = 0
+		opts.LegacyDurability = false
+		return agent.observeDurabilityStore(odOpts, func(storeCb StoreCallback) (PendingOp, error) {
+			return agent.Add(opts, storeCb)
+		}, cb)
+	}
+	if agent.integrity != nil {
+		return agent.integrity.Add(opts, cb)
+	}
 	return agent.crud.Add(opts, cb)
 }
 
 // Set stores a document.
 func (agent *Agent) Set(opts SetOptions, cb StoreCallback) (PendingOp, error) {
+	if opts.LegacyDurability {
+		odOpts := observeDurabilityOptionsFrom(opts.DurabilityLevel, opts.RetryStrategy, opts.Deadline, opts.User,
+			opts.TraceContext)
+		opts.DurabilityLevel = 0
+		opts.LegacyDurability = false
+		return agent.observeDurabilityStore(odOpts, func(storeCb StoreCallback) (PendingOp, error) {
+			return agent.Set(opts, storeCb)
+		}, cb)
+	}
+	if agent.integrity != nil {
+		return agent.integrity.Set(opts, cb)
+	}
 	return agent.crud.Set(opts, cb)
 }
 
 // Replace replaces the value of a Couchbase document with another value.
 func (agent *Agent) Replace(opts ReplaceOptions, cb StoreCallback) (PendingOp, error) {
+	if opts.LegacyDurability {
+		odOpts := observeDurabilityOptionsFrom(opts.DurabilityLevel, opts.RetryStrategy, opts.Deadline, opts.User,
+			opts.TraceContext)
+		opts.DurabilityLevel = 0
+		opts.LegacyDurability = false
+		return agent.observeDurabilityStore(odOpts, func(storeCb StoreCallback) (PendingOp, error) {
+			return agent.Replace(opts, storeCb)
+		}, cb)
+	}
+	if agent.integrity != nil {
+		return agent.integrity.Replace(opts, cb)
+	}
 	return agent.crud.Replace(opts, cb)
 }
 
+// observeDurabilityStore performs a store operation using store and then waits for it to meet the durability level
+// using observe based durability.
+func (agent *Agent) observeDurabilityStore(opts observeDurabilityOptions,
+	store func(StoreCallback) (PendingOp, error), cb StoreCallback) (PendingOp, error) {
+	var res *StoreResult
+	return agent.observeDurability.Mutate(opts, func(tokenCb func(MutationToken, error)) (PendingOp, error) {
+		return store(func(result *StoreResult, err error) {
+			if err != nil {
+				tokenCb(MutationToken{}, err)
+				return
+			}
+			res = result
+			tokenCb(result.MutationToken, nil)
+		})
+	}, func(err error) {
+		if err != nil {
+			cb(nil, err)
+			return
+		}
+		cb(res, nil)
+	})
+}
+
 // AdjoinCallback is invoked upon completion of a Append or Prepend operation.
 type AdjoinCallback func(*AdjoinResult, error)
 
@@ -118,6 +215,56 @@ func (agent *Agent) GetMeta(opts GetMetaOptions, cb GetMetaCallback) (PendingOp,
 	return agent.crud.GetMeta(opts, cb)
 }
 
+// GetDocumentMetaCallback is invoked upon completion of a GetDocumentMeta operation.
+type GetDocumentMetaCallback func(*GetDocumentMetaResult, error)
+
+// GetDocumentMeta retrieves the metadata of a document, including tombstones, from the $document virtual xattr.
+// Volatile: This API is subject to change at any time.
+func (agent *Agent) GetDocumentMeta(opts GetDocumentMetaOptions, cb GetDocumentMetaCallback) (PendingOp, error) {
+	return agent.crud.GetDocumentMeta(opts, cb)
+}
+
+// GetTombstoneCallback is invoked upon completion of a GetTombstone operation.
+type GetTombstoneCallback func(*GetTombstoneResult, error)
+
+// GetTombstone retrieves xattrs from a document whether or not it has been deleted. ErrDocumentNotFound is returned
+// only if neither a document nor a tombstone exists for the key.
+// Volatile: This API is subject to change at any time.
+func (agent *Agent) GetTombstone(opts GetTombstoneOptions, cb GetTombstoneCallback) (PendingOp, error) {
+	return agent.crud.GetTombstone(opts, cb)
+}
+
+// BatchTouchCallback is invoked upon completion of a BatchTouch operation.
+type BatchTouchCallback func(*BatchTouchResult, error)
+
+// BatchTouch updates the expiry of many documents. Keys are dispatched grouped by the node which is active for them,
+// this only changes the order in which they are dispatched as each key is still sent as a separate request. The
+// callback is invoked once every key has completed, the result of each key is reported in BatchTouchResult.Items and
+// an error is only returned to the callback if the operation could not be started, for example if no config was
+// received before the deadline.
+// Volatile: This API is subject to change at any time.
+func (agent *Agent) BatchTouch(opts BatchTouchOptions, cb BatchTouchCallback) (PendingOp, error) {
+	return agent.crud.BatchTouch(opts, cb)
+}
+
+// LockDocumentCallback is invoked upon completion of a LockDocument operation.
+type LockDocumentCallback func(*LockDocumentResult, error)
+
+// LockDocument locks a document, retrying with backoff whilst the document is locked by another actor until the
+// lock is acquired or the deadline is reached. The returned DocumentLock must be passed to UnlockDocument to release
+// the lock before the lock time expires.
+// Volatile: This API is subject to change at any time.
+func (agent *Agent) LockDocument(opts LockDocumentOptions, cb LockDocumentCallback) (PendingOp, error) {
+	return agent.crud.LockDocument(opts, cb)
+}
+
+// UnlockDocument releases a lock acquired by LockDocument. ErrDocumentNotLocked is returned if the lock expired
+// before it was released. The lock may be passed to UnlockDocument again if the unlock fails for any other reason.
+// Volatile: This API is subject to change at any time.
+func (agent *Agent) UnlockDocument(opts UnlockDocumentOptions, cb UnlockCallback) (PendingOp, error) {
+	return agent.crud.UnlockDocument(opts, cb)
+}
+
 // SetMetaCallback is invoked upon completion of a SetMeta operation.
 type SetMetaCallback func(*SetMetaResult, error)
 
@@ -163,6 +310,27 @@ func (agent *Agent) ObserveVb(opts ObserveVbOptions, cb ObserveVbCallback) (Pend
 	return agent.observe.ObserveVb(opts, cb)
 }
 
+// FailoverLogCallback is invoked upon completion of a GetFailoverLog operation.
+// Volatile: This API is subject to change at any time.
+type FailoverLogCallback func(*GetFailoverLogResult, error)
+
+// GetFailoverLog retrieves the failover log for a particular VBucket, which can be used alongside ObserveVb to detect
+// whether a vbucket has failed over since a seqno was observed.
+// Volatile: This API is subject to change at any time.
+func (agent *Agent) GetFailoverLog(opts GetFailoverLogOptions, cb FailoverLogCallback) (PendingOp, error) {
+	return agent.observe.GetFailoverLog(opts, cb)
+}
+
+// WaitForSeqNoCallback is invoked upon completion of a WaitForSeqNo operation.
+type WaitForSeqNoCallback func(*WaitForSeqNoResult, error)
+
+// WaitForSeqNo waits until a vbucket on the active, and optionally replicas, has reached a seqno. This can be used as
+// a barrier, such as before checkpointing a DCP stream, to ensure that mutations up to the seqno are durable.
+// Volatile: This API is subject to change at any time.
+func (agent *Agent) WaitForSeqNo(opts WaitForSeqNoOptions, cb WaitForSeqNoCallback) (PendingOp, error) {
+	return agent.observeDurability.WaitForSeqNo(opts, cb)
+}
+
 // SubDocOp defines a per-operation structure to be passed to MutateIn
 // or LookupIn for performing many sub-document operations.
 type SubDocOp struct {
@@ -177,7 +345,7 @@ type LookupInCallback func(*LookupInResult, error)
 
 // LookupIn performs a multiple-lookup sub-document operation on a document.
 func (agent *Agent) LookupIn(opts LookupInOptions, cb LookupInCallback) (PendingOp, error) {
-	return agent.crud.LookupIn(opts, cb)
+	return agent.crud.LookupIn(opts, agent.mirror.WrapLookupIn(opts, cb))
 }
 
 // MutateInCallback is invoked upon completion of a MutateIn operation.
@@ -185,6 +353,29 @@ type MutateInCallback func(*MutateInResult, error)
 
 // MutateIn performs a multiple-mutation sub-document operation on a document.
 func (agent *Agent) MutateIn(opts MutateInOptions, cb MutateInCallback) (PendingOp, error) {
+	if opts.LegacyDurability {
+		var res *MutateInResult
+		odOpts := observeDurabilityOptionsFrom(opts.DurabilityLevel, opts.RetryStrategy, opts.Deadline, opts.User,
+			opts.TraceContext)
+		opts.DurabilityLevel = 0
+		opts.LegacyDurability = false
+		return agent.observeDurability.Mutate(odOpts, func(tokenCb func(MutationToken, error)) (PendingOp, error) {
+			return agent.MutateIn(opts, func(result *MutateInResult, err error) {
+				if err != nil {
+					tokenCb(MutationToken{}, err)
+					return
+				}
+				res = result
+				tokenCb(result.MutationToken, nil)
+			})
+		}, func(err error) {
+			if err != nil {
+				cb(nil, err)
+				return
+			}
+			cb(res, nil)
+		})
+	}
 	return agent.crud.MutateIn(opts, cb)
 }
 
@@ -243,6 +434,34 @@ func (agent *Agent) GetCollectionManifest(opts GetCollectionManifestOptions, cb
 	return agent.collections.GetCollectionManifest(opts, cb)
 }
 
+// ManifestChangeCallback is invoked upon completion of a CreateScope, DropScope, CreateCollection or DropCollection
+// operation.
+type ManifestChangeCallback func(*ManifestChangeResult, error)
+
+// CreateScope creates a scope within the agent's bucket. The collections manifest cannot be modified using the
+// memcached protocol so this operation is performed against the management service.
+func (agent *Agent) CreateScope(opts CreateScopeOptions, cb ManifestChangeCallback) (PendingOp, error) {
+	return agent.collectionsMgmt.CreateScope(opts, cb)
+}
+
+// DropScope drops a scope, and all of the collections within it, from the agent's bucket. This operation is
+// performed against the management service.
+func (agent *Agent) DropScope(opts DropScopeOptions, cb ManifestChangeCallback) (PendingOp, error) {
+	return agent.collectionsMgmt.DropScope(opts, cb)
+}
+
+// CreateCollection creates a collection within the agent's bucket. This operation is performed against the
+// management service.
+func (agent *Agent) CreateCollection(opts CreateCollectionOptions, cb ManifestChangeCallback) (PendingOp, error) {
+	return agent.collectionsMgmt.CreateCollection(opts, cb)
+}
+
+// DropCollection drops a collection from the agent's bucket. This operation is performed against the management
+// service.
+func (agent *Agent) DropCollection(opts DropCollectionOptions, cb ManifestChangeCallback) (PendingOp, error) {
+	return agent.collectionsMgmt.DropCollection(opts, cb)
+}
+
 // GetAllCollectionManifestsCallback is invoked upon completion of a GetAllCollectionManifests operation.
 type GetAllCollectionManifestsCallback func(*GetAllCollectionManifestsResult, error)
 
@@ -263,6 +482,30 @@ func (agent *Agent) GetCollectionID(scopeName string, collectionName string, opt
 	return agent.collections.GetCollectionID(scopeName, collectionName, opts, cb)
 }
 
+// GetAllCollectionIDsCallback is invoked upon completion of a GetAllCollectionIDs operation.
+type GetAllCollectionIDsCallback func(*GetAllCollectionIDsResult, error)
+
+// GetAllCollectionIDs fetches the collection ids of many collections using a single fetch of the collections manifest,
+// rather than one GetCollectionID operation per collection. This function will also prime the client's collection id
+// cache for each collection which exists.
+// Volatile: This API is subject to change at any time.
+func (agent *Agent) GetAllCollectionIDs(opts GetAllCollectionIDsOptions, cb GetAllCollectionIDsCallback) (PendingOp, error) {
+	return agent.collections.GetAllCollectionIDs(opts, cb)
+}
+
+// RegisterConfigListener registers a listener to be notified every time that the agent applies a new route config,
+// including the nodes added and removed and how many vbuckets moved since the previous config.
+// Volatile: This API is subject to change at any time.
+func (agent *Agent) RegisterConfigListener(listener RouteConfigWatcher) {
+	agent.cfgManager.AddConfigListener(listener)
+}
+
+// UnregisterConfigListener unregisters a listener previously registered with RegisterConfigListener.
+// Volatile: This API is subject to change at any time.
+func (agent *Agent) UnregisterConfigListener(listener RouteConfigWatcher) {
+	agent.cfgManager.RemoveConfigListener(listener)
+}
+
 // PingCallback is invoked upon completion of a PingKv operation.
 type PingCallback func(*PingResult, error)
 
@@ -279,9 +522,25 @@ func (agent *Agent) Diagnostics(opts DiagnosticsOptions) (*DiagnosticInfo, error
 	return agent.diagnostics.Diagnostics(opts)
 }
 
+// CircuitBreakerStates returns the current state of the circuit breakers for every KV connection and HTTP endpoint.
+// HTTP endpoints only appear once a request has been sent to them with circuit breaking enabled for their service.
+// Volatile: This API is subject to change at any time.
+func (agent *Agent) CircuitBreakerStates() ([]CircuitBreakerInfo, error) {
+	states, err := agent.kvMux.CircuitBreakerStates()
+	if err != nil {
+		return nil, err
+	}
+
+	return append(states, agent.http.CircuitBreakerStates()...), nil
+}
+
 // WaitUntilReadyCallback is invoked upon completion of a WaitUntilReady operation.
 type WaitUntilReadyCallback func(*WaitUntilReadyResult, error)
 
+// WaitUntilReadyProgressCallback is invoked each time that a WaitUntilReady operation checks the readiness of a service.
+// Volatile: This API is subject to change at any time.
+type WaitUntilReadyProgressCallback func(WaitUntilReadyProgress)
+
 // RangeScanCreateCallback is invoked upon completion of a RangeScanCreate operation.
 type RangeScanCreateCallback func(RangeScanCreateResult, error)
 
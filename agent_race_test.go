@@ -0,0 +1,162 @@
+package gocbcore
+
+import (
+	"crypto/x509"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/couchbase/gocbcore/v10/memd"
+)
+
+type testNoopRouteConfigListener struct{}
+
+func (l *testNoopRouteConfigListener) OnRouteConfig(RouteConfigEvent) {}
+
+// TestAgentConcurrentUse exercises the public Agent API from many goroutines at once, including closing the agent
+// whilst operations are being dispatched and reconfigured. It is intended to be run with -race.
+func (suite *UnitTestSuite) TestAgentConcurrentUse() {
+	globalTestLogger.SuppressWarnings(true)
+	defer globalTestLogger.SuppressWarnings(false)
+
+	before := takeTestLeakSnapshot()
+
+	listener, err := newTestBlackholeListener()
+	suite.Require().Nil(err, err)
+	defer listener.Close()
+
+	config := AgentConfig{}
+	err = config.FromConnStr(fmt.Sprintf("ns_server://%s", listener.Addr()))
+	suite.Require().Nil(err, err)
+	config.BucketName = "default"
+	config.SecurityConfig.Auth = PasswordAuthProvider{Username: "user", Password: "pass"}
+
+	agent, err := CreateAgent(&config)
+	suite.Require().Nil(err, err)
+
+	var pending sync.WaitGroup
+	var dispatched, completed uint64
+	track := func(op PendingOp, err error) {
+		if err == nil {
+			atomic.AddUint64(&dispatched, 1)
+			return
+		}
+		// The op was rejected synchronously so its callback will never be invoked.
+		pending.Done()
+	}
+	done := func() {
+		atomic.AddUint64(&completed, 1)
+		pending.Done()
+	}
+
+	cfgListener := &testNoopRouteConfigListener{}
+	ops := []func(deadline time.Time){
+		func(deadline time.Time) {
+			pending.Add(1)
+			track(agent.Get(GetOptions{Key: []byte("key"), Deadline: deadline}, func(*GetResult, error) { done() }))
+		},
+		func(deadline time.Time) {
+			pending.Add(1)
+			track(agent.Set(SetOptions{Key: []byte("key"), Value: []byte("{}"), Deadline: deadline},
+				func(*StoreResult, error) { done() }))
+		},
+		func(deadline time.Time) {
+			pending.Add(1)
+			track(agent.Delete(DeleteOptions{Key: []byte("key"), Deadline: deadline},
+				func(*DeleteResult, error) { done() }))
+		},
+		func(deadline time.Time) {
+			pending.Add(1)
+			track(agent.LookupIn(LookupInOptions{
+				Key:      []byte("key"),
+				Ops:      []SubDocOp{{Op: memd.SubDocOpGet, Path: "name"}},
+				Deadline: deadline,
+			}, func(*LookupInResult, error) { done() }))
+		},
+		func(deadline time.Time) {
+			pending.Add(1)
+			track(agent.N1QLQuery(N1QLQueryOptions{Payload: []byte(`{"statement":"SELECT 1"}`), Deadline: deadline},
+				func(*N1QLRowReader, error) { done() }))
+		},
+		func(deadline time.Time) {
+			pending.Add(1)
+			track(agent.DoHTTPRequest(&HTTPRequest{Service: MgmtService, Method: "GET", Path: "/pools",
+				Deadline: deadline}, func(*HTTPResponse, error) { done() }))
+		},
+		func(deadline time.Time) {
+			pending.Add(1)
+			track(agent.GetCollectionID("scope", "collection", GetCollectionIDOptions{Deadline: deadline},
+				func(*GetCollectionIDResult, error) { done() }))
+		},
+		func(deadline time.Time) {
+			_, _ = agent.ConfigSnapshot()
+			_, _ = agent.Diagnostics(DiagnosticsOptions{})
+			_, _ = agent.CircuitBreakerStates()
+		},
+		func(deadline time.Time) {
+			agent.RegisterConfigListener(cfgListener)
+			agent.UnregisterConfigListener(cfgListener)
+		},
+		func(deadline time.Time) {
+			_ = agent.ReconfigureSecurity(ReconfigureSecurityOptions{
+				Auth: PasswordAuthProvider{Username: "user", Password: "pass"},
+			})
+		},
+		func(deadline time.Time) {
+			_ = agent.ReconfigureSecurity(ReconfigureSecurityOptions{
+				UseTLS: true,
+				TLSRootCAProvider: func() *x509.CertPool {
+					return nil
+				},
+			})
+			// Invalid options must not leave the agent's connection settings locked.
+			_ = agent.ReconfigureSecurity(ReconfigureSecurityOptions{UseTLS: true})
+		},
+		func(deadline time.Time) {
+			agent.ForceReconnect()
+		},
+	}
+
+	var workers sync.WaitGroup
+	stopCh := make(chan struct{})
+	for i := 0; i < 8; i++ {
+		workers.Add(1)
+		go func(i int) {
+			defer workers.Done()
+			for j := i; ; j++ {
+				select {
+				case <-stopCh:
+					return
+				default:
+				}
+
+				ops[j%len(ops)](time.Now().Add(20 * time.Millisecond))
+			}
+		}(i)
+	}
+
+	// Let the workers run for a while then close the agent whilst they are still dispatching.
+	time.Sleep(100 * time.Millisecond)
+	suite.Require().Nil(agent.Close())
+	time.Sleep(20 * time.Millisecond)
+	close(stopCh)
+	workers.Wait()
+
+	waitCh := make(chan struct{})
+	go func() {
+		pending.Wait()
+		close(waitCh)
+	}()
+	select {
+	case <-waitCh:
+	case <-time.After(5 * time.Second):
+		suite.T().Fatalf("Timed out waiting for operation callbacks, %d dispatched and %d completed",
+			atomic.LoadUint64(&dispatched), atomic.LoadUint64(&completed))
+	}
+
+	suite.Assert().Equal(atomic.LoadUint64(&dispatched), atomic.LoadUint64(&completed))
+
+	listener.Close()
+	assertNoTestLeaks(suite.T(), before, 5*time.Second)
+}
@@ -516,6 +516,63 @@ func (suite *StandardTestSuite) TestGetReplica() {
 	suite.VerifyKVMetrics(suite.meter, "GetOneReplica", 1, true, false)
 }
 
+func (suite *StandardTestSuite) TestQuorumGet() {
+	suite.EnsureSupportsFeature(TestFeatureReplicas)
+	agent, s := suite.GetAgentAndHarness()
+
+	var cas Cas
+	s.PushOp(agent.Set(SetOptions{
+		Key:            []byte("testQuorumGet"),
+		Value:          []byte("{}"),
+		CollectionName: suite.CollectionName,
+		ScopeName:      suite.ScopeName,
+	}, func(res *StoreResult, err error) {
+		s.Wrap(func() {
+			if err != nil {
+				s.Fatalf("Set operation failed: %v", err)
+			}
+			cas = res.Cas
+		})
+	}))
+	s.Wait(0)
+
+	retries := 0
+	for {
+		var found bool
+		s.PushOp(agent.QuorumGet(QuorumGetOptions{
+			Key:            []byte("testQuorumGet"),
+			CollectionName: suite.CollectionName,
+			ScopeName:      suite.ScopeName,
+			Deadline:       time.Now().Add(2 * time.Second),
+		}, func(res *QuorumGetResult, err error) {
+			s.Wrap(func() {
+				if errors.Is(err, ErrDocumentNotFound) || errors.Is(err, ErrDocumentUnretrievable) {
+					return
+				}
+				if err != nil {
+					s.Fatalf("QuorumGet operation failed: %v", err)
+				}
+				if res.Cas != cas {
+					s.Fatalf("QuorumGet returned unexpected cas, expected %d was %d", cas, res.Cas)
+				}
+				if string(res.Value) != "{}" {
+					s.Fatalf("QuorumGet returned unexpected value: %s", res.Value)
+				}
+				found = true
+			})
+		}))
+		s.Wait(0)
+		if found {
+			break
+		}
+		retries++
+		if retries >= 5 {
+			suite.T().Fatalf("QuorumGet could not locate key")
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
 func (suite *StandardTestSuite) TestDurableWriteGetReplica() {
 	suite.EnsureSupportsFeature(TestFeatureReplicas)
 	suite.EnsureSupportsFeature(TestFeatureEnhancedDurability)
@@ -1585,6 +1642,50 @@ func (suite *StandardTestSuite) TestObserveSeqNo() {
 	suite.VerifyKVMetrics(suite.meter, "ObserveVb", 2, false, false)
 }
 
+func (suite *StandardTestSuite) TestGetFailoverLog() {
+	agent, s := suite.GetAgentAndHarness()
+
+	mt := MutationToken{}
+	s.PushOp(agent.Set(SetOptions{
+		Key:            []byte("testFailoverLog"),
+		Value:          []byte("there"),
+		CollectionName: suite.CollectionName,
+		ScopeName:      suite.ScopeName,
+	}, func(res *StoreResult, err error) {
+		s.Wrap(func() {
+			if err != nil {
+				s.Fatalf("Set operation failed: %v", err)
+			}
+
+			mt = res.MutationToken
+		})
+	}))
+	s.Wait(0)
+
+	if mt.VbUUID == 0 {
+		suite.T().Skip("Mutation tokens not supported by server")
+	}
+
+	s.PushOp(agent.GetFailoverLog(GetFailoverLogOptions{
+		VbID: mt.VbID,
+	}, func(res *GetFailoverLogResult, err error) {
+		s.Wrap(func() {
+			if err != nil {
+				s.Fatalf("GetFailoverLog operation failed: %v", err)
+			}
+			if res.VbID != mt.VbID {
+				s.Fatalf("Unexpected vbucket %d, expected %d", res.VbID, mt.VbID)
+			}
+			if len(res.Entries) == 0 || res.Entries[0].VbUUID != mt.VbUUID {
+				s.Fatalf("Failover log should start with the current vbuuid: %v", res.Entries)
+			}
+		})
+	}))
+	s.Wait(0)
+
+	suite.VerifyKVMetrics(suite.meter, "GetFailoverLog", 1, false, false)
+}
+
 func (suite *StandardTestSuite) TestRandomGet() {
 	agent, s := suite.GetAgentAndHarness()
 
@@ -1913,11 +2014,29 @@ func (suite *StandardTestSuite) TestMetaOps() {
 	}))
 	s.Wait(0)
 
+	// SetMeta with a CAS which doesn't match the existing document
+	s.PushOp(agent.SetMeta(SetMetaOptions{
+		Key:        []byte("test"),
+		Value:      []byte("{}"),
+		Cas:        currentCas + 1,
+		RevNo:      1,
+		CompareCas: currentCas + 1,
+		Options:    uint32(memd.SkipConflictResolution),
+	}, func(res *SetMetaResult, err error) {
+		s.Wrap(func() {
+			if !errors.Is(err, ErrCasMismatch) {
+				s.Fatalf("SetMeta operation should have failed with cas mismatch: %v", err)
+			}
+		})
+	}))
+	s.Wait(0)
+
 	if suite.Assert().Contains(suite.tracer.Spans, nil) {
 		nilParents := suite.tracer.Spans[nil]
-		if suite.Assert().Equal(2, len(nilParents)) {
+		if suite.Assert().Equal(3, len(nilParents)) {
 			suite.AssertOpSpan(nilParents[0], "Set", agent.BucketName(), memd.CmdSet.Name(), 1, false, "test")
 			suite.AssertOpSpan(nilParents[1], "GetMeta", agent.BucketName(), memd.CmdGetMeta.Name(), 1, false, "test")
+			suite.AssertOpSpan(nilParents[2], "SetMeta", agent.BucketName(), memd.CmdSetMeta.Name(), 1, false, "test")
 		}
 	}
 
@@ -2168,6 +2287,30 @@ func (suite *StandardTestSuite) TestAgentGroupWaitUntilReadyBucket() {
 	suite.VerifyConnectedToBucket(agent, s, "TestAgentGroupWaitUntilReadyBucket", suite.CollectionName, suite.ScopeName)
 }
 
+func (suite *StandardTestSuite) TestAgentGroupCloseBucket() {
+	cfg := suite.makeAgentGroupConfig(globalTestConfig)
+	ag, err := CreateAgentGroup(&cfg)
+	suite.Require().Nil(err, err)
+	defer ag.Close()
+
+	err = ag.OpenBucket(globalTestConfig.BucketName)
+	suite.Require().Nil(err, err)
+	suite.Require().NotNil(ag.GetAgent(globalTestConfig.BucketName))
+
+	err = ag.CloseBucket(globalTestConfig.BucketName)
+	suite.Require().Nil(err, err)
+	suite.Assert().Nil(ag.GetAgent(globalTestConfig.BucketName))
+
+	// The cluster level agent must still have a source of cluster configs once the last bucket is closed.
+	ag.agentsLock.Lock()
+	suite.Assert().Contains(ag.boundAgents, "")
+	ag.agentsLock.Unlock()
+
+	// Closing a bucket which isn't open is a no-op.
+	err = ag.CloseBucket(globalTestConfig.BucketName)
+	suite.Require().Nil(err, err)
+}
+
 func (suite *StandardTestSuite) TestConnectHTTPOnlyDefaultPort() {
 	cfg := makeAgentConfig(globalTestConfig)
 	if len(cfg.SeedConfig.HTTPAddrs) == 0 {
@@ -3072,3 +3215,9 @@ func dumpManifest(agent *Agent, t *testing.T) {
 	}
 	<-waitCh
 }
+
+func (suite *UnitTestSuite) TestAgentReloadCredentialsRequiresAuth() {
+	agent := &Agent{}
+	err := agent.ReloadCredentials(ReloadCredentialsOptions{})
+	suite.Assert().ErrorIs(err, ErrInvalidArgument)
+}
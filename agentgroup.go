@@ -46,6 +46,8 @@ func CreateAgentGroup(config *AgentGroupConfig) (*AgentGroup, error) {
 		MeterConfig:          config.MeterConfig,
 		DefaultRetryStrategy: config.DefaultRetryStrategy,
 		CircuitBreakerConfig: config.CircuitBreakerConfig,
+
+		ServiceCircuitBreakerConfigs: config.ServiceCircuitBreakerConfigs,
 	})
 	if err != nil {
 		return nil, err
@@ -87,6 +89,48 @@ func (ag *AgentGroup) OpenBucket(bucketName string) error {
 	return nil
 }
 
+// CloseBucket will close the agent, if any, corresponding to the bucket name specified. The cluster level operations
+// on the AgentGroup continue to use the cluster config received from any remaining agents. If this is the last open
+// bucket then a cluster level agent is created first, as when the AgentGroup was created, so that the cluster config
+// continues to be kept up to date.
+// Volatile: This API is subject to change at any time.
+func (ag *AgentGroup) CloseBucket(bucketName string) error {
+	if bucketName == "" {
+		return wrapError(errInvalidArgument, "bucket name cannot be empty")
+	}
+
+	ag.agentsLock.Lock()
+	agent := ag.boundAgents[bucketName]
+	isLastAgent := len(ag.boundAgents) == 1
+	ag.agentsLock.Unlock()
+	if agent == nil {
+		return nil
+	}
+
+	if isLastAgent {
+		config := ag.config.toAgentConfig()
+		config.BucketName = ""
+
+		globalAgent, err := CreateAgent(config)
+		if err != nil {
+			return err
+		}
+
+		ag.clusterAgent.RegisterWith(globalAgent.cfgManager, globalAgent.dialer)
+
+		ag.agentsLock.Lock()
+		ag.boundAgents[""] = globalAgent
+		ag.agentsLock.Unlock()
+	}
+
+	ag.agentsLock.Lock()
+	delete(ag.boundAgents, bucketName)
+	ag.agentsLock.Unlock()
+
+	ag.clusterAgent.UnregisterWith(agent.cfgManager, agent.dialer)
+	return agent.Close()
+}
+
 // GetAgent will return the agent, if any, corresponding to the bucket name specified.
 func (ag *AgentGroup) GetAgent(bucketName string) *Agent {
 	if bucketName == "" {
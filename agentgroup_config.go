@@ -28,9 +28,12 @@ func (config *AgentGroupConfig) toAgentConfig() *AgentConfig {
 		HTTPConfig:           config.HTTPConfig,
 		DefaultRetryStrategy: config.DefaultRetryStrategy,
 		CircuitBreakerConfig: config.CircuitBreakerConfig,
-		OrphanReporterConfig: config.OrphanReporterConfig,
-		MeterConfig:          config.MeterConfig,
-		TracerConfig:         config.TracerConfig,
-		InternalConfig:       config.InternalConfig,
+
+		ServiceCircuitBreakerConfigs: config.ServiceCircuitBreakerConfigs,
+		OrphanReporterConfig:         config.OrphanReporterConfig,
+		MeterConfig:                  config.MeterConfig,
+		TracerConfig:                 config.TracerConfig,
+		ValueHooks:                   config.ValueHooks,
+		InternalConfig:               config.InternalConfig,
 	}
 }
@@ -5,7 +5,6 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io/ioutil"
 	"time"
 )
 
@@ -14,6 +13,8 @@ type AnalyticsRowReader struct {
 	streamer   *queryStreamer
 	statement  string
 	statusCode int
+
+	rowUnmarshaler RowUnmarshaler
 }
 
 // NextRow reads the next rows bytes from the stream
@@ -21,6 +22,19 @@ func (q *AnalyticsRowReader) NextRow() []byte {
 	return q.streamer.NextRow()
 }
 
+// NextRowInto reads the next rows bytes from the stream, reusing the memory of buf where it has the capacity.
+// Volatile: This API is subject to change at any time.
+func (q *AnalyticsRowReader) NextRowInto(buf []byte) []byte {
+	return q.streamer.NextRowInto(buf)
+}
+
+// NextRowDecode unmarshals the next row from the stream into valuePtr using the RowUnmarshaler from the query
+// options, returning false once there are no more rows. Err should be checked once all rows have been read.
+// Volatile: This API is subject to change at any time.
+func (q *AnalyticsRowReader) NextRowDecode(valuePtr interface{}) (bool, error) {
+	return q.streamer.NextRowDecode(q.rowUnmarshaler, valuePtr)
+}
+
 // Err returns any errors that occurred during streaming.
 func (q AnalyticsRowReader) Err() error {
 	err := q.streamer.Err()
@@ -61,6 +75,18 @@ func (q *AnalyticsRowReader) MetaData() ([]byte, error) {
 	return q.streamer.MetaData()
 }
 
+// Warnings returns the warnings returned by the analytics service. As warnings are sent after the rows this can
+// only be called once all rows have been read.
+// Volatile: This API is subject to change at any time.
+func (q *AnalyticsRowReader) Warnings() ([]AnalyticsWarning, error) {
+	meta, err := q.streamer.MetaData()
+	if err != nil {
+		return nil, err
+	}
+
+	return parseAnalyticsWarnings(meta)
+}
+
 // Close immediately shuts down the connection
 func (q *AnalyticsRowReader) Close() error {
 	return q.streamer.Close()
@@ -76,6 +102,10 @@ type AnalyticsQueryOptions struct {
 	// Internal: This should never be used and is not supported.
 	User string
 
+	// RowUnmarshaler is used by AnalyticsRowReader.NextRowDecode to unmarshal rows, encoding/json is used if not set.
+	// Volatile: This API is subject to change at any time.
+	RowUnmarshaler RowUnmarshaler
+
 	TraceContext RequestSpanContext
 }
 
@@ -107,6 +137,34 @@ type jsonAnalyticsError struct {
 	Msg  string `json:"msg"`
 }
 
+// AnalyticsWarning represents a single warning returned by the analytics service.
+// Volatile: This API is subject to change at any time.
+type AnalyticsWarning struct {
+	Code    uint32
+	Message string
+}
+
+type jsonAnalyticsWarningsResponse struct {
+	Warnings []jsonAnalyticsError `json:"warnings"`
+}
+
+func parseAnalyticsWarnings(meta []byte) ([]AnalyticsWarning, error) {
+	var resp jsonAnalyticsWarningsResponse
+	if err := json.Unmarshal(meta, &resp); err != nil {
+		return nil, wrapError(errParsingFailure, "failed to parse analytics warnings")
+	}
+
+	var warnings []AnalyticsWarning
+	for _, warning := range resp.Warnings {
+		warnings = append(warnings, AnalyticsWarning{
+			Code:    warning.Code,
+			Message: warning.Msg,
+		})
+	}
+
+	return warnings, nil
+}
+
 type jsonAnalyticsErrorResponse struct {
 	Errors json.RawMessage
 }
@@ -115,7 +173,7 @@ func parseAnalyticsErrorResp(req *httpRequest, statement string, resp *HTTPRespo
 	var errorDescs []AnalyticsErrorDesc
 	var err error
 	var raw string
-	respBody, readErr := ioutil.ReadAll(resp.Body)
+	respBody, readErr := resp.readBody()
 	if readErr == nil {
 		raw, errorDescs, err = parseAnalyticsError(respBody)
 	}
@@ -248,12 +306,16 @@ func (aqc *analyticsQueryComponent) AnalyticsQuery(opts AnalyticsQueryOptions, c
 
 	go func() {
 		res, err := aqc.analyticsQuery(ireq, payloadMap, statement, tracer.StartTime())
+		err = ireq.complete(err, func() error {
+			return res.Close()
+		})
 		if err != nil {
 			cancel()
-			tracer.Finish()
+			tracer.FinishWithError(err)
 			cb(nil, err)
 			return
 		}
+		res.rowUnmarshaler = opts.RowUnmarshaler
 
 		tracer.Finish()
 		cb(res, nil)
@@ -322,11 +384,13 @@ func (aqc *analyticsQueryComponent) analyticsQuery(ireq *httpRequest, payloadMap
 				return nil, analyticsErr
 			}
 
+			ireq.setServerRetryAfter(retryAfterFromHTTPResponse(resp))
 			shouldRetry, retryTime := retryOrchMaybeRetry(ireq, retryReason)
 			if !shouldRetry {
 				// analyticsErr is already wrapped here
 				return nil, analyticsErr
 			}
+			aqc.tracer.RetryCountRecord(metricValueServiceAnalyticsValue, "")
 
 			select {
 			case <-time.After(time.Until(retryTime)):
@@ -347,7 +411,7 @@ func (aqc *analyticsQueryComponent) analyticsQuery(ireq *httpRequest, payloadMap
 
 		streamer, err := newQueryStreamer(resp.Body, "results")
 		if err != nil {
-			respBody, readErr := ioutil.ReadAll(resp.Body)
+			respBody, readErr := resp.readBody()
 			if readErr != nil {
 				logDebugf("Failed to read response body: %v", readErr)
 			}
@@ -336,3 +336,16 @@ func (suite *StandardTestSuite) TestAnalyticsTimeout() {
 
 	suite.VerifyMetrics(suite.meter, "cbas:AnalyticsQuery", 1, false, false)
 }
+
+func (suite *UnitTestSuite) TestParseAnalyticsWarnings() {
+	warnings, err := parseAnalyticsWarnings([]byte(`{"requestID":"1","warnings":[{"code":1,"msg":"deprecated"}]}`))
+	suite.Require().Nil(err, err)
+	suite.Assert().Equal([]AnalyticsWarning{{Code: 1, Message: "deprecated"}}, warnings)
+
+	warnings, err = parseAnalyticsWarnings([]byte(`{"status":"success"}`))
+	suite.Require().Nil(err, err)
+	suite.Assert().Empty(warnings)
+
+	_, err = parseAnalyticsWarnings([]byte(`not json`))
+	suite.Assert().ErrorIs(err, ErrParsingFailure)
+}
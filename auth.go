@@ -42,6 +42,12 @@ func getSingleAuthCreds(auth AuthProvider, req AuthCredsRequest) (UserPassPair,
 	return creds[0], nil
 }
 
+// isCertificateOnlyCreds returns whether creds are the empty credentials of a provider which authenticates using only
+// a client certificate, in which case no credentials should be sent.
+func isCertificateOnlyCreds(creds []UserPassPair) bool {
+	return len(creds) == 1 && creds[0] == UserPassPair{}
+}
+
 func getKvAuthCreds(auth AuthProvider, endpoint string) (UserPassPair, error) {
 	return getSingleAuthCreds(auth, AuthCredsRequest{
 		Service:  MemdService,
@@ -78,3 +84,38 @@ func (auth PasswordAuthProvider) Credentials(req AuthCredsRequest) ([]UserPassPa
 		Password: auth.Password,
 	}}, nil
 }
+
+// CertificateAuthProvider is an AuthProvider which authenticates using only a client certificate, which the cluster
+// maps to a user. KV connections skip SASL authentication entirely and HTTP requests are sent without credentials.
+// Volatile: This API is subject to change at any time.
+type CertificateAuthProvider struct {
+	// ClientCertificate is the certificate to present, it is ignored if CertificateProvider is set.
+	ClientCertificate *tls.Certificate
+	// CertificateProvider, if set, is called to fetch the certificate for each connection, allowing the certificate
+	// to be rotated. TLSFileWatcher.Certificate can be used as a CertificateProvider.
+	CertificateProvider func(req AuthCertRequest) (*tls.Certificate, error)
+}
+
+// SupportsNonTLS specifies whether this authenticator supports non-TLS connections.
+func (auth CertificateAuthProvider) SupportsNonTLS() bool {
+	return false
+}
+
+// SupportsTLS specifies whether this authenticator supports TLS connections.
+func (auth CertificateAuthProvider) SupportsTLS() bool {
+	return true
+}
+
+// Certificate returns the client certificate to present for the connection.
+func (auth CertificateAuthProvider) Certificate(req AuthCertRequest) (*tls.Certificate, error) {
+	if auth.CertificateProvider != nil {
+		return auth.CertificateProvider(req)
+	}
+
+	return auth.ClientCertificate, nil
+}
+
+// Credentials returns empty credentials as the client certificate identifies the user.
+func (auth CertificateAuthProvider) Credentials(req AuthCredsRequest) ([]UserPassPair, error) {
+	return []UserPassPair{{}}, nil
+}
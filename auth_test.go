@@ -0,0 +1,70 @@
+package gocbcore
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"time"
+	"unsafe"
+)
+
+func (suite *UnitTestSuite) TestCertificateAuthProvider() {
+	staticCert := &tls.Certificate{}
+	auth := CertificateAuthProvider{ClientCertificate: staticCert}
+
+	cert, err := auth.Certificate(AuthCertRequest{Service: MemdService})
+	suite.Require().Nil(err, err)
+	suite.Assert().Same(staticCert, cert)
+
+	providedCert := &tls.Certificate{}
+	auth.CertificateProvider = func(req AuthCertRequest) (*tls.Certificate, error) {
+		return providedCert, nil
+	}
+	cert, err = auth.Certificate(AuthCertRequest{Service: MemdService})
+	suite.Require().Nil(err, err)
+	suite.Assert().Same(providedCert, cert)
+
+	creds, err := auth.Credentials(AuthCredsRequest{Service: MemdService})
+	suite.Require().Nil(err, err)
+	suite.Assert().True(isCertificateOnlyCreds(creds))
+	suite.Assert().False(isCertificateOnlyCreds([]UserPassPair{{Username: "user"}}))
+	suite.Assert().False(auth.SupportsNonTLS())
+
+	_, err = setupTLSConfig(nil, SecurityConfig{Auth: auth, RequireCertificateAuth: true}, nil)
+	suite.Assert().ErrorIs(err, ErrInvalidArgument)
+}
+
+func (suite *UnitTestSuite) TestHTTPComponentCertificateAuth() {
+	var hasAuthHeader bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, hasAuthHeader = r.Header["Authorization"]
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	mux := &httpMux{
+		muxPtr: unsafe.Pointer(&httpClientMux{
+			n1qlEpList: []routeEndpoint{{Address: srv.URL}},
+			revID:      1,
+			auth:       CertificateAuthProvider{},
+		}),
+	}
+
+	tracer := newTracerComponent(&noopTracer{}, "", true, &noopMeter{}, nil)
+	hc := newHTTPComponentWithClient(httpComponentProps{}, &http.Client{}, mux, tracer)
+
+	resp, err := hc.DoInternalHTTPRequest(&httpRequest{
+		Service:       N1qlService,
+		Method:        "POST",
+		Path:          "/query/service",
+		Body:          []byte(`{"statement":"SELECT 1"}`),
+		Deadline:      time.Now().Add(time.Second),
+		RetryStrategy: newFailFastRetryStrategy(),
+		Context:       context.Background(),
+	}, true)
+	suite.Require().Nil(err, err)
+	suite.Require().Nil(resp.Body.Close())
+
+	suite.Assert().False(hasAuthHeader)
+}
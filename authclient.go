@@ -27,6 +27,11 @@ const (
 
 	// ScramSha512AuthMechanism represents that SCRAM SHA512 auth should be performed.
 	ScramSha512AuthMechanism = AuthMechanism("SCRAM-SHA512")
+
+	// OAuthBearerAuthMechanism represents that OAUTHBEARER auth should be performed, using a bearer token supplied by
+	// a TokenAuthProvider. It is always used with a TokenAuthProvider and cannot be used with other providers.
+	// Volatile: This API is subject to change at any time.
+	OAuthBearerAuthMechanism = AuthMechanism("OAUTHBEARER")
 )
 
 // AuthClient exposes an interface for performing authentication on a
@@ -72,6 +77,26 @@ func SaslAuthPlain(username, password string, client AuthClient, deadline time.T
 	return nil
 }
 
+// SaslAuthOAuthBearer performs OAUTHBEARER SASL authentication against an AuthClient.
+// Volatile: This API is subject to change at any time.
+func SaslAuthOAuthBearer(token string, client AuthClient, deadline time.Time, cb func(err error)) error {
+	// The initial client response from RFC 7628, without an authorization identity.
+	authData := []byte("n,,\x01auth=Bearer " + token + "\x01\x01")
+
+	err := client.SaslAuth([]byte(OAuthBearerAuthMechanism), authData, deadline, func(b []byte, err error) {
+		if err != nil {
+			cb(err)
+			return
+		}
+		cb(nil)
+	})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
 func saslAuthScram(saslName []byte, newHash func() hash.Hash, username, password string, client AuthClient,
 	deadline time.Time, continueCb func(), completedCb func(err error)) error {
 	scramMgr := scram.NewClient(newHash, username, password)
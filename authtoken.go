@@ -0,0 +1,188 @@
+package gocbcore
+
+import (
+	"crypto/tls"
+	"sync"
+	"time"
+)
+
+const (
+	defaultTokenRefreshBefore = 30 * time.Second
+	tokenRefreshRetryInterval = 5 * time.Second
+	// minTokenRefreshInterval prevents tokens which are issued with less than RefreshBefore remaining from being
+	// refreshed continuously.
+	minTokenRefreshInterval = time.Second
+)
+
+// TokenFetcher fetches a new bearer token, returning the token and the time at which it expires. A zero expiry
+// indicates that the token does not expire.
+// Volatile: This API is subject to change at any time.
+type TokenFetcher func() (token string, expiry time.Time, err error)
+
+// TokenAuthProviderOptions are the options available when creating a TokenAuthProvider.
+// Volatile: This API is subject to change at any time.
+type TokenAuthProviderOptions struct {
+	// RefreshBefore is how long before a token expires that a new token is fetched, defaults to 30s.
+	RefreshBefore time.Duration
+}
+
+// TokenAuthProvider is an AuthProvider which authenticates using bearer tokens, such as JWTs, rather than a
+// username and password. Tokens are sent in the Authorization header of HTTP requests and KV connections
+// authenticate using the OAUTHBEARER SASL mechanism. Tokens are cached until shortly before they expire, whilst an
+// Agent is using the provider tokens are refreshed ahead of their expiry and KV connections are re-authenticated
+// with the new token. TokenAuthProvider only supports TLS connections.
+// Volatile: This API is subject to change at any time.
+type TokenAuthProvider struct {
+	fetcher       TokenFetcher
+	refreshBefore time.Duration
+
+	lock          sync.Mutex
+	token         string
+	expiry        time.Time
+	refreshTimer  *time.Timer
+	handlers      map[uint64]func()
+	nextHandlerID uint64
+}
+
+// tokenAuthProvider is implemented by AuthProviders which supply bearer tokens rather than credentials.
+type tokenAuthProvider interface {
+	BearerToken(req AuthCredsRequest) (string, error)
+	onTokenRefresh(handler func()) func()
+}
+
+// NewTokenAuthProvider creates a new TokenAuthProvider which uses fetcher to fetch tokens.
+// Volatile: This API is subject to change at any time.
+func NewTokenAuthProvider(fetcher TokenFetcher, opts TokenAuthProviderOptions) *TokenAuthProvider {
+	refreshBefore := opts.RefreshBefore
+	if refreshBefore <= 0 {
+		refreshBefore = defaultTokenRefreshBefore
+	}
+
+	return &TokenAuthProvider{
+		fetcher:       fetcher,
+		refreshBefore: refreshBefore,
+		handlers:      make(map[uint64]func()),
+	}
+}
+
+// SupportsNonTLS specifies whether this authenticator supports non-TLS connections.
+func (auth *TokenAuthProvider) SupportsNonTLS() bool {
+	return false
+}
+
+// SupportsTLS specifies whether this authenticator supports TLS connections.
+func (auth *TokenAuthProvider) SupportsTLS() bool {
+	return true
+}
+
+// Certificate directly returns a certificate chain to present for the connection.
+func (auth *TokenAuthProvider) Certificate(req AuthCertRequest) (*tls.Certificate, error) {
+	return nil, nil
+}
+
+// Credentials always fails as this provider only supplies bearer tokens, see BearerToken.
+func (auth *TokenAuthProvider) Credentials(req AuthCredsRequest) ([]UserPassPair, error) {
+	return nil, wrapError(errInvalidCredentials, "token auth provider only supplies bearer tokens")
+}
+
+// BearerToken returns the current token, fetching a new one if there is no token or it is about to expire.
+func (auth *TokenAuthProvider) BearerToken(req AuthCredsRequest) (string, error) {
+	auth.lock.Lock()
+	defer auth.lock.Unlock()
+
+	if auth.token != "" && !auth.needsRefreshLocked() {
+		return auth.token, nil
+	}
+
+	return auth.fetchLocked()
+}
+
+func (auth *TokenAuthProvider) needsRefreshLocked() bool {
+	return !auth.expiry.IsZero() && time.Until(auth.expiry) <= auth.refreshBefore
+}
+
+func (auth *TokenAuthProvider) fetchLocked() (string, error) {
+	token, expiry, err := auth.fetcher()
+	if err != nil {
+		return "", err
+	}
+
+	isRefresh := auth.token != ""
+	auth.token = token
+	auth.expiry = expiry
+	auth.scheduleRefreshLocked()
+
+	if isRefresh {
+		// The handlers are called asynchronously so that they are free to fetch the token.
+		for _, handler := range auth.handlers {
+			go handler()
+		}
+	}
+
+	return token, nil
+}
+
+// scheduleRefreshLocked schedules the token to be refreshed ahead of its expiry, this only happens whilst there are
+// handlers registered as there is otherwise nothing which needs the token to be kept valid.
+func (auth *TokenAuthProvider) scheduleRefreshLocked() {
+	auth.scheduleRefreshAfterLocked(time.Until(auth.expiry) - auth.refreshBefore)
+}
+
+func (auth *TokenAuthProvider) scheduleRefreshAfterLocked(after time.Duration) {
+	if auth.refreshTimer != nil {
+		auth.refreshTimer.Stop()
+		auth.refreshTimer = nil
+	}
+
+	if len(auth.handlers) == 0 || auth.token == "" || auth.expiry.IsZero() {
+		return
+	}
+
+	if after < minTokenRefreshInterval {
+		after = minTokenRefreshInterval
+	}
+	auth.refreshTimer = time.AfterFunc(after, auth.refresh)
+}
+
+func (auth *TokenAuthProvider) refresh() {
+	auth.lock.Lock()
+	defer auth.lock.Unlock()
+
+	if len(auth.handlers) == 0 {
+		return
+	}
+
+	if !auth.needsRefreshLocked() {
+		// The token has already been refreshed, or the timer fired slightly early.
+		auth.scheduleRefreshLocked()
+		return
+	}
+
+	if _, err := auth.fetchLocked(); err != nil {
+		logWarnf("Failed to refresh bearer token, will retry in %s (%v)", tokenRefreshRetryInterval, err)
+		auth.scheduleRefreshAfterLocked(tokenRefreshRetryInterval)
+	}
+}
+
+// onTokenRefresh registers a handler which is called whenever the token is refreshed, whilst any handlers are
+// registered the token is refreshed ahead of its expiry. The returned function unregisters the handler.
+func (auth *TokenAuthProvider) onTokenRefresh(handler func()) func() {
+	auth.lock.Lock()
+	id := auth.nextHandlerID
+	auth.nextHandlerID++
+	auth.handlers[id] = handler
+	if len(auth.handlers) == 1 {
+		auth.scheduleRefreshLocked()
+	}
+	auth.lock.Unlock()
+
+	return func() {
+		auth.lock.Lock()
+		delete(auth.handlers, id)
+		if len(auth.handlers) == 0 && auth.refreshTimer != nil {
+			auth.refreshTimer.Stop()
+			auth.refreshTimer = nil
+		}
+		auth.lock.Unlock()
+	}
+}
@@ -0,0 +1,124 @@
+package gocbcore
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"time"
+	"unsafe"
+)
+
+func (suite *UnitTestSuite) TestTokenAuthProviderCachesToken() {
+	var numFetches uint32
+	expiry := time.Now().Add(time.Hour)
+	auth := NewTokenAuthProvider(func() (string, time.Time, error) {
+		atomic.AddUint32(&numFetches, 1)
+		return "token", expiry, nil
+	}, TokenAuthProviderOptions{})
+
+	for i := 0; i < 3; i++ {
+		token, err := auth.BearerToken(AuthCredsRequest{Service: MemdService})
+		suite.Require().Nil(err, err)
+		suite.Assert().Equal("token", token)
+	}
+	suite.Assert().Equal(uint32(1), atomic.LoadUint32(&numFetches))
+
+	// Once the token is within RefreshBefore of expiring a new token is fetched.
+	expiry = time.Now().Add(10 * time.Second)
+	auth.lock.Lock()
+	auth.expiry = expiry
+	auth.lock.Unlock()
+
+	_, err := auth.BearerToken(AuthCredsRequest{Service: MemdService})
+	suite.Require().Nil(err, err)
+	suite.Assert().Equal(uint32(2), atomic.LoadUint32(&numFetches))
+
+	_, err = auth.Credentials(AuthCredsRequest{Service: MemdService})
+	suite.Assert().True(errors.Is(err, ErrInvalidCredentials))
+	suite.Assert().False(auth.SupportsNonTLS())
+}
+
+func (suite *UnitTestSuite) TestTokenAuthProviderFetchError() {
+	fetchErr := errors.New("fetch failed")
+	auth := NewTokenAuthProvider(func() (string, time.Time, error) {
+		return "", time.Time{}, fetchErr
+	}, TokenAuthProviderOptions{})
+
+	_, err := auth.BearerToken(AuthCredsRequest{Service: MemdService})
+	suite.Assert().Equal(fetchErr, err)
+}
+
+func (suite *UnitTestSuite) TestTokenAuthProviderRefreshesAheadOfExpiry() {
+	var numFetches uint32
+	auth := NewTokenAuthProvider(func() (string, time.Time, error) {
+		atomic.AddUint32(&numFetches, 1)
+		// The token always needs refreshing so the refresh is scheduled after minTokenRefreshInterval.
+		return "token", time.Now().Add(time.Hour), nil
+	}, TokenAuthProviderOptions{
+		RefreshBefore: time.Hour,
+	})
+
+	refreshedCh := make(chan struct{}, 1)
+	unregister := auth.onTokenRefresh(func() {
+		select {
+		case refreshedCh <- struct{}{}:
+		default:
+		}
+	})
+
+	_, err := auth.BearerToken(AuthCredsRequest{Service: MemdService})
+	suite.Require().Nil(err, err)
+
+	select {
+	case <-refreshedCh:
+	case <-time.After(5 * time.Second):
+		suite.T().Fatalf("Token was not refreshed")
+	}
+	suite.Assert().GreaterOrEqual(atomic.LoadUint32(&numFetches), uint32(2))
+
+	unregister()
+
+	auth.lock.Lock()
+	suite.Assert().Nil(auth.refreshTimer)
+	suite.Assert().Empty(auth.handlers)
+	auth.lock.Unlock()
+}
+
+func (suite *UnitTestSuite) TestHTTPComponentBearerToken() {
+	var authHeader string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authHeader = r.Header.Get("Authorization")
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	auth := NewTokenAuthProvider(func() (string, time.Time, error) {
+		return "token", time.Time{}, nil
+	}, TokenAuthProviderOptions{})
+	mux := &httpMux{
+		muxPtr: unsafe.Pointer(&httpClientMux{
+			mgmtEpList: []routeEndpoint{{Address: srv.URL}},
+			revID:      1,
+			auth:       auth,
+		}),
+	}
+
+	tracer := newTracerComponent(&noopTracer{}, "", true, &noopMeter{}, nil)
+	hc := newHTTPComponentWithClient(httpComponentProps{}, &http.Client{}, mux, tracer)
+
+	resp, err := hc.DoInternalHTTPRequest(&httpRequest{
+		Service:       MgmtService,
+		Method:        "GET",
+		Path:          "/pools/default",
+		Endpoint:      srv.URL,
+		Deadline:      time.Now().Add(time.Second),
+		RetryStrategy: newFailFastRetryStrategy(),
+		Context:       context.Background(),
+	}, true)
+	suite.Require().Nil(err, err)
+	suite.Require().Nil(resp.Body.Close())
+
+	suite.Assert().Equal("Bearer token", authHeader)
+}
@@ -1,9 +1,11 @@
 package gocbcore
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"net/url"
 	"sync"
 	"sync/atomic"
@@ -22,6 +24,24 @@ func (i *configStreamBlock) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// decodeConfigBlock decodes the next config from a config stream. The maximum response body size applies to each
+// config, rather than to the stream as a whole, so that a stream can be consumed indefinitely whilst a single
+// oversized config still fails with ErrHTTPResponseTooLarge.
+func decodeConfigBlock(dec *json.Decoder, body *httpBodyLimitReader, block *configStreamBlock) error {
+	if err := dec.Decode(block); err != nil {
+		return err
+	}
+
+	// The decoder may have already read part of the next config.
+	readAhead, err := io.Copy(ioutil.Discard, dec.Buffered())
+	if err != nil {
+		return err
+	}
+	body.reset(readAhead)
+
+	return nil
+}
+
 func hostnameFromURI(uri string) string {
 	uriInfo, err := url.Parse(uri)
 	if err != nil {
@@ -109,6 +129,18 @@ func (hcc *baseHTTPConfigController) doLoop() {
 
 	logDebugf("HTTP Looper starting.")
 
+	// In flight config requests must be cancelled when we are stopped, otherwise stopping would block until the
+	// request times out.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-hcc.looperStopSig:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
 	for {
 		select {
 		case <-hcc.looperStopSig:
@@ -149,12 +181,19 @@ func (hcc *baseHTTPConfigController) doLoop() {
 		var doConfigRequest func(bool) int
 
 		doConfigRequest = func(is2x bool) int {
-			streamPath := "bs"
-			if is2x {
-				streamPath = "bucketsStreaming"
+			var uri string
+			if hcc.bucketName == "" {
+				// Without a bucket we stream the cluster level config, this contains the node services which is
+				// all that we need to connect to the KV nodes and start GCCCP polling.
+				uri = "/pools/default/nodeServicesStreaming"
+			} else {
+				streamPath := "bs"
+				if is2x {
+					streamPath = "bucketsStreaming"
+				}
+				uri = fmt.Sprintf("/pools/default/%s/%s", streamPath, url.PathEscape(hcc.bucketName))
 			}
 			// HTTP request time!
-			uri := fmt.Sprintf("/pools/default/%s/%s", streamPath, url.PathEscape(hcc.bucketName))
 			logDebugf("Requesting config from: %s/%s.", pickedSrv, uri)
 
 			req := &httpRequest{
@@ -164,11 +203,16 @@ func (hcc *baseHTTPConfigController) doLoop() {
 				Endpoint: pickedSrv,
 				UniqueID: uuid.New().String(),
 				Deadline: time.Now().Add(hcc.confHTTPMaxWait),
+				Context:  ctx,
 			}
 
 			var err error
 			resp, err = hcc.httpComponent.DoInternalHTTPRequest(req, true)
 			if err != nil {
+				if ctx.Err() != nil {
+					logDebugf("HTTP config request cancelled as looper is stopping.")
+					return -1
+				}
 				logWarnf("Failed to connect to host. %v", err)
 				hcc.setError(err)
 				return 0
@@ -184,6 +228,11 @@ func (hcc *baseHTTPConfigController) doLoop() {
 					hcc.setError(errAuthenticationFailure)
 					return -1
 				} else if resp.StatusCode == 404 {
+					if hcc.bucketName == "" {
+						logWarnf("Failed to connect to host, cluster config not available.")
+						hcc.setError(errCliInternalError)
+						return 0
+					}
 					if is2x {
 						logWarnf("Failed to connect to host, bad bucket.")
 						hcc.setError(errAuthenticationFailure)
@@ -228,10 +277,11 @@ func (hcc *baseHTTPConfigController) doLoop() {
 			}
 		}()
 
-		dec := json.NewDecoder(resp.Body)
+		body := resp.limitedBody()
+		dec := json.NewDecoder(body)
 		configBlock := new(configStreamBlock)
 		for {
-			err := dec.Decode(configBlock)
+			err := decodeConfigBlock(dec, body, configBlock)
 			if err != nil {
 				if atomic.LoadInt32(&autoDisconnected) == 1 {
 					// If we know we intentionally disconnected, we know we do not
@@ -2,6 +2,7 @@ package gocbcore
 
 import (
 	"fmt"
+	"hash/crc32"
 	"strings"
 	"testing"
 )
@@ -22,3 +23,40 @@ func TestGenerateIDs(t *testing.T) {
 		}
 	}
 }
+
+// cbCrcReference is the byte at a time table driven hash which the server documents for vbucket mapping.
+func cbCrcReference(key []byte) uint32 {
+	crc := uint32(0xffffffff)
+	for x := 0; x < len(key); x++ {
+		crc = (crc >> 8) ^ crc32.IEEETable[(uint64(crc)^uint64(key[x]))&0xff]
+	}
+	return (^crc) >> 16 & 0x7fff
+}
+
+func TestCbCrcMatchesReference(t *testing.T) {
+	keys := []string{"", "a", "key", "rangecollectionretry-9695", strings.Repeat("long-key-", 30)}
+	for i := 0; i < 1000; i++ {
+		keys = append(keys, fmt.Sprintf("key-%d", i))
+	}
+
+	for _, key := range keys {
+		if cbCrc([]byte(key)) != cbCrcReference([]byte(key)) {
+			t.Fatalf("hash of %s did not match reference", key)
+		}
+	}
+
+	// Known mapping used by the range scan tests.
+	if cbcVbMap([]byte("rangecollectionretry-9695"), 1024) != 12 {
+		t.Fatalf("key mapped to unexpected vbucket")
+	}
+}
+
+func BenchmarkCbCrc(b *testing.B) {
+	key := []byte("a-typical-document-key::0000000001")
+	b.SetBytes(int64(len(key)))
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		cbCrc(key)
+	}
+}
@@ -119,7 +119,8 @@ func (ccc *cccpConfigController) doLoop() error {
 		var numNodesSupportNotifs int
 		iter.Iterate(nodeIdx, func(pipeline *memdPipeline) bool {
 			nodeIdx = (nodeIdx + 1) % numNodes
-			if pipeline.SupportsFeature(memd.FeatureClustermapChangeNotificationBrief) {
+			if pipeline.SupportsFeature(memd.FeatureClustermapChangeNotificationBrief) ||
+				pipeline.SupportsFeature(memd.FeatureClusterMapNotif) {
 				numNodesSupportNotifs++
 				return false
 			}
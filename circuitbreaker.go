@@ -13,11 +13,41 @@ const (
 	circuitBreakerStateOpen
 )
 
+// CircuitBreakerState is the current state of a circuit breaker.
+// Volatile: This API is subject to change at any time.
+type CircuitBreakerState uint32
+
+const (
+	// CircuitBreakerStateDisabled indicates that circuit breaking is not enabled.
+	CircuitBreakerStateDisabled = CircuitBreakerState(circuitBreakerStateDisabled)
+
+	// CircuitBreakerStateClosed indicates that requests are flowing normally.
+	CircuitBreakerStateClosed = CircuitBreakerState(circuitBreakerStateClosed)
+
+	// CircuitBreakerStateHalfOpen indicates that a canary request is being sent to see if the circuit can be closed.
+	CircuitBreakerStateHalfOpen = CircuitBreakerState(circuitBreakerStateHalfOpen)
+
+	// CircuitBreakerStateOpen indicates that requests are being rejected.
+	CircuitBreakerStateOpen = CircuitBreakerState(circuitBreakerStateOpen)
+)
+
+// CircuitBreakerInfo describes the state of the circuit breaker for a single endpoint.
+// Volatile: This API is subject to change at any time.
+type CircuitBreakerInfo struct {
+	Service  ServiceType
+	Endpoint string
+	State    CircuitBreakerState
+	// Total and Failed are the number of operations, and failed operations, within the current rolling window.
+	Total  int64
+	Failed int64
+}
+
 type circuitBreaker interface {
 	AllowsRequest() bool
 	MarkSuccessful()
 	MarkFailure()
 	State() uint32
+	Counts() (total, failed int64)
 	Reset()
 	CanaryTimeout() time.Duration
 	CompletionCallback(error) bool
@@ -27,6 +57,11 @@ type circuitBreaker interface {
 // the circuit breaker failure count.
 type CircuitBreakerCallback func(error) bool
 
+// CircuitBreakerTripCallback is invoked whenever a circuit breaker opens, the info contains the counts which caused
+// the circuit to open.
+// Volatile: This API is subject to change at any time.
+type CircuitBreakerTripCallback func(CircuitBreakerInfo)
+
 // CircuitBreakerConfig is the set of configuration settings for configuring circuit breakers.
 // If Disabled is set to true then a noop circuit breaker will be used, otherwise a lazy circuit
 // breaker.
@@ -44,6 +79,21 @@ type CircuitBreakerConfig struct {
 	CompletionCallback CircuitBreakerCallback
 	// CanaryTimeout is the timeout for the canary request until it is deemed failed.
 	CanaryTimeout time.Duration
+	// TripCallback, if set, is called whenever a circuit breaker opens. It is invoked asynchronously, in its own
+	// goroutine.
+	// Volatile: This API is subject to change at any time.
+	TripCallback CircuitBreakerTripCallback
+}
+
+func newCircuitBreakerInfo(breaker circuitBreaker, service ServiceType, endpoint string) CircuitBreakerInfo {
+	total, failed := breaker.Counts()
+	return CircuitBreakerInfo{
+		Service:  service,
+		Endpoint: endpoint,
+		State:    CircuitBreakerState(breaker.State()),
+		Total:    total,
+		Failed:   failed,
+	}
 }
 
 type noopCircuitBreaker struct {
@@ -67,6 +117,10 @@ func (ncb *noopCircuitBreaker) State() uint32 {
 	return circuitBreakerStateDisabled
 }
 
+func (ncb *noopCircuitBreaker) Counts() (int64, int64) {
+	return 0, 0
+}
+
 func (ncb *noopCircuitBreaker) Reset() {
 }
 
@@ -90,10 +144,14 @@ type lazyCircuitBreaker struct {
 	openedAt                 int64
 	sendCanaryFn             func()
 	completionCallback       CircuitBreakerCallback
+	tripCallback             CircuitBreakerTripCallback
+	service                  ServiceType
+	endpoint                 string
 	state                    uint32
 }
 
-func newLazyCircuitBreaker(config CircuitBreakerConfig, canaryFn func()) *lazyCircuitBreaker {
+func newLazyCircuitBreaker(config CircuitBreakerConfig, service ServiceType, endpoint string,
+	canaryFn func()) *lazyCircuitBreaker {
 	if config.VolumeThreshold == 0 {
 		config.VolumeThreshold = 20
 	}
@@ -123,6 +181,9 @@ func newLazyCircuitBreaker(config CircuitBreakerConfig, canaryFn func()) *lazyCi
 		canaryTimeout:            config.CanaryTimeout,
 		sendCanaryFn:             canaryFn,
 		completionCallback:       config.CompletionCallback,
+		tripCallback:             config.TripCallback,
+		service:                  service,
+		endpoint:                 endpoint,
 	}
 	breaker.Reset()
 
@@ -142,6 +203,10 @@ func (lcb *lazyCircuitBreaker) State() uint32 {
 	return atomic.LoadUint32(&lcb.state)
 }
 
+func (lcb *lazyCircuitBreaker) Counts() (int64, int64) {
+	return atomic.LoadInt64(&lcb.total), atomic.LoadInt64(&lcb.failed)
+}
+
 func (lcb *lazyCircuitBreaker) AllowsRequest() bool {
 	state := lcb.State()
 	if state == circuitBreakerStateClosed {
@@ -194,11 +259,26 @@ func (lcb *lazyCircuitBreaker) maybeOpenCircuit() {
 		return
 	}
 
-	currentPercentage := (float64(atomic.LoadInt64(&lcb.failed)) / float64(atomic.LoadInt64(&lcb.total))) * 100
+	total, failed := lcb.Counts()
+	currentPercentage := (float64(failed) / float64(total)) * 100
 	if currentPercentage >= lcb.errorPercentageThreshold {
-		logDebugf("Moving circuit breaker to open")
-		atomic.StoreUint32(&lcb.state, circuitBreakerStateOpen)
 		atomic.StoreInt64(&lcb.openedAt, time.Now().UnixNano())
+		if !atomic.CompareAndSwapUint32(&lcb.state, circuitBreakerStateClosed, circuitBreakerStateOpen) {
+			return
+		}
+
+		logDebugf("Moving circuit breaker to open")
+		if lcb.tripCallback != nil {
+			// Failures are marked from the kv read loop so the callback is dispatched asynchronously, a slow callback
+			// must not hold up the processing of responses.
+			go lcb.tripCallback(CircuitBreakerInfo{
+				Service:  lcb.service,
+				Endpoint: lcb.endpoint,
+				State:    CircuitBreakerStateOpen,
+				Total:    total,
+				Failed:   failed,
+			})
+		}
 	}
 }
 
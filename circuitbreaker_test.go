@@ -1,8 +1,13 @@
 package gocbcore
 
 import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
 	"sync/atomic"
 	"time"
+	"unsafe"
 )
 
 func (suite *StandardTestSuite) TestLazyCircuitBreakerSuccessfulCanary() {
@@ -13,7 +18,7 @@ func (suite *StandardTestSuite) TestLazyCircuitBreakerSuccessfulCanary() {
 		ErrorThresholdPercentage: 60,
 		SleepWindow:              10 * time.Millisecond,
 		RollingWindow:            70 * time.Millisecond,
-	}, func() {
+	}, MemdService, "", func() {
 		atomic.StoreInt32(&canarySent, 1)
 		breaker.MarkSuccessful()
 	})
@@ -82,7 +87,7 @@ func (suite *StandardTestSuite) TestLazyCircuitBreakerFailedCanary() {
 		ErrorThresholdPercentage: 60,
 		SleepWindow:              10 * time.Millisecond,
 		RollingWindow:            70 * time.Millisecond,
-	}, func() {
+	}, MemdService, "", func() {
 		atomic.StoreInt32(&canarySent, 1)
 		breaker.MarkFailure()
 	})
@@ -151,7 +156,7 @@ func (suite *StandardTestSuite) TestLazyCircuitBreakerReset() {
 		ErrorThresholdPercentage: 60,
 		SleepWindow:              10 * time.Millisecond,
 		RollingWindow:            1 * time.Second,
-	}, func() {
+	}, MemdService, "", func() {
 		atomic.StoreInt32(&canarySent, 1)
 		breaker.MarkFailure()
 	})
@@ -209,3 +214,110 @@ func (suite *StandardTestSuite) TestLazyCircuitBreakerReset() {
 		suite.T().Fatalf("Circuit breaker should have allowed request")
 	}
 }
+
+func (suite *UnitTestSuite) TestLazyCircuitBreakerTripCallback() {
+	trips := make(chan CircuitBreakerInfo, 2)
+	breaker := newLazyCircuitBreaker(CircuitBreakerConfig{
+		VolumeThreshold:          2,
+		ErrorThresholdPercentage: 50,
+		SleepWindow:              time.Minute,
+		TripCallback: func(info CircuitBreakerInfo) {
+			trips <- info
+		},
+	}, FtsService, "http://localhost:8094", func() {})
+
+	breaker.MarkSuccessful()
+	breaker.MarkFailure()
+	breaker.MarkFailure()
+
+	select {
+	case trip := <-trips:
+		suite.Assert().Equal(CircuitBreakerInfo{
+			Service:  FtsService,
+			Endpoint: "http://localhost:8094",
+			State:    CircuitBreakerStateOpen,
+			Total:    2,
+			Failed:   1,
+		}, trip)
+	case <-time.After(time.Second):
+		suite.T().Fatalf("Trip callback was not invoked")
+	}
+	select {
+	case <-trips:
+		suite.T().Fatalf("Trip callback should only have been invoked once")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	info := newCircuitBreakerInfo(breaker, FtsService, "http://localhost:8094")
+	suite.Assert().Equal(CircuitBreakerStateOpen, info.State)
+	suite.Assert().Equal(int64(3), info.Total)
+	suite.Assert().Equal(int64(2), info.Failed)
+}
+
+func (suite *UnitTestSuite) TestHTTPComponentCircuitBreaker() {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	suite.Require().Nil(err, err)
+	endpoint := "http://" + listener.Addr().String()
+	// Close the listener so that requests to the endpoint fail to connect.
+	suite.Require().Nil(listener.Close())
+
+	var numTrips uint32
+	tripCh := make(chan struct{})
+	mux := &httpMux{
+		muxPtr: unsafe.Pointer(&httpClientMux{
+			n1qlEpList: []routeEndpoint{{Address: endpoint}},
+			revID:      1,
+			auth:       PasswordAuthProvider{Username: "user", Password: "pass"},
+		}),
+	}
+	tracer := newTracerComponent(&noopTracer{}, "", true, &noopMeter{}, nil)
+	hc := newHTTPComponentWithClient(httpComponentProps{}, &http.Client{}, mux, tracer)
+	hc.breakers = make(map[httpBreakerKey]*lazyCircuitBreaker)
+	hc.breakerCfgs = map[ServiceType]CircuitBreakerConfig{
+		N1qlService: {
+			Enabled:         true,
+			VolumeThreshold: 1,
+			SleepWindow:     time.Minute,
+			CompletionCallback: func(err error) bool {
+				return err == nil
+			},
+			TripCallback: func(info CircuitBreakerInfo) {
+				atomic.AddUint32(&numTrips, 1)
+				close(tripCh)
+			},
+		},
+	}
+
+	doRequest := func() error {
+		_, err := hc.DoInternalHTTPRequest(&httpRequest{
+			Service:       N1qlService,
+			Method:        "GET",
+			Path:          "/admin/ping",
+			Endpoint:      endpoint,
+			IsIdempotent:  true,
+			Deadline:      time.Now().Add(time.Second),
+			RetryStrategy: newFailFastRetryStrategy(),
+			Context:       context.Background(),
+		}, true)
+		return err
+	}
+
+	err = doRequest()
+	suite.Require().NotNil(err)
+	suite.Assert().False(errors.Is(err, ErrCircuitBreakerOpen), err)
+	select {
+	case <-tripCh:
+	case <-time.After(time.Second):
+		suite.T().Fatalf("Trip callback was not invoked")
+	}
+	suite.Assert().Equal(uint32(1), atomic.LoadUint32(&numTrips))
+
+	err = doRequest()
+	suite.Assert().True(errors.Is(err, ErrCircuitBreakerOpen), err)
+
+	states := hc.CircuitBreakerStates()
+	suite.Require().Len(states, 1)
+	suite.Assert().Equal(N1qlService, states[0].Service)
+	suite.Assert().Equal(endpoint, states[0].Endpoint)
+	suite.Assert().Equal(CircuitBreakerStateOpen, states[0].State)
+}
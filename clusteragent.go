@@ -32,7 +32,12 @@ func createClusterAgent(config *clusterAgentConfig) (*clusterAgent, error) {
 
 	c.tracer = newTracerComponent(config.TracerConfig.Tracer, "", config.TracerConfig.NoRootTraceSpans, config.MeterConfig.Meter, c)
 
-	tlsConfig, err := setupTLSConfig(config.SeedConfig.MemdAddrs, config.SecurityConfig)
+	tlsBaseConfig, err := config.SecurityConfig.tlsBaseConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	tlsConfig, err := setupTLSConfig(config.SeedConfig.MemdAddrs, config.SecurityConfig, tlsBaseConfig)
 	if err != nil {
 		return nil, err
 	}
@@ -78,8 +83,11 @@ func createClusterAgent(config *clusterAgentConfig) (*clusterAgent, error) {
 	)
 	c.http = newHTTPComponent(
 		httpComponentProps{
-			UserAgent:            userAgent,
-			DefaultRetryStrategy: c.defaultRetryStrategy,
+			UserAgent:             userAgent,
+			DefaultRetryStrategy:  c.defaultRetryStrategy,
+			CircuitBreakerConfigs: config.ServiceCircuitBreakerConfigs,
+			AuditHook:             config.HTTPConfig.AuditHook,
+			MaxResponseBodySize:   config.HTTPConfig.MaxResponseBodySize,
 		},
 		httpClientProps{
 			maxIdleConns:        config.HTTPConfig.MaxIdleConns,
@@ -13,8 +13,9 @@ type clusterAgentConfig struct {
 
 	MeterConfig MeterConfig
 
-	DefaultRetryStrategy RetryStrategy
-	CircuitBreakerConfig CircuitBreakerConfig
+	DefaultRetryStrategy         RetryStrategy
+	CircuitBreakerConfig         CircuitBreakerConfig
+	ServiceCircuitBreakerConfigs map[ServiceType]CircuitBreakerConfig
 }
 
 func (config *clusterAgentConfig) redacted() interface{} {
@@ -118,6 +118,56 @@ type GetAllCollectionManifestsOptions struct {
 	User string
 }
 
+// CreateScopeOptions are the options available to the CreateScope command.
+type CreateScopeOptions struct {
+	ScopeName     string
+	TraceContext  RequestSpanContext
+	RetryStrategy RetryStrategy
+	Deadline      time.Time
+
+	// Internal: This should never be used and is not supported.
+	User string
+}
+
+// DropScopeOptions are the options available to the DropScope command.
+type DropScopeOptions struct {
+	ScopeName     string
+	TraceContext  RequestSpanContext
+	RetryStrategy RetryStrategy
+	Deadline      time.Time
+
+	// Internal: This should never be used and is not supported.
+	User string
+}
+
+// CreateCollectionOptions are the options available to the CreateCollection command.
+type CreateCollectionOptions struct {
+	ScopeName      string
+	CollectionName string
+	// MaxTTL is the maximum expiry, in seconds, of documents in the collection. 0 uses the bucket default.
+	MaxTTL int32
+	// History sets whether history retention is enabled for the collection, nil uses the bucket default.
+	History       *bool
+	TraceContext  RequestSpanContext
+	RetryStrategy RetryStrategy
+	Deadline      time.Time
+
+	// Internal: This should never be used and is not supported.
+	User string
+}
+
+// DropCollectionOptions are the options available to the DropCollection command.
+type DropCollectionOptions struct {
+	ScopeName      string
+	CollectionName string
+	TraceContext   RequestSpanContext
+	RetryStrategy  RetryStrategy
+	Deadline       time.Time
+
+	// Internal: This should never be used and is not supported.
+	User string
+}
+
 // GetCollectionIDOptions are the options available to the GetCollectionID command.
 type GetCollectionIDOptions struct {
 	RetryStrategy RetryStrategy
@@ -128,6 +178,25 @@ type GetCollectionIDOptions struct {
 	User string
 }
 
+// ScopeAndCollection identifies a collection by its scope and collection names.
+type ScopeAndCollection struct {
+	ScopeName      string
+	CollectionName string
+}
+
+// GetAllCollectionIDsOptions are the options available to the GetAllCollectionIDs command.
+// Volatile: This API is subject to change at any time.
+type GetAllCollectionIDsOptions struct {
+	// Collections are the collections to resolve the ids of, empty names refer to the default scope or collection.
+	Collections   []ScopeAndCollection
+	RetryStrategy RetryStrategy
+	TraceContext  RequestSpanContext
+	Deadline      time.Time
+
+	// Internal: This should never be used and is not supported.
+	User string
+}
+
 // GetCollectionIDResult encapsulates the result of a GetCollectionID operation.
 type GetCollectionIDResult struct {
 	ManifestID   uint64
@@ -139,6 +208,24 @@ type GetCollectionIDResult struct {
 	}
 }
 
+// CollectionIDResult encapsulates the result of resolving a single collection within a GetAllCollectionIDs
+// operation. Error is set if the scope or collection does not exist in the manifest.
+// Volatile: This API is subject to change at any time.
+type CollectionIDResult struct {
+	ScopeName      string
+	CollectionName string
+	CollectionID   uint32
+	Error          error
+}
+
+// GetAllCollectionIDsResult encapsulates the result of a GetAllCollectionIDs operation. Collections are in the same
+// order as they were requested.
+// Volatile: This API is subject to change at any time.
+type GetAllCollectionIDsResult struct {
+	ManifestID  uint64
+	Collections []CollectionIDResult
+}
+
 // GetCollectionManifestResult encapsulates the result of a GetCollectionManifest operation.
 type GetCollectionManifestResult struct {
 	Manifest []byte
@@ -149,6 +236,13 @@ type GetCollectionManifestResult struct {
 	}
 }
 
+// ManifestChangeResult encapsulates the result of an operation which modifies the collections manifest.
+type ManifestChangeResult struct {
+	// ManifestUID is the uid of the manifest containing the change. Nodes apply the new manifest asynchronously so
+	// it may not yet be visible to KV operations.
+	ManifestUID uint64
+}
+
 // SingleServerManifestResult encapsulates the result from a single server when using the GetAllCollectionManifests
 // operation.
 type SingleServerManifestResult struct {
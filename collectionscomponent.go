@@ -104,9 +104,49 @@ func (cidMgr *collectionsComponent) handleOpRoutingResp(resp *memdQResponse, req
 		}
 	}
 
+	cidMgr.enrichKvError(err)
+
 	return false, err
 }
 
+// enrichKvError fills in the scope and collection names of a KeyValueError from the cache when the operation was
+// performed using only a collection ID.
+func (cidMgr *collectionsComponent) enrichKvError(err error) {
+	var kvErr *KeyValueError
+	if !errors.As(err, &kvErr) {
+		return
+	}
+	if kvErr.ScopeName != "" || kvErr.CollectionName != "" || kvErr.CollectionID == 0 {
+		return
+	}
+
+	scopeName, collectionName, ok := cidMgr.collectionNamesForID(kvErr.CollectionID)
+	if !ok {
+		return
+	}
+
+	kvErr.ScopeName = scopeName
+	kvErr.CollectionName = collectionName
+}
+
+// collectionNamesForID looks up the scope and collection names which are cached against a collection ID.
+func (cidMgr *collectionsComponent) collectionNamesForID(id uint32) (string, string, bool) {
+	cidMgr.mapLock.Lock()
+	defer cidMgr.mapLock.Unlock()
+
+	for _, cidCache := range cidMgr.idMap {
+		cidCache.lock.Lock()
+		cacheID := cidCache.id
+		cidCache.lock.Unlock()
+
+		if cacheID == id {
+			return cidCache.scopeName, cidCache.collectionName, true
+		}
+	}
+
+	return "", "", false
+}
+
 func (cidMgr *collectionsComponent) GetCollectionManifest(opts GetCollectionManifestOptions, cb GetCollectionManifestCallback) (PendingOp, error) {
 	tracer := cidMgr.tracer.StartTelemeteryHandler(metricValueServiceAnalyticsValue, "GetCollectionManifest", opts.TraceContext)
 
@@ -290,8 +330,21 @@ func (cidMgr *collectionsComponent) GetCollectionID(scopeName string, collection
 	cb GetCollectionIDCallback) (PendingOp, error) {
 	tracer := cidMgr.tracer.StartTelemeteryHandler(metricValueServiceAnalyticsValue, "GetCollectionID", opts.TraceContext)
 
+	keyScopeName, keyCollectionName, err := normalizeCollectionNames(scopeName, collectionName)
+	if err != nil {
+		tracer.Finish()
+		return nil, err
+	}
+
 	handler := func(resp *memdQResponse, req *memdQRequest, err error) {
 		if err != nil {
+			// The request itself is not against a collection so the error won't say which one it refers to.
+			var kvErr *KeyValueError
+			if errors.As(err, &kvErr) {
+				kvErr.ScopeName = keyScopeName
+				kvErr.CollectionName = keyCollectionName
+			}
+
 			tracer.Finish()
 			cb(nil, err)
 			return
@@ -300,7 +353,7 @@ func (cidMgr *collectionsComponent) GetCollectionID(scopeName string, collection
 		manifestID := binary.BigEndian.Uint64(resp.Extras[0:])
 		collectionID := binary.BigEndian.Uint32(resp.Extras[8:])
 
-		cidMgr.upsert(scopeName, collectionName, collectionID)
+		cidMgr.upsert(keyScopeName, keyCollectionName, collectionID)
 
 		res := GetCollectionIDResult{
 			ManifestID:   manifestID,
@@ -316,15 +369,6 @@ func (cidMgr *collectionsComponent) GetCollectionID(scopeName string, collection
 		opts.RetryStrategy = cidMgr.defaultRetryStrategy
 	}
 
-	keyScopeName := scopeName
-	if keyScopeName == "" {
-		keyScopeName = "_default"
-	}
-	keyCollectionName := collectionName
-	if keyCollectionName == "" {
-		keyCollectionName = "_default"
-	}
-
 	var userFrame *memd.UserImpersonationFrame
 	if len(opts.User) > 0 {
 		userFrame = &memd.UserImpersonationFrame{
@@ -379,6 +423,74 @@ func (cidMgr *collectionsComponent) GetCollectionID(scopeName string, collection
 	return op, nil
 }
 
+// GetAllCollectionIDs resolves the ids of many collections from a single collections manifest.
+func (cidMgr *collectionsComponent) GetAllCollectionIDs(opts GetAllCollectionIDsOptions,
+	cb GetAllCollectionIDsCallback) (PendingOp, error) {
+	collections := make([]ScopeAndCollection, len(opts.Collections))
+	for i, collection := range opts.Collections {
+		scopeName, collectionName, err := normalizeCollectionNames(collection.ScopeName, collection.CollectionName)
+		if err != nil {
+			return nil, err
+		}
+
+		collections[i] = ScopeAndCollection{
+			ScopeName:      scopeName,
+			CollectionName: collectionName,
+		}
+	}
+
+	return cidMgr.GetCollectionManifest(GetCollectionManifestOptions{
+		TraceContext:  opts.TraceContext,
+		RetryStrategy: opts.RetryStrategy,
+		Deadline:      opts.Deadline,
+		User:          opts.User,
+	}, func(result *GetCollectionManifestResult, err error) {
+		if err != nil {
+			cb(nil, err)
+			return
+		}
+
+		var manifest Manifest
+		if err := json.Unmarshal(result.Manifest, &manifest); err != nil {
+			cb(nil, wrapError(err, "failed to parse collections manifest"))
+			return
+		}
+
+		ids := make(map[string]uint32)
+		scopes := make(map[string]struct{})
+		for _, scope := range manifest.Scopes {
+			scopes[scope.Name] = struct{}{}
+			for _, collection := range scope.Collections {
+				ids[cidMgr.createKey(scope.Name, collection.Name)] = collection.UID
+			}
+		}
+
+		res := GetAllCollectionIDsResult{
+			ManifestID:  manifest.UID,
+			Collections: make([]CollectionIDResult, len(collections)),
+		}
+		for i, collection := range collections {
+			entry := CollectionIDResult{
+				ScopeName:      collection.ScopeName,
+				CollectionName: collection.CollectionName,
+			}
+
+			if id, ok := ids[cidMgr.createKey(collection.ScopeName, collection.CollectionName)]; ok {
+				entry.CollectionID = id
+				cidMgr.upsert(collection.ScopeName, collection.CollectionName, id)
+			} else if _, ok := scopes[collection.ScopeName]; !ok {
+				entry.Error = errScopeNotFound
+			} else {
+				entry.Error = errCollectionNotFound
+			}
+
+			res.Collections[i] = entry
+		}
+
+		cb(&res, nil)
+	})
+}
+
 func (cidMgr *collectionsComponent) upsert(scopeName, collectionName string, value uint32) *collectionIDCache {
 	cidMgr.mapLock.Lock()
 	id, ok := cidMgr.idMap[cidMgr.createKey(scopeName, collectionName)]
@@ -581,6 +693,17 @@ func (cid *collectionIDCache) dispatch(req *memdQRequest) error {
 }
 
 func (cidMgr *collectionsComponent) Dispatch(req *memdQRequest) (PendingOp, error) {
+	scopeName, collectionName, err := normalizeCollectionNames(req.ScopeName, req.CollectionName)
+	if err != nil {
+		return nil, err
+	}
+	// When the collection ID is provided the names are informational only, so we don't fill them in as we don't know
+	// that the ID refers to the default collection.
+	if req.CollectionID == 0 {
+		req.ScopeName = scopeName
+		req.CollectionName = collectionName
+	}
+
 	isDefaultCollectionName := isDefaultCollection(req.ScopeName, req.CollectionName)
 	collectionIDPresent := req.CollectionID > 0
 
@@ -616,7 +739,7 @@ func (cidMgr *collectionsComponent) Dispatch(req *memdQRequest) (PendingOp, erro
 	}
 
 	cidCache := cidMgr.getAndMaybeInsert(req.ScopeName, req.CollectionName, unknownCid)
-	err := cidCache.dispatch(req)
+	err = cidCache.dispatch(req)
 	if err != nil {
 		return nil, err
 	}
@@ -628,7 +751,14 @@ func (cidMgr *collectionsComponent) requeue(req *memdQRequest) {
 	cidCache := cidMgr.getAndMaybeInsert(req.ScopeName, req.CollectionName, unknownCid)
 	cidCache.lock.Lock()
 	if cidCache.id != unknownCid && cidCache.id != pendingCid {
-		cidCache.setID(unknownCid)
+		// If the request was sent with a different id to the one that we have cached then the cache has already been
+		// refreshed since the request was dispatched, so we just retry with the new id rather than refreshing again.
+		if req.CollectionID == 0 || req.CollectionID == cidCache.id {
+			cidCache.setID(unknownCid)
+		} else {
+			logDebugf("Collection %s.%s already refreshed from %d to %d, retrying request", req.ScopeName,
+				req.CollectionName, req.CollectionID, cidCache.id)
+		}
 	}
 	cidCache.lock.Unlock()
 
@@ -675,3 +805,50 @@ func canRetryOnCollectionUnknown(req *memdQRequest) bool {
 func isDefaultCollection(scopeName, collectionName string) bool {
 	return (collectionName == "" || collectionName == "_default") && (scopeName == "" || scopeName == "_default")
 }
+
+// maxCollectionNameLength is the longest scope or collection name which the server accepts.
+const maxCollectionNameLength = 251
+
+// normalizeCollectionNames validates the scope and collection names given in an operation's options, returning them
+// with any which were left empty defaulted to _default.
+func normalizeCollectionNames(scopeName, collectionName string) (string, string, error) {
+	if scopeName == "" {
+		scopeName = "_default"
+	} else if err := validateCollectionName("ScopeName", scopeName); err != nil {
+		return "", "", err
+	}
+
+	if collectionName == "" {
+		collectionName = "_default"
+	} else if err := validateCollectionName("CollectionName", collectionName); err != nil {
+		return "", "", err
+	}
+
+	return scopeName, collectionName, nil
+}
+
+// validateCollectionName checks that a scope or collection name could be valid, the field is the name of the option
+// which the name was supplied in and is used in the returned error.
+func validateCollectionName(field, name string) error {
+	if name == "" {
+		return wrapError(errInvalidArgument, fmt.Sprintf("%s cannot be empty", field))
+	}
+
+	if len(name) > maxCollectionNameLength {
+		return wrapError(errInvalidArgument, fmt.Sprintf("%s cannot be longer than %d characters",
+			field, maxCollectionNameLength))
+	}
+
+	if name[0] == '%' {
+		return wrapError(errInvalidArgument, fmt.Sprintf("%s cannot start with %%", field))
+	}
+
+	for _, c := range name {
+		if (c < 'a' || c > 'z') && (c < 'A' || c > 'Z') && (c < '0' || c > '9') && c != '_' && c != '-' && c != '%' {
+			return wrapError(errInvalidArgument, fmt.Sprintf("%s %q contains an invalid character, only "+
+				"A-Z, a-z, 0-9, _, - and %% are allowed", field, name))
+		}
+	}
+
+	return nil
+}
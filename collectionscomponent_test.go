@@ -4,6 +4,7 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
@@ -928,3 +929,270 @@ func (suite *UnitTestSuite) TestCollectionsComponentCollectionsSupportedCollecti
 	cfgMgr.AssertExpectations(suite.T())
 	dispatcher.AssertExpectations(suite.T())
 }
+
+// This test is for the scenario when a request fails with collection unknown but the collection id cache has already
+// been refreshed since the request was sent, as happens when many requests are in flight during a manifest change.
+// We should see the request retried with the refreshed id rather than the cache being invalidated again.
+func (suite *UnitTestSuite) TestCollectionsComponentRequeueAlreadyRefreshed() {
+	cName := "test"
+	sName := "_default"
+
+	cfgMgr := new(mockConfigManager)
+	cfgMgr.On("AddConfigWatcher", mock.Anything).Return()
+
+	dispatcher := new(mockDispatcher)
+	dispatcher.On("SetPostCompleteErrorHandler", mock.AnythingOfType("gocbcore.postCompleteErrorHandler")).Return()
+	dispatcher.On("DispatchDirect", mock.AnythingOfType("*gocbcore.memdQRequest")).Return(&memdQRequest{}, nil).
+		Run(func(args mock.Arguments) {
+			req := args[0].(*memdQRequest)
+
+			suite.Assert().Equal(memd.CmdGet, req.Command)
+			suite.Assert().Equal(uint32(9), req.CollectionID)
+
+			time.AfterFunc(time.Millisecond, func() {
+				req.Callback(&memdQResponse{Packet: &memd.Packet{Value: []byte("test")}}, req, nil)
+			})
+		}).Once()
+
+	cidMgr := newCollectionIDManager(collectionIDProps{
+		DefaultRetryStrategy: &failFastRetryStrategy{},
+		MaxQueueSize:         100},
+		dispatcher,
+		newTracerComponent(&noopTracer{}, "", true, &noopMeter{}, cfgMgr),
+		cfgMgr,
+	)
+	cidMgr.configSeen = 1
+	cidMgr.upsert(sName, cName, 9)
+
+	waitCh := make(chan error, 1)
+	cidMgr.requeue(&memdQRequest{
+		Packet: memd.Packet{
+			Magic:        memd.CmdMagicReq,
+			Command:      memd.CmdGet,
+			Key:          []byte("test-key"),
+			CollectionID: 8,
+		},
+		CollectionName: cName,
+		ScopeName:      sName,
+		Callback: func(resp *memdQResponse, req *memdQRequest, err error) {
+			waitCh <- err
+		},
+		RootTraceContext: noopSpanContext{},
+	})
+
+	select {
+	case <-time.After(1 * time.Second):
+		suite.T().Fatalf("Timed out waiting for callback to be called")
+	case err := <-waitCh:
+		suite.Assert().Nil(err, err)
+	}
+
+	cfgMgr.AssertExpectations(suite.T())
+	dispatcher.AssertExpectations(suite.T())
+}
+
+func (suite *UnitTestSuite) TestCollectionsComponentEnrichKvError() {
+	cfgMgr := new(mockConfigManager)
+	cfgMgr.On("AddConfigWatcher", mock.Anything).Return()
+
+	dispatcher := new(mockDispatcher)
+	dispatcher.On("SetPostCompleteErrorHandler", mock.AnythingOfType("gocbcore.postCompleteErrorHandler")).Return()
+
+	cidMgr := newCollectionIDManager(collectionIDProps{
+		MaxQueueSize:         100,
+		DefaultRetryStrategy: &failFastRetryStrategy{},
+	}, dispatcher, newTracerComponent(&noopTracer{}, "", true, &noopMeter{}, cfgMgr), cfgMgr)
+	cidMgr.upsert("inventory", "airline", 9)
+
+	kvErr := &KeyValueError{
+		InnerError:   errDocumentNotFound,
+		DocumentKey:  "key",
+		CollectionID: 9,
+	}
+	retry, err := cidMgr.handleOpRoutingResp(nil, &memdQRequest{}, kvErr)
+	suite.Require().False(retry)
+	suite.Require().Equal(kvErr, err)
+
+	suite.Assert().Equal("inventory", kvErr.ScopeName)
+	suite.Assert().Equal("airline", kvErr.CollectionName)
+	suite.Assert().Equal(uint32(9), kvErr.CollectionID)
+
+	unknownErr := &KeyValueError{
+		InnerError:   errDocumentNotFound,
+		DocumentKey:  "key",
+		CollectionID: 10,
+	}
+	cidMgr.enrichKvError(unknownErr)
+
+	suite.Assert().Empty(unknownErr.ScopeName)
+	suite.Assert().Empty(unknownErr.CollectionName)
+}
+
+func (suite *UnitTestSuite) TestNormalizeCollectionNames() {
+	type tCase struct {
+		name               string
+		scopeName          string
+		collectionName     string
+		expectedScope      string
+		expectedCollection string
+		expectedErrField   string
+	}
+
+	testCases := []tCase{
+		{name: "empty", expectedScope: "_default", expectedCollection: "_default"},
+		{name: "scope only", scopeName: "inventory", expectedScope: "inventory", expectedCollection: "_default"},
+		{name: "collection only", collectionName: "airline", expectedScope: "_default", expectedCollection: "airline"},
+		{name: "both", scopeName: "inventory", collectionName: "air-line_1%", expectedScope: "inventory",
+			expectedCollection: "air-line_1%"},
+		{name: "system scope", scopeName: "_system", collectionName: "_mobile", expectedScope: "_system",
+			expectedCollection: "_mobile"},
+		{name: "invalid scope character", scopeName: "inv.entory", collectionName: "airline", expectedErrField: "ScopeName"},
+		{name: "invalid collection character", scopeName: "inventory", collectionName: "air line",
+			expectedErrField: "CollectionName"},
+		{name: "leading percent", collectionName: "%airline", expectedErrField: "CollectionName"},
+		{name: "too long", scopeName: strings.Repeat("a", maxCollectionNameLength+1), expectedErrField: "ScopeName"},
+	}
+
+	for _, tCase := range testCases {
+		suite.Run(tCase.name, func() {
+			scopeName, collectionName, err := normalizeCollectionNames(tCase.scopeName, tCase.collectionName)
+			if tCase.expectedErrField != "" {
+				suite.Require().ErrorIs(err, ErrInvalidArgument)
+				suite.Assert().Contains(err.Error(), tCase.expectedErrField)
+				return
+			}
+
+			suite.Require().Nil(err, err)
+			suite.Assert().Equal(tCase.expectedScope, scopeName)
+			suite.Assert().Equal(tCase.expectedCollection, collectionName)
+		})
+	}
+
+	suite.Assert().Nil(validateCollectionName("ScopeName", strings.Repeat("a", maxCollectionNameLength)))
+	suite.Assert().ErrorIs(validateCollectionName("ScopeName", ""), ErrInvalidArgument)
+}
+
+func (suite *UnitTestSuite) TestCollectionsComponentDispatchNormalizesNames() {
+	cfgMgr := new(mockConfigManager)
+	cfgMgr.On("AddConfigWatcher", mock.Anything).Return()
+
+	dispatcher := new(mockDispatcher)
+	dispatcher.On("SetPostCompleteErrorHandler", mock.AnythingOfType("gocbcore.postCompleteErrorHandler")).Return()
+	dispatcher.On("CollectionsEnabled").Return(true)
+	dispatcher.On("DispatchDirect", mock.AnythingOfType("*gocbcore.memdQRequest")).Return(&memdQRequest{}, nil)
+
+	cidMgr := newCollectionIDManager(collectionIDProps{
+		DefaultRetryStrategy: &failFastRetryStrategy{},
+		MaxQueueSize:         100},
+		dispatcher,
+		newTracerComponent(&noopTracer{}, "", true, &noopMeter{}, cfgMgr),
+		cfgMgr,
+	)
+	cidMgr.configSeen = 1
+
+	newReq := func(scopeName, collectionName string, collectionID uint32) *memdQRequest {
+		return &memdQRequest{
+			Packet: memd.Packet{
+				Magic:        memd.CmdMagicReq,
+				Command:      memd.CmdGet,
+				Key:          []byte("test-key"),
+				CollectionID: collectionID,
+			},
+			ScopeName:      scopeName,
+			CollectionName: collectionName,
+		}
+	}
+
+	req := newReq("", "", 0)
+	_, err := cidMgr.Dispatch(req)
+	suite.Require().Nil(err, err)
+	suite.Assert().Equal("_default", req.ScopeName)
+	suite.Assert().Equal("_default", req.CollectionName)
+
+	// Names aren't filled in when the collection ID is provided, we don't know that it refers to the default collection.
+	req = newReq("", "", 8)
+	_, err = cidMgr.Dispatch(req)
+	suite.Require().Nil(err, err)
+	suite.Assert().Empty(req.ScopeName)
+	suite.Assert().Empty(req.CollectionName)
+
+	_, err = cidMgr.Dispatch(newReq("inventory", "air line", 0))
+	suite.Require().ErrorIs(err, ErrInvalidArgument)
+	suite.Assert().Contains(err.Error(), "CollectionName")
+
+	dispatcher.AssertNumberOfCalls(suite.T(), "DispatchDirect", 2)
+}
+
+func (suite *UnitTestSuite) TestCollectionsComponentGetAllCollectionIDs() {
+	manifest := []byte(`{"uid":"1f","scopes":[` +
+		`{"uid":"0","name":"_default","collections":[{"uid":"0","name":"_default"}]},` +
+		`{"uid":"8","name":"inventory","collections":[{"uid":"9","name":"airline"},{"uid":"a","name":"hotel"}]}]}`)
+
+	cfgMgr := new(mockConfigManager)
+	cfgMgr.On("AddConfigWatcher", mock.Anything).Return()
+
+	dispatcher := new(mockDispatcher)
+	dispatcher.On("SetPostCompleteErrorHandler", mock.AnythingOfType("gocbcore.postCompleteErrorHandler")).Return()
+	dispatcher.On("DispatchDirect", mock.AnythingOfType("*gocbcore.memdQRequest")).Return(&memdQRequest{}, nil).
+		Run(func(args mock.Arguments) {
+			req := args[0].(*memdQRequest)
+
+			suite.Assert().Equal(memd.CmdCollectionsGetManifest, req.Command)
+
+			time.AfterFunc(time.Millisecond, func() {
+				req.Callback(&memdQResponse{Packet: &memd.Packet{Value: manifest}}, req, nil)
+			})
+		})
+
+	cidMgr := newCollectionIDManager(collectionIDProps{
+		DefaultRetryStrategy: &failFastRetryStrategy{},
+		MaxQueueSize:         100},
+		dispatcher,
+		newTracerComponent(&noopTracer{}, "", true, &noopMeter{}, cfgMgr),
+		cfgMgr,
+	)
+
+	type result struct {
+		res *GetAllCollectionIDsResult
+		err error
+	}
+	waitCh := make(chan result, 1)
+	_, err := cidMgr.GetAllCollectionIDs(GetAllCollectionIDsOptions{
+		Collections: []ScopeAndCollection{
+			{ScopeName: "inventory", CollectionName: "hotel"},
+			{},
+			{ScopeName: "inventory", CollectionName: "route"},
+			{ScopeName: "tenant", CollectionName: "users"},
+			{ScopeName: "inventory", CollectionName: "airline"},
+		},
+	}, func(res *GetAllCollectionIDsResult, err error) {
+		waitCh <- result{res: res, err: err}
+	})
+	suite.Require().Nil(err, err)
+
+	res := <-waitCh
+	suite.Require().Nil(res.err, res.err)
+	suite.Assert().Equal(uint64(0x1f), res.res.ManifestID)
+	suite.Require().Len(res.res.Collections, 5)
+
+	suite.Assert().Equal(CollectionIDResult{ScopeName: "inventory", CollectionName: "hotel", CollectionID: 0xa},
+		res.res.Collections[0])
+	suite.Assert().Equal(CollectionIDResult{ScopeName: "_default", CollectionName: "_default"}, res.res.Collections[1])
+	suite.Assert().ErrorIs(res.res.Collections[2].Error, ErrCollectionNotFound)
+	suite.Assert().ErrorIs(res.res.Collections[3].Error, ErrScopeNotFound)
+	suite.Assert().Equal(uint32(9), res.res.Collections[4].CollectionID)
+	suite.Assert().Nil(res.res.Collections[4].Error)
+
+	// Only the collections which exist should have been cached.
+	suite.Assert().Len(cidMgr.idMap, 3)
+	suite.Assert().Equal(uint32(0xa), cidMgr.idMap[cidMgr.createKey("inventory", "hotel")].id)
+	suite.Assert().Equal(uint32(9), cidMgr.idMap[cidMgr.createKey("inventory", "airline")].id)
+
+	_, err = cidMgr.GetAllCollectionIDs(GetAllCollectionIDsOptions{
+		Collections: []ScopeAndCollection{{ScopeName: "inventory", CollectionName: "air line"}},
+	}, func(res *GetAllCollectionIDsResult, err error) {
+		suite.T().Fatalf("Callback should not have been called")
+	})
+	suite.Assert().ErrorIs(err, ErrInvalidArgument)
+	dispatcher.AssertNumberOfCalls(suite.T(), "DispatchDirect", 1)
+}
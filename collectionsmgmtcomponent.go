@@ -0,0 +1,225 @@
+package gocbcore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// collectionsMgmtComponent creates and drops scopes and collections. The memcached protocol provides no way to modify
+// the collections manifest so these operations are performed against the management service.
+type collectionsMgmtComponent struct {
+	httpComponent        *httpComponent
+	tracer               *tracerComponent
+	bucketName           string
+	defaultRetryStrategy RetryStrategy
+}
+
+func newCollectionsMgmtComponent(httpComponent *httpComponent, tracer *tracerComponent, bucketName string,
+	defaultRetryStrategy RetryStrategy) *collectionsMgmtComponent {
+	return &collectionsMgmtComponent{
+		httpComponent:        httpComponent,
+		tracer:               tracer,
+		bucketName:           bucketName,
+		defaultRetryStrategy: defaultRetryStrategy,
+	}
+}
+
+type jsonManifestChangeResponse struct {
+	UID string `json:"uid"`
+}
+
+// parseManifestChangeError maps a failed management response to the most appropriate error. The management service
+// only reports the cause of a failure as text so the error is determined from the message.
+func parseManifestChangeError(req *httpRequest, resp *HTTPResponse) error {
+	var errMsg string
+	respBody, readErr := resp.readBody()
+	if readErr == nil {
+		errMsg = string(respBody)
+	}
+	lowerMsg := strings.ToLower(errMsg)
+
+	var err error
+	switch {
+	case resp.StatusCode == 401 || resp.StatusCode == 403:
+		err = errAuthenticationFailure
+	case resp.StatusCode == 500:
+		err = errInternalServerFailure
+	case strings.Contains(lowerMsg, "scope with") && strings.Contains(lowerMsg, "already exists"):
+		err = errScopeExists
+	case strings.Contains(lowerMsg, "collection with") && strings.Contains(lowerMsg, "already exists"):
+		err = errCollectionExists
+	case strings.Contains(lowerMsg, "scope with") && strings.Contains(lowerMsg, "not found"):
+		err = errScopeNotFound
+	case strings.Contains(lowerMsg, "collection with") && strings.Contains(lowerMsg, "not found"):
+		err = errCollectionNotFound
+	case strings.Contains(lowerMsg, "requested resource not found"):
+		err = errBucketNotFound
+	default:
+		err = fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+
+	if errMsg != "" {
+		err = wrapError(err, errMsg)
+	}
+
+	return wrapHTTPError(req, err)
+}
+
+func (cmc *collectionsMgmtComponent) CreateScope(opts CreateScopeOptions, cb ManifestChangeCallback) (PendingOp, error) {
+	if err := validateCollectionName("ScopeName", opts.ScopeName); err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Add("name", opts.ScopeName)
+
+	return cmc.manifestChange("CreateScope", "POST", "/scopes", form, opts.RetryStrategy, opts.Deadline,
+		opts.User, opts.TraceContext, cb)
+}
+
+func (cmc *collectionsMgmtComponent) DropScope(opts DropScopeOptions, cb ManifestChangeCallback) (PendingOp, error) {
+	if err := validateCollectionName("ScopeName", opts.ScopeName); err != nil {
+		return nil, err
+	}
+
+	path := fmt.Sprintf("/scopes/%s", url.PathEscape(opts.ScopeName))
+
+	return cmc.manifestChange("DropScope", "DELETE", path, nil, opts.RetryStrategy, opts.Deadline,
+		opts.User, opts.TraceContext, cb)
+}
+
+func (cmc *collectionsMgmtComponent) CreateCollection(opts CreateCollectionOptions, cb ManifestChangeCallback) (PendingOp, error) {
+	if err := validateCollectionName("ScopeName", opts.ScopeName); err != nil {
+		return nil, err
+	}
+	if err := validateCollectionName("CollectionName", opts.CollectionName); err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Add("name", opts.CollectionName)
+	if opts.MaxTTL != 0 {
+		form.Add("maxTTL", strconv.FormatInt(int64(opts.MaxTTL), 10))
+	}
+	if opts.History != nil {
+		form.Add("history", strconv.FormatBool(*opts.History))
+	}
+
+	path := fmt.Sprintf("/scopes/%s/collections", url.PathEscape(opts.ScopeName))
+
+	return cmc.manifestChange("CreateCollection", "POST", path, form, opts.RetryStrategy, opts.Deadline,
+		opts.User, opts.TraceContext, cb)
+}
+
+func (cmc *collectionsMgmtComponent) DropCollection(opts DropCollectionOptions, cb ManifestChangeCallback) (PendingOp, error) {
+	if err := validateCollectionName("ScopeName", opts.ScopeName); err != nil {
+		return nil, err
+	}
+	if err := validateCollectionName("CollectionName", opts.CollectionName); err != nil {
+		return nil, err
+	}
+
+	path := fmt.Sprintf("/scopes/%s/collections/%s", url.PathEscape(opts.ScopeName),
+		url.PathEscape(opts.CollectionName))
+
+	return cmc.manifestChange("DropCollection", "DELETE", path, nil, opts.RetryStrategy, opts.Deadline,
+		opts.User, opts.TraceContext, cb)
+}
+
+func (cmc *collectionsMgmtComponent) manifestChange(opName, method, path string, form url.Values,
+	retryStrategy RetryStrategy, deadline time.Time, user string, traceContext RequestSpanContext,
+	cb ManifestChangeCallback) (PendingOp, error) {
+	if cmc.bucketName == "" {
+		return nil, wrapError(errInvalidArgument, "collections management requires a bucket")
+	}
+
+	tracer := cmc.tracer.StartTelemeteryHandler(metricValueServiceManagementValue, opName, traceContext)
+
+	if retryStrategy == nil {
+		retryStrategy = cmc.defaultRetryStrategy
+	}
+
+	var body []byte
+	var contentType string
+	if form != nil {
+		body = []byte(form.Encode())
+		contentType = "application/x-www-form-urlencoded"
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ireq := &httpRequest{
+		Service:          MgmtService,
+		Method:           method,
+		Path:             fmt.Sprintf("/pools/default/buckets/%s%s", url.PathEscape(cmc.bucketName), path),
+		Body:             body,
+		ContentType:      contentType,
+		IsIdempotent:     false,
+		Deadline:         deadline,
+		RetryStrategy:    retryStrategy,
+		RootTraceContext: tracer.RootContext(),
+		Context:          ctx,
+		CancelFunc:       cancel,
+		User:             user,
+	}
+
+	go func() {
+		res, err := cmc.doManifestChange(ireq)
+		err = ireq.complete(err, func() error {
+			return nil
+		})
+		if err != nil {
+			cancel()
+			tracer.FinishWithError(err)
+			cb(nil, err)
+			return
+		}
+
+		tracer.Finish()
+		cb(res, nil)
+	}()
+
+	return ireq, nil
+}
+
+func (cmc *collectionsMgmtComponent) doManifestChange(ireq *httpRequest) (*ManifestChangeResult, error) {
+	resp, err := cmc.httpComponent.DoInternalHTTPRequest(ireq, false)
+	if err != nil {
+		if errors.Is(err, ErrRequestCanceled) {
+			return nil, err
+		}
+		return nil, wrapHTTPError(ireq, err)
+	}
+	defer func() {
+		err := resp.Body.Close()
+		if err != nil {
+			logDebugf("Failed to close socket (%s)", err)
+		}
+	}()
+
+	if resp.StatusCode != 200 {
+		return nil, parseManifestChangeError(ireq, resp)
+	}
+
+	var respParse jsonManifestChangeResponse
+	if err := json.NewDecoder(resp.limitedBody()).Decode(&respParse); err != nil {
+		if errors.Is(err, ErrHTTPResponseTooLarge) {
+			return nil, wrapHTTPError(ireq, err)
+		}
+		return nil, wrapHTTPError(ireq, wrapError(errProtocol, "failed to parse manifest change response"))
+	}
+
+	uid, err := strconv.ParseUint(respParse.UID, 16, 64)
+	if err != nil {
+		return nil, wrapHTTPError(ireq, wrapError(errProtocol, "failed to parse manifest uid"))
+	}
+
+	return &ManifestChangeResult{
+		ManifestUID: uid,
+	}, nil
+}
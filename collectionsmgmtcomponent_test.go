@@ -0,0 +1,70 @@
+package gocbcore
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+)
+
+func (suite *UnitTestSuite) TestParseManifestChangeError() {
+	type test struct {
+		name       string
+		statusCode int
+		body       string
+		expected   error
+	}
+
+	tests := []test{
+		{
+			name:       "ScopeExists",
+			statusCode: 400,
+			body:       `{"errors":{"name":"Scope with name \"inventory\" already exists"}}`,
+			expected:   ErrScopeExists,
+		},
+		{
+			name:       "CollectionExists",
+			statusCode: 400,
+			body:       `{"errors":{"name":"Collection with name \"airline\" in scope \"inventory\" already exists"}}`,
+			expected:   ErrCollectionExists,
+		},
+		{
+			name:       "ScopeNotFound",
+			statusCode: 404,
+			body:       `{"errors":{"_":"Scope with name \"inventory\" is not found"}}`,
+			expected:   ErrScopeNotFound,
+		},
+		{
+			name:       "CollectionNotFound",
+			statusCode: 404,
+			body:       `{"errors":{"_":"Collection with name \"airline\" in scope \"inventory\" is not found"}}`,
+			expected:   ErrCollectionNotFound,
+		},
+		{
+			name:       "BucketNotFound",
+			statusCode: 404,
+			body:       `Requested resource not found.`,
+			expected:   ErrBucketNotFound,
+		},
+		{
+			name:       "AuthenticationFailure",
+			statusCode: 403,
+			body:       `{"message":"Forbidden. User needs the following permissions","permissions":[]}`,
+			expected:   ErrAuthenticationFailure,
+		},
+	}
+
+	for _, tt := range tests {
+		suite.Run(tt.name, func() {
+			err := parseManifestChangeError(&httpRequest{Endpoint: "http://localhost:8091"}, &HTTPResponse{
+				StatusCode: tt.statusCode,
+				Body:       ioutil.NopCloser(bytes.NewReader([]byte(tt.body))),
+			})
+
+			suite.Assert().True(errors.Is(err, tt.expected), err)
+
+			var httpErr HTTPError
+			suite.Require().True(errors.As(err, &httpErr))
+			suite.Assert().Equal("http://localhost:8091", httpErr.Endpoint)
+		})
+	}
+}
@@ -0,0 +1,89 @@
+package gocbcore
+
+import (
+	"sync"
+)
+
+const (
+	// compressionRatioMinSamples is the number of values which must have been compressed for a collection before we
+	// trust its average ratio enough to start skipping compression.
+	compressionRatioMinSamples = 8
+
+	// compressionRatioProbeInterval is the number of values which are skipped before one is compressed anyway, so that
+	// a collection whose data starts compressing well again is noticed.
+	compressionRatioProbeInterval = 32
+
+	// compressionRatioWeight is the weight given to each new sample in the moving average ratio.
+	compressionRatioWeight = 0.2
+)
+
+// compressionRatioTracker tracks the compression ratio achieved by the values written to each collection. Values
+// which are compressed but do not meet the minimum ratio are sent uncompressed, so once a collection has shown that
+// its values do not compress well we skip compressing them at all rather than doing the work only to throw it away.
+// A nil tracker always allows compression.
+type compressionRatioTracker struct {
+	minRatio float64
+
+	lock        sync.Mutex
+	collections map[uint32]*compressionRatioStats
+}
+
+type compressionRatioStats struct {
+	ratio   float64
+	samples uint32
+	skipped uint32
+}
+
+func newCompressionRatioTracker(minRatio float64) *compressionRatioTracker {
+	return &compressionRatioTracker{
+		minRatio:    minRatio,
+		collections: make(map[uint32]*compressionRatioStats),
+	}
+}
+
+// ShouldCompress returns whether a value written to the collection is worth attempting to compress.
+func (crt *compressionRatioTracker) ShouldCompress(collectionID uint32) bool {
+	if crt == nil {
+		return true
+	}
+
+	crt.lock.Lock()
+	defer crt.lock.Unlock()
+
+	stats := crt.collections[collectionID]
+	if stats == nil || stats.samples < compressionRatioMinSamples || stats.ratio <= crt.minRatio {
+		return true
+	}
+
+	stats.skipped++
+	if stats.skipped >= compressionRatioProbeInterval {
+		stats.skipped = 0
+		return true
+	}
+
+	return false
+}
+
+// Record adds the ratio achieved when compressing a value written to the collection to its moving average.
+func (crt *compressionRatioTracker) Record(collectionID uint32, ratio float64) {
+	if crt == nil {
+		return
+	}
+
+	crt.lock.Lock()
+	defer crt.lock.Unlock()
+
+	stats := crt.collections[collectionID]
+	if stats == nil {
+		crt.collections[collectionID] = &compressionRatioStats{
+			ratio:   ratio,
+			samples: 1,
+		}
+		return
+	}
+
+	stats.ratio += compressionRatioWeight * (ratio - stats.ratio)
+	if stats.samples < compressionRatioMinSamples {
+		stats.samples++
+	}
+}
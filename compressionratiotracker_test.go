@@ -0,0 +1,35 @@
+package gocbcore
+
+func (suite *UnitTestSuite) TestCompressionRatioTracker() {
+	tracker := newCompressionRatioTracker(0.83)
+
+	// Until enough samples have been seen we always compress, however badly the values have compressed.
+	for i := 0; i < compressionRatioMinSamples; i++ {
+		suite.Assert().True(tracker.ShouldCompress(8))
+		tracker.Record(8, 0.99)
+		tracker.Record(9, 0.3)
+	}
+
+	// A collection which doesn't compress well is skipped, apart from periodically probing it.
+	var compressed int
+	for i := 0; i < 2*compressionRatioProbeInterval; i++ {
+		if tracker.ShouldCompress(8) {
+			compressed++
+		}
+	}
+	suite.Assert().Equal(2, compressed)
+
+	// A collection which compresses well, or which we know nothing about, is always compressed.
+	suite.Assert().True(tracker.ShouldCompress(9))
+	suite.Assert().True(tracker.ShouldCompress(10))
+
+	// Once the values start compressing well again the probes pull the average back down.
+	for i := 0; i < 20; i++ {
+		tracker.Record(8, 0.3)
+	}
+	suite.Assert().True(tracker.ShouldCompress(8))
+
+	var nilTracker *compressionRatioTracker
+	suite.Assert().True(nilTracker.ShouldCompress(8))
+	nilTracker.Record(8, 0.99)
+}
@@ -100,6 +100,19 @@ type localLoopbackAddress struct {
 	Identifier   string
 }
 
+// clusterCompatVersion returns the lowest cluster compatibility version reported by the nodes, which is the version
+// that the cluster as a whole is operating at during an upgrade, or 0 if no node reports one.
+func (cfg *cfgBucket) clusterCompatVersion() int {
+	compatVersion := 0
+	for _, node := range cfg.Nodes {
+		if node.ClusterCompatibility > 0 && (compatVersion == 0 || node.ClusterCompatibility < compatVersion) {
+			compatVersion = node.ClusterCompatibility
+		}
+	}
+
+	return compatVersion
+}
+
 // BuildRouteConfig builds a new route config from this config.
 // overwriteSeedNode indicates that we should set the hostname for a node to the cfg.SourceHostname when the config has
 // been sourced from that node.
@@ -292,6 +305,7 @@ func (cfg *cfgBucket) BuildRouteConfig(useSsl bool, networkType string, firstCon
 		bktType:                bktType,
 		clusterCapabilities:    cfg.ClusterCapabilities,
 		clusterCapabilitiesVer: cfg.ClusterCapabilitiesVer,
+		clusterCompatVersion:   cfg.clusterCompatVersion(),
 		bucketCapabilities:     cfg.Capabilities,
 		bucketCapabilitiesVer:  cfg.CapabilitiesVersion,
 		clusterUUID:            cfg.ClusterUUID,
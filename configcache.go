@@ -0,0 +1,227 @@
+package gocbcore
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ConfigCacheConfig specifies options for persisting the last known good cluster config to disk. When enabled the
+// cached config is used to build routing as soon as the agent is created, before the first config has been fetched
+// from the cluster, which reduces the time taken for short-lived processes to become ready. The cached config is only
+// used until the cluster provides a config and is always superseded by it. A cached config is only used if it
+// includes one of the seed nodes, so that a cache written for a different cluster is never used.
+// Volatile: This API is subject to change at any time.
+type ConfigCacheConfig struct {
+	// Path is the file that the config is read from and written to. The cache is disabled when empty.
+	Path string
+	// FallbackTimeout is how long the cached config is used for whilst waiting for the cluster to provide a config,
+	// after which the agent falls back to bootstrapping from the seed nodes in case the cluster has changed since the
+	// config was cached. Defaults to 10 seconds.
+	FallbackTimeout time.Duration
+}
+
+type configCacheComponent struct {
+	path            string
+	bucketName      string
+	fallbackTimeout time.Duration
+
+	lock    sync.Mutex
+	pending *cfgBucket
+	storing bool
+	wg      sync.WaitGroup
+}
+
+func newConfigCacheComponent(config ConfigCacheConfig, bucketName string) *configCacheComponent {
+	if config.Path == "" {
+		return nil
+	}
+
+	fallbackTimeout := 10 * time.Second
+	if config.FallbackTimeout > 0 {
+		fallbackTimeout = config.FallbackTimeout
+	}
+
+	return &configCacheComponent{
+		path:            config.Path,
+		bucketName:      bucketName,
+		fallbackTimeout: fallbackTimeout,
+	}
+}
+
+// Load reads the cached config from disk, returning nil if there is no usable config. A config is only usable if it
+// is for this bucket and includes one of seedHosts, which must be host names without ports. The config is returned
+// with its revision reset so that any config received from the cluster replaces it.
+func (cc *configCacheComponent) Load(seedHosts []string) *cfgBucket {
+	cfg := cc.read()
+	if cfg == nil {
+		return nil
+	}
+
+	if cfg.Name != cc.bucketName {
+		logDebugf("Ignoring cached config from %s as it is not for this bucket", cc.path)
+		return nil
+	}
+
+	if cfg.ClusterUUID == "" || !cachedConfigHasHost(cfg, seedHosts) {
+		logDebugf("Ignoring cached config from %s as it is not for the cluster being connected to", cc.path)
+		return nil
+	}
+
+	cfg.Rev = 0
+	cfg.RevEpoch = 0
+
+	return cfg
+}
+
+func (cc *configCacheComponent) read() *cfgBucket {
+	data, err := ioutil.ReadFile(cc.path)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			logWarnf("Failed to read cached config from %s: %v", cc.path, err)
+		}
+		return nil
+	}
+
+	var cfg *cfgBucket
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		logWarnf("Failed to parse cached config from %s: %v", cc.path, err)
+		return nil
+	}
+
+	return cfg
+}
+
+func cachedConfigHasHost(cfg *cfgBucket, hosts []string) bool {
+	for _, node := range cfg.NodesExt {
+		for _, host := range hosts {
+			if strings.Trim(getHostname(node.Hostname, cfg.SourceHostname), "[]") == host {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// seedHosts returns the host names of the seed endpoints, without ports, for matching against a cached config.
+func seedHosts(endpoints []routeEndpoint) []string {
+	var hosts []string
+	for _, ep := range endpoints {
+		host, _, err := net.SplitHostPort(trimSchemePrefix(ep.Address))
+		if err != nil {
+			continue
+		}
+		hosts = append(hosts, host)
+	}
+
+	return hosts
+}
+
+// Store persists cfg to disk in the background, so that config updates are never held up by disk I/O. If configs are
+// stored faster than they can be written then only the latest is written.
+func (cc *configCacheComponent) Store(cfg *cfgBucket) {
+	cc.lock.Lock()
+	cc.pending = cfg
+	if !cc.storing {
+		cc.storing = true
+		cc.wg.Add(1)
+		go cc.storeLoop()
+	}
+	cc.lock.Unlock()
+}
+
+// Close waits for any config which is being stored to be written.
+func (cc *configCacheComponent) Close() {
+	cc.wg.Wait()
+}
+
+func (cc *configCacheComponent) storeLoop() {
+	defer cc.wg.Done()
+
+	for {
+		cc.lock.Lock()
+		cfg := cc.pending
+		cc.pending = nil
+		if cfg == nil {
+			cc.storing = false
+			cc.lock.Unlock()
+			return
+		}
+		cc.lock.Unlock()
+
+		cc.write(cfg)
+	}
+}
+
+// write writes cfg to disk, replacing the file atomically so that a concurrent reader never sees a partial config.
+// The file may be shared by several processes so a newer config for the same bucket is never replaced by an older one.
+func (cc *configCacheComponent) write(cfg *cfgBucket) {
+	if existing := cc.read(); existing != nil && existing.ClusterUUID == cfg.ClusterUUID &&
+		existing.UUID == cfg.UUID && isNewerCachedConfig(existing, cfg) {
+		logDebugf("Not replacing cached config in %s with an older config", cc.path)
+		return
+	}
+
+	data, err := json.Marshal(redactCachedConfig(cfg))
+	if err != nil {
+		logWarnf("Failed to encode config for cache: %v", err)
+		return
+	}
+
+	tmpFile, err := ioutil.TempFile(filepath.Dir(cc.path), filepath.Base(cc.path)+".*.tmp")
+	if err != nil {
+		logWarnf("Failed to create config cache file: %v", err)
+		return
+	}
+	tmpPath := tmpFile.Name()
+
+	_, err = tmpFile.Write(data)
+	if closeErr := tmpFile.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmpPath, cc.path)
+	}
+	if err != nil {
+		logWarnf("Failed to write config cache to %s: %v", cc.path, err)
+		if rmErr := os.Remove(tmpPath); rmErr != nil && !os.IsNotExist(rmErr) {
+			logDebugf("Failed to remove temporary config cache file: %v", rmErr)
+		}
+	}
+}
+
+func isNewerCachedConfig(cfg, other *cfgBucket) bool {
+	if cfg.RevEpoch != other.RevEpoch {
+		return cfg.RevEpoch > other.RevEpoch
+	}
+
+	return cfg.Rev > other.Rev
+}
+
+// redactCachedConfig returns a copy of cfg holding only the data needed to build routing, node level statistics and
+// host details are dropped as they are stale by the time the cache is read. The node which served the config is not
+// recorded as it will not be the node that the next process connects to.
+func redactCachedConfig(cfg *cfgBucket) *cfgBucket {
+	redacted := *cfg
+	redacted.NodesExt = make([]cfgNodeExt, len(cfg.NodesExt))
+	for i, node := range cfg.NodesExt {
+		node.ThisNode = false
+		redacted.NodesExt[i] = node
+	}
+	redacted.Nodes = make([]cfgNode, len(cfg.Nodes))
+	for i, node := range cfg.Nodes {
+		redacted.Nodes[i] = cfgNode{
+			CouchAPIBase: node.CouchAPIBase,
+			Hostname:     node.Hostname,
+			Ports:        node.Ports,
+		}
+	}
+
+	return &redacted
+}
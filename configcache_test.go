@@ -0,0 +1,152 @@
+package gocbcore
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+func (suite *UnitTestSuite) loadCacheableConfig() *cfgBucket {
+	data, err := suite.LoadRawTestDataset("bucket_config_with_rev_epoch")
+	suite.Require().Nil(err)
+
+	cfg, err := parseConfig(data, "10.112.210.101")
+	suite.Require().Nil(err, err)
+	cfg.ClusterUUID = "cluster-uuid"
+
+	return cfg
+}
+
+func (suite *UnitTestSuite) TestConfigCache() {
+	cfg := suite.loadCacheableConfig()
+	seeds := []string{"10.112.210.101"}
+
+	dir, err := ioutil.TempDir("", "gocbcore-cfgcache")
+	suite.Require().Nil(err, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "config.json")
+	cache := newConfigCacheComponent(ConfigCacheConfig{Path: path}, cfg.Name)
+	suite.Require().NotNil(cache)
+	suite.Assert().Nil(newConfigCacheComponent(ConfigCacheConfig{}, cfg.Name))
+
+	// Nothing has been cached yet.
+	suite.Assert().Nil(cache.Load(seeds))
+
+	cache.Store(cfg)
+	cache.Close()
+
+	cached := cache.Load(seeds)
+	suite.Require().NotNil(cached)
+	suite.Assert().Equal(cfg.Name, cached.Name)
+	suite.Assert().Zero(cached.Rev)
+	suite.Assert().Zero(cached.RevEpoch)
+	suite.Assert().Equal(cfg.VBucketServerMap, cached.VBucketServerMap)
+	suite.Require().Len(cached.Nodes, len(cfg.Nodes))
+	suite.Assert().Empty(cached.Nodes[0].InterestingStats)
+	suite.Assert().Empty(cached.Nodes[0].OS)
+	for _, node := range cached.NodesExt {
+		suite.Assert().False(node.ThisNode)
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	suite.Require().Nil(err, err)
+	suite.Assert().Len(files, 1)
+
+	// A config for another bucket, or cluster, is never used.
+	suite.Assert().Nil(newConfigCacheComponent(ConfigCacheConfig{Path: path}, "other").Load(seeds))
+	suite.Assert().Nil(cache.Load([]string{"10.112.210.102"}))
+
+	// An older config for the same bucket never replaces a newer one, as the cache may be shared between processes.
+	olderCfg := *cfg
+	olderCfg.Rev--
+	olderCfg.VBucketServerMap.VBucketMap = olderCfg.VBucketServerMap.VBucketMap[:1]
+	cache.Store(&olderCfg)
+	cache.Close()
+	suite.Assert().Equal(cfg.VBucketServerMap, cache.Load(seeds).VBucketServerMap)
+
+	// A config for a different cluster does, as the cluster may have been recreated.
+	olderCfg.ClusterUUID = "other-uuid"
+	cache.Store(&olderCfg)
+	cache.Close()
+	suite.Assert().Len(cache.Load(seeds).VBucketServerMap.VBucketMap, 1)
+}
+
+func (suite *UnitTestSuite) TestConfigComponentCachedConfig() {
+	cfg := suite.loadCacheableConfig()
+	seeds := []string{"10.112.210.101"}
+
+	dir, err := ioutil.TempDir("", "gocbcore-cfgcache")
+	suite.Require().Nil(err, err)
+	defer os.RemoveAll(dir)
+
+	cache := newConfigCacheComponent(ConfigCacheConfig{Path: filepath.Join(dir, "config.json")}, cfg.Name)
+	cache.Store(cfg)
+	cache.Close()
+
+	cmpt := newConfigManager(configManagerProperties{NetworkType: "default"})
+	cmpt.SetConfigCache(cache)
+	watcher := &testRouteWatcher{}
+	cmpt.AddConfigWatcher(watcher)
+
+	cmpt.OnCachedConfig(cache.Load(seeds), &routeConfig{revID: -1})
+	suite.Require().NotNil(watcher.receivedConfig)
+	suite.Assert().Equal(int64(0), watcher.receivedConfig.revID)
+
+	// The cluster config replaces the cached config even if the vbucket count no longer matches.
+	newCfg := *cfg
+	newCfg.Rev++
+	newCfg.VBucketServerMap.VBucketMap = newCfg.VBucketServerMap.VBucketMap[:1]
+	suite.Require().True(cmpt.onNewConfig(&newCfg))
+	suite.Assert().Equal(newCfg.Rev, watcher.receivedConfig.revID)
+
+	// The new config is written back to the cache.
+	cache.Close()
+	cached := cache.Load(seeds)
+	suite.Require().NotNil(cached)
+	suite.Assert().Len(cached.VBucketServerMap.VBucketMap, 1)
+}
+
+func (suite *UnitTestSuite) TestConfigComponentCachedConfigFallback() {
+	globalTestLogger.SuppressWarnings(true)
+	defer globalTestLogger.SuppressWarnings(false)
+
+	cfg := suite.loadCacheableConfig()
+	seeds := []string{"10.112.210.101"}
+
+	dir, err := ioutil.TempDir("", "gocbcore-cfgcache")
+	suite.Require().Nil(err, err)
+	defer os.RemoveAll(dir)
+
+	cache := newConfigCacheComponent(ConfigCacheConfig{
+		Path:            filepath.Join(dir, "config.json"),
+		FallbackTimeout: 10 * time.Millisecond,
+	}, cfg.Name)
+	cache.Store(cfg)
+	cache.Close()
+
+	cmpt := newConfigManager(configManagerProperties{NetworkType: "default"})
+	cmpt.SetConfigCache(cache)
+	defer cmpt.Close()
+	watcher := &testRouteConfigWatcher{cfgCh: make(chan *routeConfig, 2)}
+	cmpt.AddConfigWatcher(watcher)
+
+	seedCfg := &routeConfig{revID: -1}
+	cmpt.OnCachedConfig(cache.Load(seeds), seedCfg)
+	suite.Assert().Equal(int64(0), (<-watcher.cfgCh).revID)
+
+	// The cluster didn't provide a config in time so the seed config replaces the cached config.
+	select {
+	case routeCfg := <-watcher.cfgCh:
+		suite.Assert().Equal(seedCfg, routeCfg)
+	case <-time.After(5 * time.Second):
+		suite.T().Fatalf("Timed out waiting for the cached config to expire")
+	}
+
+	// The cluster's config is then applied as normal.
+	newCfg := *cfg
+	newCfg.Rev++
+	suite.Require().True(cmpt.onNewConfig(&newCfg))
+	suite.Assert().Equal(newCfg.Rev, (<-watcher.cfgCh).revID)
+}
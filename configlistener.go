@@ -0,0 +1,102 @@
+package gocbcore
+
+import (
+	"sync"
+)
+
+// RouteConfigEvent describes a new route config which has been applied by the client.
+// Volatile: This API is subject to change at any time.
+type RouteConfigEvent struct {
+	RevID    int64
+	RevEpoch int64
+
+	// BucketName is the name of the bucket that the config is for, this is empty for cluster level configs.
+	BucketName string
+
+	// AddedNodes are the addresses of the kv nodes which are in this config but were not in the previous one.
+	AddedNodes []string
+
+	// RemovedNodes are the addresses of the kv nodes which were in the previous config but are not in this one.
+	RemovedNodes []string
+
+	// NumVbuckets is the number of vbuckets in the config, this is 0 for buckets which do not use vbuckets.
+	NumVbuckets int
+
+	// VbucketsMoved is the number of vbuckets whose active node has changed since the previous config.
+	VbucketsMoved int
+}
+
+// RouteConfigWatcher is notified every time that the client applies a new route config.
+// Volatile: This API is subject to change at any time.
+type RouteConfigWatcher interface {
+	// OnRouteConfig is invoked synchronously as part of applying the config and so must not block.
+	OnRouteConfig(event RouteConfigEvent)
+}
+
+// routeConfigListener adapts a RouteConfigWatcher to receive the changes between each route config that it sees.
+type routeConfigListener struct {
+	listener   RouteConfigWatcher
+	tlsEnabled func() bool
+
+	lock    sync.Mutex
+	lastCfg *routeConfig
+}
+
+func (rcl *routeConfigListener) OnNewRouteConfig(cfg *routeConfig) {
+	rcl.lock.Lock()
+	oldCfg := rcl.lastCfg
+	if oldCfg == nil {
+		oldCfg = &routeConfig{revID: -1}
+	}
+	rcl.lastCfg = cfg
+	rcl.lock.Unlock()
+
+	useSSL := rcl.tlsEnabled()
+	added, removed := cfg.NodeChangeAddresses(oldCfg, useSSL)
+	event := RouteConfigEvent{
+		RevID:         cfg.revID,
+		RevEpoch:      cfg.revEpoch,
+		BucketName:    cfg.name,
+		AddedNodes:    trimSchemePrefixes(added),
+		RemovedNodes:  trimSchemePrefixes(removed),
+		VbucketsMoved: cfg.VbucketsMoved(oldCfg, useSSL),
+	}
+	if cfg.vbMap != nil {
+		event.NumVbuckets = cfg.vbMap.NumVbuckets()
+	}
+
+	rcl.listener.OnRouteConfig(event)
+}
+
+func trimSchemePrefixes(addresses []string) []string {
+	for i, address := range addresses {
+		addresses[i] = trimSchemePrefix(address)
+	}
+
+	return addresses
+}
+
+// AddConfigListener registers an external listener to be notified of every route config applied from now on.
+func (cm *configManagementComponent) AddConfigListener(listener RouteConfigWatcher) {
+	cm.AddConfigWatcher(&routeConfigListener{
+		listener:   listener,
+		tlsEnabled: cm.TLSEnabled,
+	})
+}
+
+// RemoveConfigListener unregisters an external listener previously registered with AddConfigListener.
+func (cm *configManagementComponent) RemoveConfigListener(listener RouteConfigWatcher) {
+	cm.watchersLock.Lock()
+	var adapter routeConfigWatcher
+	for _, watcher := range cm.cfgChangeWatchers {
+		if rcl, ok := watcher.(*routeConfigListener); ok && rcl.listener == listener {
+			adapter = rcl
+			break
+		}
+	}
+	cm.watchersLock.Unlock()
+
+	if adapter != nil {
+		cm.RemoveConfigWatcher(adapter)
+	}
+}
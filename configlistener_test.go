@@ -0,0 +1,62 @@
+package gocbcore
+
+type testRouteConfigListener struct {
+	events []RouteConfigEvent
+}
+
+func (l *testRouteConfigListener) OnRouteConfig(event RouteConfigEvent) {
+	l.events = append(l.events, event)
+}
+
+func (suite *UnitTestSuite) TestConfigManagerConfigListener() {
+	makeCfg := func(revID int64, addrs []string, vbEntries [][]int) *routeConfig {
+		cfg := &routeConfig{
+			revID:    revID,
+			revEpoch: 1,
+			name:     "default",
+			vbMap:    newVbucketMap(vbEntries, 1),
+		}
+		for _, addr := range addrs {
+			cfg.kvServerList.NonSSLEndpoints = append(cfg.kvServerList.NonSSLEndpoints,
+				routeEndpoint{Address: "couchbase://" + addr})
+		}
+		return cfg
+	}
+
+	cfgMgr := newConfigManager(configManagerProperties{})
+	listener := &testRouteConfigListener{}
+	cfgMgr.AddConfigListener(listener)
+	suite.Require().Len(cfgMgr.cfgChangeWatchers, 1)
+
+	watcher := cfgMgr.cfgChangeWatchers[0]
+	watcher.OnNewRouteConfig(makeCfg(1, []string{"10.0.0.1:11210", "10.0.0.2:11210"},
+		[][]int{{0, 1}, {1, 0}, {0, 1}, {1, 0}}))
+	// Node 2 is replaced by node 3, which takes over its active vbuckets.
+	watcher.OnNewRouteConfig(makeCfg(2, []string{"10.0.0.1:11210", "10.0.0.3:11210"},
+		[][]int{{0, 1}, {1, 0}, {0, 1}, {1, 0}}))
+	// The active copy of vbucket 0 moves to node 3 without any change to the nodes.
+	watcher.OnNewRouteConfig(makeCfg(3, []string{"10.0.0.1:11210", "10.0.0.3:11210"},
+		[][]int{{1, 0}, {1, 0}, {0, 1}, {1, 0}}))
+
+	suite.Require().Len(listener.events, 3)
+
+	suite.Assert().Equal(RouteConfigEvent{
+		RevID:       1,
+		RevEpoch:    1,
+		BucketName:  "default",
+		AddedNodes:  []string{"10.0.0.1:11210", "10.0.0.2:11210"},
+		NumVbuckets: 4,
+	}, listener.events[0])
+
+	suite.Assert().Equal(int64(2), listener.events[1].RevID)
+	suite.Assert().Equal([]string{"10.0.0.3:11210"}, listener.events[1].AddedNodes)
+	suite.Assert().Equal([]string{"10.0.0.2:11210"}, listener.events[1].RemovedNodes)
+	suite.Assert().Equal(2, listener.events[1].VbucketsMoved)
+
+	suite.Assert().Empty(listener.events[2].AddedNodes)
+	suite.Assert().Empty(listener.events[2].RemovedNodes)
+	suite.Assert().Equal(1, listener.events[2].VbucketsMoved)
+
+	cfgMgr.RemoveConfigListener(listener)
+	suite.Assert().Empty(cfgMgr.cfgChangeWatchers)
+}
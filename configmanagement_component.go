@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"math/rand"
 	"sync"
+	"time"
 
 	"github.com/couchbase/gocbcore/v10/memd"
 )
@@ -26,7 +27,15 @@ type configManagementComponent struct {
 
 	seenConfig bool
 
+	// cachedConfig is the config loaded from the config cache, usingCachedConfig indicates that it is the config
+	// currently in use.
+	cachedConfig      *cfgBucket
+	usingCachedConfig bool
+	cachedConfigTimer *time.Timer
+
 	configFetcher      *cccpConfigFetcher
+	tracer             *tracerComponent
+	configCache        *configCacheComponent
 	configFetchSig     chan struct{}
 	configFetchSigLock sync.Mutex
 
@@ -68,6 +77,18 @@ func (cm *configManagementComponent) SetConfigFetcher(fetcher *cccpConfigFetcher
 	cm.configFetcher = fetcher
 }
 
+// SetTracer sets the tracer used to record the application of new configs, this must be done before OnNewConfig is
+// called.
+func (cm *configManagementComponent) SetTracer(tracer *tracerComponent) {
+	cm.tracer = tracer
+}
+
+// SetConfigCache sets the cache that each applied config is persisted to, this must be done before OnNewConfig is
+// called.
+func (cm *configManagementComponent) SetConfigCache(cache *configCacheComponent) {
+	cm.configCache = cache
+}
+
 func (cm *configManagementComponent) UseTLS(use bool) {
 	cm.configLock.Lock()
 	cm.useSSL = use
@@ -91,11 +112,69 @@ func (cm *configManagementComponent) CurrentRev() (int64, int64) {
 	return revID, revEpoch
 }
 
+// IsNewerConfig returns whether cfg has a newer revision than the config currently in use, without building a route
+// config from it. Unversioned configs are always treated as newer.
+func (cm *configManagementComponent) IsNewerConfig(cfg *cfgBucket) bool {
+	currentRev, currentEpoch := cm.CurrentRev()
+	if cfg.RevEpoch != currentEpoch {
+		return cfg.RevEpoch > currentEpoch
+	}
+
+	return cfg.Rev == 0 || cfg.Rev > currentRev
+}
+
 func (cm *configManagementComponent) OnNewConfig(cfg *cfgBucket) {
 	cm.onNewConfig(cfg)
 }
 
+// OnCachedConfig applies a config loaded from the config cache, the config is used until the cluster provides one. If
+// the cluster doesn't provide a config within the cache's fallback timeout then seedCfg is applied instead, as the
+// nodes in the cached config may no longer be part of the cluster.
+func (cm *configManagementComponent) OnCachedConfig(cfg *cfgBucket, seedCfg *routeConfig) {
+	cm.configLock.Lock()
+	cm.cachedConfig = cfg
+	cm.configLock.Unlock()
+
+	if !cm.onNewConfig(cfg) {
+		return
+	}
+
+	cm.configLock.Lock()
+	if cm.usingCachedConfig {
+		cm.cachedConfigTimer = time.AfterFunc(cm.configCache.fallbackTimeout, func() {
+			cm.expireCachedConfig(seedCfg)
+		})
+	}
+	cm.configLock.Unlock()
+}
+
+// expireCachedConfig replaces the cached config with seedCfg if the cluster has not yet provided a config.
+func (cm *configManagementComponent) expireCachedConfig(seedCfg *routeConfig) {
+	select {
+	case <-cm.shutdownSig:
+		return
+	default:
+	}
+
+	cm.configLock.Lock()
+	if !cm.usingCachedConfig {
+		cm.configLock.Unlock()
+		return
+	}
+	logWarnf("No config received from the cluster whilst using the cached config, falling back to the seed nodes")
+	cm.usingCachedConfig = false
+	cm.cachedConfig = nil
+	cm.seenConfig = false
+	cm.currentConfig = seedCfg
+	cm.configLock.Unlock()
+
+	for _, watcher := range cm.Watchers() {
+		watcher.OnNewRouteConfig(seedCfg)
+	}
+}
+
 func (cm *configManagementComponent) onNewConfig(cfg *cfgBucket) bool {
+	start := time.Now()
 	var routeCfg *routeConfig
 	cm.configLock.Lock()
 	if cm.seenConfig {
@@ -121,10 +200,26 @@ func (cm *configManagementComponent) onNewConfig(cfg *cfgBucket) bool {
 		return false
 	}
 
+	nodesAdded, nodesRemoved := routeCfg.NodeChanges(cm.currentConfig, cm.useSSL)
 	cm.currentConfig = routeCfg
 	cm.seenConfig = true
+	fromCache := cfg == cm.cachedConfig
+	if cm.usingCachedConfig && !fromCache {
+		if cm.cachedConfigTimer != nil {
+			cm.cachedConfigTimer.Stop()
+		}
+		if cfg.ClusterUUID != cm.cachedConfig.ClusterUUID {
+			logWarnf("Cached config was for a different cluster, it has been replaced by the cluster's config")
+		}
+	}
+	cm.usingCachedConfig = fromCache
 	cm.configLock.Unlock()
 
+	var span RequestSpan
+	if cm.tracer != nil {
+		span = cm.tracer.StartConfigUpdateSpan(routeCfg, nodesAdded, nodesRemoved)
+	}
+
 	logDebugf("Sending out mux routing data (update)...")
 	logDebugf("New Routing Data:\n%s", routeCfg.DebugString())
 
@@ -138,6 +233,15 @@ func (cm *configManagementComponent) onNewConfig(cfg *cfgBucket) bool {
 		watcher.OnNewRouteConfig(routeCfg)
 	}
 
+	if cm.tracer != nil {
+		span.End()
+		cm.tracer.ConfigUpdateRecord(start, nodesAdded+nodesRemoved)
+	}
+
+	if cm.configCache != nil && !fromCache {
+		cm.configCache.Store(cfg)
+	}
+
 	return true
 }
 
@@ -259,6 +363,16 @@ func (cm *configManagementComponent) fetchConfig(snapshot *pipelineSnapshot, cur
 
 func (cm *configManagementComponent) Close() {
 	close(cm.shutdownSig)
+
+	cm.configLock.Lock()
+	if cm.cachedConfigTimer != nil {
+		cm.cachedConfigTimer.Stop()
+	}
+	cm.configLock.Unlock()
+
+	if cm.configCache != nil {
+		cm.configCache.Close()
+	}
 }
 
 func (cm *configManagementComponent) Watchers() []routeConfigWatcher {
@@ -316,7 +430,8 @@ func (cm *configManagementComponent) canUpdateRouteConfig(cfg *routeConfig) bool
 
 	// Check some basic things to ensure consistency!
 	// If oldCfg name was empty and the new cfg isn't then we're moving from cluster to bucket connection.
-	if cfg.revID > -1 && (oldCfg.name != "" && cfg.name != "") {
+	// A cached config may describe a cluster which has since been recreated so it is always replaced.
+	if cfg.revID > -1 && !cm.usingCachedConfig && (oldCfg.name != "" && cfg.name != "") {
 		if (cfg.vbMap == nil) != (oldCfg.vbMap == nil) {
 			logErrorf("Received a configuration with a different number of vbuckets %s-%s.  Ignoring.", oldCfg.name, cfg.name)
 			return false
@@ -161,6 +161,66 @@ func (suite *UnitTestSuite) TestConfigComponentRevEpoch() {
 	}
 }
 
+type testConfigUpdateTracer struct {
+	spans []*testSpan
+}
+
+func (tt *testConfigUpdateTracer) RequestSpan(parentContext RequestSpanContext, operationName string) RequestSpan {
+	span := newTestSpan(operationName, parentContext)
+	tt.spans = append(tt.spans, span)
+	return span
+}
+
+func (suite *UnitTestSuite) TestConfigComponentUpdateTracing() {
+	data, err := suite.LoadRawTestDataset("bucket_config_with_rev_epoch")
+	suite.Require().Nil(err)
+
+	var cfg *cfgBucket
+	suite.Require().Nil(json.Unmarshal(data, &cfg))
+
+	oldCfg := *cfg
+	oldCfg.Rev = 1
+	oldRouteCfg := oldCfg.BuildRouteConfig(false, "default", false, nil)
+	oldRouteCfg.kvServerList.NonSSLEndpoints = []routeEndpoint{
+		{Address: "couchbase://10.0.0.98:11210"},
+		{Address: "couchbase://10.0.0.99:11210"},
+	}
+
+	tracer := &testConfigUpdateTracer{}
+	meter := newTestMeter()
+	cmpt := configManagementComponent{
+		useSSL:        false,
+		networkType:   "default",
+		currentConfig: oldRouteCfg,
+		tracer:        newTracerComponent(tracer, "default", false, meter, nil),
+	}
+
+	newCfg := *cfg
+	newCfg.Rev = 2
+	suite.Require().True(cmpt.onNewConfig(&newCfg))
+
+	suite.Require().Len(tracer.spans, 1)
+	span := tracer.spans[0]
+	suite.Assert().Equal(spanNameUpdateConfig, span.Name)
+	suite.Assert().True(span.Finished)
+	suite.Assert().Equal(spanAttribDBSystemValue, span.Tags[spanAttribDBSystemKey])
+	suite.Assert().Equal(int64(2), span.Tags[spanAttribConfigRevKey])
+	suite.Assert().Equal(1, span.Tags[spanAttribNodesAddedKey])
+	suite.Assert().Equal(2, span.Tags[spanAttribNodesRemovedKey])
+
+	key := meterNameCBConfigApplyDurations + ":" + metricValueServiceKeyValue + ":" + metricValueOperationUpdateConfig
+	suite.Require().Contains(meter.recorders, key)
+	suite.Assert().Len(meter.recorders[key].values, 1)
+
+	key = meterNameCBConfigNodesChanged + ":" + metricValueServiceKeyValue + ":" + metricValueOperationUpdateConfig
+	suite.Require().Contains(meter.counters, key)
+	suite.Assert().Equal(uint64(3), meter.counters[key].count)
+
+	// Reapplying the same revision is rejected so should not be recorded.
+	suite.Require().False(cmpt.onNewConfig(&newCfg))
+	suite.Assert().Len(tracer.spans, 1)
+}
+
 type testAlternateAddressesRouteConfigMgr struct {
 	cfg       *routeConfig
 	cfgCalled bool
@@ -356,3 +416,18 @@ func (suite *StandardTestSuite) TestAlternateAddressesInvalidConfig() {
 		suite.T().Fatalf("Expected route config to not be propagated, was propagated")
 	}
 }
+
+func (suite *UnitTestSuite) TestConfigComponentIsNewerConfig() {
+	cmpt := newConfigManager(configManagerProperties{})
+
+	// Any config is newer than having no config.
+	suite.Assert().True(cmpt.IsNewerConfig(&cfgBucket{Rev: 1}))
+
+	cmpt.currentConfig = &routeConfig{revID: 5, revEpoch: 2}
+	suite.Assert().True(cmpt.IsNewerConfig(&cfgBucket{Rev: 6, RevEpoch: 2}))
+	suite.Assert().True(cmpt.IsNewerConfig(&cfgBucket{Rev: 1, RevEpoch: 3}))
+	suite.Assert().True(cmpt.IsNewerConfig(&cfgBucket{Rev: 0, RevEpoch: 2}))
+	suite.Assert().False(cmpt.IsNewerConfig(&cfgBucket{Rev: 5, RevEpoch: 2}))
+	suite.Assert().False(cmpt.IsNewerConfig(&cfgBucket{Rev: 4, RevEpoch: 2}))
+	suite.Assert().False(cmpt.IsNewerConfig(&cfgBucket{Rev: 9, RevEpoch: 1}))
+}
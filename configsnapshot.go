@@ -10,6 +10,14 @@ func (pi ConfigSnapshot) RevID() int64 {
 	return pi.state.RevID()
 }
 
+// RevEpoch returns the config revision epoch for this snapshot. The epoch is increased when the server resets its config
+// revisions, so a config is only newer than another if it has a higher epoch, or the same epoch and a
+// higher revision.
+// Volatile: This API is subject to change at any time.
+func (pi ConfigSnapshot) RevEpoch() int64 {
+	return pi.state.RevEpoch()
+}
+
 // KeyToVbucket translates a particular key to its assigned vbucket.
 func (pi ConfigSnapshot) KeyToVbucket(key []byte) (uint16, error) {
 	if pi.state.VBMap() == nil {
@@ -70,6 +70,8 @@ const (
 	BucketCapabilityNonDedupedHistory    BucketCapability = 0x05
 	// Uncommitted: This API may change in the future.
 	BucketCapabilityReviveDocument BucketCapability = 0x06
+	// Uncommitted: This API may change in the future.
+	BucketCapabilityBinaryXattr BucketCapability = 0x07
 )
 
 type CapabilityStatus uint32
@@ -107,9 +109,29 @@ const (
 	DCPBackfillOrderSequential
 )
 
+// ReadPreference specifies whether a read may be served by a replica when the active node is unavailable.
+// Volatile: This API is subject to change at any time.
+type ReadPreference uint8
+
+const (
+	// ReadPreferenceActiveOnly means that reads are only ever served by the active node. This is the default behaviour.
+	ReadPreferenceActiveOnly ReadPreference = iota
+
+	// ReadPreferenceActivePreferred means that reads are served by the active node, falling back to the first
+	// available replica if the active node has no connections or its circuit breaker is not closed. The node is
+	// selected again each time the read is retried.
+	ReadPreferenceActivePreferred
+
+	// ReadPreferenceReplicaPreferred means that reads are served by the first available replica, falling back to
+	// the active node if no replicas are available.
+	ReadPreferenceReplicaPreferred
+)
+
 const (
 	spanNameDispatchToServer    = "dispatch_to_server"
+	spanNameUpdateConfig        = "update_config"
 	spanAttribDBSystemKey       = "db.system"
+	spanAttribServiceKey        = "db.couchbase.service"
 	spanAttribClusterUUIDKey    = "db.couchbase.cluster_uuid"
 	spanAttribClusterNameKey    = "db.couchbase.cluster_name"
 	spanAttribDBSystemValue     = "couchbase"
@@ -123,20 +145,39 @@ const (
 	spanAttribNetPeerPortKey    = "net.peer.port"
 	spanAttribServerDurationKey = "db.couchbase.server_duration"
 	spanAttribNumRetries        = "db.couchbase.retries"
+	spanAttribUserMetadataKey   = "db.couchbase.user_metadata"
+	spanAttribConfigRevKey      = "db.couchbase.config.rev"
+	spanAttribConfigRevEpochKey = "db.couchbase.config.rev_epoch"
+	spanAttribNodesAddedKey     = "db.couchbase.config.nodes_added"
+	spanAttribNodesRemovedKey   = "db.couchbase.config.nodes_removed"
 )
 
 const (
-	metricAttribServiceKey           = "db.couchbase.service"
-	metricAttribOperationKey         = "db.operation"
-	metricAttribClusterUUIDKey       = "db.couchbase.cluster_uuid"
-	metricAttribClusterNameKey       = "db.couchbase.cluster_name"
-	meterNameCBOperations            = "db.couchbase.operations"
-	metricValueServiceKeyValue       = "kv"
-	metricValueServiceQueryValue     = "n1ql"
-	metricValueServiceSearchValue    = "fts"
-	metricValueServiceAnalyticsValue = "cbas"
-	metricValueServiceViewsValue     = "capi"
-	metricValueServiceHTTPValue      = "http"
+	metricAttribServiceKey            = "db.couchbase.service"
+	metricAttribOperationKey          = "db.operation"
+	metricAttribClusterUUIDKey        = "db.couchbase.cluster_uuid"
+	metricAttribClusterNameKey        = "db.couchbase.cluster_name"
+	meterNameCBOperations             = "db.couchbase.operations"
+	meterNameCBServerDurations        = "db.couchbase.server_durations"
+	meterNameCBRetries                = "db.couchbase.retries"
+	meterNameCBTimeouts               = "db.couchbase.timeouts"
+	meterNameCBDeadlineSlack          = "db.couchbase.operations.deadline_slack"
+	meterNameCBConfigApplyDurations   = "db.couchbase.config.apply_durations"
+	meterNameCBConfigRequeueDurations = "db.couchbase.config.requeue_durations"
+	meterNameCBConfigNodesChanged     = "db.couchbase.config.nodes_changed"
+	meterNameCBCompressionBytesSaved  = "db.couchbase.compression.bytes_saved"
+	meterNameCBCompressionBytesWasted = "db.couchbase.compression.bytes_wasted"
+	meterNameCBNotMyVbuckets          = "db.couchbase.kv.not_my_vbuckets"
+	meterNameCBDispatchOverhead       = "db.couchbase.kv.dispatch_overhead"
+	metricAttribEndpointKey           = "db.couchbase.endpoint"
+	metricValueOperationUpdateConfig  = "update_config"
+	metricValueServiceKeyValue        = "kv"
+	metricValueServiceQueryValue      = "n1ql"
+	metricValueServiceSearchValue     = "fts"
+	metricValueServiceAnalyticsValue  = "cbas"
+	metricValueServiceViewsValue      = "capi"
+	metricValueServiceHTTPValue       = "http"
+	metricValueServiceManagementValue = "management"
 )
 
 type SpanStatus string
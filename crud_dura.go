@@ -36,6 +36,39 @@ type ObserveVbOptions struct {
 	TraceContext RequestSpanContext
 }
 
+// GetFailoverLogOptions encapsulates the parameters for a GetFailoverLog operation.
+// Volatile: This API is subject to change at any time.
+type GetFailoverLogOptions struct {
+	VbID          uint16
+	ReplicaIdx    int
+	RetryStrategy RetryStrategy
+	Deadline      time.Time
+
+	// Internal: This should never be used and is not supported.
+	User string
+
+	TraceContext RequestSpanContext
+}
+
+// WaitForSeqNoOptions encapsulates the parameters for a WaitForSeqNo operation.
+type WaitForSeqNoOptions struct {
+	VbID   uint16
+	VbUUID VbUUID
+	SeqNo  SeqNo
+	// Persisted specifies that the seqno must have been persisted, rather than only being present in memory.
+	Persisted bool
+	// NumReplicas is the number of replicas, as well as the active, which must have reached the seqno.
+	NumReplicas   int
+	RetryStrategy RetryStrategy
+	// Deadline is when to stop waiting for the seqno, it is required.
+	Deadline time.Time
+
+	// Internal: This should never be used and is not supported.
+	User string
+
+	TraceContext RequestSpanContext
+}
+
 // ObserveResult encapsulates the result of a ObserveEx operation.
 type ObserveResult struct {
 	KeyState memd.KeyState
@@ -47,6 +80,14 @@ type ObserveResult struct {
 	}
 }
 
+// WaitForSeqNoResult encapsulates the result of a WaitForSeqNo operation.
+type WaitForSeqNoResult struct {
+	// CurrentSeqNo and PersistSeqNo are the seqnos of the vbucket on the active at the point that it was observed to
+	// have reached the seqno.
+	CurrentSeqNo SeqNo
+	PersistSeqNo SeqNo
+}
+
 // ObserveVbResult encapsulates the result of a ObserveVbEx operation.
 type ObserveVbResult struct {
 	DidFailover  bool
@@ -62,3 +103,16 @@ type ObserveVbResult struct {
 		ResourceUnits *ResourceUnitResult
 	}
 }
+
+// GetFailoverLogResult encapsulates the result of a GetFailoverLog operation.
+// Volatile: This API is subject to change at any time.
+type GetFailoverLogResult struct {
+	VbID uint16
+	// Entries is the failover log for the vbucket, with the most recent entry first.
+	Entries []FailoverEntry
+
+	// Internal: This should never be used and is not supported.
+	Internal struct {
+		ResourceUnits *ResourceUnitResult
+	}
+}
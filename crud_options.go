@@ -19,6 +19,15 @@ type GetOptions struct {
 	User string
 
 	TraceContext RequestSpanContext
+
+	// ReadPreference specifies whether the read may be served by a replica when the active node is unavailable.
+	// Volatile: This API is subject to change at any time.
+	ReadPreference ReadPreference
+
+	// UserMetadata is an opaque value which is passed through to the result or error of the operation, to the
+	// RetryStrategy, see UserMetadataRequest, and to the trace span of each request sent to the server.
+	// Volatile: This API is subject to change at any time.
+	UserMetadata interface{}
 }
 
 // GetAndTouchOptions encapsulates the parameters for a GetAndTouchEx operation.
@@ -35,6 +44,11 @@ type GetAndTouchOptions struct {
 	User string
 
 	TraceContext RequestSpanContext
+
+	// UserMetadata is an opaque value which is passed through to the result or error of the operation, to the
+	// RetryStrategy, see UserMetadataRequest, and to the trace span of each request sent to the server.
+	// Volatile: This API is subject to change at any time.
+	UserMetadata interface{}
 }
 
 // GetAndLockOptions encapsulates the parameters for a GetAndLockEx operation.
@@ -51,6 +65,11 @@ type GetAndLockOptions struct {
 	User string
 
 	TraceContext RequestSpanContext
+
+	// UserMetadata is an opaque value which is passed through to the result or error of the operation, to the
+	// RetryStrategy, see UserMetadataRequest, and to the trace span of each request sent to the server.
+	// Volatile: This API is subject to change at any time.
+	UserMetadata interface{}
 }
 
 // GetAnyReplicaOptions encapsulates the parameters for a GetAnyReplicaEx operation.
@@ -87,6 +106,26 @@ type GetOneReplicaOptions struct {
 	TraceContext RequestSpanContext
 }
 
+// QuorumGetOptions encapsulates the parameters for a QuorumGet operation.
+// Volatile: This API is subject to change at any time.
+type QuorumGetOptions struct {
+	Key            []byte
+	CollectionName string
+	ScopeName      string
+	CollectionID   uint32
+	RetryStrategy  RetryStrategy
+	Deadline       time.Time
+
+	// Quorum is the number of copies of the document, including the active, which must be read with the same CAS
+	// before the operation completes. Defaults to a majority of the active and replica copies.
+	Quorum int
+
+	// Internal: This should never be used and is not supported.
+	User string
+
+	TraceContext RequestSpanContext
+}
+
 // TouchOptions encapsulates the parameters for a TouchEx operation.
 type TouchOptions struct {
 	Key            []byte
@@ -101,6 +140,35 @@ type TouchOptions struct {
 	User string
 
 	TraceContext RequestSpanContext
+
+	// UserMetadata is an opaque value which is passed through to the result or error of the operation, to the
+	// RetryStrategy, see UserMetadataRequest, and to the trace span of each request sent to the server.
+	// Volatile: This API is subject to change at any time.
+	UserMetadata interface{}
+}
+
+// BatchTouchOptions encapsulates the parameters for a BatchTouch operation.
+// Volatile: This API is subject to change at any time.
+type BatchTouchOptions struct {
+	Keys [][]byte
+	// Expiry is applied to every key.
+	Expiry uint32
+	// FetchValues causes each document to be fetched as it is touched, using GetAndTouch rather than Touch.
+	FetchValues    bool
+	CollectionName string
+	ScopeName      string
+	CollectionID   uint32
+	RetryStrategy  RetryStrategy
+	Deadline       time.Time
+
+	// Internal: This should never be used and is not supported.
+	User string
+
+	TraceContext RequestSpanContext
+
+	// UserMetadata is an opaque value which is passed through to the error of each key, to the RetryStrategy, see
+	// UserMetadataRequest, and to the trace span of each request sent to the server.
+	UserMetadata interface{}
 }
 
 // UnlockOptions encapsulates the parameters for a UnlockEx operation.
@@ -117,6 +185,11 @@ type UnlockOptions struct {
 	User string
 
 	TraceContext RequestSpanContext
+
+	// UserMetadata is an opaque value which is passed through to the result or error of the operation, to the
+	// RetryStrategy, see UserMetadataRequest, and to the trace span of each request sent to the server.
+	// Volatile: This API is subject to change at any time.
+	UserMetadata interface{}
 }
 
 // DeleteOptions encapsulates the parameters for a DeleteEx operation.
@@ -128,13 +201,23 @@ type DeleteOptions struct {
 	Cas                    Cas
 	DurabilityLevel        memd.DurabilityLevel
 	DurabilityLevelTimeout time.Duration
-	CollectionID           uint32
-	Deadline               time.Time
+	// LegacyDurability emulates DurabilityLevel by observing the mutation on the active and replicas rather than
+	// using synchronous durability, which is not supported by clusters older than 6.5. Requires mutation tokens and a
+	// Deadline.
+	// Volatile: This API is subject to change at any time.
+	LegacyDurability bool
+	CollectionID     uint32
+	Deadline         time.Time
 
 	// Internal: This should never be used and is not supported.
 	User string
 
 	TraceContext RequestSpanContext
+
+	// UserMetadata is an opaque value which is passed through to the result or error of the operation, to the
+	// RetryStrategy, see UserMetadataRequest, and to the trace span of each request sent to the server.
+	// Volatile: This API is subject to change at any time.
+	UserMetadata interface{}
 }
 
 // AddOptions encapsulates the parameters for a AddEx operation.
@@ -149,13 +232,23 @@ type AddOptions struct {
 	Expiry                 uint32
 	DurabilityLevel        memd.DurabilityLevel
 	DurabilityLevelTimeout time.Duration
-	CollectionID           uint32
-	Deadline               time.Time
+	// LegacyDurability emulates DurabilityLevel by observing the mutation on the active and replicas rather than
+	// using synchronous durability, which is not supported by clusters older than 6.5. Requires mutation tokens and a
+	// Deadline.
+	// Volatile: This API is subject to change at any time.
+	LegacyDurability bool
+	CollectionID     uint32
+	Deadline         time.Time
 
 	// Internal: This should never be used and is not supported.
 	User string
 
 	TraceContext RequestSpanContext
+
+	// UserMetadata is an opaque value which is passed through to the result or error of the operation, to the
+	// RetryStrategy, see UserMetadataRequest, and to the trace span of each request sent to the server.
+	// Volatile: This API is subject to change at any time.
+	UserMetadata interface{}
 }
 
 type storeOptions struct {
@@ -170,6 +263,7 @@ type storeOptions struct {
 	Expiry                 uint32
 	DurabilityLevel        memd.DurabilityLevel
 	DurabilityLevelTimeout time.Duration
+	LegacyDurability       bool
 	CollectionID           uint32
 	Deadline               time.Time
 	PreserveExpiry         bool
@@ -178,6 +272,8 @@ type storeOptions struct {
 	User string
 
 	TraceContext RequestSpanContext
+
+	UserMetadata interface{}
 }
 
 // SetOptions encapsulates the parameters for a SetEx operation.
@@ -192,14 +288,24 @@ type SetOptions struct {
 	Expiry                 uint32
 	DurabilityLevel        memd.DurabilityLevel
 	DurabilityLevelTimeout time.Duration
-	CollectionID           uint32
-	Deadline               time.Time
-	PreserveExpiry         bool
+	// LegacyDurability emulates DurabilityLevel by observing the mutation on the active and replicas rather than
+	// using synchronous durability, which is not supported by clusters older than 6.5. Requires mutation tokens and a
+	// Deadline.
+	// Volatile: This API is subject to change at any time.
+	LegacyDurability bool
+	CollectionID     uint32
+	Deadline         time.Time
+	PreserveExpiry   bool
 
 	// Internal: This should never be used and is not supported.
 	User string
 
 	TraceContext RequestSpanContext
+
+	// UserMetadata is an opaque value which is passed through to the result or error of the operation, to the
+	// RetryStrategy, see UserMetadataRequest, and to the trace span of each request sent to the server.
+	// Volatile: This API is subject to change at any time.
+	UserMetadata interface{}
 }
 
 // ReplaceOptions encapsulates the parameters for a ReplaceEx operation.
@@ -215,14 +321,24 @@ type ReplaceOptions struct {
 	Expiry                 uint32
 	DurabilityLevel        memd.DurabilityLevel
 	DurabilityLevelTimeout time.Duration
-	CollectionID           uint32
-	Deadline               time.Time
-	PreserveExpiry         bool
+	// LegacyDurability emulates DurabilityLevel by observing the mutation on the active and replicas rather than
+	// using synchronous durability, which is not supported by clusters older than 6.5. Requires mutation tokens and a
+	// Deadline.
+	// Volatile: This API is subject to change at any time.
+	LegacyDurability bool
+	CollectionID     uint32
+	Deadline         time.Time
+	PreserveExpiry   bool
 
 	// Internal: This should never be used and is not supported.
 	User string
 
 	TraceContext RequestSpanContext
+
+	// UserMetadata is an opaque value which is passed through to the result or error of the operation, to the
+	// RetryStrategy, see UserMetadataRequest, and to the trace span of each request sent to the server.
+	// Volatile: This API is subject to change at any time.
+	UserMetadata interface{}
 }
 
 // AdjoinOptions encapsulates the parameters for a AppendEx or PrependEx operation.
@@ -243,6 +359,11 @@ type AdjoinOptions struct {
 	User string
 
 	TraceContext RequestSpanContext
+
+	// UserMetadata is an opaque value which is passed through to the result or error of the operation, to the
+	// RetryStrategy, see UserMetadataRequest, and to the trace span of each request sent to the server.
+	// Volatile: This API is subject to change at any time.
+	UserMetadata interface{}
 }
 
 // CounterOptions encapsulates the parameters for a IncrementEx or DecrementEx operation.
@@ -265,6 +386,11 @@ type CounterOptions struct {
 	User string
 
 	TraceContext RequestSpanContext
+
+	// UserMetadata is an opaque value which is passed through to the result or error of the operation, to the
+	// RetryStrategy, see UserMetadataRequest, and to the trace span of each request sent to the server.
+	// Volatile: This API is subject to change at any time.
+	UserMetadata interface{}
 }
 
 // GetRandomOptions encapsulates the parameters for a GetRandomEx operation.
@@ -297,6 +423,83 @@ type GetMetaOptions struct {
 	TraceContext RequestSpanContext
 }
 
+// GetDocumentMetaOptions encapsulates the parameters for a GetDocumentMeta operation.
+// Volatile: This API is subject to change at any time.
+type GetDocumentMetaOptions struct {
+	Key            []byte
+	CollectionName string
+	ScopeName      string
+	CollectionID   uint32
+	RetryStrategy  RetryStrategy
+	Deadline       time.Time
+
+	// Internal: This should never be used and is not supported.
+	User string
+
+	TraceContext RequestSpanContext
+}
+
+// GetTombstoneOptions encapsulates the parameters for a GetTombstone operation.
+// Volatile: This API is subject to change at any time.
+type GetTombstoneOptions struct {
+	Key []byte
+	// XattrPaths are the xattrs to fetch, at least one path must be provided.
+	XattrPaths     []string
+	CollectionName string
+	ScopeName      string
+	CollectionID   uint32
+	RetryStrategy  RetryStrategy
+	Deadline       time.Time
+
+	// Internal: This should never be used and is not supported.
+	User string
+
+	TraceContext RequestSpanContext
+}
+
+// LockDocumentOptions encapsulates the parameters for a LockDocument operation.
+// Volatile: This API is subject to change at any time.
+type LockDocumentOptions struct {
+	Key            []byte
+	LockTime       uint32
+	CollectionName string
+	ScopeName      string
+	CollectionID   uint32
+	RetryStrategy  RetryStrategy
+	// Deadline is the time by which the lock must be acquired and must be provided.
+	Deadline time.Time
+	// Backoff calculates how long to wait before trying to lock the document again after ErrDocumentLocked. If nil
+	// then ControlledBackoff will be used.
+	Backoff BackoffCalculator
+
+	// Internal: This should never be used and is not supported.
+	User string
+
+	TraceContext RequestSpanContext
+
+	// UserMetadata is an opaque value which is passed through to the result or error of the operation, to the
+	// RetryStrategy, see UserMetadataRequest, and to the trace span of each request sent to the server.
+	UserMetadata interface{}
+}
+
+// UnlockDocumentOptions encapsulates the parameters for a UnlockDocument operation.
+// Volatile: This API is subject to change at any time.
+type UnlockDocumentOptions struct {
+	// Lock is the lock returned by LockDocument.
+	Lock          *DocumentLock
+	RetryStrategy RetryStrategy
+	Deadline      time.Time
+
+	// Internal: This should never be used and is not supported.
+	User string
+
+	TraceContext RequestSpanContext
+
+	// UserMetadata is an opaque value which is passed through to the result or error of the operation, to the
+	// RetryStrategy, see UserMetadataRequest, and to the trace span of each request sent to the server.
+	UserMetadata interface{}
+}
+
 // SetMetaOptions encapsulates the parameters for a SetMetaEx operation.
 type SetMetaOptions struct {
 	Key            []byte
@@ -314,6 +517,11 @@ type SetMetaOptions struct {
 	RetryStrategy  RetryStrategy
 	Deadline       time.Time
 
+	// CompareCas, if non-zero, is the CAS value which the existing document must have for the operation to succeed,
+	// whereas Cas is the CAS value which will be stored with the document.
+	// Volatile: This API is subject to change at any time.
+	CompareCas Cas
+
 	// Internal: This should never be used and is not supported.
 	User string
 
@@ -337,6 +545,11 @@ type DeleteMetaOptions struct {
 	RetryStrategy  RetryStrategy
 	Deadline       time.Time
 
+	// CompareCas, if non-zero, is the CAS value which the existing document must have for the operation to succeed,
+	// whereas Cas is the CAS value which will be stored with the document.
+	// Volatile: This API is subject to change at any time.
+	CompareCas Cas
+
 	// Internal: This should never be used and is not supported.
 	User string
 
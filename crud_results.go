@@ -1,5 +1,7 @@
 package gocbcore
 
+import "sync/atomic"
+
 // ResourceUnitResult describes the number of compute units used by an operation.
 // Internal: This should never be used and is not supported.
 type ResourceUnitResult struct {
@@ -14,6 +16,14 @@ type GetResult struct {
 	Datatype uint8
 	Cas      Cas
 
+	// IsReplica indicates that the document was read from a replica, due to the ReadPreference, and so may be stale.
+	// Volatile: This API is subject to change at any time.
+	IsReplica bool
+
+	// UserMetadata is the UserMetadata which was provided in the options of the operation.
+	// Volatile: This API is subject to change at any time.
+	UserMetadata interface{}
+
 	// Internal: This should never be used and is not supported.
 	Internal struct {
 		ResourceUnits *ResourceUnitResult
@@ -27,6 +37,10 @@ type GetAndTouchResult struct {
 	Datatype uint8
 	Cas      Cas
 
+	// UserMetadata is the UserMetadata which was provided in the options of the operation.
+	// Volatile: This API is subject to change at any time.
+	UserMetadata interface{}
+
 	// Internal: This should never be used and is not supported.
 	Internal struct {
 		ResourceUnits *ResourceUnitResult
@@ -40,6 +54,10 @@ type GetAndLockResult struct {
 	Datatype uint8
 	Cas      Cas
 
+	// UserMetadata is the UserMetadata which was provided in the options of the operation.
+	// Volatile: This API is subject to change at any time.
+	UserMetadata interface{}
+
 	// Internal: This should never be used and is not supported.
 	Internal struct {
 		ResourceUnits *ResourceUnitResult
@@ -59,22 +77,65 @@ type GetReplicaResult struct {
 	}
 }
 
+// QuorumGetResult encapsulates the result of a QuorumGet operation.
+// Volatile: This API is subject to change at any time.
+type QuorumGetResult struct {
+	Value    []byte
+	Flags    uint32
+	Datatype uint8
+	Cas      Cas
+
+	// NumResponses is the number of copies of the document which were read with the returned CAS.
+	NumResponses int
+	// IsReplica indicates whether the returned value was read from a replica.
+	IsReplica bool
+}
+
 // TouchResult encapsulates the result of a TouchEx operation.
 type TouchResult struct {
 	Cas           Cas
 	MutationToken MutationToken
 
+	// UserMetadata is the UserMetadata which was provided in the options of the operation.
+	// Volatile: This API is subject to change at any time.
+	UserMetadata interface{}
+
 	// Internal: This should never be used and is not supported.
 	Internal struct {
 		ResourceUnits *ResourceUnitResult
 	}
 }
 
+// BatchTouchItem encapsulates the result of touching a single key as part of a BatchTouch operation.
+// Volatile: This API is subject to change at any time.
+type BatchTouchItem struct {
+	Key []byte
+	// Err is the error which occurred touching this key, if any, the other fields are only populated when Err is nil.
+	Err           error
+	Cas           Cas
+	MutationToken MutationToken
+	// Value, Flags and Datatype are only populated when BatchTouchOptions.FetchValues is set.
+	Value    []byte
+	Flags    uint32
+	Datatype uint8
+}
+
+// BatchTouchResult encapsulates the result of a BatchTouch operation.
+// Volatile: This API is subject to change at any time.
+type BatchTouchResult struct {
+	// Items contains the result for each key, at the same index as the key in BatchTouchOptions.Keys.
+	Items []BatchTouchItem
+}
+
 // UnlockResult encapsulates the result of a UnlockEx operation.
 type UnlockResult struct {
 	Cas           Cas
 	MutationToken MutationToken
 
+	// UserMetadata is the UserMetadata which was provided in the options of the operation.
+	// Volatile: This API is subject to change at any time.
+	UserMetadata interface{}
+
 	// Internal: This should never be used and is not supported.
 	Internal struct {
 		ResourceUnits *ResourceUnitResult
@@ -86,6 +147,10 @@ type DeleteResult struct {
 	Cas           Cas
 	MutationToken MutationToken
 
+	// UserMetadata is the UserMetadata which was provided in the options of the operation.
+	// Volatile: This API is subject to change at any time.
+	UserMetadata interface{}
+
 	// Internal: This should never be used and is not supported.
 	Internal struct {
 		ResourceUnits *ResourceUnitResult
@@ -97,6 +162,10 @@ type StoreResult struct {
 	Cas           Cas
 	MutationToken MutationToken
 
+	// UserMetadata is the UserMetadata which was provided in the options of the operation.
+	// Volatile: This API is subject to change at any time.
+	UserMetadata interface{}
+
 	// Internal: This should never be used and is not supported.
 	Internal struct {
 		ResourceUnits *ResourceUnitResult
@@ -108,6 +177,10 @@ type AdjoinResult struct {
 	Cas           Cas
 	MutationToken MutationToken
 
+	// UserMetadata is the UserMetadata which was provided in the options of the operation.
+	// Volatile: This API is subject to change at any time.
+	UserMetadata interface{}
+
 	// Internal: This should never be used and is not supported.
 	Internal struct {
 		ResourceUnits *ResourceUnitResult
@@ -120,6 +193,10 @@ type CounterResult struct {
 	Cas           Cas
 	MutationToken MutationToken
 
+	// UserMetadata is the UserMetadata which was provided in the options of the operation.
+	// Volatile: This API is subject to change at any time.
+	UserMetadata interface{}
+
 	// Internal: This should never be used and is not supported.
 	Internal struct {
 		ResourceUnits *ResourceUnitResult
@@ -156,6 +233,82 @@ type GetMetaResult struct {
 	}
 }
 
+// GetDocumentMetaResult encapsulates the result of a GetDocumentMeta operation.
+// Volatile: This API is subject to change at any time.
+type GetDocumentMetaResult struct {
+	Cas        Cas
+	Expiry     uint32
+	Flags      uint32
+	Datatype   uint8
+	SeqNo      SeqNo
+	ValueBytes uint32
+	// Deleted indicates that the document is a tombstone.
+	Deleted bool
+
+	// Internal: This should never be used and is not supported.
+	Internal struct {
+		ResourceUnits *ResourceUnitResult
+	}
+}
+
+// GetTombstoneResult encapsulates the result of a GetTombstone operation.
+// Volatile: This API is subject to change at any time.
+type GetTombstoneResult struct {
+	Cas Cas
+	// Xattrs contains the result of fetching each xattr, at the same index as the path in GetTombstoneOptions.XattrPaths.
+	Xattrs []SubDocResult
+	// IsDeleted indicates that the document is a tombstone, rather than a live document.
+	IsDeleted bool
+
+	// Internal: This should never be used and is not supported.
+	Internal struct {
+		ResourceUnits *ResourceUnitResult
+	}
+}
+
+// DocumentLock is a lock held on a document, acquired by LockDocument and released by UnlockDocument. A lock can
+// only be released once.
+// Volatile: This API is subject to change at any time.
+type DocumentLock struct {
+	key            []byte
+	collectionName string
+	scopeName      string
+	collectionID   uint32
+	cas            Cas
+	released       uint32
+}
+
+// Key returns the key of the locked document.
+func (l *DocumentLock) Key() []byte {
+	return l.key
+}
+
+// Cas returns the CAS of the locked document, this must be provided to mutate the document whilst it is locked.
+// Mutating the document releases the lock.
+func (l *DocumentLock) Cas() Cas {
+	return l.cas
+}
+
+func (l *DocumentLock) release() {
+	atomic.StoreUint32(&l.released, 1)
+}
+
+func (l *DocumentLock) isReleased() bool {
+	return atomic.LoadUint32(&l.released) == 1
+}
+
+// LockDocumentResult encapsulates the result of a LockDocument operation.
+// Volatile: This API is subject to change at any time.
+type LockDocumentResult struct {
+	Value    []byte
+	Flags    uint32
+	Datatype uint8
+	Lock     *DocumentLock
+
+	// UserMetadata is the UserMetadata which was provided in the options of the operation.
+	UserMetadata interface{}
+}
+
 // SetMetaResult encapsulates the result of a SetMetaEx operation.
 type SetMetaResult struct {
 	Cas           Cas
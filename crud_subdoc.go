@@ -1,6 +1,8 @@
 package gocbcore
 
 import (
+	"bytes"
+	"encoding/json"
 	"time"
 
 	"github.com/couchbase/gocbcore/v10/memd"
@@ -18,6 +20,12 @@ type LookupInOptions struct {
 	Deadline       time.Time
 	ReplicaIdx     int
 
+	// AccessDeleted allows the xattrs of a deleted document (tombstone) to be looked up, this is equivalent to setting
+	// memd.SubdocDocFlagAccessDeleted in Flags. Whether the document was deleted is reported in
+	// LookupInResult.IsDeleted.
+	// Volatile: This API is subject to change at any time.
+	AccessDeleted bool
+
 	// Uncommitted: This API may change in the future.
 	ServerGroup string
 
@@ -25,6 +33,11 @@ type LookupInOptions struct {
 	User string
 
 	TraceContext RequestSpanContext
+
+	// UserMetadata is an opaque value which is passed through to the result or error of the operation, to the
+	// RetryStrategy, see UserMetadataRequest, and to the trace span of each request sent to the server.
+	// Volatile: This API is subject to change at any time.
+	UserMetadata interface{}
 }
 
 // MutateInOptions encapsulates the parameters for a MutateInEx operation.
@@ -39,14 +52,24 @@ type MutateInOptions struct {
 	RetryStrategy          RetryStrategy
 	DurabilityLevel        memd.DurabilityLevel
 	DurabilityLevelTimeout time.Duration
-	CollectionID           uint32
-	Deadline               time.Time
-	PreserveExpiry         bool
+	// LegacyDurability emulates DurabilityLevel by observing the mutation on the active and replicas rather than
+	// using synchronous durability, which is not supported by clusters older than 6.5. Requires mutation tokens and a
+	// Deadline.
+	// Volatile: This API is subject to change at any time.
+	LegacyDurability bool
+	CollectionID     uint32
+	Deadline         time.Time
+	PreserveExpiry   bool
 
 	// Internal: This should never be used and is not supported.
 	User string
 
 	TraceContext RequestSpanContext
+
+	// UserMetadata is an opaque value which is passed through to the result or error of the operation, to the
+	// RetryStrategy, see UserMetadataRequest, and to the trace span of each request sent to the server.
+	// Volatile: This API is subject to change at any time.
+	UserMetadata interface{}
 }
 
 // SubDocResult encapsulates the results from a single sub-document operation.
@@ -60,6 +83,18 @@ type LookupInResult struct {
 	Cas Cas
 	Ops []SubDocResult
 
+	// IsDeleted indicates that the document is a tombstone, this can only be the case when AccessDeleted is set.
+	// Volatile: This API is subject to change at any time.
+	IsDeleted bool
+
+	// UserMetadata is the UserMetadata which was provided in the options of the operation.
+	// Volatile: This API is subject to change at any time.
+	UserMetadata interface{}
+
+	// isReplica indicates that the lookup was served by a replica, which is only the case for lookups routed by a
+	// read preference.
+	isReplica bool
+
 	// Internal: This should never be used and is not supported.
 	Internal struct {
 		IsDeleted     bool
@@ -71,10 +106,122 @@ type LookupInResult struct {
 type MutateInResult struct {
 	Cas           Cas
 	MutationToken MutationToken
-	Ops           []SubDocResult
+	// Ops contains the result of each operation, at the same index as the operation in MutateInOptions.Ops. Values
+	// are present for operations which produce one, such as counters, and for operations which write the
+	// ${Mutation.CAS} or ${Mutation.seqno} macros with memd.SubdocFlagExpandMacros, which contain the expanded value.
+	Ops []SubDocResult
+
+	// UserMetadata is the UserMetadata which was provided in the options of the operation.
+	// Volatile: This API is subject to change at any time.
+	UserMetadata interface{}
 
 	// Internal: This should never be used and is not supported.
 	Internal struct {
 		ResourceUnits *ResourceUnitResult
 	}
 }
+
+// NewArrayPushLastOp creates a SubDocOp which appends values to the end of the array at path. Each value is encoded
+// as JSON and multiple values are pushed in a single operation.
+func NewArrayPushLastOp(path string, flags memd.SubdocFlag, values ...interface{}) (SubDocOp, error) {
+	return newSubDocArrayOp(memd.SubDocOpArrayPushLast, path, flags, values)
+}
+
+// NewArrayPushFirstOp creates a SubDocOp which prepends values to the start of the array at path. Each value is
+// encoded as JSON and multiple values are pushed in a single operation, retaining their order.
+func NewArrayPushFirstOp(path string, flags memd.SubdocFlag, values ...interface{}) (SubDocOp, error) {
+	return newSubDocArrayOp(memd.SubDocOpArrayPushFirst, path, flags, values)
+}
+
+// NewArrayInsertOp creates a SubDocOp which inserts values into the array at path, where path refers to the index
+// of the first inserted value, e.g. "arr[2]". Each value is encoded as JSON.
+func NewArrayInsertOp(path string, flags memd.SubdocFlag, values ...interface{}) (SubDocOp, error) {
+	return newSubDocArrayOp(memd.SubDocOpArrayInsert, path, flags, values)
+}
+
+// NewArrayAddUniqueOp creates a SubDocOp which adds value to the array at path if it is not already present. The
+// value is encoded as JSON and must be a primitive, the server only supports adding a single value per operation.
+func NewArrayAddUniqueOp(path string, flags memd.SubdocFlag, value interface{}) (SubDocOp, error) {
+	return newSubDocArrayOp(memd.SubDocOpArrayAddUnique, path, flags, []interface{}{value})
+}
+
+// NewGetDocOp creates a SubDocOp which fetches the whole document body. It can be combined with xattr lookups to
+// atomically read a document along with its metadata.
+func NewGetDocOp() SubDocOp {
+	return SubDocOp{
+		Op: memd.SubDocOpGetDoc,
+	}
+}
+
+// NewSetDocOp creates a SubDocOp which replaces the whole document body with value. It can be combined with xattr
+// mutations to atomically write a document along with its metadata.
+func NewSetDocOp(value []byte) SubDocOp {
+	return SubDocOp{
+		Op:    memd.SubDocOpSetDoc,
+		Value: value,
+	}
+}
+
+// NewDeleteDocOp creates a SubDocOp which removes the whole document body. When combined with xattr mutations and
+// memd.SubdocDocFlagAccessDeleted the document becomes a tombstone retaining its system xattrs.
+func NewDeleteDocOp() SubDocOp {
+	return SubDocOp{
+		Op: memd.SubDocOpDeleteDoc,
+	}
+}
+
+// SubDocMacro is a macro which the server expands when it is written to an xattr with memd.SubdocFlagExpandMacros.
+// Volatile: This API is subject to change at any time.
+type SubDocMacro string
+
+const (
+	// SubDocMacroMutationCAS expands to the CAS of the mutation.
+	SubDocMacroMutationCAS = SubDocMacro("${Mutation.CAS}")
+
+	// SubDocMacroMutationSeqNo expands to the sequence number of the mutation.
+	SubDocMacroMutationSeqNo = SubDocMacro("${Mutation.seqno}")
+
+	// SubDocMacroMutationValueCRC32c expands to the CRC32c checksum of the document body after the mutation.
+	SubDocMacroMutationValueCRC32c = SubDocMacro("${Mutation.value_crc32c}")
+
+	// SubDocMacroDocumentExptime expands to the expiry time of the document.
+	SubDocMacroDocumentExptime = SubDocMacro("${$document.exptime}")
+)
+
+// NewMacroXattrOp creates a SubDocOp which writes the server expanded value of macro to the xattr at path. The xattr
+// path and expand macros flags are always set, in addition to any provided flags.
+// Volatile: This API is subject to change at any time.
+func NewMacroXattrOp(path string, macro SubDocMacro, flags memd.SubdocFlag) SubDocOp {
+	return SubDocOp{
+		Op:    memd.SubDocOpDictSet,
+		Flags: flags | memd.SubdocFlagXattrPath | memd.SubdocFlagExpandMacros,
+		Path:  path,
+		Value: []byte(`"` + string(macro) + `"`),
+	}
+}
+
+func newSubDocArrayOp(op memd.SubDocOpType, path string, flags memd.SubdocFlag, values []interface{}) (SubDocOp, error) {
+	if len(values) == 0 {
+		return SubDocOp{}, wrapError(errInvalidArgument, "at least one value must be provided")
+	}
+
+	// Multiple values are sent as a comma separated list of JSON values, without the enclosing brackets.
+	var buf bytes.Buffer
+	for i, value := range values {
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return SubDocOp{}, wrapError(err, "failed to encode subdoc array value")
+		}
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		buf.Write(encoded)
+	}
+
+	return SubDocOp{
+		Op:    op,
+		Flags: flags,
+		Path:  path,
+		Value: buf.Bytes(),
+	}, nil
+}
@@ -20,11 +20,12 @@ type crudComponent struct {
 	clientProvider         clientProvider
 	disableDecompression   bool
 	configSnapshotProvider configSnapshotProvider
+	valueHooks             ValueHooks
 }
 
 func newCRUDComponent(cidMgr *collectionsComponent, defaultRetryStrategy RetryStrategy, tracerCmpt *tracerComponent,
 	errMapManager *errMapComponent, featureVerifier bucketCapabilityVerifier, clientProvider clientProvider,
-	disableDecompression bool, configSnapshotProvider configSnapshotProvider) *crudComponent {
+	disableDecompression bool, configSnapshotProvider configSnapshotProvider, valueHooks ValueHooks) *crudComponent {
 	return &crudComponent{
 		cidMgr:                 cidMgr,
 		defaultRetryStrategy:   defaultRetryStrategy,
@@ -34,11 +35,13 @@ func newCRUDComponent(cidMgr *collectionsComponent, defaultRetryStrategy RetrySt
 		disableDecompression:   disableDecompression,
 		clientProvider:         clientProvider,
 		configSnapshotProvider: configSnapshotProvider,
+		valueHooks:             valueHooks,
 	}
 }
 
 func (crud *crudComponent) Get(opts GetOptions, cb GetCallback) (PendingOp, error) {
 	tracer := crud.tracer.StartTelemeteryHandler(metricValueServiceKeyValue, "Get", opts.TraceContext)
+	tracer.SetDeadline(opts.Deadline)
 
 	handler := func(resp *memdQResponse, req *memdQRequest, err error) {
 		if err != nil {
@@ -53,12 +56,21 @@ func (crud *crudComponent) Get(opts GetOptions, cb GetCallback) (PendingOp, erro
 			return
 		}
 
+		value, datatype, err := crud.afterRead(req.Key, resp.Value, resp.Datatype)
+		if err != nil {
+			tracer.Finish()
+			cb(nil, err)
+			return
+		}
+
 		res := GetResult{}
-		res.Value = resp.Value
+		res.Value = value
 		res.Flags = binary.BigEndian.Uint32(resp.Extras[0:])
 		res.Cas = Cas(resp.Cas)
-		res.Datatype = resp.Datatype
+		res.Datatype = datatype
+		res.IsReplica = req.ReplicaIdx > 0
 		res.Internal.ResourceUnits = req.ResourceUnits()
+		res.UserMetadata = req.UserMetadata()
 
 		tracer.Finish()
 		cb(&res, nil)
@@ -75,26 +87,35 @@ func (crud *crudComponent) Get(opts GetOptions, cb GetCallback) (PendingOp, erro
 		opts.RetryStrategy = crud.defaultRetryStrategy
 	}
 
-	req := &memdQRequest{
-		Packet: memd.Packet{
-			Magic:                  memd.CmdMagicReq,
-			Command:                memd.CmdGet,
-			Datatype:               0,
-			Cas:                    0,
-			Extras:                 nil,
-			Key:                    opts.Key,
-			Value:                  nil,
-			CollectionID:           opts.CollectionID,
-			UserImpersonationFrame: userFrame,
-		},
-		Callback:         handler,
-		RootTraceContext: tracer.RootContext(),
-		CollectionName:   opts.CollectionName,
-		ScopeName:        opts.ScopeName,
-		RetryStrategy:    opts.RetryStrategy,
+	req := acquireMemdQRequest()
+	req.Packet = memd.Packet{
+		Magic:                  memd.CmdMagicReq,
+		Command:                memd.CmdGet,
+		Datatype:               0,
+		Cas:                    0,
+		Extras:                 nil,
+		Key:                    opts.Key,
+		Value:                  nil,
+		CollectionID:           opts.CollectionID,
+		UserImpersonationFrame: userFrame,
+	}
+	req.Callback = handler
+	req.RootTraceContext = tracer.RootContext()
+	req.CollectionName = opts.CollectionName
+	req.ScopeName = opts.ScopeName
+	req.RetryStrategy = opts.RetryStrategy
+	req.userMetadata = opts.UserMetadata
+	if opts.ReadPreference != ReadPreferenceActiveOnly {
+		// The node is selected again whenever the request is retried, as the node it was sent to may be the reason
+		// for the retry.
+		req.selectReplicaFn = func(req *memdQRequest) {
+			crud.selectReadPreferenceReplica(req, opts.ReadPreference)
+		}
+		crud.selectReadPreferenceReplica(req, opts.ReadPreference)
 	}
 
-	op, err := crud.cidMgr.Dispatch(req)
+	req.dispatchDeadline = opts.Deadline
+	_, err := crud.cidMgr.Dispatch(req)
 	if err != nil {
 		tracer.Finish()
 		return nil, err
@@ -110,11 +131,54 @@ func (crud *crudComponent) Get(opts GetOptions, cb GetCallback) (PendingOp, erro
 		}))
 	}
 
+	op := req.pendingOp()
+	req.release()
+
 	return op, nil
 }
 
+// selectReadPreferenceReplica routes a get to the node matching the read preference, switching between get and get
+// replica as needed.
+func (crud *crudComponent) selectReadPreferenceReplica(req *memdQRequest, pref ReadPreference) {
+	req.ReplicaIdx = crud.readPreferenceReplicaIdx(req.Key, pref)
+	if req.ReplicaIdx > 0 {
+		req.Command = memd.CmdGetReplica
+	} else {
+		req.Command = memd.CmdGet
+	}
+}
+
+// readPreferenceReplicaIdx returns the replica index that a read for the key should be sent to, where 0 is the
+// active node. If no node matching the preference is available then the read is sent to the active node, where it
+// will be retried as normal.
+func (crud *crudComponent) readPreferenceReplicaIdx(key []byte, pref ReadPreference) int {
+	switch pref {
+	case ReadPreferenceActivePreferred:
+		if crud.clientProvider.NodeAvailable(key, 0) {
+			return 0
+		}
+		return crud.firstAvailableReplicaIdx(key)
+	case ReadPreferenceReplicaPreferred:
+		return crud.firstAvailableReplicaIdx(key)
+	default:
+		return 0
+	}
+}
+
+func (crud *crudComponent) firstAvailableReplicaIdx(key []byte) int {
+	numReplicas := crud.clientProvider.NumReplicas()
+	for replicaIdx := 1; replicaIdx <= numReplicas; replicaIdx++ {
+		if crud.clientProvider.NodeAvailable(key, replicaIdx) {
+			return replicaIdx
+		}
+	}
+
+	return 0
+}
+
 func (crud *crudComponent) GetAndTouch(opts GetAndTouchOptions, cb GetAndTouchCallback) (PendingOp, error) {
 	tracer := crud.tracer.StartTelemeteryHandler(metricValueServiceKeyValue, "GetAndTouch", opts.TraceContext)
+	tracer.SetDeadline(opts.Deadline)
 
 	handler := func(resp *memdQResponse, req *memdQRequest, err error) {
 		if err != nil {
@@ -131,13 +195,21 @@ func (crud *crudComponent) GetAndTouch(opts GetAndTouchOptions, cb GetAndTouchCa
 
 		flags := binary.BigEndian.Uint32(resp.Extras[0:])
 
+		value, datatype, err := crud.afterRead(req.Key, resp.Value, resp.Datatype)
+		if err != nil {
+			tracer.Finish()
+			cb(nil, err)
+			return
+		}
+
 		res := &GetAndTouchResult{
-			Value:    resp.Value,
+			Value:    value,
 			Flags:    flags,
 			Cas:      Cas(resp.Cas),
-			Datatype: resp.Datatype,
+			Datatype: datatype,
 		}
 		res.Internal.ResourceUnits = req.ResourceUnits()
+		res.UserMetadata = req.UserMetadata()
 
 		tracer.Finish()
 		cb(res, nil)
@@ -174,8 +246,10 @@ func (crud *crudComponent) GetAndTouch(opts GetAndTouchOptions, cb GetAndTouchCa
 		CollectionName:   opts.CollectionName,
 		ScopeName:        opts.ScopeName,
 		RetryStrategy:    opts.RetryStrategy,
+		userMetadata:     opts.UserMetadata,
 	}
 
+	req.dispatchDeadline = opts.Deadline
 	op, err := crud.cidMgr.Dispatch(req)
 	if err != nil {
 		tracer.Finish()
@@ -197,6 +271,7 @@ func (crud *crudComponent) GetAndTouch(opts GetAndTouchOptions, cb GetAndTouchCa
 
 func (crud *crudComponent) GetAndLock(opts GetAndLockOptions, cb GetAndLockCallback) (PendingOp, error) {
 	tracer := crud.tracer.StartTelemeteryHandler(metricValueServiceKeyValue, "GetAndLock", opts.TraceContext)
+	tracer.SetDeadline(opts.Deadline)
 
 	handler := func(resp *memdQResponse, req *memdQRequest, err error) {
 		if err != nil {
@@ -212,13 +287,21 @@ func (crud *crudComponent) GetAndLock(opts GetAndLockOptions, cb GetAndLockCallb
 		}
 
 		flags := binary.BigEndian.Uint32(resp.Extras[0:])
+		value, datatype, err := crud.afterRead(req.Key, resp.Value, resp.Datatype)
+		if err != nil {
+			tracer.Finish()
+			cb(nil, err)
+			return
+		}
+
 		res := &GetAndLockResult{
-			Value:    resp.Value,
+			Value:    value,
 			Flags:    flags,
 			Cas:      Cas(resp.Cas),
-			Datatype: resp.Datatype,
+			Datatype: datatype,
 		}
 		res.Internal.ResourceUnits = req.ResourceUnits()
+		res.UserMetadata = req.UserMetadata()
 
 		tracer.Finish()
 		cb(res, nil)
@@ -255,8 +338,10 @@ func (crud *crudComponent) GetAndLock(opts GetAndLockOptions, cb GetAndLockCallb
 		CollectionName:   opts.CollectionName,
 		ScopeName:        opts.ScopeName,
 		RetryStrategy:    opts.RetryStrategy,
+		userMetadata:     opts.UserMetadata,
 	}
 
+	req.dispatchDeadline = opts.Deadline
 	op, err := crud.cidMgr.Dispatch(req)
 	if err != nil {
 		tracer.Finish()
@@ -278,6 +363,7 @@ func (crud *crudComponent) GetAndLock(opts GetAndLockOptions, cb GetAndLockCallb
 
 func (crud *crudComponent) GetOneReplica(opts GetOneReplicaOptions, cb GetReplicaCallback) (PendingOp, error) {
 	tracer := crud.tracer.StartTelemeteryHandler(metricValueServiceKeyValue, "GetOneReplica", opts.TraceContext)
+	tracer.SetDeadline(opts.Deadline)
 
 	if opts.ReplicaIdx <= 0 {
 		tracer.Finish()
@@ -298,11 +384,18 @@ func (crud *crudComponent) GetOneReplica(opts GetOneReplicaOptions, cb GetReplic
 		}
 
 		flags := binary.BigEndian.Uint32(resp.Extras[0:])
+		value, datatype, err := crud.afterRead(req.Key, resp.Value, resp.Datatype)
+		if err != nil {
+			tracer.Finish()
+			cb(nil, err)
+			return
+		}
+
 		res := &GetReplicaResult{
-			Value:    resp.Value,
+			Value:    value,
 			Flags:    flags,
 			Cas:      Cas(resp.Cas),
-			Datatype: resp.Datatype,
+			Datatype: datatype,
 		}
 		res.Internal.ResourceUnits = req.ResourceUnits()
 
@@ -342,6 +435,7 @@ func (crud *crudComponent) GetOneReplica(opts GetOneReplicaOptions, cb GetReplic
 		ServerGroup:      opts.ServerGroup,
 	}
 
+	req.dispatchDeadline = opts.Deadline
 	op, err := crud.cidMgr.Dispatch(req)
 	if err != nil {
 		tracer.Finish()
@@ -363,6 +457,7 @@ func (crud *crudComponent) GetOneReplica(opts GetOneReplicaOptions, cb GetReplic
 
 func (crud *crudComponent) Touch(opts TouchOptions, cb TouchCallback) (PendingOp, error) {
 	tracer := crud.tracer.StartTelemeteryHandler(metricValueServiceKeyValue, "Touch", opts.TraceContext)
+	tracer.SetDeadline(opts.Deadline)
 
 	handler := func(resp *memdQResponse, req *memdQRequest, err error) {
 		if err != nil {
@@ -383,6 +478,7 @@ func (crud *crudComponent) Touch(opts TouchOptions, cb TouchCallback) (PendingOp
 			MutationToken: mutToken,
 		}
 		res.Internal.ResourceUnits = req.ResourceUnits()
+		res.UserMetadata = req.UserMetadata()
 
 		tracer.Finish()
 		cb(res, nil)
@@ -419,8 +515,10 @@ func (crud *crudComponent) Touch(opts TouchOptions, cb TouchCallback) (PendingOp
 		CollectionName:   opts.CollectionName,
 		ScopeName:        opts.ScopeName,
 		RetryStrategy:    opts.RetryStrategy,
+		userMetadata:     opts.UserMetadata,
 	}
 
+	req.dispatchDeadline = opts.Deadline
 	op, err := crud.cidMgr.Dispatch(req)
 	if err != nil {
 		tracer.Finish()
@@ -442,6 +540,7 @@ func (crud *crudComponent) Touch(opts TouchOptions, cb TouchCallback) (PendingOp
 
 func (crud *crudComponent) Unlock(opts UnlockOptions, cb UnlockCallback) (PendingOp, error) {
 	tracer := crud.tracer.StartTelemeteryHandler(metricValueServiceKeyValue, "Unlock", opts.TraceContext)
+	tracer.SetDeadline(opts.Deadline)
 
 	handler := func(resp *memdQResponse, req *memdQRequest, err error) {
 		if err != nil {
@@ -462,6 +561,7 @@ func (crud *crudComponent) Unlock(opts UnlockOptions, cb UnlockCallback) (Pendin
 			MutationToken: mutToken,
 		}
 		res.Internal.ResourceUnits = req.ResourceUnits()
+		res.UserMetadata = req.UserMetadata()
 
 		tracer.Finish()
 		cb(res, nil)
@@ -495,8 +595,10 @@ func (crud *crudComponent) Unlock(opts UnlockOptions, cb UnlockCallback) (Pendin
 		CollectionName:   opts.CollectionName,
 		ScopeName:        opts.ScopeName,
 		RetryStrategy:    opts.RetryStrategy,
+		userMetadata:     opts.UserMetadata,
 	}
 
+	req.dispatchDeadline = opts.Deadline
 	op, err := crud.cidMgr.Dispatch(req)
 	if err != nil {
 		tracer.Finish()
@@ -518,6 +620,7 @@ func (crud *crudComponent) Unlock(opts UnlockOptions, cb UnlockCallback) (Pendin
 
 func (crud *crudComponent) Delete(opts DeleteOptions, cb DeleteCallback) (PendingOp, error) {
 	tracer := crud.tracer.StartTelemeteryHandler(metricValueServiceKeyValue, "Delete", opts.TraceContext)
+	tracer.SetDeadline(opts.Deadline)
 
 	handler := func(resp *memdQResponse, req *memdQRequest, err error) {
 		if err != nil {
@@ -538,6 +641,7 @@ func (crud *crudComponent) Delete(opts DeleteOptions, cb DeleteCallback) (Pendin
 			MutationToken: mutToken,
 		}
 		res.Internal.ResourceUnits = req.ResourceUnits()
+		res.UserMetadata = req.UserMetadata()
 
 		tracer.Finish()
 		cb(res, nil)
@@ -553,7 +657,7 @@ func (crud *crudComponent) Delete(opts DeleteOptions, cb DeleteCallback) (Pendin
 			DurabilityLevel: opts.DurabilityLevel,
 		}
 		duraTimeoutFrame = &memd.DurabilityTimeoutFrame{
-			DurabilityTimeout: opts.DurabilityLevelTimeout,
+			DurabilityTimeout: durabilityTimeoutOrDefault(opts.DurabilityLevelTimeout, opts.Deadline),
 		}
 	}
 
@@ -587,8 +691,10 @@ func (crud *crudComponent) Delete(opts DeleteOptions, cb DeleteCallback) (Pendin
 		CollectionName:   opts.CollectionName,
 		ScopeName:        opts.ScopeName,
 		RetryStrategy:    opts.RetryStrategy,
+		userMetadata:     opts.UserMetadata,
 	}
 
+	req.dispatchDeadline = opts.Deadline
 	op, err := crud.cidMgr.Dispatch(req)
 	if err != nil {
 		tracer.Finish()
@@ -609,7 +715,21 @@ func (crud *crudComponent) Delete(opts DeleteOptions, cb DeleteCallback) (Pendin
 }
 
 func (crud *crudComponent) store(opName string, opcode memd.CmdCode, opts storeOptions, cb StoreCallback) (PendingOp, error) {
+	value, datatype, err := crud.beforeWrite(opts.Key, opts.Value, opts.Datatype)
+	if err != nil {
+		return nil, err
+	}
+	opts.Value = value
+	opts.Datatype = datatype
+
+	return crud.storeValue(opName, opcode, opts, cb)
+}
+
+// storeValue stores opts.Value as it is, without applying the value hooks.
+func (crud *crudComponent) storeValue(opName string, opcode memd.CmdCode, opts storeOptions,
+	cb StoreCallback) (PendingOp, error) {
 	tracer := crud.tracer.StartTelemeteryHandler(metricValueServiceKeyValue, opName, opts.TraceContext)
+	tracer.SetDeadline(opts.Deadline)
 
 	handler := func(resp *memdQResponse, req *memdQRequest, err error) {
 		if err != nil {
@@ -630,6 +750,7 @@ func (crud *crudComponent) store(opName string, opcode memd.CmdCode, opts storeO
 			MutationToken: mutToken,
 		}
 		res.Internal.ResourceUnits = req.ResourceUnits()
+		res.UserMetadata = req.UserMetadata()
 
 		tracer.Finish()
 		cb(res, nil)
@@ -645,7 +766,7 @@ func (crud *crudComponent) store(opName string, opcode memd.CmdCode, opts storeO
 			DurabilityLevel: opts.DurabilityLevel,
 		}
 		duraTimeoutFrame = &memd.DurabilityTimeoutFrame{
-			DurabilityTimeout: opts.DurabilityLevelTimeout,
+			DurabilityTimeout: durabilityTimeoutOrDefault(opts.DurabilityLevelTimeout, opts.Deadline),
 		}
 	}
 
@@ -660,6 +781,7 @@ func (crud *crudComponent) store(opName string, opcode memd.CmdCode, opts storeO
 		opts.RetryStrategy = crud.defaultRetryStrategy
 	}
 
+
 	var preserveExpiryFrame *memd.PreserveExpiryFrame
 	if opts.PreserveExpiry {
 		preserveExpiryFrame = &memd.PreserveExpiryFrame{}
@@ -668,29 +790,30 @@ func (crud *crudComponent) store(opName string, opcode memd.CmdCode, opts storeO
 	extraBuf := make([]byte, 8)
 	binary.BigEndian.PutUint32(extraBuf[0:], opts.Flags)
 	binary.BigEndian.PutUint32(extraBuf[4:], opts.Expiry)
-	req := &memdQRequest{
-		Packet: memd.Packet{
-			Magic:                  memd.CmdMagicReq,
-			Command:                opcode,
-			Datatype:               opts.Datatype,
-			Cas:                    uint64(opts.Cas),
-			Extras:                 extraBuf,
-			Key:                    opts.Key,
-			Value:                  opts.Value,
-			DurabilityLevelFrame:   duraLevelFrame,
-			DurabilityTimeoutFrame: duraTimeoutFrame,
-			UserImpersonationFrame: userFrame,
-			CollectionID:           opts.CollectionID,
-			PreserveExpiryFrame:    preserveExpiryFrame,
-		},
-		Callback:         handler,
-		RootTraceContext: tracer.RootContext(),
-		CollectionName:   opts.CollectionName,
-		ScopeName:        opts.ScopeName,
-		RetryStrategy:    opts.RetryStrategy,
+	req := acquireMemdQRequest()
+	req.Packet = memd.Packet{
+		Magic:                  memd.CmdMagicReq,
+		Command:                opcode,
+		Datatype:               opts.Datatype,
+		Cas:                    uint64(opts.Cas),
+		Extras:                 extraBuf,
+		Key:                    opts.Key,
+		Value:                  opts.Value,
+		DurabilityLevelFrame:   duraLevelFrame,
+		DurabilityTimeoutFrame: duraTimeoutFrame,
+		UserImpersonationFrame: userFrame,
+		CollectionID:           opts.CollectionID,
+		PreserveExpiryFrame:    preserveExpiryFrame,
 	}
+	req.Callback = handler
+	req.RootTraceContext = tracer.RootContext()
+	req.CollectionName = opts.CollectionName
+	req.ScopeName = opts.ScopeName
+	req.RetryStrategy = opts.RetryStrategy
+	req.userMetadata = opts.UserMetadata
 
-	op, err := crud.cidMgr.Dispatch(req)
+	req.dispatchDeadline = opts.Deadline
+	_, err := crud.cidMgr.Dispatch(req)
 	if err != nil {
 		tracer.Finish()
 		return nil, err
@@ -706,6 +829,9 @@ func (crud *crudComponent) store(opName string, opcode memd.CmdCode, opts storeO
 		}))
 	}
 
+	op := req.pendingOp()
+	req.release()
+
 	return op, nil
 }
 
@@ -727,6 +853,7 @@ func (crud *crudComponent) Set(opts SetOptions, cb StoreCallback) (PendingOp, er
 		Deadline:               opts.Deadline,
 		User:                   opts.User,
 		PreserveExpiry:         opts.PreserveExpiry,
+		UserMetadata:           opts.UserMetadata,
 	}, cb)
 }
 
@@ -747,6 +874,7 @@ func (crud *crudComponent) Add(opts AddOptions, cb StoreCallback) (PendingOp, er
 		CollectionID:           opts.CollectionID,
 		Deadline:               opts.Deadline,
 		User:                   opts.User,
+		UserMetadata:           opts.UserMetadata,
 	}, cb)
 }
 
@@ -759,6 +887,7 @@ func (crud *crudComponent) Replace(opts ReplaceOptions, cb StoreCallback) (Pendi
 
 func (crud *crudComponent) adjoin(opName string, opcode memd.CmdCode, opts AdjoinOptions, cb AdjoinCallback) (PendingOp, error) {
 	tracer := crud.tracer.StartTelemeteryHandler(metricValueServiceKeyValue, opName, opts.TraceContext)
+	tracer.SetDeadline(opts.Deadline)
 
 	handler := func(resp *memdQResponse, req *memdQRequest, err error) {
 		if err != nil {
@@ -778,6 +907,7 @@ func (crud *crudComponent) adjoin(opName string, opcode memd.CmdCode, opts Adjoi
 			MutationToken: mutToken,
 		}
 		res.Internal.ResourceUnits = req.ResourceUnits()
+		res.UserMetadata = req.UserMetadata()
 
 		tracer.Finish()
 		cb(res, nil)
@@ -793,7 +923,7 @@ func (crud *crudComponent) adjoin(opName string, opcode memd.CmdCode, opts Adjoi
 			DurabilityLevel: opts.DurabilityLevel,
 		}
 		duraTimeoutFrame = &memd.DurabilityTimeoutFrame{
-			DurabilityTimeout: opts.DurabilityLevelTimeout,
+			DurabilityTimeout: durabilityTimeoutOrDefault(opts.DurabilityLevelTimeout, opts.Deadline),
 		}
 	}
 
@@ -833,8 +963,10 @@ func (crud *crudComponent) adjoin(opName string, opcode memd.CmdCode, opts Adjoi
 		CollectionName:   opts.CollectionName,
 		ScopeName:        opts.ScopeName,
 		RetryStrategy:    opts.RetryStrategy,
+		userMetadata:     opts.UserMetadata,
 	}
 
+	req.dispatchDeadline = opts.Deadline
 	op, err := crud.cidMgr.Dispatch(req)
 	if err != nil {
 		tracer.Finish()
@@ -864,6 +996,7 @@ func (crud *crudComponent) Prepend(opts AdjoinOptions, cb AdjoinCallback) (Pendi
 
 func (crud *crudComponent) counter(opName string, opcode memd.CmdCode, opts CounterOptions, cb CounterCallback) (PendingOp, error) {
 	tracer := crud.tracer.StartTelemeteryHandler(metricValueServiceKeyValue, opName, opts.TraceContext)
+	tracer.SetDeadline(opts.Deadline)
 
 	handler := func(resp *memdQResponse, req *memdQRequest, err error) {
 		if err != nil {
@@ -891,6 +1024,7 @@ func (crud *crudComponent) counter(opName string, opcode memd.CmdCode, opts Coun
 			MutationToken: mutToken,
 		}
 		res.Internal.ResourceUnits = req.ResourceUnits()
+		res.UserMetadata = req.UserMetadata()
 
 		tracer.Finish()
 		cb(res, nil)
@@ -911,7 +1045,7 @@ func (crud *crudComponent) counter(opName string, opcode memd.CmdCode, opts Coun
 			DurabilityLevel: opts.DurabilityLevel,
 		}
 		duraTimeoutFrame = &memd.DurabilityTimeoutFrame{
-			DurabilityTimeout: opts.DurabilityLevelTimeout,
+			DurabilityTimeout: durabilityTimeoutOrDefault(opts.DurabilityLevelTimeout, opts.Deadline),
 		}
 	}
 
@@ -960,8 +1094,10 @@ func (crud *crudComponent) counter(opName string, opcode memd.CmdCode, opts Coun
 		CollectionName:   opts.CollectionName,
 		ScopeName:        opts.ScopeName,
 		RetryStrategy:    opts.RetryStrategy,
+		userMetadata:     opts.UserMetadata,
 	}
 
+	req.dispatchDeadline = opts.Deadline
 	op, err := crud.cidMgr.Dispatch(req)
 	if err != nil {
 		tracer.Finish()
@@ -991,6 +1127,7 @@ func (crud *crudComponent) Decrement(opts CounterOptions, cb CounterCallback) (P
 
 func (crud *crudComponent) GetRandom(opts GetRandomOptions, cb GetRandomCallback) (PendingOp, error) {
 	tracer := crud.tracer.StartTelemeteryHandler(metricValueServiceKeyValue, "GetRandom", opts.TraceContext)
+	tracer.SetDeadline(opts.Deadline)
 
 	handler := func(resp *memdQResponse, req *memdQRequest, err error) {
 		if err != nil {
@@ -1006,12 +1143,19 @@ func (crud *crudComponent) GetRandom(opts GetRandomOptions, cb GetRandomCallback
 		}
 
 		flags := binary.BigEndian.Uint32(resp.Extras[0:])
+		value, datatype, err := crud.afterRead(resp.Key, resp.Value, resp.Datatype)
+		if err != nil {
+			tracer.Finish()
+			cb(nil, err)
+			return
+		}
+
 		res := &GetRandomResult{
 			Key:      resp.Key,
-			Value:    resp.Value,
+			Value:    value,
 			Flags:    flags,
 			Cas:      Cas(resp.Cas),
-			Datatype: resp.Datatype,
+			Datatype: datatype,
 		}
 		res.Internal.ResourceUnits = req.ResourceUnits()
 
@@ -1049,6 +1193,7 @@ func (crud *crudComponent) GetRandom(opts GetRandomOptions, cb GetRandomCallback
 		ScopeName:        opts.ScopeName,
 	}
 
+	req.dispatchDeadline = opts.Deadline
 	op, err := crud.cidMgr.Dispatch(req)
 	if err != nil {
 		tracer.Finish()
@@ -1070,6 +1215,7 @@ func (crud *crudComponent) GetRandom(opts GetRandomOptions, cb GetRandomCallback
 
 func (crud *crudComponent) GetMeta(opts GetMetaOptions, cb GetMetaCallback) (PendingOp, error) {
 	tracer := crud.tracer.StartTelemeteryHandler(metricValueServiceKeyValue, "GetMeta", opts.TraceContext)
+	tracer.SetDeadline(opts.Deadline)
 
 	handler := func(resp *memdQResponse, req *memdQRequest, err error) {
 		if err != nil {
@@ -1132,6 +1278,7 @@ func (crud *crudComponent) GetMeta(opts GetMetaOptions, cb GetMetaCallback) (Pen
 		RetryStrategy:    opts.RetryStrategy,
 	}
 
+	req.dispatchDeadline = opts.Deadline
 	op, err := crud.cidMgr.Dispatch(req)
 	if err != nil {
 		tracer.Finish()
@@ -1153,6 +1300,7 @@ func (crud *crudComponent) GetMeta(opts GetMetaOptions, cb GetMetaCallback) (Pen
 
 func (crud *crudComponent) SetMeta(opts SetMetaOptions, cb SetMetaCallback) (PendingOp, error) {
 	tracer := crud.tracer.StartTelemeteryHandler(metricValueServiceKeyValue, "SetMeta", opts.TraceContext)
+	tracer.SetDeadline(opts.Deadline)
 
 	handler := func(resp *memdQResponse, req *memdQRequest, err error) {
 		if err != nil {
@@ -1202,7 +1350,7 @@ func (crud *crudComponent) SetMeta(opts SetMetaOptions, cb SetMetaCallback) (Pen
 			Magic:                  memd.CmdMagicReq,
 			Command:                memd.CmdSetMeta,
 			Datatype:               opts.Datatype,
-			Cas:                    0,
+			Cas:                    uint64(opts.CompareCas),
 			Extras:                 extraBuf,
 			Key:                    opts.Key,
 			Value:                  opts.Value,
@@ -1216,6 +1364,7 @@ func (crud *crudComponent) SetMeta(opts SetMetaOptions, cb SetMetaCallback) (Pen
 		RetryStrategy:    opts.RetryStrategy,
 	}
 
+	req.dispatchDeadline = opts.Deadline
 	op, err := crud.cidMgr.Dispatch(req)
 	if err != nil {
 		tracer.Finish()
@@ -1237,6 +1386,7 @@ func (crud *crudComponent) SetMeta(opts SetMetaOptions, cb SetMetaCallback) (Pen
 
 func (crud *crudComponent) DeleteMeta(opts DeleteMetaOptions, cb DeleteMetaCallback) (PendingOp, error) {
 	tracer := crud.tracer.StartTelemeteryHandler(metricValueServiceKeyValue, "DeleteMeta", opts.TraceContext)
+	tracer.SetDeadline(opts.Deadline)
 
 	handler := func(resp *memdQResponse, req *memdQRequest, err error) {
 		if err != nil {
@@ -1286,7 +1436,7 @@ func (crud *crudComponent) DeleteMeta(opts DeleteMetaOptions, cb DeleteMetaCallb
 			Magic:                  memd.CmdMagicReq,
 			Command:                memd.CmdDelMeta,
 			Datatype:               opts.Datatype,
-			Cas:                    0,
+			Cas:                    uint64(opts.CompareCas),
 			Extras:                 extraBuf,
 			Key:                    opts.Key,
 			Value:                  opts.Value,
@@ -1300,6 +1450,7 @@ func (crud *crudComponent) DeleteMeta(opts DeleteMetaOptions, cb DeleteMetaCallb
 		RetryStrategy:    opts.RetryStrategy,
 	}
 
+	req.dispatchDeadline = opts.Deadline
 	op, err := crud.cidMgr.Dispatch(req)
 	if err != nil {
 		tracer.Finish()
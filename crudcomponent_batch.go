@@ -0,0 +1,132 @@
+package gocbcore
+
+import (
+	"sort"
+	"sync/atomic"
+)
+
+func (crud *crudComponent) BatchTouch(opts BatchTouchOptions, cb BatchTouchCallback) (PendingOp, error) {
+	if len(opts.Keys) == 0 {
+		return nil, wrapError(errInvalidArgument, "at least one key must be provided")
+	}
+
+	parentOp := &multiPendingOp{}
+	snapshotOp, err := crud.configSnapshotProvider.WaitForConfigSnapshot(opts.Deadline, func(result *WaitForConfigSnapshotResult, err error) {
+		parentOp.IncrementCompletedOps()
+		if err != nil {
+			cb(nil, err)
+			return
+		}
+
+		items := make([]BatchTouchItem, len(opts.Keys))
+		remaining := int32(len(opts.Keys))
+		itemCompleted := func() {
+			if atomic.AddInt32(&remaining, -1) == 0 {
+				cb(&BatchTouchResult{Items: items}, nil)
+			}
+		}
+
+		// Keys are dispatched grouped by the node which is active for them, this only affects the order in which the
+		// requests are queued as each key is still sent as a separate request.
+		for _, group := range groupKeysByServer(result.Snapshot, opts.Keys) {
+			for _, idx := range group {
+				item := &items[idx]
+				item.Key = opts.Keys[idx]
+
+				op, err := crud.batchTouchKey(opts, item, itemCompleted)
+				if err != nil {
+					item.Err = err
+					itemCompleted()
+					continue
+				}
+				parentOp.AddOp(op)
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	parentOp.AddOp(snapshotOp)
+
+	return parentOp, nil
+}
+
+func (crud *crudComponent) batchTouchKey(opts BatchTouchOptions, item *BatchTouchItem, completed func()) (PendingOp, error) {
+	if opts.FetchValues {
+		return crud.GetAndTouch(GetAndTouchOptions{
+			Key:            item.Key,
+			Expiry:         opts.Expiry,
+			CollectionName: opts.CollectionName,
+			ScopeName:      opts.ScopeName,
+			CollectionID:   opts.CollectionID,
+			RetryStrategy:  opts.RetryStrategy,
+			Deadline:       opts.Deadline,
+			User:           opts.User,
+			TraceContext:   opts.TraceContext,
+			UserMetadata:   opts.UserMetadata,
+		}, func(res *GetAndTouchResult, err error) {
+			if err != nil {
+				item.Err = err
+			} else {
+				item.Cas = res.Cas
+				item.Value = res.Value
+				item.Flags = res.Flags
+				item.Datatype = res.Datatype
+			}
+			completed()
+		})
+	}
+
+	return crud.Touch(TouchOptions{
+		Key:            item.Key,
+		Expiry:         opts.Expiry,
+		CollectionName: opts.CollectionName,
+		ScopeName:      opts.ScopeName,
+		CollectionID:   opts.CollectionID,
+		RetryStrategy:  opts.RetryStrategy,
+		Deadline:       opts.Deadline,
+		User:           opts.User,
+		TraceContext:   opts.TraceContext,
+		UserMetadata:   opts.UserMetadata,
+	}, func(res *TouchResult, err error) {
+		if err != nil {
+			item.Err = err
+		} else {
+			item.Cas = res.Cas
+			item.MutationToken = res.MutationToken
+		}
+		completed()
+	})
+}
+
+// groupKeysByServer returns the indexes of keys grouped by the index of the server which is active for each key,
+// ordered by server index. Keys which cannot be mapped to a server are grouped together last, they are still
+// dispatched so that the kv layer can retry them once the config has been updated.
+func groupKeysByServer(snapshot *ConfigSnapshot, keys [][]byte) [][]int {
+	groups := make(map[int][]int)
+	for i, key := range keys {
+		srvIdx, err := snapshot.KeyToServer(key, 0)
+		if err != nil || srvIdx < 0 {
+			srvIdx = -1
+		}
+		groups[srvIdx] = append(groups[srvIdx], i)
+	}
+
+	srvIdxs := make([]int, 0, len(groups))
+	for srvIdx := range groups {
+		srvIdxs = append(srvIdxs, srvIdx)
+	}
+	sort.Slice(srvIdxs, func(i, j int) bool {
+		if srvIdxs[i] < 0 || srvIdxs[j] < 0 {
+			return srvIdxs[j] < 0 && srvIdxs[i] >= 0
+		}
+		return srvIdxs[i] < srvIdxs[j]
+	})
+
+	grouped := make([][]int, len(srvIdxs))
+	for i, srvIdx := range srvIdxs {
+		grouped[i] = groups[srvIdx]
+	}
+
+	return grouped
+}
@@ -0,0 +1,259 @@
+package gocbcore
+
+import (
+	"encoding/binary"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/couchbase/gocbcore/v10/memd"
+	"github.com/stretchr/testify/mock"
+)
+
+// newTestBatchCrudComponent creates a crudComponent which routes with a kvMux snapshot and dispatches each request
+// to dispatch, recording the keys dispatched.
+func newTestBatchCrudComponent(dispatch func(req *memdQRequest) (PendingOp, error)) (*crudComponent, func() []string) {
+	var lock sync.Mutex
+	var dispatched []string
+	dispatcher := new(mockDispatcher)
+	dispatcher.On("CollectionsEnabled").Return(false)
+	dispatcher.On("DispatchDirect", mock.AnythingOfType("*gocbcore.memdQRequest")).Return(
+		func(req *memdQRequest) (PendingOp, error) {
+			lock.Lock()
+			dispatched = append(dispatched, string(req.Key))
+			lock.Unlock()
+
+			return dispatch(req)
+		}, nil)
+
+	crud := &crudComponent{
+		cidMgr:                 &collectionsComponent{dispatcher: dispatcher},
+		defaultRetryStrategy:   newFailFastRetryStrategy(),
+		tracer:                 newTracerComponent(&noopTracer{}, "", true, &noopMeter{}, nil),
+		configSnapshotProvider: newTestSnapshotMux([][]int{{1, 0}, {0, 1}, {-1, 0}, {1, 0}}, 1),
+	}
+
+	return crud, func() []string {
+		lock.Lock()
+		defer lock.Unlock()
+		return dispatched
+	}
+}
+
+// testBatchRespond completes each request as soon as it is dispatched, failing it if its key has an error set.
+func testBatchRespond(errs map[string]error) func(req *memdQRequest) (PendingOp, error) {
+	return func(req *memdQRequest) (PendingOp, error) {
+		if err := errs[string(req.Key)]; err != nil {
+			req.tryCallback(nil, err)
+			return req, nil
+		}
+
+		resp := &memdQResponse{
+			Packet: &memd.Packet{
+				Cas: uint64(len(req.Key)),
+			},
+		}
+		if req.Command == memd.CmdGAT {
+			resp.Extras = make([]byte, 4)
+			binary.BigEndian.PutUint32(resp.Extras, 7)
+			resp.Value = []byte("value-" + string(req.Key))
+		}
+		req.tryCallback(resp, nil)
+
+		return req, nil
+	}
+}
+
+func (suite *UnitTestSuite) waitForBatchTouch(resultCh chan *BatchTouchResult) *BatchTouchResult {
+	select {
+	case result := <-resultCh:
+		return result
+	case <-time.After(5 * time.Second):
+		suite.T().Fatalf("Timed out waiting for BatchTouch to complete")
+	}
+
+	return nil
+}
+
+func (suite *UnitTestSuite) TestBatchTouchPerKeyResults() {
+	crud, dispatched := newTestBatchCrudComponent(testBatchRespond(map[string]error{
+		"key-3": errDocumentNotFound,
+		"key-7": errDocumentLocked,
+	}))
+
+	var keys [][]byte
+	for i := 0; i < 10; i++ {
+		keys = append(keys, []byte(fmt.Sprintf("key-%d", i)))
+	}
+
+	resultCh := make(chan *BatchTouchResult, 1)
+	_, err := crud.BatchTouch(BatchTouchOptions{
+		Keys:     keys,
+		Expiry:   10,
+		Deadline: time.Now().Add(time.Second),
+	}, func(res *BatchTouchResult, err error) {
+		suite.Assert().NoError(err)
+		resultCh <- res
+	})
+	suite.Require().NoError(err)
+	result := suite.waitForBatchTouch(resultCh)
+	suite.Require().NotNil(result)
+	suite.Assert().Len(dispatched(), len(keys))
+
+	// Results are in the order of the keys, regardless of the order in which they were dispatched.
+	suite.Require().Len(result.Items, len(keys))
+	for i, item := range result.Items {
+		suite.Assert().Equal(keys[i], item.Key)
+		suite.Assert().Nil(item.Value)
+
+		switch i {
+		case 3:
+			suite.Assert().ErrorIs(item.Err, ErrDocumentNotFound)
+			suite.Assert().Zero(item.Cas)
+		case 7:
+			suite.Assert().ErrorIs(item.Err, ErrDocumentLocked)
+			suite.Assert().Zero(item.Cas)
+		default:
+			suite.Assert().NoError(item.Err)
+			suite.Assert().Equal(Cas(len(keys[i])), item.Cas)
+		}
+	}
+}
+
+func (suite *UnitTestSuite) TestBatchTouchFetchValues() {
+	crud, _ := newTestBatchCrudComponent(testBatchRespond(map[string]error{
+		"b": errDocumentNotFound,
+	}))
+
+	resultCh := make(chan *BatchTouchResult, 1)
+	_, err := crud.BatchTouch(BatchTouchOptions{
+		Keys:        [][]byte{[]byte("a"), []byte("b"), []byte("cc")},
+		FetchValues: true,
+		Deadline:    time.Now().Add(time.Second),
+	}, func(res *BatchTouchResult, err error) {
+		suite.Assert().NoError(err)
+		resultCh <- res
+	})
+	suite.Require().NoError(err)
+	result := suite.waitForBatchTouch(resultCh)
+	suite.Require().NotNil(result)
+	suite.Require().Len(result.Items, 3)
+
+	suite.Assert().NoError(result.Items[0].Err)
+	suite.Assert().Equal("value-a", string(result.Items[0].Value))
+	suite.Assert().Equal(uint32(7), result.Items[0].Flags)
+	suite.Assert().Equal(Cas(1), result.Items[0].Cas)
+
+	suite.Assert().ErrorIs(result.Items[1].Err, ErrDocumentNotFound)
+	suite.Assert().Nil(result.Items[1].Value)
+
+	suite.Assert().NoError(result.Items[2].Err)
+	suite.Assert().Equal("value-cc", string(result.Items[2].Value))
+	suite.Assert().Equal(Cas(2), result.Items[2].Cas)
+}
+
+func (suite *UnitTestSuite) TestBatchTouchCancel() {
+	// Requests are left in flight.
+	crud, dispatched := newTestBatchCrudComponent(func(req *memdQRequest) (PendingOp, error) {
+		return req, nil
+	})
+
+	keys := [][]byte{[]byte("a"), []byte("b"), []byte("c")}
+	resultCh := make(chan *BatchTouchResult, 1)
+	op, err := crud.BatchTouch(BatchTouchOptions{
+		Keys:     keys,
+		Deadline: time.Now().Add(5 * time.Second),
+	}, func(res *BatchTouchResult, err error) {
+		suite.Assert().NoError(err)
+		resultCh <- res
+	})
+	suite.Require().NoError(err)
+	suite.Require().Eventually(func() bool {
+		return len(dispatched()) == len(keys)
+	}, 5*time.Second, time.Millisecond)
+
+	// Every key which is still in flight fails individually, the batch then completes.
+	suite.Require().True(op.Cancel())
+	result := suite.waitForBatchTouch(resultCh)
+	suite.Require().NotNil(result)
+	suite.Require().Len(result.Items, len(keys))
+	for i, item := range result.Items {
+		suite.Assert().Equal(keys[i], item.Key)
+		suite.Assert().ErrorIs(item.Err, ErrRequestCanceled)
+	}
+}
+
+func (suite *UnitTestSuite) TestBatchTouchErrors() {
+	// A mux which never receives a config.
+	mux := &kvMux{
+		shutdownSig:     make(chan struct{}),
+		hasSeenConfigCh: make(chan struct{}),
+	}
+	mux.updateState(nil, &kvMuxState{})
+	crud := &crudComponent{
+		configSnapshotProvider: mux,
+	}
+
+	_, err := crud.BatchTouch(BatchTouchOptions{}, func(*BatchTouchResult, error) {
+		suite.T().Fatalf("Callback should not have been invoked")
+	})
+	suite.Assert().ErrorIs(err, ErrInvalidArgument)
+
+	errCh := make(chan error, 1)
+	_, err = crud.BatchTouch(BatchTouchOptions{
+		Keys:     [][]byte{[]byte("key")},
+		Deadline: time.Now().Add(10 * time.Millisecond),
+	}, func(res *BatchTouchResult, err error) {
+		suite.Assert().Nil(res)
+		errCh <- err
+	})
+	suite.Require().NoError(err)
+
+	select {
+	case err := <-errCh:
+		suite.Assert().ErrorIs(err, ErrUnambiguousTimeout)
+	case <-time.After(5 * time.Second):
+		suite.T().Fatalf("Timed out waiting for BatchTouch to fail")
+	}
+}
+
+func (suite *UnitTestSuite) TestGroupKeysByServer() {
+	vbEntries := [][]int{{1, 0}, {0, 1}, {-1, 0}, {1, 0}}
+	snapshot := &ConfigSnapshot{
+		state: &kvMuxState{
+			routeCfg: routeConfig{
+				vbMap: newVbucketMap(vbEntries, 1),
+			},
+		},
+	}
+
+	var keys [][]byte
+	for i := 0; i < 50; i++ {
+		keys = append(keys, []byte(fmt.Sprintf("key-%d", i)))
+	}
+
+	groups := groupKeysByServer(snapshot, keys)
+	suite.Require().NotEmpty(groups)
+
+	seen := make(map[int]struct{})
+	var srvIdxs []int
+	for _, group := range groups {
+		suite.Require().NotEmpty(group)
+
+		vbID, err := snapshot.KeyToVbucket(keys[group[0]])
+		suite.Require().NoError(err)
+		srvIdx := vbEntries[vbID][0]
+		srvIdxs = append(srvIdxs, srvIdx)
+
+		for _, idx := range group {
+			vbID, err := snapshot.KeyToVbucket(keys[idx])
+			suite.Require().NoError(err)
+			suite.Assert().Equal(srvIdx, vbEntries[vbID][0])
+
+			seen[idx] = struct{}{}
+		}
+	}
+	// Groups are ordered by server index, with the keys which could not be mapped to a server last.
+	suite.Assert().Equal([]int{0, 1, -1}, srvIdxs)
+	suite.Assert().Len(seen, len(keys))
+}
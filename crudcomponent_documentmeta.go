@@ -0,0 +1,99 @@
+package gocbcore
+
+import (
+	"encoding/json"
+	"strconv"
+
+	"github.com/couchbase/gocbcore/v10/memd"
+)
+
+const documentMetaXattrPath = "$document"
+
+// documentMetaXattr is the layout of the $document virtual xattr, numeric values which may exceed the range of a
+// JSON number are encoded as hex strings.
+type documentMetaXattr struct {
+	SeqNo      string   `json:"seqno"`
+	Exptime    uint32   `json:"exptime"`
+	Flags      uint32   `json:"flags"`
+	ValueBytes uint32   `json:"value_bytes"`
+	Deleted    bool     `json:"deleted"`
+	Datatype   []string `json:"datatype"`
+}
+
+func (crud *crudComponent) GetDocumentMeta(opts GetDocumentMetaOptions, cb GetDocumentMetaCallback) (PendingOp, error) {
+	return crud.LookupIn(LookupInOptions{
+		Key:   opts.Key,
+		Flags: memd.SubdocDocFlagAccessDeleted,
+		Ops: []SubDocOp{
+			{
+				Op:    memd.SubDocOpGet,
+				Flags: memd.SubdocFlagXattrPath,
+				Path:  documentMetaXattrPath,
+			},
+		},
+		CollectionName: opts.CollectionName,
+		ScopeName:      opts.ScopeName,
+		CollectionID:   opts.CollectionID,
+		RetryStrategy:  opts.RetryStrategy,
+		Deadline:       opts.Deadline,
+		User:           opts.User,
+		TraceContext:   opts.TraceContext,
+	}, func(result *LookupInResult, err error) {
+		if err != nil {
+			cb(nil, err)
+			return
+		}
+
+		res, err := documentMetaResult(result)
+		if err != nil {
+			cb(nil, err)
+			return
+		}
+
+		cb(res, nil)
+	})
+}
+
+func documentMetaResult(result *LookupInResult) (*GetDocumentMetaResult, error) {
+	if len(result.Ops) != 1 {
+		return nil, errProtocol
+	}
+	if result.Ops[0].Err != nil {
+		return nil, result.Ops[0].Err
+	}
+
+	var meta documentMetaXattr
+	if err := json.Unmarshal(result.Ops[0].Value, &meta); err != nil {
+		return nil, wrapError(errParsingFailure, "failed to parse $document xattr")
+	}
+
+	res := &GetDocumentMetaResult{
+		Cas:        result.Cas,
+		Expiry:     meta.Exptime,
+		Flags:      meta.Flags,
+		ValueBytes: meta.ValueBytes,
+		Deleted:    meta.Deleted || result.Internal.IsDeleted,
+	}
+	res.Internal.ResourceUnits = result.Internal.ResourceUnits
+
+	if meta.SeqNo != "" {
+		seqNo, err := strconv.ParseUint(meta.SeqNo, 0, 64)
+		if err != nil {
+			return nil, wrapError(errParsingFailure, "failed to parse $document seqno")
+		}
+		res.SeqNo = SeqNo(seqNo)
+	}
+
+	for _, datatype := range meta.Datatype {
+		switch datatype {
+		case "json":
+			res.Datatype |= uint8(memd.DatatypeFlagJSON)
+		case "snappy":
+			res.Datatype |= uint8(memd.DatatypeFlagCompressed)
+		case "xattr":
+			res.Datatype |= uint8(memd.DatatypeFlagXattrs)
+		}
+	}
+
+	return res, nil
+}
@@ -0,0 +1,43 @@
+package gocbcore
+
+import (
+	"github.com/couchbase/gocbcore/v10/memd"
+)
+
+func (suite *UnitTestSuite) TestDocumentMetaResult() {
+	result := &LookupInResult{
+		Cas: 1234,
+		Ops: []SubDocResult{
+			{
+				Value: []byte(`{"CAS":"0x4d2","vbucket_uuid":"0x1","seqno":"0x1f","revid":"3","exptime":600,"value_bytes":12,` +
+					`"deleted":false,"flags":33554432,"datatype":["json","xattr"],"value_crc32c":"0x1"}`),
+			},
+		},
+	}
+
+	res, err := documentMetaResult(result)
+	suite.Require().Nil(err, err)
+	suite.Assert().Equal(Cas(1234), res.Cas)
+	suite.Assert().Equal(uint32(600), res.Expiry)
+	suite.Assert().Equal(uint32(33554432), res.Flags)
+	suite.Assert().Equal(uint8(memd.DatatypeFlagJSON|memd.DatatypeFlagXattrs), res.Datatype)
+	suite.Assert().Equal(SeqNo(31), res.SeqNo)
+	suite.Assert().Equal(uint32(12), res.ValueBytes)
+	suite.Assert().False(res.Deleted)
+
+	// Tombstones are reported as deleted.
+	result.Ops[0].Value = []byte(`{"seqno":"0x20","deleted":true,"datatype":["raw"]}`)
+	result.Internal.IsDeleted = true
+	res, err = documentMetaResult(result)
+	suite.Require().Nil(err, err)
+	suite.Assert().True(res.Deleted)
+	suite.Assert().Zero(res.Datatype)
+
+	result.Ops[0] = SubDocResult{Err: ErrDocumentNotFound}
+	_, err = documentMetaResult(result)
+	suite.Assert().ErrorIs(err, ErrDocumentNotFound)
+
+	result.Ops[0] = SubDocResult{Value: []byte(`{"seqno":"abc"}`)}
+	_, err = documentMetaResult(result)
+	suite.Assert().ErrorIs(err, ErrParsingFailure)
+}
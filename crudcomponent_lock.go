@@ -0,0 +1,253 @@
+package gocbcore
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// lockRetryStrategy wraps a RetryStrategy so that requests failing because the document is locked are returned to
+// the lockDocumentOp, which applies its own backoff, rather than being retried within the kv layer.
+type lockRetryStrategy struct {
+	wrapped RetryStrategy
+}
+
+func (rs *lockRetryStrategy) RetryAfter(req RetryRequest, reason RetryReason) RetryAction {
+	if reason == KVLockedRetryReason {
+		return &NoRetryRetryAction{}
+	}
+
+	return rs.wrapped.RetryAfter(req, reason)
+}
+
+type lockDocumentOp struct {
+	opts       LockDocumentOptions
+	callback   LockDocumentCallback
+	getAndLock func(GetAndLockOptions, GetAndLockCallback) (PendingOp, error)
+	unlock     func(UnlockOptions, UnlockCallback) (PendingOp, error)
+	start      time.Time
+
+	lock      sync.Mutex
+	currentOp PendingOp
+	timer     *time.Timer
+	attempts  uint32
+	completed uint32
+}
+
+func (op *lockDocumentOp) Cancel() bool {
+	if !op.complete() {
+		return false
+	}
+
+	op.lock.Lock()
+	if op.timer != nil {
+		op.timer.Stop()
+	}
+	currentOp := op.currentOp
+	op.lock.Unlock()
+
+	if currentOp != nil {
+		currentOp.Cancel()
+	}
+
+	op.callback(nil, errRequestCanceled)
+	return true
+}
+
+// complete marks the op as completed, returning false if it has already been completed or cancelled.
+func (op *lockDocumentOp) complete() bool {
+	return atomic.CompareAndSwapUint32(&op.completed, 0, 1)
+}
+
+func (op *lockDocumentOp) isCompleted() bool {
+	return atomic.LoadUint32(&op.completed) == 1
+}
+
+func (op *lockDocumentOp) attempt() error {
+	pendingOp, err := op.getAndLock(GetAndLockOptions{
+		Key:            op.opts.Key,
+		LockTime:       op.opts.LockTime,
+		CollectionName: op.opts.CollectionName,
+		ScopeName:      op.opts.ScopeName,
+		CollectionID:   op.opts.CollectionID,
+		RetryStrategy:  &lockRetryStrategy{wrapped: op.opts.RetryStrategy},
+		Deadline:       op.opts.Deadline,
+		User:           op.opts.User,
+		TraceContext:   op.opts.TraceContext,
+		UserMetadata:   op.opts.UserMetadata,
+	}, op.handleResult)
+	if err != nil {
+		return err
+	}
+
+	op.lock.Lock()
+	op.currentOp = pendingOp
+	op.lock.Unlock()
+
+	return nil
+}
+
+func (op *lockDocumentOp) retry() {
+	if err := op.attempt(); err != nil {
+		if op.complete() {
+			op.callback(nil, err)
+		}
+	}
+}
+
+func (op *lockDocumentOp) handleResult(res *GetAndLockResult, err error) {
+	if err == nil {
+		if !op.complete() {
+			// The op was cancelled after the lock was acquired, the lock is released rather than leaving the
+			// document locked until the lock time expires.
+			op.releaseAbandonedLock(res.Cas)
+			return
+		}
+
+		op.callback(&LockDocumentResult{
+			Value:    res.Value,
+			Flags:    res.Flags,
+			Datatype: res.Datatype,
+			Lock: &DocumentLock{
+				key:            op.opts.Key,
+				collectionName: op.opts.CollectionName,
+				scopeName:      op.opts.ScopeName,
+				collectionID:   op.opts.CollectionID,
+				cas:            res.Cas,
+			},
+			UserMetadata: res.UserMetadata,
+		}, nil)
+		return
+	}
+
+	if !errors.Is(err, ErrDocumentLocked) {
+		if op.complete() {
+			op.callback(nil, err)
+		}
+		return
+	}
+
+	op.lock.Lock()
+	if op.isCompleted() {
+		op.lock.Unlock()
+		return
+	}
+
+	op.attempts++
+	backoff := op.opts.Backoff(op.attempts)
+	if !time.Now().Add(backoff).Before(op.opts.Deadline) {
+		attempts := op.attempts
+		op.lock.Unlock()
+
+		if op.complete() {
+			op.callback(nil, &TimeoutError{
+				InnerError:    errUnambiguousTimeout,
+				OperationID:   "LockDocument",
+				TimeObserved:  time.Since(op.start),
+				RetryReasons:  []RetryReason{KVLockedRetryReason},
+				RetryAttempts: attempts,
+				UserMetadata:  op.opts.UserMetadata,
+			})
+		}
+		return
+	}
+
+	op.currentOp = nil
+	op.timer = time.AfterFunc(backoff, op.retry)
+	op.lock.Unlock()
+}
+
+func (op *lockDocumentOp) releaseAbandonedLock(cas Cas) {
+	_, err := op.unlock(UnlockOptions{
+		Key:            op.opts.Key,
+		Cas:            cas,
+		CollectionName: op.opts.CollectionName,
+		ScopeName:      op.opts.ScopeName,
+		CollectionID:   op.opts.CollectionID,
+		RetryStrategy:  op.opts.RetryStrategy,
+		Deadline:       op.opts.Deadline,
+		User:           op.opts.User,
+	}, func(res *UnlockResult, err error) {
+		if err != nil {
+			logDebugf("Failed to release lock acquired by cancelled LockDocument: %v", err)
+		}
+	})
+	if err != nil {
+		logDebugf("Failed to release lock acquired by cancelled LockDocument: %v", err)
+	}
+}
+
+func (crud *crudComponent) LockDocument(opts LockDocumentOptions, cb LockDocumentCallback) (PendingOp, error) {
+	if opts.Deadline.IsZero() {
+		return nil, wrapError(errInvalidArgument, "a deadline must be provided")
+	}
+
+	if opts.RetryStrategy == nil {
+		opts.RetryStrategy = crud.defaultRetryStrategy
+	}
+	if opts.Backoff == nil {
+		opts.Backoff = ControlledBackoff
+	}
+
+	op := &lockDocumentOp{
+		opts:       opts,
+		callback:   cb,
+		getAndLock: crud.GetAndLock,
+		unlock:     crud.Unlock,
+		start:      time.Now(),
+	}
+	if err := op.attempt(); err != nil {
+		return nil, err
+	}
+
+	return op, nil
+}
+
+func (crud *crudComponent) UnlockDocument(opts UnlockDocumentOptions, cb UnlockCallback) (PendingOp, error) {
+	if opts.Lock == nil || opts.Lock.cas == 0 {
+		return nil, wrapError(errInvalidArgument, "a lock acquired by LockDocument must be provided")
+	}
+	if opts.Lock.isReleased() {
+		return nil, wrapError(errInvalidArgument, "lock has already been released")
+	}
+
+	op, err := crud.Unlock(UnlockOptions{
+		Key:            opts.Lock.key,
+		Cas:            opts.Lock.cas,
+		CollectionName: opts.Lock.collectionName,
+		ScopeName:      opts.Lock.scopeName,
+		CollectionID:   opts.Lock.collectionID,
+		RetryStrategy:  opts.RetryStrategy,
+		Deadline:       opts.Deadline,
+		User:           opts.User,
+		TraceContext:   opts.TraceContext,
+		UserMetadata:   opts.UserMetadata,
+	}, func(res *UnlockResult, err error) {
+		if err != nil {
+			// The lock is only marked as released once the server has released it, so that a failed unlock can be
+			// retried.
+			cb(nil, unlockDocumentError(err))
+			return
+		}
+
+		opts.Lock.release()
+		cb(res, nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return op, nil
+}
+
+// unlockDocumentError translates the errors returned by the server when a lock is no longer held. Older servers
+// return a CAS mismatch, rather than ErrDocumentNotLocked, once the lock has expired and the document been modified
+// or locked again.
+func unlockDocumentError(err error) error {
+	if errors.Is(err, ErrCasMismatch) {
+		return wrapError(errDocumentNotLocked, "lock expired before it was released")
+	}
+
+	return err
+}
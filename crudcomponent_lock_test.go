@@ -0,0 +1,209 @@
+package gocbcore
+
+import (
+	"errors"
+	"time"
+
+	"github.com/couchbase/gocbcore/v10/memd"
+	"github.com/stretchr/testify/mock"
+)
+
+type testLockPendingOp struct{}
+
+func (op *testLockPendingOp) Cancel() bool {
+	return false
+}
+
+func (suite *UnitTestSuite) newTestLockDocumentOp(results []error, deadline time.Time,
+	cb LockDocumentCallback) (*lockDocumentOp, *int) {
+	attempts := 0
+	op := &lockDocumentOp{
+		opts: LockDocumentOptions{
+			Key:           []byte("key"),
+			Deadline:      deadline,
+			RetryStrategy: NewBestEffortRetryStrategy(nil),
+			Backoff: func(retryAttempts uint32) time.Duration {
+				return 5 * time.Millisecond
+			},
+		},
+		callback: cb,
+		getAndLock: func(opts GetAndLockOptions, cb GetAndLockCallback) (PendingOp, error) {
+			action := opts.RetryStrategy.RetryAfter(&memdQRequest{}, KVLockedRetryReason)
+			suite.Assert().Equal(time.Duration(0), action.Duration())
+
+			err := results[attempts]
+			if attempts < len(results)-1 {
+				attempts++
+			}
+			go func() {
+				if err != nil {
+					cb(nil, err)
+					return
+				}
+
+				cb(&GetAndLockResult{Value: []byte("value"), Cas: 10}, nil)
+			}()
+			return &testLockPendingOp{}, nil
+		},
+		start: time.Now(),
+	}
+
+	return op, &attempts
+}
+
+func (suite *UnitTestSuite) TestLockDocumentRetriesLocked() {
+	crud := &crudComponent{}
+	_, err := crud.LockDocument(LockDocumentOptions{Key: []byte("key")}, func(*LockDocumentResult, error) {
+		suite.T().Fatalf("Callback should not have been invoked")
+	})
+	suite.Assert().ErrorIs(err, ErrInvalidArgument)
+
+	waitCh := make(chan struct{})
+	op, attempts := suite.newTestLockDocumentOp(
+		[]error{errDocumentLocked, errDocumentLocked, nil},
+		time.Now().Add(time.Second),
+		func(res *LockDocumentResult, err error) {
+			suite.Require().NoError(err)
+			suite.Assert().Equal("value", string(res.Value))
+			suite.Require().NotNil(res.Lock)
+			suite.Assert().Equal("key", string(res.Lock.Key()))
+			suite.Assert().Equal(Cas(10), res.Lock.Cas())
+			close(waitCh)
+		},
+	)
+	suite.Require().NoError(op.attempt())
+	<-waitCh
+	suite.Assert().Equal(2, *attempts)
+
+	waitCh = make(chan struct{})
+	op, _ = suite.newTestLockDocumentOp(
+		[]error{errDocumentLocked},
+		time.Now().Add(20*time.Millisecond),
+		func(res *LockDocumentResult, err error) {
+			suite.Assert().ErrorIs(err, ErrUnambiguousTimeout)
+			var tErr *TimeoutError
+			suite.Require().True(errors.As(err, &tErr))
+			suite.Assert().Equal("LockDocument", tErr.OperationID)
+			suite.Assert().Equal([]RetryReason{KVLockedRetryReason}, tErr.RetryReasons)
+			close(waitCh)
+		},
+	)
+	suite.Require().NoError(op.attempt())
+	<-waitCh
+
+	waitCh = make(chan struct{})
+	op, _ = suite.newTestLockDocumentOp(
+		[]error{errDocumentNotFound},
+		time.Now().Add(time.Second),
+		func(res *LockDocumentResult, err error) {
+			suite.Assert().ErrorIs(err, ErrDocumentNotFound)
+			close(waitCh)
+		},
+	)
+	suite.Require().NoError(op.attempt())
+	<-waitCh
+}
+
+func (suite *UnitTestSuite) TestUnlockDocumentValidatesLock() {
+	crud := &crudComponent{}
+	_, err := crud.UnlockDocument(UnlockDocumentOptions{}, func(*UnlockResult, error) {
+		suite.T().Fatalf("Callback should not have been invoked")
+	})
+	suite.Assert().ErrorIs(err, ErrInvalidArgument)
+
+	lock := &DocumentLock{key: []byte("key"), cas: 10}
+	lock.release()
+	_, err = crud.UnlockDocument(UnlockDocumentOptions{Lock: lock}, func(*UnlockResult, error) {
+		suite.T().Fatalf("Callback should not have been invoked")
+	})
+	suite.Assert().ErrorIs(err, ErrInvalidArgument)
+
+	suite.Assert().ErrorIs(unlockDocumentError(errCasMismatch), ErrDocumentNotLocked)
+	suite.Assert().ErrorIs(unlockDocumentError(errDocumentNotFound), ErrDocumentNotFound)
+}
+
+func (suite *UnitTestSuite) TestLockDocumentCancelReleasesAcquiredLock() {
+	var canceledErr error
+	op, _ := suite.newTestLockDocumentOp(nil, time.Now().Add(time.Second), func(res *LockDocumentResult, err error) {
+		suite.Assert().Nil(res)
+		canceledErr = err
+	})
+
+	// The lock is acquired by the server, but the response is only handled after the op has been cancelled.
+	var lockCb GetAndLockCallback
+	op.getAndLock = func(opts GetAndLockOptions, cb GetAndLockCallback) (PendingOp, error) {
+		lockCb = cb
+		return &testLockPendingOp{}, nil
+	}
+	unlockCh := make(chan UnlockOptions, 1)
+	op.unlock = func(opts UnlockOptions, cb UnlockCallback) (PendingOp, error) {
+		unlockCh <- opts
+		cb(&UnlockResult{}, nil)
+		return &testLockPendingOp{}, nil
+	}
+
+	suite.Require().NoError(op.attempt())
+	suite.Require().NotNil(lockCb)
+
+	suite.Require().True(op.Cancel())
+	suite.Assert().ErrorIs(canceledErr, ErrRequestCanceled)
+	suite.Assert().False(op.Cancel())
+
+	lockCb(&GetAndLockResult{Value: []byte("value"), Cas: 10}, nil)
+
+	select {
+	case opts := <-unlockCh:
+		suite.Assert().Equal("key", string(opts.Key))
+		suite.Assert().Equal(Cas(10), opts.Cas)
+	default:
+		suite.T().Fatalf("Lock acquired after cancellation was not released")
+	}
+}
+
+func (suite *UnitTestSuite) TestUnlockDocumentReleasesLockOnSuccess() {
+	reqCh := make(chan *memdQRequest, 1)
+	dispatcher := new(mockDispatcher)
+	dispatcher.On("CollectionsEnabled").Return(false)
+	dispatcher.On("DispatchDirect", mock.AnythingOfType("*gocbcore.memdQRequest")).Return(&memdQRequest{}, nil).
+		Run(func(args mock.Arguments) {
+			reqCh <- args[0].(*memdQRequest)
+		})
+
+	crud := &crudComponent{
+		cidMgr:               &collectionsComponent{dispatcher: dispatcher},
+		defaultRetryStrategy: newFailFastRetryStrategy(),
+		tracer:               newTracerComponent(&noopTracer{}, "", true, &noopMeter{}, nil),
+	}
+
+	lock := &DocumentLock{key: []byte("key"), cas: 10}
+	unlock := func() (chan error, *memdQRequest) {
+		errCh := make(chan error, 1)
+		_, err := crud.UnlockDocument(UnlockDocumentOptions{
+			Lock:     lock,
+			Deadline: time.Now().Add(5 * time.Second),
+		}, func(res *UnlockResult, err error) {
+			errCh <- err
+		})
+		suite.Require().Nil(err, err)
+
+		req := <-reqCh
+		suite.Assert().Equal(uint64(10), req.Cas)
+		return errCh, req
+	}
+
+	// A failed unlock leaves the lock held so that it can be released again.
+	errCh, req := unlock()
+	req.tryCallback(nil, errTemporaryFailure)
+	suite.Assert().ErrorIs(<-errCh, ErrTemporaryFailure)
+	suite.Assert().False(lock.isReleased())
+
+	errCh, req = unlock()
+	req.tryCallback(&memdQResponse{Packet: &memd.Packet{Cas: 11}}, nil)
+	suite.Assert().Nil(<-errCh)
+	suite.Assert().True(lock.isReleased())
+
+	_, err := crud.UnlockDocument(UnlockDocumentOptions{Lock: lock}, func(*UnlockResult, error) {
+		suite.T().Fatalf("Callback should not have been invoked")
+	})
+	suite.Assert().ErrorIs(err, ErrInvalidArgument)
+}
@@ -0,0 +1,213 @@
+package gocbcore
+
+import (
+	"errors"
+	"sync"
+)
+
+func (crud *crudComponent) QuorumGet(opts QuorumGetOptions, cb QuorumGetCallback) (PendingOp, error) {
+	if opts.Quorum < 0 {
+		return nil, wrapError(errInvalidArgument, "quorum cannot be negative")
+	}
+
+	parentOp := &multiPendingOp{
+		isIdempotent: true,
+	}
+	snapshotOp, err := crud.configSnapshotProvider.WaitForConfigSnapshot(opts.Deadline, func(result *WaitForConfigSnapshotResult, err error) {
+		if err != nil {
+			parentOp.IncrementCompletedOps()
+			cb(nil, err)
+			return
+		}
+
+		numReplicas, err := result.Snapshot.NumReplicas()
+		if err != nil {
+			parentOp.IncrementCompletedOps()
+			cb(nil, err)
+			return
+		}
+
+		numCopies := numReplicas + 1
+		quorum := opts.Quorum
+		if quorum == 0 {
+			quorum = numCopies/2 + 1
+		}
+		if quorum > numCopies {
+			parentOp.IncrementCompletedOps()
+			cb(nil, wrapError(errInvalidArgument, "quorum cannot be greater than the number of copies of the document"))
+			return
+		}
+
+		op := &multiPendingOp{
+			isIdempotent: true,
+		}
+		parentOp.AddOp(op)
+		// At this point mark the snapshot op as being completed.
+		parentOp.IncrementCompletedOps()
+
+		tally := &quorumGetTally{
+			numCopies: numCopies,
+			quorum:    quorum,
+		}
+
+		opCompleted := func(res *QuorumGetResult, err error) {
+			parentOp.IncrementCompletedOps()
+			op.IncrementCompletedOps()
+
+			finalRes, done, finalErr := tally.add(res, err)
+			if !done {
+				return
+			}
+
+			cb(finalRes, finalErr)
+			op.Cancel()
+		}
+
+		for replicaIdx := 0; replicaIdx < numCopies; replicaIdx++ {
+			var curOp PendingOp
+			var err error
+			if replicaIdx == 0 {
+				curOp, err = crud.Get(GetOptions{
+					Key:            opts.Key,
+					CollectionName: opts.CollectionName,
+					ScopeName:      opts.ScopeName,
+					CollectionID:   opts.CollectionID,
+					RetryStrategy:  opts.RetryStrategy,
+					Deadline:       opts.Deadline,
+					User:           opts.User,
+					TraceContext:   opts.TraceContext,
+				}, func(result *GetResult, err error) {
+					if err != nil {
+						opCompleted(nil, err)
+						return
+					}
+
+					opCompleted(&QuorumGetResult{
+						Value:    result.Value,
+						Flags:    result.Flags,
+						Datatype: result.Datatype,
+						Cas:      result.Cas,
+					}, nil)
+				})
+			} else {
+				curOp, err = crud.GetOneReplica(GetOneReplicaOptions{
+					Key:            opts.Key,
+					CollectionName: opts.CollectionName,
+					ScopeName:      opts.ScopeName,
+					CollectionID:   opts.CollectionID,
+					RetryStrategy:  opts.RetryStrategy,
+					ReplicaIdx:     replicaIdx,
+					Deadline:       opts.Deadline,
+					User:           opts.User,
+					TraceContext:   opts.TraceContext,
+				}, func(result *GetReplicaResult, err error) {
+					if err != nil {
+						opCompleted(nil, err)
+						return
+					}
+
+					opCompleted(&QuorumGetResult{
+						Value:     result.Value,
+						Flags:     result.Flags,
+						Datatype:  result.Datatype,
+						Cas:       result.Cas,
+						IsReplica: true,
+					}, nil)
+				})
+			}
+			if err != nil {
+				opCompleted(nil, err)
+				continue
+			}
+			op.AddOp(curOp)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	parentOp.AddOp(snapshotOp)
+
+	return parentOp, nil
+}
+
+// quorumGetTally tracks the responses for each copy of a document read by QuorumGet and decides when the operation
+// can complete. Copies only agree when they have the same CAS, copies with equal values but different CAS values have
+// diverged and do not count towards the same quorum.
+type quorumGetTally struct {
+	numCopies int
+	quorum    int
+
+	lock        sync.Mutex
+	byCas       map[Cas]*QuorumGetResult
+	numByCas    map[Cas]int
+	numSuccess  int
+	numNotFound int
+	numFailed   int
+	numCanceled int
+	completed   bool
+}
+
+// add records the outcome of reading a single copy, returning the final result of the operation once one is known.
+// Only a single call to add will ever report that the operation is done.
+func (t *quorumGetTally) add(res *QuorumGetResult, err error) (*QuorumGetResult, bool, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.completed {
+		return nil, false, nil
+	}
+
+	if t.byCas == nil {
+		t.byCas = make(map[Cas]*QuorumGetResult)
+		t.numByCas = make(map[Cas]int)
+	}
+
+	if err != nil {
+		if errors.Is(err, ErrDocumentNotFound) {
+			t.numNotFound++
+		} else {
+			t.numFailed++
+			if errors.Is(err, ErrRequestCanceled) {
+				t.numCanceled++
+			}
+		}
+	} else {
+		t.numSuccess++
+		t.numByCas[res.Cas]++
+		// Prefer the active copy when the active and replicas agree.
+		if existing, ok := t.byCas[res.Cas]; !ok || (existing.IsReplica && !res.IsReplica) {
+			t.byCas[res.Cas] = res
+		}
+
+		if t.numByCas[res.Cas] >= t.quorum {
+			t.completed = true
+			quorumRes := t.byCas[res.Cas]
+			quorumRes.NumResponses = t.numByCas[res.Cas]
+			return quorumRes, true, nil
+		}
+	}
+
+	if t.numNotFound >= t.quorum {
+		t.completed = true
+		return nil, true, errDocumentNotFound
+	}
+
+	remaining := t.numCopies - t.numSuccess - t.numNotFound - t.numFailed
+	maxAgreeing := 0
+	for _, num := range t.numByCas {
+		if num > maxAgreeing {
+			maxAgreeing = num
+		}
+	}
+	if maxAgreeing+remaining < t.quorum && t.numNotFound+remaining < t.quorum {
+		t.completed = true
+		// If the only copies which failed were cancelled then the quorum was not reached because the operation was
+		// cancelled, rather than because the copies could not be read.
+		if t.numCanceled > 0 && t.numCanceled == t.numFailed {
+			return nil, true, errRequestCanceled
+		}
+		return nil, true, errDocumentUnretrievable
+	}
+
+	return nil, false, nil
+}
@@ -0,0 +1,158 @@
+package gocbcore
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+func (suite *UnitTestSuite) TestQuorumGetTally() {
+	type outcome struct {
+		cas Cas
+		err error
+	}
+
+	tests := []struct {
+		name        string
+		numCopies   int
+		quorum      int
+		outcomes    []outcome
+		expectDone  int
+		expectCas   Cas
+		expectError error
+	}{
+		{
+			name:       "majority agreeing cas",
+			numCopies:  3,
+			quorum:     2,
+			outcomes:   []outcome{{cas: 5}, {cas: 9}, {cas: 5}},
+			expectDone: 2,
+			expectCas:  5,
+		},
+		{
+			name:        "diverged copies do not agree",
+			numCopies:   3,
+			quorum:      2,
+			outcomes:    []outcome{{cas: 1}, {cas: 5}, {cas: 9}},
+			expectDone:  2,
+			expectError: ErrDocumentUnretrievable,
+		},
+		{
+			name:        "majority not found",
+			numCopies:   3,
+			quorum:      2,
+			outcomes:    []outcome{{err: errDocumentNotFound}, {cas: 5}, {err: errDocumentNotFound}},
+			expectDone:  2,
+			expectError: ErrDocumentNotFound,
+		},
+		{
+			name:        "quorum unreachable",
+			numCopies:   3,
+			quorum:      3,
+			outcomes:    []outcome{{cas: 5}, {err: errTemporaryFailure}, {cas: 5}},
+			expectDone:  1,
+			expectError: ErrDocumentUnretrievable,
+		},
+		{
+			name:        "cancelled",
+			numCopies:   3,
+			quorum:      2,
+			outcomes:    []outcome{{err: errRequestCanceled}, {err: errRequestCanceled}, {err: errRequestCanceled}},
+			expectDone:  1,
+			expectError: ErrRequestCanceled,
+		},
+		{
+			name:        "cancelled after a failure",
+			numCopies:   3,
+			quorum:      2,
+			outcomes:    []outcome{{err: errTemporaryFailure}, {err: errRequestCanceled}, {err: errRequestCanceled}},
+			expectDone:  1,
+			expectError: ErrDocumentUnretrievable,
+		},
+		{
+			name:       "single copy",
+			numCopies:  1,
+			quorum:     1,
+			outcomes:   []outcome{{cas: 7}},
+			expectDone: 0,
+			expectCas:  7,
+		},
+	}
+
+	for _, test := range tests {
+		suite.Run(test.name, func() {
+			tally := &quorumGetTally{
+				numCopies: test.numCopies,
+				quorum:    test.quorum,
+			}
+
+			doneIdx := -1
+			var res *QuorumGetResult
+			var resErr error
+			for i, o := range test.outcomes {
+				var copyRes *QuorumGetResult
+				if o.err == nil {
+					copyRes = &QuorumGetResult{Cas: o.cas}
+				}
+
+				finalRes, done, finalErr := tally.add(copyRes, o.err)
+				if done {
+					suite.Require().Equal(-1, doneIdx, "operation completed more than once")
+					doneIdx = i
+					res = finalRes
+					resErr = finalErr
+				}
+			}
+
+			suite.Require().Equal(test.expectDone, doneIdx)
+			if test.expectError != nil {
+				suite.Assert().True(errors.Is(resErr, test.expectError), resErr)
+				return
+			}
+
+			suite.Require().Nil(resErr, resErr)
+			suite.Assert().Equal(test.expectCas, res.Cas)
+		})
+	}
+}
+
+func (suite *UnitTestSuite) TestQuorumGetCancel() {
+	var reqsLock sync.Mutex
+	var reqs []*memdQRequest
+	dispatcher := new(mockDispatcher)
+	dispatcher.On("CollectionsEnabled").Return(false)
+	dispatcher.On("DispatchDirect", mock.AnythingOfType("*gocbcore.memdQRequest")).Return(&memdQRequest{}, nil).
+		Run(func(args mock.Arguments) {
+			reqsLock.Lock()
+			reqs = append(reqs, args[0].(*memdQRequest))
+			reqsLock.Unlock()
+		})
+
+	crud := &crudComponent{
+		cidMgr:                 &collectionsComponent{dispatcher: dispatcher},
+		defaultRetryStrategy:   newFailFastRetryStrategy(),
+		tracer:                 newTracerComponent(&noopTracer{}, "", true, &noopMeter{}, nil),
+		configSnapshotProvider: newTestSnapshotMux([][]int{{0, 1, 2}}, 2),
+	}
+
+	errCh := make(chan error, 1)
+	op, err := crud.QuorumGet(QuorumGetOptions{
+		Key:      []byte("key"),
+		Deadline: time.Now().Add(5 * time.Second),
+	}, func(res *QuorumGetResult, err error) {
+		suite.Assert().Nil(res)
+		errCh <- err
+	})
+	suite.Require().Nil(err, err)
+
+	suite.Require().Eventually(func() bool {
+		reqsLock.Lock()
+		defer reqsLock.Unlock()
+		return len(reqs) == 3
+	}, 5*time.Second, time.Millisecond)
+
+	suite.Require().True(op.Cancel())
+	suite.Assert().ErrorIs(<-errCh, ErrRequestCanceled)
+}
@@ -2,6 +2,7 @@ package gocbcore
 
 import (
 	"encoding/binary"
+	"encoding/hex"
 	"sync"
 	"time"
 
@@ -32,8 +33,104 @@ func (sol *subdocOpList) Reorder(ops []SubDocOp) {
 	sol.indexes = append(xAttrIndexes, opIndexes...)
 }
 
+// expandMutationMacroResults fills in the values of operations which wrote a mutation macro, the server does not
+// return the values that it expands macros to but we can determine them from the response. Values are formatted
+// in the same way as the server writes them.
+func expandMutationMacroResults(ops []SubDocOp, results []SubDocResult, cas Cas, mutToken MutationToken) {
+	for i, op := range ops {
+		if op.Flags&memd.SubdocFlagExpandMacros == 0 || results[i].Err != nil || results[i].Value != nil {
+			continue
+		}
+
+		switch string(op.Value) {
+		case `"${Mutation.CAS}"`:
+			results[i].Value = formatMutationMacroValue(uint64(cas))
+		case `"${Mutation.seqno}"`:
+			// The seqno is only known if mutation tokens are enabled.
+			if mutToken.SeqNo != 0 {
+				results[i].Value = formatMutationMacroValue(uint64(mutToken.SeqNo))
+			}
+		}
+	}
+}
+
+// formatMutationMacroValue formats a value in the same way as the server expands mutation macros, as a JSON string
+// containing the little-endian hex encoding of the value, see parseCASToMilliseconds.
+func formatMutationMacroValue(val uint64) []byte {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], val)
+
+	return []byte(`"0x` + hex.EncodeToString(buf[:]) + `"`)
+}
+
+// verifyWholeDocOp checks that operations which act on the whole document body, rather than on a path within it, have
+// an empty path and do not target xattrs. These can be combined with xattr operations in a single request.
+func verifyWholeDocOp(op SubDocOp) error {
+	switch op.Op {
+	case memd.SubDocOpGetDoc, memd.SubDocOpSetDoc, memd.SubDocOpAddDoc, memd.SubDocOpDeleteDoc:
+	default:
+		return nil
+	}
+
+	if op.Path != "" {
+		return wrapError(errInvalidArgument, "whole document operations must have an empty path")
+	}
+	if op.Flags&memd.SubdocFlagXattrPath != 0 {
+		return wrapError(errInvalidArgument, "whole document operations cannot be used with xattr paths")
+	}
+
+	return nil
+}
+
+// verifyExpandMacrosFlag checks that the expand macros flag is only used for xattr paths, the server only expands
+// macros within xattrs and otherwise fails the whole request with a less descriptive error.
+func verifyExpandMacrosFlag(flags memd.SubdocFlag) error {
+	if flags&memd.SubdocFlagExpandMacros != 0 && flags&memd.SubdocFlagXattrPath == 0 {
+		return wrapError(errInvalidArgument, "expand macros can only be used with xattr paths")
+	}
+
+	return nil
+}
+
+// verifyBinaryXattrFlag checks that the binary value flag is only used for xattr paths, and that the bucket
+// supports binary xattrs.
+func (crud *crudComponent) verifyBinaryXattrFlag(flags memd.SubdocFlag) error {
+	if flags&memd.SubdocFlagBinaryValue == 0 {
+		return nil
+	}
+
+	if flags&memd.SubdocFlagXattrPath == 0 {
+		return wrapError(errInvalidArgument, "binary values can only be used with xattr paths")
+	}
+
+	// We can get here before support status is actually known, we'll send the request unless we know for a fact
+	// that this is unsupported.
+	if crud.featureVerifier.HasBucketCapabilityStatus(BucketCapabilityBinaryXattr, CapabilityStatusUnsupported) {
+		return errFeatureNotAvailable
+	}
+
+	return nil
+}
+
 func (crud *crudComponent) LookupIn(opts LookupInOptions, cb LookupInCallback) (PendingOp, error) {
+	return crud.lookupIn(opts, lookupInInternalOptions{}, cb)
+}
+
+// lookupInInternalOptions are options used by components which perform lookups on behalf of other operations.
+type lookupInInternalOptions struct {
+	// readPreference routes the lookup to the node matching it using replica reads, opts.ReplicaIdx is ignored unless
+	// it is ReadPreferenceActiveOnly.
+	readPreference ReadPreference
+
+	// skipSubDocHooks prevents the sub-document value hooks being applied, for lookups whose ops were not provided by
+	// the user.
+	skipSubDocHooks bool
+}
+
+func (crud *crudComponent) lookupIn(opts LookupInOptions, internalOpts lookupInInternalOptions,
+	cb LookupInCallback) (PendingOp, error) {
 	tracer := crud.tracer.StartTelemeteryHandler(metricValueServiceKeyValue, "LookupIn", opts.TraceContext)
+	tracer.SetDeadline(opts.Deadline)
 
 	results := make([]SubDocResult, len(opts.Ops))
 	var subdocs subdocOpList
@@ -72,13 +169,24 @@ func (crud *crudComponent) LookupIn(opts LookupInOptions, cb LookupInCallback) (
 			results[subdocs.indexes[i]].Value = resp.Value[respIter+6 : respIter+6+resValueLen]
 			respIter += 6 + resValueLen
 		}
+		if !internalOpts.skipSubDocHooks {
+			if err := crud.afterSubDocRead(req.Key, opts.Ops, results); err != nil {
+				tracer.Finish()
+				cb(nil, err)
+				return
+			}
+		}
+
 		res := &LookupInResult{
 			Cas: Cas(resp.Cas),
 			Ops: results,
 		}
-		res.Internal.IsDeleted = isErrorStatus(err, memd.StatusSubDocSuccessDeleted) ||
+		res.IsDeleted = isErrorStatus(err, memd.StatusSubDocSuccessDeleted) ||
 			isErrorStatus(err, memd.StatusSubDocMultiPathFailureDeleted)
+		res.Internal.IsDeleted = res.IsDeleted
 		res.Internal.ResourceUnits = req.ResourceUnits()
+		res.UserMetadata = req.UserMetadata()
+		res.isReplica = req.ReplicaIdx > 0
 
 		tracer.Finish()
 		cb(res, nil)
@@ -105,6 +213,12 @@ func (crud *crudComponent) LookupIn(opts LookupInOptions, cb LookupInCallback) (
 		if op.Value != nil {
 			return nil, errInvalidArgument
 		}
+		if err := verifyWholeDocOp(op); err != nil {
+			return nil, err
+		}
+		if err := crud.verifyBinaryXattrFlag(op.Flags); err != nil {
+			return nil, err
+		}
 
 		pathBytes := pathBytesList[i]
 		pathBytesLen := len(pathBytes)
@@ -123,6 +237,10 @@ func (crud *crudComponent) LookupIn(opts LookupInOptions, cb LookupInCallback) (
 		}
 	}
 
+	if opts.AccessDeleted {
+		opts.Flags |= memd.SubdocDocFlagAccessDeleted
+	}
+
 	var extraBuf []byte
 	if opts.Flags != 0 {
 		if opts.Flags&memd.SubdocDocFlagReplicaRead != 0 {
@@ -157,10 +275,19 @@ func (crud *crudComponent) LookupIn(opts LookupInOptions, cb LookupInCallback) (
 		CollectionName:   opts.CollectionName,
 		ScopeName:        opts.ScopeName,
 		RetryStrategy:    opts.RetryStrategy,
+		userMetadata:     opts.UserMetadata,
 		ReplicaIdx:       opts.ReplicaIdx,
 		ServerGroup:      opts.ServerGroup,
 	}
+	if readPref := internalOpts.readPreference; readPref != ReadPreferenceActiveOnly {
+		// As with Get, the node is selected again whenever the request is retried.
+		req.selectReplicaFn = func(req *memdQRequest) {
+			crud.selectLookupInReadPreferenceReplica(req, opts.Flags, readPref)
+		}
+		crud.selectLookupInReadPreferenceReplica(req, opts.Flags, readPref)
+	}
 
+	req.dispatchDeadline = opts.Deadline
 	op, err := crud.cidMgr.Dispatch(req)
 	if err != nil {
 		tracer.Finish()
@@ -180,6 +307,24 @@ func (crud *crudComponent) LookupIn(opts LookupInOptions, cb LookupInCallback) (
 	return op, nil
 }
 
+// selectLookupInReadPreferenceReplica routes a lookup in to the node matching the read preference, setting the replica
+// read flag when it is sent to a replica. Lookups are only sent to replicas when the bucket supports replica reads.
+func (crud *crudComponent) selectLookupInReadPreferenceReplica(req *memdQRequest, flags memd.SubdocDocFlag,
+	pref ReadPreference) {
+	req.ReplicaIdx = 0
+	if !crud.featureVerifier.HasBucketCapabilityStatus(BucketCapabilityReplicaRead, CapabilityStatusUnsupported) {
+		req.ReplicaIdx = crud.readPreferenceReplicaIdx(req.Key, pref)
+	}
+	if req.ReplicaIdx > 0 {
+		flags |= memd.SubdocDocFlagReplicaRead
+	}
+
+	req.Extras = nil
+	if flags != 0 {
+		req.Extras = []byte{uint8(flags)}
+	}
+}
+
 func (crud *crudComponent) LookupInServerGroup(serverGroup string, opts LookupInOptions, cb LookupInCallback) (PendingOp, error) {
 	parentOp := &multiPendingOp{
 		isIdempotent: true,
@@ -244,6 +389,7 @@ func (crud *crudComponent) LookupInServerGroup(serverGroup string, opts LookupIn
 			curOp, err := crud.LookupIn(LookupInOptions{
 				Key:            opts.Key,
 				Flags:          flags,
+				AccessDeleted:  opts.AccessDeleted,
 				Ops:            opts.Ops,
 				CollectionName: opts.CollectionName,
 				ScopeName:      opts.ScopeName,
@@ -254,6 +400,7 @@ func (crud *crudComponent) LookupInServerGroup(serverGroup string, opts LookupIn
 				ServerGroup:    serverGroup,
 				User:           opts.User,
 				TraceContext:   opts.TraceContext,
+				UserMetadata:   opts.UserMetadata,
 			}, func(result *LookupInResult, err error) {
 				if err != nil {
 					opCompleted()
@@ -293,11 +440,32 @@ func (crud *crudComponent) LookupInServerGroup(serverGroup string, opts LookupIn
 }
 
 func (crud *crudComponent) MutateIn(opts MutateInOptions, cb MutateInCallback) (PendingOp, error) {
+	return crud.mutateIn(opts, mutateInInternalOptions{}, cb)
+}
+
+// mutateInInternalOptions are options used by components which perform mutations on behalf of other operations.
+type mutateInInternalOptions struct {
+	// skipSubDocHooks prevents the sub-document value hooks being applied, for mutations whose ops were not provided
+	// by the user.
+	skipSubDocHooks bool
+}
+
+func (crud *crudComponent) mutateIn(opts MutateInOptions, internalOpts mutateInInternalOptions,
+	cb MutateInCallback) (PendingOp, error) {
 	if len(opts.Ops) == 0 {
 		return nil, wrapError(errInvalidArgument, "at least one op must be present")
 	}
 
+	if !internalOpts.skipSubDocHooks {
+		ops, err := crud.beforeSubDocWrite(opts.Key, opts.Ops)
+		if err != nil {
+			return nil, err
+		}
+		opts.Ops = ops
+	}
+
 	tracer := crud.tracer.StartTelemeteryHandler(metricValueServiceKeyValue, "MutateIn", opts.TraceContext)
+	tracer.SetDeadline(opts.Deadline)
 
 	results := make([]SubDocResult, len(opts.Ops))
 	var subdocs subdocOpList
@@ -354,12 +522,16 @@ func (crud *crudComponent) MutateIn(opts MutateInOptions, cb MutateInCallback) (
 			mutToken.VbUUID = VbUUID(binary.BigEndian.Uint64(resp.Extras[0:]))
 			mutToken.SeqNo = SeqNo(binary.BigEndian.Uint64(resp.Extras[8:]))
 		}
+
+		expandMutationMacroResults(opts.Ops, results, Cas(resp.Cas), mutToken)
+
 		res := &MutateInResult{
 			Cas:           Cas(resp.Cas),
 			MutationToken: mutToken,
 			Ops:           results,
 		}
 		res.Internal.ResourceUnits = req.ResourceUnits()
+		res.UserMetadata = req.UserMetadata()
 
 		tracer.Finish()
 		cb(res, nil)
@@ -375,7 +547,7 @@ func (crud *crudComponent) MutateIn(opts MutateInOptions, cb MutateInCallback) (
 			DurabilityLevel: opts.DurabilityLevel,
 		}
 		duraTimeoutFrame = &memd.DurabilityTimeoutFrame{
-			DurabilityTimeout: opts.DurabilityLevelTimeout,
+			DurabilityTimeout: durabilityTimeoutOrDefault(opts.DurabilityLevelTimeout, opts.Deadline),
 		}
 	}
 
@@ -447,6 +619,16 @@ func (crud *crudComponent) MutateIn(opts MutateInOptions, cb MutateInCallback) (
 			}
 		}
 
+		if err := verifyWholeDocOp(op); err != nil {
+			return nil, err
+		}
+		if err := verifyExpandMacrosFlag(op.Flags); err != nil {
+			return nil, err
+		}
+		if err := crud.verifyBinaryXattrFlag(op.Flags); err != nil {
+			return nil, err
+		}
+
 		pathBytes := pathBytesList[i]
 		pathBytesLen := len(pathBytes)
 		valueBytesLen := len(op.Value)
@@ -494,8 +676,10 @@ func (crud *crudComponent) MutateIn(opts MutateInOptions, cb MutateInCallback) (
 		CollectionName:   opts.CollectionName,
 		ScopeName:        opts.ScopeName,
 		RetryStrategy:    opts.RetryStrategy,
+		userMetadata:     opts.UserMetadata,
 	}
 
+	req.dispatchDeadline = opts.Deadline
 	op, err := crud.cidMgr.Dispatch(req)
 	if err != nil {
 		tracer.Finish()
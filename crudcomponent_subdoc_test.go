@@ -1561,3 +1561,184 @@ func (suite *StandardTestSuite) TestLookupInReplicaReads() {
 		time.Sleep(50 * time.Millisecond)
 	}
 }
+
+type testBucketCapabilityVerifier struct {
+	capabilities map[BucketCapability]CapabilityStatus
+}
+
+func (v *testBucketCapabilityVerifier) HasBucketCapabilityStatus(cap BucketCapability, status CapabilityStatus) bool {
+	return v.capabilities[cap] == status
+}
+
+func (suite *UnitTestSuite) TestCrudComponentVerifyBinaryXattrFlag() {
+	type tCase struct {
+		name        string
+		flags       memd.SubdocFlag
+		status      CapabilityStatus
+		expectedErr error
+	}
+
+	testCases := []tCase{
+		{name: "no binary flag", flags: memd.SubdocFlagXattrPath, status: CapabilityStatusUnsupported},
+		{name: "binary xattr supported", flags: memd.SubdocFlagXattrPath | memd.SubdocFlagBinaryValue,
+			status: CapabilityStatusSupported},
+		{name: "binary xattr support unknown", flags: memd.SubdocFlagXattrPath | memd.SubdocFlagBinaryValue,
+			status: CapabilityStatusUnknown},
+		{name: "binary xattr unsupported", flags: memd.SubdocFlagXattrPath | memd.SubdocFlagBinaryValue,
+			status: CapabilityStatusUnsupported, expectedErr: ErrFeatureNotAvailable},
+		{name: "binary body path", flags: memd.SubdocFlagBinaryValue, status: CapabilityStatusSupported,
+			expectedErr: ErrInvalidArgument},
+	}
+
+	for _, tc := range testCases {
+		suite.Run(tc.name, func() {
+			crud := &crudComponent{
+				featureVerifier: &testBucketCapabilityVerifier{
+					capabilities: map[BucketCapability]CapabilityStatus{
+						BucketCapabilityBinaryXattr: tc.status,
+					},
+				},
+			}
+
+			err := crud.verifyBinaryXattrFlag(tc.flags)
+			if tc.expectedErr == nil {
+				suite.Assert().Nil(err, err)
+			} else {
+				suite.Assert().True(errors.Is(err, tc.expectedErr), err)
+			}
+		})
+	}
+}
+
+func (suite *UnitTestSuite) TestExpandMutationMacroResults() {
+	ops := []SubDocOp{
+		{
+			Op:    memd.SubDocOpCounter,
+			Path:  "count",
+			Value: []byte("1"),
+		},
+		{
+			Op:    memd.SubDocOpDictSet,
+			Flags: memd.SubdocFlagXattrPath | memd.SubdocFlagExpandMacros,
+			Path:  "meta.cas",
+			Value: []byte("\"${Mutation.CAS}\""),
+		},
+		{
+			Op:    memd.SubDocOpDictSet,
+			Flags: memd.SubdocFlagXattrPath | memd.SubdocFlagExpandMacros,
+			Path:  "meta.seqno",
+			Value: []byte("\"${Mutation.seqno}\""),
+		},
+		{
+			Op:    memd.SubDocOpDictSet,
+			Flags: memd.SubdocFlagXattrPath,
+			Path:  "meta.literal",
+			Value: []byte("\"${Mutation.CAS}\""),
+		},
+	}
+	results := []SubDocResult{
+		{
+			Value: []byte("2"),
+		},
+		{},
+		{},
+		{},
+	}
+
+	expandMutationMacroResults(ops, results, Cas(0x155CD21DA7580000), MutationToken{SeqNo: 0x0102})
+
+	suite.Assert().Equal([]byte("2"), results[0].Value)
+	suite.Assert().Equal([]byte("\"0x000058a71dd25c15\""), results[1].Value)
+	suite.Assert().Equal([]byte("\"0x0201000000000000\""), results[2].Value)
+	suite.Assert().Nil(results[3].Value)
+
+	var cas string
+	suite.Require().Nil(json.Unmarshal(results[1].Value, &cas))
+	ms, err := parseCASToMilliseconds(cas)
+	suite.Require().Nil(err, err)
+	suite.Assert().Equal(int64(0x155CD21DA7580000/1000000), ms)
+
+	// Without a mutation token the seqno can't be known.
+	results = make([]SubDocResult, len(ops))
+	expandMutationMacroResults(ops, results, Cas(1), MutationToken{})
+	suite.Assert().Nil(results[2].Value)
+}
+
+func (suite *UnitTestSuite) TestSubDocArrayOps() {
+	op, err := NewArrayPushLastOp("arr", memd.SubdocFlagMkDirP, 1, "two", map[string]int{"three": 3})
+	suite.Require().Nil(err, err)
+	suite.Assert().Equal(memd.SubDocOpArrayPushLast, op.Op)
+	suite.Assert().Equal(memd.SubdocFlagMkDirP, op.Flags)
+	suite.Assert().Equal("arr", op.Path)
+	suite.Assert().Equal(`1,"two",{"three":3}`, string(op.Value))
+
+	op, err = NewArrayPushFirstOp("arr", memd.SubdocFlagNone, "one")
+	suite.Require().Nil(err, err)
+	suite.Assert().Equal(memd.SubDocOpArrayPushFirst, op.Op)
+	suite.Assert().Equal(`"one"`, string(op.Value))
+
+	op, err = NewArrayInsertOp("arr[1]", memd.SubdocFlagNone, 1, 2)
+	suite.Require().Nil(err, err)
+	suite.Assert().Equal(memd.SubDocOpArrayInsert, op.Op)
+	suite.Assert().Equal(`1,2`, string(op.Value))
+
+	op, err = NewArrayAddUniqueOp("arr", memd.SubdocFlagNone, "unique")
+	suite.Require().Nil(err, err)
+	suite.Assert().Equal(memd.SubDocOpArrayAddUnique, op.Op)
+	suite.Assert().Equal(`"unique"`, string(op.Value))
+
+	_, err = NewArrayPushLastOp("arr", memd.SubdocFlagNone)
+	suite.Assert().ErrorIs(err, ErrInvalidArgument)
+
+	_, err = NewArrayPushLastOp("arr", memd.SubdocFlagNone, make(chan int))
+	suite.Assert().NotNil(err)
+}
+
+func (suite *UnitTestSuite) TestSubDocWholeDocOps() {
+	suite.Assert().Equal(SubDocOp{Op: memd.SubDocOpGetDoc}, NewGetDocOp())
+	suite.Assert().Equal(SubDocOp{Op: memd.SubDocOpSetDoc, Value: []byte(`{"x":1}`)}, NewSetDocOp([]byte(`{"x":1}`)))
+	suite.Assert().Equal(SubDocOp{Op: memd.SubDocOpDeleteDoc}, NewDeleteDocOp())
+
+	type tCase struct {
+		name        string
+		op          SubDocOp
+		expectedErr error
+	}
+
+	testCases := []tCase{
+		{name: "get doc", op: NewGetDocOp()},
+		{name: "set doc", op: NewSetDocOp([]byte(`{}`))},
+		{name: "delete doc", op: NewDeleteDocOp()},
+		{name: "path op", op: SubDocOp{Op: memd.SubDocOpDictSet, Path: "x", Flags: memd.SubdocFlagXattrPath}},
+		{name: "get doc with path", op: SubDocOp{Op: memd.SubDocOpGetDoc, Path: "x"}, expectedErr: ErrInvalidArgument},
+		{name: "set doc with xattr flag", op: SubDocOp{Op: memd.SubDocOpSetDoc, Flags: memd.SubdocFlagXattrPath},
+			expectedErr: ErrInvalidArgument},
+	}
+
+	for _, tc := range testCases {
+		suite.Run(tc.name, func() {
+			err := verifyWholeDocOp(tc.op)
+			if tc.expectedErr == nil {
+				suite.Assert().Nil(err, err)
+			} else {
+				suite.Assert().True(errors.Is(err, tc.expectedErr), err)
+			}
+		})
+	}
+}
+
+func (suite *UnitTestSuite) TestSubDocMacroOps() {
+	op := NewMacroXattrOp("txn.cas", SubDocMacroMutationCAS, memd.SubdocFlagMkDirP)
+	suite.Assert().Equal(memd.SubDocOpDictSet, op.Op)
+	suite.Assert().Equal(memd.SubdocFlagMkDirP|memd.SubdocFlagXattrPath|memd.SubdocFlagExpandMacros, op.Flags)
+	suite.Assert().Equal("txn.cas", op.Path)
+	suite.Assert().Equal(`"${Mutation.CAS}"`, string(op.Value))
+	suite.Assert().Nil(verifyExpandMacrosFlag(op.Flags))
+
+	op = NewMacroXattrOp("exp", SubDocMacroDocumentExptime, memd.SubdocFlagNone)
+	suite.Assert().Equal(exptimeMacro, op.Value)
+	suite.Assert().Equal(crc32cMacro, NewMacroXattrOp("crc", SubDocMacroMutationValueCRC32c, 0).Value)
+
+	suite.Assert().Nil(verifyExpandMacrosFlag(memd.SubdocFlagNone))
+	suite.Assert().ErrorIs(verifyExpandMacrosFlag(memd.SubdocFlagExpandMacros), ErrInvalidArgument)
+}
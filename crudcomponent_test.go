@@ -264,3 +264,77 @@ func (suite *StandardTestSuite) TestResourceUnits() {
 // 		suite.Require().GreaterOrEqual(1, int(resourceUnits.WriteUnits))
 // 	}
 // }
+
+type testReadPreferenceClientProvider struct {
+	available   map[int]bool
+	numReplicas int
+}
+
+func (p *testReadPreferenceClientProvider) GetByConnID(connID string) (*memdClient, error) {
+	return nil, errConnectionIDInvalid
+}
+
+func (p *testReadPreferenceClientProvider) NodeAvailable(key []byte, replicaIdx int) bool {
+	return p.available[replicaIdx]
+}
+
+func (p *testReadPreferenceClientProvider) NumReplicas() int {
+	return p.numReplicas
+}
+
+func (suite *UnitTestSuite) TestReadPreferenceReplicaIdx() {
+	type tCase struct {
+		name      string
+		pref      ReadPreference
+		available map[int]bool
+		expected  int
+	}
+
+	testCases := []tCase{
+		{name: "active only, active down", pref: ReadPreferenceActiveOnly, available: map[int]bool{1: true}, expected: 0},
+		{name: "active preferred, active up", pref: ReadPreferenceActivePreferred, available: map[int]bool{0: true, 1: true}, expected: 0},
+		{name: "active preferred, active down", pref: ReadPreferenceActivePreferred, available: map[int]bool{2: true}, expected: 2},
+		{name: "active preferred, all down", pref: ReadPreferenceActivePreferred, available: map[int]bool{}, expected: 0},
+		{name: "replica preferred, replica up", pref: ReadPreferenceReplicaPreferred, available: map[int]bool{0: true, 1: true}, expected: 1},
+		{name: "replica preferred, replicas down", pref: ReadPreferenceReplicaPreferred, available: map[int]bool{0: true}, expected: 0},
+	}
+
+	for _, tc := range testCases {
+		suite.Run(tc.name, func() {
+			crud := &crudComponent{
+				clientProvider: &testReadPreferenceClientProvider{
+					available:   tc.available,
+					numReplicas: 2,
+				},
+			}
+
+			suite.Assert().Equal(tc.expected, crud.readPreferenceReplicaIdx([]byte("key"), tc.pref))
+		})
+	}
+}
+
+func (suite *UnitTestSuite) TestSelectReadPreferenceReplica() {
+	provider := &testReadPreferenceClientProvider{
+		available:   map[int]bool{1: true},
+		numReplicas: 2,
+	}
+	crud := &crudComponent{
+		clientProvider: provider,
+	}
+
+	req := &memdQRequest{
+		Packet: memd.Packet{
+			Command: memd.CmdGet,
+			Key:     []byte("key"),
+		},
+	}
+	crud.selectReadPreferenceReplica(req, ReadPreferenceActivePreferred)
+	suite.Assert().Equal(1, req.ReplicaIdx)
+	suite.Assert().Equal(memd.CmdGetReplica, req.Command)
+
+	// Once the active node is available again a retry is sent back to it.
+	provider.available = map[int]bool{0: true, 1: true}
+	crud.selectReadPreferenceReplica(req, ReadPreferenceActivePreferred)
+	suite.Assert().Equal(0, req.ReplicaIdx)
+	suite.Assert().Equal(memd.CmdGet, req.Command)
+}
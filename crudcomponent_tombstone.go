@@ -0,0 +1,51 @@
+package gocbcore
+
+import (
+	"github.com/couchbase/gocbcore/v10/memd"
+)
+
+func (crud *crudComponent) GetTombstone(opts GetTombstoneOptions, cb GetTombstoneCallback) (PendingOp, error) {
+	if len(opts.XattrPaths) == 0 {
+		return nil, wrapError(errInvalidArgument, "at least one xattr path must be provided")
+	}
+
+	ops := make([]SubDocOp, len(opts.XattrPaths))
+	for i, path := range opts.XattrPaths {
+		ops[i] = SubDocOp{
+			Op:    memd.SubDocOpGet,
+			Flags: memd.SubdocFlagXattrPath,
+			Path:  path,
+		}
+	}
+
+	return crud.LookupIn(LookupInOptions{
+		Key:            opts.Key,
+		AccessDeleted:  true,
+		Ops:            ops,
+		CollectionName: opts.CollectionName,
+		ScopeName:      opts.ScopeName,
+		CollectionID:   opts.CollectionID,
+		RetryStrategy:  opts.RetryStrategy,
+		Deadline:       opts.Deadline,
+		User:           opts.User,
+		TraceContext:   opts.TraceContext,
+	}, func(result *LookupInResult, err error) {
+		if err != nil {
+			cb(nil, err)
+			return
+		}
+
+		cb(tombstoneResult(result), nil)
+	})
+}
+
+func tombstoneResult(result *LookupInResult) *GetTombstoneResult {
+	res := &GetTombstoneResult{
+		Cas:       result.Cas,
+		Xattrs:    result.Ops,
+		IsDeleted: result.IsDeleted,
+	}
+	res.Internal.ResourceUnits = result.Internal.ResourceUnits
+
+	return res
+}
@@ -0,0 +1,27 @@
+package gocbcore
+
+func (suite *UnitTestSuite) TestGetTombstone() {
+	crud := &crudComponent{}
+	_, err := crud.GetTombstone(GetTombstoneOptions{Key: []byte("key")}, func(*GetTombstoneResult, error) {
+		suite.T().Fatalf("Callback should not have been invoked")
+	})
+	suite.Assert().ErrorIs(err, ErrInvalidArgument)
+
+	lookupRes := &LookupInResult{
+		Cas: 10,
+		Ops: []SubDocResult{
+			{Value: []byte(`{"id":"abc"}`)},
+			{Err: ErrPathNotFound},
+		},
+		IsDeleted: true,
+	}
+	res := tombstoneResult(lookupRes)
+	suite.Assert().Equal(Cas(10), res.Cas)
+	suite.Assert().True(res.IsDeleted)
+	suite.Require().Len(res.Xattrs, 2)
+	suite.Assert().Equal(`{"id":"abc"}`, string(res.Xattrs[0].Value))
+	suite.Assert().ErrorIs(res.Xattrs[1].Err, ErrPathNotFound)
+
+	lookupRes.IsDeleted = false
+	suite.Assert().False(tombstoneResult(lookupRes).IsDeleted)
+}
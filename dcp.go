@@ -1,5 +1,7 @@
 package gocbcore
 
+import "time"
+
 // OpenStreamFilterOptions are the filtering options available to the OpenStream operation.
 type OpenStreamFilterOptions struct {
 	ScopeID       uint32
@@ -201,6 +203,15 @@ type DcpStreamEnd struct {
 	StreamID uint16
 }
 
+// DcpStreamRollback represents a DCP stream request which was rejected by the server because the stream must first
+// be rolled back to SeqNo.
+// Volatile: This API is subject to change at any time.
+type DcpStreamRollback struct {
+	VbID     uint16
+	StreamID uint16
+	SeqNo    SeqNo
+}
+
 // StreamObserver provides an interface to receive events from a running DCP stream.
 type StreamObserver interface {
 	SnapshotMarker(snapshotMarker DcpSnapshotMarker)
@@ -218,6 +229,13 @@ type StreamObserver interface {
 	SeqNoAdvanced(seqNoAdvanced DcpSeqNoAdvanced)
 }
 
+// StreamRollbackObserver can optionally be implemented by a StreamObserver to be notified when a stream request
+// is rejected by the server with a rollback. RollbackPoint can be used to reopen the stream at the rollback seqno.
+// Volatile: This API is subject to change at any time.
+type StreamRollbackObserver interface {
+	Rollback(rollback DcpStreamRollback)
+}
+
 type streamFilter struct {
 	ManifestUID string   `json:"uid,omitempty"`
 	Collections []string `json:"collections,omitempty"`
@@ -240,5 +258,33 @@ type VbSeqNoEntry struct {
 	SeqNo SeqNo
 }
 
+// VbPurgeSeqNoEntry represents the purge seqno of a single vbucket, as returned by GetVbucketPurgeSeqno. Tombstones
+// for deletions at or below the purge seqno have been removed by the server, so a stream resumed from a seqno below
+// it will not see those deletions.
+// Volatile: This API is subject to change at any time.
+type VbPurgeSeqNoEntry struct {
+	VbID       uint16
+	VbUUID     VbUUID
+	PurgeSeqNo SeqNo
+}
+
+// CheckpointPurged returns whether a checkpoint at seqNo predates the purge point of the vbucket, in which case
+// resuming a stream from it may silently miss deletions and the consumer should instead perform a full resync. A
+// seqNo of 0 is never considered purged as streaming from the start of the vbucket does not rely on tombstones.
+func (e VbPurgeSeqNoEntry) CheckpointPurged(seqNo SeqNo) bool {
+	return seqNo != 0 && seqNo < e.PurgeSeqNo
+}
+
+// GetVbucketPurgeSeqnoOptions are the options available to the GetVbucketPurgeSeqno operation.
+// Volatile: This API is subject to change at any time.
+type GetVbucketPurgeSeqnoOptions struct {
+	// Deadline is the time by which the purge seqno must have been retrieved, it must be provided.
+	Deadline      time.Time
+	RetryStrategy RetryStrategy
+}
+
+// GetVbucketPurgeSeqnoCallback is invoked with the results of `GetVbucketPurgeSeqno` operations.
+type GetVbucketPurgeSeqnoCallback func(VbPurgeSeqNoEntry, error)
+
 // GetVBucketSeqnosCallback is invoked with the results of `GetVBucketSeqnos` operations.
 type GetVBucketSeqnosCallback func([]VbSeqNoEntry, error)
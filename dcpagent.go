@@ -1,6 +1,7 @@
 package gocbcore
 
 import (
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"sync"
@@ -24,6 +25,7 @@ type DCPAgent struct {
 	tracer      *tracerComponent
 	diagnostics *diagnosticsComponent
 	dcp         *dcpComponent
+	stats       *statsComponent
 	http        *httpComponent
 
 	// These connection settings are only ever changed when ForceReconnect or ReconfigureSecurity are called.
@@ -32,6 +34,9 @@ type DCPAgent struct {
 	authMechanisms         []AuthMechanism
 	tlsConfig              *dynTLSConfig
 
+	// tlsBaseConfig is the configuration which TLS connections are based on, it is used when TLS is reconfigured.
+	tlsBaseConfig *tls.Config
+
 	srvDetails *srvDetails
 
 	shutdownSig chan struct{}
@@ -65,8 +70,19 @@ func CreateDcpAgent(config *DCPAgentConfig, dcpStreamName string, openFlags memd
 	serverWaitTimeout := 5 * time.Second
 
 	kvPoolSize := 1
+	var kvPoolSizeForConfig func(cfg *routeConfig) int
 	if config.KVConfig.PoolSize > 0 {
 		kvPoolSize = config.KVConfig.PoolSize
+	} else if config.DCPConfig.MaxStreamsPerConnection > 0 {
+		// Create enough connections for a single node to stream every vbucket in the bucket without exceeding the limit.
+		maxStreams := config.DCPConfig.MaxStreamsPerConnection
+		kvPoolSizeForConfig = func(cfg *routeConfig) int {
+			if cfg.vbMap == nil {
+				return 1
+			}
+
+			return dcpPoolSize(cfg.vbMap.NumVbuckets(), maxStreams)
+		}
 	}
 
 	maxQueueSize := 2048
@@ -150,7 +166,13 @@ func CreateDcpAgent(config *DCPAgentConfig, dcpStreamName string, openFlags memd
 		shutdownSig: make(chan struct{}),
 	}
 
-	tlsConfig, err := setupTLSConfig(config.SeedConfig.MemdAddrs, config.SecurityConfig)
+	tlsBaseConfig, err := config.SecurityConfig.tlsBaseConfig()
+	if err != nil {
+		return nil, err
+	}
+	c.tlsBaseConfig = tlsBaseConfig
+
+	tlsConfig, err := setupTLSConfig(config.SeedConfig.MemdAddrs, config.SecurityConfig, tlsBaseConfig)
 	if err != nil {
 		return nil, err
 	}
@@ -245,11 +267,15 @@ func CreateDcpAgent(config *DCPAgentConfig, dcpStreamName string, openFlags memd
 			CompressionMinRatio:  compressionMinRatio,
 			DisableDecompression: disableDecompression,
 			NoTLSSeedNode:        config.SecurityConfig.NoTLSSeedNode,
+			RequireCertAuth:      config.SecurityConfig.RequireCertificateAuth,
 			ConnBufSize:          kvBufferSize,
+			WriteFlushInterval:   config.KVConfig.WriteFlushInterval,
+			MaxWriteBatchSize:    config.KVConfig.MaxWriteBatchSize,
 
 			DCPBootstrapProps: &memdBootstrapDCPProps{
 				openFlags:                    openFlags,
 				streamName:                   dcpStreamName,
+				uniqueStreamNames:            kvPoolSize > 1 || kvPoolSizeForConfig != nil,
 				disableBufferAcknowledgement: config.DCPConfig.DisableBufferAcknowledgement,
 				useOSOBackfill:               config.DCPConfig.UseOSOBackfill,
 				useStreamID:                  config.DCPConfig.UseStreamID,
@@ -278,13 +304,16 @@ func CreateDcpAgent(config *DCPAgentConfig, dcpStreamName string, openFlags memd
 		nil,
 		c.tracer,
 		c.cfgManager,
+		nil,
 	)
 	c.kvMux = newKVMux(
 		kvMuxProps{
-			QueueSize:          maxQueueSize,
-			PoolSize:           kvPoolSize,
-			CollectionsEnabled: useCollections,
-			NoTLSSeedNode:      config.SecurityConfig.NoTLSSeedNode,
+			QueueSize:            maxQueueSize,
+			PoolSize:             kvPoolSize,
+			PoolSizeForConfig:    kvPoolSizeForConfig,
+			CollectionsEnabled:   useCollections,
+			NoTLSSeedNode:        config.SecurityConfig.NoTLSSeedNode,
+			GracefulCloseTimeout: config.KVConfig.GracefulCloseTimeout,
 		},
 		c.cfgManager,
 		c.errMap,
@@ -305,7 +334,9 @@ func CreateDcpAgent(config *DCPAgentConfig, dcpStreamName string, openFlags memd
 	)
 	c.http = newHTTPComponent(
 		httpComponentProps{
-			UserAgent: userAgent,
+			UserAgent:           userAgent,
+			AuditHook:           config.HTTPConfig.AuditHook,
+			MaxResponseBodySize: config.HTTPConfig.MaxResponseBodySize,
 		},
 		httpClientProps{
 			maxIdleConns:        config.HTTPConfig.MaxIdleConns,
@@ -367,7 +398,8 @@ func CreateDcpAgent(config *DCPAgentConfig, dcpStreamName string, openFlags memd
 	c.pollerController = poller
 
 	c.diagnostics = newDiagnosticsComponent(c.kvMux, nil, nil, c.bucketName, newFailFastRetryStrategy(), c.pollerController)
-	c.dcp = newDcpComponent(c.kvMux, config.DCPConfig.UseStreamID)
+	c.stats = newStatsComponent(c.kvMux, newFailFastRetryStrategy(), c.tracer)
+	c.dcp = newDcpComponent(c.kvMux, c.stats, config.DCPConfig.UseStreamID, config.DCPConfig.MaxStreamsPerConnection)
 
 	c.dialer.AddBootstrapFailHandler(c.diagnostics)
 	c.dialer.AddCCCPUnsupportedHandler(c)
@@ -447,6 +479,15 @@ func (agent *DCPAgent) GetFailoverLog(vbID uint16, cb GetFailoverLogCallback) (P
 	return agent.dcp.GetFailoverLog(vbID, cb)
 }
 
+// RollbackPoint reopens a DCP stream for a particular VBucket after the server has rejected a stream request with
+// a rollback to rollbackSeqNo, see DCPRollbackError. The failover log is used to determine the VbUUID of the branch
+// containing rollbackSeqNo and the stream is then reopened from that seqno.
+// Volatile: This API is subject to change at any time.
+func (agent *DCPAgent) RollbackPoint(vbID uint16, flags memd.DcpStreamAddFlag, rollbackSeqNo, endSeqNo SeqNo,
+	evtHandler StreamObserver, opts OpenStreamOptions, cb OpenStreamCallback) (PendingOp, error) {
+	return agent.dcp.RollbackPoint(vbID, flags, rollbackSeqNo, endSeqNo, evtHandler, opts, cb)
+}
+
 // GetVbucketSeqnos returns the last checkpoint for a particular VBucket.  This is useful
 // for starting a DCP stream from wherever the server currently is.
 func (agent *DCPAgent) GetVbucketSeqnos(serverIdx int, state memd.VbucketState, opts GetVbucketSeqnoOptions,
@@ -454,6 +495,15 @@ func (agent *DCPAgent) GetVbucketSeqnos(serverIdx int, state memd.VbucketState,
 	return agent.dcp.GetVbucketSeqnos(serverIdx, state, opts, cb)
 }
 
+// GetVbucketPurgeSeqno retrieves the purge seqno of a particular VBucket from the node which is active for it. This
+// can be compared against a stored checkpoint to detect that a stream can no longer be safely resumed from it, see
+// VbPurgeSeqNoEntry.CheckpointPurged.
+// Volatile: This API is subject to change at any time.
+func (agent *DCPAgent) GetVbucketPurgeSeqno(vbID uint16, opts GetVbucketPurgeSeqnoOptions,
+	cb GetVbucketPurgeSeqnoCallback) (PendingOp, error) {
+	return agent.dcp.GetVbucketPurgeSeqno(vbID, opts, cb)
+}
+
 // HasCollectionsSupport verifies whether or not collections are available on the agent.
 func (agent *DCPAgent) HasCollectionsSupport() bool {
 	return agent.kvMux.SupportsCollections()
@@ -508,9 +558,10 @@ func (agent *DCPAgent) ReconfigureSecurity(opts ReconfigureSecurityOptions) erro
 	var tlsConfig *dynTLSConfig
 	if opts.UseTLS {
 		if opts.TLSRootCAProvider == nil {
+			agent.connectionSettingsLock.Unlock()
 			return wrapError(errInvalidArgument, "must provide TLSRootCAProvider when UseTLS is true")
 		}
-		tlsConfig = createTLSConfig(auth, opts.TLSRootCAProvider)
+		tlsConfig = createTLSConfig(auth, opts.TLSRootCAProvider, agent.tlsBaseConfig)
 	}
 
 	agent.auth = auth
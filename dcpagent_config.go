@@ -44,6 +44,13 @@ type DCPConfig struct {
 
 	BufferSize                   int
 	DisableBufferAcknowledgement bool
+
+	// MaxStreamsPerConnection limits the number of streams opened on each DCP connection, with streams spread across
+	// all of the connections to a node. If KVConfig.PoolSize is not set then enough connections are created to each
+	// node to stream every vbucket in the bucket, up to a maximum of 16 connections. Every connection is opened with
+	// the same DCP priority.
+	// Volatile: This API is subject to change at any time.
+	MaxStreamsPerConnection int
 }
 
 func (config DCPConfig) fromSpec(spec connstr.ResolvedConnSpec) (DCPConfig, error) {
@@ -74,6 +81,15 @@ func (config DCPConfig) fromSpec(spec connstr.ResolvedConnSpec) (DCPConfig, erro
 		config.BufferSize = int(val)
 	}
 
+	// This option is experimental
+	if valStr, ok := fetchOption(spec, "dcp_max_streams_per_connection"); ok {
+		val, err := strconv.ParseInt(valStr, 10, 64)
+		if err != nil {
+			return DCPConfig{}, fmt.Errorf("dcp max streams per connection option must be a number")
+		}
+		config.MaxStreamsPerConnection = int(val)
+	}
+
 	// This option is experimental
 	if valStr, ok := fetchOption(spec, "enable_dcp_change_streams"); ok {
 		val, err := strconv.ParseBool(valStr)
@@ -125,6 +141,7 @@ func (config *DCPAgentConfig) redacted() interface{} {
 //	orphaned_response_logging_interval (duration) - How often to print the orphan log records.
 //	orphaned_response_logging_sample_size (int) - The maximum number of orphan log records to track.
 //	dcp_priority (int) - Specifies the priority to request from the Cluster when connecting for DCP.
+//	dcp_max_streams_per_connection (int) - The maximum number of streams to open on each DCP connection.
 //	enable_dcp_change_streams (bool) - Enables the DCP connection to allow history snapshots in DCP streams.
 //	enable_dcp_expiry (bool) - Whether to enable the feature to distinguish between explicit delete and expired delete on DCP.
 //	kv_pool_size (int) - The number of connections to create to each KV node.
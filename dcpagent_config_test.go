@@ -491,6 +491,42 @@ func (suite *StandardTestSuite) TestDCPAgentConfig_EnableDCPChangeStreams() {
 	}
 }
 
+func (suite *StandardTestSuite) TestDCPAgentConfig_DCPMaxStreamsPerConnection() {
+	tests := []struct {
+		name     string
+		connStr  string
+		expected int
+		wantErr  bool
+	}{
+		{
+			name:     "valid",
+			connStr:  "couchbase://10.112.192.101?dcp_max_streams_per_connection=256",
+			expected: 256,
+		},
+		{
+			name:    "invalid",
+			connStr: "couchbase://10.112.192.101?dcp_max_streams_per_connection=squirrel",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		suite.T().Run(tt.name, func(t *testing.T) {
+			config := &DCPAgentConfig{}
+			if err := config.FromConnStr(tt.connStr); (err != nil) != tt.wantErr {
+				t.Errorf("FromConnStr() error = %v, wanted error = %t", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if config.DCPConfig.MaxStreamsPerConnection != tt.expected {
+				suite.T().Fatalf("Expected %d but was %d", tt.expected, config.DCPConfig.MaxStreamsPerConnection)
+			}
+		})
+	}
+}
+
 func (suite *StandardTestSuite) TestDCPAgentConfig_KVPoolSize() {
 	tests := []struct {
 		name     string
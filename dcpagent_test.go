@@ -0,0 +1,29 @@
+package gocbcore
+
+import (
+	"errors"
+	"time"
+)
+
+func (suite *UnitTestSuite) TestDCPAgentReconfigureSecurityRequiresRootCAProvider() {
+	agent := &DCPAgent{
+		pollerController: &seedConfigController{},
+	}
+
+	errCh := make(chan error, 2)
+	go func() {
+		// The connection settings must be unlocked when validation fails, otherwise the second call never returns.
+		for i := 0; i < 2; i++ {
+			errCh <- agent.ReconfigureSecurity(ReconfigureSecurityOptions{UseTLS: true})
+		}
+	}()
+
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-errCh:
+			suite.Assert().True(errors.Is(err, errInvalidArgument), err)
+		case <-time.After(5 * time.Second):
+			suite.T().Fatalf("Timed out waiting for ReconfigureSecurity to return")
+		}
+	}
+}
@@ -5,28 +5,62 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strconv"
 	"sync/atomic"
+	"time"
+	"unsafe"
 
 	"github.com/couchbase/gocbcore/v10/memd"
 )
 
 type dcpComponent struct {
 	kvMux           *kvMux
+	stats           *statsComponent
 	streamIDEnabled bool
+
+	// streams is only used when the number of streams per connection is limited, in which case streams are spread
+	// across all of the connections to each node.
+	streams *dcpStreamTracker
 }
 
-func newDcpComponent(kvMux *kvMux, streamIDEnabled bool) *dcpComponent {
-	return &dcpComponent{
+func newDcpComponent(kvMux *kvMux, stats *statsComponent, streamIDEnabled bool, maxStreamsPerConn int) *dcpComponent {
+	dcp := &dcpComponent{
 		kvMux:           kvMux,
+		stats:           stats,
 		streamIDEnabled: streamIDEnabled,
 	}
+	if maxStreamsPerConn > 0 {
+		dcp.streams = newDcpStreamTracker(maxStreamsPerConn)
+	}
+
+	return dcp
+}
+
+func (dcp *dcpComponent) releaseStream(key dcpStreamKey, assignment *dcpStreamAssignment) {
+	if dcp.streams == nil {
+		return
+	}
+
+	dcp.streams.Remove(key, assignment)
 }
 
 func (dcp *dcpComponent) OpenStream(vbID uint16, flags memd.DcpStreamAddFlag, vbUUID VbUUID, startSeqNo,
 	endSeqNo, snapStartSeqNo, snapEndSeqNo SeqNo, evtHandler StreamObserver, opts OpenStreamOptions,
 	cb OpenStreamCallback) (PendingOp, error) {
+	streamKey := dcpStreamKey{vbID: vbID}
+	if opts.StreamOptions != nil {
+		streamKey.streamID = opts.StreamOptions.StreamID
+	}
+
 	var req *memdQRequest
 	var openHandled uint32
+
+	// The assignment is set once the stream has been sent to a connection, which may happen after a delay if none of
+	// the connections to the node are up yet.
+	var assignmentPtr unsafe.Pointer
+	loadAssignment := func() *dcpStreamAssignment {
+		return (*dcpStreamAssignment)(atomic.LoadPointer(&assignmentPtr))
+	}
 	handler := func(resp *memdQResponse, _ *memdQRequest, err error) {
 		if resp == nil && err == nil {
 			logWarnf("DCP event occurred with no error and no response")
@@ -38,6 +72,7 @@ func (dcp *dcpComponent) OpenStream(vbID uint16, flags memd.DcpStreamAddFlag, vb
 				if atomic.CompareAndSwapUint32(&openHandled, 0, 1) {
 					// If open hasn't been handled and there's no response then it's reasonably safe to assume that
 					// this occurring for the open stream request.
+					dcp.releaseStream(streamKey, loadAssignment())
 					cb(nil, err)
 					return
 				}
@@ -50,11 +85,29 @@ func (dcp *dcpComponent) OpenStream(vbID uint16, flags memd.DcpStreamAddFlag, vb
 				// Unforunately we have to check for the memd due to earlier oversights where we missed converting
 				// it to a proper gocbcore error.
 				if errors.Is(err, ErrMemdRollback) {
-					err = DCPRollbackError{
+					rollbackErr := DCPRollbackError{
 						InnerError: err,
 						SeqNo:      SeqNo(binary.BigEndian.Uint64(resp.Value)),
+						VbID:       vbID,
 					}
+
+					if rollbackObserver, ok := evtHandler.(StreamRollbackObserver); ok {
+						var streamID uint16
+						if opts.StreamOptions != nil {
+							streamID = opts.StreamOptions.StreamID
+						}
+						rollbackObserver.Rollback(DcpStreamRollback{
+							VbID:     vbID,
+							StreamID: streamID,
+							SeqNo:    rollbackErr.SeqNo,
+						})
+					}
+
+					dcp.releaseStream(streamKey, loadAssignment())
+					dcp.completeRollback(rollbackErr, cb)
+					return
 				}
+				dcp.releaseStream(streamKey, loadAssignment())
 				cb(nil, err)
 				return
 			}
@@ -63,6 +116,7 @@ func (dcp *dcpComponent) OpenStream(vbID uint16, flags memd.DcpStreamAddFlag, vb
 			if opts.StreamOptions != nil {
 				streamID = opts.StreamOptions.StreamID
 			}
+			dcp.releaseStream(streamKey, loadAssignment())
 			evtHandler.End(DcpStreamEnd{vbID, streamID}, err)
 			return
 		}
@@ -70,16 +124,7 @@ func (dcp *dcpComponent) OpenStream(vbID uint16, flags memd.DcpStreamAddFlag, vb
 		if resp.Magic == memd.CmdMagicRes {
 			atomic.StoreUint32(&openHandled, 1)
 			// This is the response to the open stream request.
-			numEntries := len(resp.Value) / 16
-			entries := make([]FailoverEntry, numEntries)
-			for i := 0; i < numEntries; i++ {
-				entries[i] = FailoverEntry{
-					VbUUID: VbUUID(binary.BigEndian.Uint64(resp.Value[i*16+0:])),
-					SeqNo:  SeqNo(binary.BigEndian.Uint64(resp.Value[i*16+8:])),
-				}
-			}
-
-			cb(entries, nil)
+			cb(parseFailoverLog(resp.Value), nil)
 			return
 		}
 
@@ -251,6 +296,7 @@ func (dcp *dcpComponent) OpenStream(vbID uint16, flags memd.DcpStreamAddFlag, vb
 				end.StreamID = resp.StreamIDFrame.StreamID
 			}
 			if req.internalCancel(err) {
+				dcp.releaseStream(streamKey, loadAssignment())
 				evtHandler.End(end, getStreamEndStatusError(code))
 			}
 		case memd.CmdDcpOsoSnapshot:
@@ -329,11 +375,98 @@ func (dcp *dcpComponent) OpenStream(vbID uint16, flags memd.DcpStreamAddFlag, vb
 		ReplicaIdx: 0,
 		Persistent: true,
 	}
+
+	if dcp.streams != nil {
+		err := dcp.dispatchStream(req, streamKey, 0, func(assignment *dcpStreamAssignment) {
+			atomic.StorePointer(&assignmentPtr, unsafe.Pointer(assignment))
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		return req, nil
+	}
+
 	return dcp.kvMux.DispatchDirect(req)
 }
 
+// dcpStreamDispatchMaxAttempts is the number of times that dispatchStream tries to find a connection to the node for a
+// stream request before failing it.
+const dcpStreamDispatchMaxAttempts = 30
+
+// dispatchStream sends a stream request to the connection to its node which has the fewest streams. The request is
+// pinned to that connection so that any retries are sent on it too. If none of the connections to the node are up
+// yet then we wait and try again, rather than queueing the request, as a queued request could be written by any
+// connection including one which already has the maximum number of streams. We stop trying once the agent is shut
+// down or after dcpStreamDispatchMaxAttempts attempts.
+func (dcp *dcpComponent) dispatchStream(req *memdQRequest, key dcpStreamKey, attempts uint32,
+	assigned func(*dcpStreamAssignment)) error {
+	pipeline, err := dcp.kvMux.RouteRequest(req)
+	if err != nil {
+		return err
+	}
+
+	client, assignment, err := dcp.streams.Assign(key, pipeline)
+	if err != nil {
+		return err
+	}
+
+	if client == nil {
+		if attempts+1 >= dcpStreamDispatchMaxAttempts {
+			return wrapError(errServiceNotAvailable, fmt.Sprintf("no DCP connections to %s are available",
+				redactSystemData(pipeline.Address())))
+		}
+
+		go func() {
+			timer := time.NewTimer(ControlledBackoff(attempts))
+			select {
+			case <-timer.C:
+			case <-dcp.kvMux.shutdownSig:
+				timer.Stop()
+				req.tryCallback(nil, errShutdown)
+				return
+			}
+
+			if req.isCancelled() {
+				return
+			}
+
+			if err := dcp.dispatchStream(req, key, attempts+1, assigned); err != nil {
+				req.tryCallback(nil, err)
+			}
+		}()
+
+		return nil
+	}
+
+	assigned(assignment)
+	req.pinnedConnID = client.ConnID()
+
+	// We're bypassing the usual route for sending requests so need to start the cmd trace ourselves.
+	dcp.kvMux.tracer.StartCmdTrace(req)
+	if err := client.SendRequest(req); err != nil {
+		dcp.streams.Remove(key, assignment)
+		return err
+	}
+
+	return nil
+}
+
 func (dcp *dcpComponent) CloseStream(vbID uint16, opts CloseStreamOptions, cb CloseStreamCallback) (PendingOp, error) {
+	streamKey := dcpStreamKey{vbID: vbID}
+	if opts.StreamOptions != nil {
+		streamKey.streamID = opts.StreamOptions.StreamID
+	}
+
+	var assignment *dcpStreamAssignment
+	if dcp.streams != nil {
+		assignment = dcp.streams.Lookup(streamKey)
+	}
+
 	handler := func(_ *memdQResponse, _ *memdQRequest, err error) {
+		if err == nil {
+			dcp.releaseStream(streamKey, assignment)
+		}
 		cb(err)
 	}
 
@@ -366,6 +499,26 @@ func (dcp *dcpComponent) CloseStream(vbID uint16, opts CloseStreamOptions, cb Cl
 		RetryStrategy: newFailFastRetryStrategy(),
 	}
 
+	// The stream must be closed on the connection that it was opened on, if that connection has gone then so has the
+	// stream.
+	if assignment != nil {
+		client, err := dcp.kvMux.GetByConnID(assignment.connID)
+		if err != nil {
+			dcp.releaseStream(streamKey, assignment)
+			return nil, err
+		}
+
+		req.pinnedConnID = assignment.connID
+
+		// We're bypassing the usual route for sending requests so need to start the cmd trace ourselves.
+		dcp.kvMux.tracer.StartCmdTrace(req)
+		if err := client.SendRequest(req); err != nil {
+			return nil, err
+		}
+
+		return req, nil
+	}
+
 	return dcp.kvMux.DispatchDirect(req)
 }
 
@@ -376,15 +529,7 @@ func (dcp *dcpComponent) GetFailoverLog(vbID uint16, cb GetFailoverLogCallback)
 			return
 		}
 
-		numEntries := len(resp.Value) / 16
-		entries := make([]FailoverEntry, numEntries)
-		for i := 0; i < numEntries; i++ {
-			entries[i] = FailoverEntry{
-				VbUUID: VbUUID(binary.BigEndian.Uint64(resp.Value[i*16+0:])),
-				SeqNo:  SeqNo(binary.BigEndian.Uint64(resp.Value[i*16+8:])),
-			}
-		}
-		cb(entries, nil)
+		cb(parseFailoverLog(resp.Value), nil)
 	}
 
 	req := &memdQRequest{
@@ -406,6 +551,146 @@ func (dcp *dcpComponent) GetFailoverLog(vbID uint16, cb GetFailoverLogCallback)
 	return dcp.kvMux.DispatchDirect(req)
 }
 
+// parseFailoverLog decodes a failover log, as returned by both the get failover log and open stream requests.
+func parseFailoverLog(value []byte) []FailoverEntry {
+	numEntries := len(value) / 16
+	entries := make([]FailoverEntry, numEntries)
+	for i := 0; i < numEntries; i++ {
+		entries[i] = FailoverEntry{
+			VbUUID: VbUUID(binary.BigEndian.Uint64(value[i*16+0:])),
+			SeqNo:  SeqNo(binary.BigEndian.Uint64(value[i*16+8:])),
+		}
+	}
+
+	return entries
+}
+
+func (dcp *dcpComponent) RollbackPoint(vbID uint16, flags memd.DcpStreamAddFlag, rollbackSeqNo, endSeqNo SeqNo,
+	evtHandler StreamObserver, opts OpenStreamOptions, cb OpenStreamCallback) (PendingOp, error) {
+	op := &multiPendingOp{}
+	failoverOp, err := dcp.GetFailoverLog(vbID, func(entries []FailoverEntry, err error) {
+		op.IncrementCompletedOps()
+		if err != nil {
+			cb(nil, err)
+			return
+		}
+
+		entry := dcpRollbackPoint(entries, rollbackSeqNo)
+		streamOp, err := dcp.OpenStream(vbID, flags, entry.VbUUID, rollbackSeqNo, endSeqNo, rollbackSeqNo,
+			rollbackSeqNo, evtHandler, opts, cb)
+		if err != nil {
+			cb(nil, err)
+			return
+		}
+		op.AddOp(streamOp)
+	})
+	if err != nil {
+		return nil, err
+	}
+	op.AddOp(failoverOp)
+
+	return op, nil
+}
+
+// completeRollback fetches the failover log for a vbucket which the server has asked to be rolled back, so that the
+// error contains everything needed to reopen the stream. If the failover log cannot be fetched then the error is
+// returned without it.
+func (dcp *dcpComponent) completeRollback(rollbackErr DCPRollbackError, cb OpenStreamCallback) {
+	_, err := dcp.GetFailoverLog(rollbackErr.VbID, func(entries []FailoverEntry, err error) {
+		if err != nil {
+			logDebugf("Failed to fetch failover log for rollback of vbucket %d: %v", rollbackErr.VbID, err)
+		} else {
+			rollbackErr.FailoverLog = entries
+			rollbackErr.SuggestedVbUUID = dcpRollbackPoint(entries, rollbackErr.SeqNo).VbUUID
+		}
+
+		cb(nil, rollbackErr)
+	})
+	if err != nil {
+		logDebugf("Failed to fetch failover log for rollback of vbucket %d: %v", rollbackErr.VbID, err)
+		cb(nil, rollbackErr)
+	}
+}
+
+// dcpRollbackPoint finds the failover log entry which a stream should be reopened against after the server has
+// requested a rollback to rollbackSeqNo. The failover log is ordered from newest to oldest, so this is the first
+// entry which began at or before the rollback seqno.
+func dcpRollbackPoint(entries []FailoverEntry, rollbackSeqNo SeqNo) FailoverEntry {
+	if len(entries) == 0 {
+		return FailoverEntry{}
+	}
+
+	for _, entry := range entries {
+		if entry.SeqNo <= rollbackSeqNo {
+			return entry
+		}
+	}
+
+	// This should only happen when rolling back to 0, in which case the oldest entry is the right branch.
+	return entries[len(entries)-1]
+}
+
+func (dcp *dcpComponent) GetVbucketPurgeSeqno(vbID uint16, opts GetVbucketPurgeSeqnoOptions,
+	cb GetVbucketPurgeSeqnoCallback) (PendingOp, error) {
+	if opts.Deadline.IsZero() {
+		return nil, wrapError(errInvalidArgument, "a deadline must be provided")
+	}
+
+	return dcp.stats.Stats(StatsOptions{
+		Key:           fmt.Sprintf("vbucket-details %d", vbID),
+		Target:        VBucketIDStatsTarget{VbID: vbID},
+		RetryStrategy: opts.RetryStrategy,
+		Deadline:      opts.Deadline,
+	}, func(res *StatsResult, err error) {
+		if err != nil {
+			cb(VbPurgeSeqNoEntry{}, err)
+			return
+		}
+
+		// The stats are only requested from the node which is active for the vbucket.
+		for _, server := range res.Servers {
+			if server.Error != nil {
+				cb(VbPurgeSeqNoEntry{}, server.Error)
+				return
+			}
+
+			cb(parseVbucketPurgeSeqno(vbID, server.Stats))
+			return
+		}
+
+		cb(VbPurgeSeqNoEntry{}, errCliInternalError)
+	})
+}
+
+// parseVbucketPurgeSeqno extracts the purge seqno of a vbucket from the output of the vbucket-details stats group.
+func parseVbucketPurgeSeqno(vbID uint16, stats map[string]string) (VbPurgeSeqNoEntry, error) {
+	prefix := fmt.Sprintf("vb_%d:", vbID)
+
+	purgeSeqNoStr, ok := stats[prefix+"purge_seqno"]
+	if !ok {
+		return VbPurgeSeqNoEntry{}, wrapError(errParsingFailure, "purge seqno missing from vbucket details")
+	}
+	purgeSeqNo, err := strconv.ParseUint(purgeSeqNoStr, 10, 64)
+	if err != nil {
+		return VbPurgeSeqNoEntry{}, wrapError(errParsingFailure, "invalid purge seqno in vbucket details")
+	}
+
+	entry := VbPurgeSeqNoEntry{
+		VbID:       vbID,
+		PurgeSeqNo: SeqNo(purgeSeqNo),
+	}
+
+	if vbUUIDStr, ok := stats[prefix+"uuid"]; ok {
+		vbUUID, err := strconv.ParseUint(vbUUIDStr, 10, 64)
+		if err != nil {
+			return VbPurgeSeqNoEntry{}, wrapError(errParsingFailure, "invalid vbucket uuid in vbucket details")
+		}
+		entry.VbUUID = VbUUID(vbUUID)
+	}
+
+	return entry, nil
+}
+
 func (dcp *dcpComponent) GetVbucketSeqnos(serverIdx int, state memd.VbucketState, opts GetVbucketSeqnoOptions, cb GetVBucketSeqnosCallback) (PendingOp, error) {
 	handler := func(resp *memdQResponse, _ *memdQRequest, err error) {
 		if err != nil {
@@ -0,0 +1,76 @@
+package gocbcore
+
+func (suite *UnitTestSuite) TestDCPRollbackPoint() {
+	entries := []FailoverEntry{
+		{VbUUID: 3, SeqNo: 200},
+		{VbUUID: 2, SeqNo: 100},
+		{VbUUID: 1, SeqNo: 0},
+	}
+
+	suite.Assert().Equal(FailoverEntry{VbUUID: 3, SeqNo: 200}, dcpRollbackPoint(entries, 250))
+	suite.Assert().Equal(FailoverEntry{VbUUID: 3, SeqNo: 200}, dcpRollbackPoint(entries, 200))
+	suite.Assert().Equal(FailoverEntry{VbUUID: 2, SeqNo: 100}, dcpRollbackPoint(entries, 150))
+	suite.Assert().Equal(FailoverEntry{VbUUID: 1, SeqNo: 0}, dcpRollbackPoint(entries, 0))
+	suite.Assert().Equal(FailoverEntry{VbUUID: 3, SeqNo: 200}, dcpRollbackPoint(entries[:1], 0))
+	suite.Assert().Equal(FailoverEntry{}, dcpRollbackPoint(nil, 10))
+}
+
+func (suite *UnitTestSuite) TestParseFailoverLog() {
+	value := []byte{
+		0, 0, 0, 0, 0, 0, 0, 3, 0, 0, 0, 0, 0, 0, 0, 200,
+		0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0,
+	}
+
+	suite.Assert().Equal([]FailoverEntry{
+		{VbUUID: 3, SeqNo: 200},
+		{VbUUID: 1, SeqNo: 0},
+	}, parseFailoverLog(value))
+	suite.Assert().Empty(parseFailoverLog(nil))
+}
+
+func (suite *UnitTestSuite) TestDCPRollbackErrorSerialization() {
+	rollbackErr := DCPRollbackError{
+		InnerError:      ErrMemdRollback,
+		SeqNo:           150,
+		VbID:            12,
+		FailoverLog:     []FailoverEntry{{VbUUID: 3, SeqNo: 200}, {VbUUID: 2, SeqNo: 100}},
+		SuggestedVbUUID: 2,
+	}
+
+	suite.Assert().ErrorIs(rollbackErr, ErrMemdRollback)
+	suite.Assert().Contains(rollbackErr.Error(), `{"seq_no":150,"vb_id":12,"suggested_vb_uuid":2}`)
+
+	data, err := rollbackErr.MarshalJSON()
+	suite.Require().Nil(err)
+	suite.Assert().Contains(string(data), `"vb_id":12`)
+}
+
+func (suite *UnitTestSuite) TestParseVbucketPurgeSeqno() {
+	entry, err := parseVbucketPurgeSeqno(12, map[string]string{
+		"vb_12":             "active",
+		"vb_12:uuid":        "123456789",
+		"vb_12:purge_seqno": "150",
+		"vb_12:high_seqno":  "300",
+	})
+	suite.Require().Nil(err, err)
+	suite.Assert().Equal(VbPurgeSeqNoEntry{VbID: 12, VbUUID: 123456789, PurgeSeqNo: 150}, entry)
+
+	suite.Assert().True(entry.CheckpointPurged(100))
+	suite.Assert().False(entry.CheckpointPurged(150))
+	suite.Assert().False(entry.CheckpointPurged(200))
+	suite.Assert().False(entry.CheckpointPurged(0))
+
+	_, err = parseVbucketPurgeSeqno(12, map[string]string{"vb_1:purge_seqno": "150"})
+	suite.Assert().ErrorIs(err, ErrParsingFailure)
+
+	_, err = parseVbucketPurgeSeqno(12, map[string]string{"vb_12:purge_seqno": "abc"})
+	suite.Assert().ErrorIs(err, ErrParsingFailure)
+}
+
+func (suite *UnitTestSuite) TestGetVbucketPurgeSeqnoRequiresDeadline() {
+	dcp := &dcpComponent{}
+	_, err := dcp.GetVbucketPurgeSeqno(12, GetVbucketPurgeSeqnoOptions{}, func(VbPurgeSeqNoEntry, error) {
+		suite.T().Fatalf("Callback should not have been invoked")
+	})
+	suite.Assert().ErrorIs(err, ErrInvalidArgument)
+}
@@ -0,0 +1,134 @@
+package gocbcore
+
+import (
+	"fmt"
+	"sync"
+)
+
+// dcpMaxPoolSize is the largest number of connections that are created to each node when the pool is sized to fit
+// the number of streams per connection.
+const dcpMaxPoolSize = 16
+
+// dcpPoolSize returns the number of connections needed for a single node to stream every vbucket without exceeding
+// the maximum number of streams per connection, bounded by dcpMaxPoolSize.
+func dcpPoolSize(numVbuckets, maxStreamsPerConn int) int {
+	poolSize := (numVbuckets + maxStreamsPerConn - 1) / maxStreamsPerConn
+	if poolSize < 1 {
+		return 1
+	}
+	if poolSize > dcpMaxPoolSize {
+		return dcpMaxPoolSize
+	}
+
+	return poolSize
+}
+
+type dcpStreamKey struct {
+	vbID     uint16
+	streamID uint16
+}
+
+type dcpStreamAssignment struct {
+	connID string
+}
+
+// dcpStreamTracker keeps track of which DCP connection each open stream lives on, so that streams can be spread
+// across the connections to a node without any one connection exceeding the maximum number of streams.
+type dcpStreamTracker struct {
+	maxStreamsPerConn int
+
+	lock        sync.Mutex
+	streams     map[dcpStreamKey]*dcpStreamAssignment
+	connStreams map[string]int
+}
+
+func newDcpStreamTracker(maxStreamsPerConn int) *dcpStreamTracker {
+	return &dcpStreamTracker{
+		maxStreamsPerConn: maxStreamsPerConn,
+		streams:           make(map[dcpStreamKey]*dcpStreamAssignment),
+		connStreams:       make(map[string]int),
+	}
+}
+
+// Assign picks the connection with the fewest streams from the clients connected to the pipeline, returning nil if
+// none of the clients are currently connected.
+func (tracker *dcpStreamTracker) Assign(key dcpStreamKey, pipeline *memdPipeline) (*memdClient,
+	*dcpStreamAssignment, error) {
+	var clients []*memdClient
+	for _, pipeCli := range pipeline.Clients() {
+		pipeCli.lock.Lock()
+		if pipeCli.client != nil {
+			clients = append(clients, pipeCli.client)
+		}
+		pipeCli.lock.Unlock()
+	}
+
+	if len(clients) == 0 {
+		return nil, nil, nil
+	}
+
+	tracker.lock.Lock()
+	defer tracker.lock.Unlock()
+
+	var chosen *memdClient
+	for _, client := range clients {
+		if chosen == nil || tracker.connStreams[client.ConnID()] < tracker.connStreams[chosen.ConnID()] {
+			chosen = client
+		}
+	}
+
+	if tracker.connStreams[chosen.ConnID()] >= tracker.maxStreamsPerConn {
+		return nil, nil, wrapError(errOverload,
+			fmt.Sprintf("all DCP connections to %s have reached the maximum of %d streams",
+				redactSystemData(pipeline.Address()), tracker.maxStreamsPerConn))
+	}
+
+	assignment := tracker.addLocked(key, chosen.ConnID())
+
+	return chosen, assignment, nil
+}
+
+func (tracker *dcpStreamTracker) addLocked(key dcpStreamKey, connID string) *dcpStreamAssignment {
+	if existing, ok := tracker.streams[key]; ok {
+		tracker.removeLocked(key, existing)
+	}
+
+	assignment := &dcpStreamAssignment{
+		connID: connID,
+	}
+	tracker.streams[key] = assignment
+	tracker.connStreams[connID]++
+
+	return assignment
+}
+
+// Remove releases the assignment for a stream, if it has not already been released or replaced.
+func (tracker *dcpStreamTracker) Remove(key dcpStreamKey, assignment *dcpStreamAssignment) {
+	if assignment == nil {
+		return
+	}
+
+	tracker.lock.Lock()
+	tracker.removeLocked(key, assignment)
+	tracker.lock.Unlock()
+}
+
+func (tracker *dcpStreamTracker) removeLocked(key dcpStreamKey, assignment *dcpStreamAssignment) {
+	if tracker.streams[key] != assignment {
+		return
+	}
+
+	delete(tracker.streams, key)
+	tracker.connStreams[assignment.connID]--
+	if tracker.connStreams[assignment.connID] <= 0 {
+		delete(tracker.connStreams, assignment.connID)
+	}
+}
+
+// Lookup returns the assignment for a stream, if known.
+func (tracker *dcpStreamTracker) Lookup(key dcpStreamKey) *dcpStreamAssignment {
+	tracker.lock.Lock()
+	defer tracker.lock.Unlock()
+
+	return tracker.streams[key]
+}
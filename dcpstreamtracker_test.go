@@ -0,0 +1,110 @@
+package gocbcore
+
+import (
+	"errors"
+	"time"
+)
+
+func (suite *UnitTestSuite) TestDCPStreamTrackerAssign() {
+	pipeline := &memdPipeline{
+		address: "localhost:11210",
+		clients: []*memdPipelineClient{
+			{client: &memdClient{connID: "conn1"}},
+			{client: nil},
+			{client: &memdClient{connID: "conn2"}},
+		},
+	}
+
+	tracker := newDcpStreamTracker(2)
+
+	var assignments []*dcpStreamAssignment
+	for vbID := uint16(0); vbID < 4; vbID++ {
+		client, assignment, err := tracker.Assign(dcpStreamKey{vbID: vbID}, pipeline)
+		suite.Require().Nil(err)
+		suite.Require().NotNil(client)
+		suite.Assert().Equal(client.ConnID(), assignment.connID)
+		assignments = append(assignments, assignment)
+	}
+
+	// Streams should have been spread evenly across the connected clients.
+	suite.Assert().Equal(2, tracker.connStreams["conn1"])
+	suite.Assert().Equal(2, tracker.connStreams["conn2"])
+
+	_, _, err := tracker.Assign(dcpStreamKey{vbID: 4}, pipeline)
+	suite.Assert().True(errors.Is(err, ErrOverload))
+
+	// Once a stream ends there is room on its connection for another.
+	tracker.Remove(dcpStreamKey{vbID: 1}, assignments[1])
+	client, _, err := tracker.Assign(dcpStreamKey{vbID: 4}, pipeline)
+	suite.Require().Nil(err)
+	suite.Assert().Equal(assignments[1].connID, client.ConnID())
+	suite.Assert().Equal(assignments[1].connID, tracker.Lookup(dcpStreamKey{vbID: 4}).connID)
+	suite.Assert().Nil(tracker.Lookup(dcpStreamKey{vbID: 1}))
+}
+
+func (suite *UnitTestSuite) TestDCPStreamTrackerRemoveReplaced() {
+	tracker := newDcpStreamTracker(10)
+
+	key := dcpStreamKey{vbID: 1, streamID: 2}
+	oldAssignment := tracker.addLocked(key, "conn1")
+	newAssignment := tracker.addLocked(key, "conn2")
+
+	// A late end for the old stream must not release the new one.
+	tracker.Remove(key, oldAssignment)
+	suite.Assert().Equal(newAssignment, tracker.Lookup(key))
+	suite.Assert().Equal(0, tracker.connStreams["conn1"])
+	suite.Assert().Equal(1, tracker.connStreams["conn2"])
+
+	tracker.Remove(key, newAssignment)
+	suite.Assert().Nil(tracker.Lookup(key))
+	suite.Assert().Empty(tracker.connStreams)
+}
+
+func (suite *UnitTestSuite) TestDCPStreamTrackerNoClients() {
+	tracker := newDcpStreamTracker(10)
+
+	client, assignment, err := tracker.Assign(dcpStreamKey{vbID: 1}, &memdPipeline{})
+	suite.Assert().Nil(err)
+	suite.Assert().Nil(client)
+	suite.Assert().Nil(assignment)
+}
+
+func (suite *UnitTestSuite) TestDCPDispatchStreamNoClients() {
+	mux := newTestSnapshotMux([][]int{{0}}, 0)
+	mux.getState().pipelines = []*memdPipeline{{address: "localhost:11210"}}
+	dcp := newDcpComponent(mux, nil, false, 10)
+
+	assigned := func(*dcpStreamAssignment) {
+		suite.T().Fatalf("Stream should not have been assigned")
+	}
+
+	// We give up once we have run out of attempts to find a connection.
+	err := dcp.dispatchStream(&memdQRequest{}, dcpStreamKey{vbID: 0}, dcpStreamDispatchMaxAttempts-1, assigned)
+	suite.Assert().ErrorIs(err, ErrServiceNotAvailable)
+
+	// And stop waiting for one when shut down.
+	errCh := make(chan error, 1)
+	req := &memdQRequest{
+		Persistent: true,
+		Callback: func(resp *memdQResponse, req *memdQRequest, err error) {
+			errCh <- err
+		},
+	}
+	suite.Require().Nil(dcp.dispatchStream(req, dcpStreamKey{vbID: 0}, dcpStreamDispatchMaxAttempts-2, assigned))
+	close(mux.shutdownSig)
+
+	select {
+	case err := <-errCh:
+		suite.Assert().ErrorIs(err, ErrShutdown)
+	case <-time.After(500 * time.Millisecond):
+		suite.T().Fatalf("Timed out waiting for the stream request to be failed")
+	}
+}
+
+func (suite *UnitTestSuite) TestDCPPoolSize() {
+	suite.Assert().Equal(1, dcpPoolSize(64, 100))
+	suite.Assert().Equal(2, dcpPoolSize(128, 100))
+	suite.Assert().Equal(11, dcpPoolSize(1024, 100))
+	suite.Assert().Equal(dcpMaxPoolSize, dcpPoolSize(1024, 1))
+	suite.Assert().Equal(1, dcpPoolSize(0, 100))
+}
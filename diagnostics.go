@@ -6,6 +6,8 @@ import (
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"github.com/couchbase/gocbcore/v10/memd"
 )
 
 // PingState is the current state of a endpoint used in a PingResult.
@@ -22,6 +24,20 @@ const (
 	PingStateError PingState = 3
 )
 
+// KVPingMethod specifies how the KV service is checked during a ping.
+// Volatile: This API is subject to change at any time.
+type KVPingMethod uint32
+
+const (
+	// KVPingMethodNoop pings KV nodes with a NOOP request.
+	KVPingMethodNoop KVPingMethod = 0
+
+	// KVPingMethodGetMissingKey pings KV nodes with a GET for a key which is not expected to exist, treating a document
+	// not found response as healthy. This is useful when NOOP requests are blocked by a proxy between the SDK and
+	// the cluster.
+	KVPingMethodGetMissingKey KVPingMethod = 1
+)
+
 // EndpointState is the current connection state of an endpoint.
 type EndpointState uint32
 
@@ -62,18 +78,39 @@ type pingOp struct {
 	callback   PingCallback
 	bucketName string
 	httpCancel context.CancelFunc
+	closed     bool
 }
 
-func (pop *pingOp) Cancel() {
-	for _, subop := range pop.subops {
+// Cancel cancels any outstanding pings, returning whether the ping was still in progress. If it was then the callback
+// is invoked with ErrRequestCanceled and the results of any pings which complete afterwards are discarded.
+func (pop *pingOp) Cancel() bool {
+	pop.lock.Lock()
+	if pop.closed {
+		pop.lock.Unlock()
+		return false
+	}
+	pop.closed = true
+	subops := pop.subops
+	pop.lock.Unlock()
+
+	for _, subop := range subops {
 		subop.op.Cancel()
 	}
 	pop.httpCancel()
+	pop.callback(nil, errRequestCanceled)
+
+	return true
 }
 
 func (pop *pingOp) handledOneLocked(configRev int64) {
+	if pop.closed {
+		// The op has already been cancelled.
+		return
+	}
+
 	remaining := atomic.AddInt32(&pop.remaining, -1)
 	if remaining == 0 {
+		pop.closed = true
 		pop.httpCancel()
 		pop.callback(&PingResult{
 			ConfigRev: configRev,
@@ -93,6 +130,14 @@ type PingOptions struct {
 	MgmtDeadline time.Time
 	ServiceTypes []ServiceType
 
+	// KVPingMethod specifies the request used to check KV nodes, defaulting to NOOP.
+	// Volatile: This API is subject to change at any time.
+	KVPingMethod KVPingMethod
+
+	// KVPingCollectionID specifies the collection used when KVPingMethod is KVPingMethodGetMissingKey.
+	// Volatile: This API is subject to change at any time.
+	KVPingCollectionID uint32
+
 	// Internal: This should never be used and is not supported.
 	User string
 
@@ -118,6 +163,14 @@ type MemdConnInfo struct {
 	Scope        string
 	ID           string
 	State        EndpointState
+
+	// DeniedFeatures is the set of features which were requested from the server but which it did not enable.
+	// Volatile: This API is subject to change at any time.
+	DeniedFeatures []memd.HelloFeature
+
+	// AuthMechanism is the SASL mechanism which was negotiated to authenticate the connection, if any.
+	// Volatile: This API is subject to change at any time.
+	AuthMechanism AuthMechanism
 }
 
 // DiagnosticInfo is returned by the Diagnostics method and includes
@@ -126,6 +179,24 @@ type DiagnosticInfo struct {
 	ConfigRev int64
 	MemdConns []MemdConnInfo
 	State     ClusterState
+
+	// KVPools contains information about the connection pool to each KV node.
+	// Volatile: This API is subject to change at any time.
+	KVPools []KVPoolInfo
+}
+
+// KVPoolInfo describes the pool of connections to a single KV node.
+// Volatile: This API is subject to change at any time.
+type KVPoolInfo struct {
+	Address string
+	// PoolSize is the number of connections in the pool, including those which are not currently connected.
+	PoolSize int
+	// ActiveConnections is the number of connections in the pool which are connected.
+	ActiveConnections int
+	// QueueDepth is the number of requests waiting to be written to a connection.
+	QueueDepth int
+	// QueueWaitTime is how long the most recently written request spent waiting to be written to a connection.
+	QueueWaitTime time.Duration
 }
 
 // ClusterState is used to describe the state of a cluster.
@@ -146,6 +217,7 @@ type waitUntilOp struct {
 	lock       sync.Mutex
 	remaining  int32
 	callback   WaitUntilReadyCallback
+	progressCb WaitUntilReadyProgressCallback
 	stopCh     chan struct{}
 	timer      *time.Timer
 	httpCancel context.CancelFunc
@@ -193,27 +265,54 @@ func (wuo *waitUntilOp) recordRetryAttempt(reason RetryReason) {
 	}
 }
 
-func (wuo *waitUntilOp) cancel(err error) {
+func (wuo *waitUntilOp) cancel(err error) bool {
 	wuo.lock.Lock()
 	wuo.timer.Stop()
 	if wuo.closed {
 		wuo.lock.Unlock()
-		return
+		return false
 	}
 	wuo.closed = true
 	wuo.lock.Unlock()
 	close(wuo.stopCh)
 	wuo.httpCancel()
 	wuo.callback(nil, err)
+
+	return true
 }
 
-func (wuo *waitUntilOp) Cancel() {
-	wuo.cancel(errRequestCanceled)
+func (wuo *waitUntilOp) Cancel() bool {
+	return wuo.cancel(errRequestCanceled)
+}
+
+func (wuo *waitUntilOp) reportProgress(service ServiceType, ready bool, pending []string) {
+	if wuo.progressCb == nil {
+		return
+	}
+
+	wuo.lock.Lock()
+	closed := wuo.closed
+	wuo.lock.Unlock()
+	if closed {
+		return
+	}
+
+	wuo.progressCb(WaitUntilReadyProgress{
+		Service:          service,
+		Ready:            ready,
+		PendingEndpoints: pending,
+	})
 }
 
 func (wuo *waitUntilOp) handledOneLocked() {
+	if wuo.closed {
+		// The op has already been cancelled.
+		return
+	}
+
 	remaining := atomic.AddInt32(&wuo.remaining, -1)
 	if remaining == 0 {
+		wuo.closed = true
 		wuo.timer.Stop()
 		wuo.httpCancel()
 		wuo.callback(&WaitUntilReadyResult{}, nil)
@@ -230,4 +329,19 @@ type WaitUntilReadyOptions struct {
 	ServiceTypes []ServiceType // Defaults to all services
 	// If the cluster state is offline and a connect error has been observed then fast fail and return it.
 	RetryStrategy RetryStrategy
+
+	// ProgressCallback, if set, is invoked each time that the readiness of a service is checked so that callers can
+	// see which endpoints are still pending.
+	// Volatile: This API is subject to change at any time.
+	ProgressCallback WaitUntilReadyProgressCallback
+}
+
+// WaitUntilReadyProgress describes the readiness of a single service during a WaitUntilReady operation.
+// Volatile: This API is subject to change at any time.
+type WaitUntilReadyProgress struct {
+	Service ServiceType
+	Ready   bool
+
+	// PendingEndpoints is the set of endpoints which are not yet ready, this is empty if no config has been seen yet.
+	PendingEndpoints []string
 }
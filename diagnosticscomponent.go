@@ -4,7 +4,6 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"io/ioutil"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -46,8 +45,41 @@ func (dc *diagnosticsComponent) onBootstrapFail(err error) {
 	dc.preConfigBootstrapErrorLock.Unlock()
 }
 
+// kvPingMissingKey is the key used when pinging KV with KVPingMethodGetMissingKey, it is not expected to exist.
+var kvPingMissingKey = []byte("_gocbcore_ping_missing_key")
+
+// pingKVPacket creates the packet used to ping a KV node. When pinging with a GET for a missing key, the request is
+// sent for a vbucket active on the node so that it is not redirected elsewhere. If the node has no active vbuckets
+// then we fall back to a NOOP.
+func pingKVPacket(iter *pipelineSnapshot, pipelineIdx int, method KVPingMethod, collectionID uint32,
+	userFrame *memd.UserImpersonationFrame) memd.Packet {
+	if method == KVPingMethodGetMissingKey {
+		vbIDs, err := iter.VbucketsOnServer(pipelineIdx)
+		if err == nil && len(vbIDs) > 0 {
+			return memd.Packet{
+				Magic:                  memd.CmdMagicReq,
+				Command:                memd.CmdGet,
+				Key:                    kvPingMissingKey,
+				Vbucket:                vbIDs[0],
+				CollectionID:           collectionID,
+				UserImpersonationFrame: userFrame,
+			}
+		}
+	}
+
+	return memd.Packet{
+		Magic:                  memd.CmdMagicReq,
+		Command:                memd.CmdNoop,
+		Datatype:               0,
+		Cas:                    0,
+		Key:                    nil,
+		Value:                  nil,
+		UserImpersonationFrame: userFrame,
+	}
+}
+
 func (dc *diagnosticsComponent) pingKV(ctx context.Context, interval time.Duration, deadline time.Time,
-	retryStrat RetryStrategy, user string, op *pingOp) {
+	retryStrat RetryStrategy, user string, method KVPingMethod, collectionID uint32, op *pingOp) {
 
 	var userFrame *memd.UserImpersonationFrame
 	if len(user) > 0 {
@@ -108,9 +140,9 @@ func (dc *diagnosticsComponent) pingKV(ctx context.Context, interval time.Durati
 
 		if iter.RevID() > -1 {
 			var wg sync.WaitGroup
-			iter.Iterate(0, func(p *memdPipeline) bool {
+			for pipelineIdx := 0; pipelineIdx < iter.NumPipelines(); pipelineIdx++ {
 				wg.Add(1)
-				go func(pipeline *memdPipeline) {
+				go func(pipelineIdx int, pipeline *memdPipeline) {
 					serverAddress := pipeline.Address()
 
 					startTime := time.Now()
@@ -118,6 +150,10 @@ func (dc *diagnosticsComponent) pingKV(ctx context.Context, interval time.Durati
 						pingLatency := time.Since(startTime)
 
 						state := PingStateOK
+						if errors.Is(err, ErrDocumentNotFound) && req.Command == memd.CmdGet {
+							// The missing key not existing means that the node is healthy.
+							err = nil
+						}
 						if err != nil {
 							if errors.Is(err, ErrTimeout) {
 								state = PingStateTimeout
@@ -140,15 +176,7 @@ func (dc *diagnosticsComponent) pingKV(ctx context.Context, interval time.Durati
 					}
 
 					req := &memdQRequest{
-						Packet: memd.Packet{
-							Magic:                  memd.CmdMagicReq,
-							Command:                memd.CmdNoop,
-							Datatype:               0,
-							Cas:                    0,
-							Key:                    nil,
-							Value:                  nil,
-							UserImpersonationFrame: userFrame,
-						},
+						Packet:        pingKVPacket(iter, pipelineIdx, method, collectionID, userFrame),
 						Callback:      handler,
 						RetryStrategy: retryStrat,
 					}
@@ -187,16 +215,19 @@ func (dc *diagnosticsComponent) pingKV(ctx context.Context, interval time.Durati
 					}
 
 					op.lock.Lock()
+					if op.closed {
+						// The ping was cancelled whilst this request was being dispatched.
+						op.lock.Unlock()
+						curOp.Cancel()
+						return
+					}
 					op.subops = append(op.subops, pingSubOp{
 						endpoint: serverAddress,
 						op:       curOp,
 					})
 					op.lock.Unlock()
-				}(p)
-
-				// We iterate through all pipelines
-				return false
-			})
+				}(pipelineIdx, iter.PipelineAt(pipelineIdx))
+			}
 
 			wg.Wait()
 			op.lock.Lock()
@@ -242,17 +273,7 @@ func (dc *diagnosticsComponent) pingHTTP(ctx context.Context, service ServiceTyp
 
 	muxer := dc.httpMux
 
-	var path string
-	switch service {
-	case N1qlService:
-		path = "/admin/ping"
-	case CbasService:
-		path = "/admin/ping"
-	case FtsService:
-		path = "/api/ping"
-	case CapiService:
-		path = "/"
-	}
+	path := httpServicePingPath(service)
 
 	for {
 		clientMux := muxer.Get()
@@ -327,7 +348,7 @@ func (dc *diagnosticsComponent) pingHTTP(ctx context.Context, service ServiceTyp
 						defer resp.Body.Close()
 						if resp.StatusCode > 200 {
 							state = PingStateError
-							b, pErr := ioutil.ReadAll(resp.Body)
+							b, pErr := resp.readBody()
 							if pErr != nil {
 								logDebugf("Failed to read response body for ping: %v", pErr)
 							}
@@ -414,7 +435,7 @@ func (dc *diagnosticsComponent) Ping(opts PingOptions, cb PingCallback) (Pending
 	for _, serviceType := range serviceTypes {
 		switch serviceType {
 		case MemdService:
-			go dc.pingKV(ctx, interval, opts.KVDeadline, retryStrat, opts.User, op)
+			go dc.pingKV(ctx, interval, opts.KVDeadline, retryStrat, opts.User, opts.KVPingMethod, opts.KVPingCollectionID, op)
 		case CapiService:
 			go dc.pingHTTP(ctx, CapiService, interval, opts.CapiDeadline, retryStrat, op, ignoreMissingServices)
 		case N1qlService:
@@ -442,18 +463,25 @@ func (dc *diagnosticsComponent) Diagnostics(opts DiagnosticsOptions) (*Diagnosti
 		}
 
 		var conns []MemdConnInfo
+		var pools []KVPoolInfo
 
 		iter.Iterate(0, func(pipeline *memdPipeline) bool {
+			pools = append(pools, pipeline.PoolInfo())
+
 			pipeline.clientsLock.Lock()
 			for _, pipecli := range pipeline.clients {
 				localAddr := ""
 				remoteAddr := ""
 				var lastActivity time.Time
+				var deniedFeatures []memd.HelloFeature
+				var authMechanism AuthMechanism
 
 				pipecli.lock.Lock()
 				if pipecli.client != nil {
 					localAddr = pipecli.client.LocalAddress()
 					remoteAddr = pipecli.client.Address()
+					deniedFeatures = pipecli.client.DeniedFeatures()
+					authMechanism = pipecli.client.AuthMechanism()
 					lastActivityUs := atomic.LoadInt64(&pipecli.client.lastActivity)
 					if lastActivityUs != 0 {
 						lastActivity = time.Unix(0, lastActivityUs)
@@ -462,11 +490,13 @@ func (dc *diagnosticsComponent) Diagnostics(opts DiagnosticsOptions) (*Diagnosti
 				pipecli.lock.Unlock()
 
 				conn := MemdConnInfo{
-					LocalAddr:    localAddr,
-					RemoteAddr:   remoteAddr,
-					LastActivity: lastActivity,
-					ID:           fmt.Sprintf("%p", pipecli),
-					State:        pipecli.State(),
+					LocalAddr:      localAddr,
+					RemoteAddr:     remoteAddr,
+					LastActivity:   lastActivity,
+					ID:             fmt.Sprintf("%p", pipecli),
+					State:          pipecli.State(),
+					DeniedFeatures: deniedFeatures,
+					AuthMechanism:  authMechanism,
 				}
 				if dc.bucket != "" {
 					conn.Scope = redactMetaData(dc.bucket)
@@ -501,6 +531,7 @@ func (dc *diagnosticsComponent) Diagnostics(opts DiagnosticsOptions) (*Diagnosti
 				ConfigRev: iter.RevID(),
 				MemdConns: conns,
 				State:     state,
+				KVPools:   pools,
 			}, nil
 		}
 	}
@@ -554,41 +585,20 @@ func (dc *diagnosticsComponent) checkKVReady(desiredState ClusterState, op *wait
 			if connectErr == nil {
 				logDebugf("No config seen yet in kv muxer but no errors found.")
 			}
+			op.reportProgress(MemdService, false, nil)
 		} else if revID > -1 {
 			expected := iter.NumPipelines()
-			connected := 0
-			iter.Iterate(0, func(pipeline *memdPipeline) bool {
-				pipeline.clientsLock.Lock()
-				defer pipeline.clientsLock.Unlock()
-				for _, cli := range pipeline.clients {
-					state := cli.State()
-					if state == EndpointStateConnected {
-						connected++
-						if desiredState == ClusterStateDegraded {
-							// If we're after degraded state then we can just bail early as we've already fulfilled that.
-							return true
-						}
-
-						// We only need one of the pipeline clients to be connected for this pipeline to be considered
-						// online.
-						break
-					}
-
-					err := cli.Error()
-					if err != nil {
-						logDebugf("Error found in client after config seen: %v", err)
-						connectErr = err
-
-						// If the desired state is degraded then we need to keep trying as a different client or pipeline
-						// might be connected. If it's online then we can bail now as we'll never achieve that.
-						if desiredState == ClusterStateOnline {
-							return true
-						}
-					}
-				}
+			connected, pending, pipelineErr := kvPipelinesReadiness(iter, desiredState)
+			if pipelineErr != nil {
+				connectErr = pipelineErr
+			}
 
-				return false
-			})
+			ready := (desiredState == ClusterStateDegraded && connected > 0) ||
+				(desiredState == ClusterStateOnline && connected == expected)
+			if ready {
+				pending = nil
+			}
+			op.reportProgress(MemdService, ready, pending)
 
 			// If there's no error appearing from the pipeline client then let's check the poller
 			if connectErr == nil && dc.pollerErrorProvider != nil {
@@ -653,24 +663,50 @@ func (dc *diagnosticsComponent) checkKVReady(desiredState ClusterState, op *wait
 	}
 }
 
+// kvPipelinesReadiness returns the number of pipelines which have a connected client, the addresses of the pipelines
+// which don't and the last connection error seen. Every pipeline is checked, even once we know that the desired state
+// can't be reached, so that all of the pending endpoints are reported.
+func kvPipelinesReadiness(iter *pipelineSnapshot, desiredState ClusterState) (int, []string, error) {
+	var connectErr error
+	connected := 0
+	var pending []string
+	iter.Iterate(0, func(pipeline *memdPipeline) bool {
+		pipeline.clientsLock.Lock()
+		defer pipeline.clientsLock.Unlock()
+		for _, cli := range pipeline.clients {
+			state := cli.State()
+			if state == EndpointStateConnected {
+				connected++
+				if desiredState == ClusterStateDegraded {
+					// If we're after degraded state then we can just bail early as we've already fulfilled that.
+					return true
+				}
+
+				// We only need one of the pipeline clients to be connected for this pipeline to be considered
+				// online.
+				return false
+			}
+
+			err := cli.Error()
+			if err != nil {
+				logDebugf("Error found in client after config seen: %v", err)
+				connectErr = err
+			}
+		}
+
+		pending = append(pending, pipeline.Address())
+		return false
+	})
+
+	return connected, pending, connectErr
+}
+
 func (dc *diagnosticsComponent) checkHTTPReady(ctx context.Context, service ServiceType,
 	desiredState ClusterState, forceWait bool, op *waitUntilOp) {
 	retryStrat := &failFastRetryStrategy{}
 	muxer := dc.httpMux
 
-	var path string
-	switch service {
-	case N1qlService:
-		path = "/admin/ping"
-	case CbasService:
-		path = "/admin/ping"
-	case FtsService:
-		path = "/api/ping"
-	case CapiService:
-		path = "/"
-	case MgmtService:
-		path = ""
-	}
+	path := httpServicePingPath(service)
 
 	for {
 		clientMux := muxer.Get()
@@ -700,6 +736,7 @@ func (dc *diagnosticsComponent) checkHTTPReady(ctx context.Context, service Serv
 			if connectErr == nil {
 				logDebugf("No config seen yet in http muxer but no errors found.")
 			}
+			op.reportProgress(service, false, nil)
 		} else {
 			var epList []routeEndpoint
 			switch service {
@@ -716,6 +753,8 @@ func (dc *diagnosticsComponent) checkHTTPReady(ctx context.Context, service Serv
 			}
 
 			connected := uint32(0)
+			var readyLock sync.Mutex
+			readyEps := make(map[string]struct{})
 			func() {
 				ctx, cancel := context.WithCancel(ctx)
 				defer cancel()
@@ -762,6 +801,9 @@ func (dc *diagnosticsComponent) checkHTTPReady(ctx context.Context, service Serv
 							return
 						}
 						atomic.AddUint32(&connected, 1)
+						readyLock.Lock()
+						readyEps[ep] = struct{}{}
+						readyLock.Unlock()
 						if desiredState == ClusterStateDegraded {
 							// Cancel this run entirely, we've successfully satisfied the requirements
 							cancel()
@@ -772,6 +814,20 @@ func (dc *diagnosticsComponent) checkHTTPReady(ctx context.Context, service Serv
 				wg.Wait()
 			}()
 
+			numConnected := atomic.LoadUint32(&connected)
+			ready := (!forceWait && len(epList) == 0) ||
+				(len(epList) > 0 && desiredState == ClusterStateDegraded && numConnected > 0) ||
+				(len(epList) > 0 && desiredState == ClusterStateOnline && numConnected == uint32(len(epList)))
+			var pending []string
+			if !ready {
+				for _, ep := range epList {
+					if _, ok := readyEps[ep.Address]; !ok {
+						pending = append(pending, ep.Address)
+					}
+				}
+			}
+			op.reportProgress(service, ready, pending)
+
 			switch desiredState {
 			case ClusterStateDegraded:
 				if !forceWait && len(epList) == 0 {
@@ -862,6 +918,7 @@ func (dc *diagnosticsComponent) WaitUntilReady(deadline time.Time, forceWait boo
 		remaining:  int32(len(opts.ServiceTypes)),
 		stopCh:     make(chan struct{}),
 		callback:   cb,
+		progressCb: opts.ProgressCallback,
 		httpCancel: cancelFunc,
 		retryStrat: retry,
 	}
@@ -0,0 +1,122 @@
+package gocbcore
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+	"unsafe"
+
+	"github.com/couchbase/gocbcore/v10/memd"
+)
+
+func (suite *UnitTestSuite) TestWaitUntilReadyReportsPendingHTTPEndpoints() {
+	okSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(200)
+	}))
+	defer okSrv.Close()
+	failSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(503)
+	}))
+	defer failSrv.Close()
+
+	mux := &httpMux{
+		muxPtr: unsafe.Pointer(&httpClientMux{
+			n1qlEpList: []routeEndpoint{{Address: okSrv.URL}, {Address: failSrv.URL}},
+			revID:      1,
+			auth:       PasswordAuthProvider{Username: "user", Password: "pass"},
+		}),
+	}
+	tracer := newTracerComponent(&noopTracer{}, "", true, &noopMeter{}, nil)
+	dc := &diagnosticsComponent{
+		httpMux:       mux,
+		httpComponent: newHTTPComponentWithClient(httpComponentProps{}, &http.Client{}, mux, tracer),
+		defaultRetry:  newFailFastRetryStrategy(),
+	}
+
+	var progressLock sync.Mutex
+	var progress []WaitUntilReadyProgress
+	waitCh := make(chan error, 1)
+	_, err := dc.WaitUntilReady(time.Now().Add(200*time.Millisecond), false, WaitUntilReadyOptions{
+		DesiredState: ClusterStateOnline,
+		ServiceTypes: []ServiceType{N1qlService},
+		ProgressCallback: func(p WaitUntilReadyProgress) {
+			progressLock.Lock()
+			progress = append(progress, p)
+			progressLock.Unlock()
+		},
+	}, func(result *WaitUntilReadyResult, err error) {
+		waitCh <- err
+	})
+	suite.Require().Nil(err, err)
+	err = <-waitCh
+	suite.Require().True(errors.Is(err, ErrTimeout), err)
+
+	progressLock.Lock()
+	defer progressLock.Unlock()
+	suite.Require().NotEmpty(progress)
+	for _, p := range progress {
+		suite.Assert().Equal(N1qlService, p.Service)
+		suite.Assert().False(p.Ready)
+		suite.Assert().Contains(p.PendingEndpoints, failSrv.URL)
+	}
+}
+
+func (suite *UnitTestSuite) TestPingKVPacketGetMissingKey() {
+	iter := &pipelineSnapshot{
+		state: &kvMuxState{
+			routeCfg: routeConfig{
+				revID: 1,
+				vbMap: newVbucketMap([][]int{{1, 0}, {0, 1}, {1, 0}}, 1),
+			},
+		},
+	}
+
+	pkt := pingKVPacket(iter, 1, KVPingMethodGetMissingKey, 9, nil)
+	suite.Assert().Equal(memd.CmdGet, pkt.Command)
+	suite.Assert().Equal(kvPingMissingKey, pkt.Key)
+	suite.Assert().Equal(uint16(0), pkt.Vbucket)
+	suite.Assert().Equal(uint32(9), pkt.CollectionID)
+
+	// The node has no active vbuckets so we fall back to NOOP.
+	pkt = pingKVPacket(iter, 2, KVPingMethodGetMissingKey, 9, nil)
+	suite.Assert().Equal(memd.CmdNoop, pkt.Command)
+
+	pkt = pingKVPacket(iter, 1, KVPingMethodNoop, 0, nil)
+	suite.Assert().Equal(memd.CmdNoop, pkt.Command)
+
+	// Memcached buckets have no vbucket map.
+	pkt = pingKVPacket(&pipelineSnapshot{state: &kvMuxState{}}, 0, KVPingMethodGetMissingKey, 0, nil)
+	suite.Assert().Equal(memd.CmdNoop, pkt.Command)
+}
+
+func (suite *UnitTestSuite) TestKVPipelinesReadinessReportsAllPending() {
+	connectErr := errors.New("connect failed")
+	newPipeline := func(address string, state EndpointState, err error) *memdPipeline {
+		return &memdPipeline{
+			address: address,
+			clients: []*memdPipelineClient{
+				{state: uint32(state), connectError: err},
+			},
+		}
+	}
+	iter := &pipelineSnapshot{
+		state: &kvMuxState{
+			pipelines: []*memdPipeline{
+				newPipeline("a:11210", EndpointStateDisconnected, connectErr),
+				newPipeline("b:11210", EndpointStateConnected, nil),
+				newPipeline("c:11210", EndpointStateConnecting, nil),
+				newPipeline("d:11210", EndpointStateDisconnected, connectErr),
+			},
+		},
+	}
+
+	connected, pending, err := kvPipelinesReadiness(iter, ClusterStateOnline)
+	suite.Assert().Equal(1, connected)
+	suite.Assert().ElementsMatch([]string{"a:11210", "c:11210", "d:11210"}, pending)
+	suite.Assert().ErrorIs(err, connectErr)
+
+	connected, _, _ = kvPipelinesReadiness(iter, ClusterStateDegraded)
+	suite.Assert().Equal(1, connected)
+}
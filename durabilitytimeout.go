@@ -0,0 +1,48 @@
+package gocbcore
+
+import (
+	"time"
+)
+
+const (
+	// durabilityTimeoutFloor is the shortest durability timeout we send, shorter timeouts leave too little time for
+	// the mutation to be replicated to be useful.
+	durabilityTimeoutFloor = 1500 * time.Millisecond
+
+	// durabilityTimeoutCeiling is the longest durability timeout which can be encoded in the durability frame.
+	durabilityTimeoutCeiling = 65535 * time.Millisecond
+
+	// durabilityTimeoutDeadlineFraction is the portion of the time remaining until the deadline which is given to the
+	// server for durability, leaving the remainder for the response to make its way back to us.
+	durabilityTimeoutDeadlineFraction = 0.9
+)
+
+// DurabilityTimeoutForDeadline returns the durability timeout to send with a synchronously durable mutation which must
+// complete by the given deadline. This is 90% of the time remaining until the deadline, which leaves the server time to
+// respond with an ambiguous outcome before the operation itself times out, bounded to at least 1.5 seconds and at most
+// the largest timeout that the server accepts. A zero deadline returns 0, which uses the server default.
+// Mutations which have a durability level but no DurabilityLevelTimeout use this automatically.
+// Volatile: This API is subject to change at any time.
+func DurabilityTimeoutForDeadline(deadline time.Time) time.Duration {
+	if deadline.IsZero() {
+		return 0
+	}
+
+	timeout := time.Duration(float64(time.Until(deadline)) * durabilityTimeoutDeadlineFraction)
+	if timeout < durabilityTimeoutFloor {
+		return durabilityTimeoutFloor
+	}
+	if timeout > durabilityTimeoutCeiling {
+		return durabilityTimeoutCeiling
+	}
+
+	return timeout
+}
+
+func durabilityTimeoutOrDefault(timeout time.Duration, deadline time.Time) time.Duration {
+	if timeout > 0 {
+		return timeout
+	}
+
+	return DurabilityTimeoutForDeadline(deadline)
+}
@@ -0,0 +1,30 @@
+package gocbcore
+
+import (
+	"time"
+)
+
+func (suite *UnitTestSuite) TestDurabilityTimeoutForDeadline() {
+	suite.Assert().Zero(DurabilityTimeoutForDeadline(time.Time{}))
+
+	timeout := DurabilityTimeoutForDeadline(time.Now().Add(10 * time.Second))
+	suite.Assert().InDelta(float64(9*time.Second), float64(timeout), float64(100*time.Millisecond))
+
+	// Short deadlines, including ones which have already passed, are raised to the floor.
+	suite.Assert().Equal(durabilityTimeoutFloor, DurabilityTimeoutForDeadline(time.Now().Add(time.Second)))
+	suite.Assert().Equal(durabilityTimeoutFloor, DurabilityTimeoutForDeadline(time.Now().Add(-time.Second)))
+
+	// Long deadlines are capped to what the durability frame can encode.
+	suite.Assert().Equal(durabilityTimeoutCeiling, DurabilityTimeoutForDeadline(time.Now().Add(time.Hour)))
+}
+
+func (suite *UnitTestSuite) TestDurabilityTimeoutOrDefault() {
+	deadline := time.Now().Add(10 * time.Second)
+
+	// An explicit timeout is always used as is.
+	suite.Assert().Equal(500*time.Millisecond, durabilityTimeoutOrDefault(500*time.Millisecond, deadline))
+
+	suite.Assert().InDelta(float64(9*time.Second), float64(durabilityTimeoutOrDefault(0, deadline)),
+		float64(100*time.Millisecond))
+	suite.Assert().Zero(durabilityTimeoutOrDefault(0, time.Time{}))
+}
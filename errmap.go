@@ -50,6 +50,25 @@ func (retry kvErrorMapRetry) CalculateRetryDelay(retryCount uint32) time.Duratio
 	return dura
 }
 
+// ExceedsMaxDuration returns whether waiting for the next retry, after retryCount retries have already been made,
+// would take the total time spent retrying beyond the maximum duration of the spec.
+func (retry kvErrorMapRetry) ExceedsMaxDuration(retryCount uint32) bool {
+	if retry.MaxDuration <= 0 {
+		return false
+	}
+
+	maxDura := time.Duration(retry.MaxDuration) * time.Millisecond
+	var total time.Duration
+	for i := uint32(0); i <= retryCount; i++ {
+		total += retry.CalculateRetryDelay(i)
+		if total > maxDura {
+			return true
+		}
+	}
+
+	return false
+}
+
 type kvErrorMapError struct {
 	Name        string
 	Description string
@@ -1,6 +1,9 @@
 package gocbcore
 
 import (
+	"encoding/json"
+	"errors"
+
 	"github.com/couchbase/gocbcore/v10/memd"
 	"testing"
 	"time"
@@ -72,6 +75,29 @@ func TestKvErrorExponentialRetry(t *testing.T) {
 	}
 }
 
+func TestKvErrorRetryMaxDuration(t *testing.T) {
+	constant := kvErrorMapRetry{
+		Strategy:    "constant",
+		Interval:    1000,
+		After:       2000,
+		MaxDuration: 4000,
+	}
+	if constant.ExceedsMaxDuration(0) {
+		t.Fatalf("first retry should be within max duration")
+	}
+	if constant.ExceedsMaxDuration(2) {
+		t.Fatalf("third retry should be within max duration")
+	}
+	if !constant.ExceedsMaxDuration(3) {
+		t.Fatalf("fourth retry should exceed max duration")
+	}
+
+	constant.MaxDuration = 0
+	if constant.ExceedsMaxDuration(1000) {
+		t.Fatalf("should not limit retries without a max duration")
+	}
+}
+
 type errMapTestRetryStrategy struct {
 	reasons []RetryReason
 	retries int
@@ -183,3 +209,109 @@ func (suite *UnitTestSuite) TestStoreKVErrorMapV2() {
 	suite.Assert().Contains(entry.Attributes, kvErrorMapAttribute("item-only"))
 	suite.Assert().Contains(entry.Attributes, kvErrorMapAttribute("retry-now"))
 }
+
+func (suite *UnitTestSuite) TestEnhanceKvErrorDefaultCollection() {
+	errMgr := newErrMapManager("test")
+
+	err := errMgr.EnhanceKvError(errDocumentNotFound, nil, &memdQRequest{
+		Packet: memd.Packet{
+			Key: []byte("key"),
+		},
+	})
+
+	var kvErr *KeyValueError
+	suite.Require().True(errors.As(err, &kvErr))
+	suite.Assert().Equal("test", kvErr.BucketName)
+	suite.Assert().Equal("_default", kvErr.ScopeName)
+	suite.Assert().Equal("_default", kvErr.CollectionName)
+	suite.Assert().Equal(uint32(0), kvErr.CollectionID)
+
+	err = errMgr.EnhanceKvError(errDocumentNotFound, nil, &memdQRequest{
+		Packet: memd.Packet{
+			Key:          []byte("key"),
+			CollectionID: 9,
+		},
+		ScopeName:      "inventory",
+		CollectionName: "airline",
+	})
+
+	suite.Require().True(errors.As(err, &kvErr))
+	suite.Assert().Equal("inventory", kvErr.ScopeName)
+	suite.Assert().Equal("airline", kvErr.CollectionName)
+	suite.Assert().Equal(uint32(9), kvErr.CollectionID)
+}
+
+func (suite *UnitTestSuite) TestErrMapShouldRetry() {
+	errMgr := newErrMapManager("test")
+	errMgr.StoreErrorMap([]byte(`{"version":2,"revision":1,"errors":{
+		"7ff0":{"name":"DUMMY","desc":"dummy","attrs":["temp","retry-later"],
+			"retry":{"strategy":"constant","interval":10,"after":20,"ceil":100,"max-duration":40}},
+		"7ff1":{"name":"NORETRY","desc":"no retry","attrs":["temp"]}}}`))
+
+	suite.Assert().True(errMgr.ShouldRetry(memd.StatusCode(0x7ff0), 0))
+	suite.Assert().Equal(20*time.Millisecond, errMgr.RetryAfter(memd.StatusCode(0x7ff0), 0))
+	suite.Assert().True(errMgr.ShouldRetry(memd.StatusCode(0x7ff0), 2))
+	suite.Assert().Equal(10*time.Millisecond, errMgr.RetryAfter(memd.StatusCode(0x7ff0), 2))
+	suite.Assert().False(errMgr.ShouldRetry(memd.StatusCode(0x7ff0), 3))
+
+	suite.Assert().False(errMgr.ShouldRetry(memd.StatusCode(0x7ff1), 0))
+	suite.Assert().False(errMgr.ShouldRetry(memd.StatusCode(0x7ff2), 0))
+}
+
+func (suite *UnitTestSuite) TestUserMetadataPassthrough() {
+	type correlation struct {
+		id int
+	}
+	metadata := &correlation{id: 7}
+
+	req := acquireMemdQRequest()
+	req.Packet = memd.Packet{
+		Command: memd.CmdGet,
+		Key:     []byte("key"),
+	}
+	req.userMetadata = metadata
+
+	var retryReq RetryRequest = req
+	metaReq, ok := retryReq.(UserMetadataRequest)
+	suite.Require().True(ok)
+	suite.Assert().Equal(metadata, metaReq.UserMetadata())
+
+	var kvErr *KeyValueError
+	err := newErrMapManager("test").EnhanceKvError(errDocumentNotFound, nil, req)
+	suite.Require().True(errors.As(err, &kvErr))
+	suite.Assert().Equal(metadata, kvErr.UserMetadata)
+
+	tErr := makeTimeoutError(time.Now(), "Get", errUnambiguousTimeout, req)
+	suite.Assert().Equal(metadata, tErr.UserMetadata)
+
+	// Metadata must never leak into a request which is reused from the pool.
+	req.reset()
+	suite.Assert().Nil(req.UserMetadata())
+}
+
+func (suite *UnitTestSuite) TestUserMetadataNotSerialized() {
+	req := &memdQRequest{
+		Packet: memd.Packet{
+			Command: memd.CmdGet,
+			Key:     []byte("key"),
+		},
+		// Neither channels nor funcs can be marshalled to JSON.
+		userMetadata: make(chan struct{}),
+	}
+
+	kvErr := &KeyValueError{
+		InnerError:   errDocumentNotFound,
+		DocumentKey:  "key",
+		UserMetadata: func() {},
+	}
+	tErr := makeTimeoutError(time.Now(), "Get", errUnambiguousTimeout, req)
+
+	for _, err := range []interface{}{kvErr, *kvErr, tErr, *tErr} {
+		data, jErr := json.Marshal(err)
+		suite.Require().Nil(jErr, jErr)
+		suite.Assert().NotContains(string(data), "UserMetadata")
+	}
+
+	suite.Assert().Contains(kvErr.Error(), `"document_key":"key"`)
+	suite.Assert().Contains(tErr.Error(), `"s":"Get"`)
+}
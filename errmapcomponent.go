@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/couchbase/gocbcore/v10/memd"
 )
@@ -52,11 +53,18 @@ func (errMgr *errMapComponent) StoreErrorMap(mapBytes []byte) {
 	}
 }
 
-func (errMgr *errMapComponent) ShouldRetry(status memd.StatusCode) bool {
+// ShouldRetry returns whether the error map says that a request which failed with status, after having already been
+// retried retryAttempts times, should be retried. Requests are not retried beyond the max duration of the retry spec.
+func (errMgr *errMapComponent) ShouldRetry(status memd.StatusCode, retryAttempts uint32) bool {
 	kvErrData := errMgr.getKvErrMapData(status)
 	if kvErrData != nil {
 		for _, attr := range kvErrData.Attributes {
 			if attr == "auto-retry" || attr == "retry-now" || attr == "retry-later" {
+				if kvErrData.Retry.ExceedsMaxDuration(retryAttempts) {
+					logDebugf("Not retrying status 0x%02x as the error map max retry duration has been reached", uint16(status))
+					return false
+				}
+
 				return true
 			}
 		}
@@ -65,6 +73,17 @@ func (errMgr *errMapComponent) ShouldRetry(status memd.StatusCode) bool {
 	return false
 }
 
+// RetryAfter returns how long the error map says that a request which failed with status should wait before being
+// retried, or 0 if it does not specify.
+func (errMgr *errMapComponent) RetryAfter(status memd.StatusCode, retryAttempts uint32) time.Duration {
+	kvErrData := errMgr.getKvErrMapData(status)
+	if kvErrData == nil {
+		return 0
+	}
+
+	return kvErrData.Retry.CalculateRetryDelay(retryAttempts)
+}
+
 func (errMgr *errMapComponent) EnhanceKvError(err error, resp *memdQResponse, req *memdQRequest) error {
 	enhErr := &KeyValueError{
 		InnerError: err,
@@ -76,6 +95,12 @@ func (errMgr *errMapComponent) EnhanceKvError(err error, resp *memdQResponse, re
 		enhErr.ScopeName = req.ScopeName
 		enhErr.CollectionName = req.CollectionName
 		enhErr.CollectionID = req.CollectionID
+		if len(req.Key) > 0 && req.CollectionID == 0 && isDefaultCollection(req.ScopeName, req.CollectionName) {
+			// Document operations against the default collection don't need names to be provided, but the error
+			// should still say which keyspace it refers to.
+			enhErr.ScopeName = "_default"
+			enhErr.CollectionName = "_default"
+		}
 
 		retryCount, reasons := req.Retries()
 		enhErr.RetryReasons = reasons
@@ -86,6 +111,7 @@ func (errMgr *errMapComponent) EnhanceKvError(err error, resp *memdQResponse, re
 		enhErr.LastDispatchedFrom = connInfo.lastDispatchedFrom
 		enhErr.LastConnectionID = connInfo.lastConnectionID
 		enhErr.Internal.ResourceUnits = req.ResourceUnits()
+		enhErr.UserMetadata = req.UserMetadata()
 	}
 
 	if resp != nil {
@@ -141,7 +167,8 @@ func translateMemdError(err error, req *memdQRequest) error {
 		return errTemporaryFailure
 	case ErrMemdKeyExists:
 		if req.Command == memd.CmdReplace || (req.Command == memd.CmdDelete && req.Cas != 0) ||
-			(req.Command == memd.CmdSubDocMultiMutation && req.Cas != 0) {
+			(req.Command == memd.CmdSubDocMultiMutation && req.Cas != 0) ||
+			((req.Command == memd.CmdSetMeta || req.Command == memd.CmdDelMeta) && req.Cas != 0) {
 			return errCasMismatch
 		}
 		return errDocumentExists
@@ -90,6 +90,26 @@ var (
 	// vbucket id.
 	// Uncommitted: This API may change in the future.
 	ErrServerGroupMismatch = errors.New("vbucket id does not have any replica in requested server group")
+
+	// ErrEncryptionMismatch occurs when a connection is made using TLS to a port which does not use TLS, or without TLS
+	// to a port which requires it.
+	// Volatile: This API is subject to change at any time.
+	ErrEncryptionMismatch = errors.New("connection encryption does not match the server port")
+
+	// ErrChecksumMismatch occurs when the checksum stored alongside a document does not match the document body,
+	// see KVConfig.EnableChecksums.
+	// Volatile: This API is subject to change at any time.
+	ErrChecksumMismatch = errors.New("document checksum mismatch")
+
+	// ErrReadOnlyMode occurs when a mutation is dispatched whilst the agent is in read-only mode, see
+	// Agent.SetReadOnly.
+	// Volatile: This API is subject to change at any time.
+	ErrReadOnlyMode = errors.New("agent is in read-only mode")
+
+	// ErrHTTPResponseTooLarge occurs when the body of a HTTP response which is read in full exceeds
+	// HTTPConfig.MaxResponseBodySize.
+	// Volatile: This API is subject to change at any time.
+	ErrHTTPResponseTooLarge = errors.New("http response body too large")
 )
 
 // Shared Error Definitions RFC#58@15
@@ -177,6 +197,11 @@ var (
 	// Uncommitted: This API may change in the future
 	// Signals that an operation was cancelled due to the circuit breaker being open
 	ErrCircuitBreakerOpen = errors.New("circuit breaker open")
+
+	// ErrRateLimitedLocally occurs when an operation could not be dispatched within the client side rate limits
+	// configured in KVConfig.RateLimit.
+	// Volatile: This API is subject to change at any time.
+	ErrRateLimitedLocally = errors.New("rate limited locally")
 )
 
 // Query Error Definitions RFC#58@15
@@ -43,7 +43,8 @@ var (
 	ErrDCPStreamTooSlow = makeStreamEndStatusError(memd.StreamEndTooSlow)
 
 	// ErrDCPBackfillFailed occurs when there was an issue starting the backfill on
-	// the server e.g. the requested start seqno was behind the purge seqno.
+	// the server e.g. the requested start seqno was behind the purge seqno. DCPAgent.GetVbucketPurgeSeqno can be used
+	// to determine whether the stream must be restarted from 0.
 	ErrDCPBackfillFailed = makeStreamEndStatusError(memd.StreamEndBackfillFailed)
 
 	// ErrDCPStreamFilterEmpty occurs when all of the collections for a DCP stream are
@@ -153,3 +153,41 @@ func (suite *UnitTestSuite) TestGetKvStatusCodeErrorUnknown() {
 
 	suite.Assert().Equal(code, unknownErr.code)
 }
+
+func (suite *UnitTestSuite) TestTranslateMemdErrorMetaCasMismatch() {
+	for _, cmd := range []memd.CmdCode{memd.CmdSetMeta, memd.CmdDelMeta} {
+		req := &memdQRequest{
+			Packet: memd.Packet{
+				Command: cmd,
+				Cas:     123,
+			},
+		}
+		suite.Assert().ErrorIs(translateMemdError(ErrMemdKeyExists, req), ErrCasMismatch)
+
+		req.Cas = 0
+		suite.Assert().ErrorIs(translateMemdError(ErrMemdKeyExists, req), ErrDocumentExists)
+	}
+}
+
+func (suite *UnitTestSuite) TestTranslateMemdErrorRateLimited() {
+	req := &memdQRequest{
+		Packet: memd.Packet{
+			Command: memd.CmdGet,
+		},
+	}
+
+	for _, memdErr := range []error{
+		ErrMemdRateLimitedNetworkIngress,
+		ErrMemdRateLimitedNetworkEgress,
+		ErrMemdRateLimitedMaxConnections,
+		ErrMemdRateLimitedMaxCommands,
+	} {
+		err := translateMemdError(memdErr, req)
+		suite.Assert().ErrorIs(err, ErrRateLimitedFailure)
+		suite.Assert().NotErrorIs(err, ErrQuotaLimitedFailure)
+	}
+
+	err := translateMemdError(ErrMemdRateLimitedScopeSizeLimitExceeded, req)
+	suite.Assert().ErrorIs(err, ErrQuotaLimitedFailure)
+	suite.Assert().NotErrorIs(err, ErrRateLimitedFailure)
+}
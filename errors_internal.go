@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"sync/atomic"
 	"time"
 
 	"github.com/couchbase/gocbcore/v10/memd"
@@ -76,6 +77,11 @@ type KeyValueError struct {
 	LastDispatchedFrom string
 	LastConnectionID   string
 
+	// UserMetadata is the UserMetadata which was provided in the options of the operation. It is never serialized as
+	// it is opaque to the SDK and may not be serializable.
+	// Volatile: This API is subject to change at any time.
+	UserMetadata interface{} `json:"-"`
+
 	// Internal: This should never be used and is not supported.
 	Internal struct {
 		ResourceUnits *ResourceUnitResult
@@ -561,13 +567,32 @@ type TimeoutError struct {
 	LastDispatchedFrom string
 	LastConnectionID   string
 
+	// TimeQueued is how long the operation had been waiting to be written to a connection when it timed out. This is
+	// the time spent waiting for a connection for HTTP operations, it is zero if the operation was not waiting.
+	// Volatile: This API is subject to change at any time.
+	TimeQueued time.Duration
+
+	// UserMetadata is the UserMetadata which was provided in the options of the operation. It is never serialized as
+	// it is opaque to the SDK and may not be serializable.
+	// Volatile: This API is subject to change at any time.
+	UserMetadata interface{} `json:"-"`
+
 	// Internal: This should never be used and is not supported.
 	Internal struct {
 		ResourceUnits *ResourceUnitResult
 	}
+
+	// neverDispatched indicates that a kv operation timed out without ever having been written to a connection.
+	neverDispatched bool
 }
 
 func makeTimeoutError(start time.Time, op string, innerErr error, req *memdQRequest) *TimeoutError {
+	// The server can only have applied the request if it is in flight, having been written to a connection without
+	// a response yet. Otherwise it is either still queued or is waiting to be retried after being rejected.
+	if innerErr == errAmbiguousTimeout && atomic.LoadPointer(&req.waitingIn) == nil {
+		innerErr = errUnambiguousTimeout
+	}
+
 	connInfo := req.ConnectionInfo()
 	count, reasons := req.Retries()
 	err := &TimeoutError{
@@ -580,8 +605,14 @@ func makeTimeoutError(start time.Time, op string, innerErr error, req *memdQRequ
 		LastDispatchedTo:   connInfo.lastDispatchedTo,
 		LastDispatchedFrom: connInfo.lastDispatchedFrom,
 		LastConnectionID:   connInfo.lastConnectionID,
+		UserMetadata:       req.UserMetadata(),
+		neverDispatched:    connInfo.lastDispatchedTo == "",
 	}
 	err.Internal.ResourceUnits = req.ResourceUnits()
+	if err.neverDispatched && !req.queuedTime.IsZero() {
+		// The request has never been written so it has been waiting to be ever since it was queued.
+		err.TimeQueued = time.Since(req.queuedTime)
+	}
 
 	return err
 }
@@ -596,8 +627,13 @@ type timeoutError struct {
 	LastDispatchedTo   string        `json:"r,omitempty"`
 	LastDispatchedFrom string        `json:"l,omitempty"`
 	LastConnectionID   string        `json:"c,omitempty"`
+	TimeQueued         uint64        `json:"q,omitempty"`
 }
 
+// timeoutErrorNeverDispatched is reported in place of the last dispatched to endpoint when a kv operation timed out
+// without ever having been sent to the server.
+const timeoutErrorNeverDispatched = "never dispatched"
+
 // MarshalJSON implements the Marshaler interface.
 func (err *TimeoutError) MarshalJSON() ([]byte, error) {
 	toMarshal := timeoutError{
@@ -610,6 +646,10 @@ func (err *TimeoutError) MarshalJSON() ([]byte, error) {
 		LastDispatchedTo:   err.LastDispatchedTo,
 		LastDispatchedFrom: err.LastDispatchedFrom,
 		LastConnectionID:   err.LastConnectionID,
+		TimeQueued:         uint64(err.TimeQueued / time.Microsecond),
+	}
+	if err.neverDispatched {
+		toMarshal.LastDispatchedTo = timeoutErrorNeverDispatched
 	}
 
 	return json.Marshal(toMarshal)
@@ -633,12 +673,18 @@ func (err *TimeoutError) UnmarshalJSON(data []byte) error {
 	err.LastDispatchedTo = tErr.LastDispatchedTo
 	err.LastDispatchedFrom = tErr.LastDispatchedFrom
 	err.LastConnectionID = tErr.LastConnectionID
+	err.TimeQueued = time.Duration(tErr.TimeQueued) * time.Microsecond
+	if err.LastDispatchedTo == timeoutErrorNeverDispatched {
+		err.LastDispatchedTo = ""
+		err.neverDispatched = true
+	}
 
 	return nil
 }
 
 func (err TimeoutError) Error() string {
-	return err.InnerError.Error() + " | " + serializeError(err)
+	// MarshalJSON has a pointer receiver so err must be serialized by reference for it to be used.
+	return err.InnerError.Error() + " | " + serializeError(&err)
 }
 
 // Unwrap returns the underlying reason for the error
@@ -646,30 +692,54 @@ func (err TimeoutError) Unwrap() error {
 	return err.InnerError
 }
 
+// DCPRollbackError is returned when opening a DCP stream if the server requires the stream to first be rolled back
+// to SeqNo.
 type DCPRollbackError struct {
 	InnerError error
 	SeqNo      SeqNo
+
+	// VbID is the vbucket which must be rolled back.
+	// Volatile: This API is subject to change at any time.
+	VbID uint16
+
+	// FailoverLog is the failover log of the vbucket at the time of the rollback, this is empty if it could not be
+	// retrieved.
+	// Volatile: This API is subject to change at any time.
+	FailoverLog []FailoverEntry
+
+	// SuggestedVbUUID is the VbUUID of the failover log branch containing SeqNo, which a stream should be reopened
+	// with when starting from SeqNo. This is 0 if the failover log could not be retrieved.
+	// Volatile: This API is subject to change at any time.
+	SuggestedVbUUID VbUUID
 }
 
 // MarshalJSON implements the Marshaler interface.
 func (e DCPRollbackError) MarshalJSON() ([]byte, error) {
 	return json.Marshal(struct {
-		InnerError string `json:"msg,omitempty"`
-		SeqNo      uint64 `json:"seq_no,omitempty"`
+		InnerError      string `json:"msg,omitempty"`
+		SeqNo           uint64 `json:"seq_no,omitempty"`
+		VbID            uint16 `json:"vb_id"`
+		SuggestedVbUUID uint64 `json:"suggested_vb_uuid,omitempty"`
 	}{
-		InnerError: e.InnerError.Error(),
-		SeqNo:      uint64(e.SeqNo),
+		InnerError:      e.InnerError.Error(),
+		SeqNo:           uint64(e.SeqNo),
+		VbID:            e.VbID,
+		SuggestedVbUUID: uint64(e.SuggestedVbUUID),
 	})
 }
 
 // Error returns the string representation of this error.
 func (e DCPRollbackError) Error() string {
 	errBytes, serErr := json.Marshal(struct {
-		InnerError error  `json:"-"`
-		SeqNo      uint64 `json:"seq_no,omitempty"`
+		InnerError      error  `json:"-"`
+		SeqNo           uint64 `json:"seq_no,omitempty"`
+		VbID            uint16 `json:"vb_id"`
+		SuggestedVbUUID uint64 `json:"suggested_vb_uuid,omitempty"`
 	}{
-		InnerError: e.InnerError,
-		SeqNo:      uint64(e.SeqNo),
+		InnerError:      e.InnerError,
+		SeqNo:           uint64(e.SeqNo),
+		VbID:            e.VbID,
+		SuggestedVbUUID: uint64(e.SuggestedVbUUID),
 	})
 	if serErr != nil {
 		logErrorf("failed to serialize error to json: %s", serErr.Error())
@@ -683,6 +753,140 @@ func (err DCPRollbackError) Unwrap() error {
 	return err.InnerError
 }
 
+// ChecksumMismatchError is returned when a document read with checksumming enabled does not match the checksum
+// which was stored when it was written.
+// Volatile: This API is subject to change at any time.
+type ChecksumMismatchError struct {
+	InnerError  error
+	DocumentKey string
+	Expected    uint32
+	Actual      uint32
+}
+
+// MarshalJSON implements the Marshaler interface.
+func (e ChecksumMismatchError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		InnerError  string `json:"msg,omitempty"`
+		DocumentKey string `json:"document_key,omitempty"`
+		Expected    uint32 `json:"expected_checksum"`
+		Actual      uint32 `json:"actual_checksum"`
+	}{
+		InnerError:  e.InnerError.Error(),
+		DocumentKey: e.DocumentKey,
+		Expected:    e.Expected,
+		Actual:      e.Actual,
+	})
+}
+
+// Error returns the string representation of this error.
+func (e ChecksumMismatchError) Error() string {
+	errBytes, serErr := json.Marshal(struct {
+		DocumentKey string `json:"document_key,omitempty"`
+		Expected    uint32 `json:"expected_checksum"`
+		Actual      uint32 `json:"actual_checksum"`
+	}{
+		DocumentKey: e.DocumentKey,
+		Expected:    e.Expected,
+		Actual:      e.Actual,
+	})
+	if serErr != nil {
+		logErrorf("failed to serialize error to json: %s", serErr.Error())
+	}
+
+	return e.InnerError.Error() + " | " + string(errBytes)
+}
+
+// Unwrap returns the underlying reason for the error
+func (e ChecksumMismatchError) Unwrap() error {
+	return e.InnerError
+}
+
+// QueueOverloadError is returned when an operation cannot be dispatched because the queue of operations waiting to be
+// written to a node is full.
+// Volatile: This API is subject to change at any time.
+type QueueOverloadError struct {
+	InnerError  error
+	Endpoint    string
+	QueueLength int
+	OldestOpAge time.Duration
+}
+
+// MarshalJSON implements the Marshaler interface.
+func (e QueueOverloadError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		InnerError  string `json:"msg,omitempty"`
+		Endpoint    string `json:"endpoint,omitempty"`
+		QueueLength int    `json:"queue_length"`
+		OldestOpAge uint64 `json:"oldest_op_age_us"`
+	}{
+		InnerError:  e.InnerError.Error(),
+		Endpoint:    e.Endpoint,
+		QueueLength: e.QueueLength,
+		OldestOpAge: uint64(e.OldestOpAge / time.Microsecond),
+	})
+}
+
+// Error returns the string representation of this error.
+func (e QueueOverloadError) Error() string {
+	errBytes, serErr := json.Marshal(struct {
+		Endpoint    string `json:"endpoint,omitempty"`
+		QueueLength int    `json:"queue_length"`
+		OldestOpAge uint64 `json:"oldest_op_age_us"`
+	}{
+		Endpoint:    e.Endpoint,
+		QueueLength: e.QueueLength,
+		OldestOpAge: uint64(e.OldestOpAge / time.Microsecond),
+	})
+	if serErr != nil {
+		logErrorf("failed to serialize error to json: %s", serErr.Error())
+	}
+
+	return e.InnerError.Error() + " | " + string(errBytes)
+}
+
+// Unwrap returns the underlying reason for the error
+func (e QueueOverloadError) Unwrap() error {
+	return e.InnerError
+}
+
+// ConfigValidationError is returned by AgentConfig.Validate and lists every problem which was found with the
+// configuration.
+// Volatile: This API is subject to change at any time.
+type ConfigValidationError struct {
+	InnerError error
+	Problems   []string
+}
+
+// MarshalJSON implements the Marshaler interface.
+func (e ConfigValidationError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		InnerError string   `json:"msg,omitempty"`
+		Problems   []string `json:"problems,omitempty"`
+	}{
+		InnerError: e.InnerError.Error(),
+		Problems:   e.Problems,
+	})
+}
+
+// Error returns the string representation of this error.
+func (e ConfigValidationError) Error() string {
+	errBytes, serErr := json.Marshal(struct {
+		Problems []string `json:"problems,omitempty"`
+	}{
+		Problems: e.Problems,
+	})
+	if serErr != nil {
+		logErrorf("failed to serialize error to json: %s", serErr.Error())
+	}
+
+	return e.InnerError.Error() + " | " + string(errBytes)
+}
+
+// Unwrap returns the underlying reason for the error
+func (e ConfigValidationError) Unwrap() error {
+	return e.InnerError
+}
+
 // ncError is a wrapper error that provides no additional context to one of the
 // publicly exposed error types.  This is to force people to correctly use the
 // error handling behaviours to check the error, rather than direct compares.
@@ -735,6 +939,8 @@ var (
 	errScopeNotFound            = ncError{ErrScopeNotFound}
 	errIndexNotFound            = ncError{ErrIndexNotFound}
 	errIndexExists              = ncError{ErrIndexExists}
+	errScopeExists              = ncError{ErrScopeExists}
+	errCollectionExists         = ncError{ErrCollectionExists}
 	errGCCCPInUse               = ncError{ErrGCCCPInUse}
 	errNotMyVBucket             = ncError{ErrNotMyVBucket}
 	errDMLFailure               = ncError{ErrDMLFailure}
@@ -807,6 +1013,8 @@ var (
 	errStreamIDNotEnabled     = ncError{ErrStreamIDNotEnabled}
 	errDCPStreamIDInvalid     = ncError{ErrDCPStreamIDInvalid}
 	errForcedReconnect        = ncError{ErrForcedReconnect}
+	errEncryptionMismatch     = ncError{ErrEncryptionMismatch}
+	errReadOnlyMode           = ncError{ErrReadOnlyMode}
 
 	errRateLimitedFailure  = ncError{ErrRateLimitedFailure}
 	errQuotaLimitedFailure = ncError{ErrQuotaLimitedFailure}
@@ -819,4 +1027,6 @@ var (
 	errConnectionIDInvalid = ncError{ErrConnectionIDInvalid}
 
 	errCircuitBreakerOpen = ncError{ErrCircuitBreakerOpen}
+
+	errRateLimitedLocally = ncError{ErrRateLimitedLocally}
 )
@@ -4,7 +4,12 @@ import (
 	"context"
 	"errors"
 	"io"
+	"io/ioutil"
+	"math"
+	"net/http"
 	"sort"
+	"strconv"
+	"strings"
 	"sync/atomic"
 	"time"
 )
@@ -33,16 +38,52 @@ type httpRequest struct {
 
 	retryCount   uint32
 	retryReasons []RetryReason
+
+	serverRetryAfter int64
+
+	// completed is set, atomically, once the request has either completed or been cancelled.
+	completed uint32
+
+	// isCanary indicates that this request is a circuit breaker canary and so must bypass the circuit breaker.
+	isCanary bool
 }
 
 func (hr *httpRequest) retryStrategy() RetryStrategy {
 	return hr.RetryStrategy
 }
 
-func (hr *httpRequest) Cancel() {
+func (hr *httpRequest) Cancel() bool {
+	if !atomic.CompareAndSwapUint32(&hr.completed, 0, 1) {
+		return false
+	}
+
 	if hr.CancelFunc != nil {
 		hr.CancelFunc()
 	}
+
+	return true
+}
+
+// complete must be called once the request has a result and before the callback is invoked, it returns the error
+// to invoke the callback with. If the request was cancelled first then a cancellation error is returned, and if the
+// request was successful then release is used to release the result as it will not be passed to the callback.
+func (hr *httpRequest) complete(err error, release func() error) error {
+	if atomic.CompareAndSwapUint32(&hr.completed, 0, 1) {
+		return err
+	}
+
+	if err == nil {
+		if releaseErr := release(); releaseErr != nil {
+			logDebugf("Failed to release result of cancelled request (%s)", releaseErr)
+		}
+		return errRequestCanceled
+	}
+
+	if errors.Is(err, ErrRequestCanceled) {
+		return err
+	}
+
+	return errRequestCanceled
 }
 
 func (hr *httpRequest) RetryAttempts() uint32 {
@@ -61,6 +102,18 @@ func (hr *httpRequest) RetryReasons() []RetryReason {
 	return hr.retryReasons
 }
 
+func (hr *httpRequest) ServerRetryAfter() time.Duration {
+	return time.Duration(atomic.LoadInt64(&hr.serverRetryAfter))
+}
+
+func (hr *httpRequest) setServerRetryAfter(duration time.Duration) {
+	atomic.StoreInt64(&hr.serverRetryAfter, int64(duration))
+}
+
+func (hr *httpRequest) operationDeadline() time.Time {
+	return hr.Deadline
+}
+
 func (hr *httpRequest) recordRetryAttempt(reason RetryReason) {
 	atomic.AddUint32(&hr.retryCount, 1)
 	idx := sort.Search(len(hr.retryReasons), func(i int) bool {
@@ -101,6 +154,103 @@ type HTTPResponse struct {
 	StatusCode    int
 	ContentLength int64
 	Body          io.ReadCloser
+
+	header      http.Header
+	maxBodySize int64
+}
+
+// defaultMaxHTTPResponseBodySize is the largest HTTP response body which is read in full when
+// HTTPConfig.MaxResponseBodySize is not set.
+const defaultMaxHTTPResponseBodySize = 64 * 1024 * 1024
+
+// limitedBody returns a reader over the response body which fails with ErrHTTPResponseTooLarge once more than the
+// maximum body size has been read. This must be used whenever a non-streaming response is read in full.
+func (resp *HTTPResponse) limitedBody() *httpBodyLimitReader {
+	maxSize := resp.maxBodySize
+	if maxSize <= 0 {
+		maxSize = defaultMaxHTTPResponseBodySize
+	}
+
+	return &httpBodyLimitReader{
+		r:         resp.Body,
+		limit:     maxSize,
+		remaining: maxSize,
+	}
+}
+
+// readBody reads the whole response body, failing with ErrHTTPResponseTooLarge rather than buffering a body larger
+// than the maximum body size.
+func (resp *HTTPResponse) readBody() ([]byte, error) {
+	return ioutil.ReadAll(resp.limitedBody())
+}
+
+type httpBodyLimitReader struct {
+	r         io.Reader
+	limit     int64
+	remaining int64
+}
+
+// reset allows the limit to be read again, less readAhead bytes which have already been read. This is used to apply
+// the limit to each item of a streamed response rather than to the stream as a whole.
+func (lr *httpBodyLimitReader) reset(readAhead int64) {
+	lr.remaining = lr.limit - readAhead
+}
+
+func (lr *httpBodyLimitReader) Read(p []byte) (int, error) {
+	if lr.remaining < 0 {
+		return 0, ErrHTTPResponseTooLarge
+	}
+
+	// Allow one byte beyond the limit to be read so that a body of exactly the maximum size is not rejected. The data
+	// which crosses the limit is still returned, as it may complete an item of a streamed response, and the next read
+	// fails.
+	if int64(len(p)) > lr.remaining+1 {
+		p = p[:lr.remaining+1]
+	}
+	n, err := lr.r.Read(p)
+	lr.remaining -= int64(n)
+	if lr.remaining < 0 && err != nil {
+		err = ErrHTTPResponseTooLarge
+	}
+
+	return n, err
+}
+
+// retryAfterFromHTTPResponse returns how long the Retry-After header of the response says to wait before retrying,
+// or 0 if there is no valid header.
+func retryAfterFromHTTPResponse(resp *HTTPResponse) time.Duration {
+	if resp == nil || resp.header == nil {
+		return 0
+	}
+
+	return parseRetryAfterHeader(resp.header.Get("Retry-After"), time.Now())
+}
+
+// parseRetryAfterHeader parses a Retry-After header value, which can either be a number of seconds or a date.
+func parseRetryAfterHeader(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if seconds <= 0 || seconds > int64(math.MaxInt64/time.Second) {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+
+	at, err := http.ParseTime(value)
+	if err != nil {
+		return 0
+	}
+
+	wait := at.Sub(now)
+	if wait < 0 {
+		return 0
+	}
+
+	return wait
 }
 
 func wrapHTTPError(req *httpRequest, err error) HTTPError {
@@ -0,0 +1,73 @@
+package gocbcore
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+func (suite *UnitTestSuite) TestHTTPResponseReadBodyLimit() {
+	newResp := func(body []byte, maxSize int64) *HTTPResponse {
+		return &HTTPResponse{
+			Body:        ioutil.NopCloser(bytes.NewReader(body)),
+			maxBodySize: maxSize,
+		}
+	}
+
+	body, err := newResp([]byte("0123456789"), 10).readBody()
+	suite.Require().Nil(err, err)
+	suite.Assert().Equal("0123456789", string(body))
+
+	_, err = newResp([]byte("0123456789a"), 10).readBody()
+	suite.Assert().True(errors.Is(err, ErrHTTPResponseTooLarge), err)
+
+	// The default limit applies when none is configured.
+	body, err = newResp([]byte("{}"), 0).readBody()
+	suite.Require().Nil(err, err)
+	suite.Assert().Equal("{}", string(body))
+
+	var parsed map[string]string
+	err = json.NewDecoder(newResp([]byte(`{"uid":"aaaaaaaaaaaaaaaaaaaaaaaa"}`), 8).limitedBody()).Decode(&parsed)
+	suite.Assert().True(errors.Is(err, ErrHTTPResponseTooLarge), err)
+
+	config := &AgentConfig{}
+	suite.Require().Nil(config.FromConnStr("couchbase://10.112.192.101?max_http_response_body_size=1024"))
+	suite.Assert().Equal(int64(1024), config.HTTPConfig.MaxResponseBodySize)
+	suite.Assert().NotNil(config.FromConnStr("couchbase://10.112.192.101?max_http_response_body_size=-1"))
+}
+
+func (suite *UnitTestSuite) TestDecodeConfigBlockLimit() {
+	block := []byte(`{"rev":1,"name":"default"}`)
+	var stream []byte
+	for i := 0; i < 10; i++ {
+		stream = append(stream, block...)
+		stream = append(stream, "\n\n\n\n"...)
+	}
+	maxSize := int64(len(block) + 4)
+
+	// The stream as a whole exceeds the limit but each config is within it.
+	body := (&HTTPResponse{Body: ioutil.NopCloser(bytes.NewReader(stream)), maxBodySize: maxSize}).limitedBody()
+	dec := json.NewDecoder(body)
+	var decoded int
+	for {
+		var configBlock configStreamBlock
+		err := decodeConfigBlock(dec, body, &configBlock)
+		if err == io.EOF {
+			break
+		}
+		suite.Require().Nil(err, err)
+		suite.Assert().Equal(block, configBlock.Bytes)
+		decoded++
+	}
+	suite.Assert().Equal(10, decoded)
+
+	// A single config which exceeds the limit aborts the stream.
+	stream = append([]byte(`{"rev":1,"name":"`+strings.Repeat("a", 100)+`"}`), stream...)
+	body = (&HTTPResponse{Body: ioutil.NopCloser(bytes.NewReader(stream)), maxBodySize: maxSize}).limitedBody()
+	dec = json.NewDecoder(body)
+	err := decodeConfigBlock(dec, body, &configStreamBlock{})
+	suite.Assert().True(errors.Is(err, ErrHTTPResponseTooLarge), err)
+}
@@ -0,0 +1,52 @@
+package gocbcore
+
+import (
+	"time"
+)
+
+// HTTPAuditRecord describes a single HTTP request which was sent to the cluster.
+// Volatile: This API is subject to change at any time.
+type HTTPAuditRecord struct {
+	Service  ServiceType
+	Endpoint string
+	Method   string
+	Path     string
+	UniqueID string
+
+	// StatusCode is the status code of the response, or 0 if no response was received.
+	StatusCode int
+
+	// Latency is the time taken to receive the response headers from the server.
+	Latency time.Duration
+
+	// BodySize is the size of the request body in bytes, the body itself is never included as it may contain
+	// credentials or other sensitive data.
+	BodySize int
+
+	// Error is the error which prevented a response from being received, if any.
+	Error error
+}
+
+// HTTPAuditHook is invoked once for every HTTP request sent to the cluster, including each retry of a request. This
+// allows applications to audit the actions, such as cluster administration, that were performed on their behalf.
+// The hook is invoked synchronously on the goroutine performing the request and so must not block.
+// Volatile: This API is subject to change at any time.
+type HTTPAuditHook func(record HTTPAuditRecord)
+
+func (hc *httpComponent) audit(req *httpRequest, endpoint string, statusCode int, latency time.Duration, err error) {
+	if hc.auditHook == nil || req.isCanary {
+		return
+	}
+
+	hc.auditHook(HTTPAuditRecord{
+		Service:    req.Service,
+		Endpoint:   endpoint,
+		Method:     req.Method,
+		Path:       req.Path,
+		UniqueID:   req.UniqueID,
+		StatusCode: statusCode,
+		Latency:    latency,
+		BodySize:   len(req.Body),
+		Error:      err,
+	})
+}
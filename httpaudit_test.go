@@ -0,0 +1,81 @@
+package gocbcore
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"time"
+	"unsafe"
+)
+
+func (suite *UnitTestSuite) TestHTTPComponentAuditHook() {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	suite.Require().Nil(err, err)
+	unreachable := "http://" + listener.Addr().String()
+	suite.Require().Nil(listener.Close())
+
+	mux := &httpMux{
+		muxPtr: unsafe.Pointer(&httpClientMux{
+			mgmtEpList: []routeEndpoint{{Address: srv.URL}, {Address: unreachable}},
+			revID:      1,
+			auth:       PasswordAuthProvider{Username: "user", Password: "pass"},
+		}),
+	}
+
+	var records []HTTPAuditRecord
+	tracer := newTracerComponent(&noopTracer{}, "", true, &noopMeter{}, nil)
+	hc := newHTTPComponentWithClient(httpComponentProps{
+		AuditHook: func(record HTTPAuditRecord) {
+			records = append(records, record)
+		},
+	}, &http.Client{}, mux, tracer)
+
+	resp, err := hc.DoInternalHTTPRequest(&httpRequest{
+		Service:       MgmtService,
+		Method:        "POST",
+		Path:          "/pools/default/buckets",
+		Endpoint:      srv.URL,
+		Body:          []byte("name=test&ramQuotaMB=100"),
+		UniqueID:      "audit-id",
+		Deadline:      time.Now().Add(time.Second),
+		RetryStrategy: newFailFastRetryStrategy(),
+		Context:       context.Background(),
+	}, true)
+	suite.Require().Nil(err, err)
+	suite.Require().Nil(resp.Body.Close())
+
+	suite.Require().Len(records, 1)
+	suite.Assert().Equal(MgmtService, records[0].Service)
+	suite.Assert().Equal(srv.URL, records[0].Endpoint)
+	suite.Assert().Equal("POST", records[0].Method)
+	suite.Assert().Equal("/pools/default/buckets", records[0].Path)
+	suite.Assert().Equal("audit-id", records[0].UniqueID)
+	suite.Assert().Equal(http.StatusAccepted, records[0].StatusCode)
+	suite.Assert().Equal(24, records[0].BodySize)
+	suite.Assert().NotZero(records[0].Latency)
+	suite.Assert().Nil(records[0].Error)
+
+	_, err = hc.DoInternalHTTPRequest(&httpRequest{
+		Service:       MgmtService,
+		Method:        "DELETE",
+		Path:          "/pools/default/buckets/test",
+		Endpoint:      unreachable,
+		Deadline:      time.Now().Add(time.Second),
+		RetryStrategy: newFailFastRetryStrategy(),
+		Context:       context.Background(),
+	}, true)
+	suite.Require().NotNil(err)
+
+	suite.Require().Len(records, 2)
+	suite.Assert().Equal("DELETE", records[1].Method)
+	suite.Assert().Equal(unreachable, records[1].Endpoint)
+	suite.Assert().Zero(records[1].StatusCode)
+	suite.Assert().Zero(records[1].BodySize)
+	suite.Assert().NotNil(records[1].Error)
+}
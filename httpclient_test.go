@@ -9,6 +9,9 @@ func newHTTPComponentWithClient(props httpComponentProps, client *http.Client, m
 		userAgent:            props.UserAgent,
 		defaultRetryStrategy: props.DefaultRetryStrategy,
 		tracer:               tracer,
+		auditHook:            props.AuditHook,
+		endpointSelection:    props.EndpointSelection,
+		latencies:            newHTTPLatencyTracker(),
 		cli:                  client,
 	}
 
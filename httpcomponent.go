@@ -12,7 +12,9 @@ import (
 	"math/rand"
 	"net"
 	"net/http"
+	"net/http/httptrace"
 	"os"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -29,13 +31,30 @@ type httpComponent struct {
 	userAgent            string
 	tracer               *tracerComponent
 	defaultRetryStrategy RetryStrategy
+	auditHook            HTTPAuditHook
+	endpointSelection    HTTPEndpointSelectionPolicy
+	maxResponseBodySize  int64
+	latencies            *httpLatencyTracker
+
+	breakerCfgs  map[ServiceType]CircuitBreakerConfig
+	breakersLock sync.Mutex
+	breakers     map[httpBreakerKey]*lazyCircuitBreaker
 
 	shutdownSig chan struct{}
 }
 
+type httpBreakerKey struct {
+	service  ServiceType
+	endpoint string
+}
+
 type httpComponentProps struct {
-	UserAgent            string
-	DefaultRetryStrategy RetryStrategy
+	UserAgent             string
+	DefaultRetryStrategy  RetryStrategy
+	CircuitBreakerConfigs map[ServiceType]CircuitBreakerConfig
+	AuditHook             HTTPAuditHook
+	EndpointSelection     HTTPEndpointSelectionPolicy
+	MaxResponseBodySize   int64
 }
 
 type httpClientProps struct {
@@ -52,6 +71,12 @@ func newHTTPComponent(props httpComponentProps, clientProps httpClientProps, mux
 		userAgent:            props.UserAgent,
 		defaultRetryStrategy: props.DefaultRetryStrategy,
 		tracer:               tracer,
+		auditHook:            props.AuditHook,
+		endpointSelection:    props.EndpointSelection,
+		maxResponseBodySize:  props.MaxResponseBodySize,
+		latencies:            newHTTPLatencyTracker(),
+		breakerCfgs:          props.CircuitBreakerConfigs,
+		breakers:             make(map[httpBreakerKey]*lazyCircuitBreaker),
 		shutdownSig:          make(chan struct{}),
 	}
 
@@ -106,6 +131,9 @@ func (hc *httpComponent) DoHTTPRequest(req *HTTPRequest, cb DoHTTPRequestCallbac
 
 	go func() {
 		resp, err := hc.DoInternalHTTPRequest(ireq, false)
+		err = ireq.complete(err, func() error {
+			return resp.Body.Close()
+		})
 		if err != nil {
 			cancel()
 			if errors.Is(err, ErrRequestCanceled) {
@@ -113,7 +141,7 @@ func (hc *httpComponent) DoHTTPRequest(req *HTTPRequest, cb DoHTTPRequestCallbac
 				return
 			}
 
-			tracer.Finish()
+			tracer.FinishWithError(err)
 			cb(nil, wrapHTTPError(ireq, err))
 			return
 		}
@@ -195,7 +223,19 @@ func (hc *httpComponent) DoInternalHTTPRequest(req *httpRequest, skipConfigCheck
 				return nil, err
 			}
 		}
+
+		breaker := hc.circuitBreaker(req.Service, endpoint)
+		if breaker != nil && !req.isCanary && !breaker.AllowsRequest() {
+			if err := hc.maybeWait(req, CircuitBreakerOpenRetryReason, errCircuitBreakerOpen, start, endpoint, true); err != nil {
+				return nil, err
+			}
+			denylist = append(denylist, endpoint)
+
+			continue
+		}
+
 		var creds []UserPassPair
+		var token string
 		if req.Username == "" && req.Password == "" {
 			auth := hc.muxer.Auth()
 			if auth == nil {
@@ -204,10 +244,15 @@ func (hc *httpComponent) DoInternalHTTPRequest(req *httpRequest, skipConfigCheck
 			}
 
 			var err error
-			creds, err = auth.Credentials(AuthCredsRequest{
+			credsReq := AuthCredsRequest{
 				Service:  req.Service,
 				Endpoint: endpoint,
-			})
+			}
+			if tokenAuth, ok := auth.(tokenAuthProvider); ok {
+				token, err = tokenAuth.BearerToken(credsReq)
+			} else {
+				creds, err = auth.Credentials(credsReq)
+			}
 			if err != nil {
 				if err := hc.maybeWait(req, CredentialsFetchFailedRetryReason, err, start, endpoint, true); err != nil {
 					return nil, err
@@ -218,16 +263,42 @@ func (hc *httpComponent) DoInternalHTTPRequest(req *httpRequest, skipConfigCheck
 			}
 		}
 
-		hreq, err := generator.NewRequest(endpoint, creds)
+		hreq, err := generator.NewRequest(endpoint, creds, token)
 		if err != nil {
 			return nil, err
 		}
 
+		// gotConn is used to tell whether a request which times out was still waiting for a connection to the
+		// endpoint to become available.
+		var gotConn uint32
+		hreq = hreq.WithContext(httptrace.WithClientTrace(hreq.Context(), &httptrace.ClientTrace{
+			GotConn: func(httptrace.GotConnInfo) {
+				atomic.StoreUint32(&gotConn, 1)
+			},
+		}))
+
 		dSpan := hc.tracer.StartHTTPDispatchSpan(req, spanNameDispatchToServer)
 		logSchedf("Writing HTTP request to %s ID=%s", hreq.URL, req.UniqueID)
 		// we can't close the body of this response as it's long-lived beyond the function
+		dispatchStart := time.Now()
 		hresp, err := hc.cli.Do(hreq) // nolint: bodyclose
 		hc.tracer.StopHTTPDispatchSpan(dSpan, hreq, req.UniqueID, req.RetryAttempts())
+		if err != nil {
+			hc.audit(req, endpoint, 0, time.Since(dispatchStart), err)
+			if !errors.Is(err, context.Canceled) {
+				hc.latencies.RecordFailure(req.Service, endpoint)
+			}
+		} else {
+			hc.audit(req, endpoint, hresp.StatusCode, time.Since(dispatchStart), nil)
+			if hresp.StatusCode >= 500 {
+				hc.latencies.RecordFailure(req.Service, endpoint)
+			} else {
+				hc.latencies.Record(req.Service, endpoint, time.Since(dispatchStart))
+			}
+		}
+		if breaker != nil && !req.isCanary && !errors.Is(err, context.Canceled) {
+			markCircuitBreaker(breaker, httpBreakerError(err))
+		}
 		if err != nil {
 			logDebugf("Received HTTP Response for ID=%s, errored: %v", req.UniqueID, err)
 
@@ -278,7 +349,7 @@ func (hc *httpComponent) DoInternalHTTPRequest(req *httpRequest, skipConfigCheck
 						base = errAmbiguousTimeout
 					}
 
-					err = &TimeoutError{
+					tErr := &TimeoutError{
 						InnerError:       base,
 						OperationID:      "http",
 						Opaque:           req.Identifier(),
@@ -287,6 +358,10 @@ func (hc *httpComponent) DoInternalHTTPRequest(req *httpRequest, skipConfigCheck
 						RetryAttempts:    req.retryCount,
 						LastDispatchedTo: endpoint,
 					}
+					if atomic.LoadUint32(&gotConn) == 0 {
+						tErr.TimeQueued = time.Since(dispatchStart)
+					}
+					err = tErr
 				} else {
 					err = errRequestCanceled
 				}
@@ -304,6 +379,8 @@ func (hc *httpComponent) DoInternalHTTPRequest(req *httpRequest, skipConfigCheck
 			StatusCode:    hresp.StatusCode,
 			ContentLength: hresp.ContentLength,
 			Body:          hresp.Body,
+			header:        hresp.Header,
+			maxBodySize:   hc.maxResponseBodySize,
 		}
 
 		querySuccess = true
@@ -312,6 +389,103 @@ func (hc *httpComponent) DoInternalHTTPRequest(req *httpRequest, skipConfigCheck
 	}
 }
 
+// circuitBreaker returns the circuit breaker for an endpoint, or nil if circuit breaking is not enabled for the service.
+func (hc *httpComponent) circuitBreaker(service ServiceType, endpoint string) *lazyCircuitBreaker {
+	cfg, ok := hc.breakerCfgs[service]
+	if !ok || !cfg.Enabled {
+		return nil
+	}
+
+	key := httpBreakerKey{service: service, endpoint: endpoint}
+	hc.breakersLock.Lock()
+	defer hc.breakersLock.Unlock()
+	breaker, ok := hc.breakers[key]
+	if !ok {
+		breaker = newLazyCircuitBreaker(cfg, service, endpoint, func() {
+			hc.sendCanary(service, endpoint)
+		})
+		hc.breakers[key] = breaker
+	}
+
+	return breaker
+}
+
+// sendCanary pings the endpoint to determine whether its circuit breaker can be closed.
+func (hc *httpComponent) sendCanary(service ServiceType, endpoint string) {
+	breaker := hc.circuitBreaker(service, endpoint)
+	if breaker == nil {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	resp, err := hc.DoInternalHTTPRequest(&httpRequest{
+		Service:       service,
+		Method:        "GET",
+		Path:          httpServicePingPath(service),
+		Endpoint:      endpoint,
+		IsIdempotent:  true,
+		UniqueID:      uuid.New().String(),
+		Deadline:      time.Now().Add(breaker.CanaryTimeout()),
+		RetryStrategy: newFailFastRetryStrategy(),
+		Context:       ctx,
+		isCanary:      true,
+	}, true)
+	if err == nil {
+		if closeErr := resp.Body.Close(); closeErr != nil {
+			logDebugf("Failed to close canary response body: %s", closeErr)
+		}
+	}
+
+	markCircuitBreaker(breaker, httpBreakerError(err))
+}
+
+// CircuitBreakerStates returns the state of every HTTP circuit breaker.
+func (hc *httpComponent) CircuitBreakerStates() []CircuitBreakerInfo {
+	hc.breakersLock.Lock()
+	defer hc.breakersLock.Unlock()
+
+	states := make([]CircuitBreakerInfo, 0, len(hc.breakers))
+	for key, breaker := range hc.breakers {
+		states = append(states, newCircuitBreakerInfo(breaker, key.service, key.endpoint))
+	}
+
+	return states
+}
+
+// httpBreakerError translates network timeouts into timeout errors so that circuit breaker completion callbacks
+// can treat them in the same way as KV timeouts.
+func httpBreakerError(err error) error {
+	if err != nil && os.IsTimeout(err) {
+		return errUnambiguousTimeout
+	}
+
+	return err
+}
+
+func markCircuitBreaker(breaker circuitBreaker, err error) {
+	if breaker.CompletionCallback(err) {
+		breaker.MarkSuccessful()
+	} else {
+		breaker.MarkFailure()
+	}
+}
+
+// httpServicePingPath returns the path used to check that a HTTP service is available.
+func httpServicePingPath(service ServiceType) string {
+	switch service {
+	case N1qlService, CbasService:
+		return "/admin/ping"
+	case FtsService:
+		return "/api/ping"
+	case CapiService:
+		return "/"
+	}
+
+	return ""
+}
+
 func (hc *httpComponent) waitForConfig(ctx context.Context, isIdempotent bool, cancellationIsTimeout *uint32) error {
 	for {
 		revID, err := hc.muxer.ConfigRev()
@@ -346,6 +520,10 @@ func (hc *httpComponent) waitForConfig(ctx context.Context, isIdempotent bool, c
 }
 
 func (hc *httpComponent) randomEndpoint(service ServiceType, denylist []string) (string, error) {
+	if hc.endpointSelection == HTTPEndpointSelectionFastest && isLatencyTrackedService(service) {
+		return hc.fastestEndpoint(service, denylist)
+	}
+
 	var endpoint string
 	var err error
 	switch service {
@@ -373,6 +551,48 @@ func (hc *httpComponent) randomEndpoint(service ServiceType, denylist []string)
 	return endpoint, nil
 }
 
+// latencyTrackedEndpoints returns the endpoints of the service from the current config, dropping the latencies of any
+// endpoints which are no longer in the config.
+func (hc *httpComponent) latencyTrackedEndpoints(service ServiceType) []string {
+	var endpoints []string
+	switch service {
+	case N1qlService:
+		endpoints = hc.muxer.N1qlEps()
+	case FtsService:
+		endpoints = hc.muxer.FtsEps()
+	case CbasService:
+		endpoints = hc.muxer.CbasEps()
+	}
+	hc.latencies.Retain(service, endpoints)
+
+	return endpoints
+}
+
+func (hc *httpComponent) fastestEndpoint(service ServiceType, denylist []string) (string, error) {
+	endpoints := hc.latencyTrackedEndpoints(service)
+
+	var allowList []string
+	for _, ep := range endpoints {
+		if !inDenyList(ep, denylist) {
+			allowList = append(allowList, ep)
+		}
+	}
+	if len(allowList) == 0 {
+		return "", errServiceNotAvailable
+	}
+
+	return hc.latencies.Fastest(service, allowList), nil
+}
+
+// EndpointLatencies returns the recent latencies observed for each query, search and analytics endpoint.
+func (hc *httpComponent) EndpointLatencies() []HTTPEndpointLatency {
+	for _, service := range []ServiceType{N1qlService, FtsService, CbasService} {
+		hc.latencyTrackedEndpoints(service)
+	}
+
+	return hc.latencies.Latencies()
+}
+
 func (hc *httpComponent) checkEndpointExists(service ServiceType, endpoint string) error {
 	var err error
 	switch service {
@@ -405,6 +625,7 @@ func (hc *httpComponent) maybeWait(req *httpRequest, retryReason RetryReason, er
 	if !shouldRetry {
 		return err
 	}
+	hc.tracer.RetryCountRecord(metricValueServiceHTTPValue, "")
 
 	select {
 	case <-time.After(time.Until(retryTime)):
@@ -483,24 +704,34 @@ func (hc *httpComponent) validateEndpoint(endpoint string, endpoints []string) e
 	return errInvalidServer
 }
 
-func createTLSConfig(auth AuthProvider, caProvider func() *x509.CertPool) *dynTLSConfig {
-	return &dynTLSConfig{
-		BaseConfig: &tls.Config{
-			GetClientCertificate: func(info *tls.CertificateRequestInfo) (*tls.Certificate, error) {
-				cert, err := auth.Certificate(AuthCertRequest{})
-				if err != nil {
-					return nil, err
-				}
+func createTLSConfig(auth AuthProvider, caProvider func() *x509.CertPool, baseConfig *tls.Config) *dynTLSConfig {
+	var tlsConfig *tls.Config
+	if baseConfig != nil {
+		tlsConfig = baseConfig.Clone()
+	} else {
+		tlsConfig = &tls.Config{
+			MinVersion: tls.VersionTLS12,
+		}
+	}
 
-				if cert == nil {
-					return &tls.Certificate{}, nil
-				}
+	if tlsConfig.GetClientCertificate == nil && len(tlsConfig.Certificates) == 0 {
+		tlsConfig.GetClientCertificate = func(info *tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			cert, err := auth.Certificate(AuthCertRequest{})
+			if err != nil {
+				return nil, err
+			}
 
-				return cert, nil
-			},
-			MinVersion: tls.VersionTLS12,
-		},
-		Provider: caProvider,
+			if cert == nil {
+				return &tls.Certificate{}, nil
+			}
+
+			return cert, nil
+		}
+	}
+
+	return &dynTLSConfig{
+		BaseConfig: tlsConfig,
+		Provider:   caProvider,
 	}
 }
 
@@ -654,7 +885,7 @@ func newHTTPRequestGenerator(ctx context.Context, req *httpRequest, userAgent st
 	}
 }
 
-func (hrg *httpRequestGenerator) NewRequest(endpoint string, creds []UserPassPair) (*http.Request, error) {
+func (hrg *httpRequestGenerator) NewRequest(endpoint string, creds []UserPassPair, token string) (*http.Request, error) {
 	// Generate a request URI
 	reqURI := endpoint + hrg.request.Path
 
@@ -669,7 +900,9 @@ func (hrg *httpRequestGenerator) NewRequest(endpoint string, creds []UserPassPai
 	// Inject credentials into the request
 	if hrg.request.Username != "" || hrg.request.Password != "" {
 		hreq.SetBasicAuth(hrg.request.Username, hrg.request.Password)
-	} else {
+	} else if token != "" {
+		hreq.Header.Set("Authorization", "Bearer "+token)
+	} else if !isCertificateOnlyCreds(creds) {
 		if hrg.request.Service == N1qlService || hrg.request.Service == CbasService ||
 			hrg.request.Service == FtsService {
 			// Handle service which support multi-bucket authentication using
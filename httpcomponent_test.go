@@ -0,0 +1,62 @@
+package gocbcore
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"time"
+	"unsafe"
+)
+
+func (suite *UnitTestSuite) TestHTTPComponentTimeoutWaitingForConnection() {
+	releaseCh := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-releaseCh
+	}))
+	defer srv.Close()
+	defer close(releaseCh)
+
+	mux := &httpMux{
+		muxPtr: unsafe.Pointer(&httpClientMux{
+			mgmtEpList: []routeEndpoint{{Address: srv.URL}},
+			revID:      1,
+			auth:       PasswordAuthProvider{Username: "user", Password: "pass"},
+		}),
+	}
+	tracer := newTracerComponent(&noopTracer{}, "", true, &noopMeter{}, nil)
+	hc := newHTTPComponentWithClient(httpComponentProps{}, &http.Client{
+		Transport: &http.Transport{MaxConnsPerHost: 1},
+	}, mux, tracer)
+
+	doRequest := func(timeout time.Duration) error {
+		_, err := hc.DoInternalHTTPRequest(&httpRequest{
+			Service:       MgmtService,
+			Method:        "GET",
+			Path:          "/pools",
+			Endpoint:      srv.URL,
+			IsIdempotent:  true,
+			Deadline:      time.Now().Add(timeout),
+			RetryStrategy: newFailFastRetryStrategy(),
+			Context:       context.Background(),
+		}, true)
+		return err
+	}
+
+	// The first request holds the only connection to the endpoint until it times out.
+	firstErrCh := make(chan error, 1)
+	go func() {
+		firstErrCh <- doRequest(500 * time.Millisecond)
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	var tErr *TimeoutError
+	err := doRequest(50 * time.Millisecond)
+	suite.Require().True(errors.As(err, &tErr), err)
+	suite.Assert().GreaterOrEqual(int64(tErr.TimeQueued), int64(40*time.Millisecond))
+
+	// The first request had a connection when it timed out.
+	err = <-firstErrCh
+	suite.Require().True(errors.As(err, &tErr), err)
+	suite.Assert().Zero(tErr.TimeQueued)
+}
@@ -0,0 +1,195 @@
+package gocbcore
+
+import (
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// HTTPEndpointSelectionPolicy specifies how an endpoint is chosen for HTTP requests which are not sent to a
+// specific endpoint.
+// Volatile: This API is subject to change at any time.
+type HTTPEndpointSelectionPolicy uint32
+
+const (
+	// HTTPEndpointSelectionRandom chooses an endpoint at random, this is the default.
+	HTTPEndpointSelectionRandom HTTPEndpointSelectionPolicy = iota
+
+	// HTTPEndpointSelectionFastest chooses the query, search or analytics endpoint with the lowest recent p95
+	// latency. Endpoints without enough latency samples are preferred so that they are measured, and a small
+	// percentage of requests are sent to a random endpoint so that endpoints which were slow can recover. Requests
+	// which fail, or receive a server error, count as a latency of httpLatencyErrorPenalty so that failing endpoints
+	// are avoided even when they fail quickly.
+	HTTPEndpointSelectionFastest
+)
+
+const (
+	httpLatencyWindowSize    = 64
+	httpLatencyMinSamples    = 5
+	httpLatencyExploreChance = 0.05
+	httpLatencyErrorPenalty  = 10 * time.Second
+)
+
+// HTTPEndpointLatency is the recent latency observed for an endpoint of a query, search or analytics service. Latency
+// is measured from sending the request until the response headers are received, so it does not include the time taken
+// to stream the response body. Requests which failed are counted as a latency of 10 seconds.
+// Volatile: This API is subject to change at any time.
+type HTTPEndpointLatency struct {
+	Service  ServiceType
+	Endpoint string
+	P50      time.Duration
+	P95      time.Duration
+	Samples  int
+}
+
+type httpLatencyKey struct {
+	service  ServiceType
+	endpoint string
+}
+
+// httpLatencyWindow is a ring buffer holding the most recent latencies observed for an endpoint.
+type httpLatencyWindow struct {
+	samples [httpLatencyWindowSize]time.Duration
+	next    int
+	count   int
+}
+
+func (w *httpLatencyWindow) add(latency time.Duration) {
+	w.samples[w.next] = latency
+	w.next = (w.next + 1) % httpLatencyWindowSize
+	if w.count < httpLatencyWindowSize {
+		w.count++
+	}
+}
+
+func (w *httpLatencyWindow) percentiles() (time.Duration, time.Duration) {
+	sorted := make([]time.Duration, w.count)
+	copy(sorted, w.samples[:w.count])
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i] < sorted[j]
+	})
+
+	return sorted[(w.count-1)*50/100], sorted[(w.count-1)*95/100]
+}
+
+type httpLatencyTracker struct {
+	lock    sync.Mutex
+	windows map[httpLatencyKey]*httpLatencyWindow
+}
+
+func newHTTPLatencyTracker() *httpLatencyTracker {
+	return &httpLatencyTracker{
+		windows: make(map[httpLatencyKey]*httpLatencyWindow),
+	}
+}
+
+func isLatencyTrackedService(service ServiceType) bool {
+	return service == N1qlService || service == FtsService || service == CbasService
+}
+
+// Record records the time taken to receive the response headers for a successful request.
+func (lt *httpLatencyTracker) Record(service ServiceType, endpoint string, latency time.Duration) {
+	if !isLatencyTrackedService(service) {
+		return
+	}
+
+	key := httpLatencyKey{service: service, endpoint: endpoint}
+	lt.lock.Lock()
+	window, ok := lt.windows[key]
+	if !ok {
+		window = &httpLatencyWindow{}
+		lt.windows[key] = window
+	}
+	window.add(latency)
+	lt.lock.Unlock()
+}
+
+// RecordFailure records a request which failed, or received a server error, as a latency of httpLatencyErrorPenalty.
+func (lt *httpLatencyTracker) RecordFailure(service ServiceType, endpoint string) {
+	lt.Record(service, endpoint, httpLatencyErrorPenalty)
+}
+
+// Retain drops the latencies of any endpoints of the service which are not in endpoints, so that endpoints which have
+// left the cluster are forgotten.
+func (lt *httpLatencyTracker) Retain(service ServiceType, endpoints []string) {
+	configured := make(map[string]struct{}, len(endpoints))
+	for _, endpoint := range endpoints {
+		configured[endpoint] = struct{}{}
+	}
+
+	lt.lock.Lock()
+	defer lt.lock.Unlock()
+
+	for key := range lt.windows {
+		if key.service != service {
+			continue
+		}
+		if _, ok := configured[key.endpoint]; !ok {
+			delete(lt.windows, key)
+		}
+	}
+}
+
+// Fastest returns the endpoint with the lowest p95 latency, endpoints which have not yet been sufficiently measured
+// are returned ahead of any measured endpoints.
+func (lt *httpLatencyTracker) Fastest(service ServiceType, endpoints []string) string {
+	if len(endpoints) == 0 {
+		return ""
+	}
+	if rand.Float64() < httpLatencyExploreChance { // #nosec G404
+		return endpoints[rand.Intn(len(endpoints))] // #nosec G404
+	}
+
+	lt.lock.Lock()
+	defer lt.lock.Unlock()
+
+	var unmeasured []string
+	var fastest string
+	var fastestP95 time.Duration
+	for _, endpoint := range endpoints {
+		window, ok := lt.windows[httpLatencyKey{service: service, endpoint: endpoint}]
+		if !ok || window.count < httpLatencyMinSamples {
+			unmeasured = append(unmeasured, endpoint)
+			continue
+		}
+
+		_, p95 := window.percentiles()
+		if fastest == "" || p95 < fastestP95 {
+			fastest = endpoint
+			fastestP95 = p95
+		}
+	}
+
+	if len(unmeasured) > 0 {
+		return unmeasured[rand.Intn(len(unmeasured))] // #nosec G404
+	}
+
+	return fastest
+}
+
+func (lt *httpLatencyTracker) Latencies() []HTTPEndpointLatency {
+	lt.lock.Lock()
+	defer lt.lock.Unlock()
+
+	latencies := make([]HTTPEndpointLatency, 0, len(lt.windows))
+	for key, window := range lt.windows {
+		p50, p95 := window.percentiles()
+		latencies = append(latencies, HTTPEndpointLatency{
+			Service:  key.service,
+			Endpoint: key.endpoint,
+			P50:      p50,
+			P95:      p95,
+			Samples:  window.count,
+		})
+	}
+
+	sort.Slice(latencies, func(i, j int) bool {
+		if latencies[i].Service != latencies[j].Service {
+			return latencies[i].Service < latencies[j].Service
+		}
+		return latencies[i].Endpoint < latencies[j].Endpoint
+	})
+
+	return latencies
+}
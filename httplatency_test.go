@@ -0,0 +1,83 @@
+package gocbcore
+
+import (
+	"time"
+)
+
+func (suite *UnitTestSuite) TestHTTPLatencyWindowPercentiles() {
+	var window httpLatencyWindow
+	for i := 1; i <= 100; i++ {
+		window.add(time.Duration(i) * time.Millisecond)
+	}
+
+	// Only the most recent samples are kept.
+	suite.Assert().Equal(httpLatencyWindowSize, window.count)
+	p50, p95 := window.percentiles()
+	suite.Assert().Equal(68*time.Millisecond, p50)
+	suite.Assert().Equal(96*time.Millisecond, p95)
+}
+
+func (suite *UnitTestSuite) TestHTTPLatencyTrackerFastest() {
+	tracker := newHTTPLatencyTracker()
+	endpoints := []string{"http://fast:8093", "http://slow:8093", "http://new:8093"}
+
+	for i := 0; i < httpLatencyMinSamples; i++ {
+		tracker.Record(N1qlService, "http://fast:8093", 5*time.Millisecond)
+		tracker.Record(N1qlService, "http://slow:8093", 50*time.Millisecond)
+	}
+	// Latencies of other services and untracked services don't affect query endpoint selection.
+	tracker.Record(FtsService, "http://new:8093", time.Millisecond)
+	tracker.Record(MgmtService, "http://new:8093", time.Millisecond)
+
+	// Unmeasured endpoints are preferred so that they get measured. Requests are occasionally sent to a random
+	// endpoint so we only check that the expected endpoint is chosen the vast majority of the time.
+	counts := make(map[string]int)
+	for i := 0; i < 100; i++ {
+		counts[tracker.Fastest(N1qlService, endpoints)]++
+	}
+	suite.Assert().Greater(counts["http://new:8093"], 80)
+
+	counts = make(map[string]int)
+	for i := 0; i < 100; i++ {
+		counts[tracker.Fastest(N1qlService, endpoints[:2])]++
+	}
+	suite.Assert().Greater(counts["http://fast:8093"], 80)
+
+	suite.Assert().Empty(tracker.Fastest(N1qlService, nil))
+
+	latencies := tracker.Latencies()
+	suite.Require().Len(latencies, 3)
+	suite.Assert().Equal(HTTPEndpointLatency{
+		Service:  N1qlService,
+		Endpoint: "http://fast:8093",
+		P50:      5 * time.Millisecond,
+		P95:      5 * time.Millisecond,
+		Samples:  httpLatencyMinSamples,
+	}, latencies[0])
+	suite.Assert().Equal("http://slow:8093", latencies[1].Endpoint)
+	suite.Assert().Equal(FtsService, latencies[2].Service)
+}
+
+func (suite *UnitTestSuite) TestHTTPLatencyTrackerFailuresAndRetain() {
+	tracker := newHTTPLatencyTracker()
+	endpoints := []string{"http://failing:8093", "http://slow:8093"}
+
+	// An endpoint which fails quickly must not look faster than one which succeeds slowly.
+	for i := 0; i < httpLatencyMinSamples; i++ {
+		tracker.RecordFailure(N1qlService, "http://failing:8093")
+		tracker.Record(N1qlService, "http://slow:8093", 500*time.Millisecond)
+	}
+	counts := make(map[string]int)
+	for i := 0; i < 100; i++ {
+		counts[tracker.Fastest(N1qlService, endpoints)]++
+	}
+	suite.Assert().Greater(counts["http://slow:8093"], 80)
+
+	// Endpoints which have left the config are forgotten, without affecting other services.
+	tracker.Record(FtsService, "http://failing:8094", time.Millisecond)
+	tracker.Retain(N1qlService, []string{"http://slow:8093"})
+	latencies := tracker.Latencies()
+	suite.Require().Len(latencies, 2)
+	suite.Assert().Equal("http://slow:8093", latencies[0].Endpoint)
+	suite.Assert().Equal(FtsService, latencies[1].Service)
+}
@@ -205,6 +205,22 @@ func (mux *httpMux) ConfigRev() (int64, error) {
 	return clientMux.revID, nil
 }
 
+// ClusterVersion returns the cluster compatibility version from the current config, in the form major.minor, or an
+// empty string if it is not known.
+func (mux *httpMux) ClusterVersion() string {
+	clientMux := mux.Get()
+	if clientMux == nil {
+		return ""
+	}
+
+	compatVersion := clientMux.srcConfig.clusterCompatVersion
+	if compatVersion == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("%d.%d", compatVersion>>16, compatVersion&0xffff)
+}
+
 func (mux *httpMux) Close() error {
 	mux.cfgMgr.RemoveConfigWatcher(mux)
 	mux.Clear()
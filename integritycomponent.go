@@ -0,0 +1,364 @@
+package gocbcore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"strconv"
+
+	"github.com/couchbase/gocbcore/v10/memd"
+)
+
+const (
+	integrityXattrPath                 = "_integrity"
+	integrityChecksumXattrPath         = "_integrity.crc32c"
+	integrityStoredFlagsXattrPath      = "_integrity.flags"
+	integrityValueCRCXattrPath         = "_integrity.value_crc32c"
+	integrityFlagsXattrPath            = "$document.flags"
+	integrityDatatypeXattrPath         = "$document.datatype"
+	integrityDocumentValueCRCXattrPath = "$document.value_crc32c"
+)
+
+// integrityXattr is the content of the integrity xattr. ValueCRC32C is the server computed checksum of the body at the
+// time the checksum was written, if it does not match the current server computed checksum then the body has since
+// been modified without going through the integrity component, e.g. by Append or by another client, and the
+// checksum cannot be verified.
+type integrityXattr struct {
+	CRC32C      string  `json:"crc32c"`
+	Flags       *uint32 `json:"flags,omitempty"`
+	ValueCRC32C string  `json:"value_crc32c"`
+}
+
+var integrityCrcTable = crc32.MakeTable(crc32.Castagnoli)
+
+// integrityComponent transparently stores a checksum of the document body in a system xattr when documents are
+// written and verifies it when they are read. Writes are performed using sub-document operations, which cannot set
+// document flags, so the flags are stored in the xattr alongside the checksum. Only JSON values are checksummed,
+// other values are written as normal.
+type integrityComponent struct {
+	crud *crudComponent
+}
+
+func newIntegrityComponent(enabled bool, crud *crudComponent) *integrityComponent {
+	if !enabled {
+		return nil
+	}
+
+	return &integrityComponent{
+		crud: crud,
+	}
+}
+
+func integrityChecksum(value []byte) uint32 {
+	return crc32.Checksum(value, integrityCrcTable)
+}
+
+func formatIntegrityChecksum(value []byte) string {
+	return fmt.Sprintf("0x%08x", integrityChecksum(value))
+}
+
+func decodeIntegrityChecksum(checksumStr string) (uint32, error) {
+	checksum, err := strconv.ParseUint(checksumStr, 0, 32)
+	if err != nil {
+		return 0, err
+	}
+
+	return uint32(checksum), nil
+}
+
+func isIntegrityValue(flags uint32, datatype uint8) bool {
+	if datatype&uint8(memd.DatatypeFlagCompressed) != 0 {
+		return false
+	}
+	if datatype&uint8(memd.DatatypeFlagJSON) != 0 {
+		return true
+	}
+
+	valueType, _ := DecodeCommonFlags(flags)
+	return valueType == JSONType
+}
+
+// Get reads the document along with its checksum, routed by opts.ReadPreference. The checksum is verified against
+// the value as stored, before the value hooks are applied to it.
+func (ic *integrityComponent) Get(opts GetOptions, cb GetCallback) (PendingOp, error) {
+	return ic.crud.lookupIn(LookupInOptions{
+		Key: opts.Key,
+		Ops: []SubDocOp{
+			{
+				Op:    memd.SubDocOpGet,
+				Flags: memd.SubdocFlagXattrPath,
+				Path:  integrityXattrPath,
+			},
+			{
+				Op:    memd.SubDocOpGet,
+				Flags: memd.SubdocFlagXattrPath,
+				Path:  integrityFlagsXattrPath,
+			},
+			{
+				Op:    memd.SubDocOpGet,
+				Flags: memd.SubdocFlagXattrPath,
+				Path:  integrityDatatypeXattrPath,
+			},
+			{
+				Op:    memd.SubDocOpGet,
+				Flags: memd.SubdocFlagXattrPath,
+				Path:  integrityDocumentValueCRCXattrPath,
+			},
+			{
+				Op: memd.SubDocOpGetDoc,
+			},
+		},
+		CollectionName: opts.CollectionName,
+		ScopeName:      opts.ScopeName,
+		CollectionID:   opts.CollectionID,
+		RetryStrategy:  opts.RetryStrategy,
+		Deadline:       opts.Deadline,
+		User:           opts.User,
+		TraceContext:   opts.TraceContext,
+		UserMetadata:   opts.UserMetadata,
+	}, lookupInInternalOptions{
+		readPreference:  opts.ReadPreference,
+		skipSubDocHooks: true,
+	}, func(result *LookupInResult, err error) {
+		if err != nil {
+			cb(nil, err)
+			return
+		}
+
+		res, err := integrityGetResult(opts.Key, result)
+		if err != nil {
+			cb(nil, err)
+			return
+		}
+
+		res.Value, res.Datatype, err = ic.crud.afterRead(opts.Key, res.Value, res.Datatype)
+		if err != nil {
+			cb(nil, err)
+			return
+		}
+
+		cb(res, nil)
+	})
+}
+
+func integrityGetResult(key []byte, result *LookupInResult) (*GetResult, error) {
+	if len(result.Ops) != 5 {
+		return nil, errProtocol
+	}
+
+	integrityOp, flagsOp, datatypeOp, valueCRCOp, docOp := result.Ops[0], result.Ops[1], result.Ops[2], result.Ops[3],
+		result.Ops[4]
+	if docOp.Err != nil {
+		return nil, docOp.Err
+	}
+
+	res := &GetResult{
+		Value:        docOp.Value,
+		Cas:          result.Cas,
+		IsReplica:    result.isReplica,
+		UserMetadata: result.UserMetadata,
+	}
+	res.Internal.ResourceUnits = result.Internal.ResourceUnits
+
+	if flagsOp.Err == nil {
+		var flags uint32
+		if err := json.Unmarshal(flagsOp.Value, &flags); err != nil {
+			return nil, errProtocol
+		}
+		res.Flags = flags
+	}
+
+	if datatypeOp.Err == nil {
+		var datatypes []string
+		if err := json.Unmarshal(datatypeOp.Value, &datatypes); err != nil {
+			return nil, errProtocol
+		}
+		for _, datatype := range datatypes {
+			if datatype == "json" {
+				res.Datatype |= uint8(memd.DatatypeFlagJSON)
+			}
+		}
+	}
+
+	if integrityOp.Err != nil {
+		// Documents which were not written with integrity checking enabled will not have a checksum.
+		if errors.Is(integrityOp.Err, ErrPathNotFound) {
+			return res, nil
+		}
+
+		return nil, integrityOp.Err
+	}
+
+	var xattr integrityXattr
+	if err := json.Unmarshal(integrityOp.Value, &xattr); err != nil {
+		logDebugf("Failed to decode integrity xattr for %s: %v", redactUserData(string(key)), err)
+		return nil, errProtocol
+	}
+
+	var valueCRC string
+	if valueCRCOp.Err == nil {
+		if err := json.Unmarshal(valueCRCOp.Value, &valueCRC); err != nil {
+			return nil, errProtocol
+		}
+	}
+
+	if valueCRC == "" || xattr.ValueCRC32C != valueCRC {
+		// The body has been modified since the checksum was written so the checksum is stale, rather than the
+		// document being corrupt, and the document is returned without verification.
+		logDebugf("Integrity checksum for %s is stale, not verifying", redactUserData(string(key)))
+		return res, nil
+	}
+
+	// Documents written by sub-document operations have no flags, the flags which were requested are in the xattr.
+	if res.Flags == 0 && xattr.Flags != nil {
+		res.Flags = *xattr.Flags
+	}
+
+	expected, err := decodeIntegrityChecksum(xattr.CRC32C)
+	if err != nil {
+		logDebugf("Failed to decode integrity checksum for %s: %v", redactUserData(string(key)), err)
+		return nil, errProtocol
+	}
+
+	actual := integrityChecksum(res.Value)
+	if actual != expected {
+		return nil, ChecksumMismatchError{
+			InnerError:  ErrChecksumMismatch,
+			DocumentKey: string(key),
+			Expected:    expected,
+			Actual:      actual,
+		}
+	}
+
+	return res, nil
+}
+
+// integrityStoreOps returns the sub-document operations which write value along with its checksum, the requested
+// flags and the server computed checksum of the body, which is used to detect if the checksum has become stale.
+func integrityStoreOps(value []byte, flags uint32) []SubDocOp {
+	return []SubDocOp{
+		{
+			Op:    memd.SubDocOpDictSet,
+			Flags: memd.SubdocFlagXattrPath | memd.SubdocFlagMkDirP,
+			Path:  integrityChecksumXattrPath,
+			Value: []byte(strconv.Quote(formatIntegrityChecksum(value))),
+		},
+		{
+			Op:    memd.SubDocOpDictSet,
+			Flags: memd.SubdocFlagXattrPath | memd.SubdocFlagMkDirP,
+			Path:  integrityStoredFlagsXattrPath,
+			Value: []byte(strconv.FormatUint(uint64(flags), 10)),
+		},
+		{
+			Op:    memd.SubDocOpDictSet,
+			Flags: memd.SubdocFlagXattrPath | memd.SubdocFlagMkDirP | memd.SubdocFlagExpandMacros,
+			Path:  integrityValueCRCXattrPath,
+			Value: crc32cMacro,
+		},
+		{
+			Op:    memd.SubDocOpSetDoc,
+			Value: value,
+		},
+	}
+}
+
+// store applies the value hooks to the value and then, if it is a JSON value, writes it along with its checksum.
+// Other values are written as normal.
+func (ic *integrityComponent) store(opName string, opcode memd.CmdCode, opts storeOptions, flags memd.SubdocDocFlag,
+	cb StoreCallback) (PendingOp, error) {
+	value, datatype, err := ic.crud.beforeWrite(opts.Key, opts.Value, opts.Datatype)
+	if err != nil {
+		return nil, err
+	}
+	opts.Value = value
+	opts.Datatype = datatype
+
+	if !isIntegrityValue(opts.Flags, opts.Datatype) {
+		return ic.crud.storeValue(opName, opcode, opts, cb)
+	}
+
+	return ic.crud.mutateIn(MutateInOptions{
+		Key:                    opts.Key,
+		Flags:                  flags,
+		Cas:                    opts.Cas,
+		Ops:                    integrityStoreOps(opts.Value, opts.Flags),
+		Expiry:                 opts.Expiry,
+		PreserveExpiry:         opts.PreserveExpiry,
+		CollectionName:         opts.CollectionName,
+		ScopeName:              opts.ScopeName,
+		CollectionID:           opts.CollectionID,
+		RetryStrategy:          opts.RetryStrategy,
+		DurabilityLevel:        opts.DurabilityLevel,
+		DurabilityLevelTimeout: opts.DurabilityLevelTimeout,
+		Deadline:               opts.Deadline,
+		User:                   opts.User,
+		TraceContext:           opts.TraceContext,
+		UserMetadata:           opts.UserMetadata,
+	}, mutateInInternalOptions{
+		skipSubDocHooks: true,
+	}, func(result *MutateInResult, err error) {
+		if err != nil {
+			cb(nil, err)
+			return
+		}
+
+		res := &StoreResult{
+			Cas:           result.Cas,
+			MutationToken: result.MutationToken,
+		}
+		res.Internal.ResourceUnits = result.Internal.ResourceUnits
+		res.UserMetadata = result.UserMetadata
+
+		cb(res, nil)
+	})
+}
+
+func (ic *integrityComponent) Set(opts SetOptions, cb StoreCallback) (PendingOp, error) {
+	return ic.store("Set", memd.CmdSet, storeOptions{
+		Key:                    opts.Key,
+		CollectionName:         opts.CollectionName,
+		ScopeName:              opts.ScopeName,
+		RetryStrategy:          opts.RetryStrategy,
+		Value:                  opts.Value,
+		Flags:                  opts.Flags,
+		Datatype:               opts.Datatype,
+		Expiry:                 opts.Expiry,
+		DurabilityLevel:        opts.DurabilityLevel,
+		DurabilityLevelTimeout: opts.DurabilityLevelTimeout,
+		CollectionID:           opts.CollectionID,
+		Deadline:               opts.Deadline,
+		PreserveExpiry:         opts.PreserveExpiry,
+		User:                   opts.User,
+		TraceContext:           opts.TraceContext,
+		UserMetadata:           opts.UserMetadata,
+	}, memd.SubdocDocFlagMkDoc, cb)
+}
+
+func (ic *integrityComponent) Replace(opts ReplaceOptions, cb StoreCallback) (PendingOp, error) {
+	if opts.PreserveExpiry && opts.Expiry > 0 {
+		return nil, wrapError(errInvalidArgument, "cannot use preserve expiry and an expiry > 0 for replace")
+	}
+
+	return ic.store("Replace", memd.CmdReplace, storeOptions(opts), memd.SubdocDocFlagNone, cb)
+}
+
+func (ic *integrityComponent) Add(opts AddOptions, cb StoreCallback) (PendingOp, error) {
+	return ic.store("Add", memd.CmdAdd, storeOptions{
+		Key:                    opts.Key,
+		CollectionName:         opts.CollectionName,
+		ScopeName:              opts.ScopeName,
+		RetryStrategy:          opts.RetryStrategy,
+		Value:                  opts.Value,
+		Flags:                  opts.Flags,
+		Datatype:               opts.Datatype,
+		Expiry:                 opts.Expiry,
+		DurabilityLevel:        opts.DurabilityLevel,
+		DurabilityLevelTimeout: opts.DurabilityLevelTimeout,
+		CollectionID:           opts.CollectionID,
+		Deadline:               opts.Deadline,
+		User:                   opts.User,
+		TraceContext:           opts.TraceContext,
+		UserMetadata:           opts.UserMetadata,
+	}, memd.SubdocDocFlagAddDoc, cb)
+}
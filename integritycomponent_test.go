@@ -0,0 +1,208 @@
+package gocbcore
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"strconv"
+
+	"github.com/couchbase/gocbcore/v10/memd"
+	"github.com/stretchr/testify/mock"
+)
+
+func (suite *UnitTestSuite) TestIntegrityGetResult() {
+	value := []byte(`{"name":"frank"}`)
+	serverCRC := []byte(`"0x1a2b3c4d"`)
+	makeXattr := func(checksumValue []byte, flags uint32, valueCRC string) SubDocResult {
+		xattr, err := json.Marshal(integrityXattr{
+			CRC32C:      formatIntegrityChecksum(checksumValue),
+			Flags:       &flags,
+			ValueCRC32C: valueCRC,
+		})
+		suite.Require().Nil(err, err)
+		return SubDocResult{Value: xattr}
+	}
+	makeResult := func(integrityOp SubDocResult, docFlags string) *LookupInResult {
+		return &LookupInResult{
+			Cas: 1234,
+			Ops: []SubDocResult{
+				integrityOp,
+				{Value: []byte(docFlags)},
+				{Value: []byte(`["json"]`)},
+				{Value: serverCRC},
+				{Value: value},
+			},
+		}
+	}
+
+	res, err := integrityGetResult([]byte("key"), makeResult(makeXattr(value, 33554432, "0x1a2b3c4d"), "0"))
+	suite.Require().Nil(err, err)
+	suite.Assert().Equal(value, res.Value)
+	suite.Assert().Equal(Cas(1234), res.Cas)
+	suite.Assert().Equal(uint8(memd.DatatypeFlagJSON), res.Datatype)
+	// Documents written by the integrity component have no flags, the requested flags are stored in the xattr.
+	suite.Assert().Equal(uint32(33554432), res.Flags)
+
+	// Flags set on the document by a later, non sub-document, write take precedence.
+	res, err = integrityGetResult([]byte("key"), makeResult(makeXattr(value, 33554432, "0x1a2b3c4d"), "42"))
+	suite.Require().Nil(err, err)
+	suite.Assert().Equal(uint32(42), res.Flags)
+
+	// Documents written without checksums should be returned without verification.
+	res, err = integrityGetResult([]byte("key"), makeResult(SubDocResult{Err: errPathNotFound}, "33554432"))
+	suite.Require().Nil(err, err)
+	suite.Assert().Equal(value, res.Value)
+	suite.Assert().Equal(uint32(33554432), res.Flags)
+
+	// Documents whose body was modified without going through the integrity component, such as by an Append, have a
+	// stale checksum and are returned without verification rather than reporting a mismatch.
+	res, err = integrityGetResult([]byte("key"), makeResult(makeXattr([]byte("{}"), 0, "0x00000001"), "0"))
+	suite.Require().Nil(err, err)
+	suite.Assert().Equal(value, res.Value)
+
+	_, err = integrityGetResult([]byte("key"), makeResult(makeXattr([]byte("{}"), 0, "0x1a2b3c4d"), "0"))
+	suite.Require().True(errors.Is(err, ErrChecksumMismatch), err)
+
+	var mismatchErr ChecksumMismatchError
+	suite.Require().True(errors.As(err, &mismatchErr))
+	suite.Assert().Equal("key", mismatchErr.DocumentKey)
+	suite.Assert().Equal(integrityChecksum(value), mismatchErr.Actual)
+	suite.Assert().Equal(integrityChecksum([]byte("{}")), mismatchErr.Expected)
+}
+
+func newTestIntegrityComponent(dispatcher dispatcher, hooks ValueHooks) *integrityComponent {
+	return newIntegrityComponent(true, &crudComponent{
+		cidMgr:               &collectionsComponent{dispatcher: dispatcher},
+		defaultRetryStrategy: newFailFastRetryStrategy(),
+		tracer:               newTracerComponent(&noopTracer{}, "", true, &noopMeter{}, nil),
+		featureVerifier:      &testBucketCapabilityVerifier{},
+		clientProvider: &testReadPreferenceClientProvider{
+			available:   map[int]bool{2: true},
+			numReplicas: 2,
+		},
+		valueHooks: hooks,
+	})
+}
+
+func (suite *UnitTestSuite) TestIntegrityStoreValueHooks() {
+	var req *memdQRequest
+	dispatcher := new(mockDispatcher)
+	dispatcher.On("CollectionsEnabled").Return(false)
+	dispatcher.On("DispatchDirect", mock.AnythingOfType("*gocbcore.memdQRequest")).Return(&memdQRequest{}, nil).
+		Run(func(args mock.Arguments) {
+			req = args[0].(*memdQRequest)
+		})
+
+	hooks := &testSubDocValueHooks{}
+	ic := newTestIntegrityComponent(dispatcher, hooks)
+
+	_, err := ic.Set(SetOptions{
+		Key:      []byte("key"),
+		Value:    []byte(`{"name":"frank"}`),
+		Datatype: uint8(memd.DatatypeFlagJSON),
+	}, func(result *StoreResult, err error) {})
+	suite.Require().Nil(err, err)
+	suite.Require().NotNil(req)
+
+	// The value which is stored, and checksummed, is the value returned by BeforeWrite.
+	stored := []byte(`{"NAME":"FRANK"}`)
+	suite.Assert().Equal(memd.CmdSubDocMultiMutation, req.Command)
+	suite.Assert().Equal([]byte(`{"name":"frank"}`), hooks.writeValue)
+	suite.Assert().True(bytes.Contains(req.Value, stored))
+	suite.Assert().True(bytes.Contains(req.Value, []byte(strconv.Quote(formatIntegrityChecksum(stored)))))
+	// The mutation is made on behalf of the Set so the sub-document hooks are not applied to it.
+	suite.Assert().Empty(hooks.writePaths)
+
+	// Values which are not JSON are stored as normal, but still go through the hooks.
+	req = nil
+	_, err = ic.Set(SetOptions{
+		Key:   []byte("key"),
+		Value: []byte("binary"),
+		Flags: EncodeCommonFlags(BinaryType, NoCompression),
+	}, func(result *StoreResult, err error) {})
+	suite.Require().Nil(err, err)
+	suite.Require().NotNil(req)
+	suite.Assert().Equal(memd.CmdSet, req.Command)
+	suite.Assert().Equal([]byte("BINARY"), req.Value)
+}
+
+func (suite *UnitTestSuite) TestIntegrityGetReadPreferenceAndValueHooks() {
+	var req *memdQRequest
+	dispatcher := new(mockDispatcher)
+	dispatcher.On("CollectionsEnabled").Return(false)
+	dispatcher.On("DispatchDirect", mock.AnythingOfType("*gocbcore.memdQRequest")).Return(&memdQRequest{}, nil).
+		Run(func(args mock.Arguments) {
+			req = args[0].(*memdQRequest)
+		})
+
+	hooks := &testSubDocValueHooks{}
+	ic := newTestIntegrityComponent(dispatcher, hooks)
+
+	var res *GetResult
+	_, err := ic.Get(GetOptions{
+		Key:            []byte("key"),
+		ReadPreference: ReadPreferenceActivePreferred,
+	}, func(result *GetResult, err error) {
+		suite.Require().Nil(err, err)
+		res = result
+	})
+	suite.Require().Nil(err, err)
+	suite.Require().NotNil(req)
+
+	// The active node is unavailable so the lookup is sent to the available replica.
+	suite.Assert().Equal(2, req.ReplicaIdx)
+	suite.Assert().Equal([]byte{uint8(memd.SubdocDocFlagReplicaRead)}, req.Extras)
+
+	// The checksum is of the value as it is stored, which is the value after BeforeWrite has been applied.
+	stored := []byte(`{"NAME":"FRANK"}`)
+	xattr, err := json.Marshal(integrityXattr{
+		CRC32C:      formatIntegrityChecksum(stored),
+		ValueCRC32C: "0x1a2b3c4d",
+	})
+	suite.Require().Nil(err, err)
+
+	var value []byte
+	for _, opValue := range [][]byte{xattr, []byte("33554432"), []byte(`["json"]`), []byte(`"0x1a2b3c4d"`), stored} {
+		value = append(value, 0, 0)
+		value = append(value, make([]byte, 4)...)
+		binary.BigEndian.PutUint32(value[len(value)-4:], uint32(len(opValue)))
+		value = append(value, opValue...)
+	}
+	req.tryCallback(&memdQResponse{Packet: &memd.Packet{Cas: 1234, Value: value}}, nil)
+
+	suite.Require().NotNil(res)
+	suite.Assert().Equal([]byte(`{"name":"frank"}`), res.Value)
+	suite.Assert().Equal(stored, hooks.readValue)
+	suite.Assert().True(res.IsReplica)
+	suite.Assert().Equal(uint32(33554432), res.Flags)
+	// The lookup is made on behalf of the Get so the sub-document hooks are not applied to it.
+	suite.Assert().Empty(hooks.readPaths)
+}
+
+func (suite *UnitTestSuite) TestIntegrityValue() {
+	suite.Assert().True(isIntegrityValue(0, uint8(memd.DatatypeFlagJSON)))
+	suite.Assert().True(isIntegrityValue(EncodeCommonFlags(JSONType, NoCompression), 0))
+	suite.Assert().False(isIntegrityValue(EncodeCommonFlags(BinaryType, NoCompression), 0))
+	suite.Assert().False(isIntegrityValue(0, uint8(memd.DatatypeFlagJSON|memd.DatatypeFlagCompressed)))
+}
+
+func (suite *UnitTestSuite) TestIntegrityStoreOps() {
+	value := []byte(`{"name":"frank"}`)
+	ops := integrityStoreOps(value, 33554432)
+	suite.Require().Len(ops, 4)
+
+	for _, op := range ops {
+		suite.Require().Nil(verifyWholeDocOp(op))
+		suite.Require().Nil(verifyExpandMacrosFlag(op.Flags))
+	}
+
+	suite.Assert().Equal(integrityChecksumXattrPath, ops[0].Path)
+	suite.Assert().Equal(`"`+formatIntegrityChecksum(value)+`"`, string(ops[0].Value))
+	suite.Assert().Equal(integrityStoredFlagsXattrPath, ops[1].Path)
+	suite.Assert().Equal("33554432", string(ops[1].Value))
+	suite.Assert().Equal(integrityValueCRCXattrPath, ops[2].Path)
+	suite.Assert().NotZero(ops[2].Flags & memd.SubdocFlagExpandMacros)
+	suite.Assert().Equal(memd.SubDocOpSetDoc, ops[3].Op)
+	suite.Assert().Equal(value, ops[3].Value)
+}
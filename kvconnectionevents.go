@@ -0,0 +1,237 @@
+package gocbcore
+
+import (
+	"sync"
+	"time"
+)
+
+// KVIOErrorEvent describes a failure to establish a KV connection.
+// Volatile: This API is subject to change at any time.
+type KVIOErrorEvent struct {
+	Endpoint string
+	Error    error
+}
+
+// KVDisconnectEvent describes a previously established KV connection being unexpectedly dropped.
+// Volatile: This API is subject to change at any time.
+type KVDisconnectEvent struct {
+	Endpoint     string
+	ConnectionID string
+	ConnectedFor time.Duration
+	Error        error
+}
+
+// KVReconnectEvent describes a KV connection being established to an endpoint whose previous connection was dropped.
+// Volatile: This API is subject to change at any time.
+type KVReconnectEvent struct {
+	Endpoint     string
+	ConnectionID string
+	// Downtime is how long the endpoint was without a connection, from when the previous connection was dropped.
+	Downtime time.Duration
+}
+
+// KVIOErrorHandler is invoked whenever a KV connection, or reconnection, attempt fails.
+// Volatile: This API is subject to change at any time.
+type KVIOErrorHandler func(KVIOErrorEvent)
+
+// KVDisconnectHandler is invoked whenever an established KV connection is dropped.
+// Volatile: This API is subject to change at any time.
+type KVDisconnectHandler func(KVDisconnectEvent)
+
+// KVReconnectHandler is invoked whenever a KV connection is established to an endpoint whose previous connection was
+// dropped.
+// Volatile: This API is subject to change at any time.
+type KVReconnectHandler func(KVReconnectEvent)
+
+// kvConnectionEventsComponent informs handlers of changes to KV connections. Handlers are invoked in the background,
+// in the order that the events occurred, so that slow handlers never hold up connecting or tearing down connections.
+type kvConnectionEventsComponent struct {
+	lock               sync.Mutex
+	nextID             uint64
+	ioErrorHandlers    map[uint64]KVIOErrorHandler
+	disconnectHandlers map[uint64]KVDisconnectHandler
+	reconnectHandlers  map[uint64]KVReconnectHandler
+	disconnectedAt     map[string]time.Time
+
+	pending     []func()
+	dispatching bool
+}
+
+func newKVConnectionEventsComponent() *kvConnectionEventsComponent {
+	return &kvConnectionEventsComponent{
+		ioErrorHandlers:    make(map[uint64]KVIOErrorHandler),
+		disconnectHandlers: make(map[uint64]KVDisconnectHandler),
+		reconnectHandlers:  make(map[uint64]KVReconnectHandler),
+		disconnectedAt:     make(map[string]time.Time),
+	}
+}
+
+// OnIOError registers a handler for failed connection attempts, the returned function removes the handler.
+func (kce *kvConnectionEventsComponent) OnIOError(handler KVIOErrorHandler) func() {
+	kce.lock.Lock()
+	id := kce.nextID
+	kce.nextID++
+	kce.ioErrorHandlers[id] = handler
+	kce.lock.Unlock()
+
+	return func() {
+		kce.lock.Lock()
+		delete(kce.ioErrorHandlers, id)
+		kce.lock.Unlock()
+	}
+}
+
+// OnDisconnect registers a handler for dropped connections, the returned function removes the handler.
+func (kce *kvConnectionEventsComponent) OnDisconnect(handler KVDisconnectHandler) func() {
+	kce.lock.Lock()
+	id := kce.nextID
+	kce.nextID++
+	kce.disconnectHandlers[id] = handler
+	kce.lock.Unlock()
+
+	return func() {
+		kce.lock.Lock()
+		delete(kce.disconnectHandlers, id)
+		kce.lock.Unlock()
+	}
+}
+
+// OnReconnect registers a handler for connections re-established after being dropped, the returned function removes
+// the handler.
+func (kce *kvConnectionEventsComponent) OnReconnect(handler KVReconnectHandler) func() {
+	kce.lock.Lock()
+	id := kce.nextID
+	kce.nextID++
+	kce.reconnectHandlers[id] = handler
+	kce.lock.Unlock()
+
+	return func() {
+		kce.lock.Lock()
+		delete(kce.reconnectHandlers, id)
+		kce.lock.Unlock()
+	}
+}
+
+// enqueueLocked queues fn to be run in the background after any previously queued events, kce.lock must be held.
+func (kce *kvConnectionEventsComponent) enqueueLocked(fn func()) {
+	kce.pending = append(kce.pending, fn)
+	if !kce.dispatching {
+		kce.dispatching = true
+		go kce.dispatchLoop()
+	}
+}
+
+func (kce *kvConnectionEventsComponent) dispatchLoop() {
+	for {
+		kce.lock.Lock()
+		if len(kce.pending) == 0 {
+			kce.dispatching = false
+			kce.lock.Unlock()
+			return
+		}
+		fn := kce.pending[0]
+		kce.pending[0] = nil
+		kce.pending = kce.pending[1:]
+		kce.lock.Unlock()
+
+		fn()
+	}
+}
+
+func (kce *kvConnectionEventsComponent) onIOError(endpoint string, err error) {
+	if kce == nil {
+		return
+	}
+
+	kce.lock.Lock()
+	handlers := make([]KVIOErrorHandler, 0, len(kce.ioErrorHandlers))
+	for _, handler := range kce.ioErrorHandlers {
+		handlers = append(handlers, handler)
+	}
+	kce.enqueueLocked(func() {
+		for _, handler := range handlers {
+			handler(KVIOErrorEvent{
+				Endpoint: endpoint,
+				Error:    err,
+			})
+		}
+	})
+	kce.lock.Unlock()
+}
+
+func (kce *kvConnectionEventsComponent) onDisconnect(endpoint, connID string, connectedFor time.Duration, err error) {
+	if kce == nil {
+		return
+	}
+
+	kce.lock.Lock()
+	kce.disconnectedAt[endpoint] = time.Now()
+	handlers := make([]KVDisconnectHandler, 0, len(kce.disconnectHandlers))
+	for _, handler := range kce.disconnectHandlers {
+		handlers = append(handlers, handler)
+	}
+	kce.enqueueLocked(func() {
+		for _, handler := range handlers {
+			handler(KVDisconnectEvent{
+				Endpoint:     endpoint,
+				ConnectionID: connID,
+				ConnectedFor: connectedFor,
+				Error:        err,
+			})
+		}
+	})
+	kce.lock.Unlock()
+}
+
+// onConnect informs any handlers of the connection if the previous connection to the endpoint was dropped.
+func (kce *kvConnectionEventsComponent) onConnect(endpoint, connID string) {
+	if kce == nil {
+		return
+	}
+
+	kce.lock.Lock()
+	disconnectedAt, ok := kce.disconnectedAt[endpoint]
+	if !ok {
+		kce.lock.Unlock()
+		return
+	}
+	delete(kce.disconnectedAt, endpoint)
+
+	downtime := time.Since(disconnectedAt)
+	handlers := make([]KVReconnectHandler, 0, len(kce.reconnectHandlers))
+	for _, handler := range kce.reconnectHandlers {
+		handlers = append(handlers, handler)
+	}
+	kce.enqueueLocked(func() {
+		for _, handler := range handlers {
+			handler(KVReconnectEvent{
+				Endpoint:     endpoint,
+				ConnectionID: connID,
+				Downtime:     downtime,
+			})
+		}
+	})
+	kce.lock.Unlock()
+}
+
+// watchClient informs any handlers if the client has re-established a dropped connection, and then waits for the
+// client to close and informs them if the connection was dropped, rather than being closed by us.
+func (kce *kvConnectionEventsComponent) watchClient(endpoint string, client *memdClient) {
+	if kce == nil {
+		return
+	}
+
+	kce.onConnect(endpoint, client.connID)
+
+	connectedAt := time.Now()
+	go func() {
+		<-client.CloseNotify()
+
+		err := client.CloseError()
+		if err == nil {
+			return
+		}
+
+		kce.onDisconnect(endpoint, client.connID, time.Since(connectedAt), err)
+	}()
+}
@@ -0,0 +1,129 @@
+package gocbcore
+
+import (
+	"errors"
+	"io"
+	"time"
+)
+
+func (suite *UnitTestSuite) TestKVConnectionEventsDisconnect() {
+	tracer := newTracerComponent(&noopTracer{}, "", true, &noopMeter{}, nil)
+	events := newKVConnectionEventsComponent()
+
+	eventCh := make(chan KVDisconnectEvent, 2)
+	unsubscribe := events.OnDisconnect(func(event KVDisconnectEvent) {
+		eventCh <- event
+	})
+	defer unsubscribe()
+
+	// A connection which we close ourselves should not be reported.
+	server := newTestMemdServer()
+	client := newTestMemdServerClient(server, nil, tracer)
+	events.watchClient("localhost:11210", client)
+	suite.Require().Nil(client.Close())
+	<-client.CloseNotify()
+
+	suite.Assert().Nil(client.CloseError())
+
+	// A connection which is dropped should be, we record the error that the read loop would see rather than
+	// actually failing the read as that would log a warning.
+	droppedServer := newTestMemdServer()
+	droppedClient := newTestMemdServerClient(droppedServer, nil, tracer)
+	events.watchClient("localhost:11211", droppedClient)
+	droppedClient.lock.Lock()
+	droppedClient.closeErr = io.EOF
+	droppedClient.lock.Unlock()
+	suite.Require().Nil(droppedClient.Close())
+
+	select {
+	case event := <-eventCh:
+		suite.Assert().Equal("localhost:11211", event.Endpoint)
+		suite.Assert().Equal(droppedClient.connID, event.ConnectionID)
+		suite.Assert().True(errors.Is(event.Error, io.EOF), event.Error)
+	case <-time.After(5 * time.Second):
+		suite.T().Fatalf("Timed out waiting for disconnect event")
+	}
+
+	select {
+	case event := <-eventCh:
+		suite.T().Fatalf("Unexpected disconnect event for %s", event.Endpoint)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func (suite *UnitTestSuite) TestKVConnectionEventsIOErrorUnsubscribe() {
+	events := newKVConnectionEventsComponent()
+
+	eventCh := make(chan KVIOErrorEvent, 2)
+	unsubscribe := events.OnIOError(func(event KVIOErrorEvent) {
+		eventCh <- event
+	})
+
+	events.onIOError("localhost:11210", errAuthenticationFailure)
+	select {
+	case event := <-eventCh:
+		suite.Assert().Equal("localhost:11210", event.Endpoint)
+		suite.Assert().Equal(errAuthenticationFailure, event.Error)
+	case <-time.After(5 * time.Second):
+		suite.T().Fatalf("Timed out waiting for io error event")
+	}
+
+	unsubscribe()
+	events.onIOError("localhost:11210", errAuthenticationFailure)
+
+	select {
+	case event := <-eventCh:
+		suite.T().Fatalf("Unexpected io error event for %s", event.Endpoint)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func (suite *UnitTestSuite) TestKVConnectionEventsReconnect() {
+	events := newKVConnectionEventsComponent()
+
+	// Handlers are invoked in the background, so a blocked handler must not hold up the connection events.
+	unblockCh := make(chan struct{})
+	eventCh := make(chan interface{}, 3)
+	defer events.OnDisconnect(func(event KVDisconnectEvent) {
+		<-unblockCh
+		eventCh <- event
+	})()
+	defer events.OnReconnect(func(event KVReconnectEvent) {
+		eventCh <- event
+	})()
+
+	// The first connection to an endpoint is not a reconnection.
+	events.onConnect("localhost:11210", "conn1")
+
+	events.onDisconnect("localhost:11210", "conn1", time.Second, io.EOF)
+	time.Sleep(10 * time.Millisecond)
+	events.onConnect("localhost:11210", "conn2")
+	events.onConnect("localhost:11210", "conn3")
+	close(unblockCh)
+
+	nextEvent := func() interface{} {
+		select {
+		case event := <-eventCh:
+			return event
+		case <-time.After(5 * time.Second):
+			suite.T().Fatalf("Timed out waiting for connection event")
+			return nil
+		}
+	}
+
+	disconnectEvent, ok := nextEvent().(KVDisconnectEvent)
+	suite.Require().True(ok)
+	suite.Assert().Equal("conn1", disconnectEvent.ConnectionID)
+
+	reconnectEvent, ok := nextEvent().(KVReconnectEvent)
+	suite.Require().True(ok)
+	suite.Assert().Equal("localhost:11210", reconnectEvent.Endpoint)
+	suite.Assert().Equal("conn2", reconnectEvent.ConnectionID)
+	suite.Assert().GreaterOrEqual(int64(reconnectEvent.Downtime), int64(10*time.Millisecond))
+
+	select {
+	case event := <-eventCh:
+		suite.T().Fatalf("Unexpected connection event %v", event)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
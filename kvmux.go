@@ -32,6 +32,8 @@ type dispatcher interface {
 
 type clientProvider interface {
 	GetByConnID(connID string) (*memdClient, error)
+	NodeAvailable(key []byte, replicaIdx int) bool
+	NumReplicas() int
 }
 
 type kvMux struct {
@@ -41,6 +43,7 @@ type kvMux struct {
 	collectionsEnabled bool
 	queueSize          int
 	poolSize           int
+	poolSizeForConfig  func(cfg *routeConfig) int
 	cfgMgr             *configManagementComponent
 	errMapMgr          *errMapComponent
 
@@ -61,7 +64,21 @@ type kvMux struct {
 	shutdownSig   chan struct{}
 	clientCloseWg sync.WaitGroup
 
-	noTLSSeedNode bool
+	retryWheel retryTimerWheel
+
+	noTLSSeedNode        bool
+	gracefulCloseTimeout time.Duration
+	maxPoolSize          int
+	poolGrowQueueLatency time.Duration
+	poolIdleTimeout      time.Duration
+
+	blockOnFullQueueMaxWait time.Duration
+
+	nmvPolicy     NotMyVbucketPolicy
+	nmvRetryDelay time.Duration
+
+	// readOnly is accessed atomically, when set mutations are rejected with errReadOnlyMode.
+	readOnly uint32
 
 	hasSeenConfigCh chan struct{}
 }
@@ -70,25 +87,51 @@ type kvMuxProps struct {
 	CollectionsEnabled bool
 	QueueSize          int
 	PoolSize           int
-	NoTLSSeedNode      bool
+	// PoolSizeForConfig, if set, is used to size the pool of connections to each node from the bucket config rather
+	// than using PoolSize.
+	PoolSizeForConfig func(cfg *routeConfig) int
+	NoTLSSeedNode     bool
+	// GracefulCloseTimeout is how long clients being closed are given for in-flight requests to complete, 0 means
+	// wait indefinitely.
+	GracefulCloseTimeout time.Duration
+	// MaxPoolSize enables dynamic sizing of the pool of connections to each node when greater than PoolSize.
+	MaxPoolSize          int
+	PoolGrowQueueLatency time.Duration
+	PoolIdleTimeout      time.Duration
+	// BlockOnFullQueueMaxWait makes dispatching block for up to this duration when the queue for a node is full.
+	BlockOnFullQueueMaxWait time.Duration
+	// ReadOnly makes the mux start in read-only mode.
+	ReadOnly bool
+	// NotMyVbucketPolicy and NotMyVbucketRetryDelay control how requests which fail with NotMyVbucket are retried.
+	NotMyVbucketPolicy     NotMyVbucketPolicy
+	NotMyVbucketRetryDelay time.Duration
 }
 
 func newKVMux(props kvMuxProps, cfgMgr *configManagementComponent, errMapMgr *errMapComponent, tracer *tracerComponent,
 	dialer *memdClientDialerComponent, muxState *kvMuxState) *kvMux {
 	mux := &kvMux{
-		queueSize:          props.QueueSize,
-		poolSize:           props.PoolSize,
-		collectionsEnabled: props.CollectionsEnabled,
-		cfgMgr:             cfgMgr,
-		errMapMgr:          errMapMgr,
-		tracer:             tracer,
-		dialer:             dialer,
-		shutdownSig:        make(chan struct{}),
-		noTLSSeedNode:      props.NoTLSSeedNode,
-		muxPtr:             unsafe.Pointer(muxState),
-		hasSeenConfigCh:    make(chan struct{}),
-		bucketName:         muxState.expectedBucketName,
-	}
+		queueSize:               props.QueueSize,
+		poolSize:                props.PoolSize,
+		poolSizeForConfig:       props.PoolSizeForConfig,
+		collectionsEnabled:      props.CollectionsEnabled,
+		cfgMgr:                  cfgMgr,
+		errMapMgr:               errMapMgr,
+		tracer:                  tracer,
+		dialer:                  dialer,
+		shutdownSig:             make(chan struct{}),
+		noTLSSeedNode:           props.NoTLSSeedNode,
+		gracefulCloseTimeout:    props.GracefulCloseTimeout,
+		maxPoolSize:             props.MaxPoolSize,
+		poolGrowQueueLatency:    props.PoolGrowQueueLatency,
+		poolIdleTimeout:         props.PoolIdleTimeout,
+		blockOnFullQueueMaxWait: props.BlockOnFullQueueMaxWait,
+		nmvPolicy:               props.NotMyVbucketPolicy,
+		nmvRetryDelay:           props.NotMyVbucketRetryDelay,
+		muxPtr:                  unsafe.Pointer(muxState),
+		hasSeenConfigCh:         make(chan struct{}),
+		bucketName:              muxState.expectedBucketName,
+	}
+	mux.SetReadOnly(props.ReadOnly)
 
 	cfgMgr.AddConfigWatcher(mux)
 
@@ -151,7 +194,13 @@ func (mux *kvMux) OnNewRouteConfig(cfg *routeConfig) {
 			logDebugf("Collections disabled as unsupported")
 		}
 
-		close(mux.hasSeenConfigCh)
+		// We can go back to having no config if a cached config is replaced by the seed config, in which case this
+		// has already been closed.
+		select {
+		case <-mux.hasSeenConfigCh:
+		default:
+			close(mux.hasSeenConfigCh)
+		}
 	}
 
 	if !mux.collectionsEnabled {
@@ -167,7 +216,11 @@ func (mux *kvMux) OnNewRouteConfig(cfg *routeConfig) {
 		mux.reconnectPipelines(oldMuxState, newMuxState, true)
 	}
 
+	requeueStart := time.Now()
 	mux.requeueRequests(oldMuxState)
+	if mux.tracer != nil {
+		mux.tracer.ConfigRequeueValueRecord(requeueStart)
+	}
 }
 
 func (mux *kvMux) SetPostCompleteErrorHandler(handler postCompleteErrorHandler) {
@@ -341,22 +394,70 @@ func (mux *kvMux) RouteRequest(req *memdQRequest) (*memdPipeline, error) {
 	return clientMux.GetPipeline(srvIdx), nil
 }
 
+// SetReadOnly enables or disables read-only mode, whilst enabled any mutations are rejected before being dispatched.
+func (mux *kvMux) SetReadOnly(enabled bool) {
+	var val uint32
+	if enabled {
+		val = 1
+	}
+	atomic.StoreUint32(&mux.readOnly, val)
+}
+
+func (mux *kvMux) IsReadOnly() bool {
+	return atomic.LoadUint32(&mux.readOnly) == 1
+}
+
+func (mux *kvMux) checkReadOnly(req *memdQRequest) error {
+	if isMutationCommand(req.Command) && mux.IsReadOnly() {
+		return errReadOnlyMode
+	}
+
+	return nil
+}
+
+// isMutationCommand returns whether the command can modify a document, including its expiry.
+func isMutationCommand(cmd memd.CmdCode) bool {
+	switch cmd {
+	case memd.CmdSet, memd.CmdAdd, memd.CmdReplace, memd.CmdDelete, memd.CmdIncrement, memd.CmdDecrement,
+		memd.CmdAppend, memd.CmdPrepend, memd.CmdTouch, memd.CmdGAT, memd.CmdSetMeta, memd.CmdDelMeta,
+		memd.CmdSubDocDictAdd, memd.CmdSubDocDictSet, memd.CmdSubDocDelete, memd.CmdSubDocReplace,
+		memd.CmdSubDocArrayPushLast, memd.CmdSubDocArrayPushFirst, memd.CmdSubDocArrayInsert,
+		memd.CmdSubDocArrayAddUnique, memd.CmdSubDocCounter, memd.CmdSubDocMultiMutation,
+		memd.CmdSubDocReplaceBodyWithXattr:
+		return true
+	}
+
+	return false
+}
+
 func (mux *kvMux) DispatchDirect(req *memdQRequest) (PendingOp, error) {
+	if err := mux.checkReadOnly(req); err != nil {
+		return nil, err
+	}
+
 	mux.tracer.StartCmdTrace(req)
 	req.dispatchTime = time.Now()
 
+	var queueFullDeadline time.Time
+	if mux.blockOnFullQueueMaxWait > 0 && !req.dispatchDeadline.IsZero() && req.RetryAttempts() == 0 {
+		queueFullDeadline = req.dispatchTime.Add(mux.blockOnFullQueueMaxWait)
+		if req.dispatchDeadline.Before(queueFullDeadline) {
+			queueFullDeadline = req.dispatchDeadline
+		}
+	}
+
 	for {
 		pipeline, err := mux.RouteRequest(req)
 		if err != nil {
 			return nil, err
 		}
 
-		err = pipeline.SendRequest(req)
+		err = pipeline.SendRequestWithWait(req, queueFullDeadline)
 		if err == errPipelineClosed {
 			continue
 		} else if err != nil {
 			if err == errPipelineFull {
-				err = errOverload
+				err = pipeline.overloadError()
 			}
 
 			shortCircuit, routeErr := mux.handleOpRoutingResp(nil, req, err)
@@ -378,6 +479,13 @@ func (mux *kvMux) RequeueDirect(req *memdQRequest, isRetry bool) {
 }
 
 func (mux *kvMux) requeueDirect(pipeline *memdPipeline, req *memdQRequest, isRetry bool) {
+	// Read-only mode may have been enabled whilst the request was waiting to be retried.
+	if err := mux.checkReadOnly(req); err != nil {
+		logDebugf("Rejecting requeued mutation in read-only mode, Opaque=%d, Opcode=0x%x", req.Opaque, req.Command)
+		req.tryCallback(nil, err)
+		return
+	}
+
 	mux.tracer.StartCmdTrace(req)
 
 	handleError := func(err error) {
@@ -391,7 +499,27 @@ func (mux *kvMux) requeueDirect(pipeline *memdPipeline, req *memdQRequest, isRet
 
 	logDebugf("Request being requeued, Opaque=%d, Opcode=0x%x", req.Opaque, req.Command)
 
+	if req.pinnedConnID != "" {
+		client, err := mux.GetByConnID(req.pinnedConnID)
+		if err != nil {
+			// It's expected for a pinned connection to go away, any state associated with the request has gone with it.
+			logDebugf("Connection for pinned request has gone, failing request, Opaque=%d, Opcode=0x%x, ConnID=%s",
+				req.Opaque, req.Command, req.pinnedConnID)
+			req.tryCallback(nil, err)
+			return
+		}
+
+		if err := client.SendRequest(req); err != nil {
+			handleError(err)
+		}
+		return
+	}
+
 	if pipeline == nil {
+		if req.selectReplicaFn != nil {
+			req.selectReplicaFn(req)
+		}
+
 		var err error
 		pipeline, err = mux.RouteRequest(req)
 		if err != nil {
@@ -429,7 +557,7 @@ func (mux *kvMux) GetByConnID(connID string) (*memdClient, error) {
 		p.clientsLock.Lock()
 		for _, pipeCli := range p.clients {
 			pipeCli.lock.Lock()
-			if pipeCli.client.connID == connID {
+			if pipeCli.client != nil && pipeCli.client.connID == connID {
 				pipeCli.lock.Unlock()
 				p.clientsLock.Unlock()
 				return pipeCli.client, nil
@@ -443,7 +571,32 @@ func (mux *kvMux) GetByConnID(connID string) (*memdClient, error) {
 
 }
 
+// NodeAvailable returns whether the node which owns the given key at the given replica index currently has a
+// connection which is able to accept requests.
+func (mux *kvMux) NodeAvailable(key []byte, replicaIdx int) bool {
+	clientMux := mux.getState()
+	if clientMux == nil || clientMux.RevID() == -1 {
+		return false
+	}
+
+	if clientMux.BucketType() != bktTypeCouchbase || clientMux.VBMap() == nil {
+		return false
+	}
+
+	vbID := clientMux.VBMap().VbucketByKey(key)
+	srvIdx, err := clientMux.VBMap().NodeByVbucket(vbID, uint32(replicaIdx))
+	if err != nil || srvIdx < 0 {
+		return false
+	}
+
+	return clientMux.GetPipeline(srvIdx).IsAvailable()
+}
+
 func (mux *kvMux) DispatchDirectToAddress(req *memdQRequest, address string) (PendingOp, error) {
+	if err := mux.checkReadOnly(req); err != nil {
+		return nil, err
+	}
+
 	mux.tracer.StartCmdTrace(req)
 	req.dispatchTime = time.Now()
 
@@ -477,7 +630,7 @@ func (mux *kvMux) DispatchDirectToAddress(req *memdQRequest, address string) (Pe
 			continue
 		} else if err != nil {
 			if err == errPipelineFull {
-				err = errOverload
+				err = pipeline.overloadError()
 			}
 
 			shortCircuit, routeErr := mux.handleOpRoutingResp(nil, req, err)
@@ -504,6 +657,11 @@ func (mux *kvMux) Close() error {
 		return errShutdown
 	}
 
+	// Fail any requests which are waiting to be retried, they would otherwise be requeued to a closed mux.
+	for _, req := range mux.retryWheel.Close() {
+		req.tryCallback(nil, errShutdown)
+	}
+
 	// Trigger any memdclients that are in graceful close to forcibly close.
 	close(mux.shutdownSig)
 
@@ -546,6 +704,12 @@ func (mux *kvMux) ForceReconnect(tlsConfig *dynTLSConfig, authMechanisms []AuthM
 	logDebugf("Forcing reconnect of all connections")
 	mux.muxStateWriteLock.Lock()
 	muxState := mux.getState()
+	if muxState == nil {
+		// The mux has been closed, there's nothing to reconnect and we mustn't resurrect the state.
+		mux.muxStateWriteLock.Unlock()
+		logDebugf("Ignoring forced reconnect whilst shutting down kvmux")
+		return
+	}
 	newMuxState := mux.newKVMuxState(muxState.RouteConfig(), tlsConfig, authMechanisms, auth)
 
 	atomic.SwapPointer(&mux.muxPtr, unsafe.Pointer(newMuxState))
@@ -554,6 +718,27 @@ func (mux *kvMux) ForceReconnect(tlsConfig *dynTLSConfig, authMechanisms []AuthM
 	mux.muxStateWriteLock.Unlock()
 }
 
+// UpdateCredentials applies new security settings without reconnecting, existing connections are kept and only
+// newly established connections use the new settings.
+func (mux *kvMux) UpdateCredentials(tlsConfig *dynTLSConfig, authMechanisms []AuthMechanism, auth AuthProvider) {
+	mux.muxStateWriteLock.Lock()
+	defer mux.muxStateWriteLock.Unlock()
+	oldMuxState := mux.getState()
+	if oldMuxState == nil {
+		logDebugf("Ignoring credentials update whilst shutting down kvmux")
+		return
+	}
+	newMuxState := mux.newKVMuxState(oldMuxState.RouteConfig(), tlsConfig, authMechanisms, auth)
+
+	if !mux.updateState(oldMuxState, newMuxState) {
+		logWarnf("Someone preempted the credentials update, skipping update")
+		return
+	}
+
+	mux.pipelineTakeover(oldMuxState, newMuxState)
+	mux.requeueRequests(oldMuxState)
+}
+
 func (mux *kvMux) PipelineSnapshot() (*pipelineSnapshot, error) {
 	clientMux := mux.getState()
 	if clientMux == nil {
@@ -565,12 +750,83 @@ func (mux *kvMux) PipelineSnapshot() (*pipelineSnapshot, error) {
 	}, nil
 }
 
+// CircuitBreakerStates returns the state of the circuit breaker for every connected KV client.
+func (mux *kvMux) CircuitBreakerStates() ([]CircuitBreakerInfo, error) {
+	iter, err := mux.PipelineSnapshot()
+	if err != nil {
+		return nil, err
+	}
+
+	var states []CircuitBreakerInfo
+	iter.Iterate(0, func(pipeline *memdPipeline) bool {
+		pipeline.clientsLock.Lock()
+		for _, pipecli := range pipeline.clients {
+			pipecli.lock.Lock()
+			if pipecli.client != nil {
+				states = append(states, newCircuitBreakerInfo(pipecli.client.breaker, MemdService, pipeline.Address()))
+			}
+			pipecli.lock.Unlock()
+		}
+		pipeline.clientsLock.Unlock()
+		return false
+	})
+
+	return states, nil
+}
+
+// ReauthenticateClients re-authenticates every KV client which authenticated using a bearer token, this is used once
+// the token has been refreshed. Clients which fail to re-authenticate are closed so that the pipeline reconnects them.
+func (mux *kvMux) ReauthenticateClients() {
+	clientMux := mux.getState()
+	if clientMux == nil {
+		return
+	}
+
+	auth, ok := clientMux.auth.(tokenAuthProvider)
+	if !ok {
+		return
+	}
+
+	var clients []*memdClient
+	for _, pipeline := range clientMux.pipelines {
+		pipeline.clientsLock.Lock()
+		for _, pipecli := range pipeline.clients {
+			pipecli.lock.Lock()
+			if pipecli.client != nil && pipecli.client.AuthMechanism() == OAuthBearerAuthMechanism {
+				clients = append(clients, pipecli.client)
+			}
+			pipecli.lock.Unlock()
+		}
+		pipeline.clientsLock.Unlock()
+	}
+
+	for _, client := range clients {
+		if err := mux.dialer.Reauthenticate(client, auth); err != nil {
+			logWarnf("Failed to re-authenticate memdClient %s/%p, closing: %v", client.Address(), client, err)
+			if closeErr := client.Close(); closeErr != nil {
+				logDebugf("Failed to close memdClient %s/%p: %v", client.Address(), client, closeErr)
+			}
+		}
+	}
+}
+
 type waitForConfigSnapshotOp struct {
-	cancelCh chan struct{}
+	cancelCh  chan struct{}
+	completed uint32
 }
 
-func (w *waitForConfigSnapshotOp) Cancel() {
+func (w *waitForConfigSnapshotOp) Cancel() bool {
+	if !atomic.CompareAndSwapUint32(&w.completed, 0, 1) {
+		return false
+	}
+
 	close(w.cancelCh)
+	return true
+}
+
+// complete marks the op as completed, returning false if it has already been cancelled.
+func (w *waitForConfigSnapshotOp) complete() bool {
+	return atomic.CompareAndSwapUint32(&w.completed, 0, 1)
 }
 
 func (mux *kvMux) WaitForConfigSnapshot(deadline time.Time, cb WaitForConfigSnapshotCallback) (PendingOp, error) {
@@ -591,31 +847,42 @@ func (mux *kvMux) WaitForConfigSnapshot(deadline time.Time, cb WaitForConfigSnap
 
 	start := time.Now()
 	go func() {
+		var res *WaitForConfigSnapshotResult
+		var err error
 		select {
 		case <-mux.shutdownSig:
-			cb(nil, errShutdown)
+			err = errShutdown
 		case <-op.cancelCh:
 			cb(nil, errRequestCanceled)
+			return
 		case <-deadlineCh:
-			cb(nil, &TimeoutError{
+			err = &TimeoutError{
 				InnerError:   errUnambiguousTimeout,
 				OperationID:  "WaitForConfigSnapshot",
 				TimeObserved: time.Since(start),
-			})
+			}
 		case <-mux.hasSeenConfigCh:
 			// Just in case.
 			clientMux := mux.getState()
 			if clientMux == nil {
-				cb(nil, errShutdown)
-				return
+				err = errShutdown
+				break
 			}
 
-			cb(&WaitForConfigSnapshotResult{
+			res = &WaitForConfigSnapshotResult{
 				Snapshot: &ConfigSnapshot{
 					state: clientMux,
 				},
-			}, nil)
+			}
+		}
+
+		if !op.complete() {
+			// Cancel won the race, it will have closed the cancel channel.
+			cb(nil, errRequestCanceled)
+			return
 		}
+
+		cb(res, err)
 	}()
 
 	return op, nil
@@ -645,6 +912,14 @@ func (mux *kvMux) handleOpRoutingResp(resp *memdQResponse, req *memdQRequest, or
 
 	err := translateMemdError(originalErr, req)
 
+	// Make any retry delay from the error map available to the retry strategy, this is cleared when the error map
+	// doesn't have one so that a stale delay isn't used for a different failure.
+	var serverRetryAfter time.Duration
+	if resp != nil && resp.Magic == memd.CmdMagicRes {
+		serverRetryAfter = mux.errMapMgr.RetryAfter(resp.Status, req.RetryAttempts())
+	}
+	req.setServerRetryAfter(serverRetryAfter)
+
 	if err == originalErr {
 		if errors.Is(err, io.EOF) && !mux.closed() {
 			// The connection has gone away.
@@ -674,6 +949,17 @@ func (mux *kvMux) handleOpRoutingResp(resp *memdQResponse, req *memdQRequest, or
 			if mux.waitAndRetryOperation(req, SocketNotAvailableRetryReason) {
 				return true, nil
 			}
+		} else if errors.Is(err, ErrRateLimitedLocally) {
+			// Let the retry strategy know how long until the request is expected to fit within the limits.
+			var rateLimitErr *kvRateLimitedError
+			if errors.As(err, &rateLimitErr) {
+				req.setServerRetryAfter(rateLimitErr.retryAfter)
+			}
+
+			// The request was never written so it is always safe to retry.
+			if mux.waitAndRetryOperation(req, ClientRateLimitedRetryReason) {
+				return true, nil
+			}
 		} else if errors.Is(err, io.ErrShortWrite) {
 			// This is a special case where the write has failed on the underlying connection and not all the bytes
 			// were written to the network.
@@ -687,7 +973,7 @@ func (mux *kvMux) handleOpRoutingResp(resp *memdQResponse, req *memdQRequest, or
 			}
 		} else if resp != nil && resp.Magic == memd.CmdMagicRes {
 			// We don't know anything about this error so send it to the error map
-			shouldRetry := mux.errMapMgr.ShouldRetry(resp.Status)
+			shouldRetry := mux.errMapMgr.ShouldRetry(resp.Status, req.RetryAttempts())
 			if shouldRetry {
 				if mux.waitAndRetryOperation(req, KVErrMapRetryReason) {
 					return true, nil
@@ -716,6 +1002,12 @@ func (mux *kvMux) handleOpRoutingResp(resp *memdQResponse, req *memdQRequest, or
 			if mux.waitAndRetryOperation(req, KVSyncWriteRecommitInProgressRetryReason) {
 				return true, nil
 			}
+		} else if errors.Is(err, ErrRateLimitedFailure) {
+			// The server rejected the request without applying it so it is always safe to retry, quota limits are
+			// not retried as they will not clear by waiting.
+			if mux.waitAndRetryOperation(req, KVRateLimitedRetryReason) {
+				return true, nil
+			}
 		}
 		// If an error isn't in this list then we know what this error is but we don't support retries for it.
 	}
@@ -733,13 +1025,18 @@ func (mux *kvMux) closed() bool {
 	return mux.getState() == nil
 }
 
+func (mux *kvMux) requeueRetry(req *memdQRequest) {
+	mux.RequeueDirect(req, true)
+}
+
 func (mux *kvMux) waitAndRetryOperation(req *memdQRequest, reason RetryReason) bool {
 	shouldRetry, retryTime := retryOrchMaybeRetry(req, reason)
 	if shouldRetry {
-		go func() {
-			time.Sleep(time.Until(retryTime))
-			mux.RequeueDirect(req, true)
-		}()
+		if !mux.retryWheel.Schedule(req, retryTime, mux.requeueRetry) {
+			// The mux has been shut down.
+			return false
+		}
+		mux.tracer.RetryCountRecord(metricValueServiceKeyValue, req.Command.Name())
 		return true
 	}
 
@@ -757,6 +1054,24 @@ func (mux *kvMux) parseNotMyVbucketValue(value []byte, sourceAddr string) *cfgBu
 	logDebugf("Got NMV Block: %v", string(value))
 	bk, err := parseConfig(value, sourceHost)
 	if err != nil {
+		logDebugf("Failed to parse config from NMV response: %v", err)
+		return nil
+	}
+
+	return bk
+}
+
+func (mux *kvMux) parseClusterMapNotificationValue(value []byte, sourceAddr string) *cfgBucket {
+	sourceHost, err := hostFromHostPort(sourceAddr)
+	if err != nil {
+		logErrorf("Cluster map notification source address was invalid, skipping config update")
+		return nil
+	}
+
+	logDebugf("Got cluster map notification Block: %v", string(value))
+	bk, err := parseConfig(value, sourceHost)
+	if err != nil {
+		logWarnf("Failed to parse cluster map notification config. %v", err)
 		return nil
 	}
 
@@ -768,6 +1083,7 @@ func (mux *kvMux) handleNotMyVbucket(resp *memdQResponse, req *memdQRequest) boo
 	isRetryableReq := req.Command != memd.CmdRangeScanContinue
 
 	logSchedf("Received NMV for request. OP=0x%x. Opaque=%d. Vbid: %d", req.Command, req.Opaque, req.Vbucket)
+	mux.tracer.NotMyVbucketCountRecord(resp.sourceAddr)
 
 	if len(resp.Value) == 0 {
 		logDebugf("NMV response containing no new config")
@@ -781,20 +1097,27 @@ func (mux *kvMux) handleNotMyVbucket(resp *memdQResponse, req *memdQRequest) boo
 				return false
 			}
 		} else {
-			// We need to push this upstream which will then internal update the state with a new config.
-			mux.cfgMgr.OnNewConfig(bk)
+			// We need to push this upstream which will then internal update the state with a new config. Whilst a
+			// rebalance is in progress many requests can receive the same config so only apply it if it's newer, this
+			// avoids building a route config for every one of them.
+			if mux.cfgMgr.IsNewerConfig(bk) {
+				mux.cfgMgr.OnNewConfig(bk)
+			}
 
 			if !isRetryableReq {
 				return false
 			}
 
-			originalVBID := req.Vbucket
-			pipeline, err := mux.RouteRequest(req)
-			if err == nil {
-				// If the address or vbucket has changed then just redispatch directly.
-				if pipeline.Address() != resp.sourceAddr || originalVBID != req.Vbucket {
-					mux.requeueDirect(pipeline, req, true)
-					return true
+			if mux.nmvPolicy == NotMyVbucketPolicyDefault || mux.nmvPolicy == NotMyVbucketPolicyImmediate {
+				originalVBID := req.Vbucket
+				pipeline, err := mux.RouteRequest(req)
+				if err == nil {
+					// If the address or vbucket has changed, or we always redispatch, then just redispatch directly.
+					if mux.nmvPolicy == NotMyVbucketPolicyImmediate || pipeline.Address() != resp.sourceAddr ||
+						originalVBID != req.Vbucket {
+						mux.requeueDirect(pipeline, req, true)
+						return true
+					}
 				}
 			}
 		}
@@ -802,7 +1125,7 @@ func (mux *kvMux) handleNotMyVbucket(resp *memdQResponse, req *memdQRequest) boo
 
 	// Redirect it!  This may actually come back to this server, but I won't tell
 	//   if you don't ;)
-	return mux.waitAndRetryOperation(req, KVNotMyVBucketRetryReason)
+	return mux.waitAndRetryNotMyVbucket(req)
 }
 
 func (mux *kvMux) handleConfigOnly(resp *memdQResponse, req *memdQRequest) bool {
@@ -842,8 +1165,20 @@ func (mux *kvMux) drainPipelines(clientMux *kvMuxState, cb func(req *memdQReques
 func (mux *kvMux) newKVMuxState(cfg *routeConfig, tlsConfig *dynTLSConfig, authMechanisms []AuthMechanism,
 	auth AuthProvider) *kvMuxState {
 	poolSize := 1
+	var sizing poolSizingProps
 	if !cfg.IsGCCCPConfig() {
 		poolSize = mux.poolSize
+		if mux.poolSizeForConfig != nil {
+			poolSize = mux.poolSizeForConfig(cfg)
+		}
+		sizing = poolSizingProps{
+			MaxClients:       mux.maxPoolSize,
+			GrowQueueLatency: mux.poolGrowQueueLatency,
+			IdleTimeout:      mux.poolIdleTimeout,
+			CloseClientFn: func(client *memdClient) {
+				mux.closeMemdClient(client, nil)
+			},
+		}
 	}
 
 	useTls := tlsConfig != nil
@@ -893,7 +1228,7 @@ func (mux *kvMux) newKVMuxState(cfg *routeConfig, tlsConfig *dynTLSConfig, authM
 			return mux.dialer.SlowDialMemdClient(cancelSig, trimmedHostPort, tlsConfig, auth, authMechanisms,
 				mux.handleOpRoutingResp, mux.handleServerRequest)
 		}
-		pipeline := newPipeline(trimmedHostPort, poolSize, mux.queueSize, getCurClientFn)
+		pipeline := newPipeline(trimmedHostPort, poolSize, mux.queueSize, sizing, getCurClientFn)
 
 		pipelines[i] = pipeline
 	}
@@ -972,23 +1307,38 @@ func (mux *kvMux) closeMemdClient(client *memdClient, err error) {
 	mux.clientCloseWg.Add(1)
 	client.GracefulClose(err)
 	go func(client *memdClient) {
+		var graceTimeoutCh <-chan time.Time
+		if mux.gracefulCloseTimeout > 0 {
+			graceTimer := time.NewTimer(mux.gracefulCloseTimeout)
+			defer graceTimer.Stop()
+			graceTimeoutCh = graceTimer.C
+		}
+
 		select {
 		case <-client.CloseNotify():
 			logDebugf("Memdclient %s/%p completed graceful shutdown", client.Address(), client)
+		case <-graceTimeoutCh:
+			logDebugf("Memdclient %s/%p did not complete graceful shutdown within %s, forcibly shutting down",
+				client.Address(), client, mux.gracefulCloseTimeout)
+			mux.forceCloseMemdClient(client)
 		case <-mux.shutdownSig:
 			logDebugf("Memdclient %s/%p being forcibly shutdown", client.Address(), client)
-			// Force the client to close even if there are requests in flight.
-			err := client.Close()
-			if err != nil {
-				logErrorf("failed to shutdown memdclient: %s", err)
-			}
-			<-client.CloseNotify()
-			logDebugf("Memdclient %s/%p completed shutdown", client.Address(), client)
+			mux.forceCloseMemdClient(client)
 		}
 		mux.clientCloseWg.Done()
 	}(client)
 }
 
+func (mux *kvMux) forceCloseMemdClient(client *memdClient) {
+	// Force the client to close even if there are requests in flight.
+	err := client.Close()
+	if err != nil {
+		logErrorf("failed to shutdown memdclient: %s", err)
+	}
+	<-client.CloseNotify()
+	logDebugf("Memdclient %s/%p completed shutdown", client.Address(), client)
+}
+
 func (mux *kvMux) stealPipeline(address string, oldPipelines *list.List) *memdPipeline {
 	for e := oldPipelines.Front(); e != nil; e = e.Next() {
 		pipeline, ok := e.Value.(*memdPipeline)
@@ -1048,7 +1398,34 @@ func (mux *kvMux) pipelineTakeover(oldMux, newMux *kvMuxState) {
 	}
 }
 
-func (mux *kvMux) handleServerRequest(pak *memd.Packet) {
+func (mux *kvMux) handleServerRequest(resp *memdQResponse) {
+	pak := resp.Packet
+	if pak.Command == memd.CmdSet && len(pak.Value) > 0 {
+		// This is a full cluster map change notification, the new config is in the value so we can apply it
+		// immediately rather than having to fetch it. The key is the name of the bucket that the config belongs to,
+		// or empty for the cluster config, so we only apply configs for the bucket that we are connected to.
+		if string(pak.Key) != mux.bucketName {
+			logDebugf("Ignoring cluster map change notification for %s, expected %s", redactMetaData(string(pak.Key)),
+				redactMetaData(mux.bucketName))
+			return
+		}
+
+		// We copy out the value before handling it in its own goroutine for the same reason as for brief
+		// notifications below.
+		value := make([]byte, len(pak.Value))
+		copy(value, pak.Value)
+		sourceAddr := resp.sourceAddr
+		go func() {
+			bk := mux.parseClusterMapNotificationValue(value, sourceAddr)
+			if bk == nil {
+				return
+			}
+
+			mux.cfgMgr.OnNewConfig(bk)
+		}()
+		return
+	}
+
 	if pak.Command == memd.CmdSet {
 		// We copy out the extras before handling the packet in its own goroutine.
 		// If we don't do this then the memdclient is going to free the packet and by the
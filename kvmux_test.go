@@ -1,5 +1,11 @@
 package gocbcore
 
+import (
+	"time"
+
+	"github.com/couchbase/gocbcore/v10/memd"
+)
+
 func (suite *StandardTestSuite) TestKvMux_HasBucketCapabilityStatusNoState() {
 	// No mux state, shouldn't actually happen in practise.
 	mux := kvMux{}
@@ -73,3 +79,346 @@ func (suite *StandardTestSuite) TestKvMux_HasBucketCapabilityStatusSupported() {
 	suite.Assert().False(mux.HasBucketCapabilityStatus(9999, CapabilityStatusSupported))
 	suite.Assert().True(mux.HasBucketCapabilityStatus(9999, CapabilityStatusUnsupported))
 }
+
+type testRouteConfigWatcher struct {
+	cfgCh chan *routeConfig
+}
+
+func (w *testRouteConfigWatcher) OnNewRouteConfig(cfg *routeConfig) {
+	w.cfgCh <- cfg
+}
+
+func (suite *UnitTestSuite) TestKvMuxHandleServerRequestFullClusterMapNotification() {
+	cfgBytes, err := suite.LoadRawTestDataset("bucket_config_with_rev_epoch")
+	suite.Require().Nil(err, err)
+
+	cfgMgr := newConfigManager(configManagerProperties{
+		SrcMemdAddrs: []routeEndpoint{{Address: "10.112.210.101:11210"}},
+	})
+	watcher := &testRouteConfigWatcher{cfgCh: make(chan *routeConfig, 1)}
+	cfgMgr.AddConfigWatcher(watcher)
+
+	mux := &kvMux{cfgMgr: cfgMgr, bucketName: "travel-sample"}
+	sendNotification := func(bucketName string) {
+		mux.handleServerRequest(&memdQResponse{
+			Packet: &memd.Packet{
+				Magic:   memd.CmdMagicServerReq,
+				Command: memd.CmdSet,
+				Key:     []byte(bucketName),
+				Value:   cfgBytes,
+			},
+			sourceAddr: "10.112.210.101:11210",
+		})
+	}
+
+	// Notifications for other buckets, or the cluster config, are not applied.
+	sendNotification("default")
+	sendNotification("")
+	select {
+	case cfg := <-watcher.cfgCh:
+		suite.T().Fatalf("Config for a different bucket should not have been applied: %v", cfg)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	sendNotification("travel-sample")
+	select {
+	case cfg := <-watcher.cfgCh:
+		suite.Assert().Equal(int64(2), cfg.revID)
+		suite.Assert().Equal(int64(2), cfg.revEpoch)
+		suite.Require().NotEmpty(cfg.kvServerList.NonSSLEndpoints)
+		suite.Assert().Equal("couchbase://10.112.210.101:11210", cfg.kvServerList.NonSSLEndpoints[0].Address)
+	case <-time.After(5 * time.Second):
+		suite.T().Fatalf("Timed out waiting for config to be applied")
+	}
+}
+
+func (suite *UnitTestSuite) TestKvMuxCloseMemdClientGracefulCloseTimeout() {
+	tracer := newTracerComponent(&noopTracer{}, "", true, &noopMeter{}, nil)
+
+	server := newTestMemdServer()
+	// Hold back more responses than are ever sent so that the request remains in flight.
+	server.HoldResponses(2)
+
+	client := newTestMemdServerClient(server, nil, tracer)
+
+	errCh := make(chan error, 1)
+	err := client.SendRequest(&memdQRequest{
+		Packet: memd.Packet{
+			Magic:   memd.CmdMagicReq,
+			Command: memd.CmdGet,
+			Key:     []byte("key"),
+		},
+		Callback: func(resp *memdQResponse, req *memdQRequest, err error) {
+			errCh <- err
+		},
+	})
+	suite.Require().Nil(err, err)
+
+	mux := &kvMux{
+		shutdownSig:          make(chan struct{}),
+		gracefulCloseTimeout: 50 * time.Millisecond,
+	}
+	mux.closeMemdClient(client, nil)
+
+	select {
+	case <-client.CloseNotify():
+	case <-time.After(5 * time.Second):
+		suite.T().Fatalf("Client was not closed after the graceful close timeout")
+	}
+
+	select {
+	case err := <-errCh:
+		suite.Assert().NotNil(err)
+	case <-time.After(5 * time.Second):
+		suite.T().Fatalf("In-flight request was not failed when the client was closed")
+	}
+
+	mux.clientCloseWg.Wait()
+}
+
+func (suite *UnitTestSuite) TestKvMuxReadOnlyMode() {
+	mux := kvMux{}
+	mux.SetReadOnly(true)
+	suite.Assert().True(mux.IsReadOnly())
+
+	_, err := mux.DispatchDirect(&memdQRequest{
+		Packet: memd.Packet{
+			Command: memd.CmdSubDocMultiMutation,
+		},
+	})
+	suite.Assert().ErrorIs(err, ErrReadOnlyMode)
+
+	_, err = mux.DispatchDirectToAddress(&memdQRequest{
+		Packet: memd.Packet{
+			Command: memd.CmdDelete,
+		},
+	}, "127.0.0.1:11210")
+	suite.Assert().ErrorIs(err, ErrReadOnlyMode)
+
+	// Mutations waiting to be retried are failed rather than requeued.
+	respCh := make(chan error, 1)
+	mux.RequeueDirect(&memdQRequest{
+		Packet: memd.Packet{
+			Command: memd.CmdSet,
+		},
+		Callback: func(resp *memdQResponse, req *memdQRequest, err error) {
+			respCh <- err
+		},
+	}, true)
+	suite.Assert().ErrorIs(<-respCh, ErrReadOnlyMode)
+
+	suite.Assert().Nil(mux.checkReadOnly(&memdQRequest{
+		Packet: memd.Packet{
+			Command: memd.CmdGet,
+		},
+	}))
+	suite.Assert().Nil(mux.checkReadOnly(&memdQRequest{
+		Packet: memd.Packet{
+			Command: memd.CmdSubDocMultiLookup,
+		},
+	}))
+
+	mux.SetReadOnly(false)
+	suite.Assert().False(mux.IsReadOnly())
+	suite.Assert().Nil(mux.checkReadOnly(&memdQRequest{
+		Packet: memd.Packet{
+			Command: memd.CmdSet,
+		},
+	}))
+}
+
+// newTestSnapshotMux returns a kvMux which has seen a config using vbEntries as its vbucket map, so that it can be
+// used to provide config snapshots.
+func newTestSnapshotMux(vbEntries [][]int, numReplicas int) *kvMux {
+	mux := &kvMux{
+		shutdownSig:     make(chan struct{}),
+		hasSeenConfigCh: make(chan struct{}),
+	}
+	mux.updateState(nil, &kvMuxState{
+		routeCfg: routeConfig{
+			bktType: bktTypeCouchbase,
+			vbMap:   newVbucketMap(vbEntries, numReplicas),
+		},
+	})
+	close(mux.hasSeenConfigCh)
+
+	return mux
+}
+
+func (suite *UnitTestSuite) TestKvMuxWaitForConfigSnapshotCancel() {
+	mux := &kvMux{
+		shutdownSig:     make(chan struct{}),
+		hasSeenConfigCh: make(chan struct{}),
+	}
+	mux.updateState(nil, &kvMuxState{})
+
+	errCh := make(chan error, 1)
+	op, err := mux.WaitForConfigSnapshot(time.Time{}, func(result *WaitForConfigSnapshotResult, err error) {
+		errCh <- err
+	})
+	suite.Require().Nil(err, err)
+
+	suite.Assert().True(op.Cancel())
+	suite.Assert().False(op.Cancel())
+	suite.Assert().ErrorIs(<-errCh, ErrRequestCanceled)
+}
+
+func (suite *UnitTestSuite) TestKvMuxUpdateCredentials() {
+	mux := &kvMux{}
+	oldAuth := PasswordAuthProvider{Username: "user", Password: "old"}
+	oldState := mux.newKVMuxState(&routeConfig{revID: -1}, nil, []AuthMechanism{PlainAuthMechanism}, oldAuth)
+	mux.updateState(nil, oldState)
+
+	newAuth := PasswordAuthProvider{Username: "user", Password: "new"}
+	mux.UpdateCredentials(nil, []AuthMechanism{ScramSha512AuthMechanism}, newAuth)
+
+	newState := mux.getState()
+	suite.Require().NotNil(newState)
+	suite.Assert().NotSame(oldState, newState)
+	suite.Assert().Equal(newAuth, newState.auth)
+	suite.Assert().Equal([]AuthMechanism{ScramSha512AuthMechanism}, newState.authMechanisms)
+	suite.Assert().Equal(oldState.RevID(), newState.RevID())
+
+	// Credentials updates after shutdown are ignored.
+	mux.clear()
+	mux.UpdateCredentials(nil, nil, oldAuth)
+	suite.Assert().Nil(mux.getState())
+}
+
+func (suite *UnitTestSuite) TestKvMuxRequeuePinnedRequest() {
+	mux := &kvMux{
+		tracer: newTracerComponent(&noopTracer{}, "", true, &noopMeter{}, nil),
+	}
+	pipeline := newPipeline(routeEndpoint{Address: "127.0.0.1:11210"}, 1, 10, poolSizingProps{}, nil)
+	mux.updateState(nil, &kvMuxState{
+		pipelines: []*memdPipeline{pipeline},
+		deadPipe:  newDeadPipeline(10),
+	})
+
+	errCh := make(chan error, 1)
+	req := &memdQRequest{
+		Packet: memd.Packet{
+			Command: memd.CmdDcpStreamReq,
+		},
+		Callback: func(resp *memdQResponse, req *memdQRequest, err error) {
+			errCh <- err
+		},
+		Persistent:   true,
+		pinnedConnID: "conn1",
+	}
+
+	// The connection that the request is pinned to has gone so the request must fail rather than be sent elsewhere.
+	mux.RequeueDirect(req, true)
+	suite.Assert().ErrorIs(<-errCh, errConnectionIDInvalid)
+	suite.Assert().Equal(0, pipeline.queue.Len())
+}
+
+func (suite *UnitTestSuite) TestKvMuxRequeueSelectsReplica() {
+	mux := &kvMux{
+		tracer: newTracerComponent(&noopTracer{}, "", true, &noopMeter{}, nil),
+	}
+	deadPipe := newDeadPipeline(10)
+	mux.updateState(nil, &kvMuxState{
+		routeCfg: routeConfig{revID: -1},
+		deadPipe: deadPipe,
+	})
+
+	var selections int
+	req := &memdQRequest{
+		Packet: memd.Packet{
+			Command: memd.CmdGet,
+		},
+		Callback: func(resp *memdQResponse, req *memdQRequest, err error) {},
+		selectReplicaFn: func(req *memdQRequest) {
+			selections++
+			req.ReplicaIdx = 1
+		},
+	}
+
+	mux.RequeueDirect(req, true)
+	suite.Assert().Equal(1, selections)
+	suite.Assert().Equal(1, req.ReplicaIdx)
+	suite.Assert().Equal(1, deadPipe.queue.Len())
+}
+
+func (suite *UnitTestSuite) TestKvMuxRetriesServerRateLimited() {
+	mux := &kvMux{
+		errMapMgr: newErrMapManager("default"),
+		tracer:    newTracerComponent(&noopTracer{}, "", true, &noopMeter{}, nil),
+	}
+
+	handle := func(status memd.StatusCode, memdErr error) (*testRecordingRetryStrategy, error) {
+		strategy := &testRecordingRetryStrategy{}
+		req := &memdQRequest{
+			Packet: memd.Packet{
+				Magic:   memd.CmdMagicReq,
+				Command: memd.CmdSet,
+				Key:     []byte("key"),
+			},
+			RetryStrategy: strategy,
+		}
+		resp := &memdQResponse{
+			Packet: &memd.Packet{
+				Magic:  memd.CmdMagicRes,
+				Status: status,
+			},
+		}
+
+		retried, err := mux.handleOpRoutingResp(resp, req, memdErr)
+		suite.Assert().False(retried)
+		return strategy, err
+	}
+
+	// Rate limits are offered to the retry strategy, even for non-idempotent requests, as the server didn't apply
+	// the request.
+	strategy, err := handle(memd.StatusRateLimitedMaxCommands, ErrMemdRateLimitedMaxCommands)
+	suite.Assert().ErrorIs(err, ErrRateLimitedFailure)
+	suite.Assert().Equal([]RetryReason{KVRateLimitedRetryReason}, strategy.reasons)
+
+	// Quota limits won't clear by waiting so are never retried.
+	strategy, err = handle(memd.StatusRateLimitedScopeSizeLimitExceeded, ErrMemdRateLimitedScopeSizeLimitExceeded)
+	suite.Assert().ErrorIs(err, ErrQuotaLimitedFailure)
+	suite.Assert().Empty(strategy.reasons)
+}
+
+func (suite *UnitTestSuite) TestKvMuxDispatchDirectQueueFullWait() {
+	mux := &kvMux{
+		tracer:                  newTracerComponent(&noopTracer{}, "", true, &noopMeter{}, nil),
+		errMapMgr:               newErrMapManager("default"),
+		blockOnFullQueueMaxWait: time.Second,
+	}
+	deadPipe := newDeadPipeline(1)
+	mux.updateState(nil, &kvMuxState{
+		routeCfg: routeConfig{revID: -1},
+		deadPipe: deadPipe,
+	})
+	suite.Require().Nil(deadPipe.SendRequest(&memdQRequest{}))
+
+	dispatch := func(deadline time.Time, retryCount uint32) (time.Duration, error) {
+		start := time.Now()
+		_, err := mux.DispatchDirect(&memdQRequest{
+			Packet: memd.Packet{
+				Command: memd.CmdGet,
+			},
+			dispatchDeadline: deadline,
+			retryCount:       retryCount,
+		})
+		return time.Since(start), err
+	}
+
+	// Requests without a deadline, such as those dispatched from the read path, never block.
+	waited, err := dispatch(time.Time{}, 0)
+	suite.Assert().ErrorIs(err, ErrOverload)
+	suite.Assert().Less(int64(waited), int64(500*time.Millisecond))
+
+	// Neither do retries.
+	waited, err = dispatch(time.Now().Add(time.Second), 1)
+	suite.Assert().ErrorIs(err, ErrOverload)
+	suite.Assert().Less(int64(waited), int64(500*time.Millisecond))
+
+	// The wait is capped to the operation deadline when it is sooner than the maximum wait.
+	waited, err = dispatch(time.Now().Add(20*time.Millisecond), 0)
+	suite.Assert().ErrorIs(err, ErrOverload)
+	suite.Assert().GreaterOrEqual(int64(waited), int64(20*time.Millisecond))
+	suite.Assert().Less(int64(waited), int64(500*time.Millisecond))
+}
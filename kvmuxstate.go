@@ -37,6 +37,7 @@ func newKVMuxState(cfg *routeConfig, kvServerList []routeEndpoint, tlsConfig *dy
 			BucketCapabilityReplicaRead:          CapabilityStatusUnknown,
 			BucketCapabilityNonDedupedHistory:    CapabilityStatusUnknown,
 			BucketCapabilityReviveDocument:       CapabilityStatusUnknown,
+			BucketCapabilityBinaryXattr:          CapabilityStatusUnknown,
 		},
 
 		collectionsSupported: cfg.ContainsBucketCapability("collections"),
@@ -91,6 +92,12 @@ func newKVMuxState(cfg *routeConfig, kvServerList []routeEndpoint, tlsConfig *dy
 		} else {
 			mux.bucketCapabilities[BucketCapabilityReviveDocument] = CapabilityStatusUnsupported
 		}
+
+		if cfg.ContainsBucketCapability("subdoc.BinaryXattr") {
+			mux.bucketCapabilities[BucketCapabilityBinaryXattr] = CapabilityStatusSupported
+		} else {
+			mux.bucketCapabilities[BucketCapabilityBinaryXattr] = CapabilityStatusUnsupported
+		}
 	}
 
 	return mux
@@ -104,6 +111,10 @@ func (mux *kvMuxState) RevID() int64 {
 	return mux.routeCfg.revID
 }
 
+func (mux *kvMuxState) RevEpoch() int64 {
+	return mux.routeCfg.revEpoch
+}
+
 func (mux *kvMuxState) VBMap() *vbucketMap {
 	return mux.routeCfg.vbMap
 }
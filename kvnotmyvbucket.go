@@ -0,0 +1,65 @@
+package gocbcore
+
+import (
+	"time"
+)
+
+// NotMyVbucketPolicy specifies how requests which fail with NotMyVbucket, as happens whilst a rebalance is moving
+// vbuckets between nodes, are retried.
+// Volatile: This API is subject to change at any time.
+type NotMyVbucketPolicy uint32
+
+const (
+	// NotMyVbucketPolicyDefault redispatches requests immediately when the config carried in the response moves the
+	// vbucket to another node, otherwise requests are retried using ControlledBackoff. This is the default.
+	NotMyVbucketPolicyDefault NotMyVbucketPolicy = iota
+
+	// NotMyVbucketPolicyImmediate always redispatches requests immediately to the node that the config carried in the
+	// response routes them to. Requests for which the response carried no config are retried using ControlledBackoff.
+	NotMyVbucketPolicyImmediate
+
+	// NotMyVbucketPolicyFixedDelay always retries requests after NotMyVbucketRetryDelay.
+	NotMyVbucketPolicyFixedDelay
+
+	// NotMyVbucketPolicyExponentialBackoff retries requests after a delay which starts at NotMyVbucketRetryDelay and
+	// doubles with each retry, up to a maximum of 1 second.
+	NotMyVbucketPolicyExponentialBackoff
+)
+
+const (
+	defaultNotMyVbucketRetryDelay = 10 * time.Millisecond
+	maxNotMyVbucketRetryDelay     = 1 * time.Second
+)
+
+// notMyVbucketRetryDelay returns how long a request which has already been retried retryAttempts times should wait
+// before being retried following a NotMyVbucket, or 0 if the delay is determined by the retry orchestrator.
+func (mux *kvMux) notMyVbucketRetryDelay(retryAttempts uint32) time.Duration {
+	switch mux.nmvPolicy {
+	case NotMyVbucketPolicyFixedDelay:
+		return mux.nmvRetryDelay
+	case NotMyVbucketPolicyExponentialBackoff:
+		return time.Duration(cappedExponentialBackoff(float64(mux.nmvRetryDelay), float64(maxNotMyVbucketRetryDelay),
+			2, retryAttempts))
+	default:
+		return 0
+	}
+}
+
+// waitAndRetryNotMyVbucket schedules a request which failed with NotMyVbucket to be retried according to the policy.
+func (mux *kvMux) waitAndRetryNotMyVbucket(req *memdQRequest) bool {
+	duration := mux.notMyVbucketRetryDelay(req.RetryAttempts())
+	if duration == 0 {
+		return mux.waitAndRetryOperation(req, KVNotMyVBucketRetryReason)
+	}
+
+	logDebugf("Will retry request. Backoff=%s, OperationID=%s. Reason=%s", duration, req.Identifier(),
+		KVNotMyVBucketRetryReason)
+	req.recordRetryAttempt(KVNotMyVBucketRetryReason)
+
+	if !mux.retryWheel.Schedule(req, time.Now().Add(duration), mux.requeueRetry) {
+		// The mux has been shut down.
+		return false
+	}
+	mux.tracer.RetryCountRecord(metricValueServiceKeyValue, req.Command.Name())
+	return true
+}
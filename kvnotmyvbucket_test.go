@@ -0,0 +1,62 @@
+package gocbcore
+
+import (
+	"time"
+)
+
+type testEndpointMeter struct {
+	*testMeter
+	endpoints []string
+}
+
+func (tm *testEndpointMeter) Counter(name string, tags map[string]string) (Counter, error) {
+	if name == meterNameCBNotMyVbuckets {
+		tm.endpoints = append(tm.endpoints, tags[metricAttribEndpointKey])
+	}
+	return tm.testMeter.Counter(name, tags)
+}
+
+func (suite *UnitTestSuite) TestNotMyVbucketRetryDelay() {
+	mux := &kvMux{nmvRetryDelay: 10 * time.Millisecond}
+
+	suite.Assert().Zero(mux.notMyVbucketRetryDelay(3))
+
+	mux.nmvPolicy = NotMyVbucketPolicyImmediate
+	suite.Assert().Zero(mux.notMyVbucketRetryDelay(3))
+
+	mux.nmvPolicy = NotMyVbucketPolicyFixedDelay
+	suite.Assert().Equal(10*time.Millisecond, mux.notMyVbucketRetryDelay(0))
+	suite.Assert().Equal(10*time.Millisecond, mux.notMyVbucketRetryDelay(5))
+
+	mux.nmvPolicy = NotMyVbucketPolicyExponentialBackoff
+	suite.Assert().Equal(10*time.Millisecond, mux.notMyVbucketRetryDelay(0))
+	suite.Assert().Equal(20*time.Millisecond, mux.notMyVbucketRetryDelay(1))
+	suite.Assert().Equal(80*time.Millisecond, mux.notMyVbucketRetryDelay(3))
+	suite.Assert().Equal(maxNotMyVbucketRetryDelay, mux.notMyVbucketRetryDelay(20))
+}
+
+func (suite *UnitTestSuite) TestNotMyVbucketCountRecord() {
+	meter := &testEndpointMeter{testMeter: newTestMeter()}
+	tracer := newTracerComponent(&noopTracer{}, "default", true, meter, nil)
+
+	tracer.NotMyVbucketCountRecord("10.0.0.1:11210")
+	tracer.NotMyVbucketCountRecord("10.0.0.2:11210")
+
+	suite.Assert().Equal([]string{"10.0.0.1:11210", "10.0.0.2:11210"}, meter.endpoints)
+	key := meterNameCBNotMyVbuckets + ":" + metricValueServiceKeyValue
+	suite.Require().Contains(meter.counters, key)
+	suite.Assert().Equal(uint64(2), meter.counters[key].count)
+
+	// The endpoint must not leak into the attributes shared with other metrics.
+	suite.Assert().NotContains(tracer.metricAttribs(metricValueServiceKeyValue, ""), metricAttribEndpointKey)
+}
+
+func (suite *UnitTestSuite) TestNotMyVbucketPolicyConnStr() {
+	config := AgentConfig{}
+	err := config.FromConnStr("couchbase://10.0.0.1?kv_not_my_vbucket_policy=exponential&kv_not_my_vbucket_retry_delay=5ms")
+	suite.Require().Nil(err, err)
+	suite.Assert().Equal(NotMyVbucketPolicyExponentialBackoff, config.KVConfig.NotMyVbucketPolicy)
+	suite.Assert().Equal(5*time.Millisecond, config.KVConfig.NotMyVbucketRetryDelay)
+
+	suite.Assert().NotNil((&AgentConfig{}).FromConnStr("couchbase://10.0.0.1?kv_not_my_vbucket_policy=never"))
+}
@@ -0,0 +1,185 @@
+package gocbcore
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/couchbase/gocbcore/v10/memd"
+)
+
+// kvRateLimitPollInterval is how long a request is asked to wait before retrying when it doesn't fit within the
+// in-flight byte budget, as we can't know when other requests will complete and free it up.
+const kvRateLimitPollInterval = time.Millisecond
+
+// kvRateLimitedError is returned when a request doesn't fit within the client side rate limits. It carries how long
+// until the request is expected to fit so that the retry strategy can wait for that long before trying again.
+type kvRateLimitedError struct {
+	retryAfter time.Duration
+}
+
+func (e *kvRateLimitedError) Error() string {
+	return errRateLimitedLocally.Error()
+}
+
+func (e *kvRateLimitedError) Unwrap() error {
+	return errRateLimitedLocally
+}
+
+// kvRateLimiter applies client side rate limiting to the requests dispatched to each KV node. Each node has its own
+// token bucket for operations per second and its own budget of request bytes in flight, shared between all of the
+// connections to that node.
+// A nil limiter applies no limits.
+type kvRateLimiter struct {
+	opsPerSecond     float64
+	burst            float64
+	maxInFlightBytes int64
+
+	lock      sync.Mutex
+	endpoints map[string]*kvEndpointRateLimiter
+}
+
+type kvEndpointRateLimiter struct {
+	limiter *kvRateLimiter
+
+	lock          sync.Mutex
+	tokens        float64
+	lastRefill    time.Time
+	inFlightBytes int64
+}
+
+func newKVRateLimiter(config KVRateLimitConfig) *kvRateLimiter {
+	if config.OpsPerSecond <= 0 && config.MaxInFlightBytes <= 0 {
+		return nil
+	}
+
+	burst := float64(config.Burst)
+	if burst <= 0 {
+		// Default to allowing a tenth of a second's worth of operations to be dispatched at once.
+		burst = float64(config.OpsPerSecond) / 10
+	}
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &kvRateLimiter{
+		opsPerSecond:     float64(config.OpsPerSecond),
+		burst:            burst,
+		maxInFlightBytes: int64(config.MaxInFlightBytes),
+		endpoints:        make(map[string]*kvEndpointRateLimiter),
+	}
+}
+
+// Endpoint returns the limiter for the node at the given address.
+func (rl *kvRateLimiter) Endpoint(address string) *kvEndpointRateLimiter {
+	if rl == nil {
+		return nil
+	}
+
+	rl.lock.Lock()
+	defer rl.lock.Unlock()
+
+	endpoint, ok := rl.endpoints[address]
+	if !ok {
+		endpoint = &kvEndpointRateLimiter{
+			limiter:    rl,
+			tokens:     rl.burst,
+			lastRefill: time.Now(),
+		}
+		rl.endpoints[address] = endpoint
+	}
+
+	return endpoint
+}
+
+// isRateLimitExempt returns whether a request bypasses rate limiting, this covers requests which are part of
+// maintaining the connection itself rather than user operations.
+func isRateLimitExempt(req *memdQRequest) bool {
+	if req.Persistent {
+		return true
+	}
+
+	switch req.Command {
+	case memd.CmdHello, memd.CmdSASLListMechs, memd.CmdSASLAuth, memd.CmdSASLStep, memd.CmdSelectBucket,
+		memd.CmdGetErrorMap, memd.CmdGetClusterConfig, memd.CmdNoop:
+		return true
+	}
+
+	return false
+}
+
+func rateLimitRequestSize(req *memdQRequest) int64 {
+	return int64(24 + len(req.Key) + len(req.Extras) + len(req.Value))
+}
+
+// Admit checks whether the request fits within the rate limits, returning how long until it is expected to fit if it
+// does not. Admit never blocks, as it is called from the connection's dispatch loop, so requests which don't fit are
+// rejected and handed to the retry strategy. Admitted requests are charged against the in-flight byte budget until
+// Release is called for them.
+func (el *kvEndpointRateLimiter) Admit(req *memdQRequest) (time.Duration, bool) {
+	if el == nil || isRateLimitExempt(req) {
+		return 0, true
+	}
+
+	size := rateLimitRequestSize(req)
+	wait, ok := el.tryAdmit(size, time.Now())
+	if !ok {
+		return wait, false
+	}
+
+	if el.limiter.maxInFlightBytes > 0 {
+		atomic.StoreUint32(&req.rateLimitedBytes, uint32(size))
+	}
+	return 0, true
+}
+
+// tryAdmit attempts to admit a request of the given size, returning how long to wait before trying again if it could
+// not be admitted.
+func (el *kvEndpointRateLimiter) tryAdmit(size int64, now time.Time) (time.Duration, bool) {
+	rl := el.limiter
+
+	el.lock.Lock()
+	defer el.lock.Unlock()
+
+	// A request larger than the whole budget can never fit alongside others, so let it through on its own rather
+	// than blocking it forever.
+	if rl.maxInFlightBytes > 0 && el.inFlightBytes > 0 && el.inFlightBytes+size > rl.maxInFlightBytes {
+		return kvRateLimitPollInterval, false
+	}
+
+	if rl.opsPerSecond > 0 {
+		el.tokens += now.Sub(el.lastRefill).Seconds() * rl.opsPerSecond
+		if el.tokens > rl.burst {
+			el.tokens = rl.burst
+		}
+		el.lastRefill = now
+
+		if el.tokens < 1 {
+			return time.Duration((1 - el.tokens) / rl.opsPerSecond * float64(time.Second)), false
+		}
+		el.tokens--
+	}
+
+	if rl.maxInFlightBytes > 0 {
+		el.inFlightBytes += size
+	}
+
+	return 0, true
+}
+
+// Release returns the bytes charged for a request to the in-flight byte budget, it is safe to call more than once
+// for the same request.
+func (el *kvEndpointRateLimiter) Release(req *memdQRequest) {
+	if el == nil || el.limiter.maxInFlightBytes <= 0 {
+		return
+	}
+
+	size := atomic.SwapUint32(&req.rateLimitedBytes, 0)
+	if size == 0 {
+		return
+	}
+
+	el.lock.Lock()
+	el.inFlightBytes -= int64(size)
+	el.lock.Unlock()
+}
@@ -0,0 +1,98 @@
+package gocbcore
+
+import (
+	"time"
+
+	"github.com/couchbase/gocbcore/v10/memd"
+)
+
+func (suite *UnitTestSuite) TestKVRateLimiterOpsPerSecond() {
+	limiter := newKVRateLimiter(KVRateLimitConfig{
+		OpsPerSecond: 10,
+		Burst:        2,
+	}).Endpoint("10.0.0.1:11210")
+
+	now := time.Now()
+	_, ok := limiter.tryAdmit(50, now)
+	suite.Assert().True(ok)
+	_, ok = limiter.tryAdmit(50, now)
+	suite.Assert().True(ok)
+
+	wait, ok := limiter.tryAdmit(50, now)
+	suite.Require().False(ok)
+	suite.Assert().InDelta(float64(100*time.Millisecond), float64(wait), float64(time.Millisecond))
+
+	_, ok = limiter.tryAdmit(50, now.Add(100*time.Millisecond))
+	suite.Assert().True(ok)
+
+	// Each node has its own limits.
+	_, ok = limiter.limiter.Endpoint("10.0.0.2:11210").tryAdmit(50, now)
+	suite.Assert().True(ok)
+}
+
+func (suite *UnitTestSuite) TestKVRateLimiterInFlightBytes() {
+	limiter := newKVRateLimiter(KVRateLimitConfig{
+		MaxInFlightBytes: 100,
+	}).Endpoint("10.0.0.1:11210")
+
+	newReq := func(valueLen int) *memdQRequest {
+		return &memdQRequest{
+			Packet: memd.Packet{
+				Command: memd.CmdSet,
+				Value:   make([]byte, valueLen),
+			},
+		}
+	}
+
+	req1 := newReq(40)
+	suite.Require().True(isAdmitted(limiter, req1))
+
+	// The second request doesn't fit alongside the first until the first is released.
+	req2 := newReq(40)
+	suite.Assert().False(isAdmitted(limiter, req2))
+
+	limiter.Release(req1)
+	limiter.Release(req1)
+	suite.Require().True(isAdmitted(limiter, req2))
+	limiter.Release(req2)
+	suite.Assert().Zero(limiter.inFlightBytes)
+
+	// A request larger than the whole budget is allowed through on its own.
+	req3 := newReq(200)
+	suite.Require().True(isAdmitted(limiter, req3))
+	suite.Assert().False(isAdmitted(limiter, newReq(0)))
+	limiter.Release(req3)
+	suite.Assert().Zero(limiter.inFlightBytes)
+
+	// Requests which maintain the connection are never limited.
+	suite.Require().True(isAdmitted(limiter, newReq(200)))
+	suite.Assert().True(isAdmitted(limiter, &memdQRequest{Packet: memd.Packet{Command: memd.CmdGetClusterConfig}}))
+}
+
+func (suite *UnitTestSuite) TestKVRateLimiterRejectsWithRetryAfter() {
+	limiter := newKVRateLimiter(KVRateLimitConfig{
+		OpsPerSecond: 20,
+		Burst:        1,
+	}).Endpoint("10.0.0.1:11210")
+
+	req := &memdQRequest{Packet: memd.Packet{Command: memd.CmdGet}}
+	_, ok := limiter.Admit(req)
+	suite.Require().True(ok)
+
+	// Admit never waits for capacity, it reports how long until there should be some.
+	start := time.Now()
+	retryAfter, ok := limiter.Admit(req)
+	suite.Assert().False(ok)
+	suite.Assert().Less(int64(time.Since(start)), int64(10*time.Millisecond))
+	suite.Assert().InDelta(float64(50*time.Millisecond), float64(retryAfter), float64(5*time.Millisecond))
+
+	var nilLimiter *kvEndpointRateLimiter
+	suite.Assert().True(isAdmitted(nilLimiter, req))
+	nilLimiter.Release(req)
+	suite.Assert().Nil(newKVRateLimiter(KVRateLimitConfig{}))
+}
+
+func isAdmitted(limiter *kvEndpointRateLimiter, req *memdQRequest) bool {
+	_, ok := limiter.Admit(req)
+	return ok
+}
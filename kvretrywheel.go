@@ -0,0 +1,166 @@
+package gocbcore
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	retryTimerWheelTick  = time.Millisecond
+	retryTimerWheelSlots = 512
+)
+
+type retryTimerWheelEntry struct {
+	req       *memdQRequest
+	retryAt   time.Time
+	requeueFn func(req *memdQRequest)
+}
+
+// retryTimerWheel holds requests which are waiting to be retried, requeueing them once their retry time is reached.
+// Using a single wheel, rather than a goroutine per request, means that requests which time out or are cancelled
+// whilst waiting are discarded when their slot is reached and that any requests still waiting can be failed when
+// the mux shuts down. The wheel only runs whilst there are requests waiting. The zero value is ready to use.
+type retryTimerWheel struct {
+	lock     sync.Mutex
+	slots    [][]retryTimerWheelEntry
+	pos      int
+	lastTick time.Time
+	count    int
+	running  bool
+	closed   bool
+	runWg    sync.WaitGroup
+}
+
+// Schedule adds the request to the wheel, it is passed to requeueFn once retryAt is reached unless it has been
+// cancelled in the meantime. Returns false if the wheel has been closed.
+func (w *retryTimerWheel) Schedule(req *memdQRequest, retryAt time.Time, requeueFn func(req *memdQRequest)) bool {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.closed {
+		return false
+	}
+
+	if w.slots == nil {
+		w.slots = make([][]retryTimerWheelEntry, retryTimerWheelSlots)
+	}
+
+	if !w.running {
+		w.running = true
+		w.lastTick = time.Now()
+		w.runWg.Add(1)
+		go w.run()
+	}
+
+	// Requests are placed into the slot for the tick at which they are due, those due more than a full rotation
+	// away stay in their slot until the rotation in which they are due.
+	ticks := int((retryAt.Sub(w.lastTick) + retryTimerWheelTick - 1) / retryTimerWheelTick)
+	if ticks < 1 {
+		ticks = 1
+	}
+	slot := (w.pos + ticks) % retryTimerWheelSlots
+
+	w.slots[slot] = append(w.slots[slot], retryTimerWheelEntry{
+		req:       req,
+		retryAt:   retryAt,
+		requeueFn: requeueFn,
+	})
+	w.count++
+
+	return true
+}
+
+func (w *retryTimerWheel) run() {
+	defer w.runWg.Done()
+
+	ticker := time.NewTicker(retryTimerWheelTick)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		due, stop := w.advance(time.Now())
+
+		for _, entry := range due {
+			// The request may have timed out, or been cancelled, whilst it was waiting.
+			if entry.req.isCancelled() {
+				continue
+			}
+
+			entry.requeueFn(entry.req)
+		}
+
+		if stop {
+			return
+		}
+	}
+}
+
+// advance moves the wheel forward to now, returning the requests which are due and whether the wheel should stop
+// running as it is either empty or closed.
+func (w *retryTimerWheel) advance(now time.Time) ([]retryTimerWheelEntry, bool) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.closed {
+		return nil, true
+	}
+
+	// The ticker drops ticks if we fall behind, so we work out how many slots have passed rather than assuming one.
+	elapsed := int(now.Sub(w.lastTick) / retryTimerWheelTick)
+	if elapsed > retryTimerWheelSlots {
+		elapsed = retryTimerWheelSlots
+	}
+
+	var due []retryTimerWheelEntry
+	for i := 0; i < elapsed; i++ {
+		w.pos = (w.pos + 1) % retryTimerWheelSlots
+		w.lastTick = w.lastTick.Add(retryTimerWheelTick)
+
+		entries := w.slots[w.pos]
+		if len(entries) == 0 {
+			continue
+		}
+
+		var remaining []retryTimerWheelEntry
+		for _, entry := range entries {
+			if entry.retryAt.After(now) {
+				remaining = append(remaining, entry)
+				continue
+			}
+			due = append(due, entry)
+		}
+		w.slots[w.pos] = remaining
+	}
+	if elapsed == retryTimerWheelSlots {
+		w.lastTick = now
+	}
+	w.count -= len(due)
+
+	if w.count == 0 {
+		w.running = false
+		return due, true
+	}
+
+	return due, false
+}
+
+// Close stops the wheel and returns the requests which were still waiting to be retried.
+func (w *retryTimerWheel) Close() []*memdQRequest {
+	w.lock.Lock()
+	w.closed = true
+
+	var pending []*memdQRequest
+	for i, entries := range w.slots {
+		for _, entry := range entries {
+			pending = append(pending, entry.req)
+		}
+		w.slots[i] = nil
+	}
+	w.count = 0
+	w.running = false
+	w.lock.Unlock()
+
+	// Wait for the wheel to stop so that nothing is requeued after we return.
+	w.runWg.Wait()
+
+	return pending
+}
@@ -0,0 +1,116 @@
+package gocbcore
+
+import (
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"time"
+	"unsafe"
+
+	"github.com/couchbase/gocbcore/v10/memd"
+)
+
+func (suite *UnitTestSuite) TestRetryTimerWheel() {
+	var wheel retryTimerWheel
+
+	requeued := make(chan *memdQRequest, 2)
+	requeueFn := func(req *memdQRequest) {
+		requeued <- req
+	}
+
+	cancelledReq := &memdQRequest{}
+	req := &memdQRequest{}
+	start := time.Now()
+	suite.Require().True(wheel.Schedule(cancelledReq, start.Add(10*time.Millisecond), requeueFn))
+	suite.Require().True(wheel.Schedule(req, start.Add(20*time.Millisecond), requeueFn))
+	suite.Require().True(cancelledReq.internalCancel(errRequestCanceled))
+
+	select {
+	case r := <-requeued:
+		suite.Assert().Equal(req, r)
+		suite.Assert().GreaterOrEqual(int64(time.Since(start)), int64(20*time.Millisecond))
+	case <-time.After(5 * time.Second):
+		suite.T().Fatalf("Request was not requeued")
+	}
+
+	// Requests which are still waiting are handed back when the wheel is closed.
+	pendingReq := &memdQRequest{}
+	suite.Require().True(wheel.Schedule(pendingReq, time.Now().Add(time.Hour), requeueFn))
+
+	pending := wheel.Close()
+	suite.Assert().Equal([]*memdQRequest{pendingReq}, pending)
+	suite.Assert().False(wheel.Schedule(&memdQRequest{}, time.Now(), requeueFn))
+	suite.Assert().Empty(requeued)
+}
+
+func (suite *UnitTestSuite) TestMakeTimeoutErrorAmbiguity() {
+	req := &memdQRequest{}
+
+	// A request which has not been written to a connection cannot have been applied by the server.
+	err := makeTimeoutError(time.Now(), "Get", errAmbiguousTimeout, req)
+	suite.Assert().True(errors.Is(err, ErrUnambiguousTimeout), err)
+
+	client := &memdClient{}
+	atomic.StorePointer(&req.waitingIn, unsafe.Pointer(client))
+
+	err = makeTimeoutError(time.Now(), "Get", errAmbiguousTimeout, req)
+	suite.Assert().True(errors.Is(err, ErrAmbiguousTimeout), err)
+
+	err = makeTimeoutError(time.Now(), "Get", errUnambiguousTimeout, req)
+	suite.Assert().True(errors.Is(err, ErrUnambiguousTimeout), err)
+}
+
+func (suite *UnitTestSuite) TestMakeTimeoutErrorContext() {
+	q := newMemdOpQueue()
+	req := &memdQRequest{
+		Packet: memd.Packet{Command: memd.CmdGet},
+	}
+	suite.Require().Nil(q.Push(req, 0))
+	time.Sleep(10 * time.Millisecond)
+
+	// A request which has never been written has been waiting ever since it was queued.
+	err := makeTimeoutError(time.Now(), "Get", errAmbiguousTimeout, req)
+	suite.Assert().Empty(err.LastDispatchedTo)
+	suite.Assert().GreaterOrEqual(int64(err.TimeQueued), int64(10*time.Millisecond))
+	data, jErr := json.Marshal(err)
+	suite.Require().Nil(jErr)
+	suite.Assert().Contains(string(data), `"r":"never dispatched"`)
+	suite.Assert().Contains(string(data), `"q":`)
+
+	// The error string uses the same compact form.
+	suite.Assert().Contains(err.Error(), `"s":"Get"`)
+	suite.Assert().Contains(err.Error(), `"r":"never dispatched"`)
+	suite.Assert().Contains(err.Error(), `"q":`)
+
+	roundTrip := *err
+	roundTrip.InnerError = nil
+	data, jErr = json.Marshal(&roundTrip)
+	suite.Require().Nil(jErr)
+	var unmarshalled TimeoutError
+	suite.Require().Nil(json.Unmarshal(data, &unmarshalled))
+	suite.Assert().Empty(unmarshalled.LastDispatchedTo)
+	data, jErr = json.Marshal(&unmarshalled)
+	suite.Require().Nil(jErr)
+	suite.Assert().Contains(string(data), `"r":"never dispatched"`)
+
+	suite.Require().Equal(req, q.pop(q.Consumer()))
+	req.recordRetryAttempt(KVTemporaryFailureRetryReason)
+	req.SetConnectionInfo(memdQRequestConnInfo{
+		lastDispatchedTo:   "10.0.0.1:11210",
+		lastDispatchedFrom: "10.0.0.2:50000",
+		lastConnectionID:   "abc/def",
+	})
+
+	err = makeTimeoutError(time.Now(), "Get", errAmbiguousTimeout, req)
+	suite.Assert().Zero(err.TimeQueued)
+	suite.Assert().Equal("10.0.0.1:11210", err.LastDispatchedTo)
+	suite.Assert().Equal([]RetryReason{KVTemporaryFailureRetryReason}, err.RetryReasons)
+	data, jErr = json.Marshal(err)
+	suite.Require().Nil(jErr)
+	suite.Assert().Contains(string(data), `"r":"10.0.0.1:11210"`)
+
+	// Only kv operations are reported as never dispatched.
+	data, jErr = json.Marshal(&TimeoutError{OperationID: "http"})
+	suite.Require().Nil(jErr)
+	suite.Assert().NotContains(string(data), "never dispatched")
+}
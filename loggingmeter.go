@@ -0,0 +1,270 @@
+package gocbcore
+
+import (
+	"encoding/json"
+	"math/rand"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// LoggingMeterOptions specifies options for the LoggingMeter.
+// Volatile: This API is subject to change at any time.
+type LoggingMeterOptions struct {
+	// EmitInterval is the period of time between reports being logged, defaults to 10 minutes.
+	EmitInterval time.Duration
+}
+
+// LoggingMeter is a Meter implementation which aggregates counters and value recordings in memory and
+// periodically logs a summary of them, including latency percentiles, at info level.
+// Volatile: This API is subject to change at any time.
+type LoggingMeter struct {
+	interval time.Duration
+
+	lock      sync.Mutex
+	counters  map[loggingMeterKey]*loggingMeterCounter
+	recorders map[loggingMeterKey]*loggingMeterValueRecorder
+
+	stopSig chan struct{}
+	stopped uint32
+}
+
+type loggingMeterKey struct {
+	name      string
+	service   string
+	operation string
+}
+
+var loggingMeterPercentiles = []float64{50.0, 90.0, 99.0, 99.9, 100.0}
+
+const loggingMeterMaxSamples = 8192
+
+// NewLoggingMeter creates a new LoggingMeter and starts its report loop. Close must be called once the meter is no
+// longer required.
+// Volatile: This API is subject to change at any time.
+func NewLoggingMeter(opts *LoggingMeterOptions) *LoggingMeter {
+	if opts == nil {
+		opts = &LoggingMeterOptions{}
+	}
+
+	interval := 10 * time.Minute
+	if opts.EmitInterval > 0 {
+		interval = opts.EmitInterval
+	}
+
+	lm := &LoggingMeter{
+		interval:  interval,
+		counters:  make(map[loggingMeterKey]*loggingMeterCounter),
+		recorders: make(map[loggingMeterKey]*loggingMeterValueRecorder),
+		stopSig:   make(chan struct{}),
+	}
+	go lm.loop()
+
+	return lm
+}
+
+// Counter returns a Counter for the given metric name and tags.
+func (lm *LoggingMeter) Counter(name string, tags map[string]string) (Counter, error) {
+	key := loggingMeterKey{
+		name:      name,
+		service:   tags[metricAttribServiceKey],
+		operation: tags[metricAttribOperationKey],
+	}
+
+	lm.lock.Lock()
+	counter, ok := lm.counters[key]
+	if !ok {
+		counter = &loggingMeterCounter{}
+		lm.counters[key] = counter
+	}
+	lm.lock.Unlock()
+
+	return counter, nil
+}
+
+// ValueRecorder returns a ValueRecorder for the given metric name and tags.
+func (lm *LoggingMeter) ValueRecorder(name string, tags map[string]string) (ValueRecorder, error) {
+	key := loggingMeterKey{
+		name:      name,
+		service:   tags[metricAttribServiceKey],
+		operation: tags[metricAttribOperationKey],
+	}
+
+	lm.lock.Lock()
+	recorder, ok := lm.recorders[key]
+	if !ok {
+		recorder = &loggingMeterValueRecorder{}
+		lm.recorders[key] = recorder
+	}
+	lm.lock.Unlock()
+
+	return recorder, nil
+}
+
+// Close stops the meter from emitting any further reports.
+func (lm *LoggingMeter) Close() {
+	if atomic.CompareAndSwapUint32(&lm.stopped, 0, 1) {
+		close(lm.stopSig)
+	}
+}
+
+func (lm *LoggingMeter) loop() {
+	for {
+		select {
+		case <-lm.stopSig:
+			return
+		case <-time.After(lm.interval):
+		}
+
+		jsonBytes := lm.createOutput()
+		if len(jsonBytes) == 0 {
+			continue
+		}
+
+		logInfof("Aggregate metrics:\n %s", jsonBytes)
+	}
+}
+
+type loggingMeterRecorderEntry struct {
+	TotalCount    uint64            `json:"total_count"`
+	PercentilesUs map[string]uint64 `json:"percentiles_us"`
+}
+
+type loggingMeterOutput struct {
+	Meta struct {
+		EmitIntervalS uint64 `json:"emit_interval_s"`
+	} `json:"meta"`
+	Counters  map[string]map[string]map[string]uint64                    `json:"counters,omitempty"`
+	Recorders map[string]map[string]map[string]loggingMeterRecorderEntry `json:"operations,omitempty"`
+}
+
+func (lm *LoggingMeter) createOutput() []byte {
+	output := loggingMeterOutput{
+		Counters:  make(map[string]map[string]map[string]uint64),
+		Recorders: make(map[string]map[string]map[string]loggingMeterRecorderEntry),
+	}
+	output.Meta.EmitIntervalS = uint64(lm.interval.Seconds())
+
+	lm.lock.Lock()
+	counters := make(map[loggingMeterKey]*loggingMeterCounter, len(lm.counters))
+	for key, counter := range lm.counters {
+		counters[key] = counter
+	}
+	recorders := make(map[loggingMeterKey]*loggingMeterValueRecorder, len(lm.recorders))
+	for key, recorder := range lm.recorders {
+		recorders[key] = recorder
+	}
+	lm.lock.Unlock()
+
+	var hasData bool
+	for key, counter := range counters {
+		count := counter.reset()
+		if count == 0 {
+			continue
+		}
+		hasData = true
+
+		if _, ok := output.Counters[key.name]; !ok {
+			output.Counters[key.name] = make(map[string]map[string]uint64)
+		}
+		if _, ok := output.Counters[key.name][key.service]; !ok {
+			output.Counters[key.name][key.service] = make(map[string]uint64)
+		}
+		output.Counters[key.name][key.service][key.operation] = count
+	}
+
+	for key, recorder := range recorders {
+		count, values := recorder.reset()
+		if count == 0 {
+			continue
+		}
+		hasData = true
+
+		if _, ok := output.Recorders[key.name]; !ok {
+			output.Recorders[key.name] = make(map[string]map[string]loggingMeterRecorderEntry)
+		}
+		if _, ok := output.Recorders[key.name][key.service]; !ok {
+			output.Recorders[key.name][key.service] = make(map[string]loggingMeterRecorderEntry)
+		}
+		output.Recorders[key.name][key.service][key.operation] = loggingMeterRecorderEntry{
+			TotalCount:    count,
+			PercentilesUs: loggingMeterCalculatePercentiles(values),
+		}
+	}
+
+	if !hasData {
+		return nil
+	}
+
+	jsonBytes, err := json.Marshal(output)
+	if err != nil {
+		logDebugf("Failed to generate metrics JSON: %s", err)
+		return nil
+	}
+
+	return jsonBytes
+}
+
+func loggingMeterCalculatePercentiles(values []uint64) map[string]uint64 {
+	sort.Slice(values, func(i, j int) bool {
+		return values[i] < values[j]
+	})
+
+	percentiles := make(map[string]uint64, len(loggingMeterPercentiles))
+	for _, percentile := range loggingMeterPercentiles {
+		idx := int(float64(len(values))*percentile/100.0+0.5) - 1
+		if idx < 0 {
+			idx = 0
+		}
+		if idx >= len(values) {
+			idx = len(values) - 1
+		}
+		percentiles[strconv.FormatFloat(percentile, 'f', 1, 64)] = values[idx]
+	}
+
+	return percentiles
+}
+
+type loggingMeterCounter struct {
+	count uint64
+}
+
+func (lmc *loggingMeterCounter) IncrementBy(num uint64) {
+	atomic.AddUint64(&lmc.count, num)
+}
+
+func (lmc *loggingMeterCounter) reset() uint64 {
+	return atomic.SwapUint64(&lmc.count, 0)
+}
+
+// loggingMeterValueRecorder keeps a bounded, uniformly sampled, set of the values recorded during an interval so
+// that memory usage does not grow with throughput.
+type loggingMeterValueRecorder struct {
+	lock   sync.Mutex
+	count  uint64
+	values []uint64
+}
+
+func (lmvr *loggingMeterValueRecorder) RecordValue(val uint64) {
+	lmvr.lock.Lock()
+	lmvr.count++
+	if len(lmvr.values) < loggingMeterMaxSamples {
+		lmvr.values = append(lmvr.values, val)
+	} else if idx := rand.Int63n(int64(lmvr.count)); idx < loggingMeterMaxSamples { // #nosec G404
+		lmvr.values[idx] = val
+	}
+	lmvr.lock.Unlock()
+}
+
+func (lmvr *loggingMeterValueRecorder) reset() (uint64, []uint64) {
+	lmvr.lock.Lock()
+	count := lmvr.count
+	values := lmvr.values
+	lmvr.count = 0
+	lmvr.values = nil
+	lmvr.lock.Unlock()
+
+	return count, values
+}
@@ -0,0 +1,47 @@
+package gocbcore
+
+import (
+	"encoding/json"
+	"time"
+)
+
+func (suite *UnitTestSuite) TestLoggingMeterOutput() {
+	lm := NewLoggingMeter(&LoggingMeterOptions{EmitInterval: time.Hour})
+	defer lm.Close()
+
+	suite.Assert().Nil(lm.createOutput())
+
+	tags := map[string]string{
+		metricAttribServiceKey:   metricValueServiceKeyValue,
+		metricAttribOperationKey: "Get",
+	}
+
+	recorder, err := lm.ValueRecorder(meterNameCBOperations, tags)
+	suite.Require().Nil(err, err)
+	for i := uint64(1); i <= 100; i++ {
+		recorder.RecordValue(i)
+	}
+
+	counter, err := lm.Counter(meterNameCBTimeouts, tags)
+	suite.Require().Nil(err, err)
+	counter.IncrementBy(2)
+
+	jsonBytes := lm.createOutput()
+	suite.Require().NotEmpty(jsonBytes)
+
+	var output loggingMeterOutput
+	suite.Require().Nil(json.Unmarshal(jsonBytes, &output))
+
+	suite.Assert().Equal(uint64(3600), output.Meta.EmitIntervalS)
+	suite.Assert().Equal(uint64(2), output.Counters[meterNameCBTimeouts]["kv"]["Get"])
+
+	entry := output.Recorders[meterNameCBOperations]["kv"]["Get"]
+	suite.Assert().Equal(uint64(100), entry.TotalCount)
+	suite.Assert().Equal(uint64(50), entry.PercentilesUs["50.0"])
+	suite.Assert().Equal(uint64(90), entry.PercentilesUs["90.0"])
+	suite.Assert().Equal(uint64(99), entry.PercentilesUs["99.0"])
+	suite.Assert().Equal(uint64(100), entry.PercentilesUs["100.0"])
+
+	// Everything should have been reset by the previous output.
+	suite.Assert().Nil(lm.createOutput())
+}
@@ -112,105 +112,17 @@ func (c *Conn) WritePacket(pkt *Packet) error {
 		}
 	}
 
-	extLen := len(extras)
-	keyLen := len(encodedKey)
-	valLen := len(pkt.Value)
-
-	framesLen := 0
-	if pkt.BarrierFrame != nil {
-		framesLen++
-	}
-	if pkt.DurabilityLevelFrame != nil {
-		if pkt.DurabilityTimeoutFrame == nil {
-			framesLen += 2
-		} else {
-			framesLen += 4
-		}
-	}
-	if pkt.StreamIDFrame != nil {
-		framesLen += 3
-	}
-	if pkt.OpenTracingFrame != nil {
-		framesLen += calcHeaderSize(len(pkt.OpenTracingFrame.TraceContext))
-	}
-	if pkt.ServerDurationFrame != nil {
-		framesLen += 3
-	}
-	if pkt.UserImpersonationFrame != nil {
-		framesLen += calcHeaderSize(len(pkt.UserImpersonationFrame.User))
-	}
-	if pkt.PreserveExpiryFrame != nil {
-		framesLen += 1
-	}
-	for _, fr := range pkt.UnsupportedFrames {
-		framesLen += calcHeaderSize(len(fr.Data))
-	}
-
-	// We automatically upgrade a packet from normal Req or Res magic into
-	// the frame variant depending on the usage of them.
-	pktMagic := pkt.Magic
-	if framesLen > 0 {
-		switch pktMagic {
-		case CmdMagicReq:
-			if !c.IsFeatureEnabled(FeatureAltRequests) {
-				return errors.New("cannot use frames in req packets without enabling the feature")
-			}
-
-			pktMagic = cmdMagicReqExt
-		case CmdMagicRes:
-			pktMagic = cmdMagicResExt
-		default:
-			return errors.New("cannot use frames with an unsupported magic")
-		}
-	}
-
-	buffer := aquireWriteBuf()
-	defer releaseWriteBuf(buffer)
-
-	buffer.WriteByte(byte(pktMagic))
-	buffer.WriteByte(byte(pkt.Command))
-
-	// This is safe to do without checking the magic as we check the magic
-	// above before incrementing the framesLen variable
-	if framesLen > 0 {
-		buffer.WriteByte(byte(framesLen))
-		buffer.WriteByte(byte(keyLen))
-	} else {
-		writeUint16(buffer, uint16(keyLen))
-	}
-
-	buffer.WriteByte(byte(extLen))
-	buffer.WriteByte(pkt.Datatype)
-
-	switch pkt.Magic {
-	case CmdMagicReq:
-		if pkt.Status != 0 {
-			return errors.New("cannot specify status in a request packet")
-		}
-
-		writeUint16(buffer, pkt.Vbucket)
-	case CmdMagicRes:
-		if pkt.Vbucket != 0 {
-			return errors.New("cannot specify vbucket in a response packet")
-		}
-
-		writeUint16(buffer, uint16(pkt.Status))
-	default:
-		return errors.New("cannot encode status/vbucket for unknown packet magic")
-	}
-
-	writeUint32(buffer, uint32(keyLen+extLen+valLen+framesLen))
-	writeUint32(buffer, pkt.Opaque)
-	writeUint64(buffer, pkt.Cas)
-
 	// Generate the framing extra data
+	var frames frameBuilder
 
 	if pkt.BarrierFrame != nil {
 		if pkt.Magic != CmdMagicReq {
 			return errors.New("cannot use barrier frame in non-request packets")
 		}
 
-		writeFrameHeader(buffer, frameTypeReqBarrier, 0)
+		if err := frames.Add(frameTypeReqBarrier, nil); err != nil {
+			return err
+		}
 	}
 
 	if pkt.DurabilityLevelFrame != nil || pkt.DurabilityTimeoutFrame != nil {
@@ -226,18 +138,18 @@ func (c *Conn) WritePacket(pkt *Packet) error {
 			return errors.New("cannot encode durability timeout frame without durability level frame")
 		}
 
-		if pkt.DurabilityTimeoutFrame == nil {
-			writeFrameHeader(buffer, frameTypeReqSyncDurability, 1)
-			buffer.WriteByte(byte(pkt.DurabilityLevelFrame.DurabilityLevel))
-		} else {
+		body := []byte{byte(pkt.DurabilityLevelFrame.DurabilityLevel)}
+		if pkt.DurabilityTimeoutFrame != nil {
 			durabilityTimeoutMillis := pkt.DurabilityTimeoutFrame.DurabilityTimeout / time.Millisecond
 			if durabilityTimeoutMillis > 65535 {
 				durabilityTimeoutMillis = 65535
 			}
 
-			writeFrameHeader(buffer, frameTypeReqSyncDurability, 3)
-			buffer.WriteByte(byte(pkt.DurabilityLevelFrame.DurabilityLevel))
-			writeUint16(buffer, uint16(durabilityTimeoutMillis))
+			body = appendUint16(body, uint16(durabilityTimeoutMillis))
+		}
+
+		if err := frames.Add(frameTypeReqSyncDurability, body); err != nil {
+			return err
 		}
 	}
 
@@ -246,8 +158,9 @@ func (c *Conn) WritePacket(pkt *Packet) error {
 			return errors.New("cannot use stream id frame in non-request packets")
 		}
 
-		writeFrameHeader(buffer, frameTypeReqStreamID, 2)
-		writeUint16(buffer, pkt.StreamIDFrame.StreamID)
+		if err := frames.Add(frameTypeReqStreamID, appendUint16(nil, pkt.StreamIDFrame.StreamID)); err != nil {
+			return err
+		}
 	}
 
 	if pkt.OpenTracingFrame != nil {
@@ -259,9 +172,9 @@ func (c *Conn) WritePacket(pkt *Packet) error {
 			return errors.New("cannot use open tracing frames without enabling the feature")
 		}
 
-		traceCtxLen := len(pkt.OpenTracingFrame.TraceContext)
-		writeFrameHeader(buffer, frameTypeReqOpenTracing, uint8(traceCtxLen))
-		buffer.Write(pkt.OpenTracingFrame.TraceContext)
+		if err := frames.Add(frameTypeReqOpenTracing, pkt.OpenTracingFrame.TraceContext); err != nil {
+			return err
+		}
 	}
 
 	if pkt.ServerDurationFrame != nil {
@@ -273,8 +186,10 @@ func (c *Conn) WritePacket(pkt *Packet) error {
 			return errors.New("cannot use server duration frames without enabling the feature")
 		}
 
-		writeFrameHeader(buffer, frameTypeResSrvDuration, 2)
-		writeUint16(buffer, EncodeSrvDura16(pkt.ServerDurationFrame.ServerDuration))
+		body := appendUint16(nil, EncodeSrvDura16(pkt.ServerDurationFrame.ServerDuration))
+		if err := frames.Add(frameTypeResSrvDuration, body); err != nil {
+			return err
+		}
 	}
 
 	if pkt.UserImpersonationFrame != nil {
@@ -282,9 +197,9 @@ func (c *Conn) WritePacket(pkt *Packet) error {
 			return errors.New("cannot use user impersonation frame in non-request packets")
 		}
 
-		userCtxLen := len(pkt.UserImpersonationFrame.User)
-		writeFrameHeader(buffer, frameTypeReqUserImpersonation, uint8(userCtxLen))
-		buffer.Write(pkt.UserImpersonationFrame.User)
+		if err := frames.Add(frameTypeReqUserImpersonation, pkt.UserImpersonationFrame.User); err != nil {
+			return err
+		}
 	}
 
 	if pkt.PreserveExpiryFrame != nil {
@@ -296,16 +211,83 @@ func (c *Conn) WritePacket(pkt *Packet) error {
 			return errors.New("cannot use preserve expiry frames without enabling the feature")
 		}
 
-		writeFrameHeader(buffer, frameTypeReqPreserveExpiry, 0)
+		if err := frames.Add(frameTypeReqPreserveExpiry, nil); err != nil {
+			return err
+		}
 	}
 
 	// Any frames that we don't support we'll just write to the packet, and assume that
 	// the user knows what they're doing re: encoding.
 	for _, fr := range pkt.UnsupportedFrames {
-		writeFrameHeader(buffer, fr.Type, uint8(len(fr.Data)))
-		buffer.Write(fr.Data)
+		if err := frames.Add(fr.Type, fr.Data); err != nil {
+			return err
+		}
+	}
+
+	extLen := len(extras)
+	keyLen := len(encodedKey)
+	valLen := len(pkt.Value)
+	framesLen := frames.Len()
+
+	// We automatically upgrade a packet from normal Req or Res magic into
+	// the frame variant depending on the usage of them.
+	pktMagic := pkt.Magic
+	if framesLen > 0 {
+		switch pktMagic {
+		case CmdMagicReq:
+			if !c.IsFeatureEnabled(FeatureAltRequests) {
+				return errors.New("cannot use frames in req packets without enabling the feature")
+			}
+
+			pktMagic = cmdMagicReqExt
+		case CmdMagicRes:
+			pktMagic = cmdMagicResExt
+		default:
+			return errors.New("cannot use frames with an unsupported magic")
+		}
+	}
+
+	buffer := aquireWriteBuf()
+	defer releaseWriteBuf(buffer)
+
+	buffer.WriteByte(byte(pktMagic))
+	buffer.WriteByte(byte(pkt.Command))
+
+	// This is safe to do without checking the magic as we check the magic
+	// above before incrementing the framesLen variable
+	if framesLen > 0 {
+		buffer.WriteByte(byte(framesLen))
+		buffer.WriteByte(byte(keyLen))
+	} else {
+		writeUint16(buffer, uint16(keyLen))
 	}
 
+	buffer.WriteByte(byte(extLen))
+	buffer.WriteByte(pkt.Datatype)
+
+	switch pkt.Magic {
+	case CmdMagicReq:
+		if pkt.Status != 0 {
+			return errors.New("cannot specify status in a request packet")
+		}
+
+		writeUint16(buffer, pkt.Vbucket)
+	case CmdMagicRes:
+		if pkt.Vbucket != 0 {
+			return errors.New("cannot specify vbucket in a response packet")
+		}
+
+		writeUint16(buffer, uint16(pkt.Status))
+	default:
+		return errors.New("cannot encode status/vbucket for unknown packet magic")
+	}
+
+	writeUint32(buffer, uint32(keyLen+extLen+valLen+framesLen))
+	writeUint32(buffer, pkt.Opaque)
+	writeUint64(buffer, pkt.Cas)
+
+	buffer.Write(frames.Bytes())
+
 	// Copy the extras into the body of the packet
 	buffer.Write(extras)
 
@@ -327,6 +309,21 @@ func (c *Conn) WritePacket(pkt *Packet) error {
 	return nil
 }
 
+// ErrUnexpectedTLSRecord is returned by ReadPacket when the data received is a TLS record rather than a memcached
+// packet, this happens when a plaintext connection is made to a port which is expecting TLS.
+var ErrUnexpectedTLSRecord = errors.New("received a TLS record on a plaintext connection")
+
+// looksLikeTLSRecord returns whether the start of a header is a TLS handshake or alert record, which is what a TLS
+// server will respond with when it receives a memcached packet.
+func looksLikeTLSRecord(header []byte) bool {
+	if len(header) < 2 {
+		return false
+	}
+
+	// TLS records begin with the content type followed by the major version, which is always 3.
+	return (header[0] == 0x15 || header[0] == 0x16) && header[1] == 0x03
+}
+
 // ReadPacket reads a packet from the network.
 func (c *Conn) ReadPacket() (*Packet, int, error) {
 	pkt := AcquirePacket()
@@ -336,11 +333,19 @@ func (c *Conn) ReadPacket() (*Packet, int, error) {
 	}
 
 	// Read the entire 24-byte header first
-	_, err := io.ReadFull(c.stream, c.headerBuf[:])
+	n, err := io.ReadFull(c.stream, c.headerBuf[:])
 	if err != nil {
+		// A TLS alert is shorter than a memcached header so we need to check for it before giving up on a short read.
+		if looksLikeTLSRecord(c.headerBuf[:n]) {
+			return nil, 0, ErrUnexpectedTLSRecord
+		}
 		return nil, 0, err
 	}
 
+	if looksLikeTLSRecord(c.headerBuf[:]) {
+		return nil, 0, ErrUnexpectedTLSRecord
+	}
+
 	// Grab the length of the full body
 	bodyLen := binary.BigEndian.Uint32(c.headerBuf[8:])
 
@@ -526,24 +531,3 @@ func writeUint64(buffer *bytes.Buffer, n uint64) {
 	buffer.WriteByte(byte(n >> 8))
 	buffer.WriteByte(byte(n))
 }
-
-// writeFrameHeader - Write a single byte containing information about the following frame directly into the provided
-// buffer.
-func writeFrameHeader(buffer *bytes.Buffer, frameType frameType, frameLen uint8) {
-	if frameLen < 15 {
-		buffer.WriteByte(uint8(frameType)<<4 | frameLen)
-		return
-	}
-
-	buffer.WriteByte(uint8(frameType)<<4 | 15)
-	buffer.WriteByte(frameLen - 15)
-}
-
-// calcHeaderSize calculates the correct length header for a frame of variable size.
-func calcHeaderSize(frameLen int) int {
-	if frameLen < 15 {
-		return 1 + frameLen
-	}
-
-	return 2 + frameLen
-}
@@ -2,6 +2,7 @@ package memd
 
 import (
 	"bytes"
+	"errors"
 	"reflect"
 	"testing"
 	"time"
@@ -215,3 +216,97 @@ func TestPktUnsupportedFrameResExt(t *testing.T) {
 		},
 	}, allFeatures)
 }
+
+func TestPktRtLongUserImpersonationReqExt(t *testing.T) {
+	testPktRoundTrip(t, &Packet{
+		Magic:    CmdMagicReq,
+		Command:  CmdSet,
+		Datatype: 0x22,
+		Vbucket:  0x9f9e,
+		Opaque:   0x87654321,
+		Key:      []byte("Hello"),
+		Extras:   []byte("I am some data which is longer?"),
+		Value:    []byte("World"),
+		DurabilityLevelFrame: &DurabilityLevelFrame{
+			DurabilityLevel: DurabilityLevelMajority,
+		},
+		UserImpersonationFrame: &UserImpersonationFrame{
+			User: bytes.Repeat([]byte("u"), 200),
+		},
+		PreserveExpiryFrame: &PreserveExpiryFrame{},
+	}, allFeatures)
+}
+
+func TestPktUnsupportedFrameEscapedTypeReqExt(t *testing.T) {
+	testPktRoundTrip(t, &Packet{
+		Magic:    CmdMagicReq,
+		Command:  CmdGAT,
+		Datatype: 0x22,
+		Vbucket:  0x9f9e,
+		Opaque:   0x87654321,
+		Key:      []byte("Hello"),
+		Extras:   []byte("I am some data which is longer?"),
+		Value:    []byte("World"),
+		UnsupportedFrames: []UnsupportedFrame{
+			{
+				Type: 15,
+				Data: []byte("barry"),
+			},
+			{
+				Type: 42,
+				Data: []byte("barrysothermatewithareallylongname"),
+			},
+		},
+	}, allFeatures)
+}
+
+func TestWritePacketFramesTooLarge(t *testing.T) {
+	conn := NewConn(&bytes.Buffer{})
+	for _, feature := range allFeatures {
+		conn.EnableFeature(feature)
+	}
+
+	err := conn.WritePacket(&Packet{
+		Magic:   CmdMagicReq,
+		Command: CmdSet,
+		Key:     []byte("Hello"),
+		Value:   []byte("World"),
+		OpenTracingFrame: &OpenTracingFrame{
+			TraceContext: bytes.Repeat([]byte("t"), 100),
+		},
+		UserImpersonationFrame: &UserImpersonationFrame{
+			User: bytes.Repeat([]byte("u"), 200),
+		},
+	})
+	if err == nil {
+		t.Fatalf("expected writing a packet with more than 255 bytes of frames to fail")
+	}
+}
+
+func TestReadPacketTLSRecord(t *testing.T) {
+	// A TLS alert record is shorter than a memcached header.
+	alert := []byte{0x15, 0x03, 0x03, 0x00, 0x02, 0x02, 0x28}
+	_, _, err := NewConn(bytes.NewBuffer(alert)).ReadPacket()
+	if !errors.Is(err, ErrUnexpectedTLSRecord) {
+		t.Fatalf("expected unexpected TLS record error for alert, got %v", err)
+	}
+
+	handshake := make([]byte, 64)
+	copy(handshake, []byte{0x16, 0x03, 0x03, 0x00, 0x3b})
+	_, _, err = NewConn(bytes.NewBuffer(handshake)).ReadPacket()
+	if !errors.Is(err, ErrUnexpectedTLSRecord) {
+		t.Fatalf("expected unexpected TLS record error for handshake, got %v", err)
+	}
+}
+
+func TestHelloFeatureString(t *testing.T) {
+	if FeatureSnappy.String() != "Snappy" {
+		t.Fatalf("unexpected feature name %s", FeatureSnappy.String())
+	}
+	if FeatureSnappyEverywhere.String() != "SnappyEverywhere" {
+		t.Fatalf("unexpected feature name %s", FeatureSnappyEverywhere.String())
+	}
+	if HelloFeature(0xff).String() != "0xff" {
+		t.Fatalf("unexpected unknown feature name %s", HelloFeature(0xff).String())
+	}
+}
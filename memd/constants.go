@@ -132,6 +132,68 @@ const (
 	FeatureClustermapChangeNotificationBrief = HelloFeature(0x1f)
 )
 
+// String returns the name of the feature, or its hex code if the feature is unknown.
+func (feature HelloFeature) String() string {
+	switch feature {
+	case FeatureDatatype:
+		return "Datatype"
+	case FeatureTLS:
+		return "TLS"
+	case FeatureTCPNoDelay:
+		return "TCPNoDelay"
+	case FeatureSeqNo:
+		return "SeqNo"
+	case FeatureTCPDelay:
+		return "TCPDelay"
+	case FeatureXattr:
+		return "Xattr"
+	case FeatureXerror:
+		return "Xerror"
+	case FeatureSelectBucket:
+		return "SelectBucket"
+	case FeatureSnappy:
+		return "Snappy"
+	case FeatureJSON:
+		return "JSON"
+	case FeatureDuplex:
+		return "Duplex"
+	case FeatureClusterMapNotif:
+		return "ClusterMapNotif"
+	case FeatureUnorderedExec:
+		return "UnorderedExec"
+	case FeatureDurations:
+		return "Durations"
+	case FeatureAltRequests:
+		return "AltRequests"
+	case FeatureSyncReplication:
+		return "SyncReplication"
+	case FeatureCollections:
+		return "Collections"
+	case FeatureSnappyEverywhere:
+		return "SnappyEverywhere"
+	case FeaturePreserveExpiry:
+		return "PreserveExpiry"
+	case FeaturePITR:
+		return "PITR"
+	case FeatureCreateAsDeleted:
+		return "CreateAsDeleted"
+	case FeatureReplaceBodyWithXattr:
+		return "ReplaceBodyWithXattr"
+	case FeatureResourceUnits:
+		return "ResourceUnits"
+	case FeatureSubdocReplicaRead:
+		return "SubdocReplicaRead"
+	case FeatureDedupeNotMyVbucketClustermap:
+		return "DedupeNotMyVbucketClustermap"
+	case FeatureClusterMapKnownVersion:
+		return "ClusterMapKnownVersion"
+	case FeatureClustermapChangeNotificationBrief:
+		return "ClustermapChangeNotificationBrief"
+	}
+
+	return fmt.Sprintf("0x%02x", uint16(feature))
+}
+
 // StreamEndStatus represents the reason for a DCP stream ending
 type StreamEndStatus uint32
 
@@ -392,6 +454,11 @@ const (
 	// SubdocFlagExpandMacros indicates that the value portion of any sub-document mutations
 	// should be expanded if they contain macros such as ${Mutation.CAS}.
 	SubdocFlagExpandMacros = SubdocFlag(0x10)
+
+	// SubdocFlagBinaryValue indicates that the value of an xattr is binary rather than JSON, mutations will not
+	// validate the value as JSON and lookups will return it as it is stored. It must be used with
+	// SubdocFlagXattrPath.
+	SubdocFlagBinaryValue = SubdocFlag(0x20)
 )
 
 // SubdocDocFlag specifies document-level flags for a sub-document operation.
@@ -0,0 +1,69 @@
+package memd
+
+import (
+	"errors"
+)
+
+// maxFramesLen is the maximum total size of the framing extras in a packet, as the length is encoded in a single byte.
+const maxFramesLen = 255
+
+// frameBuilder encodes the framing extras for a packet. Frames can be added in any combination, with the type and
+// length escapes used for frame types and lengths which do not fit into the 4 bits available in the frame header.
+type frameBuilder struct {
+	buf []byte
+}
+
+// Add appends a frame of the given type, returning an error if the frame would make the framing extras too large.
+func (fb *frameBuilder) Add(frType frameType, body []byte) error {
+	if len(body) > 15+255 {
+		return errors.New("frame is too large to be encoded")
+	}
+
+	headerLen := 1
+	if frType >= 15 {
+		headerLen++
+	}
+	if len(body) >= 15 {
+		headerLen++
+	}
+	if len(fb.buf)+headerLen+len(body) > maxFramesLen {
+		return errors.New("framing extras are too large to be encoded")
+	}
+
+	header := byte(0)
+	if frType < 15 {
+		header |= byte(frType) << 4
+	} else {
+		header |= 15 << 4
+	}
+	if len(body) < 15 {
+		header |= byte(len(body))
+	} else {
+		header |= 15
+	}
+	fb.buf = append(fb.buf, header)
+
+	if frType >= 15 {
+		fb.buf = append(fb.buf, byte(frType-15))
+	}
+	if len(body) >= 15 {
+		fb.buf = append(fb.buf, byte(len(body)-15))
+	}
+	fb.buf = append(fb.buf, body...)
+
+	return nil
+}
+
+// Len returns the total size of the encoded frames.
+func (fb *frameBuilder) Len() int {
+	return len(fb.buf)
+}
+
+// Bytes returns the encoded frames.
+func (fb *frameBuilder) Bytes() []byte {
+	return fb.buf
+}
+
+func appendUint16(buf []byte, val uint16) []byte {
+	return append(buf, byte(val>>8), byte(val))
+}
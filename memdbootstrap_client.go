@@ -15,6 +15,8 @@ type bootstrapableClient interface {
 	ConnID() string
 	SupportsFeature(feature memd.HelloFeature) bool
 	Features([]memd.HelloFeature)
+	SetDeniedFeatures([]memd.HelloFeature)
+	SetAuthMechanism(mech AuthMechanism)
 	loggerID() string
 }
 
@@ -22,6 +24,8 @@ type bootstrapClient interface {
 	Address() string
 	ConnID() string
 	Features(features []memd.HelloFeature)
+	SetDeniedFeatures(features []memd.HelloFeature)
+	SetAuthMechanism(mech AuthMechanism)
 	SupportsFeature(feature memd.HelloFeature) bool
 	SaslAuth(k, v []byte, deadline time.Time, cb func(b []byte, err error)) error
 	SaslStep(k, v []byte, deadline time.Time, cb func(err error)) error
@@ -31,6 +35,7 @@ type bootstrapClient interface {
 	ExecHello(clientID string, features []memd.HelloFeature, deadline time.Time) (chan ExecHelloResponse, error)
 	ExecGetConfig(deadline time.Time) (chan getConfigResponse, error)
 	LoggerID() string
+	CancelSig() <-chan struct{}
 }
 
 // Due to AuthProvider we are currently tied to bootstrapping passing around a deadline and the bootstrap
@@ -62,6 +67,14 @@ func (bc *memdBootstrapClient) Features(features []memd.HelloFeature) {
 	bc.client.Features(features)
 }
 
+func (bc *memdBootstrapClient) SetDeniedFeatures(features []memd.HelloFeature) {
+	bc.client.SetDeniedFeatures(features)
+}
+
+func (bc *memdBootstrapClient) SetAuthMechanism(mech AuthMechanism) {
+	bc.client.SetAuthMechanism(mech)
+}
+
 func (bc *memdBootstrapClient) SupportsFeature(feature memd.HelloFeature) bool {
 	return bc.client.SupportsFeature(feature)
 }
@@ -70,6 +83,11 @@ func (client *memdBootstrapClient) LoggerID() string {
 	return client.client.loggerID()
 }
 
+// CancelSig returns the channel which is closed when the bootstrap is cancelled.
+func (bc *memdBootstrapClient) CancelSig() <-chan struct{} {
+	return bc.cancelSig
+}
+
 func (bc *memdBootstrapClient) SaslAuth(k, v []byte, deadline time.Time, cb func(b []byte, err error)) error {
 	err := bc.doBootstrapRequest(
 		&memdQRequest{
@@ -2,6 +2,7 @@ package gocbcore
 
 import (
 	"encoding/binary"
+	"errors"
 	"fmt"
 	"io"
 	"sync"
@@ -14,6 +15,9 @@ import (
 	"github.com/golang/snappy"
 )
 
+// isCompressibleOp returns whether the value of a command may be compressed. Append and prepend values are never
+// compressed, they are fragments which the server concatenates with the existing value and a compressed fragment
+// would corrupt the document.
 func isCompressibleOp(command memd.CmdCode) bool {
 	switch command {
 	case memd.CmdSet:
@@ -21,17 +25,13 @@ func isCompressibleOp(command memd.CmdCode) bool {
 	case memd.CmdAdd:
 		fallthrough
 	case memd.CmdReplace:
-		fallthrough
-	case memd.CmdAppend:
-		fallthrough
-	case memd.CmdPrepend:
 		return true
 	}
 	return false
 }
 
 type postCompleteErrorHandler func(resp *memdQResponse, req *memdQRequest, err error) (bool, error)
-type serverRequestHandler func(pak *memd.Packet)
+type serverRequestHandler func(resp *memdQResponse)
 
 type memdClient struct {
 	lastActivity          int64
@@ -45,6 +45,9 @@ type memdClient struct {
 	conn                  memdConn
 	opList                *memdOpMap
 	features              []memd.HelloFeature
+	deniedFeatures        []memd.HelloFeature
+	authMechanism         AuthMechanism
+	closeErr              error
 	lock                  sync.Mutex
 	streamEndNotSupported bool
 	breaker               circuitBreaker
@@ -66,7 +69,9 @@ type memdClient struct {
 
 	compressionMinSize   int
 	compressionMinRatio  float64
+	compressionTracker   *compressionRatioTracker
 	disableDecompression bool
+	rateLimiter          *kvEndpointRateLimiter
 
 	gracefulCloseTriggered uint32
 }
@@ -83,7 +88,9 @@ type memdClientProps struct {
 	DCPQueueSize         int
 	CompressionMinSize   int
 	CompressionMinRatio  float64
+	CompressionTracker   *compressionRatioTracker
 	DisableDecompression bool
+	RateLimiter          *kvEndpointRateLimiter
 }
 
 func newMemdClient(props memdClientProps, conn memdConn, breakerCfg CircuitBreakerConfig, postErrHandler postCompleteErrorHandler,
@@ -103,11 +110,13 @@ func newMemdClient(props memdClientProps, conn memdConn, breakerCfg CircuitBreak
 		dcpQueueSize:         props.DCPQueueSize,
 		compressionMinRatio:  props.CompressionMinRatio,
 		compressionMinSize:   props.CompressionMinSize,
+		compressionTracker:   props.CompressionTracker,
 		disableDecompression: props.DisableDecompression,
+		rateLimiter:          props.RateLimiter,
 	}
 
 	if breakerCfg.Enabled {
-		client.breaker = newLazyCircuitBreaker(breakerCfg, client.sendCanary)
+		client.breaker = newLazyCircuitBreaker(breakerCfg, MemdService, conn.RemoteAddr(), client.sendCanary)
 	} else {
 		client.breaker = newNoopCircuitBreaker()
 	}
@@ -129,6 +138,26 @@ func (client *memdClient) Features(features []memd.HelloFeature) {
 	}
 }
 
+// SetDeniedFeatures must be set from a context where no racey behaviours can occur, i.e. during bootstrap.
+func (client *memdClient) SetDeniedFeatures(features []memd.HelloFeature) {
+	client.deniedFeatures = features
+}
+
+// DeniedFeatures returns the features which were requested during HELLO but which the server did not enable.
+func (client *memdClient) DeniedFeatures() []memd.HelloFeature {
+	return client.deniedFeatures
+}
+
+// SetAuthMechanism must be set from a context where no racey behaviours can occur, i.e. during bootstrap.
+func (client *memdClient) SetAuthMechanism(mech AuthMechanism) {
+	client.authMechanism = mech
+}
+
+// AuthMechanism returns the SASL mechanism which was used to authenticate the connection, if any.
+func (client *memdClient) AuthMechanism() AuthMechanism {
+	return client.authMechanism
+}
+
 func (client *memdClient) EnableDcpBufferAck(bufferAckSize int) {
 	client.dcpAckSize = bufferAckSize
 }
@@ -215,6 +244,7 @@ func (client *memdClient) CancelRequest(req *memdQRequest, err error) bool {
 	removed := client.opList.Remove(req)
 	if removed {
 		atomic.CompareAndSwapPointer(&req.waitingIn, unsafe.Pointer(client), nil)
+		client.rateLimiter.Release(req)
 	}
 
 	if client.breaker.CompletionCallback(err) {
@@ -235,25 +265,48 @@ func (client *memdClient) SendRequest(req *memdQRequest) error {
 		return nil
 	}
 
+	if retryAfter, ok := client.rateLimiter.Admit(req); !ok {
+		logSchedf("Rate limiter rejecting request. %s to %s OP=0x%x. Opaque=%d", client.conn.LocalAddr(), client.Address(), req.Command, req.Opaque)
+
+		// Send this request upwards so that the retry strategy can decide whether to back off and try again.
+		shortCircuited, routeErr := client.postErrHandler(nil, req, &kvRateLimitedError{retryAfter: retryAfter})
+		if !shortCircuited {
+			req.tryCallback(nil, routeErr)
+		}
+
+		return nil
+	}
+
 	return client.internalSendRequest(req)
 }
 
 func (client *memdClient) internalSendRequest(req *memdQRequest) error {
 	if err := client.takeRequestOwnership(req); err != nil {
+		client.rateLimiter.Release(req)
 		return err
 	}
 
+	// The request may be retried, and so dispatched again, after this so req.Packet must never be modified here.
+	// Anything which changes how the packet is sent on this particular connection, such as compression, must be
+	// applied to a copy.
 	packet := &req.Packet
 	if client.SupportsFeature(memd.FeatureSnappy) {
 		isCompressed := (packet.Datatype & uint8(memd.DatatypeFlagCompressed)) != 0
 		packetSize := len(packet.Value)
-		if !isCompressed && packetSize > client.compressionMinSize && isCompressibleOp(packet.Command) {
+		if !isCompressed && packetSize > client.compressionMinSize && isCompressibleOp(packet.Command) &&
+			client.compressionTracker.ShouldCompress(packet.CollectionID) {
 			compressedValue := snappy.Encode(nil, packet.Value)
-			if float64(len(compressedValue))/float64(packetSize) <= client.compressionMinRatio {
+			ratio := float64(len(compressedValue)) / float64(packetSize)
+			client.compressionTracker.Record(packet.CollectionID, ratio)
+			if ratio <= client.compressionMinRatio {
 				newPacket := *packet
 				newPacket.Value = compressedValue
 				newPacket.Datatype = newPacket.Datatype | uint8(memd.DatatypeFlagCompressed)
 				packet = &newPacket
+
+				client.tracer.CompressionSavedRecord(req.Command.Name(), packetSize-len(compressedValue))
+			} else {
+				client.tracer.CompressionWastedRecord(req.Command.Name(), packetSize)
 			}
 		}
 	}
@@ -289,7 +342,7 @@ func (client *memdClient) resolveRequest(resp *memdQResponse) {
 
 	if resp.Magic == memd.CmdMagicServerReq {
 		logSchedf("Handling server request data on %s. OP=0x%x", client.loggerID(), resp.Command)
-		client.serverRequestHandler(resp.Packet)
+		client.serverRequestHandler(resp)
 		return
 	}
 
@@ -332,6 +385,7 @@ func (client *memdClient) resolveRequest(resp *memdQResponse) {
 
 	if !req.Persistent || stClass == statusClassError {
 		atomic.CompareAndSwapPointer(&req.waitingIn, unsafe.Pointer(client), nil)
+		client.rateLimiter.Release(req)
 	}
 
 	req.processingLock.Lock()
@@ -342,6 +396,14 @@ func (client *memdClient) resolveRequest(resp *memdQResponse) {
 		stopNetTraceLocked(req, resp, client.conn.LocalAddr(), client.conn.RemoteAddr())
 	}
 
+	if resp.ServerDurationFrame != nil {
+		client.tracer.ServerDurationValueRecord(metricValueServiceKeyValue, req.Command.Name(), resp.ServerDurationFrame.ServerDuration)
+		if !req.Persistent {
+			client.tracer.DispatchOverheadValueRecord(client.Address(), req.queuedTime,
+				resp.ServerDurationFrame.ServerDuration)
+		}
+	}
+
 	isCompressed := (resp.Datatype & uint8(memd.DatatypeFlagCompressed)) != 0
 	// We always want to decompress cluster configs if they've been compressed.
 	alwaysDecompress := req.Command == memd.CmdGetClusterConfig || resp.Status == memd.StatusNotMyVBucket
@@ -424,12 +486,25 @@ func (client *memdClient) run() {
 	}()
 
 	go func() {
+		// closeErr is the error that any requests still in flight when the connection closes will fail with.
+		var closeErr error = io.EOF
 		for {
 			packet, n, err := client.conn.ReadPacket()
 			if err != nil {
+				if errors.Is(err, memd.ErrUnexpectedTLSRecord) {
+					closeErr = wrapError(errEncryptionMismatch, fmt.Sprintf("received TLS response on plaintext "+
+						"connection to %s; check that the non-TLS port (11210 by default) is being used rather than the "+
+						"TLS port (11207), or enable TLS", client.conn.RemoteAddr()))
+				}
+
 				client.lock.Lock()
 				if !client.closed {
 					logWarnf("%p memdClient read failure on conn `%v` : %v", client, client.connID, err)
+					if closeErr != io.EOF {
+						client.closeErr = closeErr
+					} else {
+						client.closeErr = err
+					}
 				}
 				client.lock.Unlock()
 				break
@@ -526,8 +601,9 @@ func (client *memdClient) run() {
 			if !atomic.CompareAndSwapPointer(&req.waitingIn, unsafe.Pointer(client), nil) {
 				logWarnf("Encountered an unowned request in a client (%p) opMap", client)
 			}
+			client.rateLimiter.Release(req)
 
-			shortCircuited, routeErr := client.postErrHandler(nil, req, io.EOF)
+			shortCircuited, routeErr := client.postErrHandler(nil, req, closeErr)
 			if shortCircuited {
 				return
 			}
@@ -541,6 +617,13 @@ func (client *memdClient) run() {
 	}()
 }
 
+// CloseError returns the error which caused the connection to be dropped, this is nil if the client was closed by us.
+func (client *memdClient) CloseError() error {
+	client.lock.Lock()
+	defer client.lock.Unlock()
+	return client.closeErr
+}
+
 func (client *memdClient) LocalAddress() string {
 	return client.conn.LocalAddr()
 }
@@ -0,0 +1,443 @@
+package gocbcore
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/couchbase/gocbcore/v10/memd"
+)
+
+type testRecordingRetryStrategy struct {
+	reasons     []RetryReason
+	retryAfters []time.Duration
+}
+
+func (rs *testRecordingRetryStrategy) RetryAfter(req RetryRequest, reason RetryReason) RetryAction {
+	rs.reasons = append(rs.reasons, reason)
+	rs.retryAfters = append(rs.retryAfters, serverRetryAfter(req))
+	return &NoRetryRetryAction{}
+}
+
+func (suite *UnitTestSuite) TestMemdClientServerDurationFrame() {
+	meter := newTestMeter()
+	tracer := newTracerComponent(&noopTracer{}, "", true, meter, nil)
+
+	server := newTestMemdServer()
+	server.SetServerDuration(1500 * time.Microsecond)
+	server.SetHandler(memd.CmdGet, func(req *memd.Packet, resp *memd.Packet) {
+		resp.Value = []byte(`{"foo":"bar"}`)
+	})
+
+	client := newTestMemdServerClient(server, nil, tracer)
+	defer client.Close()
+
+	waitCh := make(chan error, 1)
+	err := client.SendRequest(&memdQRequest{
+		Packet: memd.Packet{
+			Magic:   memd.CmdMagicReq,
+			Command: memd.CmdGet,
+			Key:     []byte("key"),
+		},
+		Callback: func(resp *memdQResponse, req *memdQRequest, err error) {
+			waitCh <- err
+		},
+	})
+	suite.Require().Nil(err, err)
+	suite.Require().Nil(<-waitCh)
+
+	recorder := meter.recorders[meterNameCBServerDurations+":"+makeMetricsKey(metricValueServiceKeyValue, memd.CmdGet.Name())]
+	suite.Require().NotNil(recorder)
+	suite.Assert().Equal([]uint64{1500}, recorder.values)
+}
+
+func (suite *UnitTestSuite) TestMemdClientErrorMapRetry() {
+	errMap := []byte(`{
+		"version": 2,
+		"revision": 1,
+		"errors": {
+			"7ff0": {
+				"name": "TEST_RETRY",
+				"desc": "An error which should be retried",
+				"attrs": ["temp", "retry-now"]
+			},
+			"7ff1": {
+				"name": "TEST_NO_RETRY",
+				"desc": "An error which should not be retried",
+				"attrs": ["internal"]
+			}
+		}
+	}`)
+
+	meter := newTestMeter()
+	tracer := newTracerComponent(&noopTracer{}, "", true, meter, nil)
+	errMgr := newErrMapManager("default")
+	mux := &kvMux{
+		errMapMgr: errMgr,
+		tracer:    tracer,
+	}
+
+	server := newTestMemdServer()
+	server.SetErrorMap(errMap)
+
+	client := newTestMemdServerClient(server, mux.handleOpRoutingResp, tracer)
+	defer client.Close()
+
+	cancelSig := make(chan struct{})
+	defer close(cancelSig)
+	errMapCh, err := newMemdBootstrapClient(client, cancelSig).ExecGetErrorMap(2, time.Now().Add(time.Second))
+	suite.Require().Nil(err, err)
+
+	errMapResp := <-errMapCh
+	suite.Require().Nil(errMapResp.Err, errMapResp.Err)
+	errMgr.StoreErrorMap(errMapResp.Bytes)
+
+	sendGet := func(status memd.StatusCode) (*testRecordingRetryStrategy, error) {
+		server.SetHandler(memd.CmdGet, func(req *memd.Packet, resp *memd.Packet) {
+			resp.Status = status
+		})
+
+		strategy := &testRecordingRetryStrategy{}
+		waitCh := make(chan error, 1)
+		err := client.SendRequest(&memdQRequest{
+			Packet: memd.Packet{
+				Magic:   memd.CmdMagicReq,
+				Command: memd.CmdGet,
+				Key:     []byte("key"),
+			},
+			RetryStrategy: strategy,
+			Callback: func(resp *memdQResponse, req *memdQRequest, err error) {
+				waitCh <- err
+			},
+		})
+		suite.Require().Nil(err, err)
+
+		return strategy, <-waitCh
+	}
+
+	strategy, err := sendGet(memd.StatusCode(0x7ff0))
+	suite.Require().NotNil(err)
+	suite.Assert().Equal([]RetryReason{KVErrMapRetryReason}, strategy.reasons)
+
+	strategy, err = sendGet(memd.StatusCode(0x7ff1))
+	suite.Require().NotNil(err)
+	suite.Assert().Empty(strategy.reasons)
+}
+
+func (suite *UnitTestSuite) TestMemdClientOutOfOrderResponses() {
+	tracer := newTracerComponent(&noopTracer{}, "", true, &noopMeter{}, nil)
+
+	server := newTestMemdServer()
+	server.SetHandler(memd.CmdGet, func(req *memd.Packet, resp *memd.Packet) {
+		resp.Value = req.Key
+	})
+	server.HoldResponses(3)
+
+	client := newTestMemdServerClient(server, nil, tracer)
+	defer client.Close()
+	client.Features([]memd.HelloFeature{memd.FeatureUnorderedExec})
+
+	type result struct {
+		key   string
+		value string
+		err   error
+	}
+	resultCh := make(chan result, 3)
+	keys := []string{"key1", "key2", "key3"}
+	for _, key := range keys {
+		err := client.SendRequest(&memdQRequest{
+			Packet: memd.Packet{
+				Magic:   memd.CmdMagicReq,
+				Command: memd.CmdGet,
+				Key:     []byte(key),
+			},
+			Callback: func(resp *memdQResponse, req *memdQRequest, err error) {
+				if err != nil {
+					resultCh <- result{key: string(req.Key), err: err}
+					return
+				}
+				resultCh <- result{key: string(req.Key), value: string(resp.Value)}
+			},
+		})
+		suite.Require().Nil(err, err)
+	}
+
+	var order []string
+	for range keys {
+		res := <-resultCh
+		suite.Require().Nil(res.err, res.err)
+		suite.Assert().Equal(res.key, res.value)
+		order = append(order, res.key)
+	}
+	suite.Assert().Equal([]string{"key3", "key2", "key1"}, order)
+}
+
+func (suite *UnitTestSuite) TestMemdClientRetriedRequestEncodedIdentically() {
+	tracer := newTracerComponent(&noopTracer{}, "", true, &noopMeter{}, nil)
+	features := []memd.HelloFeature{memd.FeatureSnappy, memd.FeatureAltRequests, memd.FeatureSyncReplication,
+		memd.FeatureCollections}
+
+	// encode returns the bytes written to the network for a packet, ignoring the opaque which changes per dispatch.
+	encode := func(pkt *memd.Packet) []byte {
+		pktCopy := *pkt
+		pktCopy.Opaque = 0
+
+		buf := &bytes.Buffer{}
+		conn := memd.NewConn(buf)
+		for _, feature := range features {
+			conn.EnableFeature(feature)
+		}
+		suite.Require().Nil(conn.WritePacket(&pktCopy))
+		return buf.Bytes()
+	}
+
+	var lock sync.Mutex
+	var dispatched [][]byte
+	server := newTestMemdServer()
+	server.SetHandler(memd.CmdSet, func(req *memd.Packet, resp *memd.Packet) {
+		lock.Lock()
+		dispatched = append(dispatched, encode(req))
+		if len(dispatched) == 1 {
+			resp.Status = memd.StatusNotMyVBucket
+		}
+		lock.Unlock()
+	})
+
+	var client *memdClient
+	client = newTestMemdServerClient(server, func(resp *memdQResponse, req *memdQRequest, err error) (bool, error) {
+		if resp == nil || resp.Status != memd.StatusNotMyVBucket {
+			return false, err
+		}
+
+		// Requeue the request as the kv mux would following a not my vbucket.
+		go func() {
+			if err := client.SendRequest(req); err != nil {
+				req.tryCallback(nil, err)
+			}
+		}()
+		return true, nil
+	}, tracer)
+	defer client.Close()
+	client.Features(features)
+	client.compressionMinSize = 32
+	client.compressionMinRatio = 0.83
+
+	value := bytes.Repeat([]byte(`{"foo":"bar"}`), 20)
+	req := &memdQRequest{
+		Packet: memd.Packet{
+			Magic:        memd.CmdMagicReq,
+			Command:      memd.CmdSet,
+			Key:          []byte("key"),
+			Value:        value,
+			Extras:       make([]byte, 8),
+			CollectionID: 8,
+			DurabilityLevelFrame: &memd.DurabilityLevelFrame{
+				DurabilityLevel: memd.DurabilityLevelMajority,
+			},
+			DurabilityTimeoutFrame: &memd.DurabilityTimeoutFrame{
+				DurabilityTimeout: 2500 * time.Millisecond,
+			},
+			UserImpersonationFrame: &memd.UserImpersonationFrame{
+				User: []byte("user"),
+			},
+		},
+	}
+	waitCh := make(chan error, 1)
+	req.Callback = func(resp *memdQResponse, req *memdQRequest, err error) {
+		waitCh <- err
+	}
+
+	suite.Require().Nil(client.SendRequest(req))
+	suite.Require().Nil(<-waitCh)
+
+	lock.Lock()
+	defer lock.Unlock()
+	suite.Require().Len(dispatched, 2)
+	suite.Assert().Equal(dispatched[0], dispatched[1])
+
+	// The request itself must not have been modified by dispatching it, compression is applied to a copy.
+	suite.Assert().Equal(value, req.Value)
+	suite.Assert().Zero(req.Datatype & uint8(memd.DatatypeFlagCompressed))
+	suite.Assert().NotNil(req.DurabilityLevelFrame)
+	suite.Assert().NotNil(req.DurabilityTimeoutFrame)
+
+	// Sanity check that the value was actually compressed on the wire.
+	pkt, _, err := memd.NewConn(bytes.NewBuffer(dispatched[1])).ReadPacket()
+	suite.Require().Nil(err, err)
+	suite.Assert().NotZero(pkt.Datatype & uint8(memd.DatatypeFlagCompressed))
+	suite.Assert().Equal(memd.DurabilityLevelMajority, pkt.DurabilityLevelFrame.DurabilityLevel)
+}
+
+func (suite *UnitTestSuite) TestMemdClientNeverCompressesAdjoin() {
+	tracer := newTracerComponent(&noopTracer{}, "", true, &noopMeter{}, nil)
+
+	var lock sync.Mutex
+	received := make(map[memd.CmdCode]memd.Packet)
+	server := newTestMemdServer()
+	for _, cmd := range []memd.CmdCode{memd.CmdAppend, memd.CmdPrepend} {
+		server.SetHandler(cmd, func(req *memd.Packet, resp *memd.Packet) {
+			lock.Lock()
+			received[req.Command] = *req
+			lock.Unlock()
+		})
+	}
+
+	client := newTestMemdServerClient(server, nil, tracer)
+	defer client.Close()
+	client.Features([]memd.HelloFeature{memd.FeatureSnappy, memd.FeatureAltRequests, memd.FeatureSyncReplication,
+		memd.FeatureCollections})
+	client.compressionMinSize = 32
+	client.compressionMinRatio = 0.83
+
+	value := bytes.Repeat([]byte(`{"foo":"bar"}`), 20)
+	for _, cmd := range []memd.CmdCode{memd.CmdAppend, memd.CmdPrepend} {
+		req := &memdQRequest{
+			Packet: memd.Packet{
+				Magic:        memd.CmdMagicReq,
+				Command:      cmd,
+				Key:          []byte("key"),
+				Value:        value,
+				CollectionID: 8,
+				DurabilityLevelFrame: &memd.DurabilityLevelFrame{
+					DurabilityLevel: memd.DurabilityLevelMajority,
+				},
+				DurabilityTimeoutFrame: &memd.DurabilityTimeoutFrame{
+					DurabilityTimeout: 2500 * time.Millisecond,
+				},
+			},
+		}
+		waitCh := make(chan error, 1)
+		req.Callback = func(resp *memdQResponse, req *memdQRequest, err error) {
+			waitCh <- err
+		}
+
+		suite.Require().Nil(client.SendRequest(req))
+		suite.Require().Nil(<-waitCh)
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	suite.Require().Len(received, 2)
+	for cmd, pkt := range received {
+		suite.Assert().Zero(pkt.Datatype&uint8(memd.DatatypeFlagCompressed), cmd.Name())
+		suite.Assert().Equal(value, pkt.Value, cmd.Name())
+		suite.Require().NotNil(pkt.DurabilityLevelFrame, cmd.Name())
+		suite.Assert().Equal(memd.DurabilityLevelMajority, pkt.DurabilityLevelFrame.DurabilityLevel, cmd.Name())
+	}
+}
+
+func (suite *UnitTestSuite) TestMemdClientAdaptiveCompression() {
+	meter := newTestMeter()
+	tracer := newTracerComponent(&noopTracer{}, "", true, meter, nil)
+
+	var lock sync.Mutex
+	compressedByCollection := make(map[uint32]int)
+	server := newTestMemdServer()
+	server.SetHandler(memd.CmdSet, func(req *memd.Packet, resp *memd.Packet) {
+		lock.Lock()
+		if req.Datatype&uint8(memd.DatatypeFlagCompressed) != 0 {
+			compressedByCollection[req.CollectionID]++
+		}
+		lock.Unlock()
+	})
+
+	client := newTestMemdServerClient(server, nil, tracer)
+	defer client.Close()
+	client.Features([]memd.HelloFeature{memd.FeatureSnappy, memd.FeatureCollections})
+	client.compressionMinSize = 32
+	client.compressionMinRatio = 0.83
+	client.compressionTracker = newCompressionRatioTracker(0.83)
+
+	set := func(collectionID uint32, value []byte) {
+		waitCh := make(chan error, 1)
+		err := client.SendRequest(&memdQRequest{
+			Packet: memd.Packet{
+				Magic:        memd.CmdMagicReq,
+				Command:      memd.CmdSet,
+				Key:          []byte("key"),
+				Value:        value,
+				Extras:       make([]byte, 8),
+				CollectionID: collectionID,
+			},
+			Callback: func(resp *memdQResponse, req *memdQRequest, err error) {
+				waitCh <- err
+			},
+		})
+		suite.Require().Nil(err, err)
+		suite.Require().Nil(<-waitCh)
+	}
+
+	random := make([]byte, 256)
+	rand.Read(random)
+	compressible := bytes.Repeat([]byte(`{"foo":"bar"}`), 20)
+
+	for i := 0; i < compressionRatioMinSamples+compressionRatioProbeInterval; i++ {
+		set(8, random)
+		set(9, compressible)
+	}
+
+	lock.Lock()
+	suite.Assert().Zero(compressedByCollection[8])
+	suite.Assert().Equal(compressionRatioMinSamples+compressionRatioProbeInterval, compressedByCollection[9])
+	lock.Unlock()
+
+	// Only the values compressed before the collection started being skipped, and the probe, were wasted effort.
+	wasted := meter.counters[meterNameCBCompressionBytesWasted+":"+metricValueServiceKeyValue+":"+memd.CmdSet.Name()]
+	suite.Require().NotNil(wasted)
+	suite.Assert().Equal(uint64((compressionRatioMinSamples+1)*len(random)), wasted.count)
+
+	saved := meter.counters[meterNameCBCompressionBytesSaved+":"+metricValueServiceKeyValue+":"+memd.CmdSet.Name()]
+	suite.Require().NotNil(saved)
+	suite.Assert().NotZero(saved.count)
+}
+
+func (suite *UnitTestSuite) TestMemdClientRateLimited() {
+	tracer := newTracerComponent(&noopTracer{}, "", true, &noopMeter{}, nil)
+	mux := &kvMux{
+		errMapMgr: newErrMapManager("default"),
+		tracer:    tracer,
+	}
+
+	server := newTestMemdServer()
+	server.SetHandler(memd.CmdGet, func(req *memd.Packet, resp *memd.Packet) {})
+
+	client := newTestMemdServerClient(server, mux.handleOpRoutingResp, tracer)
+	defer client.Close()
+	client.rateLimiter = newKVRateLimiter(KVRateLimitConfig{
+		OpsPerSecond: 1,
+		Burst:        1,
+	}).Endpoint("test")
+
+	sendGet := func() (*testRecordingRetryStrategy, error) {
+		strategy := &testRecordingRetryStrategy{}
+		waitCh := make(chan error, 1)
+		err := client.SendRequest(&memdQRequest{
+			Packet: memd.Packet{
+				Magic:   memd.CmdMagicReq,
+				Command: memd.CmdGet,
+				Key:     []byte("key"),
+			},
+			RetryStrategy: strategy,
+			Callback: func(resp *memdQResponse, req *memdQRequest, err error) {
+				waitCh <- err
+			},
+		})
+		suite.Require().Nil(err, err)
+
+		return strategy, <-waitCh
+	}
+
+	strategy, err := sendGet()
+	suite.Require().Nil(err, err)
+	suite.Assert().Empty(strategy.reasons)
+
+	// The burst has been used up so the next request is rejected without being written and handed to the retry
+	// strategy, along with how long until it would fit.
+	strategy, err = sendGet()
+	suite.Assert().True(errors.Is(err, ErrRateLimitedLocally), err)
+	suite.Assert().Equal([]RetryReason{ClientRateLimitedRetryReason}, strategy.reasons)
+	suite.Require().Len(strategy.retryAfters, 1)
+	suite.Assert().Greater(int64(strategy.retryAfters[0]), int64(900*time.Millisecond))
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/tls"
 	"errors"
+	"fmt"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -40,12 +41,20 @@ type memdClientDialerComponent struct {
 
 	compressionMinSize   int
 	compressionMinRatio  float64
+	compressionTracker   *compressionRatioTracker
 	disableDecompression bool
+	rateLimiter          *kvRateLimiter
 	connBufSize          uint
+	writeFlushInterval   time.Duration
+	maxWriteBatchSize    int
 
 	serverFailuresLock sync.Mutex
 	serverFailures     map[string]time.Time
 
+	// saslMechs caches the SASL mechanisms supported by each endpoint so that they aren't listed on every connect.
+	saslMechsLock sync.Mutex
+	saslMechs     map[string][]AuthMechanism
+
 	tracer       *tracerComponent
 	zombieLogger *zombieLoggerComponent
 
@@ -60,11 +69,16 @@ type memdClientDialerComponent struct {
 	configApplied uint32
 
 	noTLSSeedNode bool
+	// requireCertAuth specifies that connections authenticate using only the client certificate, skipping SASL.
+	requireCertAuth bool
+
+	bucketWarmupRetryWindow time.Duration
 
 	dcpBootstrapProps *memdBootstrapDCPProps
 	dcpQueueSize      int
 
 	cfgManager *configManagementComponent
+	connEvents *kvConnectionEventsComponent
 }
 
 type memdBootstrapDCPProps struct {
@@ -76,6 +90,7 @@ type memdBootstrapDCPProps struct {
 	backfillOrderStr             string
 	priorityStr                  string
 	streamName                   string
+	uniqueStreamNames            bool
 	openFlags                    memd.DcpOpenFlag
 	bufferSize                   int
 }
@@ -88,12 +103,22 @@ type memdClientDialerProps struct {
 	CompressionMinRatio  float64
 	DisableDecompression bool
 	NoTLSSeedNode        bool
+	RequireCertAuth      bool
 	ConnBufSize          uint
+	WriteFlushInterval   time.Duration
+	MaxWriteBatchSize    int
+	RateLimit            KVRateLimitConfig
+
+	BucketWarmupRetryWindow time.Duration
 
 	DCPBootstrapProps *memdBootstrapDCPProps
 	DCPQueueSize      int
 }
 
+// kvAuthDeadlineFraction is the portion of the remaining connect budget which SASL authentication may use, this
+// ensures that a slow auth exchange still leaves time to select the bucket and fetch a config.
+const kvAuthDeadlineFraction = 0.75
+
 type memdBoostrapFailHandler interface {
 	onBootstrapFail(error)
 }
@@ -103,7 +128,8 @@ type memdBoostrapCCCPUnsupportedHandler interface {
 }
 
 func newMemdClientDialerComponent(props memdClientDialerProps, bSettings bootstrapProps, breakerCfg CircuitBreakerConfig,
-	zLogger *zombieLoggerComponent, tracer *tracerComponent, cfgManager *configManagementComponent) *memdClientDialerComponent {
+	zLogger *zombieLoggerComponent, tracer *tracerComponent, cfgManager *configManagementComponent,
+	connEvents *kvConnectionEventsComponent) *memdClientDialerComponent {
 	dialer := &memdClientDialerComponent{
 		kvConnectTimeout:  props.KVConnectTimeout,
 		serverWaitTimeout: props.ServerWaitTimeout,
@@ -119,11 +145,19 @@ func newMemdClientDialerComponent(props memdClientDialerProps, bSettings bootstr
 		dcpQueueSize:         props.DCPQueueSize,
 		compressionMinSize:   props.CompressionMinSize,
 		compressionMinRatio:  props.CompressionMinRatio,
+		compressionTracker:   newCompressionRatioTracker(props.CompressionMinRatio),
 		disableDecompression: props.DisableDecompression,
+		rateLimiter:          newKVRateLimiter(props.RateLimit),
 		noTLSSeedNode:        props.NoTLSSeedNode,
+		requireCertAuth:      props.RequireCertAuth,
 		connBufSize:          props.ConnBufSize,
+		writeFlushInterval:   props.WriteFlushInterval,
+		maxWriteBatchSize:    props.MaxWriteBatchSize,
+
+		bucketWarmupRetryWindow: props.BucketWarmupRetryWindow,
 
 		cfgManager: cfgManager,
+		connEvents: connEvents,
 	}
 
 	cfgManager.AddConfigWatcher(dialer)
@@ -198,6 +232,8 @@ func (mcc *memdClientDialerComponent) SlowDialMemdClient(cancelSig <-chan struct
 			mcc.serverFailuresLock.Lock()
 			mcc.serverFailures[address.Address] = time.Now()
 			mcc.serverFailuresLock.Unlock()
+
+			mcc.connEvents.onIOError(address.Address, err)
 		}
 
 		return nil, err
@@ -219,6 +255,9 @@ func (mcc *memdClientDialerComponent) SlowDialMemdClient(cancelSig <-chan struct
 			mcc.serverFailures[address.Address] = time.Now()
 			mcc.serverFailuresLock.Unlock()
 		}
+		if !errors.Is(err, ErrForcedReconnect) && !errors.Is(err, ErrRequestCanceled) {
+			mcc.connEvents.onIOError(address.Address, err)
+		}
 
 		mcc.bootstrapFailHandlersLock.Lock()
 		handlers := make([]memdBoostrapFailHandler, len(mcc.bootstrapFailHandlers))
@@ -231,6 +270,8 @@ func (mcc *memdClientDialerComponent) SlowDialMemdClient(cancelSig <-chan struct
 		return nil, err
 	}
 
+	mcc.connEvents.watchClient(address.Address, client)
+
 	return client, nil
 }
 
@@ -247,6 +288,9 @@ func (mcc *memdClientDialerComponent) dialMemdClient(cancelSig <-chan struct{},
 
 		tlsConfig = srvTLSConfig
 	}
+	if tlsConfig == nil && mcc.requireCertAuth {
+		return nil, wrapError(errInvalidArgument, "certificate authentication requires TLS")
+	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 	go func() {
@@ -258,7 +302,8 @@ func (mcc *memdClientDialerComponent) dialMemdClient(cancelSig <-chan struct{},
 		}
 	}()
 
-	conn, err := dialMemdConn(ctx, address.Address, tlsConfig, deadline, mcc.connBufSize)
+	conn, err := dialMemdConn(ctx, address.Address, tlsConfig, deadline, mcc.connBufSize, mcc.writeFlushInterval,
+		mcc.maxWriteBatchSize)
 	cancel()
 	if err != nil {
 		if errors.Is(err, context.Canceled) {
@@ -278,6 +323,8 @@ func (mcc *memdClientDialerComponent) dialMemdClient(cancelSig <-chan struct{},
 			DisableDecompression: mcc.disableDecompression,
 			CompressionMinRatio:  mcc.compressionMinRatio,
 			CompressionMinSize:   mcc.compressionMinSize,
+			CompressionTracker:   mcc.compressionTracker,
+			RateLimiter:          mcc.rateLimiter.Endpoint(address.Address),
 		},
 		conn,
 		mcc.breakerCfg,
@@ -296,7 +343,14 @@ func (mcc *memdClientDialerComponent) dcpBootstrap(client *dcpBootstrapClient, d
 		return err
 	}
 
-	if err := client.ExecOpenDcpConsumer(mcc.dcpBootstrapProps.streamName, mcc.dcpBootstrapProps.openFlags, deadline); err != nil {
+	// The server closes any existing DCP connection with the same name, so when there are multiple connections to
+	// each node we need to name each of them differently.
+	streamName := mcc.dcpBootstrapProps.streamName
+	if mcc.dcpBootstrapProps.uniqueStreamNames {
+		streamName = fmt.Sprintf("%s/%s", streamName, client.ConnID())
+	}
+
+	if err := client.ExecOpenDcpConsumer(streamName, mcc.dcpBootstrapProps.openFlags, deadline); err != nil {
 		return err
 	}
 
@@ -373,22 +427,43 @@ func (mcc *memdClientDialerComponent) bootstrap(client bootstrapClient, deadline
 	var completedAuthCh chan error
 	var continueAuthCh chan bool
 
-	firstAuthMethod := mcc.buildAuthHandler(client, authProvider, deadline, authMechanisms[0])
+	authDeadline := bootstrapStepDeadline(time.Now(), deadline, kvAuthDeadlineFraction)
+
+	if _, ok := authProvider.(tokenAuthProvider); ok {
+		// Bearer tokens can only be used with OAUTHBEARER.
+		authMechanisms = []AuthMechanism{OAuthBearerAuthMechanism}
+	}
+
+	// If we've already listed the mechanisms supported by this endpoint then there's no need to list them again, and
+	// we can start with the strongest mechanism that we both support rather than discovering it through failures.
+	cachedMechs := mcc.cachedSaslMechs(client.Address())
+	if len(cachedMechs) > 0 {
+		authMechanisms = preferSupportedAuthMechanisms(authMechanisms, cachedMechs)
+	}
+
+	authMechanism := authMechanisms[0]
+	var firstAuthMethod authFunc
+	if !mcc.requireCertAuth {
+		// With certificate authentication the server has already authenticated the connection during the handshake.
+		firstAuthMethod = mcc.buildAuthHandler(client, authProvider, authDeadline, authMechanism)
+	}
 
 	if firstAuthMethod != nil {
 		// If the auth method is nil then we don't actually need to do any auth so no need to Get the mechanisms.
-		listMechsCh = make(chan SaslListMechsCompleted, 1)
-		err = client.SaslListMechs(deadline, func(mechs []AuthMechanism, err error) {
+		if len(cachedMechs) == 0 {
+			listMechsCh = make(chan SaslListMechsCompleted, 1)
+			err = client.SaslListMechs(authDeadline, func(mechs []AuthMechanism, err error) {
+				if err != nil {
+					logDebugf("Memdclient %s Failed to fetch list auth mechs (%v)", client.LoggerID(), err)
+				}
+				listMechsCh <- SaslListMechsCompleted{
+					Err:   err,
+					Mechs: mechs,
+				}
+			})
 			if err != nil {
-				logDebugf("Memdclient %s Failed to fetch list auth mechs (%v)", client.LoggerID(), err)
-			}
-			listMechsCh <- SaslListMechsCompleted{
-				Err:   err,
-				Mechs: mechs,
+				logDebugf("Memdclient %s Failed to execute list auth mechs (%v)", client.LoggerID(), err)
 			}
-		})
-		if err != nil {
-			logDebugf("Memdclient %s Failed to execute list auth mechs (%v)", client.LoggerID(), err)
 		}
 
 		completedAuthCh, continueAuthCh, err = firstAuthMethod()
@@ -442,16 +517,23 @@ func (mcc *memdClientDialerComponent) bootstrap(client bootstrapClient, deadline
 		if listMechsResp.Err == nil {
 			serverAuthMechanisms = listMechsResp.Mechs
 			logDebugf("Memdclient %s Server supported auth mechanisms: %v", client.LoggerID(), serverAuthMechanisms)
+			mcc.storeSaslMechs(client.Address(), serverAuthMechanisms)
 		} else {
 			logDebugf("Memdclient %s Failed to fetch auth mechs from server (%v)", client.LoggerID(), listMechsResp.Err)
 		}
+	} else {
+		serverAuthMechanisms = cachedMechs
 	}
 
 	// If completedAuthCh isn't nil then we have attempted to do auth so we need to wait on the result of that.
 	if completedAuthCh != nil {
-		authErr := <-completedAuthCh
+		authErr := bootstrapStepError(fmt.Sprintf("SASL authentication using %s", authMechanism), <-completedAuthCh)
 		if authErr != nil {
 			logDebugf("Memdclient %s Failed to perform auth against server (%v)", client.LoggerID(), authErr)
+			if len(cachedMechs) > 0 {
+				// The mechanisms supported by the server may have changed, so list them again next time.
+				mcc.forgetSaslMechs(client.Address())
+			}
 			if errors.Is(authErr, ErrRequestCanceled) {
 				// There's no point in us trying different mechanisms if something has cancelled bootstrapping.
 				return authErr
@@ -483,7 +565,8 @@ func (mcc *memdClientDialerComponent) bootstrap(client bootstrapClient, deadline
 				}
 
 				logDebugf("Memdclient %s Retrying authentication with found supported mechanism: %s", client.LoggerID(), mech)
-				nextAuthFunc := mcc.buildAuthHandler(client, authProvider, deadline, mech)
+				authMechanism = mech
+				nextAuthFunc := mcc.buildAuthHandler(client, authProvider, authDeadline, mech)
 				if nextAuthFunc == nil {
 					// This can't really happen but just in case it somehow does.
 					logInfof("Memdclient `%p` Failed to authenticate, no available credentials", client)
@@ -512,7 +595,7 @@ func (mcc *memdClientDialerComponent) bootstrap(client bootstrapClient, deadline
 				} else {
 					selectCh, configCh = mcc.continueAfterAuth(client, bucket, continueAuthCh, deadline)
 				}
-				authErr = <-completedAuthCh
+				authErr = bootstrapStepError(fmt.Sprintf("SASL authentication using %s", authMechanism), <-completedAuthCh)
 				if authErr == nil {
 					break
 				}
@@ -523,12 +606,17 @@ func (mcc *memdClientDialerComponent) bootstrap(client bootstrapClient, deadline
 				}
 			}
 		}
-		logDebugf("Memdclient %s Authenticated successfully", client.LoggerID())
+		logDebugf("Memdclient %s Authenticated successfully using %s", client.LoggerID(), authMechanism)
+		client.SetAuthMechanism(authMechanism)
 	}
 
 	if selectCh != nil {
 		selectErr := <-selectCh
+		if isBucketWarmupError(selectErr) {
+			selectErr = mcc.retrySelectBucketDuringWarmup(client, bucket, deadline, selectErr)
+		}
 		if selectErr != nil {
+			selectErr = bootstrapStepError("select bucket", selectErr)
 			logDebugf("Memdclient %s Failed to perform select bucket against server (%v)", client.LoggerID(), selectErr)
 			return selectErr
 		}
@@ -551,12 +639,118 @@ func (mcc *memdClientDialerComponent) bootstrap(client bootstrapClient, deadline
 
 	client.Features(helloResp.SrvFeatures)
 
+	// Features which we asked for but which weren't granted change the behaviour of the connection, for example
+	// mutation tokens not being returned, so we make sure that this is visible rather than silently downgrading.
+	// Only features which the user enabled are warned about, the SDK always asks for others, such as TLS, which
+	// servers commonly do not enable.
+	deniedFeatures := deniedHelloFeatures(features, helloResp.SrvFeatures)
+	client.SetDeniedFeatures(deniedFeatures)
+	if deniedUserFeatures := userEnabledHelloFeatures(deniedFeatures); len(deniedUserFeatures) > 0 {
+		logWarnf("Memdclient %s Server did not enable requested features %v. Requested: %v, Enabled: %v",
+			client.LoggerID(), deniedUserFeatures, features, helloResp.SrvFeatures)
+	} else if len(deniedFeatures) > 0 {
+		logDebugf("Memdclient %s Server did not enable requested features %v", client.LoggerID(), deniedFeatures)
+	}
+
 	logDebugf("Memdclient %s Client Features: %+v", client.LoggerID(), features)
 	logDebugf("Memdclient %s Server Features: %+v", client.LoggerID(), helloResp.SrvFeatures)
 
+	// Responses are always matched to requests by opaque so if the server doesn't support unordered execution then
+	// this connection simply falls back to receiving responses in the order that requests were sent.
+	if mcc.bootstrapProps.HelloProps.OutOfOrderEnabled && !client.SupportsFeature(memd.FeatureUnorderedExec) {
+		logDebugf("Memdclient %s Server did not enable unordered execution, responses will be received in order",
+			client.LoggerID())
+	}
+
 	return nil
 }
 
+// isBucketWarmupError returns whether an error from select bucket could have been caused by the bucket not yet
+// existing or still warming up. Authentication failures are not included, they are far more likely to be caused by
+// invalid credentials and retrying them would only delay reporting the failure until the deadline.
+func isBucketWarmupError(err error) bool {
+	return errors.Is(err, ErrBucketNotFound)
+}
+
+// bucketWarmupRetryRequest is used to apply the retry orchestrator to select bucket retries during bucket warmup.
+type bucketWarmupRetryRequest struct {
+	connID        string
+	retryAttempts uint32
+	retryReasons  []RetryReason
+}
+
+func (req *bucketWarmupRetryRequest) RetryAttempts() uint32 {
+	return req.retryAttempts
+}
+
+func (req *bucketWarmupRetryRequest) Identifier() string {
+	return req.connID
+}
+
+func (req *bucketWarmupRetryRequest) Idempotent() bool {
+	return true
+}
+
+func (req *bucketWarmupRetryRequest) RetryReasons() []RetryReason {
+	return req.retryReasons
+}
+
+func (req *bucketWarmupRetryRequest) retryStrategy() RetryStrategy {
+	return nil
+}
+
+func (req *bucketWarmupRetryRequest) recordRetryAttempt(reason RetryReason) {
+	req.retryAttempts++
+	for _, foundReason := range req.retryReasons {
+		if foundReason == reason {
+			return
+		}
+	}
+	req.retryReasons = append(req.retryReasons, reason)
+}
+
+// retrySelectBucketDuringWarmup retries select bucket, if enabled, until it succeeds, fails with an error which is
+// not related to bucket warmup, the bootstrap deadline is reached, the warmup retry window expires, or the bootstrap
+// is cancelled. The window starts from the first failure of this connection attempt, so each attempt gets the full
+// window.
+func (mcc *memdClientDialerComponent) retrySelectBucketDuringWarmup(client bootstrapClient, bucket string,
+	deadline time.Time, selectErr error) error {
+	if mcc.bucketWarmupRetryWindow <= 0 {
+		return selectErr
+	}
+
+	windowEnd := time.Now().Add(mcc.bucketWarmupRetryWindow)
+
+	req := &bucketWarmupRetryRequest{
+		connID: client.ConnID(),
+	}
+	for isBucketWarmupError(selectErr) {
+		_, retryTime := retryOrchMaybeRetry(req, BucketWarmupRetryReason)
+		if retryTime.After(deadline) || retryTime.After(windowEnd) {
+			logDebugf("Memdclient %s Bucket warmup retry window exhausted, failing select bucket", client.LoggerID())
+			return selectErr
+		}
+
+		// The retry is never scheduled beyond the deadline, so waiting for the timer also bounds the wait by it.
+		timer := time.NewTimer(time.Until(retryTime))
+		select {
+		case <-timer.C:
+		case <-client.CancelSig():
+			timer.Stop()
+			logDebugf("Memdclient %s Bootstrap cancelled whilst waiting to retry select bucket", client.LoggerID())
+			return errRequestCanceled
+		}
+
+		selectCh, err := client.ExecSelectBucket([]byte(bucket), deadline)
+		if err != nil {
+			return err
+		}
+		selectErr = <-selectCh
+	}
+
+	return selectErr
+}
+
 func (mcc *memdClientDialerComponent) continueAfterAuth(client bootstrapClient, bucketName string, continueAuthCh chan bool,
 	deadline time.Time) (chan error, chan getConfigResponse) {
 
@@ -618,10 +812,35 @@ func (mcc *memdClientDialerComponent) continueAfterAuth(client bootstrapClient,
 	return selectCh, configCh
 }
 
+// bootstrapStepDeadline returns the deadline for a bootstrap step which may only use a fraction of the time remaining
+// until the overall bootstrap deadline.
+func bootstrapStepDeadline(now, deadline time.Time, fraction float64) time.Time {
+	remaining := deadline.Sub(now)
+	if remaining <= 0 {
+		return deadline
+	}
+
+	return now.Add(time.Duration(float64(remaining) * fraction))
+}
+
+// bootstrapStepError adds the name of the bootstrap step to timeout errors so that it is clear which part of
+// bootstrapping took too long.
+func bootstrapStepError(step string, err error) error {
+	if err == nil || !errors.Is(err, ErrTimeout) {
+		return err
+	}
+
+	return wrapError(err, fmt.Sprintf("timed out during bootstrap %s step", step))
+}
+
 type authFunc func() (continueCh chan error, completedCb chan bool, err error)
 
 func (mcc *memdClientDialerComponent) buildAuthHandler(client bootstrapClient, auth AuthProvider, deadline time.Time,
 	mechanism AuthMechanism) authFunc {
+	if tokenAuth, ok := auth.(tokenAuthProvider); ok {
+		return buildTokenAuthHandler(client, tokenAuth, deadline)
+	}
+
 	creds, err := getKvAuthCreds(auth, client.Address())
 	if err != nil {
 		return nil
@@ -657,6 +876,44 @@ func (mcc *memdClientDialerComponent) buildAuthHandler(client bootstrapClient, a
 	return nil
 }
 
+func buildTokenAuthHandler(client AuthClient, auth tokenAuthProvider, deadline time.Time) authFunc {
+	return func() (chan error, chan bool, error) {
+		token, err := auth.BearerToken(AuthCredsRequest{
+			Service:  MemdService,
+			Endpoint: client.Address(),
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+
+		continueCh := make(chan bool, 1)
+		completedCh := make(chan error, 1)
+		callErr := SaslAuthOAuthBearer(token, client, deadline, func(err error) {
+			continueCh <- err == nil
+			completedCh <- err
+		})
+		if callErr != nil {
+			return nil, nil, callErr
+		}
+		return completedCh, continueCh, nil
+	}
+}
+
+// Reauthenticate authenticates an established connection again using a fresh bearer token, this keeps connections
+// authenticated with a TokenAuthProvider valid as tokens are refreshed.
+func (mcc *memdClientDialerComponent) Reauthenticate(client *memdClient, auth tokenAuthProvider) error {
+	cancelSig := make(chan struct{})
+	defer close(cancelSig)
+
+	bClient := newMemdBootstrapClient(client, cancelSig)
+	completedCh, _, err := buildTokenAuthHandler(bClient, auth, time.Now().Add(mcc.kvConnectTimeout))()
+	if err != nil {
+		return err
+	}
+
+	return <-completedCh
+}
+
 func (mcc *memdClientDialerComponent) sendErrorToCCCPUnsupportedHandlers() {
 	mcc.cccpUnsupportedHandlersLock.Lock()
 	handlers := make([]memdBoostrapCCCPUnsupportedHandler, len(mcc.cccpUnsupportedFailHandlers))
@@ -667,6 +924,34 @@ func (mcc *memdClientDialerComponent) sendErrorToCCCPUnsupportedHandlers() {
 	}
 }
 
+// deniedHelloFeatures returns the features which were requested but not enabled by the server.
+func deniedHelloFeatures(requested, enabled []memd.HelloFeature) []memd.HelloFeature {
+	var denied []memd.HelloFeature
+	for _, feature := range requested {
+		if !checkSupportsFeature(enabled, feature) {
+			denied = append(denied, feature)
+		}
+	}
+
+	return denied
+}
+
+// userEnabledHelloFeatures returns the features which are only requested because they were enabled in the agent
+// config, and so change the behaviour seen by the user if they are not enabled by the server.
+func userEnabledHelloFeatures(features []memd.HelloFeature) []memd.HelloFeature {
+	var userFeatures []memd.HelloFeature
+	for _, feature := range features {
+		switch feature {
+		case memd.FeatureSeqNo, memd.FeatureSnappy, memd.FeatureDurations, memd.FeatureCollections,
+			memd.FeatureUnorderedExec, memd.FeatureSyncReplication, memd.FeaturePITR, memd.FeatureXerror,
+			memd.FeatureJSON:
+			userFeatures = append(userFeatures, feature)
+		}
+	}
+
+	return userFeatures
+}
+
 func checkSupportsFeature(srvFeatures []memd.HelloFeature, feature memd.HelloFeature) bool {
 	for _, srvFeature := range srvFeatures {
 		if srvFeature == feature {
@@ -676,6 +961,52 @@ func checkSupportsFeature(srvFeatures []memd.HelloFeature, feature memd.HelloFea
 	return false
 }
 
+func (mcc *memdClientDialerComponent) cachedSaslMechs(address string) []AuthMechanism {
+	mcc.saslMechsLock.Lock()
+	defer mcc.saslMechsLock.Unlock()
+
+	return mcc.saslMechs[address]
+}
+
+func (mcc *memdClientDialerComponent) storeSaslMechs(address string, mechs []AuthMechanism) {
+	mcc.saslMechsLock.Lock()
+	defer mcc.saslMechsLock.Unlock()
+
+	if mcc.saslMechs == nil {
+		mcc.saslMechs = make(map[string][]AuthMechanism)
+	}
+	mcc.saslMechs[address] = mechs
+}
+
+func (mcc *memdClientDialerComponent) forgetSaslMechs(address string) {
+	mcc.saslMechsLock.Lock()
+	defer mcc.saslMechsLock.Unlock()
+
+	delete(mcc.saslMechs, address)
+}
+
+// preferSupportedAuthMechanisms returns the mechanisms from authMechanisms, which are in order of preference, which
+// are also supported by the server. If there are none then authMechanisms is returned unchanged.
+// Channel binding mechanisms, such as SCRAM-SHA512-PLUS, are never preferred even when the server lists them, as the
+// SCRAM client does not implement channel binding.
+func preferSupportedAuthMechanisms(authMechanisms []AuthMechanism, serverAuthMechanisms []AuthMechanism) []AuthMechanism {
+	var supported []AuthMechanism
+	for _, mech := range authMechanisms {
+		for _, serverMech := range serverAuthMechanisms {
+			if mech == serverMech {
+				supported = append(supported, mech)
+				break
+			}
+		}
+	}
+
+	if len(supported) == 0 {
+		return authMechanisms
+	}
+
+	return supported
+}
+
 func findNextAuthMechanism(authMechanisms []AuthMechanism, serverAuthMechanisms []AuthMechanism) (bool, AuthMechanism, []AuthMechanism) {
 	for {
 		if len(authMechanisms) <= 1 {
@@ -753,7 +1084,10 @@ func helloFeatures(props helloProps) []memd.HelloFeature {
 	}
 
 	if props.ClusterMapNotificationsEnabled {
+		// Servers which support both forms of notification only send the brief form, the full form is used by older
+		// servers and carries the new config with it.
 		features = append(features, memd.FeatureClustermapChangeNotificationBrief)
+		features = append(features, memd.FeatureClusterMapNotif)
 	}
 
 	// These flags are informational so don't actually enable anything
@@ -0,0 +1,319 @@
+package gocbcore
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/couchbase/gocbcore/v10/memd"
+)
+
+func (suite *UnitTestSuite) TestMemdClientDialerBucketWarmupRetry() {
+	tracer := newTracerComponent(&noopTracer{}, "", true, &noopMeter{}, nil)
+
+	var numSelects uint32
+	server := newTestMemdServer()
+	server.SetHandler(memd.CmdSelectBucket, func(req *memd.Packet, resp *memd.Packet) {
+		if atomic.AddUint32(&numSelects, 1) <= 2 {
+			resp.Status = memd.StatusKeyNotFound
+		}
+	})
+
+	mux := &kvMux{
+		errMapMgr: newErrMapManager("default"),
+		tracer:    tracer,
+	}
+	client := newTestMemdServerClient(server, mux.handleOpRoutingResp, tracer)
+	defer client.Close()
+
+	cancelSig := make(chan struct{})
+	defer close(cancelSig)
+	bClient := newMemdBootstrapClient(client, cancelSig)
+
+	mcc := &memdClientDialerComponent{
+		bucketWarmupRetryWindow: time.Second,
+	}
+
+	err := mcc.retrySelectBucketDuringWarmup(bClient, "default", time.Now().Add(time.Second), errBucketNotFound)
+	suite.Require().Nil(err, err)
+	suite.Assert().Equal(uint32(3), atomic.LoadUint32(&numSelects))
+
+	// Authentication failures are not caused by bucket warmup and must not be retried.
+	err = mcc.retrySelectBucketDuringWarmup(bClient, "default", time.Now().Add(time.Second), errAuthenticationFailure)
+	suite.Assert().True(errors.Is(err, ErrAuthenticationFailure), err)
+	suite.Assert().Equal(uint32(3), atomic.LoadUint32(&numSelects))
+}
+
+func (suite *UnitTestSuite) TestMemdClientDialerBucketWarmupRetryWindowExpired() {
+	tracer := newTracerComponent(&noopTracer{}, "", true, &noopMeter{}, nil)
+
+	var bucketCreated uint32
+	server := newTestMemdServer()
+	server.SetHandler(memd.CmdSelectBucket, func(req *memd.Packet, resp *memd.Packet) {
+		if atomic.LoadUint32(&bucketCreated) == 0 {
+			resp.Status = memd.StatusKeyNotFound
+		}
+	})
+
+	mux := &kvMux{
+		errMapMgr: newErrMapManager("default"),
+		tracer:    tracer,
+	}
+	client := newTestMemdServerClient(server, mux.handleOpRoutingResp, tracer)
+	defer client.Close()
+
+	cancelSig := make(chan struct{})
+	defer close(cancelSig)
+	bClient := newMemdBootstrapClient(client, cancelSig)
+
+	mcc := &memdClientDialerComponent{
+		bucketWarmupRetryWindow: 50 * time.Millisecond,
+	}
+
+	err := mcc.retrySelectBucketDuringWarmup(bClient, "default", time.Now().Add(time.Second), errBucketNotFound)
+	suite.Assert().True(errors.Is(err, ErrBucketNotFound), err)
+
+	// A later connection attempt gets its own retry window rather than inheriting the expired one.
+	time.AfterFunc(5*time.Millisecond, func() {
+		atomic.StoreUint32(&bucketCreated, 1)
+	})
+	err = mcc.retrySelectBucketDuringWarmup(bClient, "default", time.Now().Add(time.Second), errBucketNotFound)
+	suite.Assert().Nil(err, err)
+}
+
+func (suite *UnitTestSuite) TestMemdClientDialerBucketWarmupRetryCancelled() {
+	tracer := newTracerComponent(&noopTracer{}, "", true, &noopMeter{}, nil)
+
+	server := newTestMemdServer()
+	server.SetHandler(memd.CmdSelectBucket, func(req *memd.Packet, resp *memd.Packet) {
+		resp.Status = memd.StatusKeyNotFound
+	})
+
+	mux := &kvMux{
+		errMapMgr: newErrMapManager("default"),
+		tracer:    tracer,
+	}
+	client := newTestMemdServerClient(server, mux.handleOpRoutingResp, tracer)
+	defer client.Close()
+
+	cancelSig := make(chan struct{})
+	bClient := newMemdBootstrapClient(client, cancelSig)
+
+	mcc := &memdClientDialerComponent{
+		bucketWarmupRetryWindow: time.Minute,
+	}
+
+	time.AfterFunc(50*time.Millisecond, func() {
+		close(cancelSig)
+	})
+
+	start := time.Now()
+	err := mcc.retrySelectBucketDuringWarmup(bClient, "default", time.Now().Add(time.Minute), errBucketNotFound)
+	suite.Assert().True(errors.Is(err, ErrRequestCanceled), err)
+	suite.Assert().Less(int64(time.Since(start)), int64(5*time.Second))
+}
+
+func (suite *UnitTestSuite) TestMemdClientDialerBucketWarmupRetryDisabled() {
+	mcc := &memdClientDialerComponent{}
+
+	err := mcc.retrySelectBucketDuringWarmup(nil, "default", time.Now().Add(time.Second), errBucketNotFound)
+	suite.Assert().True(errors.Is(err, ErrBucketNotFound), err)
+}
+
+func (suite *UnitTestSuite) TestBootstrapStepDeadline() {
+	now := time.Now()
+
+	deadline := bootstrapStepDeadline(now, now.Add(4*time.Second), kvAuthDeadlineFraction)
+	suite.Assert().Equal(now.Add(3*time.Second), deadline)
+
+	expired := now.Add(-time.Second)
+	suite.Assert().Equal(expired, bootstrapStepDeadline(now, expired, kvAuthDeadlineFraction))
+}
+
+func (suite *UnitTestSuite) TestBootstrapStepError() {
+	suite.Assert().Nil(bootstrapStepError("select bucket", nil))
+	suite.Assert().Equal(errAuthenticationFailure, bootstrapStepError("select bucket", errAuthenticationFailure))
+
+	err := bootstrapStepError("SASL authentication using SCRAM-SHA512", &TimeoutError{
+		InnerError:  errAmbiguousTimeout,
+		OperationID: memd.CmdSASLStep.Name(),
+	})
+	suite.Assert().True(errors.Is(err, ErrTimeout))
+	suite.Assert().Contains(err.Error(), "SASL authentication using SCRAM-SHA512")
+
+	var tErr *TimeoutError
+	suite.Require().True(errors.As(err, &tErr))
+	suite.Assert().Equal(memd.CmdSASLStep.Name(), tErr.OperationID)
+}
+
+func (suite *UnitTestSuite) TestDeniedHelloFeatures() {
+	requested := []memd.HelloFeature{memd.FeatureSnappy, memd.FeatureUnorderedExec, memd.FeatureDurations,
+		memd.FeatureCollections}
+	enabled := []memd.HelloFeature{memd.FeatureUnorderedExec, memd.FeatureCollections, memd.FeatureXattr}
+
+	suite.Assert().Equal([]memd.HelloFeature{memd.FeatureSnappy, memd.FeatureDurations},
+		deniedHelloFeatures(requested, enabled))
+	suite.Assert().Empty(deniedHelloFeatures(enabled, enabled))
+
+	// Features which the SDK always requests, and which servers commonly don't enable, are not user features.
+	suite.Assert().Equal([]memd.HelloFeature{memd.FeatureSnappy, memd.FeatureDurations},
+		userEnabledHelloFeatures([]memd.HelloFeature{memd.FeatureTLS, memd.FeatureSnappy, memd.FeatureSnappyEverywhere,
+			memd.FeatureResourceUnits, memd.FeatureDurations, memd.FeatureClustermapChangeNotificationBrief}))
+}
+
+func (suite *UnitTestSuite) TestMemdClientDialerSaslMechsCache() {
+	tracer := newTracerComponent(&noopTracer{}, "", true, &noopMeter{}, nil)
+
+	var numListMechs, numScramAuths uint32
+	newServer := func() *testMemdServer {
+		server := newTestMemdServer()
+		server.SetHandler(memd.CmdHello, func(req *memd.Packet, resp *memd.Packet) {
+			resp.Value = req.Value
+		})
+		server.SetHandler(memd.CmdSASLListMechs, func(req *memd.Packet, resp *memd.Packet) {
+			atomic.AddUint32(&numListMechs, 1)
+			resp.Value = []byte("SCRAM-SHA1 PLAIN")
+		})
+		server.SetHandler(memd.CmdSASLAuth, func(req *memd.Packet, resp *memd.Packet) {
+			if AuthMechanism(req.Key) != PlainAuthMechanism {
+				atomic.AddUint32(&numScramAuths, 1)
+				resp.Status = memd.StatusAuthError
+			}
+		})
+
+		return server
+	}
+
+	mcc := &memdClientDialerComponent{
+		configApplied: 1,
+	}
+	auth := PasswordAuthProvider{
+		Username: "user",
+		Password: "pass",
+	}
+	mechs := []AuthMechanism{ScramSha512AuthMechanism, PlainAuthMechanism}
+
+	bootstrap := func() *memdClient {
+		client := newTestMemdServerClient(newServer(), nil, tracer)
+
+		cancelSig := make(chan struct{})
+		defer close(cancelSig)
+
+		err := mcc.bootstrap(newMemdBootstrapClient(client, cancelSig), time.Now().Add(5*time.Second), mechs, auth)
+		suite.Require().Nil(err, err)
+
+		return client
+	}
+
+	// The first connection has to discover that the server doesn't support SCRAM-SHA512.
+	client := bootstrap()
+	suite.Assert().Equal(PlainAuthMechanism, client.AuthMechanism())
+	suite.Assert().Equal(uint32(1), atomic.LoadUint32(&numListMechs))
+	suite.Assert().Equal(uint32(1), atomic.LoadUint32(&numScramAuths))
+	suite.Require().Nil(client.Close())
+
+	// Subsequent connections use the cached mechanisms and go straight to the supported mechanism.
+	client = bootstrap()
+	suite.Assert().Equal(PlainAuthMechanism, client.AuthMechanism())
+	suite.Assert().Equal(uint32(1), atomic.LoadUint32(&numListMechs))
+	suite.Assert().Equal(uint32(1), atomic.LoadUint32(&numScramAuths))
+	suite.Require().Nil(client.Close())
+}
+
+func (suite *UnitTestSuite) TestPreferSupportedAuthMechanisms() {
+	mechs := []AuthMechanism{ScramSha512AuthMechanism, ScramSha256AuthMechanism, ScramSha1AuthMechanism}
+
+	suite.Assert().Equal([]AuthMechanism{ScramSha256AuthMechanism, ScramSha1AuthMechanism},
+		preferSupportedAuthMechanisms(mechs, []AuthMechanism{ScramSha1AuthMechanism, ScramSha256AuthMechanism}))
+	suite.Assert().Equal(mechs, preferSupportedAuthMechanisms(mechs, []AuthMechanism{PlainAuthMechanism}))
+	suite.Assert().Equal([]AuthMechanism{ScramSha256AuthMechanism},
+		preferSupportedAuthMechanisms(mechs, []AuthMechanism{"SCRAM-SHA512-PLUS", ScramSha256AuthMechanism}))
+}
+
+func (suite *UnitTestSuite) TestMemdClientDialerOAuthBearer() {
+	tracer := newTracerComponent(&noopTracer{}, "", true, &noopMeter{}, nil)
+
+	var numTokens, numAuths uint32
+	server := newTestMemdServer()
+	server.SetHandler(memd.CmdHello, func(req *memd.Packet, resp *memd.Packet) {
+		resp.Value = req.Value
+	})
+	server.SetHandler(memd.CmdSASLListMechs, func(req *memd.Packet, resp *memd.Packet) {
+		resp.Value = []byte("SCRAM-SHA512 PLAIN OAUTHBEARER")
+	})
+	server.SetHandler(memd.CmdSASLAuth, func(req *memd.Packet, resp *memd.Packet) {
+		n := atomic.AddUint32(&numAuths, 1)
+		expected := fmt.Sprintf("n,,\x01auth=Bearer token%d\x01\x01", n)
+		if AuthMechanism(req.Key) != OAuthBearerAuthMechanism || string(req.Value) != expected {
+			resp.Status = memd.StatusAuthError
+		}
+	})
+
+	mcc := &memdClientDialerComponent{
+		configApplied:    1,
+		kvConnectTimeout: 5 * time.Second,
+	}
+	auth := NewTokenAuthProvider(func() (string, time.Time, error) {
+		n := atomic.AddUint32(&numTokens, 1)
+		return fmt.Sprintf("token%d", n), time.Now().Add(time.Hour), nil
+	}, TokenAuthProviderOptions{})
+
+	client := newTestMemdServerClient(server, nil, tracer)
+	cancelSig := make(chan struct{})
+	err := mcc.bootstrap(newMemdBootstrapClient(client, cancelSig), time.Now().Add(5*time.Second),
+		[]AuthMechanism{ScramSha512AuthMechanism}, auth)
+	close(cancelSig)
+	suite.Require().Nil(err, err)
+	suite.Assert().Equal(OAuthBearerAuthMechanism, client.AuthMechanism())
+
+	// Force the token to be refreshed, the connection should then re-authenticate with the new token.
+	auth.lock.Lock()
+	auth.expiry = time.Now()
+	auth.lock.Unlock()
+
+	err = mcc.Reauthenticate(client, auth)
+	suite.Require().Nil(err, err)
+	suite.Assert().Equal(uint32(2), atomic.LoadUint32(&numAuths))
+
+	suite.Require().Nil(client.Close())
+}
+
+func (suite *UnitTestSuite) TestMemdClientDialerRequireCertAuth() {
+	tracer := newTracerComponent(&noopTracer{}, "", true, &noopMeter{}, nil)
+
+	var numSaslRequests uint32
+	server := newTestMemdServer()
+	server.SetHandler(memd.CmdHello, func(req *memd.Packet, resp *memd.Packet) {
+		resp.Value = req.Value
+	})
+	server.SetHandler(memd.CmdSASLListMechs, func(req *memd.Packet, resp *memd.Packet) {
+		atomic.AddUint32(&numSaslRequests, 1)
+	})
+	server.SetHandler(memd.CmdSASLAuth, func(req *memd.Packet, resp *memd.Packet) {
+		atomic.AddUint32(&numSaslRequests, 1)
+	})
+
+	mcc := &memdClientDialerComponent{
+		configApplied:   1,
+		requireCertAuth: true,
+	}
+
+	// Connections without TLS are refused before dialing.
+	_, err := mcc.dialMemdClient(make(chan struct{}), routeEndpoint{Address: "127.0.0.1:11210"},
+		time.Now().Add(time.Second), nil, nil, nil)
+	suite.Assert().ErrorIs(err, ErrInvalidArgument)
+
+	client := newTestMemdServerClient(server, nil, tracer)
+	cancelSig := make(chan struct{})
+	err = mcc.bootstrap(newMemdBootstrapClient(client, cancelSig), time.Now().Add(5*time.Second),
+		[]AuthMechanism{ScramSha512AuthMechanism}, PasswordAuthProvider{Username: "user", Password: "pass"})
+	close(cancelSig)
+	suite.Require().Nil(err, err)
+
+	// The certificate has already authenticated the connection so SASL is never used.
+	suite.Assert().Zero(atomic.LoadUint32(&numSaslRequests))
+	suite.Assert().Empty(client.AuthMechanism())
+
+	suite.Require().Nil(client.Close())
+}
@@ -4,6 +4,8 @@ import (
 	"bufio"
 	"context"
 	"crypto/tls"
+	"errors"
+	"fmt"
 	"io"
 	"net"
 	"sync"
@@ -125,7 +127,39 @@ func (s *memdConnWrap) Release() {
 	s.baseConn = nil
 }
 
-func dialMemdConn(ctx context.Context, address string, tlsConfig *tls.Config, deadline time.Time, bufSize uint) (memdConn, error) {
+// handshakeMemdConn performs the TLS handshake for a connection. The handshake does not respect the dialer deadline
+// or context so we apply them to the underlying connection ourselves, otherwise a server which accepts the connection
+// but never completes the handshake would block the dial, and anything waiting on it, indefinitely.
+func handshakeMemdConn(ctx context.Context, tcpConn *net.TCPConn, tlsConn *tls.Conn, deadline time.Time) error {
+	if !deadline.IsZero() {
+		if err := tcpConn.SetDeadline(deadline); err != nil {
+			return err
+		}
+	}
+
+	doneCh := make(chan struct{})
+	defer close(doneCh)
+	go func() {
+		select {
+		case <-ctx.Done():
+			_ = tcpConn.SetDeadline(time.Unix(1, 0))
+		case <-doneCh:
+		}
+	}()
+
+	err := tlsConn.Handshake()
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return ctxErr
+		}
+		return err
+	}
+
+	return tcpConn.SetDeadline(time.Time{})
+}
+
+func dialMemdConn(ctx context.Context, address string, tlsConfig *tls.Config, deadline time.Time, bufSize uint,
+	writeFlushInterval time.Duration, maxWriteBatchSize int) (memdConn, error) {
 	d := net.Dialer{
 		Deadline: deadline,
 	}
@@ -154,8 +188,16 @@ func dialMemdConn(ctx context.Context, address string, tlsConfig *tls.Config, de
 	var conn io.ReadWriteCloser = tcpConn
 	if tlsConfig != nil {
 		tlsConn := tls.Client(tcpConn, tlsConfig)
-		err = tlsConn.Handshake()
+		err = handshakeMemdConn(ctx, tcpConn, tlsConn, deadline)
 		if err != nil {
+			_ = tcpConn.Close()
+
+			var recordErr tls.RecordHeaderError
+			if errors.As(err, &recordErr) {
+				return nil, wrapError(errEncryptionMismatch, fmt.Sprintf("received plaintext response on TLS connection "+
+					"to %s; check that the TLS port (11207 by default) is being used rather than the non-TLS port (11210)",
+					address))
+			}
 			return nil, err
 		}
 
@@ -172,6 +214,12 @@ func dialMemdConn(ctx context.Context, address string, tlsConfig *tls.Config, de
 		Closer: conn,
 	}
 
+	if writeFlushInterval > 0 {
+		coalescer := newMemdWriteCoalescer(conn, writeFlushInterval, maxWriteBatchSize)
+		c.Writer = coalescer
+		c.Closer = coalescer
+	}
+
 	return &memdConnWrap{
 		conn:       memd.NewConn(c),
 		baseConn:   c,
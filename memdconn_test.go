@@ -0,0 +1,32 @@
+package gocbcore
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"time"
+)
+
+func (suite *UnitTestSuite) TestDialMemdConnTLSToPlaintextPort() {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	suite.Require().Nil(err, err)
+	defer listener.Close()
+
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		// Respond to the client hello with something which is not a TLS record, as a plaintext memcached port would.
+		buf := make([]byte, 1024)
+		_, _ = conn.Read(buf)
+		_, _ = conn.Write(make([]byte, 24))
+	}()
+
+	_, err = dialMemdConn(context.Background(), listener.Addr().String(), &tls.Config{InsecureSkipVerify: true}, // nolint: gosec
+		time.Now().Add(5*time.Second), 0, 0, 0)
+	suite.Require().True(errors.Is(err, ErrEncryptionMismatch), err)
+}
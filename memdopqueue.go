@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"sync"
 	"sync/atomic"
+	"time"
 	"unsafe"
 )
 
@@ -37,6 +38,13 @@ type memdOpQueue struct {
 	signal *sync.Cond
 	items  *list.List
 	isOpen bool
+
+	// lastWait is the time in nanoseconds that the most recently popped request spent in the queue.
+	lastWait int64
+
+	// spaceSignal is closed, and then cleared, whenever a request leaves the queue to wake anyone waiting for space
+	// in a full queue.
+	spaceSignal chan struct{}
 }
 
 func newMemdOpQueue() *memdOpQueue {
@@ -60,6 +68,39 @@ func (q *memdOpQueue) debugString() string {
 	return outStr
 }
 
+// Len returns the number of requests waiting in the queue.
+func (q *memdOpQueue) Len() int {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return q.items.Len()
+}
+
+// OldestWaitTime returns how long the request at the front of the queue has been waiting.
+func (q *memdOpQueue) OldestWaitTime() time.Duration {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	e := q.items.Front()
+	if e == nil {
+		return 0
+	}
+
+	return time.Since(e.Value.(*memdQRequest).queuedTime)
+}
+
+// signalSpace wakes anyone waiting for space in the queue, the queue lock must be held.
+func (q *memdOpQueue) signalSpace() {
+	if q.spaceSignal != nil {
+		close(q.spaceSignal)
+		q.spaceSignal = nil
+	}
+}
+
+// LastWaitTime returns how long the most recently popped request spent waiting in the queue.
+func (q *memdOpQueue) LastWaitTime() time.Duration {
+	return time.Duration(atomic.LoadInt64(&q.lastWait))
+}
+
 func (q *memdOpQueue) Remove(req *memdQRequest) bool {
 	q.lock.Lock()
 
@@ -71,6 +112,7 @@ func (q *memdOpQueue) Remove(req *memdQRequest) bool {
 	for e := q.items.Front(); e != nil; e = e.Next() {
 		if e.Value.(*memdQRequest) == req {
 			q.items.Remove(e)
+			q.signalSpace()
 			break
 		}
 	}
@@ -81,15 +123,43 @@ func (q *memdOpQueue) Remove(req *memdQRequest) bool {
 }
 
 func (q *memdOpQueue) Push(req *memdQRequest, maxItems int) error {
+	return q.PushWithWait(req, maxItems, time.Time{})
+}
+
+// PushWithWait pushes the request into the queue, if the queue is full then it waits until the deadline for space to
+// become available. A zero deadline means that it does not wait.
+func (q *memdOpQueue) PushWithWait(req *memdQRequest, maxItems int, deadline time.Time) error {
 	q.lock.Lock()
-	if !q.isOpen {
-		q.lock.Unlock()
-		return errOpQueueClosed
-	}
+	for {
+		if !q.isOpen {
+			q.lock.Unlock()
+			return errOpQueueClosed
+		}
 
-	if maxItems > 0 && q.items.Len() >= maxItems {
+		if maxItems <= 0 || q.items.Len() < maxItems {
+			break
+		}
+
+		waitTime := time.Until(deadline)
+		if deadline.IsZero() || waitTime <= 0 {
+			q.lock.Unlock()
+			return errOpQueueFull
+		}
+
+		if q.spaceSignal == nil {
+			q.spaceSignal = make(chan struct{})
+		}
+		spaceSignal := q.spaceSignal
 		q.lock.Unlock()
-		return errOpQueueFull
+
+		timer := time.NewTimer(waitTime)
+		select {
+		case <-spaceSignal:
+		case <-timer.C:
+		}
+		timer.Stop()
+
+		q.lock.Lock()
 	}
 
 	if !atomic.CompareAndSwapPointer(&req.queuedWith, nil, unsafe.Pointer(q)) {
@@ -104,6 +174,7 @@ func (q *memdOpQueue) Push(req *memdQRequest, maxItems int) error {
 		return errRequestCanceled
 	}
 
+	req.queuedTime = time.Now()
 	q.items.PushBack(req)
 	q.lock.Unlock()
 
@@ -140,6 +211,7 @@ func (q *memdOpQueue) pop(c *memdOpConsumer) *memdQRequest {
 
 	e := q.items.Front()
 	q.items.Remove(e)
+	q.signalSpace()
 
 	req, ok := e.Value.(*memdQRequest)
 	if !ok {
@@ -148,6 +220,7 @@ func (q *memdOpQueue) pop(c *memdOpConsumer) *memdQRequest {
 	}
 
 	atomic.CompareAndSwapPointer(&req.queuedWith, unsafe.Pointer(q), nil)
+	atomic.StoreInt64(&q.lastWait, int64(time.Since(req.queuedTime)))
 
 	q.lock.Unlock()
 
@@ -183,6 +256,7 @@ func (q *memdOpQueue) Drain(cb drainCallback) {
 func (q *memdOpQueue) Close() {
 	q.lock.Lock()
 	q.isOpen = false
+	q.signalSpace()
 	q.lock.Unlock()
 
 	q.signal.Broadcast()
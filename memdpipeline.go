@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/couchbase/gocbcore/v10/memd"
 )
@@ -15,6 +16,19 @@ var (
 
 type memdGetClientFn func(cancelSig <-chan struct{}) (*memdClient, error)
 
+// poolSizingProps configures the dynamic sizing of the clients in a pipeline. Sizing is disabled unless MaxClients is
+// greater than the number of clients that the pipeline is created with, which then becomes the minimum.
+type poolSizingProps struct {
+	// MaxClients is the number of clients that the pipeline can grow to.
+	MaxClients int
+	// GrowQueueLatency is how long requests must be waiting in the queue before another client is added.
+	GrowQueueLatency time.Duration
+	// IdleTimeout is how long a client above the minimum must go without writing a request before it is removed.
+	IdleTimeout time.Duration
+	// CloseClientFn is used to close the memdclients of removed clients, allowing in-flight requests to complete.
+	CloseClientFn func(client *memdClient)
+}
+
 type memdPipeline struct {
 	address     string
 	getClientFn memdGetClientFn
@@ -25,9 +39,15 @@ type memdPipeline struct {
 	clientsLock sync.Mutex
 	isSeedNode  bool
 	serverGroup string
+
+	sizing        poolSizingProps
+	isClosed      bool
+	reaperStopSig chan struct{}
+	reaperDoneSig chan struct{}
 }
 
-func newPipeline(endpoint routeEndpoint, maxClients, maxItems int, getClientFn memdGetClientFn) *memdPipeline {
+func newPipeline(endpoint routeEndpoint, maxClients, maxItems int, sizing poolSizingProps,
+	getClientFn memdGetClientFn) *memdPipeline {
 	return &memdPipeline{
 		address:     endpoint.Address,
 		getClientFn: getClientFn,
@@ -36,11 +56,12 @@ func newPipeline(endpoint routeEndpoint, maxClients, maxItems int, getClientFn m
 		queue:       newMemdOpQueue(),
 		isSeedNode:  endpoint.IsSeedNode,
 		serverGroup: endpoint.ServerGroup,
+		sizing:      sizing,
 	}
 }
 
 func newDeadPipeline(maxItems int) *memdPipeline {
-	return newPipeline(routeEndpoint{}, 0, maxItems, nil)
+	return newPipeline(routeEndpoint{}, 0, maxItems, poolSizingProps{}, nil)
 }
 
 // nolint: unused
@@ -72,6 +93,27 @@ func (pipeline *memdPipeline) Clients() []*memdPipelineClient {
 	return pipeline.clients
 }
 
+// IsAvailable returns whether any of the pipeline clients is connected with a closed circuit breaker. Clients with a
+// half open circuit breaker are excluded, they are still waiting for a canary to tell whether the node has recovered.
+func (pipeline *memdPipeline) IsAvailable() bool {
+	for _, pipeCli := range pipeline.Clients() {
+		pipeCli.lock.Lock()
+		client := pipeCli.client
+		pipeCli.lock.Unlock()
+
+		if client == nil {
+			continue
+		}
+
+		state := client.breaker.State()
+		if state == circuitBreakerStateClosed || state == circuitBreakerStateDisabled {
+			return true
+		}
+	}
+
+	return false
+}
+
 func (pipeline *memdPipeline) SupportsFeature(feature memd.HelloFeature) bool {
 	pipeline.clientsLock.Lock()
 	defer pipeline.clientsLock.Unlock()
@@ -96,6 +138,24 @@ func (pipeline *memdPipeline) ServerGroup() string {
 	return pipeline.serverGroup
 }
 
+// PoolInfo returns information about the current state of the clients and queue of this pipeline.
+func (pipeline *memdPipeline) PoolInfo() KVPoolInfo {
+	info := KVPoolInfo{
+		Address:       pipeline.address,
+		QueueDepth:    pipeline.queue.Len(),
+		QueueWaitTime: pipeline.queue.LastWaitTime(),
+	}
+
+	for _, pipecli := range pipeline.Clients() {
+		info.PoolSize++
+		if pipecli.State() == EndpointStateConnected {
+			info.ActiveConnections++
+		}
+	}
+
+	return info
+}
+
 func (pipeline *memdPipeline) StartClients() {
 	pipeline.clientsLock.Lock()
 	defer pipeline.clientsLock.Unlock()
@@ -106,10 +166,125 @@ func (pipeline *memdPipeline) StartClients() {
 
 		go client.Run()
 	}
+
+	if pipeline.dynamicSizingEnabled() && !pipeline.isClosed && pipeline.reaperStopSig == nil {
+		pipeline.reaperStopSig = make(chan struct{})
+		pipeline.reaperDoneSig = make(chan struct{})
+
+		go pipeline.idleClientReaper(pipeline.reaperStopSig, pipeline.reaperDoneSig)
+	}
 }
 
-func (pipeline *memdPipeline) sendRequest(req *memdQRequest, maxItems int) error {
-	err := pipeline.queue.Push(req, maxItems)
+func (pipeline *memdPipeline) dynamicSizingEnabled() bool {
+	return pipeline.sizing.MaxClients > pipeline.maxClients
+}
+
+// maybeGrowClients adds a client to the pipeline if requests are waiting in the queue for longer than the grow
+// threshold, and the pipeline has not yet reached its maximum size.
+func (pipeline *memdPipeline) maybeGrowClients(queueWait time.Duration) {
+	if !pipeline.dynamicSizingEnabled() || queueWait < pipeline.sizing.GrowQueueLatency {
+		return
+	}
+
+	pipeline.clientsLock.Lock()
+	defer pipeline.clientsLock.Unlock()
+
+	if pipeline.isClosed || len(pipeline.clients) >= pipeline.sizing.MaxClients {
+		return
+	}
+
+	// We only grow by a single client at a time, waiting for it to connect before deciding whether more are needed.
+	for _, pipecli := range pipeline.clients {
+		if pipecli.State() != EndpointStateConnected {
+			return
+		}
+	}
+
+	client := newMemdPipelineClient(pipeline)
+	pipeline.clients = append(pipeline.clients, client)
+
+	logDebugf("Pipeline %s/%p growing to %d clients, queue wait was %s", pipeline.address, pipeline,
+		len(pipeline.clients), queueWait)
+
+	go client.Run()
+}
+
+func (pipeline *memdPipeline) idleClientReaper(stopSig, doneSig chan struct{}) {
+	defer close(doneSig)
+
+	interval := pipeline.sizing.IdleTimeout / 2
+	if interval <= 0 {
+		interval = time.Millisecond
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stopSig:
+			return
+		case <-ticker.C:
+			pipeline.closeIdleClients()
+		}
+	}
+}
+
+// closeIdleClients removes any clients above the minimum which have not written a request within the idle timeout.
+func (pipeline *memdPipeline) closeIdleClients() {
+	pipeline.clientsLock.Lock()
+	excess := len(pipeline.clients) - pipeline.maxClients
+	if pipeline.isClosed || excess <= 0 {
+		pipeline.clientsLock.Unlock()
+		return
+	}
+
+	var idleClients []*memdPipelineClient
+	keepClients := make([]*memdPipelineClient, 0, len(pipeline.clients))
+	for _, pipecli := range pipeline.clients {
+		if len(idleClients) < excess && pipecli.IdleTime() >= pipeline.sizing.IdleTimeout {
+			idleClients = append(idleClients, pipecli)
+			continue
+		}
+		keepClients = append(keepClients, pipecli)
+	}
+	pipeline.clients = keepClients
+	pipeline.clientsLock.Unlock()
+
+	for _, pipecli := range idleClients {
+		logDebugf("Pipeline %s/%p removing idle client %p", pipeline.address, pipeline, pipecli)
+
+		client := pipecli.CloseAndTakeClient()
+		if client == nil {
+			continue
+		}
+
+		if pipeline.sizing.CloseClientFn != nil {
+			pipeline.sizing.CloseClientFn(client)
+		} else {
+			client.GracefulClose(nil)
+		}
+	}
+}
+
+// markClosed prevents the pipeline from growing or shrinking any further, and waits for the idle client reaper to
+// stop.
+func (pipeline *memdPipeline) markClosed() {
+	pipeline.clientsLock.Lock()
+	pipeline.isClosed = true
+	stopSig := pipeline.reaperStopSig
+	doneSig := pipeline.reaperDoneSig
+	pipeline.reaperStopSig = nil
+	pipeline.reaperDoneSig = nil
+	pipeline.clientsLock.Unlock()
+
+	if stopSig != nil {
+		close(stopSig)
+		<-doneSig
+	}
+}
+
+func (pipeline *memdPipeline) sendRequest(req *memdQRequest, maxItems int, deadline time.Time) error {
+	err := pipeline.queue.PushWithWait(req, maxItems, deadline)
 	if err == errOpQueueClosed {
 		return errPipelineClosed
 	} else if err == errOpQueueFull {
@@ -122,11 +297,26 @@ func (pipeline *memdPipeline) sendRequest(req *memdQRequest, maxItems int) error
 }
 
 func (pipeline *memdPipeline) RequeueRequest(req *memdQRequest) error {
-	return pipeline.sendRequest(req, 0)
+	return pipeline.sendRequest(req, 0, time.Time{})
 }
 
 func (pipeline *memdPipeline) SendRequest(req *memdQRequest) error {
-	return pipeline.sendRequest(req, pipeline.maxItems)
+	return pipeline.sendRequest(req, pipeline.maxItems, time.Time{})
+}
+
+// SendRequestWithWait sends the request, waiting until the deadline for space if the queue is full.
+func (pipeline *memdPipeline) SendRequestWithWait(req *memdQRequest, deadline time.Time) error {
+	return pipeline.sendRequest(req, pipeline.maxItems, deadline)
+}
+
+// overloadError returns the error to use when a request cannot be sent because the queue is full.
+func (pipeline *memdPipeline) overloadError() error {
+	return QueueOverloadError{
+		InnerError:  errOverload,
+		Endpoint:    pipeline.address,
+		QueueLength: pipeline.queue.Len(),
+		OldestOpAge: pipeline.queue.OldestWaitTime(),
+	}
 }
 
 // Performs a takeover of another pipeline.  Note that this does not
@@ -153,6 +343,8 @@ func (pipeline *memdPipeline) Takeover(oldPipeline *memdPipeline) {
 		return
 	}
 
+	oldPipeline.markClosed()
+
 	// Migrate all the clients to the new pipeline
 	oldPipeline.clientsLock.Lock()
 	clients := oldPipeline.clients
@@ -175,6 +367,8 @@ func (pipeline *memdPipeline) Takeover(oldPipeline *memdPipeline) {
 }
 
 func (pipeline *memdPipeline) GracefulClose() []*memdClient {
+	pipeline.markClosed()
+
 	// Shut down all the clients
 	pipeline.clientsLock.Lock()
 	clients := pipeline.clients
@@ -197,6 +391,8 @@ func (pipeline *memdPipeline) GracefulClose() []*memdClient {
 }
 
 func (pipeline *memdPipeline) Close() error {
+	pipeline.markClosed()
+
 	// Shut down all the clients
 	pipeline.clientsLock.Lock()
 	clients := pipeline.clients
@@ -0,0 +1,162 @@
+package gocbcore
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/couchbase/gocbcore/v10/memd"
+)
+
+func (suite *UnitTestSuite) TestMemdPipelineDynamicSizing() {
+	tracer := newTracerComponent(&noopTracer{}, "", true, &noopMeter{}, nil)
+
+	var closedClients int32
+	pipeline := newPipeline(routeEndpoint{Address: "127.0.0.1:11210"}, 1, 0, poolSizingProps{
+		MaxClients:       2,
+		GrowQueueLatency: time.Nanosecond,
+		IdleTimeout:      50 * time.Millisecond,
+		CloseClientFn: func(client *memdClient) {
+			atomic.AddInt32(&closedClients, 1)
+			client.GracefulClose(nil)
+		},
+	}, func(cancelSig <-chan struct{}) (*memdClient, error) {
+		return newTestMemdServerClient(newTestMemdServer(), nil, tracer), nil
+	})
+	pipeline.StartClients()
+
+	waitForPool := func(cond func(info KVPoolInfo) bool) KVPoolInfo {
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			info := pipeline.PoolInfo()
+			if cond(info) {
+				return info
+			}
+			if time.Now().After(deadline) {
+				suite.T().Fatalf("Pool did not reach expected state, was %+v", info)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	waitForPool(func(info KVPoolInfo) bool {
+		return info.ActiveConnections == 1
+	})
+
+	respCh := make(chan error, 1)
+	err := pipeline.SendRequest(&memdQRequest{
+		Packet: memd.Packet{
+			Magic:   memd.CmdMagicReq,
+			Command: memd.CmdGet,
+			Key:     []byte("key"),
+		},
+		Callback: func(resp *memdQResponse, req *memdQRequest, err error) {
+			respCh <- err
+		},
+	})
+	suite.Require().Nil(err, err)
+	suite.Require().Nil(<-respCh)
+
+	// The request waited for longer than the grow threshold so the pool should grow to its maximum size.
+	info := waitForPool(func(info KVPoolInfo) bool {
+		return info.PoolSize == 2 && info.ActiveConnections == 2
+	})
+	suite.Assert().Equal("127.0.0.1:11210", info.Address)
+	suite.Assert().Zero(info.QueueDepth)
+	suite.Assert().NotZero(info.QueueWaitTime)
+
+	// Once idle the pool should shrink back down to the minimum size, the idle client is closed after it has been
+	// removed from the pool.
+	waitForPool(func(info KVPoolInfo) bool {
+		return info.PoolSize == 1 && atomic.LoadInt32(&closedClients) == 1
+	})
+
+	suite.Require().Nil(pipeline.Close())
+}
+
+func (suite *UnitTestSuite) TestMemdPipelineStaticSizing() {
+	pipeline := newPipeline(routeEndpoint{Address: "127.0.0.1:11210"}, 2, 0, poolSizingProps{
+		MaxClients:       2,
+		GrowQueueLatency: time.Nanosecond,
+	}, nil)
+
+	suite.Assert().False(pipeline.dynamicSizingEnabled())
+
+	pipeline.maybeGrowClients(time.Second)
+	suite.Assert().Empty(pipeline.Clients())
+}
+
+func (suite *UnitTestSuite) TestMemdPipelineQueueOverloadError() {
+	pipeline := newPipeline(routeEndpoint{Address: "127.0.0.1:11210"}, 0, 1, poolSizingProps{}, nil)
+
+	suite.Require().Nil(pipeline.SendRequest(&memdQRequest{}))
+	time.Sleep(5 * time.Millisecond)
+
+	err := pipeline.SendRequest(&memdQRequest{})
+	suite.Require().Equal(errPipelineFull, err)
+
+	err = pipeline.overloadError()
+	suite.Assert().True(errors.Is(err, ErrOverload), err)
+
+	var overloadErr QueueOverloadError
+	suite.Require().True(errors.As(err, &overloadErr))
+	suite.Assert().Equal("127.0.0.1:11210", overloadErr.Endpoint)
+	suite.Assert().Equal(1, overloadErr.QueueLength)
+	suite.Assert().GreaterOrEqual(int64(overloadErr.OldestOpAge), int64(5*time.Millisecond))
+}
+
+func (suite *UnitTestSuite) TestMemdPipelineSendRequestWithWait() {
+	pipeline := newPipeline(routeEndpoint{Address: "127.0.0.1:11210"}, 0, 1, poolSizingProps{}, nil)
+	suite.Require().Nil(pipeline.SendRequest(&memdQRequest{}))
+
+	// Nothing is consuming from the queue so the wait should time out.
+	start := time.Now()
+	err := pipeline.SendRequestWithWait(&memdQRequest{}, start.Add(20*time.Millisecond))
+	suite.Assert().Equal(errPipelineFull, err)
+	suite.Assert().GreaterOrEqual(int64(time.Since(start)), int64(20*time.Millisecond))
+
+	// Space becoming available should allow the blocked request to be queued.
+	consumer := pipeline.queue.Consumer()
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		consumer.Pop()
+	}()
+
+	req := &memdQRequest{}
+	err = pipeline.SendRequestWithWait(req, time.Now().Add(5*time.Second))
+	suite.Require().Nil(err, err)
+	suite.Assert().Equal(req, consumer.Pop())
+
+	// Closing the queue should wake any blocked requests.
+	suite.Require().Nil(pipeline.SendRequest(&memdQRequest{}))
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		pipeline.queue.Close()
+	}()
+
+	err = pipeline.SendRequestWithWait(&memdQRequest{}, time.Now().Add(5*time.Second))
+	suite.Assert().Equal(errPipelineClosed, err)
+}
+
+func (suite *UnitTestSuite) TestPipelineIsAvailable() {
+	newClient := func(state uint32) *memdPipelineClient {
+		return &memdPipelineClient{
+			client: &memdClient{breaker: &lazyCircuitBreaker{state: state}},
+		}
+	}
+
+	pipeline := &memdPipeline{
+		clients: []*memdPipelineClient{
+			{},
+			newClient(circuitBreakerStateOpen),
+			newClient(circuitBreakerStateHalfOpen),
+		},
+	}
+	suite.Assert().False(pipeline.IsAvailable())
+
+	pipeline.clients = append(pipeline.clients, newClient(circuitBreakerStateClosed))
+	suite.Assert().True(pipeline.IsAvailable())
+
+	pipeline.clients = []*memdPipelineClient{{client: &memdClient{breaker: newNoopCircuitBreaker()}}}
+	suite.Assert().True(pipeline.IsAvailable())
+}
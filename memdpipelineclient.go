@@ -5,6 +5,7 @@ import (
 	"io"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/couchbase/gocbcore/v10/memd"
 )
@@ -25,6 +26,9 @@ type memdPipelineClient struct {
 	cancelDialSig  chan struct{}
 	state          uint32
 
+	// lastActive is the time in nanoseconds at which this client was created or last wrote a request.
+	lastActive int64
+
 	connectError error
 }
 
@@ -36,9 +40,15 @@ func newMemdPipelineClient(parent *memdPipeline) *memdPipelineClient {
 		clientTakenSig: make(chan struct{}),
 		cancelDialSig:  make(chan struct{}),
 		state:          uint32(EndpointStateDisconnected),
+		lastActive:     time.Now().UnixNano(),
 	}
 }
 
+// IdleTime returns how long it has been since this client was created or last wrote a request.
+func (pipecli *memdPipelineClient) IdleTime() time.Duration {
+	return time.Since(time.Unix(0, atomic.LoadInt64(&pipecli.lastActive)))
+}
+
 func (pipecli *memdPipelineClient) State() EndpointState {
 	return EndpointState(atomic.LoadUint32(&pipecli.state))
 }
@@ -116,6 +126,7 @@ func (pipecli *memdPipelineClient) ioLoop(client *memdClient) {
 	logDebugf("Pipeline client `%s/%p` IO loop starting...", pipecli.address, pipecli)
 
 	var localConsumer *memdOpConsumer
+	var consumerPipeline *memdPipeline
 	for {
 		if localConsumer == nil {
 			logDebugf("Pipeline client `%s/%p` fetching new consumer", pipecli.address, pipecli)
@@ -144,7 +155,8 @@ func (pipecli *memdPipelineClient) ioLoop(client *memdClient) {
 			}
 
 			// Fetch a new consumer to use for this iteration
-			localConsumer = pipecli.parent.queue.Consumer()
+			consumerPipeline = pipecli.parent
+			localConsumer = consumerPipeline.queue.Consumer()
 			pipecli.consumer = localConsumer
 
 			pipecli.lock.Unlock()
@@ -162,6 +174,9 @@ func (pipecli *memdPipelineClient) ioLoop(client *memdClient) {
 			continue
 		}
 
+		atomic.StoreInt64(&pipecli.lastActive, time.Now().UnixNano())
+		consumerPipeline.maybeGrowClients(localConsumer.Queue().LastWaitTime())
+
 		err := client.SendRequest(req)
 		if err != nil {
 			logDebugf("Pipeline client `%s/%p` encountered a socket write error: %v", pipecli.address, pipecli, err)
@@ -0,0 +1,339 @@
+package gocbcore
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/couchbase/gocbcore/v10/memd"
+)
+
+// MemdProxyConfig specifies options for a local listener which accepts memcached binary protocol connections and
+// forwards the requests that it receives through the agent's routing, so that tools such as cbc-pillowfight can be
+// pointed through the agent to reproduce routing issues. The proxy is intended for debugging only, it does not serve
+// cluster configurations or support quiet commands and clients should treat it as a single memcached endpoint.
+// Requests are forwarded using the agent's credentials so the proxy only listens on loopback addresses, unless
+// Username and Password are set in which case clients must authenticate with them before any requests are forwarded.
+// Volatile: This API is subject to change at any time.
+type MemdProxyConfig struct {
+	// Address is the local address to listen on, such as "127.0.0.1:11299". The proxy is disabled when empty.
+	Address string
+	// Username and Password are the credentials that clients must authenticate with, using PLAIN. When not set any
+	// client that can connect to the proxy may use it, so Address must be a loopback address.
+	Username string
+	Password string
+	// Timeout is the timeout applied to forwarded requests, defaults to 2.5 seconds.
+	Timeout time.Duration
+}
+
+type memdProxyComponent struct {
+	listener      net.Listener
+	kvMux         *kvMux
+	tracer        *tracerComponent
+	retryStrategy RetryStrategy
+	timeout       time.Duration
+	username      string
+	password      string
+
+	lock   sync.Mutex
+	conns  map[net.Conn]struct{}
+	closed bool
+	wg     sync.WaitGroup
+}
+
+func listenMemdProxy(config MemdProxyConfig) (net.Listener, error) {
+	if config.Address == "" {
+		return nil, nil
+	}
+
+	if config.Username == "" && !isLoopbackAddress(config.Address) {
+		return nil, wrapError(errInvalidArgument, "memd proxy address must be a loopback address unless credentials are set")
+	}
+
+	listener, err := net.Listen("tcp", config.Address)
+	if err != nil {
+		return nil, wrapError(err, "failed to start memd proxy listener")
+	}
+
+	return listener, nil
+}
+
+func isLoopbackAddress(address string) bool {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return false
+	}
+
+	if host == "localhost" {
+		return true
+	}
+
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+func newMemdProxyComponent(listener net.Listener, config MemdProxyConfig, kvMux *kvMux, tracer *tracerComponent,
+	retryStrategy RetryStrategy) *memdProxyComponent {
+	if listener == nil {
+		return nil
+	}
+
+	timeout := 2500 * time.Millisecond
+	if config.Timeout > 0 {
+		timeout = config.Timeout
+	}
+
+	mp := &memdProxyComponent{
+		listener:      listener,
+		kvMux:         kvMux,
+		tracer:        tracer,
+		retryStrategy: retryStrategy,
+		timeout:       timeout,
+		username:      config.Username,
+		password:      config.Password,
+		conns:         make(map[net.Conn]struct{}),
+	}
+
+	logInfof("Memd proxy listening on %s", listener.Addr())
+
+	mp.wg.Add(1)
+	go mp.acceptLoop()
+
+	return mp
+}
+
+// Address returns the address that the proxy is listening on.
+func (mp *memdProxyComponent) Address() string {
+	return mp.listener.Addr().String()
+}
+
+// Close stops the listener and closes all proxied connections.
+func (mp *memdProxyComponent) Close() error {
+	mp.lock.Lock()
+	if mp.closed {
+		mp.lock.Unlock()
+		return nil
+	}
+	mp.closed = true
+	err := mp.listener.Close()
+	for conn := range mp.conns {
+		if closeErr := conn.Close(); closeErr != nil {
+			logDebugf("Failed to close memd proxy connection: %v", closeErr)
+		}
+	}
+	mp.lock.Unlock()
+
+	mp.wg.Wait()
+
+	return err
+}
+
+func (mp *memdProxyComponent) isClosed() bool {
+	mp.lock.Lock()
+	defer mp.lock.Unlock()
+	return mp.closed
+}
+
+func (mp *memdProxyComponent) acceptLoop() {
+	defer mp.wg.Done()
+
+	for {
+		conn, err := mp.listener.Accept()
+		if err != nil {
+			if !mp.isClosed() {
+				logWarnf("Memd proxy listener failed, stopping proxy: %v", err)
+			}
+			return
+		}
+
+		mp.lock.Lock()
+		if mp.closed {
+			mp.lock.Unlock()
+			if err := conn.Close(); err != nil {
+				logDebugf("Failed to close memd proxy connection: %v", err)
+			}
+			return
+		}
+		mp.conns[conn] = struct{}{}
+		mp.wg.Add(1)
+		mp.lock.Unlock()
+
+		go mp.handleConn(conn)
+	}
+}
+
+func (mp *memdProxyComponent) handleConn(conn net.Conn) {
+	defer mp.wg.Done()
+
+	logDebugf("Memd proxy accepted connection from %s", conn.RemoteAddr())
+
+	mconn := memd.NewConn(conn)
+	var writeLock sync.Mutex
+	write := func(pkt *memd.Packet) {
+		writeLock.Lock()
+		err := mconn.WritePacket(pkt)
+		writeLock.Unlock()
+		if err != nil {
+			logDebugf("Memd proxy failed to write response to %s: %v", conn.RemoteAddr(), err)
+		}
+	}
+
+	authenticated := mp.username == ""
+	for {
+		pkt, _, err := mconn.ReadPacket()
+		if err != nil {
+			if !errors.Is(err, io.EOF) && !mp.isClosed() {
+				logDebugf("Memd proxy failed to read request from %s: %v", conn.RemoteAddr(), err)
+			}
+			break
+		}
+
+		if pkt.Magic != memd.CmdMagicReq {
+			logDebugf("Memd proxy received unexpected packet magic %x from %s", pkt.Magic, conn.RemoteAddr())
+			break
+		}
+
+		authenticated = mp.handlePacket(pkt, authenticated, write)
+	}
+
+	mp.lock.Lock()
+	delete(mp.conns, conn)
+	mp.lock.Unlock()
+
+	if err := conn.Close(); err != nil {
+		logDebugf("Failed to close memd proxy connection: %v", err)
+	}
+}
+
+// handlePacket handles a single request from a client, returning whether the client is now authenticated.
+func (mp *memdProxyComponent) handlePacket(pkt *memd.Packet, authenticated bool, write func(*memd.Packet)) bool {
+	resp := &memd.Packet{
+		Magic:   memd.CmdMagicRes,
+		Command: pkt.Command,
+		Opaque:  pkt.Opaque,
+	}
+
+	switch pkt.Command {
+	case memd.CmdHello:
+		// No features are negotiated so that responses never need translating for the client.
+		write(resp)
+		return authenticated
+	case memd.CmdSASLListMechs:
+		resp.Value = []byte(PlainAuthMechanism)
+		write(resp)
+		return authenticated
+	case memd.CmdSASLAuth:
+		// The agent has already authenticated on its own connections, the client only needs to prove that it is
+		// allowed to use them.
+		if mp.username != "" {
+			authenticated = mp.checkCredentials(pkt)
+		}
+		if !authenticated {
+			resp.Status = memd.StatusAuthError
+		}
+		write(resp)
+		return authenticated
+	}
+
+	if !authenticated {
+		resp.Status = memd.StatusAuthError
+		write(resp)
+		return false
+	}
+
+	switch pkt.Command {
+	case memd.CmdSelectBucket:
+		// The agent has already selected its bucket on its own connections, which is the only bucket available.
+		if string(pkt.Key) != mp.kvMux.bucketName {
+			resp.Status = memd.StatusAccessError
+		}
+		write(resp)
+	case memd.CmdNoop:
+		write(resp)
+	case memd.CmdGetErrorMap, memd.CmdGetClusterConfig:
+		resp.Status = memd.StatusNotSupported
+		write(resp)
+	default:
+		mp.forward(pkt, write)
+	}
+
+	return true
+}
+
+// checkCredentials checks the credentials in a PLAIN SASL auth request against those configured for the proxy.
+func (mp *memdProxyComponent) checkCredentials(pkt *memd.Packet) bool {
+	if string(pkt.Key) != string(PlainAuthMechanism) {
+		return false
+	}
+
+	// The PLAIN payload is the authorization identity, username and password separated by null bytes.
+	parts := bytes.Split(pkt.Value, []byte{0})
+	if len(parts) != 3 {
+		return false
+	}
+
+	usernameOk := subtle.ConstantTimeCompare(parts[1], []byte(mp.username)) == 1
+	passwordOk := subtle.ConstantTimeCompare(parts[2], []byte(mp.password)) == 1
+	return usernameOk && passwordOk
+}
+
+func (mp *memdProxyComponent) forward(pkt *memd.Packet, write func(*memd.Packet)) {
+	tracer := mp.tracer.StartTelemeteryHandler(metricValueServiceKeyValue, "MemdProxy", nil)
+
+	opaque := pkt.Opaque
+	handler := func(resp *memdQResponse, req *memdQRequest, err error) {
+		tracer.FinishWithError(err)
+
+		out := &memd.Packet{
+			Magic:   memd.CmdMagicRes,
+			Command: pkt.Command,
+			Opaque:  opaque,
+		}
+		if resp != nil {
+			out.Status = resp.Status
+			out.Cas = resp.Cas
+			out.Key = resp.Key
+			out.Value = resp.Value
+			// Mutation responses carry mutation tokens in their extras, which the client has not negotiated.
+			if !isMutationCommand(pkt.Command) {
+				out.Extras = resp.Extras
+			}
+		} else if errors.Is(err, ErrTimeout) {
+			out.Status = memd.StatusTmpFail
+		} else {
+			out.Status = memd.StatusInternalError
+		}
+
+		write(out)
+	}
+
+	req := &memdQRequest{
+		Packet: memd.Packet{
+			Magic:    memd.CmdMagicReq,
+			Command:  pkt.Command,
+			Datatype: pkt.Datatype,
+			Cas:      pkt.Cas,
+			Extras:   pkt.Extras,
+			Key:      pkt.Key,
+			Value:    pkt.Value,
+			Vbucket:  pkt.Vbucket,
+		},
+		Callback:         handler,
+		RootTraceContext: tracer.RootContext(),
+		RetryStrategy:    mp.retryStrategy,
+	}
+
+	if _, err := mp.kvMux.DispatchDirect(req); err != nil {
+		handler(nil, req, err)
+		return
+	}
+
+	start := time.Now()
+	req.SetTimer(time.AfterFunc(mp.timeout, func() {
+		req.cancelWithCallback(makeTimeoutError(start, "MemdProxy", errAmbiguousTimeout, req))
+	}))
+}
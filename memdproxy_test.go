@@ -0,0 +1,162 @@
+package gocbcore
+
+import (
+	"net"
+	"time"
+
+	"github.com/couchbase/gocbcore/v10/memd"
+)
+
+func (suite *UnitTestSuite) TestMemdProxy() {
+	config := MemdProxyConfig{
+		Address: "127.0.0.1:0",
+	}
+	listener, err := listenMemdProxy(config)
+	suite.Require().Nil(err, err)
+
+	tracer := newTracerComponent(&noopTracer{}, "", true, &noopMeter{}, nil)
+	proxy := newMemdProxyComponent(listener, config, &kvMux{bucketName: "default"}, tracer, newFailFastRetryStrategy())
+	suite.Require().NotNil(proxy)
+
+	conn, err := net.Dial("tcp", proxy.Address())
+	suite.Require().Nil(err, err)
+	suite.Require().Nil(conn.SetDeadline(time.Now().Add(5 * time.Second)))
+	mconn := memd.NewConn(conn)
+
+	roundTrip := func(req *memd.Packet) *memd.Packet {
+		suite.Require().Nil(mconn.WritePacket(req))
+		resp, _, err := mconn.ReadPacket()
+		suite.Require().Nil(err, err)
+		suite.Assert().Equal(memd.CmdMagicRes, resp.Magic)
+		suite.Assert().Equal(req.Command, resp.Command)
+		suite.Assert().Equal(req.Opaque, resp.Opaque)
+		return resp
+	}
+
+	resp := roundTrip(&memd.Packet{
+		Magic:   memd.CmdMagicReq,
+		Command: memd.CmdHello,
+		Opaque:  1,
+		Value:   []byte{0x00, byte(memd.FeatureCollections)},
+	})
+	suite.Assert().Equal(memd.StatusSuccess, resp.Status)
+	suite.Assert().Empty(resp.Value)
+
+	resp = roundTrip(&memd.Packet{
+		Magic:   memd.CmdMagicReq,
+		Command: memd.CmdSASLListMechs,
+		Opaque:  2,
+	})
+	suite.Assert().Equal(memd.StatusSuccess, resp.Status)
+	suite.Assert().Equal([]byte("PLAIN"), resp.Value)
+
+	resp = roundTrip(&memd.Packet{
+		Magic:   memd.CmdMagicReq,
+		Command: memd.CmdSelectBucket,
+		Opaque:  3,
+		Key:     []byte("other"),
+	})
+	suite.Assert().Equal(memd.StatusAccessError, resp.Status)
+
+	resp = roundTrip(&memd.Packet{
+		Magic:   memd.CmdMagicReq,
+		Command: memd.CmdSelectBucket,
+		Opaque:  3,
+		Key:     []byte("default"),
+	})
+	suite.Assert().Equal(memd.StatusSuccess, resp.Status)
+
+	resp = roundTrip(&memd.Packet{
+		Magic:   memd.CmdMagicReq,
+		Command: memd.CmdGetClusterConfig,
+		Opaque:  3,
+	})
+	suite.Assert().Equal(memd.StatusNotSupported, resp.Status)
+
+	// The mux has no state so forwarded requests fail to dispatch.
+	resp = roundTrip(&memd.Packet{
+		Magic:   memd.CmdMagicReq,
+		Command: memd.CmdGet,
+		Opaque:  4,
+		Key:     []byte("key"),
+	})
+	suite.Assert().Equal(memd.StatusInternalError, resp.Status)
+
+	suite.Require().Nil(proxy.Close())
+
+	// Closing the proxy closes proxied connections.
+	_, _, err = mconn.ReadPacket()
+	suite.Assert().NotNil(err)
+	suite.Require().Nil(conn.Close())
+
+	_, err = net.Dial("tcp", proxy.Address())
+	suite.Assert().NotNil(err)
+}
+
+func (suite *UnitTestSuite) TestMemdProxyDisabled() {
+	listener, err := listenMemdProxy(MemdProxyConfig{})
+	suite.Require().Nil(err, err)
+	suite.Assert().Nil(listener)
+	suite.Assert().Nil(newMemdProxyComponent(listener, MemdProxyConfig{}, &kvMux{}, nil, nil))
+}
+
+func (suite *UnitTestSuite) TestMemdProxyRequiresLoopbackWithoutCredentials() {
+	for _, address := range []string{":0", "0.0.0.0:0", "10.112.210.101:0", "example.com:0"} {
+		_, err := listenMemdProxy(MemdProxyConfig{Address: address})
+		suite.Assert().ErrorIs(err, ErrInvalidArgument, address)
+	}
+
+	for _, address := range []string{"127.0.0.1:0", "localhost:0", "[::1]:0"} {
+		suite.Assert().True(isLoopbackAddress(address), address)
+	}
+}
+
+func (suite *UnitTestSuite) TestMemdProxyAuthentication() {
+	config := MemdProxyConfig{
+		Address:  "127.0.0.1:0",
+		Username: "user",
+		Password: "pass",
+	}
+	listener, err := listenMemdProxy(config)
+	suite.Require().Nil(err, err)
+
+	tracer := newTracerComponent(&noopTracer{}, "", true, &noopMeter{}, nil)
+	proxy := newMemdProxyComponent(listener, config, &kvMux{bucketName: "default"}, tracer, newFailFastRetryStrategy())
+	suite.Require().NotNil(proxy)
+	defer func() {
+		suite.Require().Nil(proxy.Close())
+	}()
+
+	conn, err := net.Dial("tcp", proxy.Address())
+	suite.Require().Nil(err, err)
+	defer conn.Close()
+	suite.Require().Nil(conn.SetDeadline(time.Now().Add(5 * time.Second)))
+	mconn := memd.NewConn(conn)
+
+	roundTrip := func(req *memd.Packet) memd.StatusCode {
+		req.Magic = memd.CmdMagicReq
+		suite.Require().Nil(mconn.WritePacket(req))
+		resp, _, err := mconn.ReadPacket()
+		suite.Require().Nil(err, err)
+		return resp.Status
+	}
+	auth := func(username, password string) memd.StatusCode {
+		return roundTrip(&memd.Packet{
+			Command: memd.CmdSASLAuth,
+			Key:     []byte(PlainAuthMechanism),
+			Value:   []byte("\x00" + username + "\x00" + password),
+		})
+	}
+
+	// Nothing is allowed until the client has authenticated.
+	suite.Assert().Equal(memd.StatusAuthError, roundTrip(&memd.Packet{Command: memd.CmdSelectBucket, Key: []byte("default")}))
+	suite.Assert().Equal(memd.StatusAuthError, roundTrip(&memd.Packet{Command: memd.CmdGet, Key: []byte("key")}))
+
+	suite.Assert().Equal(memd.StatusAuthError, auth("user", "wrong"))
+	suite.Assert().Equal(memd.StatusAuthError, auth("other", "pass"))
+	suite.Assert().Equal(memd.StatusAuthError, roundTrip(&memd.Packet{Command: memd.CmdNoop}))
+
+	suite.Assert().Equal(memd.StatusSuccess, auth("user", "pass"))
+	suite.Assert().Equal(memd.StatusSuccess, roundTrip(&memd.Packet{Command: memd.CmdSelectBucket, Key: []byte("default")}))
+	suite.Assert().Equal(memd.StatusSuccess, roundTrip(&memd.Packet{Command: memd.CmdNoop}))
+}
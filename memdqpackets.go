@@ -46,6 +46,26 @@ type memdQRequest struct {
 	//   whenever the request is cancelled.
 	queuedWith unsafe.Pointer
 
+	// This tracks when the request was last pushed into a queue so that
+	//  we can measure how long requests are waiting to be written.
+	queuedTime time.Time
+
+	// pinnedConnID, if set, is the ID of the only connection that the request may be sent on. The request is failed,
+	//  rather than being rerouted, if it needs to be retried once that connection has gone.
+	pinnedConnID string
+
+	// dispatchDeadline is the deadline of the operation, dispatching the request may block waiting for space in a full
+	//  queue until then, capped to the configured maximum wait. Requests without one never block, which is the case
+	//  for anything dispatched from the read path, and neither do retries.
+	dispatchDeadline time.Time
+
+	// selectReplicaFn, if set, is used to pick the node that the request is sent to again each time that the request
+	//  is rerouted, by updating ReplicaIdx.
+	selectReplicaFn func(req *memdQRequest)
+
+	// userMetadata is the opaque value provided by the user in the options of the operation.
+	userMetadata interface{}
+
 	// This stores a pointer to the opList that currently is holding
 	//  this request.  This allows us to remove it form that list
 	//  whenever the request is cancelled
@@ -73,6 +93,9 @@ type memdQRequest struct {
 	// This is the set of reasons why this request has been retried.
 	retryReasons []RetryReason
 
+	// This is the duration that the server last indicated that the request should wait before being retried.
+	serverRetryAfter time.Duration
+
 	// This is used to lock access to the request when processing
 	// retry reasons or attempts.
 	retryLock sync.Mutex
@@ -93,6 +116,20 @@ type memdQRequest struct {
 
 	resourceUnitsLock sync.Mutex
 	resourceUnits     *ResourceUnitResult
+
+	// rateLimitedBytes is the number of bytes charged against the client side rate limiter's in-flight byte budget
+	// whilst this request is in flight.
+	rateLimitedBytes uint32
+
+	// These are used when the request has been acquired from the request pool. The request is only returned to the
+	// pool once both the dispatching operation and the completion path have released it, and only if it completed
+	// successfully on the first attempt without its timer firing. The generation is bumped on each release so that
+	// stale PendingOp handles cannot cancel the request once it has been reused.
+	pooled         bool
+	poolRefs       int32
+	poolReleasable uint32
+	timerStopped   uint32
+	generation     uint32
 }
 
 type memdQRequestConnInfo struct {
@@ -167,6 +204,22 @@ func (req *memdQRequest) Retries() (uint32, []RetryReason) {
 	return req.retryCount, req.retryReasons
 }
 
+func (req *memdQRequest) ServerRetryAfter() time.Duration {
+	req.retryLock.Lock()
+	defer req.retryLock.Unlock()
+	return req.serverRetryAfter
+}
+
+func (req *memdQRequest) setServerRetryAfter(duration time.Duration) {
+	req.retryLock.Lock()
+	req.serverRetryAfter = duration
+	req.retryLock.Unlock()
+}
+
+func (req *memdQRequest) operationDeadline() time.Time {
+	return req.dispatchDeadline
+}
+
 func (req *memdQRequest) retryStrategy() RetryStrategy {
 	return req.RetryStrategy
 }
@@ -188,6 +241,11 @@ func (req *memdQRequest) ConnectionInfo() memdQRequestConnInfo {
 	return p.(memdQRequestConnInfo)
 }
 
+// UserMetadata returns the opaque value which was provided in the options of the operation, if any.
+func (req *memdQRequest) UserMetadata() interface{} {
+	return req.userMetadata
+}
+
 func (req *memdQRequest) SetConnectionInfo(info memdQRequestConnInfo) {
 	req.connInfo.Store(info)
 }
@@ -225,7 +283,9 @@ func (req *memdQRequest) recordRetryAttempt(retryReason RetryReason) {
 
 func (req *memdQRequest) tryCallback(resp *memdQResponse, err error) {
 	if t := req.Timer(); t != nil {
-		t.Stop()
+		if t.Stop() {
+			atomic.StoreUint32(&req.timerStopped, 1)
+		}
 	}
 
 	if req.Persistent {
@@ -241,6 +301,11 @@ func (req *memdQRequest) tryCallback(resp *memdQResponse, err error) {
 	} else {
 		if atomic.SwapUint32(&req.isCompleted, 1) == 0 {
 			req.Callback(resp, req, err)
+
+			if err == nil && req.pooled && req.RetryAttempts() == 0 {
+				atomic.StoreUint32(&req.poolReleasable, 1)
+				req.release()
+			}
 		}
 	}
 }
@@ -251,10 +316,15 @@ func (req *memdQRequest) isCancelled() bool {
 
 func (req *memdQRequest) internalCancel(err error) bool {
 	req.processingLock.Lock()
+	cancelled := req.internalCancelLocked(err)
+	req.processingLock.Unlock()
 
+	return cancelled
+}
+
+func (req *memdQRequest) internalCancelLocked(err error) bool {
 	if atomic.SwapUint32(&req.isCompleted, 1) != 0 {
 		// Someone already completed this request
-		req.processingLock.Unlock()
 		return false
 	}
 
@@ -280,7 +350,6 @@ func (req *memdQRequest) internalCancel(err error) bool {
 	}
 
 	cancelReqTraceLocked(req, localAddr, remoteAddr)
-	req.processingLock.Unlock()
 
 	return true
 }
@@ -298,14 +367,19 @@ func (req *memdQRequest) cancelWithCallbackAndFinishTracer(err error, tracer *op
 	// callback immediately on the users behalf.
 	// Only if cancel succeeds we also finish the tracer.
 	if req.internalCancel(err) {
-		tracer.Finish()
+		tracer.FinishWithError(err)
 		req.Callback(nil, req, err)
 	}
 }
 
-func (req *memdQRequest) Cancel() {
+func (req *memdQRequest) Cancel() bool {
 	// Try to perform the cancellation, if it succeeds, we call the
 	// callback immediately on the users behalf.
 	err := errRequestCanceled
-	req.cancelWithCallback(err)
+	if req.internalCancel(err) {
+		req.Callback(nil, req, err)
+		return true
+	}
+
+	return false
 }
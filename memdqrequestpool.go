@@ -0,0 +1,131 @@
+package gocbcore
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/couchbase/gocbcore/v10/memd"
+)
+
+// memdQRequestPool holds requests for reuse by the hot path operations. Requests are only ever returned to the pool
+// on the happy path, anything which fails, retries or times out is left to the garbage collector as there may still
+// be references to it that we cannot account for.
+var memdQRequestPool = sync.Pool{
+	New: func() interface{} {
+		return &memdQRequest{}
+	},
+}
+
+// acquireMemdQRequest retrieves a request from the pool. The request starts with two references, one held by the
+// dispatching operation and one by the completion path, the dispatching operation must call release once it has
+// finished setting up the request.
+func acquireMemdQRequest() *memdQRequest {
+	req := memdQRequestPool.Get().(*memdQRequest)
+	req.pooled = true
+	req.poolRefs = 2
+
+	return req
+}
+
+// release drops a reference to a pooled request, returning the request to the pool once there are no references
+// left and the request is known to have completed successfully.
+func (req *memdQRequest) release() {
+	if !req.pooled {
+		return
+	}
+
+	if atomic.AddInt32(&req.poolRefs, -1) != 0 {
+		return
+	}
+
+	if atomic.LoadUint32(&req.poolReleasable) == 0 {
+		return
+	}
+
+	if t := req.Timer(); t != nil && !t.Stop() && atomic.LoadUint32(&req.timerStopped) == 0 {
+		// The timer has fired so its callback may still be referencing the request.
+		return
+	}
+
+	req.processingLock.Lock()
+	req.reset()
+	req.processingLock.Unlock()
+
+	memdQRequestPool.Put(req)
+}
+
+// reset clears the request for reuse, this must be called with the processing lock held. The processing lock itself
+// is left alone as stale PendingOp handles may be waiting on it.
+func (req *memdQRequest) reset() {
+	req.Packet = memd.Packet{}
+	req.ReplicaIdx = 0
+	req.Callback = nil
+	req.Persistent = false
+	req.ServerGroup = ""
+	req.pinnedConnID = ""
+	req.selectReplicaFn = nil
+	req.dispatchTime = time.Time{}
+	req.queuedTime = time.Time{}
+	req.dispatchDeadline = time.Time{}
+	atomic.StorePointer(&req.queuedWith, nil)
+	atomic.StorePointer(&req.waitingIn, nil)
+	atomic.StoreUint32(&req.isCompleted, 0)
+	req.retryCount = 0
+	req.RetryStrategy = nil
+	req.retryReasons = nil
+	req.serverRetryAfter = 0
+	req.timer = atomic.Value{}
+	req.connInfo = atomic.Value{}
+	req.RootTraceContext = nil
+	req.cmdTraceSpan = nil
+	req.netTraceSpan = nil
+	req.CollectionName = ""
+	req.ScopeName = ""
+	req.resourceUnits = nil
+	req.userMetadata = nil
+	atomic.StoreUint32(&req.rateLimitedBytes, 0)
+	req.pooled = false
+	req.poolRefs = 0
+	atomic.StoreUint32(&req.poolReleasable, 0)
+	atomic.StoreUint32(&req.timerStopped, 0)
+	atomic.AddUint32(&req.generation, 1)
+}
+
+// pendingOp returns the handle which should be given to the user for the request. Pooled requests are wrapped so
+// that cancelling a request after it has been reused has no effect.
+func (req *memdQRequest) pendingOp() PendingOp {
+	if !req.pooled {
+		return req
+	}
+
+	return memdQRequestHandle{
+		req:        req,
+		generation: atomic.LoadUint32(&req.generation),
+	}
+}
+
+type memdQRequestHandle struct {
+	req        *memdQRequest
+	generation uint32
+}
+
+func (h memdQRequestHandle) Cancel() bool {
+	req := h.req
+	err := errRequestCanceled
+
+	req.processingLock.Lock()
+	if atomic.LoadUint32(&req.generation) != h.generation {
+		// The request has completed and been reused for another operation.
+		req.processingLock.Unlock()
+		return false
+	}
+	cancelled := req.internalCancelLocked(err)
+	req.processingLock.Unlock()
+
+	if cancelled {
+		req.Callback(nil, req, err)
+	}
+
+	return cancelled
+}
@@ -0,0 +1,199 @@
+package gocbcore
+
+import (
+	"errors"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/couchbase/gocbcore/v10/memd"
+)
+
+func (suite *UnitTestSuite) TestMemdQRequestResetClearsAllFields() {
+	req := acquireMemdQRequest()
+	req.Packet = memd.Packet{Key: []byte("key"), Opaque: 5}
+	req.ReplicaIdx = 1
+	req.Callback = func(*memdQResponse, *memdQRequest, error) {}
+	req.Persistent = true
+	req.ServerGroup = "group"
+	req.dispatchTime = time.Now()
+	req.queuedTime = time.Now()
+	req.dispatchDeadline = time.Now()
+	req.isCompleted = 1
+	req.recordRetryAttempt(KVLockedRetryReason)
+	req.RetryStrategy = newFailFastRetryStrategy()
+	req.serverRetryAfter = time.Second
+	req.SetTimer(time.NewTimer(time.Hour))
+	req.SetConnectionInfo(memdQRequestConnInfo{lastConnectionID: "conn"})
+	req.CollectionName = "collection"
+	req.ScopeName = "scope"
+	req.AddResourceUnitsFromUnitResult(&ResourceUnitResult{ReadUnits: 1})
+	req.poolReleasable = 1
+	req.timerStopped = 1
+	req.Timer().Stop()
+	generation := req.generation
+
+	req.processingLock.Lock()
+	req.reset()
+	req.processingLock.Unlock()
+
+	suite.Assert().Equal(generation+1, req.generation)
+
+	val := reflect.ValueOf(req).Elem()
+	for i := 0; i < val.NumField(); i++ {
+		name := val.Type().Field(i).Name
+		if name == "generation" {
+			continue
+		}
+
+		suite.Assert().True(val.Field(i).IsZero(), "field %s was not reset", name)
+	}
+}
+
+func (suite *UnitTestSuite) TestMemdQRequestReleasedOnHappyPath() {
+	req := acquireMemdQRequest()
+	req.Callback = func(*memdQResponse, *memdQRequest, error) {}
+	generation := req.generation
+
+	req.release()
+	suite.Assert().Equal(generation, req.generation)
+
+	req.tryCallback(&memdQResponse{Packet: &memd.Packet{}}, nil)
+	suite.Assert().Equal(generation+1, req.generation)
+}
+
+func (suite *UnitTestSuite) TestMemdQRequestNotReleasedOnError() {
+	req := acquireMemdQRequest()
+	req.Callback = func(*memdQResponse, *memdQRequest, error) {}
+	generation := req.generation
+
+	req.tryCallback(nil, errors.New("failed"))
+	req.release()
+
+	suite.Assert().Equal(generation, req.generation)
+	suite.Assert().Equal(uint32(1), atomic.LoadUint32(&req.isCompleted))
+}
+
+func (suite *UnitTestSuite) TestMemdQRequestNotReleasedAfterTimerFired() {
+	req := acquireMemdQRequest()
+	req.Callback = func(*memdQResponse, *memdQRequest, error) {}
+	generation := req.generation
+
+	fired := make(chan struct{})
+	req.SetTimer(time.AfterFunc(0, func() {
+		close(fired)
+	}))
+	<-fired
+
+	req.release()
+	req.tryCallback(&memdQResponse{Packet: &memd.Packet{}}, nil)
+
+	suite.Assert().Equal(generation, req.generation)
+}
+
+func (suite *UnitTestSuite) TestMemdQRequestStaleHandleCancel() {
+	req := acquireMemdQRequest()
+	req.Callback = func(*memdQResponse, *memdQRequest, error) {}
+	generation := req.generation
+
+	op := req.pendingOp()
+	req.release()
+	req.tryCallback(&memdQResponse{Packet: &memd.Packet{}}, nil)
+	suite.Require().Equal(generation+1, req.generation)
+
+	// Simulate the request having been reused for a new operation.
+	req.pooled = true
+	req.Callback = func(*memdQResponse, *memdQRequest, error) {
+		suite.T().Errorf("callback should not have been called")
+	}
+
+	op.Cancel()
+
+	suite.Assert().Equal(uint32(0), atomic.LoadUint32(&req.isCompleted))
+}
+
+// benchDispatcher completes every request as soon as it is dispatched, so that benchmarks only measure the cost of
+// the operation itself.
+type benchDispatcher struct {
+	resp *memdQResponse
+}
+
+func (d *benchDispatcher) DispatchDirect(req *memdQRequest) (PendingOp, error) {
+	req.tryCallback(d.resp, nil)
+	return req, nil
+}
+
+func (d *benchDispatcher) RequeueDirect(req *memdQRequest, isRetry bool) {}
+
+func (d *benchDispatcher) DispatchDirectToAddress(req *memdQRequest, address string) (PendingOp, error) {
+	return d.DispatchDirect(req)
+}
+
+func (d *benchDispatcher) CollectionsEnabled() bool {
+	return false
+}
+
+func (d *benchDispatcher) SupportsCollections() bool {
+	return false
+}
+
+func (d *benchDispatcher) SetPostCompleteErrorHandler(handler postCompleteErrorHandler) {}
+
+func (d *benchDispatcher) PipelineSnapshot() (*pipelineSnapshot, error) {
+	return nil, errCliInternalError
+}
+
+func newBenchCrudComponent(resp *memdQResponse) *crudComponent {
+	return &crudComponent{
+		cidMgr: &collectionsComponent{
+			dispatcher: &benchDispatcher{resp: resp},
+		},
+		defaultRetryStrategy: newFailFastRetryStrategy(),
+		tracer:               newTracerComponent(&noopTracer{}, "", true, &noopMeter{}, nil),
+	}
+}
+
+func BenchmarkPooledGet(b *testing.B) {
+	crud := newBenchCrudComponent(&memdQResponse{
+		Packet: &memd.Packet{
+			Extras: make([]byte, 4),
+			Value:  []byte(`{"name":"value"}`),
+		},
+	})
+	cb := func(*GetResult, error) {}
+	opts := GetOptions{
+		Key:      []byte("key"),
+		Deadline: time.Now().Add(time.Hour),
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := crud.Get(opts, cb); err != nil {
+			b.Fatalf("Get failed: %v", err)
+		}
+	}
+}
+
+func BenchmarkPooledSet(b *testing.B) {
+	crud := newBenchCrudComponent(&memdQResponse{
+		Packet: &memd.Packet{
+			Extras: make([]byte, 16),
+		},
+	})
+	cb := func(*StoreResult, error) {}
+	opts := SetOptions{
+		Key:      []byte("key"),
+		Value:    []byte(`{"name":"value"}`),
+		Deadline: time.Now().Add(time.Hour),
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		if _, err := crud.Set(opts, cb); err != nil {
+			b.Fatalf("Set failed: %v", err)
+		}
+	}
+}
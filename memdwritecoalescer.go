@@ -0,0 +1,117 @@
+package gocbcore
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+const defaultMaxWriteBatchSize = 64 * 1024
+
+// memdWriteCoalescer gathers writes which arrive within the flush interval of one another into a single write on the
+// underlying connection, reducing the number of syscalls needed when sending many small requests. Writes are flushed
+// once the flush interval has elapsed since the first buffered write, or as soon as the buffered data reaches the
+// maximum batch size.
+// If a flush fails then the connection is closed, so that the requests which were buffered are failed by the read
+// side of the connection, and all subsequent writes return the error.
+type memdWriteCoalescer struct {
+	writer        io.Writer
+	closer        io.Closer
+	flushInterval time.Duration
+	maxBatchSize  int
+
+	lock         sync.Mutex
+	buf          []byte
+	timer        *time.Timer
+	flushPending bool
+	err          error
+}
+
+func newMemdWriteCoalescer(conn io.WriteCloser, flushInterval time.Duration, maxBatchSize int) *memdWriteCoalescer {
+	if maxBatchSize <= 0 {
+		maxBatchSize = defaultMaxWriteBatchSize
+	}
+
+	return &memdWriteCoalescer{
+		writer:        conn,
+		closer:        conn,
+		flushInterval: flushInterval,
+		maxBatchSize:  maxBatchSize,
+	}
+}
+
+func (w *memdWriteCoalescer) Write(p []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	if w.err != nil {
+		return 0, w.err
+	}
+
+	w.buf = append(w.buf, p...)
+	if len(w.buf) >= w.maxBatchSize {
+		w.flushLocked()
+		if w.err != nil {
+			return 0, w.err
+		}
+
+		return len(p), nil
+	}
+
+	if !w.flushPending {
+		w.flushPending = true
+		if w.timer == nil {
+			w.timer = time.AfterFunc(w.flushInterval, w.flush)
+		} else {
+			w.timer.Reset(w.flushInterval)
+		}
+	}
+
+	return len(p), nil
+}
+
+func (w *memdWriteCoalescer) flush() {
+	w.lock.Lock()
+	w.flushLocked()
+	w.lock.Unlock()
+}
+
+func (w *memdWriteCoalescer) flushLocked() {
+	if w.flushPending {
+		w.timer.Stop()
+		w.flushPending = false
+	}
+
+	if len(w.buf) == 0 || w.err != nil {
+		return
+	}
+
+	n, err := w.writer.Write(w.buf)
+	if err == nil && n != len(w.buf) {
+		err = io.ErrShortWrite
+	}
+	w.buf = w.buf[:0]
+
+	if err != nil {
+		logDebugf("Failed to flush coalesced writes, closing connection: %v", err)
+		w.err = err
+		_ = w.closer.Close()
+	}
+}
+
+// Close stops any pending flush and closes the underlying connection. Buffered writes are discarded, the requests
+// which they belong to will be failed as the connection closes.
+func (w *memdWriteCoalescer) Close() error {
+	w.lock.Lock()
+	if w.flushPending {
+		w.timer.Stop()
+		w.flushPending = false
+	}
+	w.buf = nil
+	if w.err == nil {
+		w.err = io.ErrClosedPipe
+	}
+	w.lock.Unlock()
+
+	return w.closer.Close()
+}
@@ -0,0 +1,108 @@
+package gocbcore
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"time"
+)
+
+type testCoalescedConn struct {
+	lock   sync.Mutex
+	writes [][]byte
+	err    error
+	closed bool
+}
+
+func (c *testCoalescedConn) Write(p []byte) (int, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.err != nil {
+		return 0, c.err
+	}
+	c.writes = append(c.writes, append([]byte{}, p...))
+	return len(p), nil
+}
+
+func (c *testCoalescedConn) Close() error {
+	c.lock.Lock()
+	c.closed = true
+	c.lock.Unlock()
+	return nil
+}
+
+func (c *testCoalescedConn) Writes() [][]byte {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.writes
+}
+
+func (suite *UnitTestSuite) TestMemdWriteCoalescerFlushesAfterInterval() {
+	conn := &testCoalescedConn{}
+	w := newMemdWriteCoalescer(conn, 20*time.Millisecond, 0)
+
+	for _, p := range []string{"one", "two", "three"} {
+		n, err := w.Write([]byte(p))
+		suite.Require().Nil(err, err)
+		suite.Assert().Equal(len(p), n)
+	}
+	suite.Assert().Empty(conn.Writes())
+
+	suite.Require().Eventually(func() bool {
+		return len(conn.Writes()) == 1
+	}, time.Second, time.Millisecond)
+	suite.Assert().Equal([]byte("onetwothree"), conn.Writes()[0])
+
+	_, err := w.Write([]byte("four"))
+	suite.Require().Nil(err, err)
+	suite.Require().Eventually(func() bool {
+		return len(conn.Writes()) == 2
+	}, time.Second, time.Millisecond)
+	suite.Assert().Equal([]byte("four"), conn.Writes()[1])
+
+	suite.Require().Nil(w.Close())
+}
+
+func (suite *UnitTestSuite) TestMemdWriteCoalescerFlushesAtBatchSize() {
+	conn := &testCoalescedConn{}
+	w := newMemdWriteCoalescer(conn, time.Hour, 8)
+
+	_, err := w.Write([]byte("1234"))
+	suite.Require().Nil(err, err)
+	suite.Assert().Empty(conn.Writes())
+
+	_, err = w.Write([]byte("5678"))
+	suite.Require().Nil(err, err)
+	suite.Require().Len(conn.Writes(), 1)
+	suite.Assert().Equal([]byte("12345678"), conn.Writes()[0])
+
+	suite.Require().Nil(w.Close())
+}
+
+func (suite *UnitTestSuite) TestMemdWriteCoalescerFlushErrorClosesConn() {
+	writeErr := errors.New("broken pipe")
+	conn := &testCoalescedConn{err: writeErr}
+	w := newMemdWriteCoalescer(conn, time.Hour, 4)
+
+	_, err := w.Write([]byte("1234"))
+	suite.Assert().Equal(writeErr, err)
+	suite.Assert().True(conn.closed)
+
+	_, err = w.Write([]byte("5678"))
+	suite.Assert().Equal(writeErr, err)
+}
+
+func (suite *UnitTestSuite) TestMemdWriteCoalescerCloseDiscardsPending() {
+	conn := &testCoalescedConn{}
+	w := newMemdWriteCoalescer(conn, 10*time.Millisecond, 0)
+
+	_, err := w.Write([]byte("1234"))
+	suite.Require().Nil(err, err)
+	suite.Require().Nil(w.Close())
+
+	time.Sleep(30 * time.Millisecond)
+	suite.Assert().Empty(conn.Writes())
+
+	_, err = w.Write([]byte("5678"))
+	suite.Assert().Equal(io.ErrClosedPipe, err)
+}
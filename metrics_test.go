@@ -74,7 +74,10 @@ func (tm *testMeter) Reset() {
 }
 
 func (tm *testMeter) Counter(name string, tags map[string]string) (Counter, error) {
-	key := tags["db.operation"]
+	key := name + ":" + tags["db.couchbase.service"]
+	if op, ok := tags["db.operation"]; ok {
+		key = key + ":" + op
+	}
 	tm.lock.Lock()
 	counter := tm.counters[key]
 	if counter == nil {
@@ -87,6 +90,9 @@ func (tm *testMeter) Counter(name string, tags map[string]string) (Counter, erro
 
 func (tm *testMeter) ValueRecorder(name string, tags map[string]string) (ValueRecorder, error) {
 	key := tags["db.couchbase.service"]
+	if name != meterNameCBOperations {
+		key = name + ":" + key
+	}
 	if op, ok := tags["db.operation"]; ok {
 		key = key + ":" + op
 	}
@@ -0,0 +1,291 @@
+package gocbcore
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"time"
+)
+
+// MirrorMismatch describes a difference observed between the result of a read operation against the
+// primary agent and the result of the same operation mirrored to the secondary agent.
+// Volatile: This API is subject to change at any time.
+type MirrorMismatch struct {
+	OperationName  string
+	Key            []byte
+	ScopeName      string
+	CollectionName string
+
+	PrimaryValue []byte
+	PrimaryFlags uint32
+	PrimaryError error
+
+	MirrorValue []byte
+	MirrorFlags uint32
+	MirrorError error
+}
+
+// MirrorMismatchCallback is invoked whenever a mirrored read operation returns a result which differs from the
+// result returned by the primary agent.
+// Volatile: This API is subject to change at any time.
+type MirrorMismatchCallback func(mismatch MirrorMismatch)
+
+// MirrorConfig specifies options for duplicating a percentage of read operations to a second agent, such as an
+// agent connected to a new cluster which is being validated. Mirrored operations are dispatched in the background once
+// the primary operation has completed and never affect the result returned to the caller, or its latency.
+// Volatile: This API is subject to change at any time.
+type MirrorConfig struct {
+	// Agent is the agent which read operations will be mirrored to.
+	Agent *Agent
+	// Percentage is the percentage of read operations, between 0 and 100, which will be mirrored.
+	Percentage float64
+	// Timeout is the timeout applied to mirrored operations, defaults to 2.5 seconds.
+	Timeout time.Duration
+	// MismatchCallback is invoked whenever a mirrored result differs from the primary result.
+	MismatchCallback MirrorMismatchCallback
+}
+
+// mirrorMaxInFlight is the maximum number of mirrored operations which may be in flight at once, operations are not
+// mirrored whilst this many are in flight.
+const mirrorMaxInFlight = 256
+
+type mirrorComponent struct {
+	get        func(GetOptions, GetCallback) (PendingOp, error)
+	lookupIn   func(LookupInOptions, LookupInCallback) (PendingOp, error)
+	percentage float64
+	timeout    time.Duration
+	onMismatch MirrorMismatchCallback
+	inFlight   chan struct{}
+}
+
+func newMirrorComponent(config MirrorConfig) *mirrorComponent {
+	if config.Agent == nil || config.Percentage <= 0 {
+		return nil
+	}
+
+	percentage := config.Percentage
+	if percentage > 100 {
+		percentage = 100
+	}
+
+	timeout := 2500 * time.Millisecond
+	if config.Timeout > 0 {
+		timeout = config.Timeout
+	}
+
+	return &mirrorComponent{
+		get:        config.Agent.Get,
+		lookupIn:   config.Agent.LookupIn,
+		percentage: percentage,
+		timeout:    timeout,
+		onMismatch: config.MismatchCallback,
+		inFlight:   make(chan struct{}, mirrorMaxInFlight),
+	}
+}
+
+func (mc *mirrorComponent) shouldMirror() bool {
+	if mc == nil {
+		return false
+	}
+
+	return rand.Float64()*100 < mc.percentage // #nosec G404
+}
+
+// goMirror runs fn, which must dispatch a mirrored operation and wait for its result, on a new goroutine so that
+// mirroring never delays the primary operation or blocks the goroutine which handled its response.
+func (mc *mirrorComponent) goMirror(opName string, fn func() error) {
+	select {
+	case mc.inFlight <- struct{}{}:
+	default:
+		logDebugf("Not mirroring %s as too many mirrored operations are in flight", opName)
+		return
+	}
+
+	go func() {
+		defer func() {
+			<-mc.inFlight
+		}()
+
+		if err := fn(); err != nil {
+			logDebugf("Failed to dispatch mirrored %s: %v", opName, err)
+		}
+	}()
+}
+
+// WrapGet returns a callback which will, if this operation is sampled, mirror the Get to the secondary agent once
+// the primary operation has completed.
+func (mc *mirrorComponent) WrapGet(opts GetOptions, cb GetCallback) GetCallback {
+	if !mc.shouldMirror() {
+		return cb
+	}
+
+	return func(res *GetResult, err error) {
+		cb(res, err)
+
+		mc.goMirror("Get", func() error {
+			type mirrorResult struct {
+				res *GetResult
+				err error
+			}
+			resultCh := make(chan mirrorResult, 1)
+			_, dispatchErr := mc.get(GetOptions{
+				Key:            opts.Key,
+				CollectionName: opts.CollectionName,
+				ScopeName:      opts.ScopeName,
+				User:           opts.User,
+				Deadline:       time.Now().Add(mc.timeout),
+			}, func(mirrorRes *GetResult, mirrorErr error) {
+				resultCh <- mirrorResult{res: mirrorRes, err: mirrorErr}
+			})
+			if dispatchErr != nil {
+				return dispatchErr
+			}
+			mirrored := <-resultCh
+
+			mismatch := MirrorMismatch{
+				OperationName:  "Get",
+				Key:            opts.Key,
+				ScopeName:      opts.ScopeName,
+				CollectionName: opts.CollectionName,
+				PrimaryError:   err,
+				MirrorError:    mirrored.err,
+			}
+			if res != nil {
+				mismatch.PrimaryValue = res.Value
+				mismatch.PrimaryFlags = res.Flags
+			}
+			if mirrored.res != nil {
+				mismatch.MirrorValue = mirrored.res.Value
+				mismatch.MirrorFlags = mirrored.res.Flags
+			}
+
+			mc.compare(mismatch)
+			return nil
+		})
+	}
+}
+
+// WrapLookupIn returns a callback which will, if this operation is sampled, mirror the LookupIn to the secondary
+// agent once the primary operation has completed.
+func (mc *mirrorComponent) WrapLookupIn(opts LookupInOptions, cb LookupInCallback) LookupInCallback {
+	if !mc.shouldMirror() {
+		return cb
+	}
+
+	return func(res *LookupInResult, err error) {
+		cb(res, err)
+
+		mc.goMirror("LookupIn", func() error {
+			type mirrorResult struct {
+				res *LookupInResult
+				err error
+			}
+			resultCh := make(chan mirrorResult, 1)
+			_, dispatchErr := mc.lookupIn(LookupInOptions{
+				Key:            opts.Key,
+				Flags:          opts.Flags,
+				Ops:            opts.Ops,
+				CollectionName: opts.CollectionName,
+				ScopeName:      opts.ScopeName,
+				User:           opts.User,
+				Deadline:       time.Now().Add(mc.timeout),
+			}, func(mirrorRes *LookupInResult, mirrorErr error) {
+				resultCh <- mirrorResult{res: mirrorRes, err: mirrorErr}
+			})
+			if dispatchErr != nil {
+				return dispatchErr
+			}
+			mirrored := <-resultCh
+
+			mismatch := MirrorMismatch{
+				OperationName:  "LookupIn",
+				Key:            opts.Key,
+				ScopeName:      opts.ScopeName,
+				CollectionName: opts.CollectionName,
+				PrimaryError:   err,
+				MirrorError:    mirrored.err,
+			}
+			if err == nil && mirrored.err == nil && !lookupInResultsMatch(res, mirrored.res) {
+				mismatch.PrimaryValue = encodeLookupInValues(res)
+				mismatch.MirrorValue = encodeLookupInValues(mirrored.res)
+				mc.report(mismatch)
+				return nil
+			}
+
+			mc.compare(mismatch)
+			return nil
+		})
+	}
+}
+
+func (mc *mirrorComponent) compare(mismatch MirrorMismatch) {
+	if !mirrorErrorsMatch(mismatch.PrimaryError, mismatch.MirrorError) {
+		mc.report(mismatch)
+		return
+	}
+
+	if mismatch.PrimaryError != nil {
+		return
+	}
+
+	if mismatch.PrimaryFlags != mismatch.MirrorFlags || !bytes.Equal(mismatch.PrimaryValue, mismatch.MirrorValue) {
+		mc.report(mismatch)
+	}
+}
+
+func (mc *mirrorComponent) report(mismatch MirrorMismatch) {
+	logDebugf("Mirrored %s result did not match primary result", mismatch.OperationName)
+	if mc.onMismatch != nil {
+		mc.onMismatch(mismatch)
+	}
+}
+
+func mirrorErrorsMatch(primary, mirror error) bool {
+	if primary == nil || mirror == nil {
+		return primary == nil && mirror == nil
+	}
+
+	var primaryKvErr, mirrorKvErr *KeyValueError
+	if errors.As(primary, &primaryKvErr) && errors.As(mirror, &mirrorKvErr) {
+		return primaryKvErr.InnerError == mirrorKvErr.InnerError
+	}
+
+	return errors.Is(mirror, primary) || errors.Is(primary, mirror)
+}
+
+func lookupInResultsMatch(primary, mirror *LookupInResult) bool {
+	if primary == nil || mirror == nil {
+		return primary == nil && mirror == nil
+	}
+
+	if len(primary.Ops) != len(mirror.Ops) {
+		return false
+	}
+
+	for i := range primary.Ops {
+		if !mirrorErrorsMatch(primary.Ops[i].Err, mirror.Ops[i].Err) {
+			return false
+		}
+		if !bytes.Equal(primary.Ops[i].Value, mirror.Ops[i].Value) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func encodeLookupInValues(res *LookupInResult) []byte {
+	if res == nil {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	for i, op := range res.Ops {
+		if i > 0 {
+			buf.WriteByte('\n')
+		}
+		buf.Write(op.Value)
+	}
+
+	return buf.Bytes()
+}
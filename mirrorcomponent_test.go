@@ -0,0 +1,101 @@
+package gocbcore
+
+import "time"
+
+func (suite *UnitTestSuite) TestMirrorComponentCompare() {
+	var reported []MirrorMismatch
+	mc := &mirrorComponent{
+		percentage: 100,
+		onMismatch: func(mismatch MirrorMismatch) {
+			reported = append(reported, mismatch)
+		},
+	}
+
+	mc.compare(MirrorMismatch{
+		OperationName: "Get",
+		PrimaryValue:  []byte(`{"a":1}`),
+		MirrorValue:   []byte(`{"a":1}`),
+	})
+	suite.Require().Empty(reported)
+
+	mc.compare(MirrorMismatch{
+		OperationName: "Get",
+		PrimaryValue:  []byte(`{"a":1}`),
+		MirrorValue:   []byte(`{"a":2}`),
+	})
+	suite.Require().Len(reported, 1)
+
+	mc.compare(MirrorMismatch{
+		OperationName: "Get",
+		PrimaryValue:  []byte(`{"a":1}`),
+		PrimaryFlags:  1,
+		MirrorValue:   []byte(`{"a":1}`),
+	})
+	suite.Require().Len(reported, 2)
+
+	mc.compare(MirrorMismatch{
+		OperationName: "Get",
+		PrimaryError:  &KeyValueError{InnerError: ErrDocumentNotFound, BucketName: "primary"},
+		MirrorError:   &KeyValueError{InnerError: ErrDocumentNotFound, BucketName: "mirror"},
+	})
+	suite.Require().Len(reported, 2)
+
+	mc.compare(MirrorMismatch{
+		OperationName: "Get",
+		PrimaryValue:  []byte(`{"a":1}`),
+		MirrorError:   &KeyValueError{InnerError: ErrDocumentNotFound},
+	})
+	suite.Require().Len(reported, 3)
+}
+
+func (suite *UnitTestSuite) TestMirrorComponentDisabled() {
+	suite.Assert().Nil(newMirrorComponent(MirrorConfig{Percentage: 50}))
+	suite.Assert().Nil(newMirrorComponent(MirrorConfig{Agent: &Agent{}}))
+
+	var mc *mirrorComponent
+	suite.Assert().False(mc.shouldMirror())
+}
+
+func (suite *UnitTestSuite) TestMirrorComponentDispatchesInBackground() {
+	releaseCh := make(chan struct{})
+	mismatchCh := make(chan MirrorMismatch, 1)
+	mc := &mirrorComponent{
+		get: func(opts GetOptions, cb GetCallback) (PendingOp, error) {
+			go func() {
+				<-releaseCh
+				cb(&GetResult{Value: []byte(`{"a":2}`)}, nil)
+			}()
+			return &multiPendingOp{}, nil
+		},
+		percentage: 100,
+		timeout:    time.Second,
+		onMismatch: func(mismatch MirrorMismatch) {
+			mismatchCh <- mismatch
+		},
+		inFlight: make(chan struct{}, 1),
+	}
+
+	// The primary callback returns without waiting for the mirrored operation.
+	primaryCh := make(chan struct{}, 2)
+	cb := mc.WrapGet(GetOptions{Key: []byte("key")}, func(*GetResult, error) {
+		primaryCh <- struct{}{}
+	})
+	cb(&GetResult{Value: []byte(`{"a":1}`)}, nil)
+	suite.Require().Len(primaryCh, 1)
+
+	// Only one mirrored operation may be in flight, so this one is not mirrored.
+	mc.WrapGet(GetOptions{Key: []byte("key")}, func(*GetResult, error) {
+		primaryCh <- struct{}{}
+	})(&GetResult{}, nil)
+	suite.Require().Len(primaryCh, 2)
+
+	close(releaseCh)
+	select {
+	case mismatch := <-mismatchCh:
+		suite.Assert().Equal(`{"a":1}`, string(mismatch.PrimaryValue))
+		suite.Assert().Equal(`{"a":2}`, string(mismatch.MirrorValue))
+	case <-time.After(5 * time.Second):
+		suite.T().Fatalf("Timed out waiting for the mirrored result to be compared")
+	}
+	suite.Assert().Empty(mismatchCh)
+}
@@ -1,15 +1,17 @@
 package gocbcore
 
 import (
+	"container/list"
 	"context"
 	"encoding/json"
 	"errors"
-	"io/ioutil"
 	"regexp"
 	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
+
+	"github.com/couchbase/gocbcore/v10/memd"
 )
 
 // N1QLRowReader providers access to the rows of a n1ql query
@@ -18,6 +20,8 @@ type N1QLRowReader struct {
 	endpoint   string
 	statement  string
 	statusCode int
+
+	rowUnmarshaler RowUnmarshaler
 }
 
 // NextRow reads the next rows bytes from the stream
@@ -25,6 +29,19 @@ func (q *N1QLRowReader) NextRow() []byte {
 	return q.streamer.NextRow()
 }
 
+// NextRowInto reads the next rows bytes from the stream, reusing the memory of buf where it has the capacity.
+// Volatile: This API is subject to change at any time.
+func (q *N1QLRowReader) NextRowInto(buf []byte) []byte {
+	return q.streamer.NextRowInto(buf)
+}
+
+// NextRowDecode unmarshals the next row from the stream into valuePtr using the RowUnmarshaler from the query
+// options, returning false once there are no more rows. Err should be checked once all rows have been read.
+// Volatile: This API is subject to change at any time.
+func (q *N1QLRowReader) NextRowDecode(valuePtr interface{}) (bool, error) {
+	return q.streamer.NextRowDecode(q.rowUnmarshaler, valuePtr)
+}
+
 // Err returns any errors that occurred during streaming.
 func (q N1QLRowReader) Err() error {
 	err := q.streamer.Err()
@@ -65,6 +82,18 @@ func (q *N1QLRowReader) MetaData() ([]byte, error) {
 	return q.streamer.MetaData()
 }
 
+// Warnings returns the warnings returned by the query service, such as index advisor and deprecation notices. As
+// warnings are sent after the rows this can only be called once all rows have been read.
+// Volatile: This API is subject to change at any time.
+func (q *N1QLRowReader) Warnings() ([]N1QLWarning, error) {
+	meta, err := q.streamer.MetaData()
+	if err != nil {
+		return nil, err
+	}
+
+	return parseN1QLWarnings(meta)
+}
+
 // Close immediately shuts down the connection
 func (q *N1QLRowReader) Close() error {
 	return q.streamer.Close()
@@ -105,9 +134,168 @@ type N1QLQueryOptions struct {
 	// Internal: This should never be used and is not supported.
 	Endpoint string
 
+	// DurabilityLevel is applied to any mutations performed by the statement, allowing the same durability
+	// requirements used for KV operations to be used for DML. It is ignored if the payload already contains
+	// a durability_level.
+	// Volatile: This API is subject to change at any time.
+	DurabilityLevel memd.DurabilityLevel
+
+	// RowUnmarshaler is used by N1QLRowReader.NextRowDecode to unmarshal rows, encoding/json is used if not set.
+	// Volatile: This API is subject to change at any time.
+	RowUnmarshaler RowUnmarshaler
+
+	// ReadOnly marks the query as readonly, allowing it to be retried and causing statements which would modify
+	// data or indexes to be rejected without being sent. It is ignored if the payload already contains readonly.
+	// Volatile: This API is subject to change at any time.
+	ReadOnly bool
+
+	// Pretty controls whether the query service formats its response for readability. The server default is used
+	// if not set, it is ignored if the payload already contains pretty.
+	// Volatile: This API is subject to change at any time.
+	Pretty *bool
+
+	// FlexIndex allows the query service to use full text search indexes for the query. It is ignored if the payload
+	// already contains use_fts.
+	// Volatile: This API is subject to change at any time.
+	FlexIndex bool
+
+	// BucketName and ScopeName set the query_context for the query, allowing keyspaces to be referenced relative to
+	// the bucket or scope. ScopeName requires BucketName to also be set. They are ignored if the payload already
+	// contains a query_context.
+	// Volatile: This API is subject to change at any time.
+	BucketName string
+	ScopeName  string
+
 	TraceContext RequestSpanContext
 }
 
+// n1qlDurabilityLevel converts a durability level into the value expected by the query service.
+func n1qlDurabilityLevel(level memd.DurabilityLevel) (string, error) {
+	switch level {
+	case 0:
+		return "none", nil
+	case memd.DurabilityLevelMajority:
+		return "majority", nil
+	case memd.DurabilityLevelMajorityAndPersistOnMaster:
+		return "majorityAndPersistActive", nil
+	case memd.DurabilityLevelPersistToMajority:
+		return "persistToMajority", nil
+	default:
+		return "", wrapError(errInvalidArgument, "unknown durability level")
+	}
+}
+
+// applyN1QLDurability adds the durability level to the query payload unless it is already present.
+func applyN1QLDurability(payloadMap map[string]interface{}, level memd.DurabilityLevel) error {
+	if level == 0 {
+		return nil
+	}
+	if _, ok := payloadMap["durability_level"]; ok {
+		return nil
+	}
+
+	durabilityLevel, err := n1qlDurabilityLevel(level)
+	if err != nil {
+		return err
+	}
+	payloadMap["durability_level"] = durabilityLevel
+
+	return nil
+}
+
+// n1qlQueryContext builds the query_context to use for queries against the given bucket and, optionally, scope.
+func n1qlQueryContext(bucketName, scopeName string) (string, error) {
+	if bucketName == "" {
+		return "", wrapError(errInvalidArgument, "bucket name must be set when scope name is set")
+	}
+	if strings.Contains(bucketName, "`") || strings.Contains(scopeName, "`") {
+		return "", wrapError(errInvalidArgument, "bucket and scope names cannot contain backticks")
+	}
+
+	if scopeName == "" {
+		return "default:`" + bucketName + "`", nil
+	}
+
+	return "default:`" + bucketName + "`.`" + scopeName + "`", nil
+}
+
+// n1qlModifyingKeywords are the keywords which begin statements that are not permitted in readonly queries.
+var n1qlModifyingKeywords = map[string]struct{}{
+	"INSERT": {}, "UPSERT": {}, "UPDATE": {}, "DELETE": {}, "MERGE": {},
+	"CREATE": {}, "DROP": {}, "ALTER": {}, "BUILD": {}, "GRANT": {}, "REVOKE": {},
+}
+
+// isN1QLModifyingStatement returns whether the statement would modify data, indexes or permissions, and so is not
+// permitted in a readonly query.
+func isN1QLModifyingStatement(statement string) bool {
+	for {
+		statement = strings.TrimLeft(statement, " \t\r\n(")
+		if strings.HasPrefix(statement, "--") {
+			idx := strings.IndexByte(statement, '\n')
+			if idx == -1 {
+				return false
+			}
+			statement = statement[idx+1:]
+		} else if strings.HasPrefix(statement, "/*") {
+			idx := strings.Index(statement, "*/")
+			if idx == -1 {
+				return false
+			}
+			statement = statement[idx+2:]
+		} else {
+			break
+		}
+	}
+
+	keywordEnd := strings.IndexFunc(statement, func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z')
+	})
+	if keywordEnd == -1 {
+		keywordEnd = len(statement)
+	}
+
+	_, ok := n1qlModifyingKeywords[strings.ToUpper(statement[:keywordEnd])]
+	return ok
+}
+
+// applyN1QLOptions adds the options which are set to the query payload, unless they are already present, and then
+// validates the resulting payload.
+func applyN1QLOptions(payloadMap map[string]interface{}, opts N1QLQueryOptions) error {
+	if err := applyN1QLDurability(payloadMap, opts.DurabilityLevel); err != nil {
+		return err
+	}
+
+	setIfAbsent := func(key string, value interface{}) {
+		if _, ok := payloadMap[key]; !ok {
+			payloadMap[key] = value
+		}
+	}
+
+	if opts.ReadOnly {
+		setIfAbsent("readonly", true)
+	}
+	if opts.Pretty != nil {
+		setIfAbsent("pretty", *opts.Pretty)
+	}
+	if opts.FlexIndex {
+		setIfAbsent("use_fts", true)
+	}
+	if opts.BucketName != "" || opts.ScopeName != "" {
+		queryContext, err := n1qlQueryContext(opts.BucketName, opts.ScopeName)
+		if err != nil {
+			return err
+		}
+		setIfAbsent("query_context", queryContext)
+	}
+
+	if getMapValueBool(payloadMap, "readonly", false) &&
+		isN1QLModifyingStatement(getMapValueString(payloadMap, "statement", "")) {
+		return wrapError(errInvalidArgument, "readonly queries cannot modify data, indexes or permissions")
+	}
+
+	return nil
+}
+
 func wrapN1QLError(req *httpRequest, statement string, err error, errBody string, statusCode int) *N1QLError {
 	if err == nil {
 		err = errors.New("query error")
@@ -138,6 +326,39 @@ type jsonN1QLError struct {
 	Retry  bool                   `json:"retry"`
 }
 
+// N1QLWarning represents a single warning returned by the query service.
+// Volatile: This API is subject to change at any time.
+type N1QLWarning struct {
+	Code    uint32
+	Message string
+	// Retry is the query service's hint as to whether the statement may succeed without the warning if retried.
+	Retry  bool
+	Reason map[string]interface{}
+}
+
+type jsonN1QLWarningsResponse struct {
+	Warnings []jsonN1QLError `json:"warnings"`
+}
+
+func parseN1QLWarnings(meta []byte) ([]N1QLWarning, error) {
+	var resp jsonN1QLWarningsResponse
+	if err := json.Unmarshal(meta, &resp); err != nil {
+		return nil, wrapError(errParsingFailure, "failed to parse query warnings")
+	}
+
+	var warnings []N1QLWarning
+	for _, warning := range resp.Warnings {
+		warnings = append(warnings, N1QLWarning{
+			Code:    warning.Code,
+			Message: warning.Msg,
+			Retry:   warning.Retry,
+			Reason:  warning.Reason,
+		})
+	}
+
+	return warnings, nil
+}
+
 type jsonN1QLErrorResponse struct {
 	Errors json.RawMessage
 }
@@ -170,7 +391,7 @@ func parseN1QLErrorResp(req *httpRequest, statement string, resp *HTTPResponse)
 	var errorDescs []N1QLErrorDesc
 	var err error
 	var raw string
-	respBody, readErr := ioutil.ReadAll(resp.Body)
+	respBody, readErr := resp.readBody()
 	if readErr == nil {
 		raw, errorDescs, err = parseN1QLError(respBody)
 	}
@@ -332,9 +553,15 @@ type n1qlQueryComponent struct {
 	useReplicaSupported       uint32
 }
 
+// defaultN1QLQueryCacheSize is the maximum number of prepared statements held by the query cache, once full the least
+// recently used statement is evicted and will be reprepared if used again.
+const defaultN1QLQueryCacheSize = 5000
+
 type n1qlQueryCache struct {
-	cache     map[n1qlQueryCacheStatementContext]*n1qlQueryCacheEntry
-	cacheLock sync.RWMutex
+	cache     map[n1qlQueryCacheStatementContext]*list.Element
+	lru       *list.List
+	maxSize   int
+	cacheLock sync.Mutex
 }
 
 type n1qlQueryCacheStatementContext struct {
@@ -342,39 +569,71 @@ type n1qlQueryCacheStatementContext struct {
 	Context   string
 }
 
+type n1qlQueryCacheItem struct {
+	statement n1qlQueryCacheStatementContext
+	entry     *n1qlQueryCacheEntry
+}
+
 func newN1qlQueryCache() *n1qlQueryCache {
+	return newN1qlQueryCacheWithSize(defaultN1QLQueryCacheSize)
+}
+
+func newN1qlQueryCacheWithSize(maxSize int) *n1qlQueryCache {
 	return &n1qlQueryCache{
-		cache: make(map[n1qlQueryCacheStatementContext]*n1qlQueryCacheEntry),
+		cache:   make(map[n1qlQueryCacheStatementContext]*list.Element),
+		lru:     list.New(),
+		maxSize: maxSize,
 	}
 }
 
 func (cache *n1qlQueryCache) Invalidate() {
 	cache.cacheLock.Lock()
-	cache.cache = make(map[n1qlQueryCacheStatementContext]*n1qlQueryCacheEntry)
+	cache.cache = make(map[n1qlQueryCacheStatementContext]*list.Element)
+	cache.lru.Init()
 	cache.cacheLock.Unlock()
 }
 
 func (cache *n1qlQueryCache) Put(statement n1qlQueryCacheStatementContext, entry *n1qlQueryCacheEntry) {
 	cache.cacheLock.Lock()
-	cache.cache[statement] = entry
-	cache.cacheLock.Unlock()
+	defer cache.cacheLock.Unlock()
+
+	if elem, ok := cache.cache[statement]; ok {
+		elem.Value.(*n1qlQueryCacheItem).entry = entry
+		cache.lru.MoveToFront(elem)
+		return
+	}
+
+	cache.cache[statement] = cache.lru.PushFront(&n1qlQueryCacheItem{
+		statement: statement,
+		entry:     entry,
+	})
+
+	for cache.maxSize > 0 && cache.lru.Len() > cache.maxSize {
+		oldest := cache.lru.Back()
+		cache.lru.Remove(oldest)
+		delete(cache.cache, oldest.Value.(*n1qlQueryCacheItem).statement)
+	}
 }
 
 func (cache *n1qlQueryCache) Delete(statement n1qlQueryCacheStatementContext) {
 	cache.cacheLock.Lock()
-	delete(cache.cache, statement)
+	if elem, ok := cache.cache[statement]; ok {
+		cache.lru.Remove(elem)
+		delete(cache.cache, statement)
+	}
 	cache.cacheLock.Unlock()
 }
 
 func (cache *n1qlQueryCache) Get(statement n1qlQueryCacheStatementContext) *n1qlQueryCacheEntry {
-	cache.cacheLock.RLock()
-	entry := cache.cache[statement]
-	if entry == nil {
-		cache.cacheLock.RUnlock()
+	cache.cacheLock.Lock()
+	elem, ok := cache.cache[statement]
+	if !ok {
+		cache.cacheLock.Unlock()
 		return nil
 	}
-	cached := *entry
-	cache.cacheLock.RUnlock()
+	cache.lru.MoveToFront(elem)
+	cached := *elem.Value.(*n1qlQueryCacheItem).entry
+	cache.cacheLock.Unlock()
 
 	return &cached
 }
@@ -427,6 +686,10 @@ func (nqc *n1qlQueryComponent) N1QLQuery(opts N1QLQueryOptions, cb N1QLQueryCall
 		tracer.Finish()
 		return nil, wrapN1QLError(nil, "", wrapError(err, "expected a JSON payload"), "", 0)
 	}
+	if err := applyN1QLOptions(payloadMap, opts); err != nil {
+		tracer.Finish()
+		return nil, wrapN1QLError(nil, "", err, "", 0)
+	}
 
 	statement := getMapValueString(payloadMap, "statement", "")
 	clientContextID := getMapValueString(payloadMap, "client_context_id", "")
@@ -455,11 +718,15 @@ func (nqc *n1qlQueryComponent) N1QLQuery(opts N1QLQueryOptions, cb N1QLQueryCall
 
 	go func() {
 		resp, err := nqc.execute(ireq, payloadMap, statement, time.Now())
+		err = ireq.complete(err, func() error {
+			return resp.Close()
+		})
 		if err != nil {
-			tracer.Finish()
+			tracer.FinishWithError(err)
 			cb(nil, err)
 			return
 		}
+		resp.rowUnmarshaler = opts.RowUnmarshaler
 
 		tracer.Finish()
 		cb(resp, nil)
@@ -480,12 +747,16 @@ func (nqc *n1qlQueryComponent) PreparedN1QLQuery(opts N1QLQueryOptions, cb N1QLQ
 
 	go func() {
 		res, err := nqc.executePrepared(ctx, cancel, tracer.RootContext(), opts)
+		err = parentReqForCancel.complete(err, func() error {
+			return res.Close()
+		})
 		if err != nil {
 			cancel()
-			tracer.Finish()
+			tracer.FinishWithError(err)
 			cb(nil, err)
 			return
 		}
+		res.rowUnmarshaler = opts.RowUnmarshaler
 
 		tracer.Finish()
 		cb(res, nil)
@@ -502,6 +773,9 @@ func (nqc *n1qlQueryComponent) executePrepared(ctx context.Context, cancel conte
 	if err != nil {
 		return nil, wrapN1QLError(nil, "", wrapError(err, "expected a JSON payload"), "", 0)
 	}
+	if err := applyN1QLOptions(payloadMap, opts); err != nil {
+		return nil, wrapN1QLError(nil, "", err, "", 0)
+	}
 
 	statement := getMapValueString(payloadMap, "statement", "")
 	clientContextID := getMapValueString(payloadMap, "client_context_id", "")
@@ -633,6 +907,7 @@ func (nqc *n1qlQueryComponent) preparedStatementMaybeEvictAndRetry(req *httpRequ
 			// n1qlErr is already wrapped here
 			return originalErr
 		}
+		nqc.tracer.RetryCountRecord(metricValueServiceQueryValue, "")
 
 		select {
 		case <-time.After(time.Until(req.Deadline)):
@@ -794,11 +1069,13 @@ func (nqc *n1qlQueryComponent) execute(ireq *httpRequest, payloadMap map[string]
 				return nil, n1qlErr
 			}
 
+			ireq.setServerRetryAfter(retryAfterFromHTTPResponse(resp))
 			shouldRetry, retryTime := retryOrchMaybeRetry(ireq, retryReason)
 			if !shouldRetry {
 				// n1qlErr is already wrapped here
 				return nil, n1qlErr
 			}
+			nqc.tracer.RetryCountRecord(metricValueServiceQueryValue, "")
 
 			select {
 			case <-time.After(time.Until(retryTime)):
@@ -819,7 +1096,7 @@ func (nqc *n1qlQueryComponent) execute(ireq *httpRequest, payloadMap map[string]
 
 		streamer, err := newQueryStreamer(resp.Body, "results")
 		if err != nil {
-			respBody, readErr := ioutil.ReadAll(resp.Body)
+			respBody, readErr := resp.readBody()
 			if readErr != nil {
 				logDebugf("Failed to read response body: %v", readErr)
 			}
@@ -10,6 +10,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/couchbase/gocbcore/v10/memd"
 	"github.com/stretchr/testify/require"
 
 	"github.com/stretchr/testify/assert"
@@ -1581,3 +1582,205 @@ func (suite *UnitTestSuite) TestN1QLEnhPreparedDifferentiatesQueryContext() {
 	suite.Require().NoError(err, err)
 	suite.Require().NoError(<-waitCh)
 }
+
+func (suite *UnitTestSuite) TestN1QLApplyDurability() {
+	type test struct {
+		name     string
+		level    memd.DurabilityLevel
+		payload  map[string]interface{}
+		expected interface{}
+	}
+
+	tests := []test{
+		{
+			name:     "None",
+			payload:  map[string]interface{}{},
+			expected: nil,
+		},
+		{
+			name:     "Majority",
+			level:    memd.DurabilityLevelMajority,
+			payload:  map[string]interface{}{},
+			expected: "majority",
+		},
+		{
+			name:     "MajorityAndPersistActive",
+			level:    memd.DurabilityLevelMajorityAndPersistOnMaster,
+			payload:  map[string]interface{}{},
+			expected: "majorityAndPersistActive",
+		},
+		{
+			name:     "PersistToMajority",
+			level:    memd.DurabilityLevelPersistToMajority,
+			payload:  map[string]interface{}{},
+			expected: "persistToMajority",
+		},
+		{
+			name:     "PayloadTakesPrecedence",
+			level:    memd.DurabilityLevelPersistToMajority,
+			payload:  map[string]interface{}{"durability_level": "majority"},
+			expected: "majority",
+		},
+	}
+
+	for _, tt := range tests {
+		suite.Run(tt.name, func() {
+			err := applyN1QLDurability(tt.payload, tt.level)
+			suite.Require().Nil(err, err)
+
+			suite.Assert().Equal(tt.expected, tt.payload["durability_level"])
+		})
+	}
+
+	err := applyN1QLDurability(map[string]interface{}{}, memd.DurabilityLevel(0xff))
+	suite.Assert().True(errors.Is(err, ErrInvalidArgument), err)
+}
+
+func (suite *UnitTestSuite) TestN1QLApplyOptions() {
+	pretty := false
+
+	type test struct {
+		name     string
+		opts     N1QLQueryOptions
+		payload  map[string]interface{}
+		expected map[string]interface{}
+		err      error
+	}
+
+	tests := []test{
+		{
+			name:     "None",
+			payload:  map[string]interface{}{"statement": "SELECT 1"},
+			expected: map[string]interface{}{"statement": "SELECT 1"},
+		},
+		{
+			name: "All",
+			opts: N1QLQueryOptions{
+				ReadOnly:   true,
+				Pretty:     &pretty,
+				FlexIndex:  true,
+				BucketName: "default",
+				ScopeName:  "inventory",
+			},
+			payload: map[string]interface{}{"statement": "SELECT 1"},
+			expected: map[string]interface{}{
+				"statement":     "SELECT 1",
+				"readonly":      true,
+				"pretty":        false,
+				"use_fts":       true,
+				"query_context": "default:`default`.`inventory`",
+			},
+		},
+		{
+			name:     "BucketOnly",
+			opts:     N1QLQueryOptions{BucketName: "default"},
+			payload:  map[string]interface{}{"statement": "SELECT 1"},
+			expected: map[string]interface{}{"statement": "SELECT 1", "query_context": "default:`default`"},
+		},
+		{
+			name:     "PayloadTakesPrecedence",
+			opts:     N1QLQueryOptions{BucketName: "default", ScopeName: "inventory", FlexIndex: true},
+			payload:  map[string]interface{}{"statement": "SELECT 1", "query_context": "default:`other`", "use_fts": false},
+			expected: map[string]interface{}{"statement": "SELECT 1", "query_context": "default:`other`", "use_fts": false},
+		},
+		{
+			name:    "ScopeWithoutBucket",
+			opts:    N1QLQueryOptions{ScopeName: "inventory"},
+			payload: map[string]interface{}{"statement": "SELECT 1"},
+			err:     ErrInvalidArgument,
+		},
+		{
+			name:    "BacktickInName",
+			opts:    N1QLQueryOptions{BucketName: "def`ault"},
+			payload: map[string]interface{}{"statement": "SELECT 1"},
+			err:     ErrInvalidArgument,
+		},
+		{
+			name:    "ReadOnlyDML",
+			opts:    N1QLQueryOptions{ReadOnly: true},
+			payload: map[string]interface{}{"statement": "  insert INTO default VALUES (\"key\", {})"},
+			err:     ErrInvalidArgument,
+		},
+		{
+			name:    "ReadOnlyPayloadDDL",
+			payload: map[string]interface{}{"statement": "/* build */ -- comment\nCREATE INDEX idx ON default(x)", "readonly": true},
+			err:     ErrInvalidArgument,
+		},
+		{
+			name:     "ReadOnlyExplainDML",
+			opts:     N1QLQueryOptions{ReadOnly: true},
+			payload:  map[string]interface{}{"statement": "EXPLAIN DELETE FROM default"},
+			expected: map[string]interface{}{"statement": "EXPLAIN DELETE FROM default", "readonly": true},
+		},
+	}
+
+	for _, tt := range tests {
+		suite.Run(tt.name, func() {
+			err := applyN1QLOptions(tt.payload, tt.opts)
+			if tt.err != nil {
+				suite.Assert().True(errors.Is(err, tt.err), err)
+				return
+			}
+			suite.Require().Nil(err, err)
+
+			suite.Assert().Equal(tt.expected, tt.payload)
+		})
+	}
+}
+
+func (suite *UnitTestSuite) TestN1QLQueryCacheEvictsLeastRecentlyUsed() {
+	cache := newN1qlQueryCacheWithSize(2)
+
+	first := n1qlQueryCacheStatementContext{Statement: "SELECT 1"}
+	second := n1qlQueryCacheStatementContext{Statement: "SELECT 2"}
+	third := n1qlQueryCacheStatementContext{Statement: "SELECT 3"}
+
+	cache.Put(first, &n1qlQueryCacheEntry{name: "first"})
+	cache.Put(second, &n1qlQueryCacheEntry{name: "second"})
+
+	// Using the first statement makes the second the least recently used.
+	suite.Require().NotNil(cache.Get(first))
+
+	cache.Put(third, &n1qlQueryCacheEntry{name: "third"})
+
+	suite.Assert().Nil(cache.Get(second))
+	suite.Require().NotNil(cache.Get(first))
+	suite.Assert().Equal("first", cache.Get(first).name)
+	suite.Require().NotNil(cache.Get(third))
+	suite.Assert().Equal("third", cache.Get(third).name)
+
+	cache.Put(first, &n1qlQueryCacheEntry{name: "updated"})
+	suite.Assert().Equal("updated", cache.Get(first).name)
+
+	cache.Delete(first)
+	suite.Assert().Nil(cache.Get(first))
+
+	cache.Invalidate()
+	suite.Assert().Nil(cache.Get(third))
+}
+
+func (suite *UnitTestSuite) TestN1QLWarnings() {
+	resp := suite.doN1QLRequest([]byte(`{"requestID":"1","results":[{"a":1}],`+
+		`"warnings":[{"code":1080,"msg":"Timeout 1ms exceeds","retry":false},`+
+		`{"code":5500,"msg":"index advisor","reason":{"indexes":["idx"]}}],"status":"success"}`), 200, nil)
+	suite.Require().Nil(resp.err, resp.err)
+
+	reader := resp.reader
+	for reader.NextRow() != nil {
+	}
+	suite.Require().Nil(reader.Err())
+
+	warnings, err := reader.Warnings()
+	suite.Require().Nil(err, err)
+	suite.Require().Len(warnings, 2)
+	suite.Assert().Equal(N1QLWarning{Code: 1080, Message: "Timeout 1ms exceeds"}, warnings[0])
+	suite.Assert().Equal(uint32(5500), warnings[1].Code)
+	suite.Assert().Equal([]interface{}{"idx"}, warnings[1].Reason["indexes"])
+
+	warnings, err = parseN1QLWarnings([]byte(`{"status":"success"}`))
+	suite.Require().Nil(err, err)
+	suite.Assert().Empty(warnings)
+
+	_, err = parseN1QLWarnings([]byte(`{"warnings":"bad"}`))
+	suite.Assert().ErrorIs(err, ErrParsingFailure)
+}
@@ -189,6 +189,7 @@ func (oc *observeComponent) ObserveVb(opts ObserveVbOptions, cb ObserveVbCallbac
 		} else if formatType == 1 {
 			// Hard Failover
 			if len(resp.Value) < 43 {
+				tracer.Finish()
 				cb(nil, errProtocol)
 				return
 			}
@@ -265,7 +266,95 @@ func (oc *observeComponent) ObserveVb(opts ObserveVbOptions, cb ObserveVbCallbac
 			count, reasons := req.Retries()
 			req.cancelWithCallbackAndFinishTracer(&TimeoutError{
 				InnerError:         errUnambiguousTimeout,
-				OperationID:        "Unlock",
+				OperationID:        "ObserveVb",
+				Opaque:             req.Identifier(),
+				TimeObserved:       time.Since(start),
+				RetryReasons:       reasons,
+				RetryAttempts:      count,
+				LastDispatchedTo:   connInfo.lastDispatchedTo,
+				LastDispatchedFrom: connInfo.lastDispatchedFrom,
+				LastConnectionID:   connInfo.lastConnectionID,
+			}, tracer)
+		}))
+	}
+
+	return op, nil
+}
+
+func (oc *observeComponent) GetFailoverLog(opts GetFailoverLogOptions, cb FailoverLogCallback) (PendingOp, error) {
+	tracer := oc.tracer.StartTelemeteryHandler(metricValueServiceKeyValue, "GetFailoverLog", opts.TraceContext)
+
+	if oc.bucketUtils.BucketType() != bktTypeCouchbase {
+		tracer.Finish()
+		return nil, errFeatureNotAvailable
+	}
+
+	handler := func(resp *memdQResponse, req *memdQRequest, err error) {
+		if err != nil {
+			tracer.Finish()
+			cb(nil, err)
+			return
+		}
+
+		if len(resp.Value)%16 != 0 {
+			tracer.Finish()
+			cb(nil, errProtocol)
+			return
+		}
+
+		res := &GetFailoverLogResult{
+			VbID:    req.Vbucket,
+			Entries: parseFailoverLog(resp.Value),
+		}
+		res.Internal.ResourceUnits = req.ResourceUnits()
+
+		tracer.Finish()
+		cb(res, nil)
+	}
+
+	var userFrame *memd.UserImpersonationFrame
+	if len(opts.User) > 0 {
+		userFrame = &memd.UserImpersonationFrame{
+			User: []byte(opts.User),
+		}
+	}
+
+	if opts.RetryStrategy == nil {
+		opts.RetryStrategy = oc.defaultRetryStrategy
+	}
+
+	req := &memdQRequest{
+		Packet: memd.Packet{
+			Magic:                  memd.CmdMagicReq,
+			Command:                memd.CmdDcpGetFailoverLog,
+			Datatype:               0,
+			Cas:                    0,
+			Extras:                 nil,
+			Key:                    nil,
+			Value:                  nil,
+			Vbucket:                opts.VbID,
+			UserImpersonationFrame: userFrame,
+		},
+		ReplicaIdx:       opts.ReplicaIdx,
+		Callback:         handler,
+		RootTraceContext: tracer.RootContext(),
+		RetryStrategy:    opts.RetryStrategy,
+	}
+
+	op, err := oc.cidMgr.Dispatch(req)
+	if err != nil {
+		tracer.Finish()
+		return nil, err
+	}
+
+	if !opts.Deadline.IsZero() {
+		start := time.Now()
+		req.SetTimer(time.AfterFunc(opts.Deadline.Sub(start), func() {
+			connInfo := req.ConnectionInfo()
+			count, reasons := req.Retries()
+			req.cancelWithCallbackAndFinishTracer(&TimeoutError{
+				InnerError:         errUnambiguousTimeout,
+				OperationID:        "GetFailoverLog",
 				Opaque:             req.Identifier(),
 				TimeObserved:       time.Since(start),
 				RetryReasons:       reasons,
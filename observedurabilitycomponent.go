@@ -0,0 +1,327 @@
+package gocbcore
+
+import (
+	"sync"
+	"time"
+
+	"github.com/couchbase/gocbcore/v10/memd"
+)
+
+// observeDurabilityComponent emulates synchronous durability for clusters which do not support it. The mutation is
+// performed without any durability requirements and then the active and replicas are polled with ObserveSeqNo until
+// enough copies have the mutation to meet the requested durability level.
+type observeDurabilityComponent struct {
+	observe     *observeComponent
+	cfgProvider configSnapshotProvider
+}
+
+type observeDurabilityOptions struct {
+	Level         memd.DurabilityLevel
+	RetryStrategy RetryStrategy
+	Deadline      time.Time
+	User          string
+	TraceContext  RequestSpanContext
+}
+
+func observeDurabilityOptionsFrom(level memd.DurabilityLevel, retryStrategy RetryStrategy, deadline time.Time,
+	user string, traceContext RequestSpanContext) observeDurabilityOptions {
+	return observeDurabilityOptions{
+		Level:         level,
+		RetryStrategy: retryStrategy,
+		Deadline:      deadline,
+		User:          user,
+		TraceContext:  traceContext,
+	}
+}
+
+// observeDurabilityMutateFunc performs a mutation, calling cb with the mutation token of the result.
+type observeDurabilityMutateFunc func(cb func(MutationToken, error)) (PendingOp, error)
+
+func newObserveDurabilityComponent(observe *observeComponent, cfgProvider configSnapshotProvider) *observeDurabilityComponent {
+	return &observeDurabilityComponent{
+		observe:     observe,
+		cfgProvider: cfgProvider,
+	}
+}
+
+// Mutate performs the mutation and then waits for it to meet the durability level before calling cb.
+func (odc *observeDurabilityComponent) Mutate(opts observeDurabilityOptions, mutate observeDurabilityMutateFunc,
+	cb func(error)) (PendingOp, error) {
+	if opts.Level == 0 {
+		return mutate(func(_ MutationToken, err error) {
+			cb(err)
+		})
+	}
+
+	if opts.Deadline.IsZero() {
+		return nil, wrapError(errInvalidArgument, "a deadline is required for legacy durability")
+	}
+
+	parentOp := &multiPendingOp{}
+	op, err := mutate(func(token MutationToken, err error) {
+		if err != nil {
+			cb(err)
+			return
+		}
+
+		if token.VbUUID == 0 {
+			cb(wrapError(errFeatureNotAvailable, "legacy durability requires mutation tokens to be enabled"))
+			return
+		}
+
+		odc.waitForDurability(parentOp, opts, token, cb)
+	})
+	if err != nil {
+		return nil, err
+	}
+	parentOp.AddOp(op)
+
+	return parentOp, nil
+}
+
+func (odc *observeDurabilityComponent) waitForDurability(parentOp *multiPendingOp, opts observeDurabilityOptions,
+	token MutationToken, cb func(error)) {
+	start := time.Now()
+	snapshotOp, err := odc.cfgProvider.WaitForConfigSnapshot(opts.Deadline, func(result *WaitForConfigSnapshotResult, err error) {
+		if err != nil {
+			cb(err)
+			return
+		}
+
+		numReplicas, err := result.Snapshot.NumReplicas()
+		if err != nil {
+			cb(err)
+			return
+		}
+
+		satisfied := func(results []*ObserveVbResult) (bool, error) {
+			return observeDurabilitySatisfied(opts.Level, token, results)
+		}
+		odc.pollSeqNo(parentOp, "ObserveBasedDurability", opts, token, numReplicas+1, satisfied, 0, start, cb)
+	})
+	if err != nil {
+		cb(err)
+		return
+	}
+	parentOp.AddOp(snapshotOp)
+}
+
+// pollSeqNo observes the vbucket on the active and first numCopies-1 replicas until satisfied reports that the
+// results meet the requirements of the caller, or the deadline is reached. Only the observes of the current poll are
+// held by parentOp, so that it does not grow with each poll.
+func (odc *observeDurabilityComponent) pollSeqNo(parentOp *multiPendingOp, opName string, opts observeDurabilityOptions,
+	token MutationToken, numCopies int, satisfied func([]*ObserveVbResult) (bool, error), attempt uint32,
+	start time.Time, cb func(error)) {
+	if parentOp.isCancelled() {
+		cb(errRequestCanceled)
+		return
+	}
+
+	pollOp := &multiPendingOp{}
+	parentOp.ReplaceOps(pollOp)
+
+	results := make([]*ObserveVbResult, numCopies)
+	var lock sync.Mutex
+	remaining := numCopies
+
+	copyCompleted := func(replicaIdx int, res *ObserveVbResult) {
+		lock.Lock()
+		results[replicaIdx] = res
+		remaining--
+		if remaining > 0 {
+			lock.Unlock()
+			return
+		}
+		lock.Unlock()
+
+		done, err := satisfied(results)
+		if err != nil {
+			cb(err)
+			return
+		}
+		if done {
+			cb(nil)
+			return
+		}
+
+		backoff := ControlledBackoff(attempt)
+		if time.Now().Add(backoff).After(opts.Deadline) {
+			cb(&TimeoutError{
+				InnerError:   errAmbiguousTimeout,
+				OperationID:  opName,
+				TimeObserved: time.Since(start),
+			})
+			return
+		}
+
+		time.AfterFunc(backoff, func() {
+			odc.pollSeqNo(parentOp, opName, opts, token, numCopies, satisfied, attempt+1, start, cb)
+		})
+	}
+
+	for replicaIdx := 0; replicaIdx < numCopies; replicaIdx++ {
+		idx := replicaIdx
+		op, err := odc.observe.ObserveVb(ObserveVbOptions{
+			VbID:          token.VbID,
+			VbUUID:        token.VbUUID,
+			ReplicaIdx:    idx,
+			RetryStrategy: opts.RetryStrategy,
+			Deadline:      opts.Deadline,
+			User:          opts.User,
+			TraceContext:  opts.TraceContext,
+		}, func(res *ObserveVbResult, err error) {
+			if err != nil {
+				// A copy which cannot be observed, for example because the replica is not available, simply does not
+				// count towards the durability requirement.
+				logDebugf("Failed to observe vbucket %d on replica %d: %v", token.VbID, idx, err)
+				copyCompleted(idx, nil)
+				return
+			}
+
+			copyCompleted(idx, res)
+		})
+		if err != nil {
+			logDebugf("Failed to observe vbucket %d on replica %d: %v", token.VbID, idx, err)
+			copyCompleted(idx, nil)
+			continue
+		}
+		pollOp.AddOp(op)
+	}
+}
+
+// observeDurabilitySatisfied returns whether the observe results, indexed by replica with the active at index 0 and
+// nil for any copy which could not be observed, show that the mutation meets the durability level.
+func observeDurabilitySatisfied(level memd.DurabilityLevel, token MutationToken, results []*ObserveVbResult) (bool, error) {
+	majority := len(results)/2 + 1
+
+	var numReplicated, numPersisted int
+	var activePersisted bool
+	for idx, res := range results {
+		if res == nil {
+			continue
+		}
+
+		if res.VbUUID != token.VbUUID {
+			if res.DidFailover && res.OldVbUUID == token.VbUUID && res.LastSeqNo < token.SeqNo {
+				return false, errMutationLost
+			}
+
+			continue
+		}
+
+		if res.CurrentSeqNo >= token.SeqNo {
+			numReplicated++
+		}
+		if res.PersistSeqNo >= token.SeqNo {
+			numPersisted++
+			if idx == 0 {
+				activePersisted = true
+			}
+		}
+	}
+
+	switch level {
+	case memd.DurabilityLevelMajority:
+		return numReplicated >= majority, nil
+	case memd.DurabilityLevelMajorityAndPersistOnMaster:
+		return numReplicated >= majority && activePersisted, nil
+	case memd.DurabilityLevelPersistToMajority:
+		return numPersisted >= majority, nil
+	default:
+		return false, errInvalidArgument
+	}
+}
+
+// WaitForSeqNo waits until the active, and optionally replicas, for a vbucket have reached a seqno.
+func (odc *observeDurabilityComponent) WaitForSeqNo(opts WaitForSeqNoOptions, cb WaitForSeqNoCallback) (PendingOp, error) {
+	if opts.NumReplicas < 0 {
+		return nil, wrapError(errInvalidArgument, "number of replicas cannot be negative")
+	}
+	if opts.Deadline.IsZero() {
+		return nil, wrapError(errInvalidArgument, "a deadline is required")
+	}
+
+	start := time.Now()
+	parentOp := &multiPendingOp{}
+	token := MutationToken{
+		VbID:   opts.VbID,
+		VbUUID: opts.VbUUID,
+		SeqNo:  opts.SeqNo,
+	}
+	pollOpts := observeDurabilityOptions{
+		RetryStrategy: opts.RetryStrategy,
+		Deadline:      opts.Deadline,
+		User:          opts.User,
+		TraceContext:  opts.TraceContext,
+	}
+
+	snapshotOp, err := odc.cfgProvider.WaitForConfigSnapshot(opts.Deadline, func(result *WaitForConfigSnapshotResult, err error) {
+		if err != nil {
+			cb(nil, err)
+			return
+		}
+
+		numReplicas, err := result.Snapshot.NumReplicas()
+		if err != nil {
+			cb(nil, err)
+			return
+		}
+		if opts.NumReplicas > numReplicas {
+			cb(nil, wrapError(errInvalidArgument, "number of replicas cannot be greater than the bucket replicas"))
+			return
+		}
+
+		var res *WaitForSeqNoResult
+		satisfied := func(results []*ObserveVbResult) (bool, error) {
+			done, err := observeSeqNoReached(token, opts.Persisted, results)
+			if done {
+				res = &WaitForSeqNoResult{
+					CurrentSeqNo: results[0].CurrentSeqNo,
+					PersistSeqNo: results[0].PersistSeqNo,
+				}
+			}
+			return done, err
+		}
+		odc.pollSeqNo(parentOp, "WaitForSeqNo", pollOpts, token, opts.NumReplicas+1, satisfied, 0, start, func(err error) {
+			if err != nil {
+				cb(nil, err)
+				return
+			}
+
+			cb(res, nil)
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	parentOp.AddOp(snapshotOp)
+
+	return parentOp, nil
+}
+
+// observeSeqNoReached returns whether every copy in the observe results has reached the seqno in the token.
+func observeSeqNoReached(token MutationToken, persisted bool, results []*ObserveVbResult) (bool, error) {
+	for _, res := range results {
+		if res == nil {
+			return false, nil
+		}
+
+		if res.VbUUID != token.VbUUID {
+			if res.DidFailover && res.OldVbUUID == token.VbUUID && res.LastSeqNo < token.SeqNo {
+				return false, errMutationLost
+			}
+
+			return false, nil
+		}
+
+		seqNo := res.CurrentSeqNo
+		if persisted {
+			seqNo = res.PersistSeqNo
+		}
+		if seqNo < token.SeqNo {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
@@ -0,0 +1,222 @@
+package gocbcore
+
+import (
+	"encoding/binary"
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/couchbase/gocbcore/v10/memd"
+	"github.com/stretchr/testify/mock"
+)
+
+func (suite *UnitTestSuite) TestObserveDurabilitySatisfied() {
+	token := MutationToken{VbID: 12, VbUUID: 1234, SeqNo: 10}
+	replicated := &ObserveVbResult{VbID: 12, VbUUID: 1234, CurrentSeqNo: 10, PersistSeqNo: 9}
+	persisted := &ObserveVbResult{VbID: 12, VbUUID: 1234, CurrentSeqNo: 10, PersistSeqNo: 10}
+	behind := &ObserveVbResult{VbID: 12, VbUUID: 1234, CurrentSeqNo: 9, PersistSeqNo: 9}
+
+	type test struct {
+		name     string
+		level    memd.DurabilityLevel
+		results  []*ObserveVbResult
+		expected bool
+		err      error
+	}
+
+	tests := []test{
+		{
+			name:     "MajorityReplicated",
+			level:    memd.DurabilityLevelMajority,
+			results:  []*ObserveVbResult{replicated, replicated, behind},
+			expected: true,
+		},
+		{
+			name:     "MajorityNotReplicated",
+			level:    memd.DurabilityLevelMajority,
+			results:  []*ObserveVbResult{replicated, behind, nil},
+			expected: false,
+		},
+		{
+			name:     "MajorityAndPersistActiveNotPersisted",
+			level:    memd.DurabilityLevelMajorityAndPersistOnMaster,
+			results:  []*ObserveVbResult{replicated, persisted, persisted},
+			expected: false,
+		},
+		{
+			name:     "MajorityAndPersistActivePersisted",
+			level:    memd.DurabilityLevelMajorityAndPersistOnMaster,
+			results:  []*ObserveVbResult{persisted, replicated, behind},
+			expected: true,
+		},
+		{
+			name:     "PersistToMajorityNotPersisted",
+			level:    memd.DurabilityLevelPersistToMajority,
+			results:  []*ObserveVbResult{persisted, replicated, replicated},
+			expected: false,
+		},
+		{
+			name:     "PersistToMajorityPersisted",
+			level:    memd.DurabilityLevelPersistToMajority,
+			results:  []*ObserveVbResult{persisted, nil, persisted},
+			expected: true,
+		},
+		{
+			name:     "DifferentVbUUID",
+			level:    memd.DurabilityLevelMajority,
+			results:  []*ObserveVbResult{replicated, {VbID: 12, VbUUID: 5678, CurrentSeqNo: 10}, nil},
+			expected: false,
+		},
+		{
+			name:  "MutationLost",
+			level: memd.DurabilityLevelMajority,
+			results: []*ObserveVbResult{
+				{VbID: 12, VbUUID: 5678, CurrentSeqNo: 10, DidFailover: true, OldVbUUID: 1234, LastSeqNo: 8},
+			},
+			err: ErrMutationLost,
+		},
+	}
+
+	for _, tt := range tests {
+		suite.Run(tt.name, func() {
+			done, err := observeDurabilitySatisfied(tt.level, token, tt.results)
+			if tt.err != nil {
+				suite.Assert().True(errors.Is(err, tt.err), err)
+				return
+			}
+
+			suite.Require().Nil(err, err)
+			suite.Assert().Equal(tt.expected, done)
+		})
+	}
+}
+
+func (suite *UnitTestSuite) TestObserveSeqNoReached() {
+	token := MutationToken{VbID: 12, VbUUID: 1234, SeqNo: 10}
+	persisted := &ObserveVbResult{VbID: 12, VbUUID: 1234, CurrentSeqNo: 10, PersistSeqNo: 10}
+	replicated := &ObserveVbResult{VbID: 12, VbUUID: 1234, CurrentSeqNo: 11, PersistSeqNo: 9}
+	behind := &ObserveVbResult{VbID: 12, VbUUID: 1234, CurrentSeqNo: 9, PersistSeqNo: 9}
+
+	type test struct {
+		name      string
+		persisted bool
+		results   []*ObserveVbResult
+		expected  bool
+		err       error
+	}
+
+	tests := []test{
+		{
+			name:     "ActiveReached",
+			results:  []*ObserveVbResult{replicated},
+			expected: true,
+		},
+		{
+			name:     "ActiveBehind",
+			results:  []*ObserveVbResult{behind},
+			expected: false,
+		},
+		{
+			name:      "ActiveNotPersisted",
+			persisted: true,
+			results:   []*ObserveVbResult{replicated},
+			expected:  false,
+		},
+		{
+			name:      "AllPersisted",
+			persisted: true,
+			results:   []*ObserveVbResult{persisted, persisted},
+			expected:  true,
+		},
+		{
+			name:     "ReplicaBehind",
+			results:  []*ObserveVbResult{replicated, behind},
+			expected: false,
+		},
+		{
+			name:     "ReplicaUnavailable",
+			results:  []*ObserveVbResult{replicated, nil},
+			expected: false,
+		},
+		{
+			name: "MutationLost",
+			results: []*ObserveVbResult{
+				{VbID: 12, VbUUID: 5678, CurrentSeqNo: 10, DidFailover: true, OldVbUUID: 1234, LastSeqNo: 8},
+			},
+			err: ErrMutationLost,
+		},
+	}
+
+	for _, tt := range tests {
+		suite.Run(tt.name, func() {
+			done, err := observeSeqNoReached(token, tt.persisted, tt.results)
+			if tt.err != nil {
+				suite.Assert().True(errors.Is(err, tt.err), err)
+				return
+			}
+
+			suite.Require().Nil(err, err)
+			suite.Assert().Equal(tt.expected, done)
+		})
+	}
+}
+
+func (suite *UnitTestSuite) TestObserveDurabilityRequiresDeadline() {
+	odc := newObserveDurabilityComponent(nil, nil)
+
+	_, err := odc.Mutate(observeDurabilityOptions{Level: memd.DurabilityLevelMajority},
+		func(cb func(MutationToken, error)) (PendingOp, error) {
+			suite.T().Fatalf("Mutation should not have been performed")
+			return nil, nil
+		}, func(err error) {})
+	suite.Assert().ErrorIs(err, ErrInvalidArgument)
+
+	_, err = odc.WaitForSeqNo(WaitForSeqNoOptions{}, func(res *WaitForSeqNoResult, err error) {})
+	suite.Assert().ErrorIs(err, ErrInvalidArgument)
+}
+
+func (suite *UnitTestSuite) TestObserveDurabilityPollReplacesOps() {
+	var numObserves uint32
+	dispatcher := new(mockDispatcher)
+	dispatcher.On("CollectionsEnabled").Return(false)
+	dispatcher.On("DispatchDirect", mock.AnythingOfType("*gocbcore.memdQRequest")).Return(&memdQRequest{}, nil).
+		Run(func(args mock.Arguments) {
+			req := args[0].(*memdQRequest)
+
+			// The seqno is only reached on the third poll.
+			seqNo := uint64(1)
+			if atomic.AddUint32(&numObserves, 1) >= 3 {
+				seqNo = 10
+			}
+			value := make([]byte, 27)
+			binary.BigEndian.PutUint64(value[3:], 1234)
+			binary.BigEndian.PutUint64(value[11:], seqNo)
+			binary.BigEndian.PutUint64(value[19:], seqNo)
+
+			go req.tryCallback(&memdQResponse{Packet: &memd.Packet{Value: value}}, nil)
+		})
+
+	mux := newTestSnapshotMux([][]int{{0}}, 0)
+	tracer := newTracerComponent(&noopTracer{}, "", true, &noopMeter{}, nil)
+	observe := newObserveComponent(&collectionsComponent{dispatcher: dispatcher}, newFailFastRetryStrategy(), tracer,
+		mux)
+	odc := newObserveDurabilityComponent(observe, mux)
+
+	resCh := make(chan *WaitForSeqNoResult, 1)
+	op, err := odc.WaitForSeqNo(WaitForSeqNoOptions{
+		VbUUID:   1234,
+		SeqNo:    10,
+		Deadline: time.Now().Add(5 * time.Second),
+	}, func(res *WaitForSeqNoResult, err error) {
+		suite.Require().Nil(err, err)
+		resCh <- res
+	})
+	suite.Require().Nil(err, err)
+
+	res := <-resCh
+	suite.Assert().Equal(SeqNo(10), res.CurrentSeqNo)
+	suite.Assert().Equal(uint32(3), atomic.LoadUint32(&numObserves))
+
+	// Only the observes from the last poll are held, rather than those from every poll.
+	suite.Assert().Equal(1, op.(*multiPendingOp).Len())
+}
@@ -10,7 +10,11 @@ import (
 // This can also be used to Get information about the operation once
 // it has completed (cancelled or successful).
 type PendingOp interface {
-	Cancel()
+	// Cancel attempts to cancel the operation, returning whether the cancellation won the race with the operation
+	// completing. If true then the callback for the operation is, or will be, invoked with ErrRequestCanceled. If
+	// false then the operation had already completed, or been cancelled, and its callback has received, or will
+	// receive, the result of that instead.
+	Cancel() bool
 }
 
 type multiPendingOp struct {
@@ -39,16 +43,46 @@ func (mp *multiPendingOp) AddOp(op PendingOp) {
 	mp.lock.Unlock()
 }
 
-func (mp *multiPendingOp) Cancel() {
+// ReplaceOps replaces all of the ops with op, for operations which are made up of a sequence of steps where only the
+// current step needs to be cancellable.
+func (mp *multiPendingOp) ReplaceOps(op PendingOp) {
 	mp.lock.Lock()
+	if mp.cancelled {
+		mp.lock.Unlock()
+		op.Cancel()
+		return
+	}
+
+	mp.ops = []PendingOp{op}
+	mp.lock.Unlock()
+}
+
+// Cancel cancels all of the ops, returning true if any of them were cancelled.
+func (mp *multiPendingOp) Cancel() bool {
+	mp.lock.Lock()
+	if mp.cancelled {
+		mp.lock.Unlock()
+		return false
+	}
 	mp.cancelled = true
 	var ops []PendingOp
 	ops = append(ops, mp.ops...)
 	mp.lock.Unlock()
 
+	var cancelled bool
 	for _, op := range ops {
-		op.Cancel()
+		if op.Cancel() {
+			cancelled = true
+		}
 	}
+
+	return cancelled
+}
+
+func (mp *multiPendingOp) isCancelled() bool {
+	mp.lock.Lock()
+	defer mp.lock.Unlock()
+	return mp.cancelled
 }
 
 func (mp *multiPendingOp) CompletedOps() uint32 {
@@ -0,0 +1,132 @@
+package gocbcore
+
+import (
+	"errors"
+	"sync/atomic"
+)
+
+func (suite *UnitTestSuite) TestMemdQRequestCancelResult() {
+	var calls uint32
+	var cbErr error
+	req := &memdQRequest{
+		Callback: func(resp *memdQResponse, req *memdQRequest, err error) {
+			atomic.AddUint32(&calls, 1)
+			cbErr = err
+		},
+	}
+
+	suite.Assert().True(req.Cancel())
+	suite.Assert().False(req.Cancel())
+	suite.Assert().Equal(uint32(1), atomic.LoadUint32(&calls))
+	suite.Assert().ErrorIs(cbErr, ErrRequestCanceled)
+
+	// A request which has already completed cannot be cancelled.
+	req = &memdQRequest{
+		Callback: func(resp *memdQResponse, req *memdQRequest, err error) {
+			suite.T().Fatalf("Callback should not have been invoked")
+		},
+	}
+	atomic.StoreUint32(&req.isCompleted, 1)
+	suite.Assert().False(req.Cancel())
+}
+
+func (suite *UnitTestSuite) TestMultiPendingOpCancelResult() {
+	completedReq := &memdQRequest{}
+	atomic.StoreUint32(&completedReq.isCompleted, 1)
+
+	op := &multiPendingOp{}
+	op.AddOp(completedReq)
+	suite.Assert().False(op.Cancel())
+
+	op = &multiPendingOp{}
+	op.AddOp(completedReq)
+	op.AddOp(&memdQRequest{
+		Callback: func(resp *memdQResponse, req *memdQRequest, err error) {},
+	})
+	suite.Assert().True(op.Cancel())
+	suite.Assert().False(op.Cancel())
+}
+
+func (suite *UnitTestSuite) TestHTTPRequestCancelResult() {
+	var cancelled, released bool
+	release := func() error {
+		released = true
+		return nil
+	}
+
+	// Cancelling before the request completes discards the result.
+	req := &httpRequest{
+		CancelFunc: func() {
+			cancelled = true
+		},
+	}
+	suite.Assert().True(req.Cancel())
+	suite.Assert().True(cancelled)
+	suite.Assert().False(req.Cancel())
+
+	err := req.complete(nil, release)
+	suite.Assert().ErrorIs(err, ErrRequestCanceled)
+	suite.Assert().True(released)
+
+	// Errors are replaced by a cancellation error, unless they already are one.
+	req = &httpRequest{}
+	suite.Require().True(req.Cancel())
+	err = req.complete(errUnambiguousTimeout, release)
+	suite.Assert().ErrorIs(err, ErrRequestCanceled)
+
+	req = &httpRequest{}
+	suite.Require().True(req.Cancel())
+	wrapped := wrapError(errRequestCanceled, "wrapped")
+	suite.Assert().Equal(wrapped, req.complete(wrapped, release))
+
+	// Once completed the request can no longer be cancelled.
+	released = false
+	req = &httpRequest{}
+	testErr := errors.New("test")
+	suite.Assert().Equal(testErr, req.complete(testErr, release))
+	suite.Assert().False(req.Cancel())
+	suite.Assert().False(released)
+}
+
+func (suite *UnitTestSuite) TestPingOpCancelResult() {
+	var calls uint32
+	var cbErr error
+	subop := &memdQRequest{
+		Callback: func(resp *memdQResponse, req *memdQRequest, err error) {},
+	}
+	op := &pingOp{
+		subops:     []pingSubOp{{endpoint: "127.0.0.1:11210", op: subop}},
+		remaining:  1,
+		results:    make(map[ServiceType][]EndpointPingResult),
+		httpCancel: func() {},
+		callback: func(res *PingResult, err error) {
+			atomic.AddUint32(&calls, 1)
+			suite.Assert().Nil(res)
+			cbErr = err
+		},
+	}
+
+	suite.Assert().True(op.Cancel())
+	suite.Assert().ErrorIs(cbErr, ErrRequestCanceled)
+	suite.Assert().Equal(uint32(1), atomic.LoadUint32(&subop.isCompleted))
+
+	// Pings completing after the cancellation are discarded.
+	op.lock.Lock()
+	op.handledOneLocked(1)
+	op.lock.Unlock()
+	suite.Assert().False(op.Cancel())
+	suite.Assert().Equal(uint32(1), atomic.LoadUint32(&calls))
+
+	// Once completed the ping can no longer be cancelled.
+	op = &pingOp{
+		remaining:  1,
+		httpCancel: func() {},
+		callback: func(res *PingResult, err error) {
+			suite.Assert().NoError(err)
+		},
+	}
+	op.lock.Lock()
+	op.handledOneLocked(1)
+	op.lock.Unlock()
+	suite.Assert().False(op.Cancel())
+}
@@ -38,3 +38,11 @@ func (pi pipelineSnapshot) NodeByVbucket(vbID uint16, replicaID uint32) (int, er
 
 	return pi.state.VBMap().NodeByVbucket(vbID, replicaID)
 }
+
+func (pi pipelineSnapshot) VbucketsOnServer(index int) ([]uint16, error) {
+	if pi.state.VBMap() == nil {
+		return nil, errUnsupportedOperation
+	}
+
+	return pi.state.VBMap().VbucketsOnServer(index)
+}
@@ -49,7 +49,13 @@ func newPollerController(cccpPoller *cccpConfigController, httpPoller *httpConfi
 
 // OnNewRouteConfig listens out for every config that comes in so that we (re)start the cccp if applicable.
 func (pc *pollerController) OnNewRouteConfig(cfg *routeConfig) {
-	if cfg.bktType != bktTypeCouchbase && cfg.bktType != bktTypeMemcached {
+	if cfg.bktType == bktTypeNone {
+		// A cluster level config from a bucketless http poller means that we now know the KV nodes and can move
+		// over to GCCCP.
+		if pc.httpPoller == nil || pc.httpPoller.bucketName != "" {
+			return
+		}
+	} else if cfg.bktType != bktTypeCouchbase && cfg.bktType != bktTypeMemcached {
 		return
 	}
 	atomic.SwapUint32(&pc.bucketConfigSeen, 1)
@@ -65,7 +71,7 @@ func (pc *pollerController) OnNewRouteConfig(cfg *routeConfig) {
 			return
 		}
 		if pc.activeController == pc.httpPoller {
-			logInfof("Found couchbase bucket and HTTP poller in use. Restarting poller run loop to start cccp.")
+			logInfof("Found couchbase bucket or cluster config and HTTP poller in use. Restarting poller run loop to start cccp.")
 			pc.activeController = nil
 
 			// Stopping the poller will trigger the run loop to loop again.
@@ -0,0 +1,41 @@
+package gocbcore
+
+import (
+	"time"
+
+	"github.com/stretchr/testify/mock"
+)
+
+func (suite *UnitTestSuite) TestPollerControllerBucketlessHTTPMovesToCCCP() {
+	cfgMgr := new(mockConfigManager)
+	cfgMgr.On("AddConfigWatcher", mock.Anything).Return()
+
+	httpPoller := newHTTPConfigController("", httpPollerProperties{}, nil, nil)
+	pc := newPollerController(&cccpConfigController{}, httpPoller, cfgMgr, func(error) bool { return false })
+	pc.activeController = httpPoller
+
+	pc.OnNewRouteConfig(&routeConfig{bktType: bktTypeNone})
+
+	select {
+	case <-httpPoller.looperStopSig:
+	case <-time.After(time.Second):
+		suite.T().Fatalf("HTTP poller was not stopped after receiving a cluster config")
+	}
+}
+
+func (suite *UnitTestSuite) TestPollerControllerBucketHTTPIgnoresClusterConfig() {
+	cfgMgr := new(mockConfigManager)
+	cfgMgr.On("AddConfigWatcher", mock.Anything).Return()
+
+	httpPoller := newHTTPConfigController("default", httpPollerProperties{}, nil, nil)
+	pc := newPollerController(&cccpConfigController{}, httpPoller, cfgMgr, func(error) bool { return false })
+	pc.activeController = httpPoller
+
+	pc.OnNewRouteConfig(&routeConfig{bktType: bktTypeNone})
+
+	select {
+	case <-httpPoller.looperStopSig:
+		suite.T().Fatalf("HTTP poller should not have been stopped by a cluster config")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
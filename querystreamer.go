@@ -7,6 +7,20 @@ import (
 	"sync"
 )
 
+// RowUnmarshaler unmarshals the bytes of a single query row into a value. It allows JSON libraries other than
+// encoding/json to be used when decoding rows. The row bytes are reused for subsequent rows so implementations must
+// not retain them once Unmarshal returns.
+// Volatile: This API is subject to change at any time.
+type RowUnmarshaler interface {
+	Unmarshal(data []byte, valuePtr interface{}) error
+}
+
+type jsonRowUnmarshaler struct{}
+
+func (u jsonRowUnmarshaler) Unmarshal(data []byte, valuePtr interface{}) error {
+	return json.Unmarshal(data, valuePtr)
+}
+
 // QueryResult allows access to the results of a N1QL query.
 type queryStreamer struct {
 	metaDataBytes []byte
@@ -15,6 +29,9 @@ type queryStreamer struct {
 
 	stream   io.ReadCloser
 	streamer *rowStreamer
+
+	// rowBuf is reused between rows by NextRowDecode.
+	rowBuf []byte
 }
 
 func newQueryStreamer(stream io.ReadCloser, rowsAttrib string) (*queryStreamer, error) {
@@ -36,11 +53,17 @@ func newQueryStreamer(stream io.ReadCloser, rowsAttrib string) (*queryStreamer,
 
 // NextRow returns the next row from the results, returning nil when the rows are exhausted.
 func (r *queryStreamer) NextRow() []byte {
+	return r.NextRowInto(nil)
+}
+
+// NextRowInto returns the next row from the results using the memory of buf where it has the capacity, returning
+// nil when the rows are exhausted.
+func (r *queryStreamer) NextRowInto(buf []byte) []byte {
 	if r.streamer == nil {
 		return nil
 	}
 
-	rowBytes, err := r.streamer.NextRowBytes()
+	rowBytes, err := r.streamer.NextRowBytesInto(buf)
 	if err != nil {
 		r.finishWithError(err)
 		return nil
@@ -55,6 +78,26 @@ func (r *queryStreamer) NextRow() []byte {
 	return rowBytes
 }
 
+// NextRowDecode unmarshals the next row from the results into valuePtr, returning false when the rows are exhausted.
+// The memory used to read the row is reused between calls.
+func (r *queryStreamer) NextRowDecode(unmarshaler RowUnmarshaler, valuePtr interface{}) (bool, error) {
+	if unmarshaler == nil {
+		unmarshaler = jsonRowUnmarshaler{}
+	}
+
+	rowBytes := r.NextRowInto(r.rowBuf)
+	if rowBytes == nil {
+		return false, nil
+	}
+	r.rowBuf = rowBytes
+
+	if err := unmarshaler.Unmarshal(rowBytes, valuePtr); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
 // Err returns any errors that have occurred on the stream
 func (r *queryStreamer) Err() error {
 	r.lock.Lock()
@@ -96,7 +139,9 @@ func (r *queryStreamer) finishWithoutError() {
 
 func (r *queryStreamer) finishWithError(err error) {
 	// Lets record the error that happened
+	r.lock.Lock()
 	r.err = err
+	r.lock.Unlock()
 
 	// Our streamer is invalidated as soon as an error occurs
 	r.streamer = nil
@@ -110,7 +155,9 @@ func (r *queryStreamer) finishWithError(err error) {
 	}
 
 	// The stream itself is now no longer valid
+	r.lock.Lock()
 	r.stream = nil
+	r.lock.Unlock()
 }
 
 // Close marks the results as closed, returning any errors that occurred during reading the results.
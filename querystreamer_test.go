@@ -0,0 +1,133 @@
+package gocbcore
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+)
+
+// trackingReader records how much of the underlying data has been read so that tests can check that rows are
+// being streamed rather than the whole response being buffered.
+type trackingReader struct {
+	reader    io.Reader
+	bytesRead int
+}
+
+func (r *trackingReader) Read(p []byte) (int, error) {
+	// Only hand out a small amount at a time so that reads reflect what the streamer actually needs.
+	if len(p) > 16 {
+		p = p[:16]
+	}
+	n, err := r.reader.Read(p)
+	r.bytesRead += n
+	return n, err
+}
+
+func (suite *UnitTestSuite) TestQueryStreamerStreamsRows() {
+	var body bytes.Buffer
+	body.WriteString(`{"requestID":"1234","results":[`)
+	for i := 0; i < 1000; i++ {
+		if i > 0 {
+			body.WriteString(",")
+		}
+		body.WriteString(`{"name":"a row which is long enough to take up some space in the response"}`)
+	}
+	body.WriteString(`],"status":"success","metrics":{"resultCount":1000}}`)
+	totalLen := body.Len()
+
+	tracker := &trackingReader{reader: &body}
+	streamer, err := newQueryStreamer(ioutil.NopCloser(tracker), "results")
+	suite.Require().Nil(err, err)
+
+	suite.Require().NotNil(streamer.NextRow())
+	suite.Assert().Less(tracker.bytesRead, totalLen/2)
+
+	_, err = streamer.MetaData()
+	suite.Assert().NotNil(err)
+
+	numRows := 1
+	for streamer.NextRow() != nil {
+		numRows++
+	}
+	suite.Assert().Equal(1000, numRows)
+	suite.Require().Nil(streamer.Err())
+
+	meta, err := streamer.MetaData()
+	suite.Require().Nil(err, err)
+	suite.Assert().Contains(string(meta), `"resultCount":1000`)
+	suite.Assert().Contains(string(meta), `"status":"success"`)
+}
+
+func (suite *UnitTestSuite) TestQueryStreamerMalformedRows() {
+	body := bytes.NewBufferString(`{"results":[{"name":"a"},{"name":`)
+
+	streamer, err := newQueryStreamer(ioutil.NopCloser(body), "results")
+	suite.Require().Nil(err, err)
+
+	suite.Require().NotNil(streamer.NextRow())
+	suite.Assert().Nil(streamer.NextRow())
+	suite.Assert().NotNil(streamer.Err())
+	suite.Assert().NotNil(streamer.Close())
+}
+
+type countingRowUnmarshaler struct {
+	calls int
+}
+
+func (u *countingRowUnmarshaler) Unmarshal(data []byte, valuePtr interface{}) error {
+	u.calls++
+	return jsonRowUnmarshaler{}.Unmarshal(data, valuePtr)
+}
+
+func (suite *UnitTestSuite) TestQueryStreamerNextRowDecodeReusesBuffer() {
+	body := bytes.NewBufferString(`{"results":[{"name":"alice"},{"name":"bob"},{"name":"carol"}],"status":"success"}`)
+
+	streamer, err := newQueryStreamer(ioutil.NopCloser(body), "results")
+	suite.Require().Nil(err, err)
+
+	unmarshaler := &countingRowUnmarshaler{}
+	var names []string
+	var firstBuf []byte
+	for {
+		var row struct {
+			Name string `json:"name"`
+		}
+		ok, err := streamer.NextRowDecode(unmarshaler, &row)
+		suite.Require().Nil(err, err)
+		if !ok {
+			break
+		}
+		if firstBuf == nil {
+			firstBuf = streamer.rowBuf[:1]
+		}
+		names = append(names, row.Name)
+	}
+
+	suite.Require().Nil(streamer.Err())
+	suite.Assert().Equal([]string{"alice", "bob", "carol"}, names)
+	suite.Assert().Equal(3, unmarshaler.calls)
+	// Later rows are no longer than the first so should have been read into the same memory.
+	suite.Assert().Equal(&firstBuf[0], &streamer.rowBuf[:1][0])
+
+	meta, err := streamer.MetaData()
+	suite.Require().Nil(err, err)
+	suite.Assert().Contains(string(meta), `"status":"success"`)
+}
+
+func (suite *UnitTestSuite) TestQueryStreamerNextRowInto() {
+	body := bytes.NewBufferString(`{"results":[{"a":1},{"b":2}]}`)
+
+	streamer, err := newQueryStreamer(ioutil.NopCloser(body), "results")
+	suite.Require().Nil(err, err)
+
+	buf := make([]byte, 0, 64)
+	row := streamer.NextRowInto(buf)
+	suite.Assert().Equal(`{"a":1}`, string(row))
+	suite.Assert().Equal(&buf[:1][0], &row[0])
+
+	row = streamer.NextRowInto(buf)
+	suite.Assert().Equal(`{"b":2}`, string(row))
+
+	suite.Assert().Nil(streamer.NextRowInto(buf))
+	suite.Assert().Nil(streamer.Err())
+}
@@ -3,6 +3,7 @@ package gocbcore
 import (
 	"encoding/json"
 	"math"
+	"math/rand"
 	"time"
 
 	"github.com/couchbase/gocbcore/v10/memd"
@@ -19,6 +20,62 @@ type RetryRequest interface {
 	recordRetryAttempt(reason RetryReason)
 }
 
+// UserMetadataRequest is implemented by RetryRequests for operations which accept a UserMetadata option.
+// RetryStrategy implementations can type assert a RetryRequest to this interface to correlate retries with the
+// operation that the user performed.
+// Volatile: This API is subject to change at any time.
+type UserMetadataRequest interface {
+	RetryRequest
+
+	// UserMetadata returns the opaque value which was provided in the options of the operation, if any.
+	UserMetadata() interface{}
+}
+
+// ServerRetryAfterRequest is implemented by RetryRequests which can report how long the server asked for the request
+// to wait before being retried, such as from the KV error map or a HTTP Retry-After header. RetryStrategy
+// implementations can type assert a RetryRequest to this interface to honor the server's hint.
+// Volatile: This API is subject to change at any time.
+type ServerRetryAfterRequest interface {
+	RetryRequest
+
+	// ServerRetryAfter returns the duration the server asked the request to wait before retrying, or 0 if the
+	// server did not supply one for the most recent failure.
+	ServerRetryAfter() time.Duration
+}
+
+// maxServerRetryAfter is the longest that a request will wait to be retried when the server asks it to wait, so that
+// a bad hint cannot hold up requests which have no deadline indefinitely.
+const maxServerRetryAfter = 30 * time.Second
+
+// deadlineRetryRequest is implemented by RetryRequests which know the deadline of the operation that they are for.
+type deadlineRetryRequest interface {
+	operationDeadline() time.Time
+}
+
+// serverRetryAfter returns the server supplied retry duration for the request, if any, capped to maxServerRetryAfter
+// and to the time remaining until the deadline of the operation.
+func serverRetryAfter(req RetryRequest) time.Duration {
+	hintReq, ok := req.(ServerRetryAfterRequest)
+	if !ok {
+		return 0
+	}
+
+	duration := hintReq.ServerRetryAfter()
+	if duration > maxServerRetryAfter {
+		duration = maxServerRetryAfter
+	}
+
+	if deadlineReq, ok := req.(deadlineRetryRequest); ok {
+		if deadline := deadlineReq.operationDeadline(); !deadline.IsZero() {
+			if remaining := time.Until(deadline); duration > remaining {
+				duration = remaining
+			}
+		}
+	}
+
+	return duration
+}
+
 // RetryReason represents the reason for an operation possibly being retried.
 type RetryReason interface {
 	AllowsNonIdempotentRetry() bool
@@ -101,6 +158,15 @@ var (
 	// CircuitBreakerOpenRetryReason indicates that the operation failed because the circuit breaker for the underlying socket was open.
 	CircuitBreakerOpenRetryReason = retryReason{allowsNonIdempotentRetry: true, alwaysRetry: false, description: "CIRCUIT_BREAKER_OPEN"}
 
+	// ClientRateLimitedRetryReason indicates that the operation failed because it could not be dispatched within the
+	// client side rate limits.
+	// Volatile: This API is subject to change at any time.
+	ClientRateLimitedRetryReason = retryReason{allowsNonIdempotentRetry: true, alwaysRetry: false, description: "CLIENT_RATE_LIMITED"}
+
+	// KVRateLimitedRetryReason indicates that the operation failed because the server rate limited it.
+	// Volatile: This API is subject to change at any time.
+	KVRateLimitedRetryReason = retryReason{allowsNonIdempotentRetry: true, alwaysRetry: false, description: "KV_RATE_LIMITED"}
+
 	// QueryIndexNotFoundRetryReason indicates that the operation failed to to a missing query index
 	QueryIndexNotFoundRetryReason = retryReason{allowsNonIdempotentRetry: true, alwaysRetry: false, description: "QUERY_INDEX_NOT_FOUND"}
 
@@ -127,6 +193,11 @@ var (
 	// Uncommitted: This API may change in the future.
 	BucketNotReadyReason = retryReason{allowsNonIdempotentRetry: true, alwaysRetry: false, description: "BUCKET_NOT_FOUND"}
 
+	// BucketWarmupRetryReason indicates that selecting a bucket during bootstrap failed because the bucket is still
+	// being created or warmed up, and that the select is being retried. See KVConfig.BucketWarmupRetryWindow.
+	// Volatile: This API is subject to change at any time.
+	BucketWarmupRetryReason = retryReason{allowsNonIdempotentRetry: true, alwaysRetry: true, description: "BUCKET_WARMUP"}
+
 	// ConnectionErrorRetryReason indicates that there were errors reported by underlying connections.
 	// Check server ports and cluster encryption setting.
 	ConnectionErrorRetryReason = retryReason{allowsNonIdempotentRetry: true, alwaysRetry: false, description: "CONNECTION_ERROR"}
@@ -243,17 +314,24 @@ func NewBestEffortRetryStrategy(calculator BackoffCalculator) *BestEffortRetrySt
 	return &BestEffortRetryStrategy{backoffCalculator: calculator}
 }
 
-// RetryAfter calculates and returns a RetryAction describing how long to wait before retrying an operation.
+// RetryAfter calculates and returns a RetryAction describing how long to wait before retrying an operation. If the
+// server has supplied a duration to wait then that is used rather than the backoff calculator, capped so that it never
+// extends beyond the deadline of the operation.
 func (rs *BestEffortRetryStrategy) RetryAfter(req RetryRequest, reason RetryReason) RetryAction {
 	if req.Idempotent() || reason.AllowsNonIdempotentRetry() {
+		if duration := serverRetryAfter(req); duration > 0 {
+			return &WithDurationRetryAction{WithDuration: duration}
+		}
+
 		return &WithDurationRetryAction{WithDuration: rs.backoffCalculator(req.RetryAttempts())}
 	}
 
 	return &NoRetryRetryAction{}
 }
 
-// ExponentialBackoff calculates a backoff time duration from the retry attempts on a given request.
-func ExponentialBackoff(min, max time.Duration, backoffFactor float64) BackoffCalculator {
+// exponentialBackoffParams returns the minimum backoff, maximum backoff and backoff factor to use for a backoff
+// calculator, replacing any unset values with the defaults.
+func exponentialBackoffParams(min, max time.Duration, backoffFactor float64) (float64, float64, float64) {
 	var minBackoff float64 = 1000000   // 1 Millisecond
 	var maxBackoff float64 = 500000000 // 500 Milliseconds
 	var factor float64 = 2
@@ -268,17 +346,98 @@ func ExponentialBackoff(min, max time.Duration, backoffFactor float64) BackoffCa
 		factor = backoffFactor
 	}
 
+	return minBackoff, maxBackoff, factor
+}
+
+// cappedExponentialBackoff calculates minBackoff * factor^retryAttempts bounded to between minBackoff and
+// maxBackoff. Large numbers of retry attempts result in an infinite intermediate value which is then capped.
+func cappedExponentialBackoff(minBackoff, maxBackoff, factor float64, retryAttempts uint32) float64 {
+	backoff := minBackoff * (math.Pow(factor, float64(retryAttempts)))
+
+	if backoff > maxBackoff || math.IsNaN(backoff) {
+		backoff = maxBackoff
+	}
+	if backoff < minBackoff {
+		backoff = minBackoff
+	}
+
+	return backoff
+}
+
+// durationFromBackoff converts a backoff in nanoseconds to a duration, saturating rather than overflowing.
+func durationFromBackoff(backoff float64) time.Duration {
+	if backoff >= math.MaxInt64 {
+		return time.Duration(math.MaxInt64)
+	}
+	if backoff <= 0 {
+		return 0
+	}
+
+	return time.Duration(backoff)
+}
+
+// randomBackoffBetween returns a random backoff in the range [low, high].
+func randomBackoffBetween(low, high float64) float64 {
+	if high <= low {
+		return low
+	}
+
+	return low + rand.Float64()*(high-low) // #nosec G404
+}
+
+// ExponentialBackoff calculates a backoff time duration from the retry attempts on a given request.
+func ExponentialBackoff(min, max time.Duration, backoffFactor float64) BackoffCalculator {
+	minBackoff, maxBackoff, factor := exponentialBackoffParams(min, max, backoffFactor)
+
 	return func(retryAttempts uint32) time.Duration {
-		backoff := minBackoff * (math.Pow(factor, float64(retryAttempts)))
+		return durationFromBackoff(cappedExponentialBackoff(minBackoff, maxBackoff, factor, retryAttempts))
+	}
+}
 
-		if backoff > maxBackoff {
-			backoff = maxBackoff
-		}
-		if backoff < minBackoff {
-			backoff = minBackoff
+// FullJitterBackoff calculates a backoff time duration from the retry attempts on a given request. The duration is
+// chosen at random between min and the value that ExponentialBackoff would return for the same parameters.
+// Volatile: This API is subject to change at any time.
+func FullJitterBackoff(min, max time.Duration, backoffFactor float64) BackoffCalculator {
+	minBackoff, maxBackoff, factor := exponentialBackoffParams(min, max, backoffFactor)
+
+	return func(retryAttempts uint32) time.Duration {
+		backoff := cappedExponentialBackoff(minBackoff, maxBackoff, factor, retryAttempts)
+
+		return durationFromBackoff(randomBackoffBetween(minBackoff, backoff))
+	}
+}
+
+// EqualJitterBackoff calculates a backoff time duration from the retry attempts on a given request. The duration is
+// half of the value that ExponentialBackoff would return for the same parameters, plus a random amount up to the
+// other half, but is never less than min.
+// Volatile: This API is subject to change at any time.
+func EqualJitterBackoff(min, max time.Duration, backoffFactor float64) BackoffCalculator {
+	minBackoff, maxBackoff, factor := exponentialBackoffParams(min, max, backoffFactor)
+
+	return func(retryAttempts uint32) time.Duration {
+		backoff := cappedExponentialBackoff(minBackoff, maxBackoff, factor, retryAttempts)
+		half := backoff / 2
+		jittered := randomBackoffBetween(half, backoff)
+		if jittered < minBackoff {
+			jittered = minBackoff
 		}
 
-		return time.Duration(backoff)
+		return durationFromBackoff(jittered)
+	}
+}
+
+// DecorrelatedJitterBackoff calculates a backoff time duration from the retry attempts on a given request. The
+// duration is chosen at random between min and three times the largest duration that could have been chosen for the
+// previous attempt, capped at max. BackoffCalculator is not given the previous duration, so the upper bound is
+// derived from the number of retry attempts rather than from the duration that was actually used.
+// Volatile: This API is subject to change at any time.
+func DecorrelatedJitterBackoff(min, max time.Duration) BackoffCalculator {
+	minBackoff, maxBackoff, _ := exponentialBackoffParams(min, max, 0)
+
+	return func(retryAttempts uint32) time.Duration {
+		upper := cappedExponentialBackoff(minBackoff, maxBackoff, 3, retryAttempts)
+
+		return durationFromBackoff(randomBackoffBetween(minBackoff, upper))
 	}
 }
 
@@ -2,6 +2,7 @@ package gocbcore
 
 import (
 	"fmt"
+	"math"
 	"reflect"
 	"testing"
 	"time"
@@ -404,3 +405,90 @@ func (suite *StandardTestSuite) TestExponentialBackoffNonDefaults() {
 		}
 	}
 }
+
+func (suite *UnitTestSuite) TestExponentialBackoffOverflow() {
+	backoff := ExponentialBackoff(time.Millisecond, time.Duration(math.MaxInt64), 10)
+	suite.Assert().Equal(time.Millisecond, backoff(0))
+	suite.Assert().Equal(time.Duration(math.MaxInt64), backoff(math.MaxUint32))
+
+	backoff = ExponentialBackoff(0, 0, 0)
+	suite.Assert().Equal(500*time.Millisecond, backoff(math.MaxUint32))
+}
+
+func (suite *UnitTestSuite) TestJitterBackoffBounds() {
+	min := 10 * time.Millisecond
+	max := time.Second
+
+	fullJitter := FullJitterBackoff(min, max, 2)
+	equalJitter := EqualJitterBackoff(min, max, 2)
+	decorrelatedJitter := DecorrelatedJitterBackoff(min, max)
+	for attempts := uint32(0); attempts < 20; attempts++ {
+		upper := ExponentialBackoff(min, max, 2)(attempts)
+
+		backoff := fullJitter(attempts)
+		suite.Assert().GreaterOrEqual(backoff, min)
+		suite.Assert().LessOrEqual(backoff, upper)
+
+		backoff = equalJitter(attempts)
+		suite.Assert().GreaterOrEqual(backoff, min)
+		suite.Assert().GreaterOrEqual(backoff, upper/2)
+		suite.Assert().LessOrEqual(backoff, upper)
+
+		backoff = decorrelatedJitter(attempts)
+		suite.Assert().GreaterOrEqual(backoff, min)
+		suite.Assert().LessOrEqual(backoff, ExponentialBackoff(min, max, 3)(attempts))
+	}
+
+	suite.Assert().GreaterOrEqual(FullJitterBackoff(0, time.Duration(math.MaxInt64), 2)(math.MaxUint32),
+		time.Millisecond)
+}
+
+func (suite *UnitTestSuite) TestBestEffortRetryStrategyServerRetryAfter() {
+	strategy := NewBestEffortRetryStrategy(func(retryAttempts uint32) time.Duration {
+		return 5 * time.Millisecond
+	})
+
+	req := &httpRequest{IsIdempotent: true}
+	action := strategy.RetryAfter(req, UnknownRetryReason)
+	suite.Assert().Equal(5*time.Millisecond, action.Duration())
+
+	req.setServerRetryAfter(2 * time.Second)
+	action = strategy.RetryAfter(req, UnknownRetryReason)
+	suite.Assert().Equal(2*time.Second, action.Duration())
+
+	memdReq := &memdQRequest{}
+	memdReq.setServerRetryAfter(3 * time.Second)
+	action = strategy.RetryAfter(memdReq, KVErrMapRetryReason)
+	suite.Assert().Equal(3*time.Second, action.Duration())
+
+	// The server's hint is capped, so that a request without a deadline cannot be held up indefinitely.
+	memdReq.setServerRetryAfter(time.Hour)
+	action = strategy.RetryAfter(memdReq, KVErrMapRetryReason)
+	suite.Assert().Equal(maxServerRetryAfter, action.Duration())
+
+	// It never extends beyond the deadline of the operation.
+	memdReq.dispatchDeadline = time.Now().Add(time.Second)
+	action = strategy.RetryAfter(memdReq, KVErrMapRetryReason)
+	suite.Assert().LessOrEqual(int64(action.Duration()), int64(time.Second))
+	suite.Assert().Greater(int64(action.Duration()), int64(0))
+
+	req.Deadline = time.Now().Add(time.Second)
+	action = strategy.RetryAfter(req, UnknownRetryReason)
+	suite.Assert().LessOrEqual(int64(action.Duration()), int64(time.Second))
+
+	// Once the deadline has passed the backoff calculator is used instead.
+	req.Deadline = time.Now().Add(-time.Second)
+	action = strategy.RetryAfter(req, UnknownRetryReason)
+	suite.Assert().Equal(5*time.Millisecond, action.Duration())
+}
+
+func (suite *UnitTestSuite) TestParseRetryAfterHeader() {
+	now := time.Date(2021, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	suite.Assert().Equal(120*time.Second, parseRetryAfterHeader("120", now))
+	suite.Assert().Equal(30*time.Second, parseRetryAfterHeader("Fri, 01 Jan 2021 12:00:30 GMT", now))
+	suite.Assert().Equal(time.Duration(0), parseRetryAfterHeader("Fri, 01 Jan 2021 11:59:00 GMT", now))
+	suite.Assert().Equal(time.Duration(0), parseRetryAfterHeader("", now))
+	suite.Assert().Equal(time.Duration(0), parseRetryAfterHeader("-1", now))
+	suite.Assert().Equal(time.Duration(0), parseRetryAfterHeader("soon", now))
+}
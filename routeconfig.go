@@ -36,6 +36,9 @@ type routeConfig struct {
 
 	clusterCapabilitiesVer []int
 	clusterCapabilities    map[string][]string
+	// clusterCompatVersion is the cluster compatibility version reported by the nodes, encoded as major<<16|minor,
+	// or 0 if the config doesn't include it.
+	clusterCompatVersion int
 
 	bucketCapabilities    []string
 	bucketCapabilitiesVer string
@@ -163,3 +166,76 @@ func (config *routeConfig) IsNewerThan(oldCfg *routeConfig) bool {
 
 	return true
 }
+
+// NodeChanges returns the number of kv nodes present in this config but not in the old config, and the number present
+// in the old config but not in this one.
+func (config *routeConfig) NodeChanges(oldCfg *routeConfig, useSSL bool) (int, int) {
+	added, removed := config.NodeChangeAddresses(oldCfg, useSSL)
+	return len(added), len(removed)
+}
+
+// NodeChangeAddresses returns the addresses of the kv nodes present in this config but not in the old config, and of
+// those present in the old config but not in this one.
+func (config *routeConfig) NodeChangeAddresses(oldCfg *routeConfig, useSSL bool) ([]string, []string) {
+	oldAddrs := make(map[string]struct{})
+	for _, ep := range oldCfg.kvEndpoints(useSSL) {
+		oldAddrs[ep.Address] = struct{}{}
+	}
+
+	var added []string
+	newAddrs := make(map[string]struct{})
+	for _, ep := range config.kvEndpoints(useSSL) {
+		newAddrs[ep.Address] = struct{}{}
+		if _, ok := oldAddrs[ep.Address]; !ok {
+			added = append(added, ep.Address)
+		}
+	}
+
+	var removed []string
+	for _, ep := range oldCfg.kvEndpoints(useSSL) {
+		if _, ok := newAddrs[ep.Address]; !ok {
+			removed = append(removed, ep.Address)
+		}
+	}
+
+	return added, removed
+}
+
+// VbucketsMoved returns the number of vbuckets whose active node is different in this config to in the old config.
+// If either config has no vbucket map, or they have different numbers of vbuckets, then no vbuckets are considered
+// to have moved.
+func (config *routeConfig) VbucketsMoved(oldCfg *routeConfig, useSSL bool) int {
+	if config.vbMap == nil || oldCfg.vbMap == nil || config.vbMap.NumVbuckets() != oldCfg.vbMap.NumVbuckets() {
+		return 0
+	}
+
+	activeAddress := func(cfg *routeConfig, vbID uint16) string {
+		idx, err := cfg.vbMap.NodeByVbucket(vbID, 0)
+		if err != nil {
+			return ""
+		}
+
+		endpoints := cfg.kvEndpoints(useSSL)
+		if idx < 0 || idx >= len(endpoints) {
+			return ""
+		}
+
+		return endpoints[idx].Address
+	}
+
+	var moved int
+	for vbID := 0; vbID < config.vbMap.NumVbuckets(); vbID++ {
+		if activeAddress(config, uint16(vbID)) != activeAddress(oldCfg, uint16(vbID)) {
+			moved++
+		}
+	}
+
+	return moved
+}
+
+func (config *routeConfig) kvEndpoints(useSSL bool) []routeEndpoint {
+	if useSSL {
+		return config.kvServerList.SSLEndpoints
+	}
+	return config.kvServerList.NonSSLEndpoints
+}
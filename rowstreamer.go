@@ -104,6 +104,11 @@ func (s *rowStreamer) begin() error {
 }
 
 func (s *rowStreamer) readRow() (json.RawMessage, error) {
+	return s.readRowInto(nil)
+}
+
+// readRowInto reads the next row, reusing the memory of buf for the row bytes where it has the capacity.
+func (s *rowStreamer) readRowInto(buf []byte) (json.RawMessage, error) {
 	if s.state < rowStreamStateRows {
 		return nil, errors.New("unexpected parsing state during readRow")
 	}
@@ -120,8 +125,9 @@ func (s *rowStreamer) readRow() (json.RawMessage, error) {
 		return nil, nil
 	}
 
-	// Decode this row and return a raw message
-	var msg json.RawMessage
+	// Decode this row and return a raw message, decoding into a raw message appends to its existing contents so
+	// this will reuse buf where possible.
+	msg := json.RawMessage(buf[:0])
 	err := s.decoder.Decode(&msg)
 	if err != nil {
 		return nil, err
@@ -185,6 +191,10 @@ func (s *rowStreamer) NextRowBytes() (json.RawMessage, error) {
 	return s.readRow()
 }
 
+func (s *rowStreamer) NextRowBytesInto(buf []byte) (json.RawMessage, error) {
+	return s.readRowInto(buf)
+}
+
 func (s *rowStreamer) Finalize() (json.RawMessage, error) {
 	// Make sure we've read until the end of the object
 	for {
@@ -5,7 +5,6 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io/ioutil"
 	"net/url"
 	"strings"
 	"sync"
@@ -37,6 +36,75 @@ func (q *SearchRowReader) Close() error {
 	return q.streamer.Close()
 }
 
+// Status returns the status of the query from the metadata, which includes whether any of the index partitions
+// failed, in which case the hits returned may be incomplete. This is only available once all rows have been read.
+// Volatile: This API is subject to change at any time.
+func (q *SearchRowReader) Status() (*SearchStatus, error) {
+	meta, err := q.streamer.MetaData()
+	if err != nil {
+		return nil, err
+	}
+
+	return parseSearchStatus(meta)
+}
+
+// SearchStatus describes how many of the index partitions (pindexes) successfully executed a search query.
+// Volatile: This API is subject to change at any time.
+type SearchStatus struct {
+	Total      int
+	Failed     int
+	Successful int
+	// Errors maps the name of each index partition which failed to the error that it returned.
+	Errors map[string]string
+	// PartialResults indicates that some of the index partitions failed, so the hits returned may be incomplete.
+	PartialResults bool
+}
+
+type jsonSearchStatus struct {
+	Total      int             `json:"total"`
+	Failed     int             `json:"failed"`
+	Successful int             `json:"successful"`
+	Errors     json.RawMessage `json:"errors,omitempty"`
+}
+
+func parseSearchStatus(meta []byte) (*SearchStatus, error) {
+	var metaParse struct {
+		Status jsonSearchStatus `json:"status"`
+	}
+	if err := json.Unmarshal(meta, &metaParse); err != nil {
+		return nil, wrapError(errProtocol, "failed to parse search metadata")
+	}
+
+	status := &SearchStatus{
+		Total:          metaParse.Status.Total,
+		Failed:         metaParse.Status.Failed,
+		Successful:     metaParse.Status.Successful,
+		PartialResults: metaParse.Status.Failed > 0,
+	}
+
+	if len(metaParse.Status.Errors) > 0 {
+		// Errors are normally keyed by index partition, but a list of errors is also accepted.
+		var errs map[string]string
+		if err := json.Unmarshal(metaParse.Status.Errors, &errs); err != nil {
+			var errList []string
+			if err := json.Unmarshal(metaParse.Status.Errors, &errList); err != nil {
+				return nil, wrapError(errProtocol, "failed to parse search status errors")
+			}
+
+			errs = make(map[string]string, len(errList))
+			for i, errMsg := range errList {
+				errs[fmt.Sprintf("%d", i)] = errMsg
+			}
+		}
+		if len(errs) > 0 {
+			status.Errors = errs
+			status.PartialResults = true
+		}
+	}
+
+	return status, nil
+}
+
 // SearchQueryOptions represents the various options available for a search query.
 type SearchQueryOptions struct {
 	BucketName    string
@@ -46,12 +114,53 @@ type SearchQueryOptions struct {
 	RetryStrategy RetryStrategy
 	Deadline      time.Time
 
+	// Collections restricts the query to documents within these collections.
+	// Volatile: This API is subject to change at any time.
+	Collections []string
+	// Facets are the facet requests to include in the query, keyed by facet name.
+	// Volatile: This API is subject to change at any time.
+	Facets map[string]json.RawMessage
+	// Knn are the vector search queries to perform.
+	// Volatile: This API is subject to change at any time.
+	Knn []json.RawMessage
+	// KnnOperator is how the results of the Knn queries are combined with each other and the query, "and" or "or".
+	// Volatile: This API is subject to change at any time.
+	KnnOperator string
+
 	// Internal: This should never be used and is not supported.
 	User string
 
 	TraceContext RequestSpanContext
 }
 
+// applySearchQueryOptions adds the query options which are part of the request body to the payload, values which are
+// already present in the payload take precedence. Returns whether the payload was modified.
+func applySearchQueryOptions(payloadMap map[string]interface{}, opts SearchQueryOptions) bool {
+	var modified bool
+	setIfAbsent := func(key string, value interface{}) {
+		if _, ok := payloadMap[key]; ok {
+			return
+		}
+		payloadMap[key] = value
+		modified = true
+	}
+
+	if len(opts.Collections) > 0 {
+		setIfAbsent("collections", opts.Collections)
+	}
+	if len(opts.Facets) > 0 {
+		setIfAbsent("facets", opts.Facets)
+	}
+	if len(opts.Knn) > 0 {
+		setIfAbsent("knn", opts.Knn)
+	}
+	if opts.KnnOperator != "" {
+		setIfAbsent("knn_operator", opts.KnnOperator)
+	}
+
+	return modified
+}
+
 type jsonSearchErrorResponse struct {
 	Error string
 }
@@ -82,7 +191,7 @@ func parseSearchError(req *httpRequest, indexName string, query interface{}, res
 	var err error
 	var errMsg string
 
-	respBody, readErr := ioutil.ReadAll(resp.Body)
+	respBody, readErr := resp.readBody()
 	if readErr == nil {
 		var respParse jsonSearchErrorResponse
 		parseErr := json.Unmarshal(respBody, &respParse)
@@ -202,6 +311,15 @@ func (sqc *searchQueryComponent) SearchQuery(opts SearchQueryOptions, cb SearchQ
 		ctlMap = make(map[string]interface{})
 	}
 
+	body := opts.Payload
+	if applySearchQueryOptions(payloadMap, opts) {
+		body, err = json.Marshal(payloadMap)
+		if err != nil {
+			tracer.Finish()
+			return nil, wrapSearchError(nil, "", nil, wrapError(err, "failed to produce payload"), 0)
+		}
+	}
+
 	if opts.BucketName != "" && opts.ScopeName != "" {
 		if sqc.capabilityStatus(SearchCapabilityScopedIndexes) == CapabilityStatusUnsupported {
 			return nil, wrapSearchError(nil, "", nil,
@@ -231,7 +349,7 @@ func (sqc *searchQueryComponent) SearchQuery(opts SearchQueryOptions, cb SearchQ
 		Service:          FtsService,
 		Method:           "POST",
 		Path:             reqURI,
-		Body:             opts.Payload,
+		Body:             body,
 		IsIdempotent:     true,
 		Deadline:         opts.Deadline,
 		RetryStrategy:    opts.RetryStrategy,
@@ -243,9 +361,12 @@ func (sqc *searchQueryComponent) SearchQuery(opts SearchQueryOptions, cb SearchQ
 
 	go func() {
 		res, err := sqc.searchQuery(ireq, indexName, query, payloadMap, ctlMap, tracer.StartTime())
+		err = ireq.complete(err, func() error {
+			return res.Close()
+		})
 		if err != nil {
 			cancel()
-			tracer.Finish()
+			tracer.FinishWithError(err)
 			cb(nil, err)
 			return
 		}
@@ -311,11 +432,13 @@ func (sqc *searchQueryComponent) searchQuery(ireq *httpRequest, indexName string
 				return nil, searchErr
 			}
 
+			ireq.setServerRetryAfter(retryAfterFromHTTPResponse(resp))
 			shouldRetry, retryTime := retryOrchMaybeRetry(ireq, retryReason)
 			if !shouldRetry {
 				// searchErr is already wrapped here
 				return nil, searchErr
 			}
+			sqc.tracer.RetryCountRecord(metricValueServiceSearchValue, "")
 
 			select {
 			case <-time.After(time.Until(retryTime)):
@@ -336,7 +459,7 @@ func (sqc *searchQueryComponent) searchQuery(ireq *httpRequest, indexName string
 
 		streamer, err := newQueryStreamer(resp.Body, "hits")
 		if err != nil {
-			respBody, readErr := ioutil.ReadAll(resp.Body)
+			respBody, readErr := resp.readBody()
 			if readErr != nil {
 				logDebugf("Failed to read response body: %v", readErr)
 			}
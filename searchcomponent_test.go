@@ -3,6 +3,7 @@ package gocbcore
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"github.com/stretchr/testify/mock"
 	"io/ioutil"
 )
@@ -41,6 +42,44 @@ func (suite *UnitTestSuite) TestSearchComponentNilRows() {
 	suite.Assert().Len(errs, 6)
 }
 
+func (suite *UnitTestSuite) TestSearchComponentStatusPartialResults() {
+	d, err := suite.LoadRawTestDataset("search_hits_nil")
+	suite.Require().Nil(err)
+
+	qStreamer, err := newQueryStreamer(ioutil.NopCloser(bytes.NewBuffer(d)), "hits")
+	suite.Require().Nil(err, err)
+
+	reader := SearchRowReader{
+		streamer: qStreamer,
+	}
+	for reader.NextRow() != nil {
+	}
+
+	status, err := reader.Status()
+	suite.Require().Nil(err, err)
+
+	suite.Assert().True(status.PartialResults)
+	suite.Assert().Equal(6, status.Total)
+	suite.Assert().Equal(6, status.Failed)
+	suite.Assert().Zero(status.Successful)
+	suite.Assert().Len(status.Errors, 6)
+	suite.Assert().Equal("context deadline exceeded", status.Errors["travel_a464a32f957f35f1_13aa53f3"])
+}
+
+func (suite *UnitTestSuite) TestSearchComponentParseStatus() {
+	status, err := parseSearchStatus([]byte(`{"status":{"total":2,"failed":0,"successful":2},"total_hits":0}`))
+	suite.Require().Nil(err, err)
+	suite.Assert().Equal(&SearchStatus{Total: 2, Successful: 2}, status)
+
+	status, err = parseSearchStatus([]byte(`{"status":{"total":2,"failed":1,"successful":1,"errors":["pindex failed"]}}`))
+	suite.Require().Nil(err, err)
+	suite.Assert().True(status.PartialResults)
+	suite.Assert().Equal(map[string]string{"0": "pindex failed"}, status.Errors)
+
+	_, err = parseSearchStatus([]byte(`{"status":{"errors":5}}`))
+	suite.Assert().True(errors.Is(err, ErrProtocol), err)
+}
+
 func (suite *UnitTestSuite) TestSearchComponentRouteConfigHandling() {
 	configC := new(mockConfigManager)
 	configC.On("AddConfigWatcher", mock.AnythingOfType("*gocbcore.searchQueryComponent"))
@@ -109,3 +148,55 @@ func (suite *UnitTestSuite) TestSearchComponentScopedIndexUnsupported() {
 	suite.Assert().ErrorIs(err, ErrFeatureNotAvailable)
 	suite.Assert().Contains(err.Error(), "scoped search indexes are not supported by this cluster version")
 }
+
+func (suite *UnitTestSuite) TestSearchComponentKnnOptionUnsupported() {
+	configC := new(mockConfigManager)
+	configC.On("AddConfigWatcher", mock.Anything)
+
+	sqc := newSearchQueryComponent(nil, configC, newTracerComponent(&noopTracer{}, "", true, &noopMeter{}, configC))
+	sqc.caps[SearchCapabilityVectorSearch] = CapabilityStatusUnsupported
+
+	opts := SearchQueryOptions{
+		IndexName: "test-index",
+		Payload:   []byte("{}"),
+		Knn:       []json.RawMessage{json.RawMessage(`{"field":"vec","k":2,"vector":[0.1,0.2]}`)},
+	}
+	_, err := sqc.SearchQuery(opts, nil)
+
+	suite.Assert().ErrorIs(err, ErrFeatureNotAvailable)
+}
+
+func (suite *UnitTestSuite) TestSearchComponentApplyQueryOptions() {
+	payloadMap := map[string]interface{}{
+		"query":  map[string]interface{}{"match_all": map[string]interface{}{}},
+		"facets": map[string]interface{}{"existing": map[string]interface{}{}},
+	}
+
+	modified := applySearchQueryOptions(payloadMap, SearchQueryOptions{
+		Collections: []string{"airline", "hotel"},
+		Facets:      map[string]json.RawMessage{"type": json.RawMessage(`{"field":"type","size":5}`)},
+		Knn:         []json.RawMessage{json.RawMessage(`{"field":"vec","k":2,"vector":[0.1,0.2]}`)},
+		KnnOperator: "or",
+	})
+	suite.Require().True(modified)
+
+	payload, err := json.Marshal(payloadMap)
+	suite.Require().Nil(err, err)
+
+	var decoded struct {
+		Collections []string                   `json:"collections"`
+		Facets      map[string]json.RawMessage `json:"facets"`
+		Knn         []json.RawMessage          `json:"knn"`
+		KnnOperator string                     `json:"knn_operator"`
+	}
+	suite.Require().Nil(json.Unmarshal(payload, &decoded))
+
+	suite.Assert().Equal([]string{"airline", "hotel"}, decoded.Collections)
+	// Facets in the payload take precedence over the option.
+	suite.Assert().Contains(decoded.Facets, "existing")
+	suite.Assert().NotContains(decoded.Facets, "type")
+	suite.Assert().Len(decoded.Knn, 1)
+	suite.Assert().Equal("or", decoded.KnnOperator)
+
+	suite.Assert().False(applySearchQueryOptions(map[string]interface{}{}, SearchQueryOptions{}))
+}
@@ -0,0 +1,177 @@
+package gocbcore
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testLeakSnapshot records the goroutines running in the process, grouped by the function which created them, and the
+// number of open sockets. Comparing a snapshot taken before an agent is created against the state after it has been
+// closed tells us whether the agent has left anything behind.
+type testLeakSnapshot struct {
+	goroutines map[string]int
+	sockets    int
+}
+
+func takeTestLeakSnapshot() testLeakSnapshot {
+	return testLeakSnapshot{
+		goroutines: testGoroutinesByCreator(),
+		sockets:    testOpenSocketCount(),
+	}
+}
+
+// leaksSince returns a description of every goroutine group and socket count which has grown since the before snapshot.
+func (snap testLeakSnapshot) leaksSince(before testLeakSnapshot) []string {
+	var leaks []string
+	for creator, count := range snap.goroutines {
+		if count > before.goroutines[creator] {
+			leaks = append(leaks, fmt.Sprintf("%d goroutine(s) created by %s", count-before.goroutines[creator], creator))
+		}
+	}
+	sort.Strings(leaks)
+
+	if before.sockets >= 0 && snap.sockets > before.sockets {
+		leaks = append(leaks, fmt.Sprintf("%d socket(s)", snap.sockets-before.sockets))
+	}
+
+	return leaks
+}
+
+// assertNoTestLeaks waits for the goroutines and sockets in the process to return to the state recorded in the before
+// snapshot, failing the test if they have not done so within the timeout.
+func assertNoTestLeaks(t *testing.T, before testLeakSnapshot, timeout time.Duration) {
+	deadline := time.Now().Add(timeout)
+	for {
+		leaks := takeTestLeakSnapshot().leaksSince(before)
+		if len(leaks) == 0 {
+			return
+		}
+
+		if time.Now().After(deadline) {
+			buf := make([]byte, 1<<20)
+			t.Errorf("Detected leaks after close: %s\n%s", strings.Join(leaks, ", "), buf[:runtime.Stack(buf, true)])
+			return
+		}
+
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func testGoroutinesByCreator() map[string]int {
+	buf := make([]byte, 1<<20)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+
+	goroutines := make(map[string]int)
+	for _, stack := range strings.Split(string(buf), "\n\n") {
+		idx := strings.Index(stack, "\ncreated by ")
+		if idx < 0 {
+			continue
+		}
+
+		creator := stack[idx+len("\ncreated by "):]
+		if end := strings.IndexAny(creator, " \n"); end >= 0 {
+			creator = creator[:end]
+		}
+
+		// The testing package runs each test in its own goroutine, these are not ours to account for.
+		if strings.HasPrefix(creator, "testing.") {
+			continue
+		}
+
+		goroutines[creator]++
+	}
+
+	return goroutines
+}
+
+// testOpenSocketCount returns the number of sockets open in the process, or -1 if this cannot be determined on the
+// current platform.
+func testOpenSocketCount() int {
+	fds, err := ioutil.ReadDir("/proc/self/fd")
+	if err != nil {
+		return -1
+	}
+
+	var sockets int
+	for _, fd := range fds {
+		link, err := os.Readlink("/proc/self/fd/" + fd.Name())
+		if err != nil {
+			continue
+		}
+
+		if strings.HasPrefix(link, "socket:") {
+			sockets++
+		}
+	}
+
+	return sockets
+}
+
+// testBlackholeListener accepts connections but never responds on them, leaving agents stuck part way through
+// bootstrapping, which is where shutdown ordering problems tend to show up.
+type testBlackholeListener struct {
+	listener net.Listener
+
+	lock  sync.Mutex
+	conns []net.Conn
+	wg    sync.WaitGroup
+}
+
+func newTestBlackholeListener() (*testBlackholeListener, error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+
+	l := &testBlackholeListener{
+		listener: listener,
+	}
+
+	l.wg.Add(1)
+	go func() {
+		defer l.wg.Done()
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+
+			l.lock.Lock()
+			l.conns = append(l.conns, conn)
+			l.lock.Unlock()
+		}
+	}()
+
+	return l, nil
+}
+
+func (l *testBlackholeListener) Addr() string {
+	return l.listener.Addr().String()
+}
+
+func (l *testBlackholeListener) Close() {
+	_ = l.listener.Close()
+	l.wg.Wait()
+
+	l.lock.Lock()
+	for _, conn := range l.conns {
+		_ = conn.Close()
+	}
+	l.conns = nil
+	l.lock.Unlock()
+}
@@ -0,0 +1,195 @@
+package gocbcore
+
+import (
+	"io"
+	"sync"
+	"time"
+
+	"github.com/couchbase/gocbcore/v10/memd"
+)
+
+// testMemdHandler is used by testMemdServer to build the response to a request, the response has already been
+// populated with the fields that must match the request.
+type testMemdHandler func(req *memd.Packet, resp *memd.Packet)
+
+// testMemdServer is an in-process memdConn which responds to requests using handlers registered per command. It
+// allows tests to control exactly what the server sends back, including server duration frames and the error map,
+// without relying on a real or mock cluster.
+type testMemdServer struct {
+	lock           sync.Mutex
+	handlers       map[memd.CmdCode]testMemdHandler
+	serverDuration time.Duration
+	errorMap       []byte
+	features       map[memd.HelloFeature]bool
+	holdCount      int
+	held           []*memd.Packet
+
+	respCh    chan *memd.Packet
+	closeCh   chan struct{}
+	closeOnce sync.Once
+}
+
+func newTestMemdServer() *testMemdServer {
+	return &testMemdServer{
+		handlers: make(map[memd.CmdCode]testMemdHandler),
+		features: make(map[memd.HelloFeature]bool),
+		respCh:   make(chan *memd.Packet, 100),
+		closeCh:  make(chan struct{}),
+	}
+}
+
+// SetHandler sets the handler used for any requests for the given command, requests for commands without a
+// handler receive an empty success response.
+func (s *testMemdServer) SetHandler(cmd memd.CmdCode, handler testMemdHandler) {
+	s.lock.Lock()
+	s.handlers[cmd] = handler
+	s.lock.Unlock()
+}
+
+// SetServerDuration sets the duration attached as a server duration frame to every response, zero disables the
+// frame.
+func (s *testMemdServer) SetServerDuration(duration time.Duration) {
+	s.lock.Lock()
+	s.serverDuration = duration
+	s.lock.Unlock()
+}
+
+// SetErrorMap sets the error map returned in response to CmdGetErrorMap.
+func (s *testMemdServer) SetErrorMap(errMap []byte) {
+	s.lock.Lock()
+	s.errorMap = errMap
+	s.lock.Unlock()
+}
+
+// HoldResponses holds back the responses to the next n requests and then sends them all in the reverse order to
+// which the requests were received, imitating a server executing requests out of order.
+func (s *testMemdServer) HoldResponses(n int) {
+	s.lock.Lock()
+	s.holdCount = n
+	s.lock.Unlock()
+}
+
+func (s *testMemdServer) LocalAddr() string {
+	return "127.0.0.1:52450"
+}
+
+func (s *testMemdServer) RemoteAddr() string {
+	return "127.0.0.1:11210"
+}
+
+func (s *testMemdServer) WritePacket(req *memd.Packet) error {
+	resp := memd.AcquirePacket()
+	resp.Magic = memd.CmdMagicRes
+	resp.Command = req.Command
+	resp.Opaque = req.Opaque
+	resp.Vbucket = req.Vbucket
+	resp.Status = memd.StatusSuccess
+
+	s.lock.Lock()
+	handler := s.handlers[req.Command]
+	serverDuration := s.serverDuration
+	errMap := s.errorMap
+	s.lock.Unlock()
+
+	if req.Command == memd.CmdGetErrorMap && handler == nil {
+		if errMap == nil {
+			resp.Status = memd.StatusUnknownCommand
+		} else {
+			resp.Value = errMap
+		}
+	} else if handler != nil {
+		handler(req, resp)
+	}
+
+	if serverDuration > 0 {
+		resp.ServerDurationFrame = &memd.ServerDurationFrame{
+			ServerDuration: serverDuration,
+		}
+	}
+
+	s.lock.Lock()
+	if s.holdCount > 0 {
+		s.held = append(s.held, resp)
+		if len(s.held) < s.holdCount {
+			s.lock.Unlock()
+			return nil
+		}
+		held := s.held
+		s.held = nil
+		s.holdCount = 0
+		s.lock.Unlock()
+
+		for i := len(held) - 1; i >= 0; i-- {
+			if err := s.sendResponse(held[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	s.lock.Unlock()
+
+	return s.sendResponse(resp)
+}
+
+func (s *testMemdServer) sendResponse(resp *memd.Packet) error {
+	select {
+	case s.respCh <- resp:
+		return nil
+	case <-s.closeCh:
+		return io.EOF
+	}
+}
+
+func (s *testMemdServer) ReadPacket() (*memd.Packet, int, error) {
+	select {
+	case resp := <-s.respCh:
+		return resp, 24 + len(resp.Key) + len(resp.Extras) + len(resp.Value), nil
+	case <-s.closeCh:
+		return nil, 0, io.EOF
+	}
+}
+
+func (s *testMemdServer) Close() error {
+	s.closeOnce.Do(func() {
+		close(s.closeCh)
+	})
+	return nil
+}
+
+func (s *testMemdServer) Release() {
+}
+
+func (s *testMemdServer) EnableFeature(feature memd.HelloFeature) {
+	s.lock.Lock()
+	s.features[feature] = true
+	s.lock.Unlock()
+}
+
+func (s *testMemdServer) IsFeatureEnabled(feature memd.HelloFeature) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.features[feature]
+}
+
+// newTestMemdServerClient creates a memdClient which is connected to the given testMemdServer.
+func newTestMemdServerClient(server *testMemdServer, postErrHandler postCompleteErrorHandler,
+	tracer *tracerComponent) *memdClient {
+	if postErrHandler == nil {
+		postErrHandler = func(resp *memdQResponse, req *memdQRequest, err error) (bool, error) {
+			return false, err
+		}
+	}
+
+	return newMemdClient(
+		memdClientProps{
+			ClientID:     "test",
+			DCPQueueSize: 1,
+		},
+		server,
+		CircuitBreakerConfig{},
+		postErrHandler,
+		tracer,
+		nil,
+		func(resp *memdQResponse) {},
+	)
+}
@@ -0,0 +1,366 @@
+package gocbcore
+
+import (
+	"encoding/json"
+	"net"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ThresholdLoggingOptions specifies options for the ThresholdLoggingTracer.
+// Volatile: This API is subject to change at any time.
+type ThresholdLoggingOptions struct {
+	// Interval is the period of time between reports being logged, defaults to 10 seconds.
+	Interval time.Duration
+	// SampleSize is the number of slow operations which will be reported for each service, defaults to 10.
+	SampleSize uint32
+
+	// The thresholds above which an operation is considered slow, per service. KVThreshold defaults to 500
+	// milliseconds, all other services default to 1 second.
+	KVThreshold        time.Duration
+	ViewsThreshold     time.Duration
+	QueryThreshold     time.Duration
+	SearchThreshold    time.Duration
+	AnalyticsThreshold time.Duration
+}
+
+// ThresholdLoggingTracer is a RequestTracer which records operations which take longer than the configured
+// per-service threshold and periodically logs a JSON report of the slowest of them.
+// Volatile: This API is subject to change at any time.
+type ThresholdLoggingTracer struct {
+	interval   time.Duration
+	sampleSize int
+	thresholds map[string]time.Duration
+
+	groupsLock sync.Mutex
+	groups     map[string]*thresholdLogGroup
+
+	stopSig chan struct{}
+	stopped uint32
+}
+
+// NewThresholdLoggingTracer creates a new ThresholdLoggingTracer and starts its report loop. Close must be called
+// once the tracer is no longer required.
+// Volatile: This API is subject to change at any time.
+func NewThresholdLoggingTracer(opts *ThresholdLoggingOptions) *ThresholdLoggingTracer {
+	if opts == nil {
+		opts = &ThresholdLoggingOptions{}
+	}
+
+	interval := 10 * time.Second
+	if opts.Interval > 0 {
+		interval = opts.Interval
+	}
+	sampleSize := 10
+	if opts.SampleSize > 0 {
+		sampleSize = int(opts.SampleSize)
+	}
+
+	thresholdOrDefault := func(threshold, def time.Duration) time.Duration {
+		if threshold > 0 {
+			return threshold
+		}
+		return def
+	}
+
+	tlt := &ThresholdLoggingTracer{
+		interval:   interval,
+		sampleSize: sampleSize,
+		thresholds: map[string]time.Duration{
+			metricValueServiceKeyValue:       thresholdOrDefault(opts.KVThreshold, 500*time.Millisecond),
+			metricValueServiceViewsValue:     thresholdOrDefault(opts.ViewsThreshold, 1*time.Second),
+			metricValueServiceQueryValue:     thresholdOrDefault(opts.QueryThreshold, 1*time.Second),
+			metricValueServiceSearchValue:    thresholdOrDefault(opts.SearchThreshold, 1*time.Second),
+			metricValueServiceAnalyticsValue: thresholdOrDefault(opts.AnalyticsThreshold, 1*time.Second),
+		},
+		groups:  make(map[string]*thresholdLogGroup),
+		stopSig: make(chan struct{}),
+	}
+	go tlt.loop()
+
+	return tlt
+}
+
+// RequestSpan creates a new span, if the parent context is a span created by this tracer then the new span will
+// report its timings back into it.
+func (tlt *ThresholdLoggingTracer) RequestSpan(parentContext RequestSpanContext, operationName string) RequestSpan {
+	parent, _ := parentContext.(*thresholdLogSpan)
+
+	return &thresholdLogSpan{
+		tracer:    tlt,
+		parent:    parent,
+		opName:    operationName,
+		startTime: time.Now(),
+	}
+}
+
+// Close stops the tracer from emitting any further reports.
+func (tlt *ThresholdLoggingTracer) Close() {
+	if atomic.CompareAndSwapUint32(&tlt.stopped, 0, 1) {
+		close(tlt.stopSig)
+	}
+}
+
+func (tlt *ThresholdLoggingTracer) loop() {
+	for {
+		select {
+		case <-tlt.stopSig:
+			return
+		case <-time.After(tlt.interval):
+		}
+
+		jsonBytes := tlt.createOutput()
+		if len(jsonBytes) == 0 {
+			continue
+		}
+
+		logInfof("Threshold Log:\n %s", jsonBytes)
+	}
+}
+
+func (tlt *ThresholdLoggingTracer) recordOp(service string, span *thresholdLogSpan) {
+	threshold, ok := tlt.thresholds[service]
+	if !ok || span.duration < threshold {
+		return
+	}
+
+	tlt.groupsLock.Lock()
+	group, ok := tlt.groups[service]
+	if !ok {
+		group = &thresholdLogGroup{
+			sampleSize: tlt.sampleSize,
+		}
+		tlt.groups[service] = group
+	}
+	tlt.groupsLock.Unlock()
+
+	group.recordOp(span)
+}
+
+func (tlt *ThresholdLoggingTracer) createOutput() []byte {
+	tlt.groupsLock.Lock()
+	groups := make(map[string]*thresholdLogGroup, len(tlt.groups))
+	for service, group := range tlt.groups {
+		groups[service] = group
+	}
+	tlt.groupsLock.Unlock()
+
+	output := make(map[string]thresholdLogService)
+	for service, group := range groups {
+		count, ops := group.reset()
+		if count == 0 {
+			continue
+		}
+
+		entry := thresholdLogService{
+			Count: count,
+			Top:   make([]thresholdLogItem, len(ops)),
+		}
+		for i, op := range ops {
+			// Ops are stored fastest first, the report should list the slowest first.
+			entry.Top[len(ops)-i-1] = op.logItem()
+		}
+
+		output[service] = entry
+	}
+
+	if len(output) == 0 {
+		return nil
+	}
+
+	jsonBytes, err := json.Marshal(output)
+	if err != nil {
+		logDebugf("Failed to generate threshold logging JSON: %s", err)
+		return nil
+	}
+
+	return jsonBytes
+}
+
+type thresholdLogItem struct {
+	OperationName          string `json:"operation_name"`
+	TotalTimeUs            uint64 `json:"total_duration_us"`
+	TotalDispatchTimeUs    uint64 `json:"total_dispatch_duration_us,omitempty"`
+	TotalServerTimeUs      uint64 `json:"total_server_duration_us,omitempty"`
+	LastDispatchDurationUs uint64 `json:"last_dispatch_duration_us,omitempty"`
+	LastServerDurationUs   uint64 `json:"last_server_duration_us,omitempty"`
+	LastOperationID        string `json:"operation_id,omitempty"`
+	LastLocalID            string `json:"last_local_id,omitempty"`
+	LastLocalSocket        string `json:"last_local_socket,omitempty"`
+	LastRemoteSocket       string `json:"last_remote_socket,omitempty"`
+}
+
+type thresholdLogService struct {
+	Count uint64             `json:"total_count"`
+	Top   []thresholdLogItem `json:"top_requests"`
+}
+
+type thresholdLogGroup struct {
+	sampleSize int
+
+	lock  sync.Mutex
+	count uint64
+	ops   []*thresholdLogSpan
+}
+
+func (g *thresholdLogGroup) recordOp(span *thresholdLogSpan) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	g.count++
+
+	if len(g.ops) == g.sampleSize && span.duration <= g.ops[0].duration {
+		// We are at capacity and this op is faster than the fastest op that we are already holding.
+		return
+	}
+
+	i := sort.Search(len(g.ops), func(i int) bool { return span.duration < g.ops[i].duration })
+	g.ops = append(g.ops, nil)
+	copy(g.ops[i+1:], g.ops[i:])
+	g.ops[i] = span
+
+	if len(g.ops) > g.sampleSize {
+		g.ops = g.ops[1:]
+	}
+}
+
+func (g *thresholdLogGroup) reset() (uint64, []*thresholdLogSpan) {
+	g.lock.Lock()
+	count := g.count
+	ops := g.ops
+	g.count = 0
+	g.ops = nil
+	g.lock.Unlock()
+
+	return count, ops
+}
+
+type thresholdLogSpan struct {
+	tracer    *ThresholdLoggingTracer
+	parent    *thresholdLogSpan
+	opName    string
+	startTime time.Time
+
+	lock                  sync.Mutex
+	service               string
+	operationID           string
+	localID               string
+	localHost             string
+	localPort             string
+	peerHost              string
+	peerPort              string
+	duration              time.Duration
+	serverDuration        time.Duration
+	totalServerDuration   time.Duration
+	dispatchDuration      time.Duration
+	totalDispatchDuration time.Duration
+}
+
+func (span *thresholdLogSpan) Context() RequestSpanContext {
+	return span
+}
+
+func (span *thresholdLogSpan) AddEvent(name string, timestamp time.Time) {
+}
+
+func (span *thresholdLogSpan) SetAttribute(key string, value interface{}) {
+	span.lock.Lock()
+	defer span.lock.Unlock()
+
+	switch key {
+	case spanAttribServiceKey:
+		span.service, _ = value.(string)
+	case spanAttribOperationIDKey:
+		span.operationID, _ = value.(string)
+	case spanAttribLocalIDKey:
+		span.localID, _ = value.(string)
+	case spanAttribNetHostNameKey:
+		span.localHost, _ = value.(string)
+	case spanAttribNetHostPortKey:
+		span.localPort, _ = value.(string)
+	case spanAttribNetPeerNameKey:
+		span.peerHost, _ = value.(string)
+	case spanAttribNetPeerPortKey:
+		span.peerPort, _ = value.(string)
+	case spanAttribServerDurationKey:
+		span.serverDuration, _ = value.(time.Duration)
+	}
+}
+
+func (span *thresholdLogSpan) End() {
+	span.lock.Lock()
+	span.duration = time.Since(span.startTime)
+	isDispatch := span.opName == spanNameDispatchToServer
+	if isDispatch {
+		span.dispatchDuration = span.duration
+		span.totalDispatchDuration = span.duration
+		span.totalServerDuration = span.serverDuration
+	}
+	service := span.service
+	span.lock.Unlock()
+
+	if span.parent != nil {
+		span.parent.childEnded(span, isDispatch)
+		return
+	}
+
+	if service != "" {
+		span.tracer.recordOp(service, span)
+	}
+}
+
+// childEnded propagates the dispatch details of a child span up into this span so that the root span holds the
+// details of the last dispatch as well as the totals across all dispatches.
+func (span *thresholdLogSpan) childEnded(child *thresholdLogSpan, isDispatch bool) {
+	child.lock.Lock()
+	operationID := child.operationID
+	localID := child.localID
+	localHost, localPort := child.localHost, child.localPort
+	peerHost, peerPort := child.peerHost, child.peerPort
+	serverDuration := child.serverDuration
+	dispatchDuration := child.dispatchDuration
+	totalServerDuration := child.totalServerDuration
+	totalDispatchDuration := child.totalDispatchDuration
+	child.lock.Unlock()
+
+	if !isDispatch && dispatchDuration == 0 {
+		return
+	}
+
+	span.lock.Lock()
+	span.operationID = operationID
+	span.localID = localID
+	span.localHost, span.localPort = localHost, localPort
+	span.peerHost, span.peerPort = peerHost, peerPort
+	span.serverDuration = serverDuration
+	span.dispatchDuration = dispatchDuration
+	span.totalServerDuration += totalServerDuration
+	span.totalDispatchDuration += totalDispatchDuration
+	span.lock.Unlock()
+}
+
+func (span *thresholdLogSpan) logItem() thresholdLogItem {
+	span.lock.Lock()
+	defer span.lock.Unlock()
+
+	item := thresholdLogItem{
+		OperationName:          span.opName,
+		TotalTimeUs:            uint64(span.duration / time.Microsecond),
+		TotalDispatchTimeUs:    uint64(span.totalDispatchDuration / time.Microsecond),
+		TotalServerTimeUs:      uint64(span.totalServerDuration / time.Microsecond),
+		LastDispatchDurationUs: uint64(span.dispatchDuration / time.Microsecond),
+		LastServerDurationUs:   uint64(span.serverDuration / time.Microsecond),
+		LastOperationID:        span.operationID,
+		LastLocalID:            span.localID,
+	}
+	if span.localHost != "" {
+		item.LastLocalSocket = net.JoinHostPort(span.localHost, span.localPort)
+	}
+	if span.peerHost != "" {
+		item.LastRemoteSocket = net.JoinHostPort(span.peerHost, span.peerPort)
+	}
+
+	return item
+}
@@ -0,0 +1,73 @@
+package gocbcore
+
+import (
+	"encoding/json"
+	"time"
+)
+
+func (suite *UnitTestSuite) TestThresholdLoggingTracer() {
+	tracer := NewThresholdLoggingTracer(&ThresholdLoggingOptions{
+		Interval:    time.Hour,
+		SampleSize:  2,
+		KVThreshold: time.Nanosecond,
+	})
+	defer tracer.Close()
+
+	suite.Assert().Nil(tracer.createOutput())
+
+	doOp := func(name string, sleep time.Duration) {
+		opSpan := tracer.RequestSpan(nil, name)
+		opSpan.SetAttribute(spanAttribServiceKey, metricValueServiceKeyValue)
+
+		cmdSpan := tracer.RequestSpan(opSpan.Context(), "CMD_GET")
+		netSpan := tracer.RequestSpan(cmdSpan.Context(), spanNameDispatchToServer)
+		netSpan.SetAttribute(spanAttribOperationIDKey, "0x21")
+		netSpan.SetAttribute(spanAttribLocalIDKey, "66388CF5BFCF7522/18CC8791579B567C")
+		netSpan.SetAttribute(spanAttribNetHostNameKey, "10.211.55.3")
+		netSpan.SetAttribute(spanAttribNetHostPortKey, "52450")
+		netSpan.SetAttribute(spanAttribNetPeerNameKey, "10.112.180.101")
+		netSpan.SetAttribute(spanAttribNetPeerPortKey, "11210")
+		netSpan.SetAttribute(spanAttribServerDurationKey, 10*time.Microsecond)
+		time.Sleep(sleep)
+		netSpan.End()
+		cmdSpan.End()
+		opSpan.End()
+	}
+
+	doOp("Get", 1*time.Millisecond)
+	doOp("Get", 20*time.Millisecond)
+	doOp("Get", 10*time.Millisecond)
+
+	// This op is for a service which has no threshold so should not be recorded.
+	unknown := tracer.RequestSpan(nil, "http")
+	unknown.SetAttribute(spanAttribServiceKey, metricValueServiceHTTPValue)
+	unknown.End()
+
+	jsonBytes := tracer.createOutput()
+	suite.Require().NotEmpty(jsonBytes)
+
+	var output map[string]thresholdLogService
+	suite.Require().Nil(json.Unmarshal(jsonBytes, &output))
+
+	suite.Require().Len(output, 1)
+	suite.Require().Contains(output, "kv")
+
+	kv := output["kv"]
+	suite.Assert().Equal(uint64(3), kv.Count)
+	suite.Require().Len(kv.Top, 2)
+	suite.Assert().GreaterOrEqual(kv.Top[0].TotalTimeUs, uint64(20000))
+	suite.Assert().GreaterOrEqual(kv.Top[1].TotalTimeUs, uint64(10000))
+	suite.Assert().Less(kv.Top[1].TotalTimeUs, kv.Top[0].TotalTimeUs)
+
+	item := kv.Top[0]
+	suite.Assert().Equal("Get", item.OperationName)
+	suite.Assert().Equal("0x21", item.LastOperationID)
+	suite.Assert().Equal("66388CF5BFCF7522/18CC8791579B567C", item.LastLocalID)
+	suite.Assert().Equal("10.211.55.3:52450", item.LastLocalSocket)
+	suite.Assert().Equal("10.112.180.101:11210", item.LastRemoteSocket)
+	suite.Assert().Equal(uint64(10), item.LastServerDurationUs)
+	suite.Assert().Equal(uint64(10), item.TotalServerTimeUs)
+	suite.Assert().NotZero(item.LastDispatchDurationUs)
+
+	suite.Assert().Nil(tracer.createOutput())
+}
@@ -0,0 +1,200 @@
+package gocbcore
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"sync"
+	"time"
+)
+
+// TLSFileWatcherOptions are the options available when creating a TLSFileWatcher.
+// Volatile: This API is subject to change at any time.
+type TLSFileWatcherOptions struct {
+	// RootCAFile is the path to a PEM file containing the root CAs to trust.
+	RootCAFile string
+	// CertFile and KeyFile are the paths to the PEM encoded client certificate and private key, if any.
+	CertFile string
+	KeyFile  string
+	// PollInterval is how often the files are checked for changes, defaults to 1 minute.
+	PollInterval time.Duration
+}
+
+// TLSFileWatcher loads root CAs and a client certificate from files on disk and reloads them whenever the files
+// change, so that rotated certificates are used by subsequently dialed connections. RootCAs can be used as a
+// TLSRootCAProvider and Certificate can be used to implement the Certificate method of an AuthProvider.
+// Volatile: This API is subject to change at any time.
+type TLSFileWatcher struct {
+	opts TLSFileWatcherOptions
+
+	lock       sync.Mutex
+	rootCAPEM  []byte
+	rootCAs    *x509.CertPool
+	certPEM    []byte
+	keyPEM     []byte
+	cert       *tls.Certificate
+	handlers   map[uint64]func(rootCAsChanged bool)
+	nextHandle uint64
+
+	closeCh   chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// NewTLSFileWatcher creates a new TLSFileWatcher, failing if the files cannot be loaded.
+// Volatile: This API is subject to change at any time.
+func NewTLSFileWatcher(opts TLSFileWatcherOptions) (*TLSFileWatcher, error) {
+	if opts.RootCAFile == "" && opts.CertFile == "" {
+		return nil, wrapError(errInvalidArgument, "must provide RootCAFile or CertFile")
+	}
+	if (opts.CertFile == "") != (opts.KeyFile == "") {
+		return nil, wrapError(errInvalidArgument, "CertFile and KeyFile must be provided together")
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = time.Minute
+	}
+
+	w := &TLSFileWatcher{
+		opts:     opts,
+		handlers: make(map[uint64]func(bool)),
+		closeCh:  make(chan struct{}),
+	}
+
+	if err := w.Refresh(); err != nil {
+		return nil, err
+	}
+
+	w.wg.Add(1)
+	go w.pollLoop()
+
+	return w, nil
+}
+
+// RootCAs returns the most recently loaded root CAs.
+func (w *TLSFileWatcher) RootCAs() *x509.CertPool {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.rootCAs
+}
+
+// Certificate returns the most recently loaded client certificate, or nil if no certificate is configured.
+func (w *TLSFileWatcher) Certificate(req AuthCertRequest) (*tls.Certificate, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	return w.cert, nil
+}
+
+// Refresh immediately reloads the files rather than waiting for the next poll. If a file fails to load then the
+// previously loaded certificates are kept.
+func (w *TLSFileWatcher) Refresh() error {
+	var rootCAPEM, certPEM, keyPEM []byte
+	var err error
+	if w.opts.RootCAFile != "" {
+		rootCAPEM, err = ioutil.ReadFile(w.opts.RootCAFile)
+		if err != nil {
+			return wrapError(err, "failed to read root CA file")
+		}
+	}
+	if w.opts.CertFile != "" {
+		certPEM, err = ioutil.ReadFile(w.opts.CertFile)
+		if err != nil {
+			return wrapError(err, "failed to read certificate file")
+		}
+		keyPEM, err = ioutil.ReadFile(w.opts.KeyFile)
+		if err != nil {
+			return wrapError(err, "failed to read key file")
+		}
+	}
+
+	w.lock.Lock()
+	rootCAsChanged := !bytes.Equal(rootCAPEM, w.rootCAPEM)
+	certChanged := !bytes.Equal(certPEM, w.certPEM) || !bytes.Equal(keyPEM, w.keyPEM)
+	if !rootCAsChanged && !certChanged {
+		w.lock.Unlock()
+		return nil
+	}
+
+	rootCAs := w.rootCAs
+	if rootCAsChanged && rootCAPEM != nil {
+		rootCAs = x509.NewCertPool()
+		if !rootCAs.AppendCertsFromPEM(rootCAPEM) {
+			w.lock.Unlock()
+			return errors.New("failed to parse root CA file")
+		}
+	}
+
+	cert := w.cert
+	if certChanged && certPEM != nil {
+		keyPair, err := tls.X509KeyPair(certPEM, keyPEM)
+		if err != nil {
+			w.lock.Unlock()
+			return wrapError(err, "failed to parse certificate")
+		}
+		cert = &keyPair
+	}
+
+	isInitialLoad := w.rootCAPEM == nil && w.certPEM == nil
+	w.rootCAPEM = rootCAPEM
+	w.rootCAs = rootCAs
+	w.certPEM = certPEM
+	w.keyPEM = keyPEM
+	w.cert = cert
+
+	var handlers []func(bool)
+	if !isInitialLoad {
+		for _, handler := range w.handlers {
+			handlers = append(handlers, handler)
+		}
+	}
+	w.lock.Unlock()
+
+	for _, handler := range handlers {
+		handler(rootCAsChanged)
+	}
+
+	return nil
+}
+
+// Close stops watching the files for changes.
+func (w *TLSFileWatcher) Close() {
+	w.closeOnce.Do(func() {
+		close(w.closeCh)
+	})
+	w.wg.Wait()
+}
+
+func (w *TLSFileWatcher) pollLoop() {
+	defer w.wg.Done()
+
+	ticker := time.NewTicker(w.opts.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := w.Refresh(); err != nil {
+				logWarnf("Failed to reload TLS certificates, will retry: %v", err)
+			}
+		case <-w.closeCh:
+			return
+		}
+	}
+}
+
+// onChange registers a handler which is called whenever reloaded files have changed. The returned function
+// unregisters the handler.
+func (w *TLSFileWatcher) onChange(handler func(rootCAsChanged bool)) func() {
+	w.lock.Lock()
+	id := w.nextHandle
+	w.nextHandle++
+	w.handlers[id] = handler
+	w.lock.Unlock()
+
+	return func() {
+		w.lock.Lock()
+		delete(w.handlers, id)
+		w.lock.Unlock()
+	}
+}
@@ -0,0 +1,128 @@
+package gocbcore
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+func (suite *UnitTestSuite) makeTestCertPEM(commonName string) ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	suite.Require().Nil(err, err)
+
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	suite.Require().Nil(err, err)
+
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	suite.Require().Nil(err, err)
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+}
+
+func (suite *UnitTestSuite) TestTLSFileWatcher() {
+	dir, err := ioutil.TempDir("", "gocbcore-tls")
+	suite.Require().Nil(err, err)
+	defer os.RemoveAll(dir)
+
+	caFile := filepath.Join(dir, "ca.pem")
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+
+	caPEM, _ := suite.makeTestCertPEM("ca1")
+	certPEM, keyPEM := suite.makeTestCertPEM("client1")
+	suite.Require().Nil(ioutil.WriteFile(caFile, caPEM, 0600))
+	suite.Require().Nil(ioutil.WriteFile(certFile, certPEM, 0600))
+	suite.Require().Nil(ioutil.WriteFile(keyFile, keyPEM, 0600))
+
+	watcher, err := NewTLSFileWatcher(TLSFileWatcherOptions{
+		RootCAFile:   caFile,
+		CertFile:     certFile,
+		KeyFile:      keyFile,
+		PollInterval: time.Hour,
+	})
+	suite.Require().Nil(err, err)
+	defer watcher.Close()
+
+	var changes []bool
+	unregister := watcher.onChange(func(rootCAsChanged bool) {
+		changes = append(changes, rootCAsChanged)
+	})
+
+	rootCAs := watcher.RootCAs()
+	suite.Require().NotNil(rootCAs)
+	cert, err := watcher.Certificate(AuthCertRequest{})
+	suite.Require().Nil(err, err)
+	suite.Require().NotNil(cert)
+	certBlock, _ := pem.Decode(certPEM)
+	suite.Assert().Equal(certBlock.Bytes, cert.Certificate[0])
+
+	// Unchanged files don't notify handlers.
+	suite.Require().Nil(watcher.Refresh())
+	suite.Assert().Empty(changes)
+
+	caPEM, _ = suite.makeTestCertPEM("ca2")
+	suite.Require().Nil(ioutil.WriteFile(caFile, caPEM, 0600))
+	suite.Require().Nil(watcher.Refresh())
+	suite.Assert().Equal([]bool{true}, changes)
+	suite.Assert().NotSame(rootCAs, watcher.RootCAs())
+
+	certPEM, keyPEM = suite.makeTestCertPEM("client2")
+	suite.Require().Nil(ioutil.WriteFile(certFile, certPEM, 0600))
+	suite.Require().Nil(ioutil.WriteFile(keyFile, keyPEM, 0600))
+	suite.Require().Nil(watcher.Refresh())
+	suite.Assert().Equal([]bool{true, false}, changes)
+	cert, err = watcher.Certificate(AuthCertRequest{})
+	suite.Require().Nil(err, err)
+	certBlock, _ = pem.Decode(certPEM)
+	suite.Assert().Equal(certBlock.Bytes, cert.Certificate[0])
+
+	// Files which fail to load leave the previous certificates in place.
+	rootCAs = watcher.RootCAs()
+	suite.Require().Nil(ioutil.WriteFile(caFile, []byte("not a certificate"), 0600))
+	suite.Assert().NotNil(watcher.Refresh())
+	suite.Assert().Same(rootCAs, watcher.RootCAs())
+	suite.Assert().Len(changes, 2)
+
+	// Connections use whichever root CAs the watcher most recently loaded.
+	dynConfig, err := setupTLSConfig(nil, SecurityConfig{UseTLS: true, TLSFileWatcher: watcher}, nil)
+	suite.Require().Nil(err, err)
+	tlsConfig, err := dynConfig.MakeForHost("localhost")
+	suite.Require().Nil(err, err)
+	suite.Assert().Same(rootCAs, tlsConfig.RootCAs)
+
+	unregister()
+	caPEM, _ = suite.makeTestCertPEM("ca3")
+	suite.Require().Nil(ioutil.WriteFile(caFile, caPEM, 0600))
+	suite.Require().Nil(watcher.Refresh())
+	suite.Assert().NotSame(rootCAs, watcher.RootCAs())
+	suite.Assert().Len(changes, 2)
+}
+
+func (suite *UnitTestSuite) TestTLSFileWatcherInvalidOptions() {
+	_, err := NewTLSFileWatcher(TLSFileWatcherOptions{})
+	suite.Assert().ErrorIs(err, ErrInvalidArgument)
+
+	_, err = NewTLSFileWatcher(TLSFileWatcherOptions{CertFile: "cert.pem"})
+	suite.Assert().ErrorIs(err, ErrInvalidArgument)
+
+	_, err = NewTLSFileWatcher(TLSFileWatcherOptions{RootCAFile: filepath.Join(os.TempDir(), "gocbcore-missing.pem")})
+	suite.Assert().NotNil(err)
+}
@@ -1,6 +1,7 @@
 package gocbcore
 
 import (
+	"errors"
 	"net"
 	"net/http"
 	"strconv"
@@ -185,6 +186,9 @@ func (tc *tracerComponent) StartCmdTrace(req *memdQRequest) {
 	if labels.ClusterUUID != "" {
 		req.cmdTraceSpan.SetAttribute(spanAttribClusterUUIDKey, labels.ClusterUUID)
 	}
+	if req.userMetadata != nil {
+		req.cmdTraceSpan.SetAttribute(spanAttribUserMetadataKey, req.userMetadata)
+	}
 	req.processingLock.Unlock()
 }
 
@@ -213,10 +217,7 @@ func (tc *tracerComponent) StartNetTrace(req *memdQRequest) {
 	req.processingLock.Unlock()
 }
 
-func (tc *tracerComponent) ResponseValueRecord(service, operation string, start time.Time) {
-	if tc.metrics == nil {
-		return
-	}
+func (tc *tracerComponent) metricAttribs(service, operation string) map[string]string {
 	key := service + "." + operation
 	attribs, ok := tc.valueRecorderAttribsCache.Load(key)
 	if !ok {
@@ -238,18 +239,198 @@ func (tc *tracerComponent) ResponseValueRecord(service, operation string, start
 		tc.valueRecorderAttribsCache.Store(key, attribs)
 	}
 
-	recorder, err := tc.metrics.ValueRecorder(meterNameCBOperations, attribs.(map[string]string))
+	return attribs.(map[string]string)
+}
+
+// endpointMetricAttribs returns the attributes for a metric recorded against a specific endpoint of service.
+func (tc *tracerComponent) endpointMetricAttribs(service, endpoint string) map[string]string {
+	key := "endpoint:" + service + "." + endpoint
+	attribs, ok := tc.valueRecorderAttribsCache.Load(key)
+	if !ok {
+		endpointAttribs := make(map[string]string)
+		for k, v := range tc.metricAttribs(service, "") {
+			endpointAttribs[k] = v
+		}
+		endpointAttribs[metricAttribEndpointKey] = endpoint
+		attribs = endpointAttribs
+		tc.valueRecorderAttribsCache.Store(key, attribs)
+	}
+
+	return attribs.(map[string]string)
+}
+
+func (tc *tracerComponent) recordValue(name, service, operation string, duration time.Duration) {
+	tc.recordValueWithAttribs(name, tc.metricAttribs(service, operation), duration)
+}
+
+func (tc *tracerComponent) recordValueWithAttribs(name string, attribs map[string]string, duration time.Duration) {
+	recorder, err := tc.metrics.ValueRecorder(name, attribs)
 	if err != nil {
 		logDebugf("Failed to get value recorder: %v", err)
 		return
 	}
 
-	duration := uint64(time.Since(start).Microseconds())
-	if duration == 0 {
-		duration = uint64(1 * time.Microsecond)
+	if duration < 0 {
+		duration = 0
+	}
+
+	recorder.RecordValue(uint64(duration.Microseconds()))
+}
+
+func (tc *tracerComponent) incrementCounter(name, service, operation string) {
+	tc.incrementCounterBy(name, service, operation, 1)
+}
+
+func (tc *tracerComponent) incrementCounterBy(name, service, operation string, num uint64) {
+	counter, err := tc.metrics.Counter(name, tc.metricAttribs(service, operation))
+	if err != nil {
+		logDebugf("Failed to get counter: %v", err)
+		return
+	}
+
+	counter.IncrementBy(num)
+}
+
+func (tc *tracerComponent) ResponseValueRecord(service, operation string, start time.Time) {
+	if tc.metrics == nil {
+		return
+	}
+
+	duration := time.Since(start)
+	if duration < time.Microsecond {
+		duration = time.Microsecond
 	}
 
-	recorder.RecordValue(duration)
+	tc.recordValue(meterNameCBOperations, service, operation, duration)
+}
+
+// ServerDurationValueRecord records the server duration reported by the server for a request.
+func (tc *tracerComponent) ServerDurationValueRecord(service, operation string, duration time.Duration) {
+	if tc.metrics == nil {
+		return
+	}
+
+	tc.recordValue(meterNameCBServerDurations, service, operation, duration)
+}
+
+// DispatchOverheadValueRecord records the time that a request to endpoint spent outside of the server, that is the
+// time from it being queued to its response being received less the server duration reported in the response. This
+// is the overhead added by client side queueing and the network, allowing it to be told apart from server slowness.
+func (tc *tracerComponent) DispatchOverheadValueRecord(endpoint string, queuedTime time.Time,
+	serverDuration time.Duration) {
+	if tc.metrics == nil || queuedTime.IsZero() {
+		return
+	}
+
+	// The server duration is encoded with limited precision so can exceed the time that we measured, in which case the
+	// overhead is recorded as zero.
+	tc.recordValueWithAttribs(meterNameCBDispatchOverhead,
+		tc.endpointMetricAttribs(metricValueServiceKeyValue, endpoint), time.Since(queuedTime)-serverDuration)
+}
+
+// DeadlineSlackValueRecord records how long before its deadline an operation completed, operations which completed
+// after their deadline are recorded as having no slack.
+func (tc *tracerComponent) DeadlineSlackValueRecord(service, operation string, deadline time.Time) {
+	if tc.metrics == nil {
+		return
+	}
+
+	tc.recordValue(meterNameCBDeadlineSlack, service, operation, time.Until(deadline))
+}
+
+// RetryCountRecord records that a request is going to be retried.
+func (tc *tracerComponent) RetryCountRecord(service, operation string) {
+	if tc.metrics == nil {
+		return
+	}
+
+	tc.incrementCounter(meterNameCBRetries, service, operation)
+}
+
+// TimeoutCountRecord records that an operation has timed out.
+func (tc *tracerComponent) TimeoutCountRecord(service, operation string) {
+	if tc.metrics == nil {
+		return
+	}
+
+	tc.incrementCounter(meterNameCBTimeouts, service, operation)
+}
+
+// StartConfigUpdateSpan starts a span covering the application of a new route config, from it being accepted through
+// to every config watcher having been updated.
+func (tc *tracerComponent) StartConfigUpdateSpan(cfg *routeConfig, nodesAdded, nodesRemoved int) RequestSpan {
+	span := tc.tracer.RequestSpan(nil, spanNameUpdateConfig)
+	span.SetAttribute(spanAttribDBSystemKey, spanAttribDBSystemValue)
+	span.SetAttribute(spanAttribConfigRevKey, cfg.revID)
+	span.SetAttribute(spanAttribConfigRevEpochKey, cfg.revEpoch)
+	span.SetAttribute(spanAttribNodesAddedKey, nodesAdded)
+	span.SetAttribute(spanAttribNodesRemovedKey, nodesRemoved)
+
+	return span
+}
+
+// ConfigUpdateRecord records the time taken to apply a new route config and the number of nodes which were added to
+// or removed from the cluster by it.
+func (tc *tracerComponent) ConfigUpdateRecord(start time.Time, nodesChanged int) {
+	if tc.metrics == nil {
+		return
+	}
+
+	tc.recordValue(meterNameCBConfigApplyDurations, metricValueServiceKeyValue, metricValueOperationUpdateConfig,
+		time.Since(start))
+
+	if nodesChanged == 0 {
+		return
+	}
+
+	tc.incrementCounterBy(meterNameCBConfigNodesChanged, metricValueServiceKeyValue, metricValueOperationUpdateConfig,
+		uint64(nodesChanged))
+}
+
+// CompressionSavedRecord records the number of bytes saved by sending a value compressed.
+func (tc *tracerComponent) CompressionSavedRecord(operation string, saved int) {
+	if tc.metrics == nil {
+		return
+	}
+
+	tc.incrementCounterBy(meterNameCBCompressionBytesSaved, metricValueServiceKeyValue, operation, uint64(saved))
+}
+
+// CompressionWastedRecord records the number of bytes which were compressed but then sent uncompressed as the
+// compression ratio achieved was not good enough.
+func (tc *tracerComponent) CompressionWastedRecord(operation string, wasted int) {
+	if tc.metrics == nil {
+		return
+	}
+
+	tc.incrementCounterBy(meterNameCBCompressionBytesWasted, metricValueServiceKeyValue, operation, uint64(wasted))
+}
+
+// NotMyVbucketCountRecord records that a request sent to endpoint failed with NotMyVbucket.
+func (tc *tracerComponent) NotMyVbucketCountRecord(endpoint string) {
+	if tc.metrics == nil {
+		return
+	}
+
+	counter, err := tc.metrics.Counter(meterNameCBNotMyVbuckets,
+		tc.endpointMetricAttribs(metricValueServiceKeyValue, endpoint))
+	if err != nil {
+		logDebugf("Failed to get counter: %v", err)
+		return
+	}
+
+	counter.IncrementBy(1)
+}
+
+// ConfigRequeueValueRecord records the time taken to requeue the requests from old pipelines onto new ones following
+// a route config change.
+func (tc *tracerComponent) ConfigRequeueValueRecord(start time.Time) {
+	if tc.metrics == nil {
+		return
+	}
+
+	tc.recordValue(meterNameCBConfigRequeueDurations, metricValueServiceKeyValue, metricValueOperationUpdateConfig,
+		time.Since(start))
 }
 
 func (tc *tracerComponent) OnNewRouteConfig(cfg *routeConfig) {
@@ -335,16 +516,26 @@ type opTelemetryHandler struct {
 	service           string
 	operation         string
 	start             time.Time
+	deadline          time.Time
 	metricsCompleteFn func(string, string, time.Time)
+	metricsTimeoutFn  func(string, string)
+	metricsSlackFn    func(string, string, time.Time)
 }
 
 func (tc *tracerComponent) StartTelemeteryHandler(service, operation string, traceContext RequestSpanContext) *opTelemetryHandler {
+	tracer := tc.CreateOpTrace(operation, traceContext)
+	if tracer.opSpan != nil {
+		tracer.opSpan.SetAttribute(spanAttribServiceKey, service)
+	}
+
 	return &opTelemetryHandler{
-		tracer:            tc.CreateOpTrace(operation, traceContext),
+		tracer:            tracer,
 		service:           service,
 		operation:         operation,
 		start:             time.Now(),
 		metricsCompleteFn: tc.ResponseValueRecord,
+		metricsTimeoutFn:  tc.TimeoutCountRecord,
+		metricsSlackFn:    tc.DeadlineSlackValueRecord,
 	}
 }
 
@@ -356,7 +547,24 @@ func (oth *opTelemetryHandler) StartTime() time.Time {
 	return oth.start
 }
 
+// SetDeadline sets the deadline of the operation so that how close to it the operation completes can be recorded, it
+// must be called before the operation is dispatched.
+func (oth *opTelemetryHandler) SetDeadline(deadline time.Time) {
+	oth.deadline = deadline
+}
+
 func (oth *opTelemetryHandler) Finish() {
 	oth.tracer.Finish()
 	oth.metricsCompleteFn(oth.service, oth.operation, oth.start)
+	if !oth.deadline.IsZero() {
+		oth.metricsSlackFn(oth.service, oth.operation, oth.deadline)
+	}
+}
+
+// FinishWithError behaves as Finish but also records the operation as timed out if err is a timeout.
+func (oth *opTelemetryHandler) FinishWithError(err error) {
+	if errors.Is(err, ErrTimeout) {
+		oth.metricsTimeoutFn(oth.service, oth.operation)
+	}
+	oth.Finish()
 }
@@ -49,8 +49,9 @@ func newTestTracer() *testTracer {
 }
 
 func (tt *testTracer) RequestSpan(parentContext RequestSpanContext, operationName string) RequestSpan {
-	// CCCP looper will send us spans which will mess with our trace verifications.
-	if operationName == memd.CmdGetClusterConfig.Name() || (operationName == spanNameDispatchToServer && parentContext == nil) {
+	// CCCP looper and config updates will send us spans which will mess with our trace verifications.
+	if operationName == memd.CmdGetClusterConfig.Name() || operationName == spanNameUpdateConfig ||
+		(operationName == spanNameDispatchToServer && parentContext == nil) {
 		return &noopSpan{}
 	}
 
@@ -86,8 +87,9 @@ func (suite *StandardTestSuite) AssertOpSpan(span *testSpan, expectedName, bucke
 
 func (suite *StandardTestSuite) AssertTopLevelSpan(span *testSpan, expectedName, bucketName string) {
 	suite.Assert().Equal(expectedName, span.Name)
-	numTags := 1
+	numTags := 2
 	suite.Assert().Equal("couchbase", span.Tags["db.system"])
+	suite.Assert().NotEmpty(span.Tags["db.couchbase.service"])
 	if suite.SupportsFeature(TestFeatureClusterLabels) {
 		suite.Assert().NotEmpty(span.Tags["db.couchbase.cluster_name"])
 		suite.Assert().NotEmpty(span.Tags["db.couchbase.cluster_uuid"])
@@ -168,7 +170,8 @@ func (suite *StandardTestSuite) AssertNetSpan(span *testSpan) {
 func (suite *StandardTestSuite) AssertHTTPSpan(span *testSpan, expectedName string) {
 	suite.Assert().Equal(expectedName, span.Name)
 	suite.Assert().Equal("couchbase", span.Tags["db.system"])
-	numTags := 1
+	suite.Assert().NotEmpty(span.Tags["db.couchbase.service"])
+	numTags := 2
 	if suite.SupportsFeature(TestFeatureClusterLabels) {
 		suite.Assert().NotEmpty(span.Tags["db.couchbase.cluster_name"])
 		suite.Assert().NotEmpty(span.Tags["db.couchbase.cluster_uuid"])
@@ -200,7 +203,8 @@ func (suite *StandardTestSuite) AssertHTTPSpan(span *testSpan, expectedName stri
 func (suite *StandardTestSuite) AssertHTTPSpanNoDispatch(span *testSpan, expectedName string) {
 	suite.Assert().Equal(expectedName, span.Name)
 	suite.Assert().Equal("couchbase", span.Tags["db.system"])
-	numTags := 1
+	suite.Assert().NotEmpty(span.Tags["db.couchbase.service"])
+	numTags := 2
 	if suite.SupportsFeature(TestFeatureClusterLabels) {
 		suite.Assert().NotEmpty(span.Tags["db.couchbase.cluster_name"])
 		suite.Assert().NotEmpty(span.Tags["db.couchbase.cluster_uuid"])
@@ -309,3 +313,55 @@ func (suite *StandardTestSuite) TestTracerComponentTracksClusterLabels() {
 	suite.Assert().Equal("test-cluster", tc.ClusterLabels().ClusterName)
 	suite.Assert().Equal("48d5d855660452102a8c279dc6155e01", tc.ClusterLabels().ClusterUUID)
 }
+
+func (suite *UnitTestSuite) TestOpTelemetryHandlerDeadlineSlack() {
+	meter := newTestMeter()
+	tc := newTracerComponent(&noopTracer{}, "", true, meter, nil)
+
+	handler := tc.StartTelemeteryHandler(metricValueServiceKeyValue, "Get", nil)
+	handler.SetDeadline(time.Now().Add(time.Hour))
+	handler.Finish()
+
+	handler = tc.StartTelemeteryHandler(metricValueServiceKeyValue, "Get", nil)
+	handler.SetDeadline(time.Now().Add(-time.Second))
+	handler.Finish()
+
+	// Operations without a deadline have no slack to record.
+	handler = tc.StartTelemeteryHandler(metricValueServiceKeyValue, "Upsert", nil)
+	handler.Finish()
+
+	key := meterNameCBDeadlineSlack + ":" + makeMetricsKey(metricValueServiceKeyValue, "Get")
+	suite.Require().Contains(meter.recorders, key)
+	values := meter.recorders[key].values
+	suite.Require().Len(values, 2)
+	suite.Assert().Greater(values[0], uint64((59 * time.Minute).Microseconds()))
+	suite.Assert().LessOrEqual(values[0], uint64(time.Hour.Microseconds()))
+	// Operations which completed after their deadline are recorded as having no slack.
+	suite.Assert().Zero(values[1])
+
+	suite.Assert().NotContains(meter.recorders, meterNameCBDeadlineSlack+":"+
+		makeMetricsKey(metricValueServiceKeyValue, "Upsert"))
+}
+
+func (suite *UnitTestSuite) TestDispatchOverheadValueRecord() {
+	meter := newTestMeter()
+	tc := newTracerComponent(&noopTracer{}, "", true, meter, nil)
+
+	tc.DispatchOverheadValueRecord("10.0.0.1:11210", time.Now().Add(-100*time.Millisecond), 40*time.Millisecond)
+	// The server duration exceeding the measured time is recorded as no overhead.
+	tc.DispatchOverheadValueRecord("10.0.0.1:11210", time.Now(), time.Second)
+	// Requests which were never queued have nothing to compare against.
+	tc.DispatchOverheadValueRecord("10.0.0.1:11210", time.Time{}, time.Millisecond)
+
+	key := meterNameCBDispatchOverhead + ":" + metricValueServiceKeyValue
+	suite.Require().Contains(meter.recorders, key)
+	values := meter.recorders[key].values
+	suite.Require().Len(values, 2)
+	suite.Assert().GreaterOrEqual(values[0], uint64((60 * time.Millisecond).Microseconds()))
+	suite.Assert().Less(values[0], uint64((100 * time.Millisecond).Microseconds()))
+	suite.Assert().Zero(values[1])
+
+	attribs := tc.endpointMetricAttribs(metricValueServiceKeyValue, "10.0.0.1:11210")
+	suite.Assert().Equal("10.0.0.1:11210", attribs[metricAttribEndpointKey])
+	suite.Assert().Equal(metricValueServiceKeyValue, attribs[metricAttribServiceKey])
+}
@@ -0,0 +1,166 @@
+package gocbcore
+
+import (
+	"encoding/json"
+
+	"github.com/couchbase/gocbcore/v10/memd"
+	"github.com/golang/snappy"
+)
+
+// ValueHooks allows document values to be transformed before they are written to, and after they are read from, the
+// server. This allows things like field level encryption, alternative compression or custom serialization to be
+// layered beneath the SDK. Values passed to the hooks are never snappy compressed, any snappy compression is removed
+// before the hooks are invoked and is applied, if enabled, after the value returned by BeforeWrite.
+// Hooks are invoked for Set, Add and Replace writes, and for Get, GetAndTouch, GetAndLock, GetOneReplica and
+// GetRandom reads, including when KVConfig.EnableChecksums is set.
+// Volatile: This API is subject to change at any time.
+type ValueHooks interface {
+	// BeforeWrite is invoked with the value and datatype of a document before it is written, the returned value and
+	// datatype are written in their place.
+	BeforeWrite(key, value []byte, datatype uint8) ([]byte, uint8, error)
+
+	// AfterRead is invoked with the value and datatype of a document after it is read, the returned value and datatype
+	// are returned in the result in their place.
+	AfterRead(key, value []byte, datatype uint8) ([]byte, uint8, error)
+}
+
+// SubDocValueHooks can optionally be implemented by a ValueHooks to also transform the values of sub-document
+// operations, allowing individual fields written with MutateIn to be, for example, encrypted and then decrypted when
+// they are read with LookupIn. Operations against xattrs, flagged with memd.SubdocFlagXattrPath, are passed to the hooks
+// as well so that hooks can recognise the metadata written by NewXattrMetadataWriteOp.
+// Volatile: This API is subject to change at any time.
+type SubDocValueHooks interface {
+	// BeforeSubDocWrite is invoked for each MutateIn operation before it is written, the returned value is written in
+	// place of op.Value.
+	BeforeSubDocWrite(key []byte, op SubDocOp) ([]byte, error)
+
+	// AfterSubDocRead is invoked for each successful LookupIn operation with the value that was read, the returned
+	// value is returned in the result in its place.
+	AfterSubDocRead(key []byte, op SubDocOp, value []byte) ([]byte, error)
+}
+
+// NewXattrMetadataWriteOp creates a MutateIn SubDocOp which stores metadata, encoded as JSON, in the xattr at path.
+// This allows libraries layered on ValueHooks, such as field level encryption, to record metadata like the key used
+// for each field atomically with the fields themselves.
+// Volatile: This API is subject to change at any time.
+func NewXattrMetadataWriteOp(path string, metadata interface{}) (SubDocOp, error) {
+	value, err := json.Marshal(metadata)
+	if err != nil {
+		return SubDocOp{}, wrapError(err, "failed to encode xattr metadata")
+	}
+
+	return SubDocOp{
+		Op:    memd.SubDocOpDictSet,
+		Flags: memd.SubdocFlagXattrPath | memd.SubdocFlagMkDirP,
+		Path:  path,
+		Value: value,
+	}, nil
+}
+
+// NewXattrMetadataReadOp creates a LookupIn SubDocOp which reads the metadata stored in the xattr at path, the result
+// can be decoded with DecodeXattrMetadata.
+// Volatile: This API is subject to change at any time.
+func NewXattrMetadataReadOp(path string) SubDocOp {
+	return SubDocOp{
+		Op:    memd.SubDocOpGet,
+		Flags: memd.SubdocFlagXattrPath,
+		Path:  path,
+	}
+}
+
+// DecodeXattrMetadata decodes the result of an operation created by NewXattrMetadataReadOp into metadata.
+// Volatile: This API is subject to change at any time.
+func DecodeXattrMetadata(result SubDocResult, metadata interface{}) error {
+	if result.Err != nil {
+		return result.Err
+	}
+
+	if err := json.Unmarshal(result.Value, metadata); err != nil {
+		return wrapError(err, "failed to decode xattr metadata")
+	}
+
+	return nil
+}
+
+func decompressForValueHook(value []byte, datatype uint8) ([]byte, uint8, error) {
+	if datatype&uint8(memd.DatatypeFlagCompressed) == 0 {
+		return value, datatype, nil
+	}
+
+	decompressed, err := snappy.Decode(nil, value)
+	if err != nil {
+		return nil, 0, wrapError(errInvalidArgument, "failed to decompress value for value hook")
+	}
+
+	return decompressed, datatype & ^uint8(memd.DatatypeFlagCompressed), nil
+}
+
+func (crud *crudComponent) beforeWrite(key, value []byte, datatype uint8) ([]byte, uint8, error) {
+	if crud.valueHooks == nil {
+		return value, datatype, nil
+	}
+
+	value, datatype, err := decompressForValueHook(value, datatype)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return crud.valueHooks.BeforeWrite(key, value, datatype)
+}
+
+func (crud *crudComponent) afterRead(key, value []byte, datatype uint8) ([]byte, uint8, error) {
+	if crud.valueHooks == nil {
+		return value, datatype, nil
+	}
+
+	value, datatype, err := decompressForValueHook(value, datatype)
+	if err != nil {
+		return nil, 0, wrapError(errProtocol, "failed to decompress value from the server for value hook")
+	}
+
+	return crud.valueHooks.AfterRead(key, value, datatype)
+}
+
+// beforeSubDocWrite returns ops with the values transformed by the sub-document value hooks, if any are set. The
+// returned slice is a copy so that the caller's ops are left untouched.
+func (crud *crudComponent) beforeSubDocWrite(key []byte, ops []SubDocOp) ([]SubDocOp, error) {
+	hooks, ok := crud.valueHooks.(SubDocValueHooks)
+	if !ok {
+		return ops, nil
+	}
+
+	hookedOps := make([]SubDocOp, len(ops))
+	for i, op := range ops {
+		value, err := hooks.BeforeSubDocWrite(key, op)
+		if err != nil {
+			return nil, err
+		}
+
+		op.Value = value
+		hookedOps[i] = op
+	}
+
+	return hookedOps, nil
+}
+
+func (crud *crudComponent) afterSubDocRead(key []byte, ops []SubDocOp, results []SubDocResult) error {
+	hooks, ok := crud.valueHooks.(SubDocValueHooks)
+	if !ok {
+		return nil
+	}
+
+	for i, op := range ops {
+		if results[i].Err != nil {
+			continue
+		}
+
+		value, err := hooks.AfterSubDocRead(key, op, results[i].Value)
+		if err != nil {
+			return err
+		}
+
+		results[i].Value = value
+	}
+
+	return nil
+}
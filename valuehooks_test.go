@@ -0,0 +1,160 @@
+package gocbcore
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/couchbase/gocbcore/v10/memd"
+	"github.com/golang/snappy"
+)
+
+type testValueHooks struct {
+	writeValue    []byte
+	writeDatatype uint8
+	readValue     []byte
+	readDatatype  uint8
+	err           error
+}
+
+func (h *testValueHooks) BeforeWrite(key, value []byte, datatype uint8) ([]byte, uint8, error) {
+	h.writeValue = value
+	h.writeDatatype = datatype
+	if h.err != nil {
+		return nil, 0, h.err
+	}
+	return bytes.ToUpper(value), datatype, nil
+}
+
+func (h *testValueHooks) AfterRead(key, value []byte, datatype uint8) ([]byte, uint8, error) {
+	h.readValue = value
+	h.readDatatype = datatype
+	if h.err != nil {
+		return nil, 0, h.err
+	}
+	return bytes.ToLower(value), datatype, nil
+}
+
+func (suite *UnitTestSuite) TestValueHooksNotConfigured() {
+	crud := &crudComponent{}
+	compressed := snappy.Encode(nil, []byte(`"value"`))
+	datatype := uint8(memd.DatatypeFlagJSON | memd.DatatypeFlagCompressed)
+
+	value, outDatatype, err := crud.beforeWrite([]byte("key"), compressed, datatype)
+	suite.Require().Nil(err)
+	suite.Assert().Equal(compressed, value)
+	suite.Assert().Equal(datatype, outDatatype)
+
+	value, outDatatype, err = crud.afterRead([]byte("key"), compressed, datatype)
+	suite.Require().Nil(err)
+	suite.Assert().Equal(compressed, value)
+	suite.Assert().Equal(datatype, outDatatype)
+}
+
+func (suite *UnitTestSuite) TestValueHooksReceiveDecompressedValues() {
+	hooks := &testValueHooks{}
+	crud := &crudComponent{valueHooks: hooks}
+	compressed := snappy.Encode(nil, []byte(`"Value"`))
+	datatype := uint8(memd.DatatypeFlagJSON | memd.DatatypeFlagCompressed)
+
+	value, outDatatype, err := crud.beforeWrite([]byte("key"), compressed, datatype)
+	suite.Require().Nil(err)
+	suite.Assert().Equal([]byte(`"Value"`), hooks.writeValue)
+	suite.Assert().Equal(uint8(memd.DatatypeFlagJSON), hooks.writeDatatype)
+	suite.Assert().Equal([]byte(`"VALUE"`), value)
+	suite.Assert().Equal(uint8(memd.DatatypeFlagJSON), outDatatype)
+
+	value, outDatatype, err = crud.afterRead([]byte("key"), compressed, datatype)
+	suite.Require().Nil(err)
+	suite.Assert().Equal([]byte(`"Value"`), hooks.readValue)
+	suite.Assert().Equal(uint8(memd.DatatypeFlagJSON), hooks.readDatatype)
+	suite.Assert().Equal([]byte(`"value"`), value)
+	suite.Assert().Equal(uint8(memd.DatatypeFlagJSON), outDatatype)
+}
+
+func (suite *UnitTestSuite) TestValueHooksErrors() {
+	hookErr := errors.New("hook failed")
+	crud := &crudComponent{valueHooks: &testValueHooks{err: hookErr}}
+
+	_, _, err := crud.beforeWrite([]byte("key"), []byte("value"), 0)
+	suite.Assert().Equal(hookErr, err)
+
+	_, _, err = crud.afterRead([]byte("key"), []byte("value"), 0)
+	suite.Assert().Equal(hookErr, err)
+
+	_, _, err = crud.afterRead([]byte("key"), []byte("not snappy"), uint8(memd.DatatypeFlagCompressed))
+	suite.Assert().True(errors.Is(err, errProtocol))
+
+	_, _, err = crud.beforeWrite([]byte("key"), []byte("not snappy"), uint8(memd.DatatypeFlagCompressed))
+	suite.Assert().True(errors.Is(err, errInvalidArgument))
+}
+
+type testSubDocValueHooks struct {
+	testValueHooks
+	writePaths []string
+	readPaths  []string
+}
+
+func (h *testSubDocValueHooks) BeforeSubDocWrite(key []byte, op SubDocOp) ([]byte, error) {
+	h.writePaths = append(h.writePaths, op.Path)
+	if op.Flags&memd.SubdocFlagXattrPath != 0 {
+		return op.Value, nil
+	}
+	return bytes.ToUpper(op.Value), nil
+}
+
+func (h *testSubDocValueHooks) AfterSubDocRead(key []byte, op SubDocOp, value []byte) ([]byte, error) {
+	h.readPaths = append(h.readPaths, op.Path)
+	if op.Flags&memd.SubdocFlagXattrPath != 0 {
+		return value, nil
+	}
+	return bytes.ToLower(value), nil
+}
+
+func (suite *UnitTestSuite) TestSubDocValueHooks() {
+	hooks := &testSubDocValueHooks{}
+	crud := &crudComponent{valueHooks: hooks}
+
+	metaOp, err := NewXattrMetadataWriteOp("encryption", map[string]string{"field": "key1"})
+	suite.Require().Nil(err, err)
+	suite.Assert().Equal(memd.SubDocOpDictSet, metaOp.Op)
+	suite.Assert().Equal(memd.SubdocFlagXattrPath|memd.SubdocFlagMkDirP, metaOp.Flags)
+
+	ops := []SubDocOp{
+		{Op: memd.SubDocOpDictSet, Path: "field", Value: []byte(`"secret"`)},
+		metaOp,
+	}
+	hookedOps, err := crud.beforeSubDocWrite([]byte("key"), ops)
+	suite.Require().Nil(err, err)
+	suite.Assert().Equal([]byte(`"SECRET"`), hookedOps[0].Value)
+	suite.Assert().Equal([]byte(`{"field":"key1"}`), hookedOps[1].Value)
+	suite.Assert().Equal([]byte(`"secret"`), ops[0].Value)
+	suite.Assert().Equal([]string{"field", "encryption"}, hooks.writePaths)
+
+	readOps := []SubDocOp{
+		{Op: memd.SubDocOpGet, Path: "field"},
+		NewXattrMetadataReadOp("encryption"),
+		{Op: memd.SubDocOpGet, Path: "missing"},
+	}
+	results := []SubDocResult{
+		{Value: []byte(`"SECRET"`)},
+		{Value: []byte(`{"field":"key1"}`)},
+		{Err: errPathNotFound},
+	}
+	suite.Require().Nil(crud.afterSubDocRead([]byte("key"), readOps, results))
+	suite.Assert().Equal([]byte(`"secret"`), results[0].Value)
+	suite.Assert().Equal([]string{"field", "encryption"}, hooks.readPaths)
+
+	var metadata map[string]string
+	suite.Require().Nil(DecodeXattrMetadata(results[1], &metadata))
+	suite.Assert().Equal(map[string]string{"field": "key1"}, metadata)
+	suite.Assert().Equal(errPathNotFound, DecodeXattrMetadata(results[2], &metadata))
+}
+
+func (suite *UnitTestSuite) TestSubDocValueHooksNotImplemented() {
+	crud := &crudComponent{valueHooks: &testValueHooks{}}
+
+	ops := []SubDocOp{{Op: memd.SubDocOpDictSet, Path: "field", Value: []byte(`"value"`)}}
+	hookedOps, err := crud.beforeSubDocWrite([]byte("key"), ops)
+	suite.Require().Nil(err, err)
+	suite.Assert().Equal(ops, hookedOps)
+}
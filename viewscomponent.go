@@ -5,7 +5,6 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io/ioutil"
 	"net/url"
 	"strings"
 	"time"
@@ -78,7 +77,7 @@ func parseViewQueryError(req *httpRequest, ddoc, view string, resp *HTTPResponse
 	var err error
 	var errorDescs []ViewQueryErrorDesc
 
-	respBody, readErr := ioutil.ReadAll(resp.Body)
+	respBody, readErr := resp.readBody()
 	if readErr == nil {
 		var errsMap map[string]string
 		var errsArr []string
@@ -163,9 +162,12 @@ func (vqc *viewQueryComponent) ViewQuery(opts ViewQueryOptions, cb ViewQueryCall
 
 	go func() {
 		res, err := vqc.viewQuery(ireq, ddoc, view)
+		err = ireq.complete(err, func() error {
+			return res.Close()
+		})
 		if err != nil {
 			cancel()
-			tracer.Finish()
+			tracer.FinishWithError(err)
 			cb(nil, err)
 			return
 		}
@@ -177,12 +179,27 @@ func (vqc *viewQueryComponent) ViewQuery(opts ViewQueryOptions, cb ViewQueryCall
 	return ireq, nil
 }
 
+func (vqc *viewQueryComponent) viewsNotAvailableError() error {
+	clusterVersion := vqc.httpComponent.muxer.ClusterVersion()
+	if clusterVersion == "" {
+		return wrapError(errFeatureNotAvailable, "views are not available for this bucket")
+	}
+
+	return wrapError(errFeatureNotAvailable,
+		fmt.Sprintf("views are not available for this bucket, cluster version %s", clusterVersion))
+}
+
 func (vqc *viewQueryComponent) viewQuery(ireq *httpRequest, ddoc, view string) (*ViewQueryRowReader, error) {
 	resp, err := vqc.httpComponent.DoInternalHTTPRequest(ireq, false)
 	if err != nil {
 		if errors.Is(err, ErrRequestCanceled) {
 			return nil, err
 		}
+		if errors.Is(err, ErrServiceNotAvailable) {
+			// The config contains no views endpoints so either the bucket type doesn't support views or the views
+			// engine has been removed from the cluster, either way retrying won't help.
+			return nil, wrapViewQueryError(ireq, ddoc, view, vqc.viewsNotAvailableError(), "", 0)
+		}
 		// execHTTPRequest will handle retrying due to in-flight socket close based
 		// on whether or not IsIdempotent is set on the httpRequest
 		return nil, wrapViewQueryError(ireq, ddoc, view, err, "", 0)
@@ -197,7 +214,7 @@ func (vqc *viewQueryComponent) viewQuery(ireq *httpRequest, ddoc, view string) (
 
 	streamer, err := newQueryStreamer(resp.Body, "rows")
 	if err != nil {
-		respBody, readErr := ioutil.ReadAll(resp.Body)
+		respBody, readErr := resp.readBody()
 		if readErr != nil {
 			logDebugf("Failed to read response body: %v", readErr)
 		}
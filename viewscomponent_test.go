@@ -0,0 +1,57 @@
+package gocbcore
+
+import (
+	"errors"
+	"net/http"
+	"time"
+	"unsafe"
+)
+
+func (suite *UnitTestSuite) TestViewQueryViewsNotAvailable() {
+	mux := &httpMux{
+		muxPtr: unsafe.Pointer(&httpClientMux{
+			mgmtEpList: []routeEndpoint{{Address: "http://10.112.210.101:8091"}},
+			revID:      1,
+			srcConfig: routeConfig{
+				clusterCompatVersion: 7<<16 | 6,
+			},
+			auth: PasswordAuthProvider{Username: "user", Password: "pass"},
+		}),
+	}
+	tracer := newTracerComponent(&noopTracer{}, "", true, &noopMeter{}, nil)
+	vqc := newViewQueryComponent(newHTTPComponentWithClient(httpComponentProps{}, &http.Client{}, mux, tracer), tracer)
+
+	errCh := make(chan error, 1)
+	_, err := vqc.ViewQuery(ViewQueryOptions{
+		DesignDocumentName: "ddoc",
+		ViewType:           "_view",
+		ViewName:           "view",
+		RetryStrategy:      newFailFastRetryStrategy(),
+		Deadline:           time.Now().Add(time.Second),
+	}, func(reader *ViewQueryRowReader, err error) {
+		errCh <- err
+	})
+	suite.Require().Nil(err, err)
+
+	err = <-errCh
+	suite.Require().ErrorIs(err, ErrFeatureNotAvailable)
+	suite.Assert().Contains(err.Error(), "cluster version 7.6")
+
+	var viewErr *ViewError
+	suite.Require().True(errors.As(err, &viewErr))
+	suite.Assert().Equal("ddoc", viewErr.DesignDocumentName)
+	suite.Assert().Equal("view", viewErr.ViewName)
+}
+
+func (suite *UnitTestSuite) TestClusterCompatVersionDuringUpgrade() {
+	cfg := &cfgBucket{
+		Nodes: []cfgNode{
+			{ClusterCompatibility: 7<<16 | 6},
+			{ClusterCompatibility: 7<<16 | 2},
+			{},
+		},
+	}
+	suite.Assert().Equal(7<<16|2, cfg.BuildRouteConfig(false, "default", false, nil).clusterCompatVersion)
+
+	suite.Assert().Zero((&cfgBucket{Nodes: []cfgNode{{}}}).clusterCompatVersion())
+}
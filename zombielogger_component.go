@@ -1,10 +1,12 @@
 package gocbcore
 
 import (
+	"container/heap"
 	"encoding/json"
 	"fmt"
 	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -15,6 +17,8 @@ type zombieLogEntry struct {
 	localSocket   string
 	duration      time.Duration
 	operationName string
+	// seq is the order in which the entry was added to its bucket, used to hold the most recent of equally slow entries.
+	seq uint64
 }
 
 type zombieLogItem struct {
@@ -33,24 +37,122 @@ type zombieLogJsonEntry struct {
 
 type zombieLogService map[string]zombieLogJsonEntry
 
+// zombieLogHeap is a min-heap of orphaned responses ordered by server duration, and then by age, so the fastest, and
+// oldest, of the slowest responses seen is always at the root and can be cheaply replaced.
+type zombieLogHeap []*zombieLogEntry
+
+func (h zombieLogHeap) Len() int { return len(h) }
+func (h zombieLogHeap) Less(i, j int) bool {
+	if h[i].duration == h[j].duration {
+		return h[i].seq < h[j].seq
+	}
+	return h[i].duration < h[j].duration
+}
+func (h zombieLogHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *zombieLogHeap) Push(x interface{}) {
+	*h = append(*h, x.(*zombieLogEntry))
+}
+
+func (h *zombieLogHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	entry := old[n-1]
+	*h = old[:n-1]
+	return entry
+}
+
+// zombieLogServiceBucket holds the slowest orphaned responses seen by a service during a single logging interval,
+// bounded to the sample size. Each service has its own bucket and lock so that services don't contend with each other,
+// and once a bucket is full any response which is faster than those already held is dropped without locking.
+// Responses which are as slow as the fastest held replace it, so that when many responses have the same duration, such
+// as when the server does not report durations, the most recent are held rather than the earliest. The trade-off is
+// that these responses do take the lock, rather than this being lock-free as reservoir sampling would be, as we would
+// rather report the slowest responses than a random sample of them.
+type zombieLogServiceBucket struct {
+	// seen and minDuration must be first in the struct so that they are 64-bit aligned for atomic access on 32-bit
+	// platforms.
+	seen uint64
+	// minDuration is the duration of the fastest response held once the bucket is full, or -1 if it is not full.
+	minDuration int64
+
+	sampleSize int
+	lock       sync.Mutex
+	nextSeq    uint64
+	ops        zombieLogHeap
+}
+
+func newZombieLogServiceBucket(sampleSize int) *zombieLogServiceBucket {
+	return &zombieLogServiceBucket{
+		minDuration: -1,
+		sampleSize:  sampleSize,
+		ops:         make(zombieLogHeap, 0, sampleSize),
+	}
+}
+
+func (bucket *zombieLogServiceBucket) record(entry *zombieLogEntry) {
+	atomic.AddUint64(&bucket.seen, 1)
+	if bucket.sampleSize == 0 {
+		return
+	}
+
+	if minDuration := atomic.LoadInt64(&bucket.minDuration); minDuration >= 0 && int64(entry.duration) < minDuration {
+		// We are at capacity and we are faster than the fastest slow op.
+		return
+	}
+
+	bucket.lock.Lock()
+	entry.seq = bucket.nextSeq
+	bucket.nextSeq++
+	if len(bucket.ops) < bucket.sampleSize {
+		heap.Push(&bucket.ops, entry)
+	} else if entry.duration >= bucket.ops[0].duration {
+		bucket.ops[0] = entry
+		heap.Fix(&bucket.ops, 0)
+	}
+	if len(bucket.ops) == bucket.sampleSize {
+		atomic.StoreInt64(&bucket.minDuration, int64(bucket.ops[0].duration))
+	}
+	bucket.lock.Unlock()
+}
+
+// take empties the bucket, returning the slowest responses seen ordered slowest first.
+func (bucket *zombieLogServiceBucket) take() []*zombieLogEntry {
+	bucket.lock.Lock()
+	ops := bucket.ops
+	bucket.ops = make(zombieLogHeap, 0, bucket.sampleSize)
+	atomic.StoreUint64(&bucket.seen, 0)
+	atomic.StoreInt64(&bucket.minDuration, -1)
+	bucket.lock.Unlock()
+
+	sort.Slice(ops, func(i, j int) bool {
+		return ops[i].duration > ops[j].duration
+	})
+
+	return ops
+}
+
 type zombieLoggerComponent struct {
-	zombieLock sync.RWMutex
-	zombieOps  []*zombieLogEntry
+	// services is populated on creation and never modified afterwards so can be read without locking.
+	services   map[string]*zombieLogServiceBucket
 	interval   time.Duration
 	sampleSize int
 	stopSig    chan struct{}
 }
 
 func newZombieLoggerComponent(interval time.Duration, sampleSize int) *zombieLoggerComponent {
-	return &zombieLoggerComponent{
-		// zombieOps must have a static capacity for its lifetime, the capacity should
-		// never be altered so that it is consistent across the zombieLogger and
-		// recordZombieResponse.
-		zombieOps:  make([]*zombieLogEntry, 0, sampleSize),
+	zlc := &zombieLoggerComponent{
+		services:   make(map[string]*zombieLogServiceBucket),
 		interval:   interval,
 		sampleSize: sampleSize,
 		stopSig:    make(chan struct{}),
 	}
+
+	for _, service := range []string{metricValueServiceKeyValue} {
+		zlc.services[service] = newZombieLogServiceBucket(sampleSize)
+	}
+
+	return zlc
 }
 
 func (zlc *zombieLoggerComponent) Start() {
@@ -75,47 +177,44 @@ func (zlc *zombieLoggerComponent) Start() {
 }
 
 func (zlc *zombieLoggerComponent) createOutput() []byte {
-	// Preallocate space to copy the ops into...
-	oldOps := make([]*zombieLogEntry, zlc.sampleSize)
+	output := make(zombieLogService)
+	for service, bucket := range zlc.services {
+		// Escape early if we have no ops to log...
+		if atomic.LoadUint64(&bucket.seen) == 0 {
+			continue
+		}
 
-	zlc.zombieLock.Lock()
-	// Escape early if we have no ops to log...
-	if len(zlc.zombieOps) == 0 {
-		zlc.zombieLock.Unlock()
-		return nil
-	}
+		// Take our ops out of the bucket so we can cheaply print them out without blocking our ops from
+		// being recorded in other goroutines.
+		oldOps := bucket.take()
+		if len(oldOps) == 0 {
+			continue
+		}
 
-	// Copy out our ops so we can cheaply print them out without blocking
-	// our ops from actually being recorded in other goroutines (which would
-	// effectively slow down the op pipeline for logging).
-	oldOps = oldOps[0:len(zlc.zombieOps)]
-	copy(oldOps, zlc.zombieOps)
-	zlc.zombieOps = zlc.zombieOps[:0]
+		entries := zombieLogJsonEntry{
+			Count: len(oldOps),
+			Top:   make([]zombieLogItem, len(oldOps)),
+		}
 
-	zlc.zombieLock.Unlock()
+		for i, op := range oldOps {
+			entries.Top[i] = zombieLogItem{
+				OperationID:      op.operationID,
+				ConnectionID:     op.connectionID,
+				RemoteSocket:     op.remoteSocket,
+				LocalSocket:      op.localSocket,
+				ServerDurationUs: uint64(op.duration.Microseconds()),
+				OperationName:    op.operationName,
+			}
+		}
 
-	entries := zombieLogJsonEntry{
-		Top: make([]zombieLogItem, len(oldOps)),
+		output[service] = entries
 	}
 
-	for i := 0; i < len(oldOps); i++ {
-		op := oldOps[i]
-
-		entries.Top[len(oldOps)-i-1] = zombieLogItem{
-			OperationID:      op.operationID,
-			ConnectionID:     op.connectionID,
-			RemoteSocket:     op.remoteSocket,
-			LocalSocket:      op.localSocket,
-			ServerDurationUs: uint64(op.duration.Microseconds()),
-			OperationName:    op.operationName,
-		}
+	if len(output) == 0 {
+		return nil
 	}
 
-	entries.Count = len(entries.Top)
-
-	jsonBytes, err := json.Marshal(zombieLogService{
-		"kv": entries,
-	})
+	jsonBytes, err := json.Marshal(output)
 	if err != nil {
 		logDebugf("Failed to generate zombie logging JSON: %s", err)
 	}
@@ -141,45 +240,5 @@ func (zlc *zombieLoggerComponent) RecordZombieResponse(resp *memdQResponse, conn
 		entry.duration = resp.Packet.ServerDurationFrame.ServerDuration
 	}
 
-	zlc.zombieLock.RLock()
-
-	if cap(zlc.zombieOps) == 0 || (len(zlc.zombieOps) == cap(zlc.zombieOps) &&
-		entry.duration < zlc.zombieOps[0].duration) {
-		// we are at capacity and we are faster than the fastest slow op or somehow in a state where capacity is 0.
-		zlc.zombieLock.RUnlock()
-		return
-	}
-	zlc.zombieLock.RUnlock()
-
-	zlc.zombieLock.Lock()
-	if cap(zlc.zombieOps) == 0 || (len(zlc.zombieOps) == cap(zlc.zombieOps) &&
-		entry.duration < zlc.zombieOps[0].duration) {
-		// we are at capacity and we are faster than the fastest slow op or somehow in a state where capacity is 0.
-		zlc.zombieLock.Unlock()
-		return
-	}
-
-	l := len(zlc.zombieOps)
-	i := sort.Search(l, func(i int) bool { return entry.duration < zlc.zombieOps[i].duration })
-
-	// i represents the slot where it should be inserted
-
-	if len(zlc.zombieOps) < cap(zlc.zombieOps) {
-		if i == l {
-			zlc.zombieOps = append(zlc.zombieOps, entry)
-		} else {
-			zlc.zombieOps = append(zlc.zombieOps, nil)
-			copy(zlc.zombieOps[i+1:], zlc.zombieOps[i:])
-			zlc.zombieOps[i] = entry
-		}
-	} else {
-		if i == 0 {
-			zlc.zombieOps[i] = entry
-		} else {
-			copy(zlc.zombieOps[0:i-1], zlc.zombieOps[1:i])
-			zlc.zombieOps[i-1] = entry
-		}
-	}
-
-	zlc.zombieLock.Unlock()
+	zlc.services[metricValueServiceKeyValue].record(entry)
 }
@@ -3,8 +3,10 @@ package gocbcore
 import (
 	"encoding/json"
 	"fmt"
-	"github.com/couchbase/gocbcore/v10/memd"
+	"sync"
 	"time"
+
+	"github.com/couchbase/gocbcore/v10/memd"
 )
 
 func (suite *UnitTestSuite) TestZombieLoggerComponent() {
@@ -139,3 +141,78 @@ func (suite *UnitTestSuite) TestZombieLoggerComponent() {
 
 	suite.Assert().Equal(expectedJsonOutput, []byte(mapInnerOutput["top_requests"]), fmt.Sprintf("Expected output to be %s but was %s", string(expectedJsonOutput), string(mapInnerOutput["top_requests"])))
 }
+
+func (suite *UnitTestSuite) TestZombieLoggerComponentKeepsSlowest() {
+	z := newZombieLoggerComponent(1*time.Second, 10)
+
+	numResponses := 10000
+	var wg sync.WaitGroup
+	for g := 0; g < 4; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := g; i < numResponses; i += 4 {
+				z.RecordZombieResponse(&memdQResponse{
+					Packet: &memd.Packet{
+						Command: memd.CmdGet,
+						Opaque:  uint32(i),
+						ServerDurationFrame: &memd.ServerDurationFrame{
+							ServerDuration: time.Duration(i) * time.Microsecond,
+						},
+					},
+				}, "conn", "local", "remote")
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	var output map[string]struct {
+		Count int `json:"total_count"`
+		Top   []struct {
+			OperationID      string `json:"operation_id"`
+			ServerDurationUs uint64 `json:"last_server_duration_us"`
+		} `json:"top_requests"`
+	}
+	suite.Require().Nil(json.Unmarshal(z.createOutput(), &output))
+	suite.Require().Contains(output, "kv")
+	suite.Assert().Equal(10, output["kv"].Count)
+	suite.Require().Len(output["kv"].Top, 10)
+
+	// Only the slowest responses are kept, slowest first.
+	for i, item := range output["kv"].Top {
+		expected := uint64(numResponses - 1 - i)
+		suite.Assert().Equal(expected, item.ServerDurationUs)
+		suite.Assert().Equal(fmt.Sprintf("0x%x", expected), item.OperationID)
+	}
+
+	// The bucket is reset after each output.
+	suite.Assert().Nil(z.createOutput())
+}
+
+func (suite *UnitTestSuite) TestZombieLoggerComponentKeepsLatestOfEqualDuration() {
+	z := newZombieLoggerComponent(1*time.Second, 2)
+
+	// Responses without a server duration all have the same duration so the latest should be kept.
+	for i := 0; i < 5; i++ {
+		z.RecordZombieResponse(&memdQResponse{
+			Packet: &memd.Packet{
+				Command: memd.CmdGet,
+				Opaque:  uint32(i),
+			},
+		}, "conn", "local", "remote")
+	}
+
+	var output map[string]struct {
+		Top []struct {
+			OperationID string `json:"operation_id"`
+		} `json:"top_requests"`
+	}
+	suite.Require().Nil(json.Unmarshal(z.createOutput(), &output))
+	suite.Require().Len(output["kv"].Top, 2)
+
+	var ids []string
+	for _, item := range output["kv"].Top {
+		ids = append(ids, item.OperationID)
+	}
+	suite.Assert().ElementsMatch([]string{"0x3", "0x4"}, ids)
+}